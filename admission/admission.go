@@ -0,0 +1,391 @@
+// Package admission limits the requests the clients make to the services of
+// a conode, so that a single client can't use all its resources:
+//
+//   - a token bucket bounds the rate of the requests of every client
+//   - a cap bounds the requests of every client processed at the same time
+//   - a quota bounds the requests of every client over a period
+//
+// A client is identified by its IP address, the common name of its
+// certificate when the conode uses client authentication, or all the clients
+// share the same limits.
+//
+// The services declare their default limits with RegisterDefaults. The
+// conode loads a Config, which adds limits or replaces the default ones, and
+// sets its Policy. The requests of every service are checked by Admit in
+// clienthook.Process, which admits everything without a policy. Before
+// closing, the conode calls Drain so that the new requests are rejected
+// while the running ones finish.
+package admission
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Any matches all the services in the Endpoint of a Limit.
+const Any = "*"
+
+// The ways of identifying the clients in the Per of a Limit.
+const (
+	// PerIP identifies the clients by their IP address. It is the default.
+	PerIP = "ip"
+	// PerCertificate identifies the clients by the common name of their
+	// certificate, or by their IP address without one.
+	PerCertificate = "certificate"
+	// Global shares the limit between all the clients.
+	Global = "global"
+)
+
+// ErrRejected is wrapped in the errors of the rejected requests.
+var ErrRejected = errors.New("request rejected")
+
+// Limit bounds the requests made by every client to an endpoint.
+type Limit struct {
+	// Endpoint is the service name, like "ByzCoin", the endpoint, like
+	// "ByzCoin/AddTransaction", or Any for all of them.
+	Endpoint string
+	// Per is how the clients are identified: PerIP, PerCertificate or
+	// Global.
+	Per string
+	// Rate is the number of requests per second a client can make, with
+	// bursts of up to Burst requests. There is no rate when it is zero.
+	Rate  float64
+	Burst int
+	// Concurrent is the number of requests of a client processed at the same
+	// time. There is no cap when it is zero.
+	Concurrent int
+	// Quota is the number of requests a client can make during every
+	// Period, like "24h". There is no quota when it is zero.
+	Quota  int
+	Period string
+
+	period time.Duration
+}
+
+// check validates the limit and parses its period.
+func (l *Limit) check() error {
+	if l.Endpoint == "" {
+		return errors.New("limit without an Endpoint")
+	}
+	switch l.Per {
+	case "":
+		l.Per = PerIP
+	case PerIP, PerCertificate, Global:
+	default:
+		return fmt.Errorf("limit of %s: unknown Per %q", l.Endpoint, l.Per)
+	}
+	if l.Rate < 0 || l.Burst < 0 || l.Concurrent < 0 || l.Quota < 0 {
+		return fmt.Errorf("limit of %s: negative value", l.Endpoint)
+	}
+	if l.Rate == 0 && l.Concurrent == 0 && l.Quota == 0 {
+		return fmt.Errorf("limit of %s has no Rate, Concurrent nor Quota",
+			l.Endpoint)
+	}
+	if l.Rate > 0 && l.Burst == 0 {
+		l.Burst = 1
+	}
+	if l.Quota > 0 {
+		var err error
+		l.period, err = time.ParseDuration(l.Period)
+		if err != nil || l.period <= 0 {
+			return fmt.Errorf("limit of %s: invalid Period %q", l.Endpoint,
+				l.Period)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the limit applies to the endpoint of the service.
+func (l *Limit) matches(service, endpoint string) bool {
+	return l.Endpoint == Any || l.Endpoint == service ||
+		l.Endpoint == service+"/"+endpoint
+}
+
+var (
+	defaultsMutex sync.Mutex
+	defaults      []Limit
+)
+
+// RegisterDefaults adds the limits a service applies when the conode
+// enables the admission control, unless its Config replaces them. It is
+// called by the services in their init.
+func RegisterDefaults(limits ...Limit) error {
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	for _, l := range limits {
+		if err := l.check(); err != nil {
+			return err
+		}
+		defaults = append(defaults, l)
+	}
+	return nil
+}
+
+// Config is the admission control of a conode, as read from its TOML file:
+//
+//	Exempt = ["127.0.0.1", "::1"]
+//
+//	[[Limit]]
+//	Endpoint = "ByzCoin/AddTransaction"
+//	Rate = 5.0
+//	Burst = 20
+//
+//	[[Limit]]
+//	Endpoint = "*"
+//	Concurrent = 8
+//	Quota = 100000
+//	Period = "24h"
+type Config struct {
+	// Exempt are the IP addresses and the common names of the clients that
+	// are never limited.
+	Exempt []string
+	// TrustForwardedFor identifies the clients by the last address of the
+	// X-Forwarded-For header, as set by a reverse proxy in front of the
+	// conode. It must only be set when all the requests go through the proxy.
+	TrustForwardedFor bool
+	// NoDefaults drops the default limits of the services.
+	NoDefaults bool
+	// Limit are the limits added to the default ones. A limit with the same
+	// Endpoint as a default one replaces it.
+	Limit []Limit
+}
+
+// LoadConfig reads the admission control of a conode from a TOML file.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	_, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read admission control: %v", err)
+	}
+	for i := range cfg.Limit {
+		if err := cfg.Limit[i].check(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Policy returns the policy of the limits of the config and the default
+// limits it doesn't replace.
+func (cfg *Config) Policy() *Policy {
+	p := &Policy{
+		exempt:            make(map[string]bool),
+		trustForwardedFor: cfg.TrustForwardedFor,
+	}
+	for _, e := range cfg.Exempt {
+		p.exempt[e] = true
+	}
+
+	replaced := make(map[string]bool)
+	for _, l := range cfg.Limit {
+		replaced[l.Endpoint] = true
+		p.limiters = append(p.limiters, newLimiter(l))
+	}
+	if !cfg.NoDefaults {
+		defaultsMutex.Lock()
+		for _, l := range defaults {
+			if !replaced[l.Endpoint] {
+				p.limiters = append(p.limiters, newLimiter(l))
+			}
+		}
+		defaultsMutex.Unlock()
+	}
+	return p
+}
+
+// Policy holds the limits and the state of the clients.
+type Policy struct {
+	limiters          []*limiter
+	exempt            map[string]bool
+	trustForwardedFor bool
+}
+
+// Limits returns the limits of the policy.
+func (p *Policy) Limits() []Limit {
+	limits := make([]Limit, len(p.limiters))
+	for i, l := range p.limiters {
+		limits[i] = l.Limit
+	}
+	return limits
+}
+
+// Admit returns an error wrapping ErrRejected if the request of the client
+// to the endpoint of the service is over one of the limits. Otherwise, the
+// returned function must be called once the request has been processed.
+func (p *Policy) Admit(req *http.Request, service, endpoint string) (func(),
+	error) {
+	endpoint = strings.TrimPrefix(endpoint, "/")
+	ip, commonName := p.client(req)
+	if p.exempt[ip] || commonName != "" && p.exempt[commonName] {
+		return func() {}, nil
+	}
+
+	now := time.Now()
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, l := range p.limiters {
+		if !l.matches(service, endpoint) {
+			continue
+		}
+		id := ip
+		switch {
+		case l.Per == Global:
+			id = ""
+		case l.Per == PerCertificate && commonName != "":
+			id = "cn:" + commonName
+		}
+		r, err := l.admit(id, now)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("%w: %s/%s: %v", ErrRejected, service,
+				endpoint, err)
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+// client returns the IP address of the client of the request, and the
+// common name of its certificate if it has one.
+func (p *Policy) client(req *http.Request) (string, string) {
+	if req == nil {
+		return "", ""
+	}
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		ip = host
+	}
+	if p.trustForwardedFor {
+		if fwd := req.Header.Get("X-Forwarded-For"); fwd != "" {
+			addrs := strings.Split(fwd, ",")
+			ip = strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	commonName := ""
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		commonName = req.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	return ip, commonName
+}
+
+var (
+	policyMutex sync.RWMutex
+	policy      *Policy
+)
+
+// SetPolicy sets the policy applied by Admit. A nil policy admits all the
+// requests.
+func SetPolicy(p *Policy) {
+	policyMutex.Lock()
+	policy = p
+	policyMutex.Unlock()
+}
+
+// Admit checks the request of the client to the endpoint of the service
+// against the policy of the conode, see Policy.Admit, and rejects it while
+// the conode drains the requests. The returned function must be called once
+// the request has been processed.
+func Admit(req *http.Request, service, endpoint string) (func(), error) {
+	leave, err := requests.enter(service, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	policyMutex.RLock()
+	p := policy
+	policyMutex.RUnlock()
+	if p == nil {
+		return leave, nil
+	}
+	release, err := p.Admit(req, service, endpoint)
+	if err != nil {
+		leave()
+		return nil, err
+	}
+	return func() {
+		release()
+		leave()
+	}, nil
+}
+
+// Drain rejects the new requests, and waits for the ones being processed
+// for at most the timeout. It returns false if some were still running.
+func Drain(timeout time.Duration) bool {
+	return requests.drain(timeout)
+}
+
+// Resume admits the requests again after Drain.
+func Resume() {
+	requests.resume()
+}
+
+// inFlight counts the requests being processed.
+type inFlight struct {
+	sync.Mutex
+	draining bool
+	running  int
+	idle     chan struct{}
+}
+
+var requests inFlight
+
+// enter counts a new request, and returns the function to call once it has
+// been processed.
+func (f *inFlight) enter(service, endpoint string) (func(), error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.draining {
+		return nil, fmt.Errorf("%w: %s/%s: the conode is shutting down",
+			ErrRejected, service, strings.TrimPrefix(endpoint, "/"))
+	}
+	f.running++
+	var once sync.Once
+	return func() { once.Do(f.leave) }, nil
+}
+
+func (f *inFlight) leave() {
+	f.Lock()
+	defer f.Unlock()
+	f.running--
+	if f.running == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+func (f *inFlight) drain(timeout time.Duration) bool {
+	f.Lock()
+	f.draining = true
+	if f.running == 0 {
+		f.Unlock()
+		return true
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (f *inFlight) resume() {
+	f.Lock()
+	f.draining = false
+	f.Unlock()
+}
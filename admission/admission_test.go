@@ -0,0 +1,215 @@
+package admission
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// withDefaults replaces the default limits during a test.
+func withDefaults(t *testing.T, limits ...Limit) func() {
+	defaultsMutex.Lock()
+	old := defaults
+	defaults = nil
+	defaultsMutex.Unlock()
+	require.NoError(t, RegisterDefaults(limits...))
+	return func() {
+		defaultsMutex.Lock()
+		defaults = old
+		defaultsMutex.Unlock()
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admission")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "admission.toml")
+	write := func(s string) {
+		require.NoError(t, ioutil.WriteFile(fn, []byte(s), 0600))
+	}
+
+	write(`Exempt = ["127.0.0.1"]
+
+[[Limit]]
+Endpoint = "ByzCoin/AddTransaction"
+Rate = 5.0
+
+[[Limit]]
+Endpoint = "*"
+Per = "global"
+Concurrent = 8
+Quota = 1000
+Period = "24h"
+`)
+	cfg, err := LoadConfig(fn)
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.1"}, cfg.Exempt)
+	require.Equal(t, 2, len(cfg.Limit))
+	require.Equal(t, PerIP, cfg.Limit[0].Per)
+	require.Equal(t, 1, cfg.Limit[0].Burst)
+	require.Equal(t, Global, cfg.Limit[1].Per)
+
+	for _, bad := range []string{
+		"[[Limit]]\nRate = 1.0\n",
+		"[[Limit]]\nEndpoint = \"*\"\n",
+		"[[Limit]]\nEndpoint = \"*\"\nRate = 1.0\nPer = \"user\"\n",
+		"[[Limit]]\nEndpoint = \"*\"\nQuota = 10\n",
+		"[[Limit]]\nEndpoint = \"*\"\nConcurrent = -1\n",
+	} {
+		write(bad)
+		_, err = LoadConfig(fn)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestConfig_Policy(t *testing.T) {
+	defer withDefaults(t,
+		Limit{Endpoint: "ByzCoin/AddTransaction", Rate: 10},
+		Limit{Endpoint: "Skipchain", Concurrent: 4})()
+
+	cfg := &Config{Limit: []Limit{{Endpoint: "Skipchain", Rate: 1}}}
+	limits := cfg.Policy().Limits()
+	require.Equal(t, 2, len(limits))
+	require.Equal(t, "Skipchain", limits[0].Endpoint)
+	require.Equal(t, 0, limits[0].Concurrent)
+	require.Equal(t, "ByzCoin/AddTransaction", limits[1].Endpoint)
+
+	cfg.NoDefaults = true
+	require.Equal(t, 1, len(cfg.Policy().Limits()))
+
+	require.Error(t, RegisterDefaults(Limit{Endpoint: "ByzCoin"}))
+}
+
+func request(remote string) *http.Request {
+	return &http.Request{RemoteAddr: remote, Header: http.Header{}}
+}
+
+func TestPolicy_Admit(t *testing.T) {
+	defer withDefaults(t)()
+	cfg := &Config{
+		Exempt: []string{"10.0.0.9", "admin"},
+		Limit: []Limit{
+			{Endpoint: "ByzCoin/AddTransaction", Rate: 0.001, Burst: 1},
+			{Endpoint: "Skipchain", Per: Global, Rate: 0.001, Burst: 2},
+			{Endpoint: "Calypso", Per: PerCertificate, Rate: 0.001,
+				Burst: 1},
+		},
+	}
+	for i := range cfg.Limit {
+		require.NoError(t, cfg.Limit[i].check())
+	}
+	p := cfg.Policy()
+
+	admit := func(req *http.Request, service, endpoint string) error {
+		release, err := p.Admit(req, service, endpoint)
+		if err == nil {
+			release()
+		}
+		return err
+	}
+
+	// Per IP address, and only for the endpoint.
+	require.NoError(t, admit(request("10.0.0.1:1234"), "ByzCoin",
+		"AddTransaction"))
+	err := admit(request("10.0.0.1:5678"), "ByzCoin", "/AddTransaction")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrRejected))
+	require.NoError(t, admit(request("10.0.0.2:1234"), "ByzCoin",
+		"AddTransaction"))
+	require.NoError(t, admit(request("10.0.0.1:1234"), "ByzCoin",
+		"GetProof"))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, admit(request("10.0.0.9:1234"), "ByzCoin",
+			"AddTransaction"))
+	}
+
+	// Shared by all the clients.
+	require.NoError(t, admit(request("10.0.0.1:1"), "Skipchain", "GetBlock"))
+	require.NoError(t, admit(request("10.0.0.2:1"), "Skipchain",
+		"StoreSkipBlock"))
+	require.Error(t, admit(request("10.0.0.3:1"), "Skipchain", "GetBlock"))
+
+	// Per certificate, the exempt admin is never limited.
+	withCert := func(cn string) *http.Request {
+		req := request("10.0.0.1:1")
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: cn}}}}
+		return req
+	}
+	require.NoError(t, admit(withCert("alice"), "Calypso", "DecryptKey"))
+	require.Error(t, admit(withCert("alice"), "Calypso", "DecryptKey"))
+	require.NoError(t, admit(withCert("bob"), "Calypso", "DecryptKey"))
+	require.NoError(t, admit(withCert("admin"), "Calypso", "DecryptKey"))
+	require.NoError(t, admit(withCert("admin"), "Calypso", "DecryptKey"))
+}
+
+func TestPolicy_ForwardedFor(t *testing.T) {
+	defer withDefaults(t)()
+	cfg := &Config{
+		TrustForwardedFor: true,
+		Limit:             []Limit{{Endpoint: Any, Rate: 0.001, Burst: 1}},
+	}
+	p := cfg.Policy()
+	forwarded := func(fwd string) *http.Request {
+		req := request("127.0.0.1:1234")
+		req.Header.Set("X-Forwarded-For", fwd)
+		return req
+	}
+	_, err := p.Admit(forwarded("1.2.3.4, 10.0.0.1"), "Status", "Request")
+	require.NoError(t, err)
+	_, err = p.Admit(forwarded("10.0.0.1"), "Status", "Request")
+	require.Error(t, err)
+	_, err = p.Admit(forwarded("10.0.0.2"), "Status", "Request")
+	require.NoError(t, err)
+}
+
+func TestAdmit(t *testing.T) {
+	defer withDefaults(t)()
+	defer SetPolicy(nil)
+	cfg := &Config{Limit: []Limit{{Endpoint: Any, Concurrent: 1}}}
+	require.NoError(t, cfg.Limit[0].check())
+
+	release, err := Admit(nil, "Status", "Request")
+	require.NoError(t, err)
+	release()
+
+	SetPolicy(cfg.Policy())
+	release, err = Admit(nil, "Status", "Request")
+	require.NoError(t, err)
+	_, err = Admit(nil, "Status", "Request")
+	require.Error(t, err)
+	release()
+	release, err = Admit(nil, "Status", "Request")
+	require.NoError(t, err)
+	release()
+}
+
+func TestDrain(t *testing.T) {
+	defer Resume()
+	require.True(t, Drain(time.Millisecond))
+	_, err := Admit(nil, "Status", "Request")
+	require.True(t, errors.Is(err, ErrRejected))
+	Resume()
+
+	release, err := Admit(nil, "Status", "Request")
+	require.NoError(t, err)
+	require.False(t, Drain(10*time.Millisecond))
+	_, err = Admit(nil, "Status", "Request")
+	require.Error(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	require.True(t, Drain(time.Second))
+}
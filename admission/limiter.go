@@ -0,0 +1,154 @@
+package admission
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxClients is the number of clients above which the state of the clients
+// back to their initial state is removed.
+const maxClients = 1000
+
+// Buckets is a token bucket for each client.
+type Buckets struct {
+	sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Allow returns true if the client still has a token, and takes it, given
+// that the tokens come back at the rate per second up to the burst.
+func (bs *Buckets) Allow(id string, rate float64, burst int) bool {
+	return bs.allowAt(id, rate, burst, time.Now())
+}
+
+func (bs *Buckets) allowAt(id string, rate float64, burst int,
+	now time.Time) bool {
+	bs.Lock()
+	defer bs.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	if bs.buckets == nil {
+		bs.buckets = make(map[string]*bucket)
+	}
+	if len(bs.buckets) > maxClients {
+		for k, b := range bs.buckets {
+			if b.refill(now, rate, burst) == float64(burst) {
+				delete(bs.buckets, k)
+			}
+		}
+	}
+
+	b, ok := bs.buckets[id]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		bs.buckets[id] = b
+	}
+	if b.refill(now, rate, burst) < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *bucket) refill(now time.Time, rate float64, burst int) float64 {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	return b.tokens
+}
+
+// quota is the number of requests of a client in the current period.
+type quota struct {
+	count int
+	start time.Time
+}
+
+// limiter keeps the state of the clients of a limit.
+type limiter struct {
+	Limit
+	buckets Buckets
+
+	sync.Mutex
+	running map[string]int
+	quotas  map[string]*quota
+}
+
+func newLimiter(l Limit) *limiter {
+	return &limiter{
+		Limit:   l,
+		running: make(map[string]int),
+		quotas:  make(map[string]*quota),
+	}
+}
+
+// admit checks the request of the client against the limit, and returns
+// the function to call once the request has been processed.
+func (l *limiter) admit(id string, now time.Time) (func(), error) {
+	l.Lock()
+	if l.Concurrent > 0 && l.running[id] >= l.Concurrent {
+		l.Unlock()
+		return nil, fmt.Errorf("over %d requests at the same time",
+			l.Concurrent)
+	}
+	l.running[id]++
+	l.Unlock()
+
+	// The requests over the rate don't count in the quota.
+	if l.Rate > 0 && !l.buckets.allowAt(id, l.Rate, l.Burst, now) {
+		l.done(id)
+		return nil, fmt.Errorf("over %v requests per second", l.Rate)
+	}
+	if l.Quota > 0 {
+		l.Lock()
+		err := l.takeQuota(id, now)
+		l.Unlock()
+		if err != nil {
+			l.done(id)
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { l.done(id) }) }, nil
+}
+
+// takeQuota counts the request in the quota of the client, starting a new
+// period if the current one is over. The lock must be held.
+func (l *limiter) takeQuota(id string, now time.Time) error {
+	if len(l.quotas) > maxClients {
+		for k, q := range l.quotas {
+			if now.Sub(q.start) >= l.period {
+				delete(l.quotas, k)
+			}
+		}
+	}
+	q, ok := l.quotas[id]
+	if !ok || now.Sub(q.start) >= l.period {
+		q = &quota{start: now}
+		l.quotas[id] = q
+	}
+	if q.count >= l.Quota {
+		return fmt.Errorf("over the quota of %d requests per %v", l.Quota,
+			l.period)
+	}
+	q.count++
+	return nil
+}
+
+// done ends a request of the client.
+func (l *limiter) done(id string) {
+	l.Lock()
+	defer l.Unlock()
+	l.running[id]--
+	if l.running[id] <= 0 {
+		delete(l.running, id)
+	}
+}
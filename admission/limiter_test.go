@@ -0,0 +1,65 @@
+package admission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuckets(t *testing.T) {
+	bs := Buckets{}
+	require.True(t, bs.Allow("a", 1000, 1))
+	require.False(t, bs.Allow("a", 0, 1))
+	require.True(t, bs.Allow("b", 0, 1))
+
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, bs.Allow("a", 1000, 1))
+}
+
+func TestLimiter_Concurrent(t *testing.T) {
+	l := newLimiter(Limit{Endpoint: Any, Concurrent: 2})
+	now := time.Now()
+	r1, err := l.admit("a", now)
+	require.NoError(t, err)
+	_, err = l.admit("a", now)
+	require.NoError(t, err)
+	_, err = l.admit("a", now)
+	require.Error(t, err)
+	_, err = l.admit("b", now)
+	require.NoError(t, err)
+
+	// Releasing twice only ends one request.
+	r1()
+	r1()
+	_, err = l.admit("a", now)
+	require.NoError(t, err)
+	_, err = l.admit("a", now)
+	require.Error(t, err)
+}
+
+func TestLimiter_Quota(t *testing.T) {
+	l := Limit{Endpoint: Any, Quota: 2, Period: "1h", Rate: 1, Burst: 2}
+	require.NoError(t, l.check())
+	lim := newLimiter(l)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		r, err := lim.admit("a", now)
+		require.NoError(t, err)
+		r()
+	}
+	_, err := lim.admit("a", now.Add(time.Second))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "quota")
+	require.Equal(t, 0, lim.running["a"])
+
+	// The quota starts again with the next period.
+	next := now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		r, err := lim.admit("a", next)
+		require.NoError(t, err)
+		r()
+	}
+	_, err = lim.admit("b", next)
+	require.NoError(t, err)
+}
@@ -0,0 +1,264 @@
+// Package apitoken restricts the privileged endpoints of the services of a
+// conode to the clients presenting a bearer token signed by the admin of the
+// node.
+//
+// A token is signed with the private key of the conode, which only its admin
+// has, and gives the endpoints it can be used for until it expires. The
+// conode sets the Policy, and Authorize checks the token of the requests of
+// every service in clienthook.Process.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/protobuf"
+)
+
+// Any is the scope allowing all the endpoints.
+const Any = "*"
+
+// signatureTag separates the signatures of the tokens from the other
+// signatures of the key of the conode.
+const signatureTag = "cothority api token"
+
+// DefaultEndpoints are the privileged endpoints requiring a token when the
+// conode enables the tokens.
+var DefaultEndpoints = []string{
+	"Calypso/CreateLTS",
+	"Skipchain/StoreSkipBlock",
+	"Status/Request",
+}
+
+// Claims are the signed content of a token.
+type Claims struct {
+	// ID identifies the token in the logs.
+	ID []byte
+	// Scopes are the service names, like "Calypso", or the endpoints, like
+	// "Calypso/CreateLTS", the token can be used for. Any allows all of them.
+	Scopes []string
+	// Expiry is the time, in seconds since the epoch, after which the token
+	// is rejected.
+	Expiry int64
+}
+
+// message returns the hash of the claims signed by the conode.
+func (c *Claims) message() ([]byte, error) {
+	buf, err := protobuf.Encode(c)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode claims: %v", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(signatureTag))
+	h.Write(buf)
+	return h.Sum(nil), nil
+}
+
+// token is what the clients send, encoded in base64.
+type token struct {
+	Claims    Claims
+	Signature []byte
+}
+
+// Issue returns a new token with the scopes, valid until expiry and signed
+// with the private key of the conode.
+func Issue(private kyber.Scalar, scopes []string,
+	expiry time.Time) (string, error) {
+	if len(scopes) == 0 {
+		return "", errors.New("a token needs at least one scope")
+	}
+	t := token{Claims: Claims{
+		ID:     make([]byte, 8),
+		Scopes: scopes,
+		Expiry: expiry.Unix(),
+	}}
+	_, err := rand.Read(t.Claims.ID)
+	if err != nil {
+		return "", fmt.Errorf("couldn't pick an ID: %v", err)
+	}
+
+	msg, err := t.Claims.message()
+	if err != nil {
+		return "", err
+	}
+	t.Signature, err = schnorr.Sign(cothority.Suite, private, msg)
+	if err != nil {
+		return "", fmt.Errorf("couldn't sign token: %v", err)
+	}
+	buf, err := protobuf.Encode(&t)
+	if err != nil {
+		return "", fmt.Errorf("couldn't encode token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Verify returns the claims of a token if it is signed by the public key and
+// not expired at now.
+func Verify(tok string, public kyber.Point, now time.Time) (*Claims, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(tok)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token encoding: %v", err)
+	}
+	var t token
+	err = protobuf.Decode(buf, &t)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode token: %v", err)
+	}
+
+	msg, err := t.Claims.message()
+	if err != nil {
+		return nil, err
+	}
+	err = schnorr.Verify(cothority.Suite, public, msg, t.Signature)
+	if err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+	if now.Unix() > t.Claims.Expiry {
+		return nil, fmt.Errorf("token %x expired at %v", t.Claims.ID,
+			time.Unix(t.Claims.Expiry, 0).UTC())
+	}
+	return &t.Claims, nil
+}
+
+// Allows returns true if the claims give access to the endpoint of the
+// service.
+func (c *Claims) Allows(service, endpoint string) bool {
+	for _, s := range c.Scopes {
+		if s == Any || s == service || s == service+"/"+endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy gives the endpoints requiring a token, and the key the tokens are
+// signed with.
+type Policy struct {
+	Key kyber.Point
+	// Endpoints are the privileged endpoints, like "Calypso/CreateLTS", or
+	// service names for all the endpoints of a service.
+	Endpoints []string
+	// Keys returns the keys the tokens can be signed with, like
+	// keyrotation.Keys, so that the tokens signed with a key the conode
+	// rotated from stay valid during the grace window. Only Key is accepted
+	// if it is nil.
+	Keys func(key kyber.Point, now time.Time) []kyber.Point
+}
+
+// keys returns the keys the tokens can be signed with at now.
+func (p *Policy) keys(now time.Time) []kyber.Point {
+	if p.Keys == nil {
+		return []kyber.Point{p.Key}
+	}
+	return p.Keys(p.Key, now)
+}
+
+// Requires returns true if the endpoint of the service needs a token.
+func (p *Policy) Requires(service, endpoint string) bool {
+	for _, e := range p.Endpoints {
+		if e == service || e == service+"/"+endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error if an endpoint of the policy is not of one of the
+// services, so that a misspelled endpoint is not left without a token.
+func (p *Policy) Check(services []string) error {
+	for _, e := range p.Endpoints {
+		name := strings.SplitN(e, "/", 2)[0]
+		found := false
+		for _, s := range services {
+			if s == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("endpoint %s is not of a service of the conode", e)
+		}
+	}
+	return nil
+}
+
+var policyMutex sync.RWMutex
+var policy *Policy
+
+// SetPolicy sets the policy checked by Authorize. A nil policy requires no
+// token, which is the default.
+func SetPolicy(p *Policy) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+	policy = p
+}
+
+// Required returns true if the current policy needs a token for the endpoint
+// of the service.
+func Required(service, endpoint string) bool {
+	policyMutex.RLock()
+	defer policyMutex.RUnlock()
+	return policy != nil && policy.Requires(service, endpoint)
+}
+
+// Authorize returns an error if the endpoint of the service is privileged
+// under the current policy and the request has no valid token for it. The
+// token is read from the "Authorization: Bearer" header, or else from the
+// "token" query parameter for the websocket clients that can't set headers.
+func Authorize(req *http.Request, service, endpoint string) error {
+	policyMutex.RLock()
+	p := policy
+	policyMutex.RUnlock()
+	endpoint = strings.TrimPrefix(endpoint, "/")
+	if p == nil || !p.Requires(service, endpoint) {
+		return nil
+	}
+
+	tok := bearer(req)
+	if tok == "" {
+		return fmt.Errorf("%s/%s requires an API token", service, endpoint)
+	}
+	// The tokens signed with a key the conode rotated from stay valid
+	// during the grace window of the rotation.
+	now := time.Now()
+	var claims *Claims
+	var err error
+	for _, key := range p.keys(now) {
+		if claims, err = Verify(tok, key, now); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !claims.Allows(service, endpoint) {
+		return fmt.Errorf("token %x doesn't allow %s/%s", claims.ID, service,
+			endpoint)
+	}
+	return nil
+}
+
+// bearer returns the token of the request, or an empty string.
+func bearer(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	auth := req.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if req.URL != nil {
+		return req.URL.Query().Get("token")
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package apitoken
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/protobuf"
+)
+
+func TestIssueVerify(t *testing.T) {
+	kp := key.NewKeyPair(cothority.Suite)
+	now := time.Now()
+
+	tok, err := Issue(kp.Private, []string{"Calypso/CreateLTS"},
+		now.Add(time.Hour))
+	require.NoError(t, err)
+	claims, err := Verify(tok, kp.Public, now)
+	require.NoError(t, err)
+	require.Len(t, claims.ID, 8)
+	require.True(t, claims.Allows("Calypso", "CreateLTS"))
+	require.False(t, claims.Allows("Calypso", "Authorize"))
+	require.False(t, claims.Allows("Skipchain", "StoreSkipBlock"))
+
+	_, err = Verify(tok, kp.Public, now.Add(2*time.Hour))
+	require.Error(t, err)
+	_, err = Verify(tok, key.NewKeyPair(cothority.Suite).Public, now)
+	require.Error(t, err)
+	_, err = Verify(tok[:len(tok)-2], kp.Public, now)
+	require.Error(t, err)
+
+	_, err = Issue(kp.Private, nil, now.Add(time.Hour))
+	require.Error(t, err)
+
+	// A signature of the claims for another purpose is not a token.
+	forged := token{Claims: *claims}
+	msg, err := protobuf.Encode(&forged.Claims)
+	require.NoError(t, err)
+	forged.Signature, err = schnorr.Sign(cothority.Suite, kp.Private, msg)
+	require.NoError(t, err)
+	buf, err := protobuf.Encode(&forged)
+	require.NoError(t, err)
+	_, err = Verify(base64.RawURLEncoding.EncodeToString(buf), kp.Public, now)
+	require.Error(t, err)
+}
+
+func TestAuthorize(t *testing.T) {
+	defer SetPolicy(nil)
+	kp := key.NewKeyPair(cothority.Suite)
+	tok, err := Issue(kp.Private, []string{"Skipchain"}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	req := &http.Request{Header: http.Header{}, URL: &url.URL{}}
+	require.NoError(t, Authorize(req, "Skipchain", "StoreSkipBlock"))
+
+	SetPolicy(&Policy{Key: kp.Public, Endpoints: DefaultEndpoints})
+	require.True(t, Required("Status", "Request"))
+	require.False(t, Required("Status", "CheckConnectivity"))
+	require.NoError(t, Authorize(req, "Skipchain", "GetUpdateChain"))
+	require.Error(t, Authorize(req, "Skipchain", "StoreSkipBlock"))
+
+	req.Header.Set("Authorization", "Bearer "+tok)
+	require.NoError(t, Authorize(req, "Skipchain", "/StoreSkipBlock"))
+	require.Error(t, Authorize(req, "Calypso", "CreateLTS"))
+
+	req = &http.Request{Header: http.Header{},
+		URL: &url.URL{RawQuery: "token=" + tok}}
+	require.NoError(t, Authorize(req, "Skipchain", "StoreSkipBlock"))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := &Policy{Endpoints: DefaultEndpoints}
+	require.NoError(t, p.Check([]string{"Calypso", "Skipchain", "Status"}))
+	require.Error(t, p.Check([]string{"Calypso", "Skipchain"}))
+
+	p.Endpoints = []string{"ByzCoin"}
+	require.NoError(t, p.Check([]string{"ByzCoin"}))
+	require.Error(t, p.Check([]string{"Byzcoin"}))
+}
@@ -0,0 +1,206 @@
+// Package atrest encrypts the data the conodes store in their databases, so
+// that a stolen disk or backup doesn't expose the chains, the states of
+// ByzCoin or the shares of the DKGs.
+//
+// The conode loads the Key at startup with LoadKey, from the environment, a
+// file, or a command asking a KMS for it, and sets it with SetKey. From then
+// on, the services seal the values they write with AES-256-GCM, bound to
+// their bucket and key so that they can't be moved, and open them when they
+// read them:
+//
+//   - the skipblocks of the skipchain service
+//   - the tries and the state changes of ByzCoin, with the event logs
+//   - the storage of the services, with the shares of the DKGs, and the
+//     states of the running DKGs
+//   - the receipts of the BLS CoSi service
+//
+// Once the key is set, the values written before it are refused, so that a
+// value can't be replaced by a plaintext one. An existing database is sealed
+// once with SetMigrate: the services then read the plaintext values and seal
+// them when they open the database. A sealed value can't be read without the
+// key.
+//
+// The private.toml file of the conode is sealed with SealConfig by
+// 'conode seal-config', and opened by the conode before onet parses it.
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// KeySize is the length of the keys.
+const KeySize = 32
+
+// magic starts every sealed value, to tell them from the plaintext values
+// written before the key was set.
+var magic = []byte("\x00atrest\x01")
+
+// ErrNoKey is returned when opening a sealed value without a key.
+var ErrNoKey = errors.New("the data is encrypted, but no key is set")
+
+// ErrNotSealed is returned when opening a plaintext value with a key, out of
+// the migration of the database.
+var ErrNotSealed = errors.New("the data is not encrypted: seal the database " +
+	"first")
+
+// Key seals and opens the values of the databases.
+type Key struct {
+	aead cipher.AEAD
+}
+
+// NewKey returns the key for the secret of KeySize bytes.
+func NewKey(secret []byte) (*Key, error) {
+	if len(secret) != KeySize {
+		return nil, fmt.Errorf("key of %d bytes instead of %d", len(secret),
+			KeySize)
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the AEAD: %v", err)
+	}
+	return &Key{aead: aead}, nil
+}
+
+// GenerateSecret returns a new random secret for NewKey.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, KeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("couldn't generate the secret: %v", err)
+	}
+	return secret, nil
+}
+
+// Seal encrypts the data, authenticating the location it is written to,
+// like the one returned by Location.
+func (k *Key) Seal(location, data []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("couldn't generate the nonce: %v", err)
+	}
+	out := make([]byte, 0, len(magic)+len(nonce)+len(data)+k.aead.Overhead())
+	out = append(append(out, magic...), nonce...)
+	return k.aead.Seal(out, nonce, data, location), nil
+}
+
+// Open decrypts the data sealed for the location. The data that is not
+// sealed is refused with ErrNotSealed.
+func (k *Key) Open(location, data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, ErrNotSealed
+	}
+	data = data[len(magic):]
+	if len(data) < k.aead.NonceSize() {
+		return nil, errors.New("sealed data is too short")
+	}
+	nonce, data := data[:k.aead.NonceSize()], data[k.aead.NonceSize():]
+	plain, err := k.aead.Open(nil, nonce, data, location)
+	if err != nil {
+		return nil, errors.New("couldn't open the sealed data: wrong key, " +
+			"or data moved or modified")
+	}
+	return plain, nil
+}
+
+// IsSealed returns true if the data was sealed by a key.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Location returns the location of a value in a bucket of a database, to
+// seal it with.
+func Location(bucket, key []byte) []byte {
+	loc := make([]byte, 0, len(bucket)+1+len(key))
+	return append(append(append(loc, bucket...), 0), key...)
+}
+
+// configLocation is the location the config files of the conodes are sealed
+// for, as they are not in a database.
+var configLocation = []byte("conode config")
+
+// SealConfig encrypts the config file of a conode, which holds its private
+// key.
+func (k *Key) SealConfig(data []byte) ([]byte, error) {
+	return k.Seal(configLocation, data)
+}
+
+// OpenConfig decrypts the config file of a conode sealed by SealConfig.
+func (k *Key) OpenConfig(data []byte) ([]byte, error) {
+	return k.Open(configLocation, data)
+}
+
+var keyMutex sync.RWMutex
+var current *Key
+var migrating bool
+
+// SetKey sets the key used by Seal and Open. A nil key leaves the values
+// written in plaintext, which is the default.
+func SetKey(k *Key) {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	current = k
+}
+
+// SetMigrate sets whether the plaintext values are still read while a key is
+// set, so that the services seal them. It is set once, to seal an existing
+// database, and the plaintext values are refused again without it.
+func SetMigrate(m bool) {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	migrating = m
+}
+
+// Migrating returns true if a key is set and the plaintext values are still
+// read to be sealed.
+func Migrating() bool {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+	return current != nil && migrating
+}
+
+// Enabled returns true if a key is set.
+func Enabled() bool {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+	return current != nil
+}
+
+// Seal encrypts the data for the location with the key set by SetKey, or
+// returns it as is if there is none.
+func Seal(location, data []byte) ([]byte, error) {
+	keyMutex.RLock()
+	k := current
+	keyMutex.RUnlock()
+	if k == nil {
+		return data, nil
+	}
+	return k.Seal(location, data)
+}
+
+// Open decrypts the data sealed for the location with the key set by
+// SetKey. The data that is not sealed is returned as is without a key or
+// while migrating, and refused otherwise.
+func Open(location, data []byte) ([]byte, error) {
+	keyMutex.RLock()
+	k, m := current, migrating
+	keyMutex.RUnlock()
+	if k == nil {
+		if IsSealed(data) {
+			return nil, ErrNoKey
+		}
+		return data, nil
+	}
+	if m && !IsSealed(data) {
+		return data, nil
+	}
+	return k.Open(location, data)
+}
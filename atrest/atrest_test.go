@@ -0,0 +1,135 @@
+package atrest
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) *Key {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	k, err := NewKey(secret)
+	require.NoError(t, err)
+	return k
+}
+
+func TestKey_SealOpen(t *testing.T) {
+	k := newTestKey(t)
+	loc := Location([]byte("bucket"), []byte("key"))
+	data := []byte("chain state")
+
+	sealed, err := k.Seal(loc, data)
+	require.NoError(t, err)
+	require.True(t, IsSealed(sealed))
+	require.NotContains(t, string(sealed), string(data))
+	plain, err := k.Open(loc, sealed)
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+
+	_, err = k.Open(Location([]byte("bucket"), []byte("other")), sealed)
+	require.Error(t, err, "the value was moved")
+	sealed[len(sealed)-1] ^= 1
+	_, err = k.Open(loc, sealed)
+	require.Error(t, err, "the value was modified")
+	_, err = newTestKey(t).Open(loc, sealed)
+	require.Error(t, err, "wrong key")
+
+	_, err = k.Open(loc, data)
+	require.Equal(t, ErrNotSealed, err, "plaintext is refused")
+
+	_, err = NewKey(make([]byte, KeySize-1))
+	require.Error(t, err)
+}
+
+func TestSealOpen(t *testing.T) {
+	defer SetKey(nil)
+	loc := []byte("location")
+	data := []byte("data")
+
+	buf, err := Seal(loc, data)
+	require.NoError(t, err)
+	require.Equal(t, data, buf, "no key, no encryption")
+
+	plain, err := Open(loc, data)
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+
+	SetKey(newTestKey(t))
+	require.True(t, Enabled())
+	sealed, err := Seal(loc, data)
+	require.NoError(t, err)
+	plain, err = Open(loc, sealed)
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+
+	// The plaintext is only read while migrating.
+	_, err = Open(loc, data)
+	require.Equal(t, ErrNotSealed, err)
+	SetMigrate(true)
+	defer SetMigrate(false)
+	require.True(t, Migrating())
+	plain, err = Open(loc, data)
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+	SetMigrate(false)
+
+	SetKey(nil)
+	_, err = Open(loc, sealed)
+	require.Equal(t, ErrNoKey, err)
+}
+
+func TestLoadKey(t *testing.T) {
+	for _, env := range []string{EnvKey, EnvKeyFile, EnvKeyCommand} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	k, err := LoadKey()
+	require.NoError(t, err)
+	require.Nil(t, k)
+
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	encoded := hex.EncodeToString(secret)
+
+	os.Setenv(EnvKey, encoded)
+	k, err = LoadKey()
+	require.NoError(t, err)
+	require.NotNil(t, k)
+
+	dir, err := ioutil.TempDir("", "atrest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(file, []byte(encoded+"\n"), 0600))
+	os.Setenv(EnvKeyFile, file)
+	_, err = LoadKey()
+	require.Error(t, err, "two sources")
+
+	os.Unsetenv(EnvKey)
+	k, err = LoadKey()
+	require.NoError(t, err)
+	require.NotNil(t, k)
+	require.NoError(t, ioutil.WriteFile(file, secret, 0600))
+	k, err = LoadKey()
+	require.NoError(t, err)
+	require.NotNil(t, k)
+
+	os.Unsetenv(EnvKeyFile)
+	os.Setenv(EnvKeyCommand, "echo "+encoded)
+	k, err = LoadKey()
+	require.NoError(t, err)
+	require.NotNil(t, k)
+	os.Setenv(EnvKeyCommand, "false")
+	_, err = LoadKey()
+	require.Error(t, err)
+
+	os.Unsetenv(EnvKeyCommand)
+	os.Setenv(EnvKey, "not hex")
+	_, err = LoadKey()
+	require.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package atrest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+)
+
+const (
+	// EnvKey holds the key in hexadecimal.
+	EnvKey = "COTHORITY_DB_KEY"
+	// EnvKeyFile is the file holding the key, in hexadecimal or as
+	// KeySize raw bytes.
+	EnvKeyFile = "COTHORITY_DB_KEY_FILE"
+	// EnvKeyCommand is a shell command printing the key in hexadecimal, for
+	// example asking a KMS to decrypt it.
+	EnvKeyCommand = "COTHORITY_DB_KEY_COMMAND"
+)
+
+// LoadKey returns the key given by the environment, with one of EnvKey,
+// EnvKeyFile or EnvKeyCommand, or nil if none is set.
+func LoadKey() (*Key, error) {
+	var set []string
+	for _, env := range []string{EnvKey, EnvKeyFile, EnvKeyCommand} {
+		if os.Getenv(env) != "" {
+			set = append(set, env)
+		}
+	}
+	switch len(set) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("only one of %v can be set", set)
+	}
+
+	var secret []byte
+	var err error
+	switch set[0] {
+	case EnvKey:
+		secret, err = ParseSecret([]byte(os.Getenv(EnvKey)))
+	case EnvKeyFile:
+		secret, err = readSecretFile(os.Getenv(EnvKeyFile))
+	case EnvKeyCommand:
+		secret, err = runSecretCommand(os.Getenv(EnvKeyCommand))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", set[0], err)
+	}
+	return NewKey(secret)
+}
+
+// ParseSecret decodes a secret written in hexadecimal, ignoring the spaces
+// and newlines around it.
+func ParseSecret(buf []byte) ([]byte, error) {
+	buf = bytes.TrimSpace(buf)
+	secret := make([]byte, hex.DecodedLen(len(buf)))
+	if _, err := hex.Decode(secret, buf); err != nil {
+		return nil, fmt.Errorf("invalid hexadecimal key: %v", err)
+	}
+	if len(secret) != KeySize {
+		return nil, fmt.Errorf("key of %d bytes instead of %d", len(secret),
+			KeySize)
+	}
+	return secret, nil
+}
+
+func readSecretFile(file string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the key: %v", err)
+	}
+	if len(buf) == KeySize {
+		return buf, nil
+	}
+	return ParseSecret(buf)
+}
+
+func runSecretCommand(command string) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the key: %v", err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("the command didn't print a key")
+	}
+	return ParseSecret(out)
+}
@@ -0,0 +1,205 @@
+package atrest
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3/network"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// sealBatch is how many values SealBucket seals in one transaction.
+const sealBatch = 1000
+
+func init() {
+	network.RegisterMessage(&Sealed{})
+}
+
+// Sealed is a message of a service, sealed before it is saved.
+type Sealed struct {
+	Data []byte
+}
+
+// Storage is where the services save their messages, like onet.Context.
+type Storage interface {
+	Save(key []byte, data interface{}) error
+	Load(key []byte) (interface{}, error)
+}
+
+// Save saves the message under the key of the storage of the service,
+// sealed if a key is set. The sealed message is bound to the service and the
+// key, like a value of a bucket.
+func Save(st Storage, service string, key []byte, msg interface{}) error {
+	if !Enabled() {
+		return st.Save(key, msg)
+	}
+	buf, err := network.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal %T: %v", msg, err)
+	}
+	sealed, err := Seal(Location([]byte(service), key), buf)
+	if err != nil {
+		return err
+	}
+	return st.Save(key, &Sealed{Data: sealed})
+}
+
+// Load returns the message saved under the key of the storage of the
+// service, or nil if there is none. With a key, a plaintext message is
+// refused, or sealed while migrating.
+func Load(st Storage, service string, key []byte) (interface{}, error) {
+	msg, err := st.Load(key)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+	sealed, ok := msg.(*Sealed)
+	if !ok {
+		if !Enabled() {
+			return msg, nil
+		}
+		if !Migrating() {
+			return nil, ErrNotSealed
+		}
+		if err := Save(st, service, key, msg); err != nil {
+			return nil, fmt.Errorf("couldn't seal %T: %v", msg, err)
+		}
+		return msg, nil
+	}
+	buf, err := Open(Location([]byte(service), key), sealed.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, msg, err = network.Unmarshal(buf, cothority.Suite)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal the sealed message: %v",
+			err)
+	}
+	return msg, nil
+}
+
+// Put seals the value for its location in the bucket, if a key is set, and
+// stores it.
+func Put(b *bbolt.Bucket, bucket, key, value []byte) error {
+	sealed, err := Seal(Location(bucket, key), value)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, sealed)
+}
+
+// Get returns the value of the key in the bucket, opened if it is sealed,
+// or nil if there is none.
+func Get(b *bbolt.Bucket, bucket, key []byte) ([]byte, error) {
+	value := b.Get(key)
+	if value == nil {
+		return nil, nil
+	}
+	return Open(Location(bucket, key), value)
+}
+
+// SealBucket seals the values of the bucket that were written before the
+// key was set, and returns how many there were. It does nothing without a
+// key, and fails on a plaintext value out of the migration.
+func SealBucket(db *bbolt.DB, bucket []byte) (int, error) {
+	return sealBucket(db, bucket, nil)
+}
+
+// SealNestedBuckets seals the values of the buckets nested in the bucket,
+// each for its location in its nested bucket, like SealBucket.
+func SealNestedBuckets(db *bbolt.DB, bucket []byte) (int, error) {
+	if !Enabled() {
+		return 0, nil
+	}
+	var nested [][]byte
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return errors.New("missing bucket")
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				nested = append(nested, append([]byte{}, k...))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	for _, name := range nested {
+		n, err := sealBucket(db, bucket, name)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// sealBucket seals the values of the bucket, or of the bucket nested in it
+// if nested is not nil.
+func sealBucket(db *bbolt.DB, bucket, nested []byte) (int, error) {
+	if !Enabled() {
+		return 0, nil
+	}
+	open := func(tx *bbolt.Tx) *bbolt.Bucket {
+		b := tx.Bucket(bucket)
+		if b != nil && nested != nil {
+			b = b.Bucket(nested)
+		}
+		return b
+	}
+	name := bucket
+	if nested != nil {
+		name = nested
+	}
+
+	var keys [][]byte
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := open(tx)
+		if b == nil {
+			return errors.New("missing bucket")
+		}
+		return b.ForEach(func(k, v []byte) error {
+			// The nested buckets have no value.
+			if v != nil && !IsSealed(v) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) > 0 && !Migrating() {
+		return 0, fmt.Errorf("%d values of %x: %v", len(keys), name,
+			ErrNotSealed)
+	}
+
+	for start := 0; start < len(keys); start += sealBatch {
+		end := start + sealBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		err := db.Update(func(tx *bbolt.Tx) error {
+			b := open(tx)
+			for _, k := range keys[start:end] {
+				v := b.Get(k)
+				if v == nil || IsSealed(v) {
+					continue
+				}
+				if err := Put(b, name, k, append([]byte{}, v...)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return start, fmt.Errorf("couldn't seal the bucket: %v", err)
+		}
+	}
+	return len(keys), nil
+}
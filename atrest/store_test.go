@@ -0,0 +1,145 @@
+package atrest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3/network"
+	bbolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	network.RegisterMessage(&testMessage{})
+}
+
+type testMessage struct {
+	Share []byte
+}
+
+// testStorage keeps the messages in memory, like onet.Context keeps them in
+// the database.
+type testStorage map[string]interface{}
+
+func (ts testStorage) Save(key []byte, data interface{}) error {
+	ts[string(key)] = data
+	return nil
+}
+
+func (ts testStorage) Load(key []byte) (interface{}, error) {
+	return ts[string(key)], nil
+}
+
+func TestSaveLoad(t *testing.T) {
+	defer SetKey(nil)
+	ts := testStorage{}
+	secret := &testMessage{Share: []byte("share")}
+
+	require.NoError(t, Save(ts, "service", []byte("plain"), secret))
+	require.Equal(t, secret, ts["plain"])
+
+	SetKey(newTestKey(t))
+	require.NoError(t, Save(ts, "service", []byte("sealed"), secret))
+	sealed := ts["sealed"].(*Sealed)
+	require.True(t, IsSealed(sealed.Data))
+
+	msg, err := Load(ts, "service", []byte("sealed"))
+	require.NoError(t, err)
+	require.Equal(t, secret, msg)
+	// The sealed message is bound to its service and key.
+	_, err = Load(ts, "other", []byte("sealed"))
+	require.Error(t, err)
+	ts["moved"] = ts["sealed"]
+	_, err = Load(ts, "service", []byte("moved"))
+	require.Error(t, err)
+
+	// The plaintext message is refused, or sealed while migrating.
+	_, err = Load(ts, "service", []byte("plain"))
+	require.Equal(t, ErrNotSealed, err)
+	SetMigrate(true)
+	defer SetMigrate(false)
+	msg, err = Load(ts, "service", []byte("plain"))
+	require.NoError(t, err)
+	require.Equal(t, secret, msg)
+	require.True(t, IsSealed(ts["plain"].(*Sealed).Data))
+	SetMigrate(false)
+	msg, err = Load(ts, "service", []byte("plain"))
+	require.NoError(t, err)
+	require.Equal(t, secret, msg)
+
+	msg, err = Load(ts, "service", []byte("missing"))
+	require.NoError(t, err)
+	require.Nil(t, msg)
+}
+
+func TestSealBucket(t *testing.T) {
+	defer SetKey(nil)
+	defer SetMigrate(false)
+	dir, err := ioutil.TempDir("", "atrest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := bbolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	bucket := []byte("bucket")
+	n := sealBatch + 10
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket(bucket)
+		if err != nil {
+			return err
+		}
+		nb, err := b.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		if err := Put(nb, []byte("nested"), []byte("key"),
+			[]byte("value")); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := Put(b, bucket, []byte{byte(i >> 8), byte(i)},
+				[]byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	sealed, err := SealBucket(db, bucket)
+	require.NoError(t, err)
+	require.Equal(t, 0, sealed, "no key")
+
+	SetKey(newTestKey(t))
+	_, err = SealBucket(db, bucket)
+	require.Error(t, err, "plaintext out of the migration")
+	SetMigrate(true)
+	sealed, err = SealBucket(db, bucket)
+	require.NoError(t, err)
+	require.Equal(t, n, sealed)
+	sealed, err = SealNestedBuckets(db, bucket)
+	require.NoError(t, err)
+	require.Equal(t, 1, sealed)
+	SetMigrate(false)
+	sealed, err = SealBucket(db, bucket)
+	require.NoError(t, err)
+	require.Equal(t, 0, sealed)
+
+	require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for i := 0; i < n; i++ {
+			k := []byte{byte(i >> 8), byte(i)}
+			require.True(t, IsSealed(b.Get(k)))
+			v, err := Get(b, bucket, k)
+			require.NoError(t, err)
+			require.Equal(t, []byte{byte(i)}, v)
+		}
+		v, err := Get(b.Bucket([]byte("nested")), []byte("nested"),
+			[]byte("key"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), v)
+		return nil
+	}))
+}
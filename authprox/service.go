@@ -8,8 +8,10 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/http"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/kyber/v3/sign/dss"
@@ -34,6 +36,13 @@ type service struct {
 	bucket     []byte
 }
 
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
 func init() {
 	var err error
 	authProxID, err = onet.RegisterNewService(ServiceName, newService)
@@ -0,0 +1,380 @@
+// Package backup takes periodic snapshots of the database of a conode, which
+// holds the storage of all its services, and uploads them to a Destination:
+// a directory, or a bucket of S3 or of a compatible store.
+//
+// A snapshot is taken in a single read transaction of the database, so that
+// the storage of the services is consistent, even while they keep writing.
+// Every snapshot comes with a Manifest giving its size and hash, signed by
+// the key of the conode, so that a restore can check that the snapshot is
+// complete and comes from the conode.
+//
+// The Backup service takes the snapshots when the environment gives a
+// destination:
+//
+//   - COTHORITY_BACKUP_DESTINATION is a directory, or an URL like
+//     s3://bucket/prefix?region=eu-west-1
+//   - COTHORITY_BACKUP_INTERVAL is the time between two snapshots, 24h by
+//     default
+//   - COTHORITY_BACKUP_KEEP is how many snapshots of the conode are kept, 7
+//     by default
+//
+// The `conode backup` commands list, verify and restore the snapshots.
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/encoding"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// signatureTag separates the signatures of the manifests from the other
+// signatures of the keys of the conodes.
+const signatureTag = "cothority backup"
+
+const (
+	snapshotSuffix = ".db"
+	manifestSuffix = ".json"
+	// timeFormat sorts the snapshots of a conode by time.
+	timeFormat = "20060102T150405.000Z"
+)
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	// Name of the snapshot, without the suffix.
+	Name string
+	// Node is the public key of the conode, in hexadecimal.
+	Node string
+	// Time of the snapshot, in seconds since the epoch.
+	Time int64
+	// Size of the snapshot, in bytes.
+	Size int64
+	// SHA256 of the snapshot, in hexadecimal.
+	SHA256 string
+	// Signature of the manifest by the conode.
+	Signature []byte
+}
+
+// message returns the hash signed by the conode.
+func (m *Manifest) message() []byte {
+	h := sha256.New()
+	h.Write([]byte(signatureTag))
+	for _, s := range []string{m.Name, m.Node, m.SHA256} {
+		binary.Write(h, binary.LittleEndian, uint64(len(s)))
+		h.Write([]byte(s))
+	}
+	binary.Write(h, binary.LittleEndian, m.Time)
+	binary.Write(h, binary.LittleEndian, m.Size)
+	return h.Sum(nil)
+}
+
+// sign signs the manifest with the private key of the conode.
+func (m *Manifest) sign(private kyber.Scalar) error {
+	var err error
+	m.Signature, err = schnorr.Sign(cothority.Suite, private, m.message())
+	if err != nil {
+		return fmt.Errorf("couldn't sign the manifest: %v", err)
+	}
+	return nil
+}
+
+// Public returns the public key of the conode that took the snapshot.
+func (m *Manifest) Public() (kyber.Point, error) {
+	p, err := encoding.StringHexToPoint(cothority.Suite, m.Node)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node key: %v", err)
+	}
+	return p, nil
+}
+
+// Verify checks that the manifest is signed by one of the keys, like the ones
+// returned by keyrotation.Keys for the key of the conode.
+func (m *Manifest) Verify(keys []kyber.Point) error {
+	p, err := m.Public()
+	if err != nil {
+		return err
+	}
+	if !hasKey(keys, p) {
+		return fmt.Errorf("snapshot of %s, not of this conode", m.Node)
+	}
+	if err := schnorr.Verify(cothority.Suite, p, m.message(),
+		m.Signature); err != nil {
+		return errors.New("invalid signature of the manifest")
+	}
+	return nil
+}
+
+func hasKey(keys []kyber.Point, key kyber.Point) bool {
+	for _, k := range keys {
+		if k.Equal(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashingWriter counts and hashes what is written.
+type hashingWriter struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func newHashingWriter(w io.Writer) *hashingWriter {
+	return &hashingWriter{w: w, hash: sha256.New()}
+}
+
+func (hw *hashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.hash.Write(p[:n])
+	hw.size += int64(n)
+	return n, err
+}
+
+// Snapshot writes a consistent copy of the database and returns its
+// manifest, signed by the private key.
+func Snapshot(db *bbolt.DB, w io.Writer, private kyber.Scalar,
+	now time.Time) (*Manifest, error) {
+	public := cothority.Suite.Point().Mul(private, nil)
+	node, err := encoding.PointToStringHex(cothority.Suite, public)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode the node key: %v", err)
+	}
+
+	hw := newHashingWriter(w)
+	err = db.View(func(tx *bbolt.Tx) error {
+		_, err := tx.WriteTo(hw)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't copy the database: %w", err)
+	}
+
+	m := &Manifest{
+		Name:   snapshotName(node, now),
+		Node:   node,
+		Time:   now.Unix(),
+		Size:   hw.size,
+		SHA256: hex.EncodeToString(hw.hash.Sum(nil)),
+	}
+	return m, m.sign(private)
+}
+
+// snapshotName returns the name of the snapshot of the node at the time.
+// The snapshots of a node share the prefix of snapshotPrefix.
+func snapshotName(node string, t time.Time) string {
+	return snapshotPrefix(node) + t.UTC().Format(timeFormat)
+}
+
+func snapshotPrefix(node string) string {
+	if len(node) > 16 {
+		node = node[:16]
+	}
+	return "conode-" + node + "-"
+}
+
+// Upload takes a snapshot of the database and uploads it with its manifest
+// to the destination. The snapshot is written to a temporary file first, as
+// its size must be known to upload it.
+func Upload(db *bbolt.DB, dst Destination, private kyber.Scalar,
+	now time.Time) (*Manifest, error) {
+	tmp, err := ioutil.TempFile("", "conode-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	m, err := Snapshot(db, tmp, private, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("couldn't rewind the snapshot: %v", err)
+	}
+	if err := dst.Put(m.Name+snapshotSuffix, tmp, m.Size); err != nil {
+		return nil, fmt.Errorf("couldn't upload the snapshot: %v", err)
+	}
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode the manifest: %v", err)
+	}
+	// The manifest is uploaded last, so that a snapshot with a manifest is
+	// complete.
+	err = dst.Put(m.Name+manifestSuffix, bytes.NewReader(buf),
+		int64(len(buf)))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't upload the manifest: %v", err)
+	}
+	return m, nil
+}
+
+// List returns the manifests of the snapshots in the destination, the
+// oldest first. If keys is not empty, only the snapshots of the conode with
+// one of these keys are returned, so that the snapshots taken before a
+// rotation of its key are kept.
+func List(dst Destination, keys []kyber.Point) ([]*Manifest, error) {
+	names, err := dst.List()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list the snapshots: %v", err)
+	}
+	var prefixes []string
+	for _, k := range keys {
+		node, err := encoding.PointToStringHex(cothority.Suite, k)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't encode the node key: %v", err)
+		}
+		prefixes = append(prefixes, snapshotPrefix(node))
+	}
+
+	var list []*Manifest
+	for _, name := range names {
+		if !hasPrefix(name, prefixes) ||
+			!strings.HasSuffix(name, manifestSuffix) {
+			continue
+		}
+		m, err := GetManifest(dst, strings.TrimSuffix(name, manifestSuffix))
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			if p, err := m.Public(); err != nil || !hasKey(keys, p) {
+				continue
+			}
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Time != list[j].Time {
+			return list[i].Time < list[j].Time
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// hasPrefix returns true if the name starts with one of the prefixes, or if
+// there are none.
+func hasPrefix(name string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetManifest returns the manifest of the snapshot.
+func GetManifest(dst Destination, name string) (*Manifest, error) {
+	r, err := dst.Get(name + manifestSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the manifest of %s: %v", name,
+			err)
+	}
+	defer r.Close()
+	m := &Manifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid manifest of %s: %v", name, err)
+	}
+	if m.Name != name {
+		return nil, fmt.Errorf("manifest of %s is for %s", name, m.Name)
+	}
+	return m, nil
+}
+
+// Prune deletes the oldest snapshots of the conode with one of the keys, so
+// that only keep of them are left.
+func Prune(dst Destination, keys []kyber.Point, keep int) error {
+	list, err := List(dst, keys)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(list)-keep; i++ {
+		// The manifest goes first, so that a snapshot without a manifest is
+		// never listed.
+		for _, suffix := range []string{manifestSuffix, snapshotSuffix} {
+			if err := dst.Delete(list[i].Name + suffix); err != nil {
+				return fmt.Errorf("couldn't delete %s: %v", list[i].Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Download writes the snapshot to w and returns an error if it doesn't
+// match its manifest, or if the manifest is not signed by one of the keys.
+func Download(dst Destination, m *Manifest, keys []kyber.Point,
+	w io.Writer) error {
+	if err := m.Verify(keys); err != nil {
+		return err
+	}
+	r, err := dst.Get(m.Name + snapshotSuffix)
+	if err != nil {
+		return fmt.Errorf("couldn't get the snapshot: %v", err)
+	}
+	defer r.Close()
+
+	hw := newHashingWriter(w)
+	if _, err := io.Copy(hw, r); err != nil {
+		return fmt.Errorf("couldn't download the snapshot: %v", err)
+	}
+	if hw.size != m.Size {
+		return fmt.Errorf("snapshot of %d bytes instead of %d", hw.size,
+			m.Size)
+	}
+	if sum := hex.EncodeToString(hw.hash.Sum(nil)); sum != m.SHA256 {
+		return fmt.Errorf("snapshot has the hash %s instead of %s", sum,
+			m.SHA256)
+	}
+	return nil
+}
+
+// Restore downloads the snapshot to the file of the database, once it is
+// verified. The conode must be stopped, and the file must not exist unless
+// force is true.
+func Restore(dst Destination, m *Manifest, keys []kyber.Point, file string,
+	force bool) error {
+	if _, err := os.Stat(file); err == nil && !force {
+		return fmt.Errorf("%s already exists", file)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".restore-")
+	if err != nil {
+		return fmt.Errorf("couldn't create the temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := Download(dst, m, keys, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("couldn't write the snapshot: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("couldn't set the mode of the database: %v", err)
+	}
+	// The database is replaced only once the snapshot is verified.
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("couldn't replace the database: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// testDB returns a database with a value in a bucket, in a temporary
+// directory to remove.
+func testDB(t *testing.T) (*bbolt.DB, string) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	db, err := bbolt.Open(filepath.Join(dir, "conode.db"), 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("key"), []byte("value"))
+	}))
+	return db, dir
+}
+
+func TestSnapshot(t *testing.T) {
+	db, dir := testDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+	kp := key.NewKeyPair(cothority.Suite)
+
+	var buf bytes.Buffer
+	now := time.Unix(1500000000, 0)
+	m, err := Snapshot(db, &buf, kp.Private, now)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), m.Size)
+	require.Equal(t, now.Unix(), m.Time)
+	require.True(t, strings.HasSuffix(m.Name, "-20170714T024000.000Z"))
+	require.NoError(t, m.Verify([]kyber.Point{kp.Public}))
+
+	other := key.NewKeyPair(cothority.Suite)
+	require.Error(t, m.Verify([]kyber.Point{other.Public}))
+	m.Size++
+	require.Error(t, m.Verify([]kyber.Point{kp.Public}))
+}
+
+func TestUploadRestore(t *testing.T) {
+	db, dir := testDB(t)
+	defer os.RemoveAll(dir)
+	dst, err := NewDir(filepath.Join(dir, "backups"))
+	require.NoError(t, err)
+	kp := key.NewKeyPair(cothority.Suite)
+	other := key.NewKeyPair(cothority.Suite)
+
+	start := time.Unix(1500000000, 0)
+	for i := 0; i < 3; i++ {
+		_, err := Upload(db, dst, kp.Private,
+			start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+	_, err = Upload(db, dst, other.Private, start)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	list, err := List(dst, []kyber.Point{kp.Public})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(list))
+	require.True(t, list[0].Time < list[2].Time)
+	all, err := List(dst, nil)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(all))
+
+	require.NoError(t, Prune(dst, []kyber.Point{kp.Public}, 2))
+	list, err = List(dst, []kyber.Point{kp.Public})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	require.Equal(t, start.Add(time.Hour).Unix(), list[0].Time)
+	names, err := dst.List()
+	require.NoError(t, err)
+	require.Equal(t, 6, len(names))
+
+	// The restored database must hold the value.
+	m := list[1]
+	file := filepath.Join(dir, "conode.db")
+	require.Error(t, Restore(dst, m, []kyber.Point{kp.Public}, file, false))
+	require.Error(t, Restore(dst, m, []kyber.Point{other.Public}, file, true))
+	require.NoError(t, Restore(dst, m, []kyber.Point{kp.Public}, file, true))
+	db, err = bbolt.Open(file, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+		require.Equal(t, []byte("value"),
+			tx.Bucket([]byte("bucket")).Get([]byte("key")))
+		return nil
+	}))
+	require.NoError(t, db.Close())
+
+	// A corrupted snapshot must not be restored.
+	snapshot := filepath.Join(dst.path, m.Name+snapshotSuffix)
+	buf, err := ioutil.ReadFile(snapshot)
+	require.NoError(t, err)
+	buf[len(buf)/2] ^= 1
+	require.NoError(t, ioutil.WriteFile(snapshot, buf, 0600))
+	err = Restore(dst, m, []kyber.Point{kp.Public}, file, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "hash")
+	require.NoError(t, Download(dst, list[0], []kyber.Point{kp.Public},
+		ioutil.Discard))
+
+	// After a rotation, the snapshots of the old key are still listed and
+	// restored.
+	rotated := key.NewKeyPair(cothority.Suite)
+	keys := []kyber.Point{rotated.Public, kp.Public}
+	list, err = List(dst, keys)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	require.NoError(t, Download(dst, list[0], keys, ioutil.Discard))
+	require.Error(t, Download(dst, list[0], []kyber.Point{rotated.Public},
+		ioutil.Discard))
+}
+
+func TestDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dst, err := ParseDestination("file://" + dir)
+	require.NoError(t, err)
+
+	require.NoError(t, dst.Put("a.db", strings.NewReader("data"), 4))
+	require.Error(t, dst.Put("b.db", strings.NewReader("data"), 5))
+	require.Error(t, dst.Put("../c.db", strings.NewReader("data"), 4))
+	names, err := dst.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.db"}, names)
+	require.NoError(t, dst.Delete("a.db"))
+	_, err = dst.Get("a.db")
+	require.Error(t, err)
+
+	_, err = ParseDestination("ftp://host/dir")
+	require.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Destination stores the snapshots and their manifests by name.
+type Destination interface {
+	// Put stores the size bytes of r under the name.
+	Put(name string, r io.Reader, size int64) error
+	// Get returns the content stored under the name.
+	Get(name string) (io.ReadCloser, error)
+	// List returns the names of everything stored.
+	List() ([]string, error)
+	// Delete removes what is stored under the name.
+	Delete(name string) error
+}
+
+// ParseDestination returns the destination of the string, which is either a
+// directory, a file:// URL, or an URL like
+// s3://bucket/prefix?region=eu-west-1&endpoint=https://minio:9000. The
+// credentials of S3 are taken from the environment, as for the AWS tools.
+func ParseDestination(s string) (Destination, error) {
+	if !strings.Contains(s, "://") {
+		return NewDir(s)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %v", err)
+	}
+	switch u.Scheme {
+	case "file":
+		return NewDir(u.Path)
+	case "s3":
+		return newS3FromURL(u)
+	default:
+		return nil, fmt.Errorf("unknown destination scheme %q", u.Scheme)
+	}
+}
+
+// Dir is a Destination storing the snapshots in a directory.
+type Dir struct {
+	path string
+}
+
+// NewDir returns a Destination in the directory, which is created if
+// needed.
+func NewDir(path string) (*Dir, error) {
+	if path == "" {
+		return nil, errors.New("empty directory")
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("couldn't create the directory: %v", err)
+	}
+	return &Dir{path: path}, nil
+}
+
+// file returns the file of the name, refusing the names that would escape
+// the directory.
+func (d *Dir) file(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name,
+		".") {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+	return filepath.Join(d.path, name), nil
+}
+
+// Put implements Destination. The file is written under a temporary name
+// first, so that a failed upload never leaves a partial snapshot.
+func (d *Dir) Put(name string, r io.Reader, size int64) error {
+	file, err := d.file(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(d.path, ".upload-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if n != size {
+		tmp.Close()
+		return fmt.Errorf("wrote %d bytes instead of %d", n, size)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// Get implements Destination.
+func (d *Dir) Get(name string) (io.ReadCloser, error) {
+	file, err := d.file(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(file)
+}
+
+// List implements Destination.
+func (d *Dir) List() ([]string, error) {
+	infos, err := ioutil.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete implements Destination.
+func (d *Dir) Delete(name string) error {
+	file, err := d.file(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(file)
+}
@@ -0,0 +1,324 @@
+package backup
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is the hash of the bodies of the uploads, which are not
+// signed as they are streamed. The connection to S3 protects them.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// emptyPayload is the hash of an empty body.
+const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3 is a Destination storing the snapshots in a bucket of S3, or of a
+// compatible store, with the requests signed by the Signature Version 4 of
+// AWS. A snapshot must be smaller than 5 GB, the limit of a single upload.
+type S3 struct {
+	// Endpoint of the store, https://s3.<region>.amazonaws.com by default.
+	Endpoint string
+	Bucket   string
+	// Prefix of the names of the objects, like a directory.
+	Prefix string
+	Region string
+	// AccessKey, SecretKey and SessionToken are the credentials, the session
+	// token being optional.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Client       *http.Client
+
+	now func() time.Time
+}
+
+// newS3FromURL returns the S3 destination of an s3:// URL, with the
+// credentials of the environment.
+func newS3FromURL(u *url.URL) (*S3, error) {
+	s := &S3{
+		Bucket:       u.Host,
+		Prefix:       strings.Trim(u.Path, "/"),
+		Region:       u.Query().Get("region"),
+		Endpoint:     u.Query().Get("endpoint"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.Region == "" {
+		s.Region = os.Getenv("AWS_REGION")
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	if s.Bucket == "" {
+		return nil, errors.New("missing the bucket")
+	}
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return nil, errors.New("missing AWS_ACCESS_KEY_ID or " +
+			"AWS_SECRET_ACCESS_KEY")
+	}
+	return s, nil
+}
+
+// key returns the key of the object of the name.
+func (s *S3) key(name string) string {
+	if s.Prefix == "" {
+		return name
+	}
+	return s.Prefix + "/" + name
+}
+
+// Put implements Destination.
+func (s *S3) Put(name string, r io.Reader, size int64) error {
+	req, err := s.request(http.MethodPut, s.key(name), nil, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Get implements Destination.
+func (s *S3) Get(name string) (io.ReadCloser, error) {
+	req, err := s.request(http.MethodGet, s.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete implements Destination.
+func (s *S3) Delete(name string) error {
+	req, err := s.request(http.MethodDelete, s.key(name), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// listBucketResult is the answer of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List implements Destination, returning only the objects directly under the
+// prefix.
+func (s *S3) List() ([]string, error) {
+	prefix := s.key("")
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.request(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, emptyPayload)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid list of the bucket: %v", err)
+		}
+		for _, c := range result.Contents {
+			name := strings.TrimPrefix(c.Key, prefix)
+			if name != "" && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// request returns the request of the key in the bucket, addressed by path
+// so that the stores other than AWS work too.
+func (s *S3) request(method, key string, query url.Values,
+	body io.Reader) (*http.Request, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %v", err)
+	}
+	path, rawPath := u.Path+"/"+s.Bucket, u.EscapedPath()+"/"+
+		uriEncode(s.Bucket, true)
+	if key != "" {
+		path, rawPath = path+"/"+key, rawPath+"/"+uriEncode(key, false)
+	}
+	u.Path, u.RawPath = path, rawPath
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the request: %v", err)
+	}
+	return req, nil
+}
+
+// do signs and sends the request, and returns an error if S3 doesn't answer
+// with a success.
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response,
+	error) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, payloadHash, now())
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path,
+			resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// sign adds the headers of the Signature Version 4 of AWS to the request,
+// signing all its headers.
+func (s *S3) sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" +
+			strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+
+		s.AccessKey+"/"+scope+", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
+
+// canonicalQuery returns the query with the keys sorted and the keys and
+// values encoded as AWS expects.
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for k, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes everything but the unreserved characters, and the
+// slashes unless encodeSlash is true.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/cothority/v3/keyrotation"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// ServiceName is the name of the service taking the snapshots.
+const ServiceName = "Backup"
+
+const (
+	// destinationEnv holds the destination of the snapshots. No snapshot is
+	// taken if it is empty.
+	destinationEnv = "COTHORITY_BACKUP_DESTINATION"
+	// intervalEnv holds the time between two snapshots, like "6h".
+	intervalEnv = "COTHORITY_BACKUP_INTERVAL"
+	// keepEnv holds how many snapshots of the conode are kept.
+	keepEnv = "COTHORITY_BACKUP_KEEP"
+)
+
+const (
+	defaultInterval = 24 * time.Hour
+	defaultKeep     = 7
+)
+
+// bucketName is only used to get the database of the conode.
+var bucketName = []byte("backup")
+
+func init() {
+	_, err := onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+}
+
+// Service takes the snapshots of the database of the conode.
+type Service struct {
+	*onet.ServiceProcessor
+	db *bbolt.DB
+
+	// backupMutex makes sure only one snapshot is taken at a time.
+	backupMutex sync.Mutex
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// Schedule is when the snapshots are taken and how many are kept.
+type Schedule struct {
+	Destination Destination
+	Interval    time.Duration
+	Keep        int
+}
+
+// scheduleFromEnv returns the schedule set in the environment, or nil if no
+// destination is set.
+func scheduleFromEnv() (*Schedule, error) {
+	env := os.Getenv(destinationEnv)
+	if env == "" {
+		return nil, nil
+	}
+	dst, err := ParseDestination(env)
+	if err != nil {
+		return nil, errors.New("invalid " + destinationEnv + ": " +
+			err.Error())
+	}
+	sch := &Schedule{Destination: dst, Interval: defaultInterval,
+		Keep: defaultKeep}
+	if env := os.Getenv(intervalEnv); env != "" {
+		sch.Interval, err = time.ParseDuration(env)
+		if err != nil || sch.Interval <= 0 {
+			return nil, errors.New("invalid " + intervalEnv + ": " + env)
+		}
+	}
+	if env := os.Getenv(keepEnv); env != "" {
+		sch.Keep, err = strconv.Atoi(env)
+		if err != nil || sch.Keep <= 0 {
+			return nil, errors.New("invalid " + keepEnv + ": " + env)
+		}
+	}
+	return sch, nil
+}
+
+// Backup takes a snapshot of the database, uploads it to the destination,
+// and deletes the oldest snapshots of the conode so that keep of them are
+// left. Nothing is deleted if keep is 0.
+func (s *Service) Backup(dst Destination, keep int) (*Manifest, error) {
+	s.backupMutex.Lock()
+	defer s.backupMutex.Unlock()
+
+	m, err := Upload(s.db, dst, s.ServerIdentity().GetPrivate(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if keep > 0 {
+		if err := Prune(dst, s.keys(), keep); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// keys returns the key of the conode, and the keys it replaced that are still
+// in their grace window, whose snapshots are still the ones of the conode.
+func (s *Service) keys() []kyber.Point {
+	return keyrotation.Keys(s.ServerIdentity().Public, time.Now())
+}
+
+// run takes the snapshots of the schedule, starting one interval after the
+// latest snapshot in the destination, until the database is closed.
+func (s *Service) run(sch *Schedule) {
+	next := time.Now()
+	list, err := List(sch.Destination, s.keys())
+	if err != nil {
+		log.Error(s.ServerIdentity(), "couldn't list the snapshots:", err)
+	} else if len(list) > 0 {
+		next = time.Unix(list[len(list)-1].Time, 0).Add(sch.Interval)
+	}
+
+	for {
+		time.Sleep(time.Until(next))
+		m, err := s.Backup(sch.Destination, sch.Keep)
+		if errors.Is(err, bbolt.ErrDatabaseNotOpen) {
+			// The conode stopped, and there is no hook to stop before.
+			return
+		}
+		if err != nil {
+			log.Error(s.ServerIdentity(), "couldn't back up:", err)
+		} else {
+			log.Lvlf2("%s: stored the snapshot %s of %d bytes",
+				s.ServerIdentity(), m.Name, m.Size)
+		}
+		next = time.Now().Add(sch.Interval)
+	}
+}
+
+// database returns the database of the conode.
+func database(c *onet.Context) (db *bbolt.DB, err error) {
+	// onet panics if it can't create the bucket.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("couldn't get the database: %v", r)
+		}
+	}()
+	db, _ = c.GetAdditionalBucket(bucketName)
+	if db == nil {
+		return nil, errors.New("database is not open")
+	}
+	return db, nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{ServiceProcessor: onet.NewServiceProcessor(c)}
+	db, err := database(c)
+	if err != nil {
+		return nil, err
+	}
+	s.db = db
+
+	sch, err := scheduleFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if sch != nil {
+		go s.run(sch)
+	}
+	return s, nil
+}
@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_Backup(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	servers, _, _ := local.GenTree(2, false)
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dst, err := NewDir(dir)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		for _, server := range servers {
+			s := server.Service(ServiceName).(*Service)
+			_, err := s.Backup(dst, 2)
+			require.NoError(t, err)
+		}
+	}
+	for _, server := range servers {
+		list, err := List(dst,
+			[]kyber.Point{server.ServerIdentity.Public})
+		require.NoError(t, err)
+		require.Equal(t, 2, len(list))
+		require.NoError(t, Download(dst, list[1],
+			[]kyber.Point{server.ServerIdentity.Public}, ioutil.Discard))
+	}
+}
+
+func TestScheduleFromEnv(t *testing.T) {
+	for _, env := range []string{destinationEnv, intervalEnv, keepEnv} {
+		defer os.Setenv(env, os.Getenv(env))
+	}
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv(destinationEnv, "")
+	sch, err := scheduleFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, sch)
+
+	os.Setenv(destinationEnv, dir)
+	os.Setenv(intervalEnv, "")
+	os.Setenv(keepEnv, "")
+	sch, err = scheduleFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, defaultInterval, sch.Interval)
+	require.Equal(t, defaultKeep, sch.Keep)
+
+	os.Setenv(intervalEnv, "1h")
+	os.Setenv(keepEnv, "3")
+	sch, err = scheduleFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 3, sch.Keep)
+
+	os.Setenv(keepEnv, "-1")
+	_, err = scheduleFromEnv()
+	require.Error(t, err)
+}
@@ -2,9 +2,11 @@ package bevm
 
 import (
 	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"reflect"
 	"strings"
@@ -16,8 +18,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -54,7 +58,7 @@ func NewEvmContract(name string, abiJSON string, binData string) (
 			"contract ABI: %v", err)
 	}
 
-	contractBytecode := common.Hex2Bytes(binData)
+	contractBytecode := common.FromHex(strings.TrimSpace(binData))
 
 	return &EvmContract{
 		name:     name,
@@ -63,6 +67,44 @@ func NewEvmContract(name string, abiJSON string, binData string) (
 	}, nil
 }
 
+// LoadEvmContract creates a new EvmContract from the files containing its ABI
+// and its bytecode, as produced by the Solidity compiler.
+func LoadEvmContract(name string, abiFilepath string, binFilepath string) (
+	*EvmContract, error) {
+	abiData, err := ioutil.ReadFile(abiFilepath)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read contract ABI: %v", err)
+	}
+
+	binData, err := ioutil.ReadFile(binFilepath)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read contract bytecode: %v", err)
+	}
+
+	return NewEvmContract(name, string(abiData), string(binData))
+}
+
+// At returns the instance of the contract deployed at the given address.
+func (contract *EvmContract) At(address common.Address) *EvmContractInstance {
+	return &EvmContractInstance{
+		Parent:  contract,
+		Address: address,
+	}
+}
+
+// DecodeConstructorArgs decodes the JSON-encoded arguments of the contract
+// constructor into the Go values expected by Deploy().
+func (contract EvmContract) DecodeConstructorArgs(jsonArgs []string) (
+	[]interface{}, error) {
+	inputs := contract.Abi.Constructor.Inputs
+	if len(jsonArgs) != len(inputs) {
+		return nil, xerrors.Errorf("wrong number of arguments for contract "+
+			"constructor: expected %d, got %d", len(inputs), len(jsonArgs))
+	}
+
+	return DecodeEvmArgs(jsonArgs, inputs)
+}
+
 func (contract EvmContract) String() string {
 	return fmt.Sprintf("EvmContract[%s]", contract.name)
 }
@@ -77,6 +119,25 @@ func (contractInstance EvmContractInstance) String() string {
 		contractInstance.Parent.name, contractInstance.Address.Hex())
 }
 
+// DecodeMethodArgs decodes the JSON-encoded arguments of a contract method
+// into the Go values expected by Transaction() and Call().
+func (contractInstance EvmContractInstance) DecodeMethodArgs(method string,
+	jsonArgs []string) ([]interface{}, error) {
+	methodAbi, ok := contractInstance.getAbi().Methods[method]
+	if !ok {
+		return nil, xerrors.Errorf("method \"%s\" does not exist for "+
+			"this contract", method)
+	}
+
+	if len(jsonArgs) != len(methodAbi.Inputs) {
+		return nil, xerrors.Errorf("wrong number of arguments for \"%s\": "+
+			"expected %d, got %d", method, len(methodAbi.Inputs),
+			len(jsonArgs))
+	}
+
+	return DecodeEvmArgs(jsonArgs, methodAbi.Inputs)
+}
+
 func (contractInstance EvmContractInstance) packMethod(method string,
 	args ...interface{}) ([]byte, error) {
 	return contractInstance.Parent.Abi.Pack(method, args...)
@@ -139,6 +200,7 @@ type Client struct {
 	bcClient   *byzcoin.Client
 	signer     darc.Signer
 	instanceID byzcoin.InstanceID
+	coinID     *byzcoin.InstanceID // Coin account paying for the gas
 }
 
 // NewBEvm creates a new ByzCoin EVM instance
@@ -173,6 +235,41 @@ func NewClient(bcClient *byzcoin.Client, signer darc.Signer,
 	}, nil
 }
 
+// SetCoinAccount sets the coin instance paying for the gas of the
+// transactions, when the BEvm instance has a gas price. The signer of the
+// client must be allowed to fetch and store coins on it.
+func (client *Client) SetCoinAccount(coinID byzcoin.InstanceID) {
+	client.coinID = &coinID
+}
+
+// GetGasConfig returns the gas configuration of the BEvm instance
+func (client *Client) GetGasConfig() (*GasConfig, error) {
+	bs, err := getState(client.bcClient, client.instanceID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve BEvm state: %v", err)
+	}
+
+	return &bs.GasConfig, nil
+}
+
+// SetGasConfig replaces the gas configuration of the BEvm instance
+func (client *Client) SetGasConfig(config GasConfig) error {
+	configBuf, err := protobuf.Encode(&config)
+	if err != nil {
+		return xerrors.Errorf("failed to encode gas configuration: %v", err)
+	}
+
+	_, err = client.invoke("gasConfig", byzcoin.Arguments{
+		{Name: "gasConfig", Value: configBuf},
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to invoke ByzCoin transaction for "+
+			"gas configuration: %v", err)
+	}
+
+	return nil
+}
+
 // Delete deletes the ByzCoin EVM client and all its state
 func (client *Client) Delete() error {
 	_, err := client.deleteBEvm(&byzcoin.Delete{
@@ -208,18 +305,14 @@ func (client *Client) Deploy(gasLimit uint64, gasPrice uint64, amount uint64,
 			"for EVM contract deployment: %v", err)
 	}
 
-	bcTx, err := client.invoke("transaction", byzcoin.Arguments{
-		{Name: "tx", Value: signedTxBuffer},
-	})
+	bcTx, err := client.invokeTransaction(gasLimit, signedTxBuffer)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("failed to invoke ByzCoin transaction "+
 			"for EVM contract deployment: %v", err)
 	}
 
-	contractInstance := &EvmContractInstance{
-		Parent:  contract,
-		Address: crypto.CreateAddress(account.Address, account.Nonce),
-	}
+	contractInstance := contract.At(
+		crypto.CreateAddress(account.Address, account.Nonce))
 
 	account.Nonce++
 
@@ -249,9 +342,7 @@ func (client *Client) Transaction(gasLimit uint64, gasPrice uint64,
 			"EVM method execution: %v", err)
 	}
 
-	bcTx, err := client.invoke("transaction", byzcoin.Arguments{
-		{Name: "tx", Value: signedTxBuffer},
-	})
+	bcTx, err := client.invokeTransaction(gasLimit, signedTxBuffer)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to invoke ByzCoin transaction for "+
 			"EVM method execution: %v", err)
@@ -262,6 +353,90 @@ func (client *Client) Transaction(gasLimit uint64, gasPrice uint64,
 	return bcTx, nil
 }
 
+// EstimateGas returns the lowest gas limit with which a transaction
+// (contract method call with state change) would succeed on the current EVM
+// state, or the reason why it fails.
+func (client *Client) EstimateGas(amount uint64, account *EvmAccount,
+	contractInstance *EvmContractInstance, method string,
+	args ...interface{}) (*EstimateGasResponse, error) {
+	log.Lvlf2(">>> Estimate gas of EVM method '%s()' on %s", method,
+		contractInstance)
+	defer log.Lvlf2("<<< Estimate gas of EVM method '%s()' on %s", method,
+		contractInstance)
+
+	callData, err := contractInstance.packMethod(method, args...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to pack arguments for contract "+
+			"method '%s': %v", method, err)
+	}
+
+	return client.estimateGas(amount, account, contractInstance.Address[:],
+		callData)
+}
+
+// EstimateDeployGas returns the lowest gas limit with which the deployment of
+// a contract would succeed on the current EVM state, or the reason why it
+// fails.
+func (client *Client) EstimateDeployGas(amount uint64, account *EvmAccount,
+	contract *EvmContract, args ...interface{}) (*EstimateGasResponse, error) {
+	log.Lvlf2(">>> Estimate gas of EVM contract '%s' deployment", contract)
+	defer log.Lvlf2("<<< Estimate gas of EVM contract '%s' deployment",
+		contract)
+
+	packedArgs, err := contract.packConstructor(args...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to pack arguments for "+
+			"contract constructor: %v", err)
+	}
+
+	return client.estimateGas(amount, account, nil,
+		append(contract.Bytecode, packedArgs...))
+}
+
+// TraceTransaction executes a transaction (contract method call with state
+// change) on the current EVM state without applying it, and returns the trace
+// of its calls, and of its opcodes if asked for. The account nonce is left
+// unchanged.
+func (client *Client) TraceTransaction(opcodes bool, gasLimit uint64,
+	gasPrice uint64, amount uint64, account *EvmAccount,
+	contractInstance *EvmContractInstance, method string,
+	args ...interface{}) (*TraceTransactionResponse, error) {
+	log.Lvlf2(">>> Trace EVM method '%s()' on %s", method, contractInstance)
+	defer log.Lvlf2("<<< Trace EVM method '%s()' on %s", method,
+		contractInstance)
+
+	callData, err := contractInstance.packMethod(method, args...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to pack arguments for contract "+
+			"method '%s': %v", method, err)
+	}
+
+	tx := types.NewTransaction(account.Nonce, contractInstance.Address,
+		big.NewInt(int64(amount)), gasLimit, big.NewInt(int64(gasPrice)),
+		callData)
+	signedTxBuffer, err := account.signAndMarshalTx(tx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to prepare EVM transaction for "+
+			"EVM method trace: %v", err)
+	}
+
+	request := &TraceTransactionRequest{
+		ByzCoinID:      client.bcClient.ID,
+		BEvmInstanceID: client.instanceID[:],
+		Transaction:    signedTxBuffer,
+		Opcodes:        opcodes,
+	}
+	response := &TraceTransactionResponse{}
+
+	err = client.Client.SendProtobuf(client.bcClient.Roster.List[0],
+		request, response)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to trace EVM transaction: %v", err)
+	}
+
+	return response, nil
+}
+
 // Call performs a new call (contract view method call, without state change)
 // on the EVM
 func (client *Client) Call(account *EvmAccount,
@@ -271,6 +446,50 @@ func (client *Client) Call(account *EvmAccount,
 	defer log.Lvlf2("<<< EVM view method '%s()' on %s",
 		method, contractInstance)
 
+	ret, err := client.call(account, contractInstance, method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unpack the result
+	result, err := unpackResult(contractInstance.getAbi(), method, ret)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unpack EVM view "+
+			"method result: %v", err)
+	}
+
+	return result, nil
+}
+
+// CallInto performs a new call (contract view method call, without state
+// change) on the EVM, and decodes the result into the Go value pointed to by
+// result: a variable of the type of the output for a single output, or a
+// struct with one field per output.
+func (client *Client) CallInto(account *EvmAccount,
+	contractInstance *EvmContractInstance, result interface{},
+	method string, args ...interface{}) error {
+	log.Lvlf2(">>> EVM view method '%s()' on %s", method, contractInstance)
+	defer log.Lvlf2("<<< EVM view method '%s()' on %s",
+		method, contractInstance)
+
+	ret, err := client.call(account, contractInstance, method, args...)
+	if err != nil {
+		return err
+	}
+
+	err = contractInstance.getAbi().Unpack(result, method, ret)
+	if err != nil {
+		return xerrors.Errorf("failed to unpack EVM view method "+
+			"result: %v", err)
+	}
+
+	return nil
+}
+
+// Perform a call on the EVM and return the packed result
+func (client *Client) call(account *EvmAccount,
+	contractInstance *EvmContractInstance,
+	method string, args ...interface{}) ([]byte, error) {
 	// Pack the method call and arguments
 	callData, err := contractInstance.packMethod(method, args...)
 	if err != nil {
@@ -295,14 +514,7 @@ func (client *Client) Call(account *EvmAccount,
 		return nil, xerrors.Errorf("failed to call EVM: %v", err)
 	}
 
-	// Unpack the result
-	result, err := unpackResult(contractInstance.getAbi(), method, ret)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to unpack EVM view "+
-			"method result: %v", err)
-	}
-
-	return result, nil
+	return ret, nil
 }
 
 // CallEVM performs a low-level call (contract view method call, without state
@@ -320,8 +532,12 @@ func CallEVM(accountAddress common.Address, contractAddress common.Address,
 		getVMConfig())
 
 	// Perform the call (1 Ether should be enough for everyone [tm]...)
-	ret, _, err := evm.Call(vm.AccountRef(accountAddress), contractAddress,
-		callData, uint64(1*WeiPerEther), big.NewInt(0))
+	var ret []byte
+	err := runWithReader(stateDb, func() (err error) {
+		ret, _, err = evm.Call(vm.AccountRef(accountAddress),
+			contractAddress, callData, uint64(1*WeiPerEther), big.NewInt(0))
+		return
+	})
 	if err != nil {
 		return nil, xerrors.Errorf("failed to execute EVM call: %v ", err)
 	}
@@ -360,6 +576,217 @@ func (client *Client) GetAccountBalance(address common.Address) (
 	return balance, nil
 }
 
+// GetBridgeConfig returns the coin bridge configuration of the BEvm instance
+func (client *Client) GetBridgeConfig() (*BridgeConfig, error) {
+	bs, err := getState(client.bcClient, client.instanceID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve BEvm state: %v", err)
+	}
+
+	return &bs.BridgeConfig, nil
+}
+
+// SetBridgeConfig replaces the coin bridge configuration of the BEvm instance
+func (client *Client) SetBridgeConfig(config BridgeConfig) error {
+	configBuf, err := protobuf.Encode(&config)
+	if err != nil {
+		return xerrors.Errorf("failed to encode bridge configuration: %v",
+			err)
+	}
+
+	_, err = client.invoke("bridgeConfig", byzcoin.Arguments{
+		{Name: "bridgeConfig", Value: configBuf},
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to invoke ByzCoin transaction for "+
+			"bridge configuration: %v", err)
+	}
+
+	return nil
+}
+
+// Deposit moves the given amount of coins from a ByzCoin coin instance to
+// the balance of an Ethereum address, in a single ByzCoin transaction
+func (client *Client) Deposit(coinID byzcoin.InstanceID, amount uint64,
+	address common.Address) (*byzcoin.ClientTransaction, error) {
+	amountBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amountBuf, amount)
+
+	bcTx, err := sendByzCoinTx(client.bcClient, client.signer,
+		byzcoin.Instruction{
+			InstanceID: coinID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: contracts.ContractCoinID,
+				Command:    "fetch",
+				Args: byzcoin.Arguments{
+					{Name: "coins", Value: amountBuf},
+				},
+			},
+		},
+		byzcoin.Instruction{
+			InstanceID: client.instanceID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractBEvmID,
+				Command:    "deposit",
+				Args: byzcoin.Arguments{
+					{Name: "address", Value: address.Bytes()},
+				},
+			},
+		})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to deposit coins: %v", err)
+	}
+
+	log.Lvlf2("Deposited %d coins on '%x'", amount, address)
+
+	return bcTx, nil
+}
+
+// Withdraw moves the given amount of coins from the balance of an Ethereum
+// account to a ByzCoin coin instance, which the owner of the account signs
+// for
+func (client *Client) Withdraw(account *EvmAccount, coinID byzcoin.InstanceID,
+	amount uint64) (*byzcoin.ClientTransaction, error) {
+	amountBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amountBuf, amount)
+
+	signature, err := crypto.Sign(
+		WithdrawHash(client.instanceID, coinID, amount, account.Nonce),
+		account.PrivateKey)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to sign withdrawal: %v", err)
+	}
+
+	bcTx, err := sendByzCoinTx(client.bcClient, client.signer,
+		byzcoin.Instruction{
+			InstanceID: client.instanceID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractBEvmID,
+				Command:    "withdraw",
+				Args: byzcoin.Arguments{
+					{Name: "address", Value: account.Address.Bytes()},
+					{Name: "amount", Value: amountBuf},
+					{Name: "destination", Value: coinID.Slice()},
+					{Name: "signature", Value: signature},
+				},
+			},
+		})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to withdraw coins: %v", err)
+	}
+
+	account.Nonce++
+
+	log.Lvlf2("Withdrew %d coins from '%x'", amount, account.Address)
+
+	return bcTx, nil
+}
+
+// GetLogs returns the logs emitted by the EVM contracts of the BEvm instance,
+// from the contract at the given address (nil for all the contracts), having
+// the given topics (nil topics match any topic) and emitted in the blocks
+// from fromBlock to toBlock (0 for no upper limit).
+func (client *Client) GetLogs(address *common.Address, topics []*common.Hash,
+	fromBlock int, toBlock int) ([]EvmLog, error) {
+	log.Lvlf2(">>> Get EVM logs")
+	defer log.Lvlf2("<<< Get EVM logs")
+
+	request := &GetLogsRequest{
+		ByzCoinID:      client.bcClient.ID,
+		BEvmInstanceID: client.instanceID[:],
+		FromBlock:      fromBlock,
+		ToBlock:        toBlock,
+	}
+	if address != nil {
+		request.Address = address.Bytes()
+	}
+	for _, topic := range topics {
+		if topic == nil {
+			request.Topics = append(request.Topics, nil)
+		} else {
+			request.Topics = append(request.Topics, topic.Bytes())
+		}
+	}
+	response := &GetLogsResponse{}
+
+	err := client.Client.SendProtobuf(client.bcClient.Roster.List[0],
+		request, response)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get EVM logs: %v", err)
+	}
+
+	return response.Logs, nil
+}
+
+// SendRawTransaction applies an Ethereum transaction signed and RLP-encoded
+// by another tool, like a wallet, and returns its hash. As for Transaction(),
+// the gas is paid by the coin account of the client, if a price is set.
+func (client *Client) SendRawTransaction(rawTx []byte) (common.Hash, error) {
+	var tx types.Transaction
+	err := rlp.DecodeBytes(rawTx, &tx)
+	if err != nil {
+		return common.Hash{}, xerrors.Errorf("failed to decode RLP for EVM "+
+			"transaction: %v", err)
+	}
+
+	txBuffer, err := tx.MarshalJSON()
+	if err != nil {
+		return common.Hash{}, xerrors.Errorf("failed to serialize EVM "+
+			"transaction to JSON: %v", err)
+	}
+
+	_, err = client.invokeTransaction(tx.Gas(), txBuffer)
+	if err != nil {
+		return common.Hash{}, xerrors.Errorf("failed to invoke ByzCoin "+
+			"transaction: %v", err)
+	}
+
+	return tx.Hash(), nil
+}
+
+// GetReceipt returns the receipt and the logs of an Ethereum transaction
+// applied by the BEvm instance. The receipt is nil if the transaction is
+// unknown.
+func (client *Client) GetReceipt(txHash common.Hash) (*GetReceiptResponse,
+	error) {
+	request := &GetReceiptRequest{
+		ByzCoinID:      client.bcClient.ID,
+		BEvmInstanceID: client.instanceID[:],
+		TxHash:         txHash.Bytes(),
+	}
+	response := &GetReceiptResponse{}
+
+	err := client.Client.SendProtobuf(client.bcClient.Roster.List[0],
+		request, response)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get EVM receipt: %v", err)
+	}
+
+	return response, nil
+}
+
+// Send an EstimateGasRequest
+func (client *Client) estimateGas(amount uint64, account *EvmAccount,
+	contractAddress []byte, callData []byte) (*EstimateGasResponse, error) {
+	request := &EstimateGasRequest{
+		ByzCoinID:       client.bcClient.ID,
+		BEvmInstanceID:  client.instanceID[:],
+		AccountAddress:  account.Address[:],
+		ContractAddress: contractAddress,
+		Amount:          amount,
+		CallData:        callData,
+	}
+	response := &EstimateGasResponse{}
+
+	err := client.Client.SendProtobuf(client.bcClient.Roster.List[0],
+		request, response)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to estimate EVM gas: %v", err)
+	}
+
+	return response, nil
+}
+
 // ---------------------------------------------------------------------------
 // Service methods
 
@@ -607,9 +1034,9 @@ func (account EvmAccount) signAndMarshalTx(tx *types.Transaction) (
 	return signedBuffer, nil
 }
 
-// Retrieve a read-only EVM state database backed by a ByzCoin client
-func getEvmDb(bcClient *byzcoin.Client, instID byzcoin.InstanceID) (
-	*state.StateDB, error) {
+// Retrieve the BEvm state using a ByzCoin client
+func getState(bcClient *byzcoin.Client, instID byzcoin.InstanceID) (
+	*State, error) {
 	// Retrieve the proof of the Byzcoin instance
 	proofResponse, err := bcClient.GetProofFromLatest(instID[:])
 	if err != nil {
@@ -630,6 +1057,17 @@ func getEvmDb(bcClient *byzcoin.Client, instID byzcoin.InstanceID) (
 			"value: %v", err)
 	}
 
+	return &bs, nil
+}
+
+// Retrieve a read-only EVM state database backed by a ByzCoin client
+func getEvmDb(bcClient *byzcoin.Client, instID byzcoin.InstanceID) (
+	*state.StateDB, error) {
+	bs, err := getState(bcClient, instID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a client ByzDB instance
 	byzDb, err := NewClientByzDatabase(instID, bcClient)
 	if err != nil {
@@ -697,6 +1135,60 @@ func (client *Client) invoke(command string, args byzcoin.Arguments) (
 	return bcTx, nil
 }
 
+// Invoke an EVM transaction on a ByzCoin EVM instance. If the instance has a
+// gas price and the client has a coin account, the coins for the whole gas
+// limit are fetched from the account and what is left is stored back.
+func (client *Client) invokeTransaction(gasLimit uint64,
+	signedTxBuffer []byte) (*byzcoin.ClientTransaction, error) {
+	invokeInstr := byzcoin.Instruction{
+		InstanceID: client.instanceID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractBEvmID,
+			Command:    "transaction",
+			Args: byzcoin.Arguments{
+				{Name: "tx", Value: signedTxBuffer},
+			},
+		},
+	}
+
+	if client.coinID == nil {
+		return sendByzCoinTx(client.bcClient, client.signer, invokeInstr)
+	}
+
+	config, err := client.GetGasConfig()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve gas config: %v", err)
+	}
+	fee, err := config.fee(gasLimit)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute gas fee: %v", err)
+	}
+	if fee == 0 {
+		return sendByzCoinTx(client.bcClient, client.signer, invokeInstr)
+	}
+
+	feeBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(feeBuf, fee)
+
+	return sendByzCoinTx(client.bcClient, client.signer,
+		byzcoin.Instruction{
+			InstanceID: *client.coinID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: contracts.ContractCoinID,
+				Command:    "fetch",
+				Args:       byzcoin.Arguments{{Name: "coins", Value: feeBuf}},
+			},
+		},
+		invokeInstr,
+		byzcoin.Instruction{
+			InstanceID: *client.coinID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: contracts.ContractCoinID,
+				Command:    "store",
+			},
+		})
+}
+
 func spawnBEvm(bcClient *byzcoin.Client, signer darc.Signer,
 	instanceID byzcoin.InstanceID, instr *byzcoin.Spawn) (
 	*byzcoin.ClientTransaction, error) {
@@ -719,19 +1211,30 @@ func execByzCoinTx(bcClient *byzcoin.Client,
 	signer darc.Signer, instanceID byzcoin.InstanceID,
 	spawnInstr *byzcoin.Spawn, invokeInstr *byzcoin.Invoke,
 	deleteInstr *byzcoin.Delete) (*byzcoin.ClientTransaction, error) {
+	return sendByzCoinTx(bcClient, signer, byzcoin.Instruction{
+		InstanceID: instanceID,
+		Spawn:      spawnInstr,
+		Invoke:     invokeInstr,
+		Delete:     deleteInstr,
+	})
+}
+
+// Send a ByzCoin transaction with the given instructions, all signed by the
+// signer, and wait for it to be included
+func sendByzCoinTx(bcClient *byzcoin.Client, signer darc.Signer,
+	instrs ...byzcoin.Instruction) (*byzcoin.ClientTransaction, error) {
 	counters, err := bcClient.GetSignerCounters(signer.Identity().String())
 	if err != nil {
 		return nil, xerrors.Errorf("failed to retrieve signer "+
 			"counters from ByzCoin: %v", err)
 	}
 
-	tx, err := bcClient.CreateTransaction(byzcoin.Instruction{
-		InstanceID:    instanceID,
-		SignerCounter: []uint64{counters.Counters[0] + 1},
-		Spawn:         spawnInstr,
-		Invoke:        invokeInstr,
-		Delete:        deleteInstr,
-	})
+	for i := range instrs {
+		instrs[i].SignerCounter = []uint64{counters.Counters[0] + 1 +
+			uint64(i)}
+	}
+
+	tx, err := bcClient.CreateTransaction(instrs...)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to create ByzCoin "+
 			"transaction: %v", err)
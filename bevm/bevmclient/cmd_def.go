@@ -132,4 +132,23 @@ var cmds = cli.Commands{
 		),
 		Action: executeCall,
 	},
+	{
+		Name:      "serveRPC",
+		Usage:     "serve the Ethereum JSON-RPC API of a BEvm instance",
+		Aliases:   []string{"rpc"},
+		ArgsUsage: "",
+		Flags: append(commonFlags,
+			cli.StringFlag{
+				Name:  "listen",
+				Value: "localhost:8545",
+				Usage: "address to listen on",
+			},
+			cli.StringFlag{
+				Name:   "token",
+				EnvVar: "BEVM_RPC_TOKEN",
+				Usage:  "bearer token the requests must carry, required if the address is not a loopback one",
+			},
+		),
+		Action: serveRPC,
+	},
 }
@@ -2,8 +2,9 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
 	"math/big"
+	"net"
+	"net/http"
 	"strconv"
 
 	"golang.org/x/xerrors"
@@ -124,32 +125,13 @@ func deployContract(ctx *cli.Context) error {
 	abiFilepath := ctx.Args().Get(0)
 	binFilepath := ctx.Args().Get(1)
 
-	abiData, err := ioutil.ReadFile(abiFilepath)
-	if err != nil {
-		return xerrors.Errorf("failed to read contract ABI from "+
-			"provided file: %v", err)
-	}
-
-	binData, err := ioutil.ReadFile(binFilepath)
-	if err != nil {
-		return xerrors.Errorf("failed to read contract Bytecode "+
-			"from provided file: %v", err)
-	}
-
-	contract, err := bevm.NewEvmContract("newContract",
-		string(abiData), string(binData))
+	contract, err := bevm.LoadEvmContract("newContract",
+		abiFilepath, binFilepath)
 	if err != nil {
 		return xerrors.Errorf("failed to create new BEvm contract: %v", err)
 	}
 
-	constrAbi := contract.Abi.Constructor
-	userArgs := ctx.Args()[2:]
-	if len(userArgs) != len(constrAbi.Inputs) {
-		return xerrors.Errorf("wrong number of arguments for contract "+
-			"constructor: expected %d, got %d",
-			len(constrAbi.Inputs), len(userArgs))
-	}
-	args, err := bevm.DecodeEvmArgs(userArgs, constrAbi.Inputs)
+	args, err := contract.DecodeConstructorArgs(ctx.Args()[2:])
 	if err != nil {
 		return xerrors.Errorf("failed to decode contract constructor "+
 			"arguments: %v", err)
@@ -204,18 +186,7 @@ func executeTransaction(ctx *cli.Context) error {
 	}
 
 	method := ctx.Args().First()
-	methodAbi, ok := contractInstance.Parent.Abi.Methods[method]
-	if !ok {
-		return xerrors.Errorf("transaction \"%s\" does not exist "+
-			"for this contract", method)
-	}
-
-	userArgs := ctx.Args().Tail()
-	if len(userArgs) != len(methodAbi.Inputs) {
-		return xerrors.Errorf("wrong number of arguments for \"%s\": "+
-			"expected %d, got %d", method, len(methodAbi.Inputs), len(userArgs))
-	}
-	args, err := bevm.DecodeEvmArgs(userArgs, methodAbi.Inputs)
+	args, err := contractInstance.DecodeMethodArgs(method, ctx.Args().Tail())
 	if err != nil {
 		return xerrors.Errorf("failed to decode contract transaction "+
 			"arguments: %v", err)
@@ -272,12 +243,7 @@ func executeCall(ctx *cli.Context) error {
 		return xerrors.Errorf("callable \"%s\" is not a view method", method)
 	}
 
-	userArgs := ctx.Args().Tail()
-	if len(userArgs) != len(methodAbi.Inputs) {
-		return xerrors.Errorf("wrong number of arguments for \"%s\": "+
-			"expected %d, got %d", method, len(methodAbi.Inputs), len(userArgs))
-	}
-	args, err := bevm.DecodeEvmArgs(userArgs, methodAbi.Inputs)
+	args, err := contractInstance.DecodeMethodArgs(method, ctx.Args().Tail())
 	if err != nil {
 		return xerrors.Errorf("failed to decode contract view method "+
 			"arguments: %v", err)
@@ -303,3 +269,51 @@ func executeCall(ctx *cli.Context) error {
 
 	return nil
 }
+
+func serveRPC(ctx *cli.Context) error {
+	// Retrieve options
+
+	bevmClient, err := loadBEvmClient(ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to handle provided options: %v", err)
+	}
+
+	listen := ctx.String("listen")
+	token := ctx.String("token")
+	if token == "" && !isLoopback(listen) {
+		return xerrors.Errorf("--token is required to listen on %s, which "+
+			"is not a loopback address", listen)
+	}
+
+	// Perform command
+
+	handler, err := bevm.NewRPCHandler(bevmClient)
+	if err != nil {
+		return xerrors.Errorf("failed to create JSON-RPC handler: %v", err)
+	}
+	if token != "" {
+		handler = bevm.NewAuthHandler(handler, token)
+	}
+
+	_, err = fmt.Fprintf(ctx.App.Writer, "Serving JSON-RPC API on %s\n",
+		listen)
+	if err != nil {
+		return xerrors.Errorf("failed to write report msg: %v", err)
+	}
+
+	return http.ListenAndServe(listen, handler)
+}
+
+// isLoopback returns whether the listening address only accepts local
+// connections. An empty host listens on all the interfaces.
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
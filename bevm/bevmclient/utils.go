@@ -131,14 +131,9 @@ func readContractFile(name string) (*bevm.EvmContractInstance, error) {
 			"ABI: %v", err)
 	}
 
-	contractInstance := bevm.EvmContractInstance{
-		Parent: &bevm.EvmContract{
-			Abi: abi,
-		},
-		Address: tmp.Address,
-	}
+	contractInstance := (&bevm.EvmContract{Abi: abi}).At(tmp.Address)
 
-	return &contractInstance, nil
+	return contractInstance, nil
 }
 
 type commonOptions struct {
@@ -148,10 +143,29 @@ type commonOptions struct {
 }
 
 func handleCommonOptions(ctx *cli.Context) (*commonOptions, error) {
+	accountName := ctx.String("accountName")
+
+	bevmClient, err := loadBEvmClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := readAccountFile(accountName)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read account from file: %v", err)
+	}
+
+	return &commonOptions{
+		account:     account,
+		accountName: accountName,
+		bevmClient:  bevmClient,
+	}, nil
+}
+
+func loadBEvmClient(ctx *cli.Context) (*bevm.Client, error) {
 	bcFile := ctx.String("bc")
 	bevmIDStr := ctx.String("bevmID")
 	signerStr := ctx.String("sign")
-	accountName := ctx.String("accountName")
 
 	bevmID, err := hex.DecodeString(bevmIDStr)
 	if err != nil {
@@ -180,14 +194,5 @@ func handleCommonOptions(ctx *cli.Context) (*commonOptions, error) {
 			"instance: %v", err)
 	}
 
-	account, err := readAccountFile(accountName)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to read account from file: %v", err)
-	}
-
-	return &commonOptions{
-		account:     account,
-		accountName: accountName,
-		bevmClient:  bevmClient,
-	}, nil
+	return bevmClient, nil
 }
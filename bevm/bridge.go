@@ -0,0 +1,254 @@
+package bevm
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// BridgeConfig ties a ByzCoin coin to the Ether balances of a BEvm instance,
+// so that coins can be deposited to and withdrawn from EVM accounts.
+type BridgeConfig struct {
+	// CoinName is the coin accepted by the bridge. A zero value disables the
+	// bridge.
+	CoinName byzcoin.InstanceID
+	// WeiPerCoin is the amount of Wei credited for every coin deposited.
+	WeiPerCoin uint64
+}
+
+// Check that the bridge is enabled
+func (config BridgeConfig) check() error {
+	if config.CoinName.Equal(byzcoin.InstanceID{}) {
+		return xerrors.New("the coin bridge is not configured")
+	}
+	if config.WeiPerCoin == 0 {
+		return xerrors.New("the coin bridge has a zero conversion rate")
+	}
+
+	return nil
+}
+
+// Return the Wei corresponding to an amount of coins
+func (config BridgeConfig) toWei(amount uint64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(amount),
+		new(big.Int).SetUint64(config.WeiPerCoin))
+}
+
+// WithdrawHash returns the hash that the owner of an EVM account signs to
+// withdraw coins from it to the destination coin instance. The account nonce
+// protects against replays, and the destination against a withdrawal being
+// redirected to another coin instance.
+func WithdrawHash(bevmID byzcoin.InstanceID, destination byzcoin.InstanceID,
+	amount uint64, nonce uint64) []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf[:8], amount)
+	binary.BigEndian.PutUint64(buf[8:], nonce)
+
+	return crypto.Keccak256(bevmID[:], []byte("withdraw"), destination[:],
+		buf)
+}
+
+// Return the coins of the bridge deposited and not withdrawn yet, adding
+// them to the state if none were deposited yet
+func (st *State) bridgedCoin(name byzcoin.InstanceID) *byzcoin.Coin {
+	for i := range st.BridgedCoins {
+		if st.BridgedCoins[i].Name.Equal(name) {
+			return &st.BridgedCoins[i]
+		}
+	}
+	st.BridgedCoins = append(st.BridgedCoins, byzcoin.Coin{Name: name})
+
+	return &st.BridgedCoins[len(st.BridgedCoins)-1]
+}
+
+// Replace the bridge configuration, as long as no coins were deposited
+func (c *contractBEvm) invokeBridgeConfig(rst byzcoin.ReadOnlyStateTrie,
+	inst byzcoin.Instruction) error {
+	if rst.GetVersion() < byzcoin.VersionBEvmGas {
+		return xerrors.New("the bridge configuration needs a newer " +
+			"ByzCoin version")
+	}
+	if len(c.State.BridgedCoins) > 0 {
+		return xerrors.New("the bridge configuration cannot change once " +
+			"coins were deposited")
+	}
+
+	err := checkArguments(inst, "bridgeConfig")
+	if err != nil {
+		return xerrors.Errorf("failed to validate arguments for "+
+			"'bridgeConfig' invocation on BEvm: %v", err)
+	}
+
+	var config BridgeConfig
+	err = protobuf.Decode(inst.Invoke.Args.Search("bridgeConfig"), &config)
+	if err != nil {
+		return xerrors.Errorf("failed to decode BEvm bridge configuration: "+
+			"%v", err)
+	}
+
+	c.State.BridgeConfig = config
+
+	return nil
+}
+
+// Credit an Ethereum account with all the bridge coins given to the
+// instruction
+func (c *contractBEvm) invokeDeposit(inst byzcoin.Instruction,
+	stateDb *state.StateDB, coins []byzcoin.Coin) ([]byzcoin.Coin, error) {
+	err := checkArguments(inst, "address")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to validate arguments for "+
+			"'deposit' invocation on BEvm: %v", err)
+	}
+
+	config := c.State.BridgeConfig
+	err = config.check()
+	if err != nil {
+		return nil, err
+	}
+
+	address := common.BytesToAddress(inst.Invoke.Args.Search("address"))
+
+	var cout []byzcoin.Coin
+	deposited := false
+	for _, coin := range coins {
+		if !coin.Name.Equal(config.CoinName) {
+			cout = append(cout, coin)
+			continue
+		}
+		err = c.State.bridgedCoin(config.CoinName).SafeAdd(coin.Value)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to deposit coins: %v", err)
+		}
+		stateDb.AddBalance(address, config.toWei(coin.Value))
+		deposited = true
+	}
+
+	if !deposited {
+		return nil, xerrors.New("no coins to deposit")
+	}
+
+	return cout, nil
+}
+
+// Debit an Ethereum account, on behalf of its owner, and credit the coins to
+// the coin instance the owner signed for
+func (c *contractBEvm) invokeWithdraw(rst byzcoin.ReadOnlyStateTrie,
+	inst byzcoin.Instruction,
+	stateDb *state.StateDB) ([]byzcoin.StateChange, error) {
+	err := checkArguments(inst, "address", "amount", "destination",
+		"signature")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to validate arguments for "+
+			"'withdraw' invocation on BEvm: %v", err)
+	}
+
+	config := c.State.BridgeConfig
+	err = config.check()
+	if err != nil {
+		return nil, err
+	}
+
+	amountBuf := inst.Invoke.Args.Search("amount")
+	if len(amountBuf) != 8 {
+		return nil, xerrors.New("the amount must be a 64-bit integer")
+	}
+	amount := binary.LittleEndian.Uint64(amountBuf)
+	address := common.BytesToAddress(inst.Invoke.Args.Search("address"))
+	destBuf := inst.Invoke.Args.Search("destination")
+	if len(destBuf) != len(byzcoin.InstanceID{}) {
+		return nil, xerrors.New("the destination must be an instance ID")
+	}
+	destination := byzcoin.NewInstanceID(destBuf)
+
+	err = checkWithdrawSignature(inst.InstanceID, stateDb, address,
+		destination, amount, inst.Invoke.Args.Search("signature"))
+	if err != nil {
+		return nil, err
+	}
+
+	wei := config.toWei(amount)
+	if stateDb.GetBalance(address).Cmp(wei) < 0 {
+		return nil, xerrors.Errorf("balance of '%s' too low to withdraw %d "+
+			"coins", address.Hex(), amount)
+	}
+	err = c.State.bridgedCoin(config.CoinName).SafeSub(amount)
+	if err != nil {
+		return nil, xerrors.Errorf("only the deposited coins can be "+
+			"withdrawn: %v", err)
+	}
+
+	sc, err := creditCoin(rst, destination, config.CoinName, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	stateDb.SubBalance(address, wei)
+	stateDb.SetNonce(address, stateDb.GetNonce(address)+1)
+
+	return []byzcoin.StateChange{sc}, nil
+}
+
+// Add coins to a coin instance holding the coins of the bridge
+func creditCoin(rst byzcoin.ReadOnlyStateTrie, coinID byzcoin.InstanceID,
+	name byzcoin.InstanceID, amount uint64) (byzcoin.StateChange, error) {
+	buf, _, contractID, darcID, err := rst.GetValues(coinID.Slice())
+	if err != nil {
+		return byzcoin.StateChange{}, xerrors.Errorf("failed to get the "+
+			"destination coin instance: %v", err)
+	}
+	if contractID != contracts.ContractCoinID {
+		return byzcoin.StateChange{}, xerrors.Errorf("the destination is a "+
+			"'%s' instance, not a coin instance", contractID)
+	}
+
+	var coin byzcoin.Coin
+	err = protobuf.Decode(buf, &coin)
+	if err != nil {
+		return byzcoin.StateChange{}, xerrors.Errorf("failed to decode the "+
+			"destination coin instance: %v", err)
+	}
+	if !coin.Name.Equal(name) {
+		return byzcoin.StateChange{}, xerrors.New("the destination doesn't " +
+			"hold the coins of the bridge")
+	}
+	err = coin.SafeAdd(amount)
+	if err != nil {
+		return byzcoin.StateChange{}, xerrors.Errorf("failed to credit the "+
+			"destination: %v", err)
+	}
+	buf, err = protobuf.Encode(&coin)
+	if err != nil {
+		return byzcoin.StateChange{}, xerrors.Errorf("failed to encode the "+
+			"destination coin instance: %v", err)
+	}
+
+	return byzcoin.NewStateChange(byzcoin.Update, coinID,
+		contracts.ContractCoinID, buf, darcID), nil
+}
+
+// Check that the owner of an EVM account signed a withdrawal to the
+// destination with its current nonce
+func checkWithdrawSignature(bevmID byzcoin.InstanceID, stateDb *state.StateDB,
+	address common.Address, destination byzcoin.InstanceID, amount uint64,
+	signature []byte) error {
+	hash := WithdrawHash(bevmID, destination, amount,
+		stateDb.GetNonce(address))
+
+	pubKey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return xerrors.Errorf("invalid withdrawal signature: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pubKey) != address {
+		return xerrors.Errorf("withdrawal not signed by '%s'", address.Hex())
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/rlp"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
@@ -61,6 +62,37 @@ func contractBEvmFromBytes(in []byte) (byzcoin.Contract, error) {
 type State struct {
 	RootHash common.Hash // Hash of the last commit in the EVM state database
 	KeyList  []string    // List of keys contained in the EVM state database
+	// GasConfig bounds and prices the gas of the transactions. It and the
+	// next fields are only stored from byzcoin.VersionBEvmGas on.
+	GasConfig GasConfig
+	// BlockIndex is the index of the block of the last transaction, and
+	// BlockGasUsed the gas used by the transactions of that block
+	BlockIndex   int
+	BlockGasUsed uint64
+	// BridgeConfig sets the coin exchanged with the Ether balances
+	BridgeConfig BridgeConfig
+	// BridgedCoins are the coins deposited and not withdrawn yet, per coin.
+	// The withdrawals can't take more.
+	BridgedCoins []byzcoin.Coin
+}
+
+// legacyState is the encoding of State before byzcoin.VersionBEvmGas
+type legacyState struct {
+	RootHash common.Hash
+	KeyList  []string
+}
+
+// Encode the contract state, without the fields that the ByzCoin version of
+// the state trie doesn't know yet
+func encodeState(rst byzcoin.ReadOnlyStateTrie, st *State) ([]byte, error) {
+	if rst.GetVersion() < byzcoin.VersionBEvmGas {
+		return protobuf.Encode(&legacyState{
+			RootHash: st.RootHash,
+			KeyList:  st.KeyList,
+		})
+	}
+
+	return protobuf.Encode(st)
 }
 
 // NewEvmDb creates a new EVM state database from the contract state
@@ -165,7 +197,33 @@ func (c *contractBEvm) Spawn(rst byzcoin.ReadOnlyStateTrie,
 			xerrors.Errorf("failed to create new BEvm contract state: %v", err)
 	}
 
-	contractData, err := protobuf.Encode(contractState)
+	if inst.Spawn.Args.Search("gasConfig") != nil {
+		if rst.GetVersion() < byzcoin.VersionBEvmGas {
+			return nil, nil, xerrors.New("the gas configuration needs " +
+				"a newer ByzCoin version")
+		}
+		err = protobuf.Decode(inst.Spawn.Args.Search("gasConfig"),
+			&contractState.GasConfig)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to decode BEvm gas "+
+				"configuration: %v", err)
+		}
+	}
+
+	if inst.Spawn.Args.Search("bridgeConfig") != nil {
+		if rst.GetVersion() < byzcoin.VersionBEvmGas {
+			return nil, nil, xerrors.New("the bridge configuration needs " +
+				"a newer ByzCoin version")
+		}
+		err = protobuf.Decode(inst.Spawn.Args.Search("bridgeConfig"),
+			&contractState.BridgeConfig)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to decode BEvm bridge "+
+				"configuration: %v", err)
+		}
+	}
+
+	contractData, err := encodeState(rst, contractState)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("failed to encode BEvm "+
 			"contract state: %v", err)
@@ -219,12 +277,41 @@ func (c *contractBEvm) Invoke(rst byzcoin.ReadOnlyStateTrie,
 		}
 
 	case "transaction":
-		methodSc, err = c.invokeTransaction(rst, inst, stateDb, darcID)
+		methodSc, err = c.invokeTransaction(rst, inst, stateDb, darcID,
+			cout)
 		if err != nil {
 			return nil, nil, xerrors.Errorf("failed to execute \"credit\" "+
 				"method on BEvm contract: %v", err)
 		}
 
+	case "gasConfig":
+		err = c.invokeGasConfig(rst, inst)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to execute "+
+				"\"gasConfig\" method on BEvm contract: %v", err)
+		}
+
+	case "bridgeConfig":
+		err = c.invokeBridgeConfig(rst, inst)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to execute "+
+				"\"bridgeConfig\" method on BEvm contract: %v", err)
+		}
+
+	case "deposit":
+		cout, err = c.invokeDeposit(inst, stateDb, cout)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to execute \"deposit\" "+
+				"method on BEvm contract: %v", err)
+		}
+
+	case "withdraw":
+		methodSc, err = c.invokeWithdraw(rst, inst, stateDb)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to execute "+
+				"\"withdraw\" method on BEvm contract: %v", err)
+		}
+
 	default:
 		err = fmt.Errorf("unknown Invoke command: '%s'", inst.Invoke.Command)
 	}
@@ -235,8 +322,13 @@ func (c *contractBEvm) Invoke(rst byzcoin.ReadOnlyStateTrie,
 			xerrors.Errorf("failed to create new BEvm contract "+
 				"state: %v", err)
 	}
+	contractState.GasConfig = c.State.GasConfig
+	contractState.BlockIndex = c.State.BlockIndex
+	contractState.BlockGasUsed = c.State.BlockGasUsed
+	contractState.BridgeConfig = c.State.BridgeConfig
+	contractState.BridgedCoins = c.State.BridgedCoins
 
-	contractData, err := protobuf.Encode(contractState)
+	contractData, err := encodeState(rst, contractState)
 	if err != nil {
 		return nil, nil,
 			xerrors.Errorf("failed to encode BEvm contract state: %v", err)
@@ -267,6 +359,12 @@ func (c *contractBEvm) invokeCredit(rst byzcoin.ReadOnlyStateTrie,
 				"invocation on BEvm: %v", err)
 	}
 
+	// The balances must stay backed by the coins of the bridge
+	if !c.State.BridgeConfig.CoinName.Equal(byzcoin.InstanceID{}) {
+		return nil, xerrors.New("cannot credit accounts once the coin " +
+			"bridge is configured")
+	}
+
 	address := common.BytesToAddress(inst.Invoke.Args.Search("address"))
 	amount := new(big.Int).SetBytes(inst.Invoke.Args.Search("amount"))
 
@@ -275,10 +373,37 @@ func (c *contractBEvm) invokeCredit(rst byzcoin.ReadOnlyStateTrie,
 	return nil, nil
 }
 
-// Perform an Ethereum transaction (contract method call with state change)
+// Replace the gas configuration
+func (c *contractBEvm) invokeGasConfig(rst byzcoin.ReadOnlyStateTrie,
+	inst byzcoin.Instruction) error {
+	if rst.GetVersion() < byzcoin.VersionBEvmGas {
+		return xerrors.New("the gas configuration needs a newer ByzCoin " +
+			"version")
+	}
+
+	err := checkArguments(inst, "gasConfig")
+	if err != nil {
+		return xerrors.Errorf("failed to validate arguments for 'gasConfig' "+
+			"invocation on BEvm: %v", err)
+	}
+
+	var config GasConfig
+	err = protobuf.Decode(inst.Invoke.Args.Search("gasConfig"), &config)
+	if err != nil {
+		return xerrors.Errorf("failed to decode BEvm gas configuration: %v",
+			err)
+	}
+
+	c.State.GasConfig = config
+
+	return nil
+}
+
+// Perform an Ethereum transaction (contract method call with state change),
+// charging its gas on the coins
 func (c *contractBEvm) invokeTransaction(rst byzcoin.ReadOnlyStateTrie,
 	inst byzcoin.Instruction, stateDb *state.StateDB,
-	darcID darc.ID) ([]byzcoin.StateChange, error) {
+	darcID darc.ID, coins []byzcoin.Coin) ([]byzcoin.StateChange, error) {
 	// Retrieve the Ethereum transaction
 	var ethTx types.Transaction
 
@@ -318,13 +443,32 @@ func (c *contractBEvm) invokeTransaction(rst byzcoin.ReadOnlyStateTrie,
 	// Compute the timestamp for the EVM, converting [ns] to [s]
 	evmTs := uint64(tr.GetCurrentBlockTimestamp() / 1e9)
 
+	// Check the gas limits, and that the coins can pay for the whole gas
+	// limit before running the transaction
+	blockGasLeft, err := c.State.blockGasLeft(ethTx.Gas(), rst.GetIndex())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to check EVM gas limits: %v", err)
+	}
+	err = c.State.GasConfig.checkCoins(coins, ethTx.Gas())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to check EVM gas fee: %v", err)
+	}
+
 	stateDb.Prepare(ethTx.Hash(), common.Hash{}, 0)
-	txReceipt, err := sendTx(&ethTx, stateDb, evmTs)
+	txReceipt, err := sendTx(&ethTx, stateDb, evmTs, blockGasLeft)
 	if err != nil {
 		return nil,
 			xerrors.Errorf("failed to send transaction to EVM: %v", err)
 	}
 
+	// Only the gas used is charged, even if the transaction failed. The fee
+	// is burnt: it is taken from the coins and credited to no one.
+	err = c.State.GasConfig.charge(coins, txReceipt.GasUsed)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to charge EVM gas fee: %v", err)
+	}
+	c.State.addBlockGas(rst.GetIndex(), txReceipt.GasUsed)
+
 	if txReceipt.ContractAddress.Hex() != nilAddress.Hex() {
 		log.Lvlf2("Contract deployed at '%s'",
 			txReceipt.ContractAddress.Hex())
@@ -340,20 +484,37 @@ func (c *contractBEvm) invokeTransaction(rst byzcoin.ReadOnlyStateTrie,
 			"logs: %v", err)
 	}
 
+	// The older versions don't know the logs instance
+	if rst.GetVersion() >= byzcoin.VersionBEvmLogs {
+		logsSc, err := logsStateChange(rst, inst.InstanceID, darcID, &ethTx,
+			txReceipt)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to store EVM transaction "+
+				"logs: %v", err)
+		}
+		eventStateChanges = append(eventStateChanges, *logsSc)
+	}
+
 	return eventStateChanges, nil
 }
 
-// Helper function that sends a transaction to the EVM
-func sendTx(tx *types.Transaction, stateDb *state.StateDB, timestamp uint64) (
-	*types.Receipt, error) {
+// Helper function that sends a transaction to the EVM, with the given gas left
+// in the block
+func sendTx(tx *types.Transaction, stateDb *state.StateDB, timestamp uint64,
+	blockGasLeft uint64) (*types.Receipt, error) {
+	return applyTx(tx, stateDb, timestamp, blockGasLeft, getVMConfig())
+}
 
+// Helper function that applies a transaction to the EVM state, using the
+// given EVM configuration
+func applyTx(tx *types.Transaction, stateDb *state.StateDB, timestamp uint64,
+	blockGasLeft uint64, vmConfig vm.Config) (*types.Receipt, error) {
 	// Gets parameters defined in params
 	chainConfig := getChainConfig()
-	vmConfig := getVMConfig()
 
 	// GasPool tracks the amount of gas available during execution of the
 	// transactions in a block
-	gp := new(core.GasPool).AddGas(uint64(1e18))
+	gp := new(core.GasPool).AddGas(blockGasLeft)
 	usedGas := uint64(0)
 	ug := &usedGas
 
@@ -361,17 +522,15 @@ func sendTx(tx *types.Transaction, stateDb *state.StateDB, timestamp uint64) (
 	// the current blockchain to be used during transaction processing.
 	var bc core.ChainContext
 
-	// Header represents a block header in the Ethereum blockchain.
-	header := &types.Header{
-		Number:     big.NewInt(0),
-		Difficulty: big.NewInt(0),
-		ParentHash: common.Hash{0},
-		Time:       timestamp,
-	}
+	header := evmHeader(timestamp)
 
 	// Apply transaction to the general EVM state
-	receipt, usedGas, err := core.ApplyTransaction(chainConfig, bc,
-		&nilAddress, gp, stateDb, header, tx, ug, vmConfig)
+	var receipt *types.Receipt
+	err := runWithReader(stateDb, func() (err error) {
+		receipt, usedGas, err = core.ApplyTransaction(chainConfig, bc,
+			&nilAddress, gp, stateDb, header, tx, ug, vmConfig)
+		return
+	})
 	if err != nil {
 		return nil, xerrors.Errorf("failed to apply transaction "+
 			"on EVM: %v", err)
@@ -380,6 +539,17 @@ func sendTx(tx *types.Transaction, stateDb *state.StateDB, timestamp uint64) (
 	return receipt, nil
 }
 
+// Helper function that creates the Ethereum block header of an EVM execution
+func evmHeader(timestamp uint64) *types.Header {
+	// Header represents a block header in the Ethereum blockchain.
+	return &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(0),
+		ParentHash: common.Hash{0},
+		Time:       timestamp,
+	}
+}
+
 // Delete deletes an existing BEvm contract
 func (c *contractBEvm) Delete(rst byzcoin.ReadOnlyStateTrie,
 	inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange,
@@ -404,12 +574,19 @@ func (c *contractBEvm) Delete(rst byzcoin.ReadOnlyStateTrie,
 	}
 
 	// State changes to ByzCoin contain the Delete of the main contract state,
-	// plus the Delete of all the BEvmValue contracts known to it.
+	// plus the Delete of all the BEvmValue contracts known to it, plus the
+	// Delete of the logs if there are any.
 	sc = append([]byzcoin.StateChange{
 		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
 			ContractBEvmID, nil, darcID),
 	}, stateChanges...)
 
+	logsID := LogsInstanceID(inst.InstanceID)
+	if _, _, _, _, err := rst.GetValues(logsID[:]); err == nil {
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, logsID,
+			ContractBEvmLogsID, nil, darcID))
+	}
+
 	return
 }
 
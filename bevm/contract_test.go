@@ -1,17 +1,24 @@
 package bevm
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
 )
 
 var txParams = struct {
@@ -294,6 +301,227 @@ func Test_ABIv2(t *testing.T) {
 	}
 }
 
+func Test_ABIHelpers(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	// Initialize an account
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), a.Address)
+	require.NoError(t, err)
+
+	// Load a Candy contract from the files of the Solidity compiler
+	dir := filepath.Join("testdata", "Candy")
+	candyContract, err := LoadEvmContract("Candy",
+		filepath.Join(dir, "Candy_sol_Candy.abi"),
+		filepath.Join(dir, "Candy_sol_Candy.bin"))
+	require.NoError(t, err)
+	_, err = LoadEvmContract("Candy", filepath.Join(dir, "missing.abi"),
+		filepath.Join(dir, "Candy_sol_Candy.bin"))
+	require.Error(t, err)
+
+	// Deploy it with JSON-encoded arguments
+	_, err = candyContract.DecodeConstructorArgs(nil)
+	require.Error(t, err)
+	args, err := candyContract.DecodeConstructorArgs([]string{`"100"`})
+	require.NoError(t, err)
+	_, candyInstance, err := bevmClient.Deploy(
+		txParams.GasLimit, txParams.GasPrice, 0, a, candyContract, args...)
+	require.NoError(t, err)
+
+	// Eat 10 candies
+	_, err = candyInstance.DecodeMethodArgs("eatCandies", []string{`"10"`})
+	require.Error(t, err)
+	_, err = candyInstance.DecodeMethodArgs("eatCandy", nil)
+	require.Error(t, err)
+	args, err = candyInstance.DecodeMethodArgs("eatCandy", []string{`"10"`})
+	require.NoError(t, err)
+	_, err = bevmClient.Transaction(
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		candyInstance, "eatCandy", args...)
+	require.NoError(t, err)
+
+	// The result is decoded into a Go value, also from a new instance at
+	// the same address
+	var remaining *big.Int
+	err = bevmClient.CallInto(a, candyContract.At(candyInstance.Address),
+		&remaining, "getRemainingCandies")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(90), remaining)
+}
+
+func Test_ByzCoinPrecompile(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Read the genesis darc through the precompiled contract, with proofs
+	stateDb, err := getEvmDb(bct.Client, instanceID)
+	require.NoError(t, err)
+	output, err := CallEVM(nilAddress, ByzCoinPrecompileAddress,
+		bct.GenesisDarc.GetBaseID(), stateDb, 0)
+	require.NoError(t, err)
+
+	outputAbi, err := abi.JSON(strings.NewReader(byzcoinPrecompileAbiJSON))
+	require.NoError(t, err)
+	var result struct {
+		ContractID string
+		Value      []byte
+	}
+	require.NoError(t, outputAbi.Unpack(&result, "getInstance", output))
+	require.Equal(t, byzcoin.ContractDarcID, result.ContractID)
+	genesisDarc, err := darc.NewFromProtobuf(result.Value)
+	require.NoError(t, err)
+	require.Equal(t, bct.GenesisDarc.GetBaseID(), genesisDarc.GetBaseID())
+}
+
+func Test_TraceTransaction(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	// Initialize an account
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), a.Address)
+	require.NoError(t, err)
+
+	// Deploy a Candy contract
+	candyContract, err := NewEvmContract(
+		"Candy",
+		getContractData(t, "Candy", "abi"),
+		getContractData(t, "Candy", "bin"))
+	require.NoError(t, err)
+	_, candyInstance, err := bevmClient.Deploy(
+		txParams.GasLimit, txParams.GasPrice, 0, a, candyContract,
+		big.NewInt(100))
+	require.NoError(t, err)
+
+	// Eating too many candies is reverted, with a reason
+	trace, err := bevmClient.TraceTransaction(true,
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		candyInstance, "eatCandy", big.NewInt(101))
+	require.NoError(t, err)
+	require.False(t, trace.Success)
+	require.Equal(t, "error", trace.RevertReason)
+	require.Len(t, trace.Calls, 1)
+	require.Equal(t, "CALL", trace.Calls[0].Type)
+	require.Equal(t, candyInstance.Address.Bytes(), trace.Calls[0].To)
+	require.False(t, trace.Calls[0].Success)
+	require.NotEmpty(t, trace.Steps)
+	require.Equal(t, "REVERT", trace.Steps[len(trace.Steps)-1].Op)
+
+	// A successful transaction is traced without the opcodes
+	trace, err = bevmClient.TraceTransaction(false,
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		candyInstance, "eatCandy", big.NewInt(10))
+	require.NoError(t, err)
+	require.True(t, trace.Success)
+	require.True(t, trace.GasUsed > 0)
+	require.True(t, trace.Calls[0].Success)
+	require.Empty(t, trace.Steps)
+
+	// The stack of the opcodes is cut to its top
+	trace, err = bevmClient.TraceTransaction(true,
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		candyInstance, "eatCandy", big.NewInt(10))
+	require.NoError(t, err)
+	for _, step := range trace.Steps {
+		require.True(t, len(step.Stack) <= maxTraceStack)
+	}
+
+	// The gas limit is bounded
+	_, err = bevmClient.TraceTransaction(false,
+		estimateGasCap+1, txParams.GasPrice, 0, a,
+		candyInstance, "eatCandy", big.NewInt(10))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exceeds the maximum")
+
+	// The traced transactions were not applied
+	result, err := bevmClient.Call(a, candyInstance, "getRemainingCandies")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), result[0])
+}
+
+func Test_EstimateGas(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	// Initialize an account
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), a.Address)
+	require.NoError(t, err)
+
+	// Deploy a Candy contract with the estimated gas
+	candyContract, err := NewEvmContract(
+		"Candy",
+		getContractData(t, "Candy", "abi"),
+		getContractData(t, "Candy", "bin"))
+	require.NoError(t, err)
+	estimate, err := bevmClient.EstimateDeployGas(0, a, candyContract,
+		big.NewInt(100))
+	require.NoError(t, err)
+	require.True(t, estimate.Success)
+	require.True(t, estimate.Gas > 21000)
+	_, candyInstance, err := bevmClient.Deploy(
+		estimate.Gas, txParams.GasPrice, 0, a, candyContract,
+		big.NewInt(100))
+	require.NoError(t, err)
+
+	// Eat candies with the estimated gas
+	estimate, err = bevmClient.EstimateGas(0, a, candyInstance, "eatCandy",
+		big.NewInt(10))
+	require.NoError(t, err)
+	require.True(t, estimate.Success)
+	_, err = bevmClient.Transaction(
+		estimate.Gas, txParams.GasPrice, 0, a,
+		candyInstance, "eatCandy", big.NewInt(10))
+	require.NoError(t, err)
+
+	result, err := bevmClient.Call(a, candyInstance, "getRemainingCandies")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(90), result[0])
+
+	// Eating too many candies fails, with a reason
+	estimate, err = bevmClient.EstimateGas(0, a, candyInstance, "eatCandy",
+		big.NewInt(91))
+	require.NoError(t, err)
+	require.False(t, estimate.Success)
+	require.Equal(t, "error", estimate.RevertReason)
+}
+
 func Test_InvokeTokenContract(t *testing.T) {
 	// Create a new ledger and prepare for proper closing
 	bct := newBCTest(t)
@@ -392,6 +620,305 @@ func Test_InvokeTokenContract(t *testing.T) {
 	require.Equal(t, newB, result[0])
 }
 
+func Test_GetLogs(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	// Initialize two accounts
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	b, err := NewEvmAccount(testPrivateKeys[1])
+	require.NoError(t, err)
+
+	// Credit the owner account
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), a.Address)
+	require.NoError(t, err)
+
+	// No logs so far
+	logs, err := bevmClient.GetLogs(nil, nil, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, logs)
+
+	// Deploy an ERC20 Token contract, which emits a Transfer of the total
+	// supply to A
+	erc20Contract, err := NewEvmContract(
+		"ERC20Token",
+		getContractData(t, "ERC20Token", "abi"),
+		getContractData(t, "ERC20Token", "bin"))
+	require.NoError(t, err)
+	_, erc20Instance, err := bevmClient.Deploy(
+		txParams.GasLimit, txParams.GasPrice, 0, a, erc20Contract)
+	require.NoError(t, err)
+
+	// Transfer 100 tokens from A to B
+	_, err = bevmClient.Transaction(
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		erc20Instance, "transfer", b.Address, big.NewInt(100))
+	require.NoError(t, err)
+
+	// Both Transfer events are logged by the ERC20 contract
+	logs, err = bevmClient.GetLogs(&erc20Instance.Address, nil, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	require.True(t, logs[0].BlockIndex < logs[1].BlockIndex)
+
+	// Only the second one is to B
+	transferID := erc20Contract.Abi.Events["Transfer"].Id()
+	toB := common.BytesToHash(b.Address.Bytes())
+	logs, err = bevmClient.GetLogs(nil,
+		[]*common.Hash{&transferID, nil, &toB}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, erc20Instance.Address.Bytes(), logs[0].Address)
+	require.Equal(t, big.NewInt(100), new(big.Int).SetBytes(logs[0].Data))
+
+	// The block range restricts the logs
+	logs, err = bevmClient.GetLogs(nil, nil, logs[0].BlockIndex, 0)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	// Deleting the BEvm instance deletes its logs
+	err = bevmClient.Delete()
+	require.NoError(t, err)
+	resp, err := bct.Client.GetProof(LogsInstanceID(instanceID).Slice())
+	require.NoError(t, err)
+	require.False(t, resp.Proof.InclusionProof.Match(
+		LogsInstanceID(instanceID).Slice()))
+}
+
+func Test_GasConfig(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	// Initialize an account
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), a.Address)
+	require.NoError(t, err)
+
+	erc20Contract, err := NewEvmContract(
+		"ERC20Token",
+		getContractData(t, "ERC20Token", "abi"),
+		getContractData(t, "ERC20Token", "bin"))
+	require.NoError(t, err)
+
+	// By default, there are no limits and no price
+	config, err := bevmClient.GetGasConfig()
+	require.NoError(t, err)
+	require.Equal(t, GasConfig{}, *config)
+
+	// A transaction gas limit above the maximum is rejected
+	err = bevmClient.SetGasConfig(GasConfig{TxGasLimit: txParams.GasLimit})
+	require.NoError(t, err)
+	_, _, err = bevmClient.Deploy(
+		txParams.GasLimit+1, txParams.GasPrice, 0, a, erc20Contract)
+	require.Error(t, err)
+	_, erc20Instance, err := bevmClient.Deploy(
+		txParams.GasLimit, txParams.GasPrice, 0, a, erc20Contract)
+	require.NoError(t, err)
+
+	// Create a coin account to pay for the gas
+	mint := uint64(10 * txParams.GasLimit)
+	mintBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(mintBuf, mint)
+	spawnTx, err := sendByzCoinTx(bct.Client, bct.Signer, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(bct.GenesisDarc.GetBaseID()),
+		Spawn:      &byzcoin.Spawn{ContractID: contracts.ContractCoinID},
+	})
+	require.NoError(t, err)
+	coinID := spawnTx.Instructions[0].DeriveID("")
+	_, err = sendByzCoinTx(bct.Client, bct.Signer, byzcoin.Instruction{
+		InstanceID: coinID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contracts.ContractCoinID,
+			Command:    "mint",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: mintBuf}},
+		},
+	})
+	require.NoError(t, err)
+
+	// With a gas price, a transaction without coins is rejected
+	err = bevmClient.SetGasConfig(GasConfig{
+		GasPrice: byzcoin.Coin{Name: contracts.CoinName, Value: 1},
+	})
+	require.NoError(t, err)
+	_, err = bevmClient.Transaction(
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		erc20Instance, "transfer", a.Address, big.NewInt(1))
+	require.Error(t, err)
+
+	// The coin account pays for the gas used, and keeps the rest
+	bevmClient.SetCoinAccount(coinID)
+	_, err = bevmClient.Transaction(
+		txParams.GasLimit, txParams.GasPrice, 0, a,
+		erc20Instance, "transfer", a.Address, big.NewInt(1))
+	require.NoError(t, err)
+
+	resp, err := bct.Client.GetProofFromLatest(coinID.Slice())
+	require.NoError(t, err)
+	_, coinBuf, _, _, err := resp.Proof.KeyValue()
+	require.NoError(t, err)
+	var coin byzcoin.Coin
+	require.NoError(t, protobuf.Decode(coinBuf, &coin))
+	require.True(t, coin.Value < mint)
+	require.True(t, coin.Value > mint-txParams.GasLimit)
+}
+
+func Test_Bridge(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	b, err := NewEvmAccount(testPrivateKeys[1])
+	require.NoError(t, err)
+
+	// Create a coin account
+	mint := uint64(1000)
+	mintBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(mintBuf, mint)
+	spawnTx, err := sendByzCoinTx(bct.Client, bct.Signer, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(bct.GenesisDarc.GetBaseID()),
+		Spawn:      &byzcoin.Spawn{ContractID: contracts.ContractCoinID},
+	})
+	require.NoError(t, err)
+	coinID := spawnTx.Instructions[0].DeriveID("")
+	_, err = sendByzCoinTx(bct.Client, bct.Signer, byzcoin.Instruction{
+		InstanceID: coinID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contracts.ContractCoinID,
+			Command:    "mint",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: mintBuf}},
+		},
+	})
+	require.NoError(t, err)
+
+	coinValue := func() uint64 {
+		resp, err := bct.Client.GetProofFromLatest(coinID.Slice())
+		require.NoError(t, err)
+		_, coinBuf, _, _, err := resp.Proof.KeyValue()
+		require.NoError(t, err)
+		var coin byzcoin.Coin
+		require.NoError(t, protobuf.Decode(coinBuf, &coin))
+		return coin.Value
+	}
+
+	// The bridge is disabled by default
+	_, err = bevmClient.Deposit(coinID, 100, a.Address)
+	require.Error(t, err)
+
+	// Ether credited without coins can't be withdrawn as coins
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), b.Address)
+	require.NoError(t, err)
+
+	config := BridgeConfig{CoinName: contracts.CoinName, WeiPerCoin: 1e9}
+	err = bevmClient.SetBridgeConfig(config)
+	require.NoError(t, err)
+	bridgeConfig, err := bevmClient.GetBridgeConfig()
+	require.NoError(t, err)
+	require.Equal(t, config, *bridgeConfig)
+
+	// Deposit coins on the EVM account
+	_, err = bevmClient.Deposit(coinID, 100, a.Address)
+	require.NoError(t, err)
+	require.Equal(t, mint-100, coinValue())
+	balance, err := bevmClient.GetAccountBalance(a.Address)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100*1e9), balance)
+
+	_, err = bevmClient.Withdraw(b, coinID, 101)
+	require.Error(t, err)
+
+	// Only the owner of the EVM account can withdraw from it
+	bSigningForA := &EvmAccount{Address: a.Address, PrivateKey: b.PrivateKey}
+	_, err = bevmClient.Withdraw(bSigningForA, coinID, 40)
+	require.Error(t, err)
+
+	// The balance must cover the withdrawal
+	_, err = bevmClient.Withdraw(a, coinID, 101)
+	require.Error(t, err)
+
+	_, err = bevmClient.Withdraw(a, coinID, 40)
+	require.NoError(t, err)
+	require.Equal(t, mint-60, coinValue())
+	balance, err = bevmClient.GetAccountBalance(a.Address)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(60*1e9), balance)
+
+	// A withdrawal signature cannot be replayed
+	a.Nonce--
+	_, err = bevmClient.Withdraw(a, coinID, 40)
+	require.Error(t, err)
+	a.Nonce++
+
+	// A withdrawal cannot be redirected to another coin instance
+	spawnTx, err = sendByzCoinTx(bct.Client, bct.Signer, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(bct.GenesisDarc.GetBaseID()),
+		Spawn:      &byzcoin.Spawn{ContractID: contracts.ContractCoinID},
+	})
+	require.NoError(t, err)
+	otherID := spawnTx.Instructions[0].DeriveID("")
+	amountBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amountBuf, 10)
+	signature, err := crypto.Sign(
+		WithdrawHash(instanceID, coinID, 10, a.Nonce), a.PrivateKey)
+	require.NoError(t, err)
+	_, err = sendByzCoinTx(bct.Client, bct.Signer, byzcoin.Instruction{
+		InstanceID: instanceID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractBEvmID,
+			Command:    "withdraw",
+			Args: byzcoin.Arguments{
+				{Name: "address", Value: a.Address.Bytes()},
+				{Name: "amount", Value: amountBuf},
+				{Name: "destination", Value: otherID.Slice()},
+				{Name: "signature", Value: signature},
+			},
+		},
+	})
+	require.Error(t, err)
+
+	// The configuration is fixed once coins were deposited
+	err = bevmClient.SetBridgeConfig(BridgeConfig{
+		CoinName: contracts.CoinName, WeiPerCoin: 1})
+	require.Error(t, err)
+
+	// Accounts can no longer be credited without coins, and the
+	// withdrawals can't take more than the deposited coins
+	_, err = bevmClient.CreditAccount(big.NewInt(100*1e9), a.Address)
+	require.Error(t, err)
+	_, err = bevmClient.Withdraw(a, coinID, 60)
+	require.NoError(t, err)
+	require.Equal(t, mint, coinValue())
+}
+
 func Test_InvokeLoanContract(t *testing.T) {
 	//Preparing ledger
 	bct := newBCTest(t)
@@ -527,7 +1054,15 @@ func newBCTest(t *testing.T) (out *byzcoin.BCTest) {
 	out.AddGenesisRules("spawn:bevm",
 		"invoke:bevm.credit",
 		"invoke:bevm.transaction",
-		"delete:bevm")
+		"invoke:bevm.gasConfig",
+		"invoke:bevm.bridgeConfig",
+		"invoke:bevm.deposit",
+		"invoke:bevm.withdraw",
+		"delete:bevm",
+		"spawn:coin",
+		"invoke:coin.mint",
+		"invoke:coin.fetch",
+		"invoke:coin.store")
 	out.CreateByzCoin()
 	return out
 }
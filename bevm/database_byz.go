@@ -7,6 +7,7 @@ import (
 
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
 	"golang.org/x/xerrors"
 
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -78,6 +79,22 @@ func (db *StateTrieByzDatabase) getBEvmValue(key []byte) ([]byte, error) {
 	return value, nil
 }
 
+// Read a ByzCoin instance from the StateTrie
+func (db *StateTrieByzDatabase) readInstance(id byzcoin.InstanceID) ([]byte,
+	string, error) {
+	value, _, contractID, _, err := db.rst.GetValues(id[:])
+	if err != nil {
+		return nil, "", xerrors.Errorf("failed to retrieve instance: %v", err)
+	}
+
+	return value, contractID, nil
+}
+
+// Check the version of the StateTrie for the precompiled contract
+func (db *StateTrieByzDatabase) precompileEnabled() bool {
+	return db.rst.GetVersion() >= byzcoin.VersionBEvmPrecompile
+}
+
 // Has implements Has()
 func (db *StateTrieByzDatabase) Has(key []byte) (bool, error) {
 	_, err := db.getBEvmValue(key)
@@ -155,6 +172,41 @@ func (db *ClientByzDatabase) getBEvmValue(key []byte) ([]byte, error) {
 	return value, nil
 }
 
+// Read a ByzCoin instance from its verified proof
+func (db *ClientByzDatabase) readInstance(id byzcoin.InstanceID) ([]byte,
+	string, error) {
+	proofResponse, err := db.client.GetProofFromLatest(id[:])
+	if err != nil {
+		return nil, "", xerrors.Errorf("failed to retrieve instance: %v", err)
+	}
+
+	value, contractID, _, err := proofResponse.Proof.Get(id[:])
+	if err != nil {
+		return nil, "", xerrors.Errorf("failed to get instance value: %v",
+			err)
+	}
+
+	return value, contractID, nil
+}
+
+// Check the version of the latest block for the precompiled contract
+func (db *ClientByzDatabase) precompileEnabled() bool {
+	proofResponse, err := db.client.GetProofFromLatest(db.bevmIID[:])
+	if err != nil {
+		log.Lvlf2("failed to retrieve the latest block: %v", err)
+		return false
+	}
+
+	var header byzcoin.DataHeader
+	err = protobuf.Decode(proofResponse.Proof.Latest.Data, &header)
+	if err != nil {
+		log.Lvlf2("failed to decode the latest block header: %v", err)
+		return false
+	}
+
+	return header.Version >= byzcoin.VersionBEvmPrecompile
+}
+
 // Has implements Has()
 func (db *ClientByzDatabase) Has(key []byte) (bool, error) {
 	_, err := db.getBEvmValue(key)
@@ -233,6 +285,23 @@ func NewServerByzDatabase(bevmIID byzcoin.InstanceID, keyList []string,
 	}, nil
 }
 
+// Read a ByzCoin instance from the StateTrie, as it was before the EVM
+// execution
+func (db *ServerByzDatabase) readInstance(id byzcoin.InstanceID) ([]byte,
+	string, error) {
+	value, _, contractID, _, err := db.roStateTrie.GetValues(id[:])
+	if err != nil {
+		return nil, "", xerrors.Errorf("failed to retrieve instance: %v", err)
+	}
+
+	return value, contractID, nil
+}
+
+// Check the version of the StateTrie for the precompiled contract
+func (db *ServerByzDatabase) precompileEnabled() bool {
+	return db.roStateTrie.GetVersion() >= byzcoin.VersionBEvmPrecompile
+}
+
 // Dump returns the list of StateChanges to apply to ByzCoin as well as the
 // list of keys in the Ethereum state database, representing the modifications
 // that the EVM performed on its state database
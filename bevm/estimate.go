@@ -0,0 +1,76 @@
+package bevm
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"golang.org/x/xerrors"
+)
+
+// Highest gas limit tried when estimating the gas of a transaction, if
+// neither the request nor the instance sets one
+const estimateGasCap = uint64(1e8)
+
+// Estimate the lowest gas limit with which a transaction succeeds, like
+// eth_estimateGas, by running it on the state database with different gas
+// limits. The changes made to the state database are reverted.
+func estimateGas(stateDb *state.StateDB, timestamp uint64,
+	from common.Address, to *common.Address, amount *big.Int, data []byte,
+	gasCap uint64) (*EstimateGasResponse, error) {
+	// Run the transaction with the given gas limit
+	run := func(gasLimit uint64) (bool, []byte, uint64, error) {
+		msg := types.NewMessage(from, to, stateDb.GetNonce(from), amount,
+			gasLimit, big.NewInt(0), data, false)
+
+		snapshot := stateDb.Snapshot()
+		defer stateDb.RevertToSnapshot(snapshot)
+
+		var ret []byte
+		var gasUsed uint64
+		var failed bool
+		err := runWithReader(stateDb, func() (err error) {
+			context := core.NewEVMContext(msg, evmHeader(timestamp), nil,
+				&nilAddress)
+			evm := vm.NewEVM(context, stateDb, getChainConfig(),
+				getVMConfig())
+			gp := new(core.GasPool).AddGas(gasLimit)
+			ret, gasUsed, failed, err = core.ApplyMessage(evm, msg, gp)
+			return
+		})
+
+		return !failed, ret, gasUsed, err
+	}
+
+	// The transaction must succeed with the highest gas limit
+	success, ret, gasUsed, err := run(gasCap)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to execute EVM transaction: %v",
+			err)
+	}
+	if !success {
+		return &EstimateGasResponse{
+			Gas:          gasUsed,
+			RevertReason: revertReason(ret),
+		}, nil
+	}
+
+	// Because of refunds and of the gas kept by the callers of nested calls,
+	// the gas used is not always enough: find the lowest limit that works.
+	lo, hi := params.TxGas-1, gasCap
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		success, _, _, err = run(mid)
+		if err == nil && success {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	return &EstimateGasResponse{Gas: hi, Success: true}, nil
+}
@@ -0,0 +1,111 @@
+package bevm
+
+import (
+	"math"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"golang.org/x/xerrors"
+)
+
+// Gas available to the transactions when there is no block gas limit
+const unlimitedGas = uint64(1e18)
+
+// GasConfig bounds the cost of the EVM transactions of a BEvm instance, and
+// sets the price that users pay in ByzCoin coins for the gas they use.
+type GasConfig struct {
+	// TxGasLimit is the maximum gas limit of a transaction, 0 for no limit.
+	TxGasLimit uint64
+	// BlockGasLimit is the maximum gas used by all the transactions of a
+	// ByzCoin block, 0 for no limit.
+	BlockGasLimit uint64
+	// GasPrice is the coin and the amount of it charged for every unit of
+	// gas used. A zero value lets the transactions run for free.
+	GasPrice byzcoin.Coin
+}
+
+// Check that a transaction with the given gas limit can run in the given
+// block, as far as the configured limits are concerned, and return the gas
+// left in the block.
+func (st *State) blockGasLeft(txGasLimit uint64, blockIndex int) (uint64,
+	error) {
+	config := st.GasConfig
+	if config.TxGasLimit > 0 && txGasLimit > config.TxGasLimit {
+		return 0, xerrors.Errorf("transaction gas limit %d exceeds the "+
+			"maximum of %d", txGasLimit, config.TxGasLimit)
+	}
+
+	if config.BlockGasLimit == 0 {
+		return unlimitedGas, nil
+	}
+
+	blockGasUsed := uint64(0)
+	if st.BlockIndex == blockIndex {
+		blockGasUsed = st.BlockGasUsed
+	}
+	if blockGasUsed >= config.BlockGasLimit {
+		return 0, xerrors.Errorf("block gas limit of %d reached",
+			config.BlockGasLimit)
+	}
+
+	return config.BlockGasLimit - blockGasUsed, nil
+}
+
+// Return the amount of coins to pay for the given gas
+func (config GasConfig) fee(gas uint64) (uint64, error) {
+	price := config.GasPrice.Value
+	if price > 0 && gas > math.MaxUint64/price {
+		return 0, xerrors.Errorf("fee for %d gas overflows", gas)
+	}
+
+	return gas * price, nil
+}
+
+// Check that the coins given to an instruction can pay for the given gas
+func (config GasConfig) checkCoins(coins []byzcoin.Coin, gas uint64) error {
+	fee, err := config.fee(gas)
+	if err != nil {
+		return err
+	}
+	if fee == 0 {
+		return nil
+	}
+
+	for _, coin := range coins {
+		if coin.Name.Equal(config.GasPrice.Name) && coin.Value >= fee {
+			return nil
+		}
+	}
+
+	return xerrors.Errorf("not enough coins to pay for %d gas: needed %d",
+		gas, fee)
+}
+
+// Charge the fee of the given gas on the coins given to an instruction
+func (config GasConfig) charge(coins []byzcoin.Coin, gas uint64) error {
+	fee, err := config.fee(gas)
+	if err != nil {
+		return err
+	}
+	if fee == 0 {
+		return nil
+	}
+
+	for i := range coins {
+		if coins[i].Name.Equal(config.GasPrice.Name) &&
+			coins[i].Value >= fee {
+			return coins[i].SafeSub(fee)
+		}
+	}
+
+	return xerrors.Errorf("not enough coins to pay for %d gas: needed %d",
+		gas, fee)
+}
+
+// Record the gas used by a transaction in the given block
+func (st *State) addBlockGas(blockIndex int, gasUsed uint64) {
+	if st.BlockIndex != blockIndex {
+		st.BlockIndex = blockIndex
+		st.BlockGasUsed = 0
+	}
+	st.BlockGasUsed += gasUsed
+}
@@ -0,0 +1,64 @@
+package bevm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+func TestState_BlockGasLeft(t *testing.T) {
+	st := &State{GasConfig: GasConfig{TxGasLimit: 100, BlockGasLimit: 150}}
+
+	_, err := st.blockGasLeft(101, 1)
+	require.Error(t, err)
+
+	left, err := st.blockGasLeft(100, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), left)
+
+	st.addBlockGas(1, 100)
+	left, err = st.blockGasLeft(100, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), left)
+
+	st.addBlockGas(1, 50)
+	_, err = st.blockGasLeft(100, 1)
+	require.Error(t, err)
+
+	// The gas used is reset in a new block
+	left, err = st.blockGasLeft(100, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), left)
+	st.addBlockGas(2, 10)
+	require.Equal(t, uint64(10), st.BlockGasUsed)
+
+	// Without a block gas limit, there is always gas left
+	st.GasConfig.BlockGasLimit = 0
+	left, err = st.blockGasLeft(100, 2)
+	require.NoError(t, err)
+	require.Equal(t, unlimitedGas, left)
+}
+
+func TestGasConfig_Charge(t *testing.T) {
+	name := byzcoin.NewInstanceID([]byte("gas"))
+	config := GasConfig{GasPrice: byzcoin.Coin{Name: name, Value: 2}}
+	coins := []byzcoin.Coin{
+		{Name: byzcoin.NewInstanceID([]byte("other")), Value: 100},
+		{Name: name, Value: 100},
+	}
+
+	require.NoError(t, config.checkCoins(coins, 50))
+	require.Error(t, config.checkCoins(coins, 51))
+
+	require.NoError(t, config.charge(coins, 20))
+	require.Equal(t, uint64(100), coins[0].Value)
+	require.Equal(t, uint64(60), coins[1].Value)
+	require.Error(t, config.charge(coins, 31))
+
+	// Without a price, nothing is charged
+	require.NoError(t, GasConfig{}.charge(nil, 1000))
+
+	_, err := config.fee(1 << 63)
+	require.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package bevm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractBEvmLogsID identifies the ByzCoin contract holding the EVM logs and
+// the receipt of the last transaction of a BEvm instance. Like the BEvm
+// values, it is only a byproduct of BEvm transactions. The previous versions
+// of the instance, kept by ByzCoin, are the index of all the logs and
+// receipts.
+var ContractBEvmLogsID = "bevm_logs"
+
+// LogsInstanceID returns the ID of the logs instance of a BEvm instance.
+func LogsInstanceID(bevmID byzcoin.InstanceID) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write(bevmID[:])
+	h.Write([]byte("logs"))
+
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+// Build the state change storing the logs and the receipt of an Ethereum
+// transaction
+func logsStateChange(rst byzcoin.ReadOnlyStateTrie, bevmID byzcoin.InstanceID,
+	darcID darc.ID, tx *types.Transaction, receipt *types.Receipt) (
+	*byzcoin.StateChange, error) {
+	signer := types.MakeSigner(getChainConfig(), big.NewInt(0))
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve the sender of the "+
+			"EVM transaction: %v", err)
+	}
+
+	evmLogs := EvmLogs{
+		Receipt: EvmReceipt{
+			TxHash:  tx.Hash().Bytes(),
+			From:    from.Bytes(),
+			Success: receipt.Status == types.ReceiptStatusSuccessful,
+			GasUsed: receipt.GasUsed,
+		},
+	}
+	if tx.To() != nil {
+		evmLogs.Receipt.To = tx.To().Bytes()
+	} else {
+		evmLogs.Receipt.ContractAddress = receipt.ContractAddress.Bytes()
+	}
+
+	for i, logEntry := range receipt.Logs {
+		evmLog := EvmLog{
+			Address: logEntry.Address.Bytes(),
+			Data:    logEntry.Data,
+			TxHash:  tx.Hash().Bytes(),
+			Index:   i,
+		}
+		for _, topic := range logEntry.Topics {
+			evmLog.Topics = append(evmLog.Topics, topic.Bytes())
+		}
+		evmLogs.Logs = append(evmLogs.Logs, evmLog)
+	}
+
+	value, err := protobuf.Encode(&evmLogs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode EVM logs: %v", err)
+	}
+
+	logsID := LogsInstanceID(bevmID)
+	action := byzcoin.Update
+	if _, _, _, _, err := rst.GetValues(logsID[:]); err != nil {
+		action = byzcoin.Create
+	}
+	sc := byzcoin.NewStateChange(action, logsID, ContractBEvmLogsID, value,
+		darcID)
+
+	return &sc, nil
+}
+
+// Check whether a log matches the filter of a request
+func (req *GetLogsRequest) matches(evmLog EvmLog) bool {
+	if len(req.Address) > 0 && !bytes.Equal(req.Address, evmLog.Address) {
+		return false
+	}
+
+	for i, topic := range req.Topics {
+		if len(topic) == 0 {
+			continue
+		}
+		if i >= len(evmLog.Topics) || !bytes.Equal(topic, evmLog.Topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check whether a block index is in the range of a request
+func (req *GetLogsRequest) inRange(blockIndex int) bool {
+	return blockIndex >= req.FromBlock &&
+		(req.ToBlock == 0 || blockIndex <= req.ToBlock)
+}
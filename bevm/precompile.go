@@ -0,0 +1,130 @@
+package bevm
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"golang.org/x/xerrors"
+)
+
+// ByzCoinPrecompileAddress is the address of the precompiled contract giving
+// read access to the ByzCoin instances to the EVM contracts. It is called
+// with the 32-byte ID of an instance, and returns its contract ID and its
+// value, ABI-encoded as (string, bytes). From Solidity:
+//
+//	(bool ok, bytes memory ret) = address(0xbc).staticcall(
+//	    abi.encode(instanceID));
+//	(string memory contractID, bytes memory value) =
+//	    abi.decode(ret, (string, bytes));
+//
+// The call fails if the instance does not exist. The contract only exists
+// from byzcoin.VersionBEvmPrecompile on.
+var ByzCoinPrecompileAddress = common.HexToAddress(
+	"0x00000000000000000000000000000000000000bc")
+
+// Gas charged for reading a ByzCoin instance
+const byzcoinPrecompileGas = 5000
+
+// ABI of the output of the precompiled contract
+const byzcoinPrecompileAbiJSON = `` +
+	`[` +
+	`  {` +
+	`    "constant": true,` +
+	`    "inputs": [],` +
+	`    "name": "getInstance",` +
+	`    "outputs": [` +
+	`      {` +
+	`        "name": "contractID",` +
+	`        "type": "string"` +
+	`      },` +
+	`      {` +
+	`        "name": "value",` +
+	`        "type": "bytes"` +
+	`      }` +
+	`    ],` +
+	`    "payable": false,` +
+	`    "stateMutability": "view",` +
+	`    "type": "function"` +
+	`  }` +
+	`]`
+
+// instanceReader is implemented by the EVM state databases able to read the
+// ByzCoin instances, from a state trie on the server or with verified proofs
+// on the client.
+type instanceReader interface {
+	readInstance(id byzcoin.InstanceID) (value []byte, contractID string,
+		err error)
+	// precompileEnabled returns whether the ByzCoin version has the
+	// precompiled contract.
+	precompileEnabled() bool
+}
+
+// The precompiled contracts of go-ethereum 1.8 are looked up in a map of the
+// package and only get their input, there is no way to give them to a single
+// EVM. The precompiled contract is therefore only in the map during the
+// executions that enable it, with their reader, which are serialized. The
+// other executions run concurrently, and never see it.
+var precompiles sync.RWMutex
+
+// Run an EVM execution on a state database, letting the precompiled contract
+// read the ByzCoin instances through it if its ByzCoin version allows it
+func runWithReader(stateDb *state.StateDB, f func() error) error {
+	reader, ok := stateDb.Database().TrieDB().DiskDB().(instanceReader)
+	if !ok || !reader.precompileEnabled() {
+		precompiles.RLock()
+		defer precompiles.RUnlock()
+		return f()
+	}
+
+	precompiles.Lock()
+	defer precompiles.Unlock()
+	vm.PrecompiledContractsByzantium[ByzCoinPrecompileAddress] =
+		&byzcoinPrecompile{reader: reader}
+	defer delete(vm.PrecompiledContractsByzantium, ByzCoinPrecompileAddress)
+
+	return f()
+}
+
+// byzcoinPrecompile is the precompiled contract reading ByzCoin instances
+// with the reader of an EVM execution
+type byzcoinPrecompile struct {
+	reader instanceReader
+}
+
+// RequiredGas implements vm.PrecompiledContract.RequiredGas()
+func (p *byzcoinPrecompile) RequiredGas(input []byte) uint64 {
+	return byzcoinPrecompileGas
+}
+
+// Run implements vm.PrecompiledContract.Run()
+func (p *byzcoinPrecompile) Run(input []byte) ([]byte, error) {
+	if len(input) != len(byzcoin.InstanceID{}) {
+		return nil, xerrors.Errorf("expected a %d-byte instance ID, got %d "+
+			"bytes", len(byzcoin.InstanceID{}), len(input))
+	}
+
+	value, contractID, err := p.reader.readInstance(
+		byzcoin.NewInstanceID(input))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read ByzCoin instance: %v", err)
+	}
+
+	outputAbi, err := abi.JSON(strings.NewReader(byzcoinPrecompileAbiJSON))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode ABI for ByzCoin "+
+			"precompiled contract: %v", err)
+	}
+
+	output, err := outputAbi.Methods["getInstance"].Outputs.Pack(contractID,
+		value)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to pack ByzCoin instance: %v", err)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,138 @@
+package bevm
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"golang.org/x/xerrors"
+)
+
+type testInstanceReader map[byzcoin.InstanceID]string
+
+func (r testInstanceReader) readInstance(id byzcoin.InstanceID) ([]byte,
+	string, error) {
+	value, ok := r[id]
+	if !ok {
+		return nil, "", xerrors.New("no such instance")
+	}
+
+	return []byte(value), "value", nil
+}
+
+func (r testInstanceReader) precompileEnabled() bool {
+	return true
+}
+
+// testOldReader is a reader of a ByzCoin version without the precompiled
+// contract.
+type testOldReader struct {
+	testInstanceReader
+}
+
+func (r testOldReader) precompileEnabled() bool {
+	return false
+}
+
+type testReaderDatabase struct {
+	*MemDatabase
+	instanceReader
+}
+
+func callPrecompile(t *testing.T, reader instanceReader,
+	input []byte) ([]byte, error) {
+	memDb, err := NewMemDatabase([]byte{})
+	require.NoError(t, err)
+	sdb, err := state.New(common.Hash{},
+		state.NewDatabase(testReaderDatabase{memDb, reader}))
+	require.NoError(t, err)
+
+	return CallEVM(nilAddress, ByzCoinPrecompileAddress, input, sdb, 0)
+}
+
+func TestPrecompile_ReadInstance(t *testing.T) {
+	id := byzcoin.NewInstanceID([]byte("instance"))
+	reader := testInstanceReader{id: "hello"}
+
+	output, err := callPrecompile(t, reader, id.Slice())
+	require.NoError(t, err)
+
+	outputAbi, err := abi.JSON(strings.NewReader(byzcoinPrecompileAbiJSON))
+	require.NoError(t, err)
+	var result struct {
+		ContractID string
+		Value      []byte
+	}
+	require.NoError(t, outputAbi.Unpack(&result, "getInstance", output))
+	require.Equal(t, "value", result.ContractID)
+	require.Equal(t, []byte("hello"), result.Value)
+
+	// Unknown instances and wrong inputs fail the call
+	_, err = callPrecompile(t, reader,
+		byzcoin.NewInstanceID([]byte("unknown")).Slice())
+	require.Error(t, err)
+	_, err = callPrecompile(t, reader, []byte("short"))
+	require.Error(t, err)
+}
+
+func TestPrecompile_NoReader(t *testing.T) {
+	id := byzcoin.NewInstanceID([]byte("instance"))
+	reader := testInstanceReader{id: "hello"}
+
+	// Without a reader, or before its version, the address is an empty
+	// account
+	sdb, err := newEvmMemDb()
+	require.NoError(t, err)
+	output, err := CallEVM(nilAddress, ByzCoinPrecompileAddress,
+		id.Slice(), sdb, 0)
+	require.NoError(t, err)
+	require.Empty(t, output)
+
+	output, err = callPrecompile(t, testOldReader{reader}, id.Slice())
+	require.NoError(t, err)
+	require.Empty(t, output)
+
+	// Outside of runWithReader, the precompile is not installed
+	evm := vm.NewEVM(getContext(0), sdb, getChainConfig(), getVMConfig())
+	output, _, err = evm.Call(vm.AccountRef(common.Address{}),
+		ByzCoinPrecompileAddress, id.Slice(), 1e6, big.NewInt(0))
+	require.NoError(t, err)
+	require.Empty(t, output)
+	_, ok := vm.PrecompiledContractsByzantium[ByzCoinPrecompileAddress]
+	require.False(t, ok)
+}
+
+func TestPrecompile_Concurrent(t *testing.T) {
+	id := byzcoin.NewInstanceID([]byte("instance"))
+	readers := []instanceReader{
+		testInstanceReader{id: "first"},
+		testInstanceReader{id: "second"},
+		testOldReader{testInstanceReader{id: "old"}},
+	}
+
+	// Every execution reads through its own reader
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(reader instanceReader) {
+			defer wg.Done()
+			output, err := callPrecompile(t, reader, id.Slice())
+			require.NoError(t, err)
+			if !reader.precompileEnabled() {
+				require.Empty(t, output)
+				return
+			}
+			expected, _, err := reader.readInstance(id)
+			require.NoError(t, err)
+			require.Contains(t, string(output), string(expected))
+		}(readers[i%len(readers)])
+	}
+	wg.Wait()
+}
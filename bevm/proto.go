@@ -20,3 +20,159 @@ type ViewCallRequest struct {
 type ViewCallResponse struct {
 	Result []byte
 }
+
+// EvmLog is a log entry emitted by an EVM contract.
+type EvmLog struct {
+	// Address of the contract that emitted the log.
+	Address []byte
+	Topics  [][]byte
+	Data    []byte
+	// TxHash is the hash of the Ethereum transaction.
+	TxHash []byte
+	// BlockIndex is the index of the ByzCoin block of the transaction. It is
+	// only set in GetLogsResponse.
+	BlockIndex int
+	// Index is the position of the log in the logs of the transaction.
+	Index int
+}
+
+// EvmReceipt is the receipt of an Ethereum transaction.
+type EvmReceipt struct {
+	TxHash []byte
+	// From is the sender of the transaction, and To its recipient, empty for
+	// a contract creation.
+	From    []byte
+	To      []byte
+	Success bool
+	GasUsed uint64
+	// ContractAddress is the address of the contract created by the
+	// transaction, empty if there is none.
+	ContractAddress []byte
+}
+
+// EvmLogs is the value of a BEvm logs instance: the logs and the receipt of
+// the last transaction.
+type EvmLogs struct {
+	Logs    []EvmLog
+	Receipt EvmReceipt
+}
+
+// GetLogsRequest is a request for the logs emitted by the EVM contracts of a
+// BEvm instance.
+type GetLogsRequest struct {
+	ByzCoinID      []byte
+	BEvmInstanceID []byte
+	// Address of the contract, empty for all the contracts.
+	Address []byte
+	// Topics every log must have at the same position, an empty topic
+	// matches any topic.
+	Topics [][]byte
+	// FromBlock and ToBlock are the range of the block indexes, ToBlock
+	// being 0 for no upper limit.
+	FromBlock int
+	ToBlock   int
+}
+
+// GetLogsResponse is the response to GetLogsRequest, containing the logs in
+// the order they were emitted.
+type GetLogsResponse struct {
+	Logs []EvmLog
+}
+
+// GetReceiptRequest is a request for the receipt of an Ethereum transaction
+// applied by a BEvm instance.
+type GetReceiptRequest struct {
+	ByzCoinID      []byte
+	BEvmInstanceID []byte
+	TxHash         []byte
+}
+
+// GetReceiptResponse is the response to GetReceiptRequest.
+type GetReceiptResponse struct {
+	// Receipt is nil if the transaction is unknown.
+	Receipt *EvmReceipt
+	// Logs are the logs emitted by the transaction.
+	Logs []EvmLog
+	// BlockIndex is the index of the ByzCoin block of the transaction.
+	BlockIndex int
+}
+
+// TraceTransactionRequest is a request to trace the execution of a signed
+// Ethereum transaction, without applying it.
+type TraceTransactionRequest struct {
+	ByzCoinID      []byte
+	BEvmInstanceID []byte
+	// Transaction is the signed Ethereum transaction, encoded in JSON.
+	Transaction []byte
+	// Opcodes asks for the trace of every executed opcode, on top of the
+	// trace of the calls.
+	Opcodes bool
+}
+
+// TraceTransactionResponse is the response to TraceTransactionRequest.
+type TraceTransactionResponse struct {
+	// Success is false if the transaction was reverted.
+	Success bool
+	GasUsed uint64
+	Output  []byte
+	// RevertReason is the reason given to revert(), if any.
+	RevertReason string
+	// Calls are the calls made by the transaction, starting with the
+	// transaction itself.
+	Calls []TraceCall
+	// Steps are the executed opcodes, if they were asked for.
+	Steps []TraceStep
+}
+
+// TraceCall is a call or a contract creation in a traced transaction.
+type TraceCall struct {
+	// Depth is 0 for the transaction, and increases with nested calls.
+	Depth int
+	// Type is the opcode of the call, like CALL or CREATE.
+	Type    string
+	From    []byte
+	To      []byte
+	Input   []byte
+	Gas     uint64
+	Success bool
+}
+
+// TraceStep is an opcode executed by a traced transaction.
+type TraceStep struct {
+	Pc      uint64
+	Op      string
+	Gas     uint64
+	GasCost uint64
+	Depth   int
+	// Stack holds the top of the stack before the opcode, at most 8 values,
+	// the top being last.
+	Stack [][]byte
+	Error string
+}
+
+// EstimateGasRequest is a request to estimate the gas needed by a
+// transaction, without applying it.
+type EstimateGasRequest struct {
+	ByzCoinID      []byte
+	BEvmInstanceID []byte
+	AccountAddress []byte
+	// ContractAddress is empty for a contract deployment.
+	ContractAddress []byte
+	Amount          uint64
+	CallData        []byte
+	// GasLimit is the highest gas limit to try. If 0 or higher than the
+	// transaction gas limit of the instance, or a default one if unset, the
+	// latter is used.
+	GasLimit uint64
+}
+
+// EstimateGasResponse is the response to EstimateGasRequest.
+type EstimateGasResponse struct {
+	// Gas is the lowest gas limit with which the transaction succeeds, or
+	// the gas it used with the highest limit if it failed.
+	Gas     uint64
+	Success bool
+	// RevertReason is the reason given to revert() if the transaction
+	// failed.
+	RevertReason string
+}
@@ -0,0 +1,294 @@
+package bevm
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"golang.org/x/xerrors"
+)
+
+// NewRPCHandler returns an HTTP handler serving a subset of the Ethereum
+// JSON-RPC API on top of a BEvm client, so that Ethereum tools can target the
+// BEvm instance. The ByzCoin block indexes are used as Ethereum block
+// numbers, and only the latest EVM state is available.
+func NewRPCHandler(client *Client) (http.Handler, error) {
+	server := rpc.NewServer()
+
+	err := server.RegisterName("eth", &ethAPI{
+		client:      client,
+		blockHashes: map[int]common.Hash{},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to register the eth "+
+			"JSON-RPC API: %v", err)
+	}
+
+	err = server.RegisterName("net", &netAPI{})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to register the net "+
+			"JSON-RPC API: %v", err)
+	}
+
+	return server, nil
+}
+
+// NewAuthHandler returns an HTTP handler serving the requests of the given
+// handler that carry the token in an "Authorization: Bearer <token>" header,
+// and refusing the others.
+func NewAuthHandler(handler http.Handler, token string) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// netAPI implements the net_* JSON-RPC methods
+type netAPI struct{}
+
+// Version implements net_version
+func (api *netAPI) Version() string {
+	return getChainConfig().ChainID.String()
+}
+
+// ethAPI implements the eth_* JSON-RPC methods. Its exported methods are all
+// served by the JSON-RPC server.
+type ethAPI struct {
+	client *Client
+
+	// Hashes of the ByzCoin blocks already retrieved, by index
+	blockHashesLock sync.Mutex
+	blockHashes     map[int]common.Hash
+}
+
+// callArgs are the arguments of eth_call
+type callArgs struct {
+	From *common.Address `json:"from"`
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+// filterQuery is the filter of eth_getLogs. Only one address and one topic
+// per position are supported.
+type filterQuery struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	Address   *common.Address  `json:"address"`
+	Topics    []*common.Hash   `json:"topics"`
+}
+
+// ChainId implements eth_chainId
+func (api *ethAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(getChainConfig().ChainID)
+}
+
+// BlockNumber implements eth_blockNumber
+func (api *ethAPI) BlockNumber() (hexutil.Uint64, error) {
+	index, err := api.latestBlockIndex()
+	if err != nil {
+		return 0, err
+	}
+
+	return hexutil.Uint64(index), nil
+}
+
+// GetTransactionCount implements eth_getTransactionCount, on the latest state
+func (api *ethAPI) GetTransactionCount(address common.Address,
+	block rpc.BlockNumber) (hexutil.Uint64, error) {
+	stateDb, err := getEvmDb(api.client.bcClient, api.client.instanceID)
+	if err != nil {
+		return 0, xerrors.Errorf("failed to retrieve EVM state: %v", err)
+	}
+
+	return hexutil.Uint64(stateDb.GetNonce(address)), nil
+}
+
+// SendRawTransaction implements eth_sendRawTransaction. It returns once the
+// transaction is included in a ByzCoin block.
+func (api *ethAPI) SendRawTransaction(encodedTx hexutil.Bytes) (common.Hash,
+	error) {
+	return api.client.SendRawTransaction(encodedTx)
+}
+
+// Call implements eth_call, on the latest state
+func (api *ethAPI) Call(args callArgs, block rpc.BlockNumber) (hexutil.Bytes,
+	error) {
+	if args.To == nil {
+		return nil, xerrors.New("eth_call without a contract address is " +
+			"not supported")
+	}
+
+	from := common.Address{}
+	if args.From != nil {
+		from = *args.From
+	}
+
+	response, err := api.client.viewCall(api.client.bcClient.Roster.List[0],
+		api.client.bcClient.ID, api.client.instanceID, from.Bytes(),
+		args.To.Bytes(), args.Data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to call EVM: %v", err)
+	}
+
+	return response.Result, nil
+}
+
+// GetLogs implements eth_getLogs
+func (api *ethAPI) GetLogs(query filterQuery) ([]*types.Log, error) {
+	fromBlock, err := api.blockIndex(query.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	// A block range up to the latest block has no upper limit
+	toBlock := 0
+	if query.ToBlock != nil && *query.ToBlock >= 0 {
+		toBlock = int(*query.ToBlock)
+	}
+
+	evmLogs, err := api.client.GetLogs(query.Address, query.Topics,
+		fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ethLogs(evmLogs)
+}
+
+// GetTransactionReceipt implements eth_getTransactionReceipt. The receipt is
+// nil if the transaction is unknown.
+func (api *ethAPI) GetTransactionReceipt(txHash common.Hash) (
+	map[string]interface{}, error) {
+	response, err := api.client.GetReceipt(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if response.Receipt == nil {
+		return nil, nil
+	}
+
+	logs, err := api.ethLogs(response.Logs)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash, err := api.blockHash(response.BlockIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	bloom := types.BytesToBloom(types.LogsBloom(logs).Bytes())
+
+	receipt := response.Receipt
+	status := hexutil.Uint(types.ReceiptStatusFailed)
+	if receipt.Success {
+		status = hexutil.Uint(types.ReceiptStatusSuccessful)
+	}
+
+	fields := map[string]interface{}{
+		"transactionHash":   txHash,
+		"transactionIndex":  hexutil.Uint(0),
+		"blockHash":         blockHash,
+		"blockNumber":       hexutil.Uint64(response.BlockIndex),
+		"from":              common.BytesToAddress(receipt.From),
+		"to":                nil,
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.GasUsed),
+		"contractAddress":   nil,
+		"logs":              logs,
+		"logsBloom":         bloom,
+		"status":            status,
+	}
+	if len(receipt.To) > 0 {
+		fields["to"] = common.BytesToAddress(receipt.To)
+	}
+	if len(receipt.ContractAddress) > 0 {
+		fields["contractAddress"] =
+			common.BytesToAddress(receipt.ContractAddress)
+	}
+
+	return fields, nil
+}
+
+// Convert BEvm logs to Ethereum logs
+func (api *ethAPI) ethLogs(evmLogs []EvmLog) ([]*types.Log, error) {
+	logs := []*types.Log{}
+	for _, evmLog := range evmLogs {
+		blockHash, err := api.blockHash(evmLog.BlockIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		ethLog := &types.Log{
+			Address:     common.BytesToAddress(evmLog.Address),
+			Topics:      []common.Hash{},
+			Data:        evmLog.Data,
+			BlockNumber: uint64(evmLog.BlockIndex),
+			TxHash:      common.BytesToHash(evmLog.TxHash),
+			BlockHash:   blockHash,
+			Index:       uint(evmLog.Index),
+		}
+		for _, topic := range evmLog.Topics {
+			ethLog.Topics = append(ethLog.Topics, common.BytesToHash(topic))
+		}
+
+		logs = append(logs, ethLog)
+	}
+
+	return logs, nil
+}
+
+// Convert a JSON-RPC block number to a ByzCoin block index, the latest block
+// if there is none
+func (api *ethAPI) blockIndex(block *rpc.BlockNumber) (int, error) {
+	if block != nil && *block >= 0 {
+		return int(*block), nil
+	}
+
+	return api.latestBlockIndex()
+}
+
+// Retrieve the index of the latest ByzCoin block
+func (api *ethAPI) latestBlockIndex() (int, error) {
+	response, err := api.client.bcClient.GetProofFromLatest(
+		api.client.instanceID.Slice())
+	if err != nil {
+		return 0, xerrors.Errorf("failed to retrieve the latest ByzCoin "+
+			"block: %v", err)
+	}
+
+	return response.Proof.Latest.Index, nil
+}
+
+// Retrieve the hash of a ByzCoin block
+func (api *ethAPI) blockHash(index int) (common.Hash, error) {
+	api.blockHashesLock.Lock()
+	defer api.blockHashesLock.Unlock()
+
+	hash, ok := api.blockHashes[index]
+	if ok {
+		return hash, nil
+	}
+
+	reply, err := skipchain.NewClient().GetSingleBlockByIndex(
+		api.client.bcClient.Roster, api.client.bcClient.ID, index)
+	if err != nil {
+		return common.Hash{}, xerrors.Errorf("failed to retrieve ByzCoin "+
+			"block %d: %v", index, err)
+	}
+
+	hash = common.BytesToHash(reply.SkipBlock.Hash)
+	api.blockHashes[index] = hash
+
+	return hash, nil
+}
@@ -0,0 +1,147 @@
+package bevm
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RPCHandler(t *testing.T) {
+	// Create a new ledger and prepare for proper closing
+	bct := newBCTest(t)
+	defer bct.CloseAll()
+
+	// Spawn a new BEvm instance
+	instanceID, err := NewBEvm(bct.Client, bct.Signer, bct.GenesisDarc)
+	require.NoError(t, err)
+
+	// Create a new BEvm client
+	bevmClient, err := NewClient(bct.Client, bct.Signer, instanceID)
+	require.NoError(t, err)
+
+	a, err := NewEvmAccount(testPrivateKeys[0])
+	require.NoError(t, err)
+	_, err = bevmClient.CreditAccount(big.NewInt(5*WeiPerEther), a.Address)
+	require.NoError(t, err)
+
+	// Serve the JSON-RPC API
+	handler, err := NewRPCHandler(bevmClient)
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	rpcClient, err := rpc.DialHTTP(server.URL)
+	require.NoError(t, err)
+	defer rpcClient.Close()
+
+	var chainID hexutil.Big
+	require.NoError(t, rpcClient.Call(&chainID, "eth_chainId"))
+	require.Equal(t, getChainConfig().ChainID, chainID.ToInt())
+
+	// Deploy an ERC20 Token contract with a transaction signed outside of
+	// BEvm, as a wallet would
+	erc20Contract, err := NewEvmContract(
+		"ERC20Token",
+		getContractData(t, "ERC20Token", "abi"),
+		getContractData(t, "ERC20Token", "bin"))
+	require.NoError(t, err)
+
+	tx := types.NewContractCreation(a.Nonce, big.NewInt(0),
+		txParams.GasLimit, big.NewInt(int64(txParams.GasPrice)),
+		erc20Contract.Bytecode)
+	tx, err = types.SignTx(tx, types.NewEIP155Signer(chainID.ToInt()),
+		a.PrivateKey)
+	require.NoError(t, err)
+	rawTx, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+
+	var txHash common.Hash
+	err = rpcClient.Call(&txHash, "eth_sendRawTransaction",
+		hexutil.Bytes(rawTx))
+	require.NoError(t, err)
+	require.Equal(t, tx.Hash(), txHash)
+
+	var nonce hexutil.Uint64
+	err = rpcClient.Call(&nonce, "eth_getTransactionCount", a.Address,
+		"latest")
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1), nonce)
+
+	// The receipt gives the address of the contract, and its Transfer of
+	// the total supply to A
+	var receipt struct {
+		Status          hexutil.Uint
+		BlockNumber     hexutil.Uint64
+		From            common.Address
+		ContractAddress *common.Address
+		Logs            []*types.Log
+	}
+	err = rpcClient.Call(&receipt, "eth_getTransactionReceipt", txHash)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint(types.ReceiptStatusSuccessful),
+		receipt.Status)
+	require.Equal(t, a.Address, receipt.From)
+	contractAddress := crypto.CreateAddress(a.Address, 0)
+	require.Equal(t, &contractAddress, receipt.ContractAddress)
+	require.Len(t, receipt.Logs, 1)
+	require.Equal(t, txHash, receipt.Logs[0].TxHash)
+
+	var logs []*types.Log
+	err = rpcClient.Call(&logs, "eth_getLogs", map[string]interface{}{
+		"fromBlock": "earliest",
+		"address":   contractAddress,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, uint64(receipt.BlockNumber), logs[0].BlockNumber)
+
+	// The balance of A is the total supply
+	callData, err := erc20Contract.Abi.Pack("balanceOf", a.Address)
+	require.NoError(t, err)
+	var result hexutil.Bytes
+	err = rpcClient.Call(&result, "eth_call", map[string]interface{}{
+		"from": a.Address,
+		"to":   contractAddress,
+		"data": hexutil.Bytes(callData),
+	}, "latest")
+	require.NoError(t, err)
+	require.Equal(t, logs[0].Data, []byte(result))
+
+	// An unknown transaction has no receipt
+	var unknown map[string]interface{}
+	err = rpcClient.Call(&unknown, "eth_getTransactionReceipt",
+		common.Hash{})
+	require.NoError(t, err)
+	require.Nil(t, unknown)
+}
+
+func Test_AuthHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(NewAuthHandler(ok, "secret"))
+	defer server.Close()
+
+	for header, status := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer other":  http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	} {
+		req, err := http.NewRequest("POST", server.URL, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode, header)
+	}
+}
@@ -2,12 +2,18 @@ package bevm
 
 import (
 	"encoding/hex"
+	"math/big"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
 	"golang.org/x/xerrors"
 )
 
@@ -17,6 +23,27 @@ const ServiceName = "BEvm"
 // Service is the service that performs BEvm operations.
 type Service struct {
 	*onet.ServiceProcessor
+
+	// Index of the receipts of the logs instances, by ByzCoin ID and logs
+	// instance ID
+	receiptsLock sync.Mutex
+	receipts     map[string]*receiptIndex
+}
+
+// receiptIndex maps the hashes of the transactions of a logs instance to the
+// version of the instance holding their receipt. Each lookup indexes the
+// versions stored since the previous one.
+type receiptIndex struct {
+	versions map[string]uint64
+	// next is the first version not indexed yet
+	next uint64
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (service *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		service.ServiceProcessor.ProcessClientRequest)
 }
 
 func init() {
@@ -30,6 +57,8 @@ func init() {
 		contractBEvmFromBytes))
 	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractBEvmValueID,
 		nil))
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractBEvmLogsID,
+		nil))
 
 	// Initialize service
 	_, err := onet.RegisterNewService(ServiceName, newBEvmService)
@@ -43,15 +72,9 @@ func (service *Service) ViewCall(req *ViewCallRequest) (*ViewCallResponse,
 	accountAddress := common.BytesToAddress(req.AccountAddress)
 	contractAddress := common.BytesToAddress(req.ContractAddress)
 
-	serv := service.Context.Service(byzcoin.ServiceName)
-	if serv == nil {
-		return nil, xerrors.New("cannot find \"byzcoin\" service")
-	}
-
-	bcService, ok := serv.(*byzcoin.Service)
-	if !ok {
-		return nil,
-			xerrors.New("internal error: service is not a byzcoin.Service")
+	bcService, err := service.byzcoinService()
+	if err != nil {
+		return nil, err
 	}
 
 	rst, err := bcService.GetReadOnlyStateTrie(req.ByzCoinID)
@@ -84,14 +107,308 @@ func (service *Service) ViewCall(req *ViewCallRequest) (*ViewCallResponse,
 	return &ViewCallResponse{Result: result}, nil
 }
 
+// TraceTransaction executes a signed Ethereum transaction on the current
+// state of a BEvm instance, without applying it, and returns the trace of its
+// execution.
+func (service *Service) TraceTransaction(req *TraceTransactionRequest) (
+	*TraceTransactionResponse, error) {
+	var ethTx types.Transaction
+	err := ethTx.UnmarshalJSON(req.Transaction)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode JSON for EVM "+
+			"transaction: %v", err)
+	}
+
+	bcService, err := service.byzcoinService()
+	if err != nil {
+		return nil, err
+	}
+
+	rst, err := bcService.GetReadOnlyStateTrie(req.ByzCoinID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve ReadOnlyStateTrie: %v",
+			err)
+	}
+
+	bevmID := byzcoin.NewInstanceID(req.BEvmInstanceID)
+	value, _, _, _, err := rst.GetValues(bevmID[:])
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve BEvm instance: %v", err)
+	}
+	var bs State
+	err = protobuf.Decode(value, &bs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode BEvm instance state: %v",
+			err)
+	}
+
+	// The trace runs for free on this node, so it is bounded like the
+	// transactions of the instance.
+	gasCap := bs.GasConfig.TxGasLimit
+	if gasCap == 0 {
+		gasCap = estimateGasCap
+	}
+	if ethTx.Gas() > gasCap {
+		return nil, xerrors.Errorf("transaction gas limit %d exceeds the "+
+			"maximum of %d", ethTx.Gas(), gasCap)
+	}
+
+	// Retrieve the EVM state
+	stateDb, err := getEvmDbRst(rst, bevmID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to obtain stateTrie-backed database "+
+			"for BEvm: %v", err)
+	}
+
+	// timestamp in ByzCoin is in [ns], whereas in EVM it is in [s]
+	evmTs := uint64(time.Now().UnixNano() / 1e9)
+
+	return traceTx(&ethTx, stateDb, evmTs, req.Opcodes)
+}
+
+// EstimateGas simulates a transaction on the current state of a BEvm instance
+// and returns the lowest gas limit with which it succeeds, or the reason why
+// it fails.
+func (service *Service) EstimateGas(req *EstimateGasRequest) (
+	*EstimateGasResponse, error) {
+	bcService, err := service.byzcoinService()
+	if err != nil {
+		return nil, err
+	}
+
+	rst, err := bcService.GetReadOnlyStateTrie(req.ByzCoinID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve ReadOnlyStateTrie: %v",
+			err)
+	}
+
+	bevmID := byzcoin.NewInstanceID(req.BEvmInstanceID)
+	value, _, _, _, err := rst.GetValues(bevmID[:])
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve BEvm instance: %v", err)
+	}
+	var bs State
+	err = protobuf.Decode(value, &bs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode BEvm instance state: %v",
+			err)
+	}
+
+	// The estimation runs for free on this node, so the requested gas limit
+	// can't be higher than the one of the transactions of the instance.
+	maxGas := bs.GasConfig.TxGasLimit
+	if maxGas == 0 {
+		maxGas = estimateGasCap
+	}
+	gasCap := req.GasLimit
+	if gasCap == 0 || gasCap > maxGas {
+		gasCap = maxGas
+	}
+
+	// Retrieve the EVM state
+	stateDb, err := getEvmDbRst(rst, bevmID)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to obtain stateTrie-backed database "+
+			"for BEvm: %v", err)
+	}
+
+	var to *common.Address
+	if len(req.ContractAddress) > 0 {
+		contractAddress := common.BytesToAddress(req.ContractAddress)
+		to = &contractAddress
+	}
+
+	// timestamp in ByzCoin is in [ns], whereas in EVM it is in [s]
+	evmTs := uint64(time.Now().UnixNano() / 1e9)
+
+	return estimateGas(stateDb, evmTs,
+		common.BytesToAddress(req.AccountAddress), to,
+		new(big.Int).SetUint64(req.Amount), req.CallData, gasCap)
+}
+
+// GetLogs returns the logs emitted by the EVM contracts of a BEvm instance
+// that match the request, read from the versions of its logs instance.
+func (service *Service) GetLogs(req *GetLogsRequest) (*GetLogsResponse,
+	error) {
+	versions, err := service.logsVersions(req.ByzCoinID, req.BEvmInstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetLogsResponse{}
+	for _, version := range versions {
+		sc := version.StateChange
+		if sc.StateAction == byzcoin.Remove ||
+			!req.inRange(version.BlockIndex) {
+			continue
+		}
+
+		var evmLogs EvmLogs
+		err = protobuf.Decode(sc.Value, &evmLogs)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to decode EVM logs: %v", err)
+		}
+
+		for _, evmLog := range evmLogs.Logs {
+			if req.matches(evmLog) {
+				evmLog.BlockIndex = version.BlockIndex
+				response.Logs = append(response.Logs, evmLog)
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// GetReceipt returns the receipt and the logs of an Ethereum transaction
+// applied by a BEvm instance
+func (service *Service) GetReceipt(req *GetReceiptRequest) (
+	*GetReceiptResponse, error) {
+	bcService, err := service.byzcoinService()
+	if err != nil {
+		return nil, err
+	}
+
+	logsID := LogsInstanceID(byzcoin.NewInstanceID(req.BEvmInstanceID))
+	version, ok, err := service.receiptVersion(bcService, req.ByzCoinID,
+		logsID, req.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &GetReceiptResponse{}, nil
+	}
+
+	resp, err := bcService.GetInstanceVersion(&byzcoin.GetInstanceVersion{
+		SkipChainID: req.ByzCoinID,
+		InstanceID:  logsID,
+		Version:     version,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve the version of the "+
+			"logs instance: %v", err)
+	}
+
+	var evmLogs EvmLogs
+	err = protobuf.Decode(resp.StateChange.Value, &evmLogs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode EVM logs: %v", err)
+	}
+
+	for i := range evmLogs.Logs {
+		evmLogs.Logs[i].BlockIndex = resp.BlockIndex
+	}
+
+	return &GetReceiptResponse{
+		Receipt:    &evmLogs.Receipt,
+		Logs:       evmLogs.Logs,
+		BlockIndex: resp.BlockIndex,
+	}, nil
+}
+
+// Look up the version of the logs instance holding the receipt of a
+// transaction, indexing the versions stored since the previous lookup. The
+// versions no longer kept by ByzCoin are skipped.
+func (service *Service) receiptVersion(bcService *byzcoin.Service,
+	byzcoinID []byte, logsID byzcoin.InstanceID,
+	txHash []byte) (uint64, bool, error) {
+	service.receiptsLock.Lock()
+	defer service.receiptsLock.Unlock()
+
+	key := string(byzcoinID) + string(logsID[:])
+	index := service.receipts[key]
+	if index == nil {
+		index = &receiptIndex{versions: map[string]uint64{}}
+		service.receipts[key] = index
+	}
+	if version, ok := index.versions[string(txHash)]; ok {
+		return version, true, nil
+	}
+
+	last, err := bcService.GetLastInstanceVersion(
+		&byzcoin.GetLastInstanceVersion{
+			SkipChainID: byzcoinID,
+			InstanceID:  logsID,
+		})
+	if err != nil {
+		// No transaction stored logs yet
+		return 0, false, nil
+	}
+
+	for ; index.next <= last.StateChange.Version; index.next++ {
+		resp, err := bcService.GetInstanceVersion(&byzcoin.GetInstanceVersion{
+			SkipChainID: byzcoinID,
+			InstanceID:  logsID,
+			Version:     index.next,
+		})
+		if err != nil || resp.StateChange.StateAction == byzcoin.Remove {
+			continue
+		}
+
+		var evmLogs EvmLogs
+		err = protobuf.Decode(resp.StateChange.Value, &evmLogs)
+		if err != nil {
+			return 0, false, xerrors.Errorf("failed to decode EVM logs: %v",
+				err)
+		}
+		index.versions[string(evmLogs.Receipt.TxHash)] = index.next
+	}
+
+	version, ok := index.versions[string(txHash)]
+	return version, ok, nil
+}
+
+// Retrieve all the versions of the logs instance of a BEvm instance
+func (service *Service) logsVersions(byzcoinID []byte,
+	bevmInstanceID []byte) ([]byzcoin.GetInstanceVersionResponse, error) {
+	bcService, err := service.byzcoinService()
+	if err != nil {
+		return nil, err
+	}
+
+	logsID := LogsInstanceID(byzcoin.NewInstanceID(bevmInstanceID))
+	versions, err := bcService.GetAllInstanceVersion(
+		&byzcoin.GetAllInstanceVersion{
+			SkipChainID: byzcoinID,
+			InstanceID:  logsID,
+		})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to retrieve the versions of the "+
+			"logs instance: %v", err)
+	}
+
+	return versions.StateChanges, nil
+}
+
+// Retrieve the ByzCoin service running next to this service
+func (service *Service) byzcoinService() (*byzcoin.Service, error) {
+	serv := service.Context.Service(byzcoin.ServiceName)
+	if serv == nil {
+		return nil, xerrors.New("cannot find \"byzcoin\" service")
+	}
+
+	bcService, ok := serv.(*byzcoin.Service)
+	if !ok {
+		return nil,
+			xerrors.New("internal error: service is not a byzcoin.Service")
+	}
+
+	return bcService, nil
+}
+
 // newBEvmService creates a new service for BEvm functionality
 func newBEvmService(context *onet.Context) (onet.Service, error) {
 	service := &Service{
 		ServiceProcessor: onet.NewServiceProcessor(context),
+		receipts:         map[string]*receiptIndex{},
 	}
 
 	err := service.RegisterHandlers(
 		service.ViewCall,
+		service.GetLogs,
+		service.GetReceipt,
+		service.TraceTransaction,
+		service.EstimateGas,
 	)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to register service "+
@@ -0,0 +1,306 @@
+package bevm
+
+import (
+	"bytes"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"golang.org/x/xerrors"
+)
+
+// Maximum number of opcodes kept in a trace
+const maxTraceSteps = 100000
+
+// Maximum number of values kept from the top of the stack of an opcode, so
+// that a trace holds at most maxTraceSteps * maxTraceStack words
+const maxTraceStack = 8
+
+// Selector of the Error(string) revert reason
+var revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// Trace the execution of a transaction on a state database. The changes
+// made to the state database are not committed.
+func traceTx(tx *types.Transaction, stateDb *state.StateDB, timestamp uint64,
+	opcodes bool) (*TraceTransactionResponse, error) {
+	calls := &callTracer{}
+	tracers := multiTracer{calls}
+
+	var steps *stepTracer
+	if opcodes {
+		steps = &stepTracer{}
+		tracers = append(tracers, steps)
+	}
+
+	vmConfig := getVMConfig()
+	vmConfig.Debug = true
+	vmConfig.Tracer = tracers
+
+	stateDb.Prepare(tx.Hash(), common.Hash{}, 0)
+	receipt, err := applyTx(tx, stateDb, timestamp, tx.Gas(), vmConfig)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to trace transaction: %v", err)
+	}
+
+	response := &TraceTransactionResponse{
+		Success:      receipt.Status == types.ReceiptStatusSuccessful,
+		GasUsed:      receipt.GasUsed,
+		Output:       calls.output,
+		RevertReason: revertReason(calls.output),
+		Calls:        calls.calls,
+	}
+
+	if steps != nil {
+		response.Steps = steps.steps
+	}
+
+	return response, nil
+}
+
+// Decode the reason given to revert(), if the output holds one
+func revertReason(output []byte) string {
+	if len(output) < len(revertSelector) ||
+		!bytes.Equal(output[:len(revertSelector)], revertSelector) {
+		return ""
+	}
+
+	// The reason is ABI-encoded as an offset, a length and the string
+	data := output[len(revertSelector):]
+	if len(data) < 64 {
+		return ""
+	}
+	length := new(big.Int).SetBytes(data[32:64])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data)-64) {
+		return ""
+	}
+
+	return string(data[64 : 64+length.Uint64()])
+}
+
+// multiTracer forwards the EVM execution to several tracers, going on with
+// the next ones when one fails
+type multiTracer []vm.Tracer
+
+// CaptureStart implements vm.Tracer.CaptureStart()
+func (t multiTracer) CaptureStart(from common.Address, to common.Address,
+	create bool, input []byte, gas uint64, value *big.Int) (err error) {
+	for _, tracer := range t {
+		e := tracer.CaptureStart(from, to, create, input, gas, value)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// CaptureState implements vm.Tracer.CaptureState()
+func (t multiTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas,
+	cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract,
+	depth int, err error) (tracerErr error) {
+	for _, tracer := range t {
+		e := tracer.CaptureState(env, pc, op, gas, cost, memory, stack,
+			contract, depth, err)
+		if e != nil && tracerErr == nil {
+			tracerErr = e
+		}
+	}
+
+	return tracerErr
+}
+
+// CaptureFault implements vm.Tracer.CaptureFault()
+func (t multiTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas,
+	cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract,
+	depth int, err error) (tracerErr error) {
+	for _, tracer := range t {
+		e := tracer.CaptureFault(env, pc, op, gas, cost, memory, stack,
+			contract, depth, err)
+		if e != nil && tracerErr == nil {
+			tracerErr = e
+		}
+	}
+
+	return tracerErr
+}
+
+// CaptureEnd implements vm.Tracer.CaptureEnd()
+func (t multiTracer) CaptureEnd(output []byte, gasUsed uint64,
+	d time.Duration, err error) (tracerErr error) {
+	for _, tracer := range t {
+		e := tracer.CaptureEnd(output, gasUsed, d, err)
+		if e != nil && tracerErr == nil {
+			tracerErr = e
+		}
+	}
+
+	return tracerErr
+}
+
+// callTracer keeps track of the calls made by a transaction. The EVM only
+// reports the opcodes of the nested calls, so a call starts with a call
+// opcode, and ends when the execution comes back to its depth.
+type callTracer struct {
+	calls  []TraceCall
+	output []byte
+	// Indexes of the nested calls in progress
+	pending []int
+}
+
+// CaptureStart implements vm.Tracer.CaptureStart()
+func (t *callTracer) CaptureStart(from common.Address, to common.Address,
+	create bool, input []byte, gas uint64, value *big.Int) error {
+	call := TraceCall{
+		Type:  vm.CALL.String(),
+		From:  from.Bytes(),
+		To:    to.Bytes(),
+		Input: input,
+		Gas:   gas,
+	}
+	if create {
+		call.Type = vm.CREATE.String()
+	}
+	t.calls = append(t.calls, call)
+
+	return nil
+}
+
+// CaptureState implements vm.Tracer.CaptureState()
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas,
+	cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract,
+	depth int, err error) error {
+	// The EVM depth starts at 1 for the transaction itself
+	depth--
+
+	// Back at the depth of a call opcode, whose result is on the stack
+	for len(t.pending) > 0 {
+		call := &t.calls[t.pending[len(t.pending)-1]]
+		if call.Depth <= depth {
+			break
+		}
+		t.pending = t.pending[:len(t.pending)-1]
+		if len(stack.Data()) == 0 {
+			continue
+		}
+		result := stack.Back(0)
+		call.Success = result.Sign() != 0
+		if call.Type == vm.CREATE.String() ||
+			call.Type == vm.CREATE2.String() {
+			call.To = common.BigToAddress(result).Bytes()
+		}
+	}
+
+	call := TraceCall{
+		Depth: depth + 1,
+		Type:  op.String(),
+		From:  contract.Address().Bytes(),
+	}
+	// Position of the input offset on the stack
+	inputPos := 0
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		inputPos = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		inputPos = 2
+	case vm.CREATE, vm.CREATE2:
+		inputPos = 1
+	default:
+		return nil
+	}
+
+	if len(stack.Data()) > inputPos+1 {
+		// The calls have the gas and the address first
+		if inputPos > 1 {
+			call.Gas = stack.Back(0).Uint64()
+			call.To = common.BigToAddress(stack.Back(1)).Bytes()
+		}
+		offset := stack.Back(inputPos)
+		size := stack.Back(inputPos + 1)
+		if offset.IsInt64() && size.IsInt64() &&
+			offset.Int64()+size.Int64() <= int64(memory.Len()) {
+			call.Input = memory.Get(offset.Int64(), size.Int64())
+		}
+	}
+
+	t.calls = append(t.calls, call)
+	t.pending = append(t.pending, len(t.calls)-1)
+
+	return nil
+}
+
+// CaptureFault implements vm.Tracer.CaptureFault()
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas,
+	cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract,
+	depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.CaptureEnd()
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64,
+	d time.Duration, err error) error {
+	t.output = output
+	if len(t.calls) > 0 {
+		t.calls[0].Success = err == nil
+	}
+
+	return nil
+}
+
+// stepTracer keeps the first maxTraceSteps opcodes executed, with the top of
+// their stack. Unlike vm.StructLogger, it doesn't copy the whole stack.
+type stepTracer struct {
+	steps []TraceStep
+}
+
+// CaptureStart implements vm.Tracer.CaptureStart()
+func (t *stepTracer) CaptureStart(from common.Address, to common.Address,
+	create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements vm.Tracer.CaptureState()
+func (t *stepTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas,
+	cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract,
+	depth int, err error) error {
+	if len(t.steps) >= maxTraceSteps {
+		return nil
+	}
+
+	step := TraceStep{
+		Pc:      pc,
+		Op:      op.String(),
+		Gas:     gas,
+		GasCost: cost,
+		Depth:   depth - 1,
+	}
+	values := stack.Data()
+	if len(values) > maxTraceStack {
+		values = values[len(values)-maxTraceStack:]
+	}
+	for _, value := range values {
+		step.Stack = append(step.Stack, value.Bytes())
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	t.steps = append(t.steps, step)
+
+	return nil
+}
+
+// CaptureFault implements vm.Tracer.CaptureFault()
+func (t *stepTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas,
+	cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract,
+	depth int, err error) error {
+	// CaptureState already recorded the opcode with its error
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.CaptureEnd()
+func (t *stepTracer) CaptureEnd(output []byte, gasUsed uint64,
+	d time.Duration, err error) error {
+	return nil
+}
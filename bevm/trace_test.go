@@ -0,0 +1,24 @@
+package bevm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevertReason(t *testing.T) {
+	reasonAbi, err := abi.JSON(strings.NewReader(`[{"name":"Error",` +
+		`"type":"function","inputs":[{"name":"reason","type":"string"}]}]`))
+	require.NoError(t, err)
+
+	output, err := reasonAbi.Pack("Error", "not enough candies")
+	require.NoError(t, err)
+	require.Equal(t, revertSelector, output[:4])
+	require.Equal(t, "not enough candies", revertReason(output))
+
+	require.Equal(t, "", revertReason(nil))
+	require.Equal(t, "", revertReason(output[:40]))
+	require.Equal(t, "", revertReason([]byte{1, 2, 3, 4, 5}))
+}
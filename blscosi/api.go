@@ -2,15 +2,27 @@ package blscosi
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
 )
 
 // Client is a structure to communicate with the CoSi
 // service
 type Client struct {
 	*onet.Client
+	// KeyPair, if not nil, is used to sign the requests for the services
+	// that only accept some clients.
+	KeyPair *key.Pair
 }
 
 // NewClient instantiates a new blscosi.Client
@@ -21,17 +33,176 @@ func NewClient() *Client {
 // SignatureRequest sends a CoSi sign request to the Cothority defined by the given
 // Roster
 func (c *Client) SignatureRequest(r *onet.Roster, msg []byte) (*SignatureResponse, error) {
+	return c.SignatureRequestWithDomain(r, nil, msg)
+}
+
+// SignatureRequestWithDomain is the same as SignatureRequest but the message
+// is signed for the given domain-separation tag. The signature must be
+// verified over protocol.DomainMessage(domain, msg).
+func (c *Client) SignatureRequestWithDomain(r *onet.Roster, domain, msg []byte) (*SignatureResponse, error) {
 	serviceReq := &SignatureRequest{
 		Roster:  r,
 		Message: msg,
+		Domain:  domain,
 	}
 	if len(r.List) == 0 {
 		return nil, errors.New("Got an empty roster-list")
 	}
+	var err error
+	serviceReq.Client, serviceReq.ClientSignature, serviceReq.ClientTime, err = c.signRequest(msg, domain, r)
+	if err != nil {
+		return nil, err
+	}
 	dst := r.List[0]
 	log.Lvl4("Sending message to", dst)
 	reply := &SignatureResponse{}
-	err := c.SendProtobuf(dst, serviceReq, reply)
+	err = c.SendProtobuf(dst, serviceReq, reply)
+
+	return reply, err
+}
+
+// SignatureRequestEdDSA asks the roster for a Schnorr collective signature of
+// the message that can be verified with VerifyEdDSA, or as a standard Ed25519
+// signature for the key returned by EdDSAPublic. Every member of the roster
+// must sign.
+func (c *Client) SignatureRequestEdDSA(r *onet.Roster, domain, msg []byte) (*SignatureResponse, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	serviceReq := &SignatureRequest{
+		Roster:  r,
+		Message: msg,
+		Domain:  domain,
+		EdDSA:   true,
+	}
+	var err error
+	serviceReq.Client, serviceReq.ClientSignature, serviceReq.ClientTime, err = c.signRequest(msg, domain, r)
+	if err != nil {
+		return nil, err
+	}
 
+	reply := &SignatureResponse{}
+	err = c.SendProtobuf(r.List[0], serviceReq, reply)
 	return reply, err
 }
+
+// PartialSignatureRequest asks every member of the roster for its signature
+// of the message and aggregates them once threshold valid signatures are
+// received, so that no leader is needed and the slowest members are not
+// waited for. If threshold is zero, the default threshold of the roster is
+// used. The domain-separation tag can be nil.
+func (c *Client) PartialSignatureRequest(r *onet.Roster, domain, msg []byte, threshold int) (protocol.BlsSignature, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	if threshold == 0 {
+		threshold = protocol.DefaultThreshold(len(r.List))
+	}
+
+	req := &PartialSignatureRequest{
+		Roster:  r,
+		Message: msg,
+		Domain:  domain,
+	}
+	var err error
+	req.Client, req.ClientSignature, req.ClientTime, err = c.signRequest(msg, domain, r)
+	if err != nil {
+		return nil, err
+	}
+
+	publics := r.ServicePublics(ServiceName)
+	msg = protocol.DomainMessage(domain, msg)
+
+	// each member gets its own connection, which is closed to cancel the
+	// request if the threshold is reached before it answers, and the channel
+	// is buffered so that the cancelled requests don't block
+	replies := make(chan *PartialSignatureResponse, len(r.List))
+	clients := make([]*onet.Client, len(r.List))
+	defer func() {
+		for _, cl := range clients {
+			cl.Close()
+		}
+	}()
+	for i, si := range r.List {
+		clients[i] = onet.NewClient(suite, ServiceName)
+		go func(cl *onet.Client, i int, si *network.ServerIdentity) {
+			reply := &PartialSignatureResponse{}
+			if err := cl.SendProtobuf(si, req, reply); err != nil {
+				log.Lvlf2("Couldn't get the partial signature of %v: %v", si, err)
+				replies <- nil
+				return
+			}
+			// the index comes from the position in the roster so that a
+			// node can't sign for another one
+			reply.Partial.Index = i
+			replies <- reply
+		}(clients[i], i, si)
+	}
+
+	partials := []protocol.PartialSignature{}
+	bdn := false
+	for range r.List {
+		reply := <-replies
+		if reply == nil {
+			continue
+		}
+		if err := reply.Partial.Verify(suite, msg, publics); err != nil {
+			log.Lvlf2("Invalid partial signature of %v: %v",
+				r.List[reply.Partial.Index], err)
+			continue
+		}
+		if len(partials) > 0 && reply.Bdn != bdn {
+			return nil, errors.New("the members use different signature schemes")
+		}
+		bdn = reply.Bdn
+		partials = append(partials, reply.Partial)
+		if len(partials) == threshold {
+			// the members that didn't answer yet aren't waited for
+			break
+		}
+	}
+
+	if bdn {
+		sig, err := bdnproto.AggregatePartials(suite, msg, publics, partials, threshold)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't aggregate: %v", err)
+		}
+		return protocol.BlsSignature(sig), nil
+	}
+
+	sig, err := protocol.AggregatePartials(suite, msg, publics, partials, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't aggregate: %v", err)
+	}
+	return sig, nil
+}
+
+// Receipts asks the node for the receipts of the messages it co-signed
+// between from and to. The request must be signed with the private key of
+// the server identity of the node.
+func (c *Client) Receipts(si *network.ServerIdentity, priv kyber.Scalar, from, to time.Time) ([]Receipt, error) {
+	req := &ReceiptsRequest{
+		From:      from.UnixNano(),
+		To:        to.UnixNano(),
+		Timestamp: time.Now().UnixNano(),
+	}
+	var err error
+	req.Signature, err = schnorr.Sign(cothority.Suite, priv,
+		receiptsRange(req.From, req.To, req.Timestamp))
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &ReceiptsResponse{}
+	err = c.SendProtobuf(si, req, reply)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range reply.Receipts {
+		if err := r.Verify(si.Public); err != nil {
+			return nil, fmt.Errorf("invalid receipt: %v", err)
+		}
+	}
+	return reply.Receipts, nil
+}
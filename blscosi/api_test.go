@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 )
@@ -32,3 +33,49 @@ func TestClient_SignatureRequest(t *testing.T) {
 		require.Nil(t, reply.Signature.Verify(testSuite, msg, publics))
 	}
 }
+
+func TestClient_PartialSignatureRequest(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	servers, roster, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	msg := []byte("hello blscosi service")
+
+	_, err := client.PartialSignatureRequest(&onet.Roster{}, nil, msg, 0)
+	require.Error(t, err)
+
+	// the leader isn't needed anymore
+	servers[0].Pause()
+
+	sig, err := client.PartialSignatureRequest(roster, nil, msg, 0)
+	require.NoError(t, err)
+	publics := roster.ServicePublics(ServiceName)
+	require.NoError(t, sig.Verify(testSuite, msg, publics))
+
+	_, err = client.PartialSignatureRequest(roster, nil, msg, 5)
+	require.Error(t, err)
+}
+
+func TestClient_SignatureRequestWithDomain(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	_, roster, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	msg := []byte("hello blscosi service")
+	publics := roster.ServicePublics(ServiceName)
+
+	reply, err := client.SignatureRequestWithDomain(roster, []byte("forward-link"), msg)
+	require.NoError(t, err)
+	require.Error(t, reply.Signature.Verify(testSuite, msg, publics))
+	require.Error(t, reply.Signature.Verify(testSuite,
+		protocol.DomainMessage([]byte("beacon"), msg), publics))
+	require.NoError(t, reply.Signature.Verify(testSuite,
+		protocol.DomainMessage([]byte("forward-link"), msg), publics))
+
+	sig, err := client.PartialSignatureRequest(roster, []byte("beacon"), msg, 0)
+	require.NoError(t, err)
+	require.NoError(t, sig.Verify(testSuite,
+		protocol.DomainMessage([]byte("beacon"), msg), publics))
+}
@@ -0,0 +1,174 @@
+package blscosi
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/admission"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+)
+
+// AuthorizeFn returns an error if the client is not allowed to get the
+// message signed. The client is nil for anonymous requests, otherwise it is
+// the public key the request has been signed with.
+type AuthorizeFn func(client kyber.Point, msg []byte) error
+
+// AllowList returns an authorization function that only accepts the requests
+// signed by one of the given public keys.
+func AllowList(publics ...kyber.Point) AuthorizeFn {
+	return func(client kyber.Point, msg []byte) error {
+		if client == nil {
+			return errors.New("anonymous requests are not allowed")
+		}
+		for _, pub := range publics {
+			if pub.Equal(client) {
+				return nil
+			}
+		}
+		return fmt.Errorf("client %v is not allowed", client)
+	}
+}
+
+// AuthWindow is how far the time of a signed request can be from the clock
+// of the node. A signed request is accepted only once within the window.
+const AuthWindow = 2 * time.Minute
+
+// authTag separates the signatures of the requests from the other messages
+// signed with the key of the client.
+const authTag = "blscosi-request"
+
+// authMessage returns what the client signs to authenticate a request at the
+// given time, in nanoseconds. Every field is prefixed with its length so that
+// the boundaries between them are not ambiguous.
+func authMessage(msg, domain []byte, roster *onet.Roster, ts int64) []byte {
+	h := sha256.New()
+	var rosterID []byte
+	if roster != nil {
+		rosterID = roster.ID[:]
+	}
+	for _, f := range [][]byte{[]byte(authTag), msg, domain, rosterID} {
+		binary.Write(h, binary.LittleEndian, uint64(len(f)))
+		h.Write(f)
+	}
+	binary.Write(h, binary.LittleEndian, ts)
+	return h.Sum(nil)
+}
+
+// signRequest returns the signature of the request by the key pair of the
+// client with the time it is signed at, or nil if the client is anonymous.
+func (c *Client) signRequest(msg, domain []byte, roster *onet.Roster) (kyber.Point, []byte, int64, error) {
+	if c.KeyPair == nil {
+		return nil, nil, 0, nil
+	}
+	ts := time.Now().UnixNano()
+	sig, err := schnorr.Sign(cothority.Suite, c.KeyPair.Private, authMessage(msg, domain, roster, ts))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return c.KeyPair.Public, sig, ts, nil
+}
+
+// maxMessageSize is the size of the biggest message, with its domain, the
+// service signs.
+const maxMessageSize = 1 << 16
+
+// checkRequest makes sure the message of a request is not too big, that the
+// request is authorized and under its rate limit, and then authenticates the
+// client and makes sure the request is fresh. The cheap checks come first, so
+// that the signatures of the refused requests are neither verified nor kept.
+func (s *Service) checkRequest(client kyber.Point, sig []byte, ts int64, msg, domain []byte, roster *onet.Roster) error {
+	if len(msg)+len(domain) > maxMessageSize {
+		return fmt.Errorf("the message is bigger than %d bytes", maxMessageSize)
+	}
+	if client == nil && sig != nil {
+		return errors.New("signature without a client public key")
+	}
+
+	if s.Authorize != nil {
+		if err := s.Authorize(client, msg); err != nil {
+			return fmt.Errorf("request not authorized: %v", err)
+		}
+	}
+
+	if s.RateLimit > 0 {
+		id := ""
+		if client != nil {
+			id = client.String()
+		}
+		if !s.limiter.allow(id, s.RateLimit, s.RateBurst) {
+			return errors.New("rate limit exceeded")
+		}
+	}
+
+	if client != nil {
+		err := schnorr.Verify(cothority.Suite, client, authMessage(msg, domain, roster, ts), sig)
+		if err != nil {
+			return fmt.Errorf("invalid client signature: %v", err)
+		}
+		if err := s.replays.check(sig, time.Unix(0, ts)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rateLimiter is a token bucket for each client.
+type rateLimiter struct {
+	admission.Buckets
+}
+
+// allow returns true if the client still has a token, given that the tokens
+// come back at the rate per second up to the burst.
+func (rl *rateLimiter) allow(id string, rate float64, burst int) bool {
+	return rl.Allow(id, rate, burst)
+}
+
+// maxReplays is the number of signed requests a node accepts within the
+// authentication window, which bounds the memory of its replay cache.
+const maxReplays = 1 << 16
+
+// replayCache remembers the signatures of the requests accepted within the
+// authentication window.
+type replayCache struct {
+	sync.Mutex
+	seen map[string]time.Time
+}
+
+// check returns an error if the request signed at ts is outside of the
+// window or if its signature has already been seen.
+func (rc *replayCache) check(sig []byte, ts time.Time) error {
+	now := time.Now()
+	if ts.Before(now.Add(-AuthWindow)) || ts.After(now.Add(AuthWindow)) {
+		return fmt.Errorf("request signed at %v is outside of the window of %v",
+			ts, AuthWindow)
+	}
+
+	rc.Lock()
+	defer rc.Unlock()
+	if rc.seen == nil {
+		rc.seen = make(map[string]time.Time)
+	}
+	for k, expiry := range rc.seen {
+		if now.After(expiry) {
+			delete(rc.seen, k)
+		}
+	}
+	if _, ok := rc.seen[string(sig)]; ok {
+		return errors.New("request already received")
+	}
+	// Forgetting a signature before the end of the window would let it be
+	// replayed, so the new requests are refused instead.
+	if len(rc.seen) >= maxReplays {
+		return errors.New("too many signed requests in the window")
+	}
+	rc.seen[string(sig)] = ts.Add(AuthWindow)
+	return nil
+}
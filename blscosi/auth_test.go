@@ -0,0 +1,124 @@
+package blscosi
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestClient_Authorization(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(3, false)
+	defer local.CloseAll()
+
+	allowed := key.NewKeyPair(cothority.Suite)
+	for _, h := range hosts {
+		h.Service(ServiceName).(*Service).Authorize = AllowList(allowed.Public)
+	}
+
+	client := NewClient()
+	msg := []byte("hello blscosi service")
+
+	_, err := client.SignatureRequest(roster, msg)
+	require.Error(t, err)
+
+	client.KeyPair = key.NewKeyPair(cothority.Suite)
+	_, err = client.SignatureRequest(roster, msg)
+	require.Error(t, err)
+
+	client.KeyPair = allowed
+	reply, err := client.SignatureRequest(roster, msg)
+	require.NoError(t, err)
+	require.NoError(t, reply.Signature.Verify(testSuite, msg, roster.ServicePublics(ServiceName)))
+
+	_, err = client.PartialSignatureRequest(roster, nil, msg, 0)
+	require.NoError(t, err)
+
+	// a request signed for another message must be refused
+	service := hosts[0].Service(ServiceName).(*Service)
+	req := &SignatureRequest{Roster: roster, Message: msg}
+	req.Client, req.ClientSignature, req.ClientTime, err = client.signRequest([]byte("abc"), nil, roster)
+	require.NoError(t, err)
+	_, err = service.SignatureRequest(req)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid client signature")
+
+	// a signed request is accepted only once
+	req.Client, req.ClientSignature, req.ClientTime, err = client.signRequest(msg, nil, roster)
+	require.NoError(t, err)
+	_, err = service.SignatureRequest(req)
+	require.NoError(t, err)
+	_, err = service.SignatureRequest(req)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already received")
+
+	// the time is part of the signature
+	req.ClientTime++
+	_, err = service.SignatureRequest(req)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid client signature")
+}
+
+func TestReplayCache(t *testing.T) {
+	rc := replayCache{}
+	now := time.Now()
+	require.NoError(t, rc.check([]byte("a"), now))
+	require.Error(t, rc.check([]byte("a"), now))
+	require.NoError(t, rc.check([]byte("b"), now))
+	require.Error(t, rc.check([]byte("c"), now.Add(-2*AuthWindow)))
+	require.Error(t, rc.check([]byte("c"), now.Add(2*AuthWindow)))
+
+	// the cache is bounded, but its signatures expire with the window
+	for i := len(rc.seen); i < maxReplays; i++ {
+		rc.seen[fmt.Sprint(i)] = now.Add(AuthWindow)
+	}
+	err := rc.check([]byte("c"), now)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "too many signed requests")
+	for k := range rc.seen {
+		rc.seen[k] = now
+	}
+	require.NoError(t, rc.check([]byte("c"), now))
+}
+
+func TestService_RateLimit(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(3, false)
+	defer local.CloseAll()
+
+	service := hosts[0].Service(ServiceName).(*Service)
+	service.RateLimit = 0.001
+	service.RateBurst = 2
+
+	req := &SignatureRequest{Roster: roster, Message: []byte("abc")}
+	for i := 0; i < 2; i++ {
+		_, err := service.SignatureRequest(req)
+		require.NoError(t, err)
+	}
+	_, err := service.SignatureRequest(req)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "rate limit exceeded")
+
+	// another client has its own limit
+	client := NewClient()
+	client.KeyPair = key.NewKeyPair(cothority.Suite)
+	req.Client, req.ClientSignature, req.ClientTime, err = client.signRequest(req.Message, nil, roster)
+	require.NoError(t, err)
+	_, err = service.SignatureRequest(req)
+	require.NoError(t, err)
+}
+
+func TestRateLimiter(t *testing.T) {
+	rl := rateLimiter{}
+	require.True(t, rl.allow("a", 1000, 1))
+	require.False(t, rl.allow("a", 0, 1))
+	require.True(t, rl.allow("b", 0, 1))
+
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, rl.allow("a", 1000, 1))
+}
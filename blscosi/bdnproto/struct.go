@@ -66,3 +66,24 @@ func (sig BdnSignature) VerifyWithPolicy(suite pairing.Suite, msg []byte, pubkey
 
 	return nil
 }
+
+// BatchVerify checks many BDN signatures with a single pairing verification.
+// It returns nil only if every signature is valid and fulfills its policy.
+func BatchVerify(suite pairing.Suite, items []protocol.BatchItem) error {
+	return protocol.BatchVerifyWith(suite, items, bdn.AggregatePublicKeys)
+}
+
+// AggregatePartials verifies the partial signatures and aggregates threshold
+// valid ones into a BDN collective signature.
+func AggregatePartials(suite pairing.Suite, msg []byte, pubkeys []kyber.Point,
+	partials []protocol.PartialSignature, threshold int) (BdnSignature, error) {
+	sig, err := protocol.AggregatePartialsWith(suite, msg, pubkeys, partials, threshold,
+		func(suite pairing.Suite, mask *sign.Mask, sigs [][]byte) ([]byte, error) {
+			agg, err := bdn.AggregateSignatures(suite, sigs, mask)
+			if err != nil {
+				return nil, err
+			}
+			return agg.MarshalBinary()
+		})
+	return BdnSignature(sig), err
+}
@@ -0,0 +1,193 @@
+package blscosi
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	ftprotocol "go.dedis.ch/cothority/v3/ftcosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/cosi"
+	"go.dedis.ch/onet/v3"
+)
+
+// The EdDSA signatures are collected with the ftcosi protocols, under names
+// of this service so that every node keeps a receipt of what it signs.
+const (
+	eddsaProtocolName    = "blsCoSiEdDSA"
+	eddsaSubProtocolName = "blsCoSiEdDSASub"
+)
+
+// eddsaVerify is the verification of the ftcosi protocols, which accepts any
+// message and keeps its receipt.
+func (s *Service) eddsaVerify(msg, data []byte) bool {
+	s.onSigned(msg)
+	return true
+}
+
+// registerEdDSA registers the ftcosi protocols used for the EdDSA
+// signatures. The nodes sign with their key weighted by its coefficient.
+func (s *Service) registerEdDSA() error {
+	_, err := s.ProtocolRegister(eddsaSubProtocolName, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		pi, err := ftprotocol.NewSubFtCosi(n, s.eddsaVerify, cothority.Suite)
+		if err != nil {
+			return nil, err
+		}
+		pi.(*ftprotocol.SubFtCosi).SigningKey = eddsaSigningKey(n.Roster(), n.Public(), n.Private())
+		return pi, nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.ProtocolRegister(eddsaProtocolName, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		pi, err := ftprotocol.NewFtCosi(n, s.eddsaVerify, eddsaSubProtocolName, cothority.Suite)
+		if err != nil {
+			return nil, err
+		}
+		p := pi.(*ftprotocol.FtCosi)
+		p.Publics = eddsaPublics(n.Roster())
+		p.SigningKey = eddsaSigningKey(n.Roster(), n.Public(), n.Private())
+		return p, nil
+	})
+	return err
+}
+
+// eddsaSignature collects a Schnorr collective signature of the request with
+// the ftcosi protocol. Every member of the roster must sign so that the
+// signature can be verified as a standard Ed25519 signature.
+func (s *Service) eddsaSignature(req *SignatureRequest) (*SignatureResponse, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster provided")
+	}
+	err := s.checkRequest(req.Client, req.ClientSignature, req.ClientTime, req.Message, req.Domain, req.Roster)
+	if err != nil {
+		return nil, err
+	}
+
+	nNodes := len(req.Roster.List)
+	rooted := req.Roster.NewRosterWithRoot(s.ServerIdentity())
+	if rooted == nil {
+		return nil, errors.New("we're not in the roster")
+	}
+	tree := rooted.GenerateNaryTree(nNodes)
+	if tree == nil {
+		return nil, errors.New("failed to generate tree")
+	}
+	pi, err := s.CreateProtocol(eddsaProtocolName, tree)
+	if err != nil {
+		return nil, errors.New("Couldn't make new protocol: " + err.Error())
+	}
+
+	msg := protocol.DomainMessage(req.Domain, req.Message)
+	p := pi.(*ftprotocol.FtCosi)
+	p.CreateProtocol = s.CreateProtocol
+	p.Msg = msg
+	p.NSubtrees = int(math.Sqrt(float64(nNodes)))
+	if p.NSubtrees < 1 {
+		p.NSubtrees = 1
+	}
+	p.Timeout = s.Timeout
+	// every member must sign
+	p.Threshold = p.Tree().Size()
+	if err := pi.Start(); err != nil {
+		return nil, err
+	}
+
+	var sig []byte
+	select {
+	case sig = <-p.FinalSignature:
+	case <-time.After(p.Timeout + time.Second):
+		return nil, errors.New("protocol timed out")
+	}
+	if sig == nil {
+		return nil, errors.New("the protocol failed to sign")
+	}
+
+	// the hash is the message ftcosi actually signs
+	h := cothority.Suite.Hash()
+	h.Write(msg)
+	return &SignatureResponse{
+		Hash:      h.Sum(nil),
+		Signature: sig,
+	}, nil
+}
+
+// eddsaCoefficients returns the coefficient of each key of the roster, as in
+// MuSig: a_i = H(L, X_i) where L is the sorted list of the keys. Weighting
+// the keys prevents a member from choosing its key to cancel the others in
+// the aggregate.
+func eddsaCoefficients(roster *onet.Roster) []kyber.Scalar {
+	publics := roster.Publics()
+	keys := make([][]byte, len(publics))
+	for i, pub := range publics {
+		keys[i], _ = pub.MarshalBinary()
+	}
+	sorted := append([][]byte{}, keys...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	h := sha512.New()
+	for _, key := range sorted {
+		h.Write(key)
+	}
+	list := h.Sum(nil)
+
+	coefs := make([]kyber.Scalar, len(keys))
+	for i, key := range keys {
+		h := sha512.New()
+		h.Write(list)
+		h.Write(key)
+		coefs[i] = cothority.Suite.Scalar().SetBytes(h.Sum(nil))
+	}
+	return coefs
+}
+
+// eddsaPublics returns the keys of the roster weighted by their
+// coefficients.
+func eddsaPublics(roster *onet.Roster) []kyber.Point {
+	coefs := eddsaCoefficients(roster)
+	publics := roster.Publics()
+	for i := range publics {
+		publics[i] = cothority.Suite.Point().Mul(coefs[i], publics[i])
+	}
+	return publics
+}
+
+// eddsaSigningKey returns the private key weighted by the coefficient of its
+// public key in the roster.
+func eddsaSigningKey(roster *onet.Roster, public kyber.Point, private kyber.Scalar) kyber.Scalar {
+	coefs := eddsaCoefficients(roster)
+	for i, pub := range roster.Publics() {
+		if pub.Equal(public) {
+			return cothority.Suite.Scalar().Mul(coefs[i], private)
+		}
+	}
+	return private
+}
+
+// EdDSAPublic returns the aggregate public key of the roster, the sum of its
+// keys weighted by their coefficients. The first 64 bytes of an EdDSA
+// collective signature are a standard Ed25519 signature for this key.
+func EdDSAPublic(roster *onet.Roster) kyber.Point {
+	agg := cothority.Suite.Point().Null()
+	for _, pub := range eddsaPublics(roster) {
+		agg.Add(agg, pub)
+	}
+	return agg
+}
+
+// VerifyEdDSA checks an EdDSA collective signature of the message with the
+// given domain-separation tag, which can be nil. Every member of the roster
+// must have signed.
+func VerifyEdDSA(roster *onet.Roster, domain, msg, sig []byte) error {
+	if roster == nil {
+		return errors.New("no roster provided")
+	}
+	return cosi.Verify(cothority.Suite, eddsaPublics(roster),
+		protocol.DomainMessage(domain, msg), sig, cosi.CompletePolicy{})
+}
@@ -0,0 +1,50 @@
+package blscosi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3/sign/eddsa"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestClient_SignatureRequestEdDSA(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	_, roster, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	msg := []byte("hello blscosi service")
+	domain := []byte("test")
+
+	_, err := client.SignatureRequestEdDSA(&onet.Roster{}, domain, msg)
+	require.Error(t, err)
+
+	reply, err := client.SignatureRequestEdDSA(roster, domain, msg)
+	require.NoError(t, err)
+	require.NoError(t, VerifyEdDSA(roster, domain, msg, reply.Signature))
+	require.Error(t, VerifyEdDSA(roster, nil, msg, reply.Signature))
+
+	// the signature is a standard Ed25519 one for the aggregate key
+	require.NoError(t, eddsa.Verify(EdDSAPublic(roster),
+		protocol.DomainMessage(domain, msg), reply.Signature[:64]))
+}
+
+func TestEdDSAPublic(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	_, roster, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	// the aggregate doesn't depend on the order of the roster
+	agg := EdDSAPublic(roster)
+	require.True(t, agg.Equal(EdDSAPublic(roster.NewRosterWithRoot(roster.List[2]))))
+
+	// the keys are weighted, so the aggregate is not their sum
+	sum := cothority.Suite.Point().Null()
+	for _, pub := range roster.Publics() {
+		sum.Add(sum, pub)
+	}
+	require.False(t, agg.Equal(sum))
+}
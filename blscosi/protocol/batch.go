@@ -0,0 +1,143 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// BatchItem is a collective signature of a message that is verified with
+// others in a batch.
+type BatchItem struct {
+	Msg       []byte
+	Signature BlsSignature
+	// Publics are the public keys of the roster that created the signature.
+	Publics []kyber.Point
+	// Policy is checked against the mask of the signature. If it is nil, the
+	// default threshold policy is used.
+	Policy sign.Policy
+}
+
+// AggregatePublicsFn returns the public key used to verify the signature of
+// the participants enabled in the mask.
+type AggregatePublicsFn func(suite pairing.Suite, mask *sign.Mask) (kyber.Point, error)
+
+// BatchVerify checks the collective signatures of many messages using a single
+// pairing verification. It returns nil only if every signature is valid and
+// fulfills its policy. When the batch is invalid, the signatures are verified
+// one by one to return the index of the first faulty one.
+func BatchVerify(suite pairing.Suite, items []BatchItem) error {
+	return BatchVerifyWith(suite, items, aggregatePublics)
+}
+
+// BatchVerifyWith is the same as BatchVerify but uses the given function to
+// aggregate the public keys of the participants, so that it can be used for
+// other schemes like BDN.
+//
+// Every signature and its aggregate public key are multiplied by a random
+// scalar before being summed so that invalid signatures can't cancel each
+// other out.
+func BatchVerifyWith(suite pairing.Suite, items []BatchItem, fn AggregatePublicsFn) error {
+	if len(items) == 0 {
+		return errors.New("no signature provided")
+	}
+
+	lenCom := suite.G1().PointLen()
+	aggSig := suite.G1().Point().Null()
+	// The signatures of the same message are verified with the sum of their
+	// public keys because the messages of a batch must be distinct.
+	msgs := [][]byte{}
+	keys := []kyber.Point{}
+	msgIndex := make(map[string]int)
+
+	for i, item := range items {
+		pub, sig, err := item.prepare(suite, lenCom, fn)
+		if err != nil {
+			return fmt.Errorf("signature %d: %v", i, err)
+		}
+
+		r := suite.G2().Scalar().Pick(random.New())
+		aggSig.Add(aggSig, sig.Mul(r, sig))
+		pub.Mul(r, pub)
+
+		idx, ok := msgIndex[string(item.Msg)]
+		if ok {
+			keys[idx].Add(keys[idx], pub)
+		} else {
+			msgIndex[string(item.Msg)] = len(msgs)
+			msgs = append(msgs, item.Msg)
+			keys = append(keys, pub)
+		}
+	}
+
+	buf, err := aggSig.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if bls.BatchVerify(suite, keys, msgs, buf) == nil {
+		return nil
+	}
+
+	// Find the faulty signature to help the caller.
+	for i, item := range items {
+		pub, _, err := item.prepare(suite, lenCom, fn)
+		if err != nil {
+			return fmt.Errorf("signature %d: %v", i, err)
+		}
+		err = bls.Verify(suite, pub, item.Msg, item.Signature[:lenCom])
+		if err != nil {
+			return fmt.Errorf("signature %d: didn't get a valid signature: %v", i, err)
+		}
+	}
+	return errors.New("batch verification failed")
+}
+
+// prepare checks the policy of the item and returns the aggregate public key
+// of the participants and the point of the signature.
+func (item BatchItem) prepare(suite pairing.Suite, lenCom int, fn AggregatePublicsFn) (kyber.Point, kyber.Point, error) {
+	if len(item.Publics) == 0 {
+		return nil, nil, errors.New("no public keys provided")
+	}
+	if item.Msg == nil {
+		return nil, nil, errors.New("no message provided")
+	}
+	if len(item.Signature) < lenCom {
+		return nil, nil, errors.New("invalid signature length")
+	}
+
+	mask, err := item.Signature.GetMask(suite, item.Publics)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy := item.Policy
+	if policy == nil {
+		policy = sign.NewThresholdPolicy(DefaultThreshold(len(item.Publics)))
+	}
+	if !policy.Check(mask) {
+		return nil, nil, errors.New("the policy is not fulfilled")
+	}
+
+	pub, err := fn(suite, mask)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sig, err := item.Signature[:lenCom].Point(suite)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pub, sig, nil
+}
+
+// aggregatePublics sums the public keys of the participants.
+func aggregatePublics(suite pairing.Suite, mask *sign.Mask) (kyber.Point, error) {
+	return bls.AggregatePublicKeys(suite, mask.Participants()...), nil
+}
@@ -0,0 +1,72 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// makeCollectiveSignature signs the message with the first n secrets and
+// returns the aggregated signature with the mask appended.
+func makeCollectiveSignature(t *testing.T, secrets []kyber.Scalar, publics []kyber.Point, n int, msg []byte) BlsSignature {
+	mask, err := sign.NewMask(testSuite, publics, nil)
+	require.NoError(t, err)
+
+	sigs := [][]byte{}
+	for i := 0; i < n; i++ {
+		sig, err := bls.Sign(testSuite, secrets[i], msg)
+		require.NoError(t, err)
+		sigs = append(sigs, sig)
+		require.NoError(t, mask.SetBit(i, true))
+	}
+
+	agg, err := bls.AggregateSignatures(testSuite, sigs...)
+	require.NoError(t, err)
+	return append(agg, mask.Mask()...)
+}
+
+func TestBatchVerify(t *testing.T) {
+	n := 4
+	secrets := make([]kyber.Scalar, n)
+	publics := make([]kyber.Point, n)
+	for i := range secrets {
+		secrets[i], publics[i] = bls.NewKeyPair(testSuite, random.New())
+	}
+
+	msgs := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("a")}
+	items := make([]BatchItem, len(msgs))
+	for i, msg := range msgs {
+		items[i] = BatchItem{
+			Msg:       msg,
+			Signature: makeCollectiveSignature(t, secrets, publics, n-i%2, msg),
+			Publics:   publics,
+		}
+	}
+
+	require.Error(t, BatchVerify(testSuite, nil))
+	require.NoError(t, BatchVerify(testSuite, items[:1]))
+	// duplicated messages are accepted
+	require.NoError(t, BatchVerify(testSuite, items))
+
+	// the policy is checked for each signature
+	items[1].Policy = sign.NewThresholdPolicy(n)
+	err := BatchVerify(testSuite, items)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "signature 1:")
+	items[1].Policy = nil
+
+	// a signature of the wrong message is detected
+	items[2].Msg = []byte("d")
+	err = BatchVerify(testSuite, items)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "signature 2:")
+	items[2].Msg = msgs[2]
+
+	// swapping the signatures keeps the sum the same but must be detected
+	items[1].Signature, items[2].Signature = items[2].Signature, items[1].Signature
+	require.Error(t, BatchVerify(testSuite, items))
+}
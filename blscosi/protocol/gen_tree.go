@@ -16,6 +16,56 @@ func NewBlsProtocolTree(tree *onet.Tree, nSubTrees int) (BlsProtocolTree, error)
 	return genTrees(tree, nSubTrees)
 }
 
+// TreeBuilderFn groups the nodes of the tree into the subtrees used by the
+// protocol. It can be used to build the subtrees out of the latencies between
+// the nodes, for example by putting close nodes in the same subtree.
+type TreeBuilderFn func(tree *onet.Tree) (BlsProtocolTree, error)
+
+// NewBlsProtocolTreeWithFanout creates the subtrees so that every subleader
+// has at most fanout children. The depth of the tree is fixed to three
+// levels by the protocol, so a smaller fanout makes more subtrees.
+func NewBlsProtocolTreeWithFanout(tree *onet.Tree, fanout int) (BlsProtocolTree, error) {
+	if fanout < 0 {
+		return nil, fmt.Errorf("fanout cannot be negative, but is %d", fanout)
+	}
+	nNodes := len(tree.Roster.List) - 1
+	// each subtree has a subleader and up to fanout leaves
+	nSubtrees := (nNodes + fanout) / (fanout + 1)
+	if nSubtrees < 1 {
+		nSubtrees = 1
+	}
+	return genTrees(tree, nSubtrees)
+}
+
+// NewBlsProtocolTreeFromGroups creates one subtree for each group of indexes
+// to the roster of the tree. The first index of a group is the subleader and
+// the others are its leaves. Nodes absent from the groups won't be asked to
+// sign.
+func NewBlsProtocolTreeFromGroups(tree *onet.Tree, groups [][]int) (BlsProtocolTree, error) {
+	if len(groups) == 0 {
+		return nil, errors.New("need at least one group")
+	}
+
+	root := tree.Root.RosterIndex
+	seen := map[int]bool{root: true}
+	trees := make(BlsProtocolTree, len(groups))
+	for i, group := range groups {
+		for _, idx := range group {
+			if seen[idx] {
+				return nil, fmt.Errorf("index %d is the root or in more than one group", idx)
+			}
+			seen[idx] = true
+		}
+
+		var err error
+		trees[i], err = genSubtree(tree.Roster, append([]int{root}, group...))
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %v", i, err)
+		}
+	}
+	return trees, nil
+}
+
 // GetLeaves returns the server identities of the leaves
 func (pt BlsProtocolTree) GetLeaves() []*network.ServerIdentity {
 	si := []*network.ServerIdentity{}
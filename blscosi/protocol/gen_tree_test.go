@@ -360,3 +360,66 @@ func TestGenSubtreeErrors(t *testing.T) {
 		local.CloseAll()
 	}
 }
+
+// tests that the subleaders don't have more children than the fanout
+func TestGenTreesWithFanout(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	defer local.CloseAll()
+	_, _, tree := local.GenTree(11, false)
+
+	for _, fanout := range []int{0, 1, 3, 9, 20} {
+		trees, err := NewBlsProtocolTreeWithFanout(tree, fanout)
+		if err != nil {
+			t.Fatal("Error in tree generation:", err)
+		}
+		count := 0
+		for _, subtree := range trees {
+			subleader := subtree.Root.Children[0]
+			if len(subleader.Children) > fanout {
+				t.Fatal("subleader has", len(subleader.Children), "children with a fanout of", fanout)
+			}
+			count += subtree.Size() - 1
+		}
+		if count != 10 {
+			t.Fatal("trees should cover 10 nodes, but have", count)
+		}
+	}
+
+	if _, err := NewBlsProtocolTreeWithFanout(tree, -1); err == nil {
+		t.Fatal("a negative fanout should be refused")
+	}
+}
+
+// tests the generation of the subtrees out of groups of nodes
+func TestGenTreesFromGroups(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	defer local.CloseAll()
+	_, _, tree := local.GenTree(6, false)
+
+	trees, err := NewBlsProtocolTreeFromGroups(tree, [][]int{{3, 1}, {2, 4, 5}})
+	if err != nil {
+		t.Fatal("Error in tree generation:", err)
+	}
+	if len(trees) != 2 {
+		t.Fatal("there should be 2 subtrees, but there are", len(trees))
+	}
+	if !trees[0].Root.Children[0].ServerIdentity.Equal(tree.Roster.List[3]) {
+		t.Fatal("the first node of a group should be the subleader")
+	}
+	if len(trees[1].Root.Children[0].Children) != 2 {
+		t.Fatal("the second subleader should have 2 children")
+	}
+
+	if _, err := NewBlsProtocolTreeFromGroups(tree, nil); err == nil {
+		t.Fatal("empty groups should be refused")
+	}
+	if _, err := NewBlsProtocolTreeFromGroups(tree, [][]int{{1, 2}, {2, 3}}); err == nil {
+		t.Fatal("a node in two groups should be refused")
+	}
+	if _, err := NewBlsProtocolTreeFromGroups(tree, [][]int{{1, 0}}); err == nil {
+		t.Fatal("the root in a group should be refused")
+	}
+	if _, err := NewBlsProtocolTreeFromGroups(tree, [][]int{{1}, {}}); err == nil {
+		t.Fatal("an empty group should be refused")
+	}
+}
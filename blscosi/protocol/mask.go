@@ -0,0 +1,167 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Mask tells which members of a roster participated in a collective
+// signature. The bits follow the order of the roster and use the same
+// encoding as the mask appended to the signatures.
+type Mask struct {
+	Roster *onet.Roster
+	Bits   []byte
+}
+
+// NewMask creates a mask for the roster out of the given bits. If bits is
+// nil, every member is disabled.
+func NewMask(roster *onet.Roster, bits []byte) (*Mask, error) {
+	if roster == nil || len(roster.List) == 0 {
+		return nil, errors.New("empty roster")
+	}
+	lenBits := (len(roster.List) + 7) >> 3
+	if bits == nil {
+		bits = make([]byte, lenBits)
+	}
+	if len(bits) != lenBits {
+		return nil, fmt.Errorf("mask of %d bytes for a roster of %d members",
+			len(bits), len(roster.List))
+	}
+
+	m := &Mask{
+		Roster: roster,
+		Bits:   make([]byte, lenBits),
+	}
+	copy(m.Bits, bits)
+	return m, nil
+}
+
+// ParticipationMask returns the mask of the signature for the roster that
+// created it.
+func (sig BlsSignature) ParticipationMask(suite pairing.Suite, roster *onet.Roster) (*Mask, error) {
+	if roster == nil {
+		return nil, errors.New("empty roster")
+	}
+	lenCom := suite.G1().PointLen()
+	if len(sig) < lenCom {
+		return nil, errors.New("invalid signature length")
+	}
+
+	bits := sig[lenCom:]
+	if len(bits) == 0 {
+		// no mask means that every member signed
+		m, err := NewMask(roster, nil)
+		if err != nil {
+			return nil, err
+		}
+		for i := range roster.List {
+			m.SetBit(i, true)
+		}
+		return m, nil
+	}
+
+	return NewMask(roster, bits)
+}
+
+// IsEnabled returns true if the member at the given index participated.
+func (m *Mask) IsEnabled(i int) bool {
+	if i < 0 || i >= len(m.Roster.List) {
+		return false
+	}
+	return m.Bits[i>>3]&(byte(1)<<uint(i&7)) != 0
+}
+
+// SetBit enables or disables the member at the given index.
+func (m *Mask) SetBit(i int, enable bool) error {
+	if i < 0 || i >= len(m.Roster.List) {
+		return errors.New("index out of range")
+	}
+	if enable {
+		m.Bits[i>>3] |= byte(1) << uint(i&7)
+	} else {
+		m.Bits[i>>3] &^= byte(1) << uint(i&7)
+	}
+	return nil
+}
+
+// CountEnabled returns the number of participants.
+func (m *Mask) CountEnabled() int {
+	n := 0
+	for i := range m.Roster.List {
+		if m.IsEnabled(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// Participants returns the server identities of the members that
+// participated, in the order of the roster.
+func (m *Mask) Participants() []*network.ServerIdentity {
+	sis := []*network.ServerIdentity{}
+	for i, si := range m.Roster.List {
+		if m.IsEnabled(i) {
+			sis = append(sis, si)
+		}
+	}
+	return sis
+}
+
+// Translate returns the mask of the same participants for another roster,
+// for example a reordered one or one where members have been added or
+// removed. It returns an error if a participant is not a member of the new
+// roster as the signature can't be verified with it anymore.
+func (m *Mask) Translate(roster *onet.Roster) (*Mask, error) {
+	tm, err := NewMask(roster, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, si := range m.Participants() {
+		idx, _ := roster.Search(si.ID)
+		if idx < 0 {
+			return nil, fmt.Errorf("participant %v is not in the roster", si)
+		}
+		tm.SetBit(idx, true)
+	}
+	return tm, nil
+}
+
+// SignMask returns the kyber mask to use with the public keys of the roster,
+// in the same order.
+func (m *Mask) SignMask(suite pairing.Suite, publics []kyber.Point) (*sign.Mask, error) {
+	if len(publics) != len(m.Roster.List) {
+		return nil, errors.New("the number of public keys doesn't match the roster")
+	}
+	mask, err := sign.NewMask(suite, publics, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = mask.SetMask(m.Bits)
+	if err != nil {
+		return nil, err
+	}
+	return mask, nil
+}
+
+// String returns a human-readable list of the participants using their
+// description, or their address if there is none.
+func (m *Mask) String() string {
+	names := []string{}
+	for _, si := range m.Participants() {
+		name := si.Description
+		if name == "" {
+			name = si.Address.String()
+		}
+		names = append(names, name)
+	}
+	return fmt.Sprintf("%d/%d signers: %s", len(names), len(m.Roster.List),
+		strings.Join(names, ", "))
+}
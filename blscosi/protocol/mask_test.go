@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func genRoster(n int) *onet.Roster {
+	sis := make([]*network.ServerIdentity, n)
+	for i := range sis {
+		addr := network.NewAddress(network.TLS, fmt.Sprintf("127.0.0.1:%d", 7000+i))
+		sis[i] = network.NewServerIdentity(key.NewKeyPair(cothority.Suite).Public, addr)
+		sis[i].Description = fmt.Sprintf("node%d", i)
+	}
+	return onet.NewRoster(sis)
+}
+
+func TestMask_Translate(t *testing.T) {
+	roster := genRoster(10)
+
+	_, err := NewMask(roster, []byte{0})
+	require.Error(t, err)
+
+	m, err := NewMask(roster, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, m.CountEnabled())
+	require.NoError(t, m.SetBit(1, true))
+	require.NoError(t, m.SetBit(8, true))
+	require.Error(t, m.SetBit(10, true))
+	require.True(t, m.IsEnabled(8))
+	require.False(t, m.IsEnabled(10))
+	require.Equal(t, "2/10 signers: node1, node8", m.String())
+
+	// reversing the roster must keep the same participants
+	list := make([]*network.ServerIdentity, len(roster.List))
+	for i, si := range roster.List {
+		list[len(list)-1-i] = si
+	}
+	reversed := onet.NewRoster(list)
+	tm, err := m.Translate(reversed)
+	require.NoError(t, err)
+	require.True(t, tm.IsEnabled(8))
+	require.True(t, tm.IsEnabled(1))
+	require.Equal(t, 2, tm.CountEnabled())
+	require.Equal(t, "2/10 signers: node8, node1", tm.String())
+
+	// a smaller roster with the participants is fine
+	smaller := onet.NewRoster(roster.List[1:9])
+	tm, err = m.Translate(smaller)
+	require.NoError(t, err)
+	require.Equal(t, []*network.ServerIdentity{roster.List[1], roster.List[8]},
+		tm.Participants())
+
+	// but not if a participant is missing
+	_, err = m.Translate(onet.NewRoster(roster.List[2:]))
+	require.Error(t, err)
+}
+
+func TestBlsSignature_ParticipationMask(t *testing.T) {
+	roster := genRoster(3)
+	lenCom := testSuite.G1().PointLen()
+
+	_, err := BlsSignature([]byte{1}).ParticipationMask(testSuite, roster)
+	require.Error(t, err)
+
+	sig := BlsSignature(make([]byte, lenCom))
+	m, err := sig.ParticipationMask(testSuite, roster)
+	require.NoError(t, err)
+	require.Equal(t, 3, m.CountEnabled())
+
+	sig = append(sig, 0x5)
+	m, err = sig.ParticipationMask(testSuite, roster)
+	require.NoError(t, err)
+	require.Equal(t, []*network.ServerIdentity{roster.List[0], roster.List[2]},
+		m.Participants())
+}
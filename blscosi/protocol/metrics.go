@@ -0,0 +1,155 @@
+package protocol
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Metrics is notified by the root of the latencies measured in the tree so
+// that slow subtrees can be identified. The subleaders are at level 1 and
+// the leaves at level 2. The methods can be called concurrently.
+type Metrics interface {
+	// NodeLatency is called with the time a node took to respond once it
+	// has been contacted by its parent. The latency of a subleader includes
+	// the time it waited for its leaves.
+	NodeLatency(si *network.ServerIdentity, level int, latency time.Duration)
+	// LevelLatency is called at the end of the protocol with the latency of
+	// the slowest node of a level.
+	LevelLatency(level int, latency time.Duration)
+}
+
+// Latency is the time a node took to respond, where the node is referred to
+// by its index in the roster.
+type Latency struct {
+	Index    int
+	Duration time.Duration
+}
+
+// LatencyStat holds aggregated values of latencies.
+type LatencyStat struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the average latency.
+func (ls LatencyStat) Mean() time.Duration {
+	if ls.Count == 0 {
+		return 0
+	}
+	return ls.Total / time.Duration(ls.Count)
+}
+
+func (ls *LatencyStat) add(d time.Duration) {
+	ls.Count++
+	ls.Total += d
+	if d > ls.Max {
+		ls.Max = d
+	}
+}
+
+// NodeLatencyStat are the latencies of a node at a given level.
+type NodeLatencyStat struct {
+	ServerIdentity *network.ServerIdentity
+	Level          int
+	LatencyStat
+}
+
+// LatencyRecorder is an implementation of Metrics that keeps the statistics
+// in memory over multiple runs of the protocol.
+type LatencyRecorder struct {
+	sync.Mutex
+	levels map[int]*LatencyStat
+	nodes  map[network.ServerIdentityID]*NodeLatencyStat
+}
+
+// NewLatencyRecorder returns an empty recorder.
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{
+		levels: make(map[int]*LatencyStat),
+		nodes:  make(map[network.ServerIdentityID]*NodeLatencyStat),
+	}
+}
+
+// NodeLatency implements Metrics.
+func (lr *LatencyRecorder) NodeLatency(si *network.ServerIdentity, level int, latency time.Duration) {
+	lr.Lock()
+	defer lr.Unlock()
+	stat, ok := lr.nodes[si.ID]
+	if !ok {
+		stat = &NodeLatencyStat{ServerIdentity: si}
+		lr.nodes[si.ID] = stat
+	}
+	// a node can change level when its subtree gets a new subleader
+	stat.Level = level
+	stat.add(latency)
+}
+
+// LevelLatency implements Metrics.
+func (lr *LatencyRecorder) LevelLatency(level int, latency time.Duration) {
+	lr.Lock()
+	defer lr.Unlock()
+	stat, ok := lr.levels[level]
+	if !ok {
+		stat = &LatencyStat{}
+		lr.levels[level] = stat
+	}
+	stat.add(latency)
+}
+
+// Levels returns a copy of the statistics of each level.
+func (lr *LatencyRecorder) Levels() map[int]LatencyStat {
+	lr.Lock()
+	defer lr.Unlock()
+	levels := make(map[int]LatencyStat, len(lr.levels))
+	for l, stat := range lr.levels {
+		levels[l] = *stat
+	}
+	return levels
+}
+
+// SlowestNodes returns the statistics of the nodes, starting with the
+// highest mean latency.
+func (lr *LatencyRecorder) SlowestNodes() []NodeLatencyStat {
+	lr.Lock()
+	nodes := make([]NodeLatencyStat, 0, len(lr.nodes))
+	for _, stat := range lr.nodes {
+		nodes = append(nodes, *stat)
+	}
+	lr.Unlock()
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Mean() > nodes[j].Mean()
+	})
+	return nodes
+}
+
+// levelLatencies keeps the latency of the slowest node of each level during
+// one run of the protocol.
+type levelLatencies struct {
+	sync.Mutex
+	max map[int]time.Duration
+}
+
+func newLevelLatencies() *levelLatencies {
+	return &levelLatencies{max: make(map[int]time.Duration)}
+}
+
+func (ll *levelLatencies) add(level int, latency time.Duration) {
+	ll.Lock()
+	if latency > ll.max[level] {
+		ll.max[level] = latency
+	}
+	ll.Unlock()
+}
+
+func (ll *levelLatencies) report(m Metrics) {
+	ll.Lock()
+	defer ll.Unlock()
+	for level, latency := range ll.max {
+		m.LevelLatency(level, latency)
+	}
+}
@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+)
+
+// PartialSignature is the signature of a single member of the roster, where
+// the member is referred to by its index in the roster.
+type PartialSignature struct {
+	Index     int
+	Signature []byte
+}
+
+// Verify checks the partial signature over the message with the public key
+// of the member.
+func (ps PartialSignature) Verify(suite pairing.Suite, msg []byte, publics []kyber.Point) error {
+	if ps.Index < 0 || ps.Index >= len(publics) {
+		return fmt.Errorf("index %d out of range", ps.Index)
+	}
+	return bls.Verify(suite, publics[ps.Index], msg, ps.Signature)
+}
+
+// AggregatePartials verifies the partial signatures and aggregates them into
+// a collective signature with its mask, as soon as threshold valid ones are
+// found. Invalid and duplicated partial signatures are ignored so that a
+// single faulty member can't prevent the aggregation.
+func AggregatePartials(suite pairing.Suite, msg []byte, publics []kyber.Point,
+	partials []PartialSignature, threshold int) (BlsSignature, error) {
+	return AggregatePartialsWith(suite, msg, publics, partials, threshold, aggregate)
+}
+
+// AggregatePartialsWith is the same as AggregatePartials but uses the given
+// function to aggregate the signatures, so that it can be used for other
+// schemes like BDN.
+func AggregatePartialsWith(suite pairing.Suite, msg []byte, publics []kyber.Point,
+	partials []PartialSignature, threshold int, fn AggregateFn) (BlsSignature, error) {
+	if len(publics) == 0 {
+		return nil, errors.New("no public keys provided")
+	}
+	if threshold < 1 || threshold > len(publics) {
+		return nil, fmt.Errorf("invalid threshold %d for %d members", threshold, len(publics))
+	}
+
+	mask, err := sign.NewMask(suite, publics, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([][]byte, len(publics))
+	count := 0
+	for _, ps := range partials {
+		if count == threshold {
+			break
+		}
+		if ps.Index < 0 || ps.Index >= len(publics) || valid[ps.Index] != nil {
+			continue
+		}
+		if err := ps.Verify(suite, msg, publics); err != nil {
+			continue
+		}
+
+		valid[ps.Index] = ps.Signature
+		mask.SetBit(ps.Index, true)
+		count++
+	}
+
+	if count < threshold {
+		return nil, fmt.Errorf("only %d valid partial signatures for a threshold of %d",
+			count, threshold)
+	}
+
+	// the signatures must follow the order of the mask for BDN
+	sigs := make([][]byte, 0, count)
+	for _, sig := range valid {
+		if sig != nil {
+			sigs = append(sigs, sig)
+		}
+	}
+
+	sig, err := fn(suite, mask, sigs)
+	if err != nil {
+		return nil, err
+	}
+	return append(sig, mask.Mask()...), nil
+}
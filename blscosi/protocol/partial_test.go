@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func TestAggregatePartials(t *testing.T) {
+	n := 5
+	msg := []byte("abc")
+	publics := make([]kyber.Point, n)
+	partials := make([]PartialSignature, n)
+	for i := range publics {
+		var secret kyber.Scalar
+		secret, publics[i] = bls.NewKeyPair(testSuite, random.New())
+		sig, err := bls.Sign(testSuite, secret, msg)
+		require.NoError(t, err)
+		partials[i] = PartialSignature{Index: i, Signature: sig}
+	}
+
+	_, err := AggregatePartials(testSuite, msg, publics, partials, n+1)
+	require.Error(t, err)
+
+	// a faulty signature and a duplicate are ignored
+	faulty := []PartialSignature{
+		{Index: 0, Signature: partials[1].Signature},
+		partials[1], partials[1], partials[3], partials[4],
+	}
+	sig, err := AggregatePartials(testSuite, msg, publics, faulty, 3)
+	require.NoError(t, err)
+	mask, err := sig.GetMask(testSuite, publics)
+	require.NoError(t, err)
+	require.Equal(t, 3, mask.CountEnabled())
+	require.NoError(t, sig.Verify(testSuite, msg, publics))
+
+	_, err = AggregatePartials(testSuite, msg, publics, faulty, 4)
+	require.Error(t, err)
+}
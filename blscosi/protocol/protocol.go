@@ -12,9 +12,11 @@ import (
 	"sync"
 	"time"
 
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
 	"go.dedis.ch/kyber/v3/sign/bls"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -55,18 +57,46 @@ type BlsCosi struct {
 	*onet.TreeNodeInstance
 	Msg            []byte
 	Data           []byte
+	Domain         []byte // domain-separation tag, see DomainMessage
 	CreateProtocol CreateProtocolFunction
 	Verify         VerifyFn
 	Sign           SignFn
 	Aggregate      AggregateFn
 	// Timeout is not a global timeout for the protocol, but a timeout used
 	// for waiting for responses for sub protocols.
-	Timeout           time.Duration
+	Timeout time.Duration
+	// SubleaderTimeout is the time given to a subleader to respond before a
+	// new subleader is chosen for its subtree. When it is zero, the Timeout is
+	// divided so that SubleaderFailures+1 subleaders can be tried.
+	SubleaderTimeout time.Duration
+	// SubleaderFailures is the number of times a subtree gets a new subleader
+	// before it is abandoned and its nodes are counted as failing.
 	SubleaderFailures int
 	Threshold         int
-	FinalSignature    chan []byte // final signature that is sent back to client
-
-	stoppedOnce      sync.Once
+	// MinResponses is the number of signatures, including the one of the
+	// root, which is still accepted when the Timeout is reached before the
+	// Threshold. When it is zero, the Threshold is used.
+	MinResponses   int
+	FinalSignature chan []byte // final signature that is sent back to client
+	// Metrics, if not nil, is given the latencies of the nodes and of the
+	// levels of the tree.
+	Metrics Metrics
+	// OnSigned, if not nil, is called with the message after the root signed
+	// it, including the domain-separation tag.
+	OnSigned func(msg []byte)
+	// TraceID identifies the signature in the spans of the nodes. A new one
+	// is created by Start if it is not set.
+	TraceID tracing.ID
+	// SpanID is the parent of the span of the protocol, if any.
+	SpanID tracing.SpanID
+
+	span        *tracing.Span
+	bdn         bool
+	stoppedOnce sync.Once
+	// stopped is closed by Shutdown, under finalLock so that the signature
+	// is never sent on the closed FinalSignature.
+	stopped          chan struct{}
+	finalLock        sync.Mutex
 	subProtocolsLock sync.Mutex
 	subProtocols     []*SubBlsCosi
 	subProtocolName  string
@@ -104,6 +134,7 @@ func NewBlsCosi(n *onet.TreeNodeInstance, vf VerificationFn, subProtocolName str
 	c := &BlsCosi{
 		TreeNodeInstance:  n,
 		FinalSignature:    make(chan []byte, 1),
+		stopped:           make(chan struct{}),
 		Timeout:           defaultTimeout,
 		SubleaderFailures: defaultSubleaderFailures,
 		Threshold:         DefaultThreshold(nNodes),
@@ -118,6 +149,18 @@ func NewBlsCosi(n *onet.TreeNodeInstance, vf VerificationFn, subProtocolName str
 	return c, nil
 }
 
+// UseBdn makes the protocol sign and aggregate with the BDN signature scheme
+// which protects the aggregate against rogue public-key attacks. The
+// sub-protocols are told to do the same in the announcement so that it can be
+// used with the default protocol names. The final signature must then be
+// verified as a BDN signature.
+func (p *BlsCosi) UseBdn() {
+	p.bdn = true
+	p.Sign = bdn.Sign
+	p.Verify = bdn.Verify
+	p.Aggregate = bdnAggregate
+}
+
 // SetNbrSubTree generates N new subtrees that will be used
 // for the protocol
 func (p *BlsCosi) SetNbrSubTree(nbr int) error {
@@ -141,9 +184,58 @@ func (p *BlsCosi) SetNbrSubTree(nbr int) error {
 	return nil
 }
 
-// Shutdown stops the protocol
+// SetFanout generates the subtrees so that every subleader has at most
+// fanout children.
+func (p *BlsCosi) SetFanout(fanout int) error {
+	if p.Threshold == 1 {
+		p.subTrees = []*onet.Tree{}
+		return nil
+	}
+
+	var err error
+	p.subTrees, err = NewBlsProtocolTreeWithFanout(p.Tree(), fanout)
+	if err != nil {
+		return xerrors.Errorf("error in tree generation: %v", err)
+	}
+
+	return nil
+}
+
+// SetTreeBuilder uses the given function to generate the subtrees of the
+// protocol.
+func (p *BlsCosi) SetTreeBuilder(fn TreeBuilderFn) error {
+	if p.Threshold == 1 {
+		p.subTrees = []*onet.Tree{}
+		return nil
+	}
+
+	trees, err := fn(p.Tree())
+	if err != nil {
+		return xerrors.Errorf("error in tree generation: %v", err)
+	}
+	for i, tree := range trees {
+		if !tree.Root.ServerIdentity.Equal(p.ServerIdentity()) {
+			return xerrors.Errorf("subtree %d is not rooted at this node", i)
+		}
+		if len(tree.Root.Children) != 1 {
+			return xerrors.Errorf("subtree %d must have exactly one subleader", i)
+		}
+	}
+	p.subTrees = trees
+
+	return nil
+}
+
+// Shutdown stops the protocol. If it is called before the signature is
+// produced, the protocol stops collecting the responses and FinalSignature
+// is closed without a signature.
 func (p *BlsCosi) Shutdown() error {
 	p.stoppedOnce.Do(func() {
+		p.finalLock.Lock()
+		close(p.stopped)
+		close(p.FinalSignature)
+		p.finalLock.Unlock()
+
 		p.subProtocolsLock.Lock()
 		for _, subCosi := range p.subProtocols {
 			// we're stopping the root thus it will stop the children
@@ -151,7 +243,6 @@ func (p *BlsCosi) Shutdown() error {
 			subCosi.Shutdown()
 		}
 		p.subProtocolsLock.Unlock()
-		close(p.FinalSignature)
 	})
 
 	log.Lvl3("BLS CoSi ends")
@@ -189,6 +280,10 @@ func (p *BlsCosi) Start() error {
 	}
 
 	log.Lvlf3("Starting BLS CoSi on %v", p.ServerIdentity())
+	p.TraceID = tracing.OrNew(p.TraceID)
+	p.span = tracing.StartSpan(p.TraceID, p.SpanID, p.Name(), "nodes",
+		len(p.Roster().List), "subtrees", len(p.subTrees), "threshold",
+		p.Threshold)
 
 	go p.runSubProtocols()
 
@@ -196,21 +291,33 @@ func (p *BlsCosi) Start() error {
 }
 
 func (p *BlsCosi) runSubProtocols() {
+	var err error
+	defer func() { p.span.End(err) }()
 	defer p.Done()
 
 	// Verification of the data is done before contacting the children
-	if ok := p.verificationFn(p.Msg, p.Data); !ok {
+	phase := p.span.Child("verify")
+	ok := p.verificationFn(p.Msg, p.Data)
+	phase.End(nil)
+	if !ok {
 		// root should not fail the verification otherwise it would not have started the protocol
-		log.Errorf("verification failed on root node")
+		err = xerrors.New("verification failed on root node")
+		log.Error(err)
 		return
 	}
 
-	// start all subprotocols
+	// start all subprotocols, unless the protocol was shut down before
 	p.subProtocolsLock.Lock()
+	select {
+	case <-p.stopped:
+		p.subProtocolsLock.Unlock()
+		err = xerrors.New("protocol stopped")
+		return
+	default:
+	}
 	p.subProtocols = make([]*SubBlsCosi, len(p.subTrees))
 	for i, tree := range p.subTrees {
 		log.Lvlf3("Invoking start sub protocol on %v", tree.Root.ServerIdentity)
-		var err error
 		p.subProtocols[i], err = p.startSubProtocol(tree)
 		if err != nil {
 			p.subProtocolsLock.Unlock()
@@ -222,7 +329,10 @@ func (p *BlsCosi) runSubProtocols() {
 	log.Lvl3(p.ServerIdentity().Address, "all protocols started")
 
 	// Wait and collect all the signature responses
+	phase = p.span.Child("collectSignatures")
 	responses, err := p.collectSignatures()
+	phase.SetAttributes("responses", len(responses))
+	phase.End(err)
 	if err != nil {
 		log.Error(err)
 		return
@@ -231,13 +341,22 @@ func (p *BlsCosi) runSubProtocols() {
 	log.Lvl3(p.ServerIdentity().Address, "collected all signature responses")
 
 	// generate root signature
+	phase = p.span.Child("generateSignature")
 	sig, err := p.generateSignature(responses)
+	phase.End(err)
 	if err != nil {
 		log.Error(err)
 		return
 	}
 
-	p.FinalSignature <- sig
+	p.finalLock.Lock()
+	defer p.finalLock.Unlock()
+	select {
+	case <-p.stopped:
+		err = xerrors.New("protocol stopped before the signature was sent")
+	default:
+		p.FinalSignature <- sig
+	}
 }
 
 // checkIntegrity checks if the protocol has been instantiated with
@@ -264,14 +383,43 @@ func (p *BlsCosi) checkIntegrity() error {
 	if p.Threshold < 1 {
 		return fmt.Errorf("threshold of %d smaller than one node", p.Threshold)
 	}
+	if p.SubleaderTimeout < 0 {
+		return fmt.Errorf("negative subleader timeout")
+	}
+	if p.SubleaderFailures < 0 {
+		return fmt.Errorf("negative number of subleader failures")
+	}
+	if p.MinResponses < 0 || p.MinResponses > p.Threshold {
+		return fmt.Errorf("minimum responses (%d) must be between 0 and the threshold (%d)",
+			p.MinResponses, p.Threshold)
+	}
 
 	return nil
 }
 
 // checkFailureThreshold returns true when the number of failures
-// is above the threshold
+// is too high to get the minimum number of responses
 func (p *BlsCosi) checkFailureThreshold(numFailure int) bool {
-	return numFailure > len(p.Roster().List)-p.Threshold
+	return numFailure > len(p.Roster().List)-p.minResponses()
+}
+
+// minResponses returns the number of signatures needed for the protocol
+// to succeed when the timeout is reached.
+func (p *BlsCosi) minResponses() int {
+	if p.MinResponses > 0 {
+		return p.MinResponses
+	}
+	return p.Threshold
+}
+
+// subleaderTimeout returns the time a subprotocol waits for its subleader.
+func (p *BlsCosi) subleaderTimeout() time.Duration {
+	if p.SubleaderTimeout > 0 {
+		return p.SubleaderTimeout
+	}
+	// Fail fast enough if the subleader is failing to try
+	// at least three leaves as new subleader
+	return p.Timeout / time.Duration(p.SubleaderFailures+1)
 }
 
 // startSubProtocol creates, parametrize and starts a subprotocol on a given tree
@@ -284,9 +432,13 @@ func (p *BlsCosi) startSubProtocol(tree *onet.Tree) (*SubBlsCosi, error) {
 	cosiSubProtocol := pi.(*SubBlsCosi)
 	cosiSubProtocol.Msg = p.Msg
 	cosiSubProtocol.Data = p.Data
-	// Fail fast enough if the subleader is failing to try
-	// at least three leaves as new subleader
-	cosiSubProtocol.Timeout = p.Timeout / time.Duration(p.SubleaderFailures+1)
+	cosiSubProtocol.Domain = p.Domain
+	cosiSubProtocol.TraceID = p.TraceID
+	cosiSubProtocol.SpanID = p.span.ID()
+	if p.bdn {
+		cosiSubProtocol.UseBdn()
+	}
+	cosiSubProtocol.Timeout = p.subleaderTimeout()
 	// Give one leaf for free but as we don't know how many leaves
 	// could fail from the other trees, we need as much as possible
 	// responses. The main protocol will deal with early answers.
@@ -307,13 +459,22 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 	p.subProtocolsLock.Lock()
 	numSubProtocols := len(p.subProtocols)
 	responsesChan := make(chan StructResponse, numSubProtocols)
+	// failuresChan receives the number of nodes of abandoned subtrees
+	failuresChan := make(chan int, numSubProtocols)
 	errChan := make(chan error, numSubProtocols)
 	closeChan := make(chan bool)
 	// force to stop pending selects in case of timeout or quick answers
 	defer func() { close(closeChan) }()
 
+	levels := newLevelLatencies()
+	if p.Metrics != nil {
+		defer levels.report(p.Metrics)
+	}
+
 	for i, subProtocol := range p.subProtocols {
 		go func(i int, subProtocol *SubBlsCosi) {
+			reassignments := 0
+			started := time.Now()
 			for {
 				// this select doesn't have any timeout because a global is used
 				// when aggregating the response. The close channel will act as
@@ -334,10 +495,17 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 					}
 
 					if len(nodes) < 2 || subleaderID > nodes[1] {
-						errChan <- fmt.Errorf("(subprotocol %v) failed with every subleader, ignoring this subtree",
-							i)
+						log.Warnf("(subprotocol %v) failed with every subleader, ignoring this subtree", i)
+						failuresChan <- p.subTrees[i].Size() - 1
 						return
 					}
+					if reassignments >= p.SubleaderFailures {
+						log.Warnf("(subprotocol %v) failed with %d subleaders, ignoring this subtree",
+							i, reassignments+1)
+						failuresChan <- p.subTrees[i].Size() - 1
+						return
+					}
+					reassignments++
 					nodes = append(nodes, subleaderID)
 
 					var err error
@@ -359,7 +527,11 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 					p.subProtocolsLock.Lock()
 					p.subProtocols[i] = subProtocol
 					p.subProtocolsLock.Unlock()
+					started = time.Now()
 				case response := <-subProtocol.subResponse:
+					if p.Metrics != nil {
+						p.reportLatencies(levels, response, time.Since(started))
+					}
 					responsesChan <- response
 					return
 				}
@@ -376,6 +548,7 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 	for numSubProtocols > 0 && numSignature < p.Threshold-1 && !p.checkFailureThreshold(numFailure) {
 		select {
 		case res := <-responsesChan:
+			numSubProtocols--
 			publics := p.Publics()
 			mask, err := sign.NewMask(p.suite, publics, nil)
 			if err != nil {
@@ -396,12 +569,22 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 					responseMap[index] = &res.Response
 				}
 			}
+		case failures := <-failuresChan:
+			numSubProtocols--
+			numFailure += failures
 		case err := <-errChan:
 			err = fmt.Errorf("error in getting responses: %s", err)
 			return nil, err
+		case <-p.stopped:
+			return nil, xerrors.New("protocol stopped")
 		case <-timeout:
 			// here we use the entire timeout so that the protocol won't take
 			// more than Timeout + root computation time
+			if numSignature >= p.minResponses()-1 {
+				log.Lvlf2("timeout reached with %d responses, which is enough "+
+					"for the minimum of %d", numSignature, p.minResponses())
+				return responseMap, nil
+			}
 			return nil, fmt.Errorf("not enough replies from nodes at timeout %v "+
 				"for Threshold %d, got %d responses for %d requests", p.Timeout,
 				p.Threshold, numSignature, len(p.Roster().List)-1)
@@ -410,12 +593,33 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 
 	if p.checkFailureThreshold(numFailure) {
 		return nil, fmt.Errorf("too many refusals (got %d), the threshold of %d cannot be achieved",
-			numFailure, p.Threshold)
+			numFailure, p.minResponses())
+	}
+	if numSignature < p.minResponses()-1 {
+		return nil, fmt.Errorf("not enough replies from nodes for Threshold %d, "+
+			"got %d responses for %d requests", p.minResponses(), numSignature,
+			len(p.Roster().List)-1)
 	}
 
 	return responseMap, nil
 }
 
+// reportLatencies gives the latency of the subleader and the ones of the
+// leaves, measured by the subleader, to the metrics.
+func (p *BlsCosi) reportLatencies(levels *levelLatencies, res StructResponse, latency time.Duration) {
+	p.Metrics.NodeLatency(res.ServerIdentity, 1, latency)
+	levels.add(1, latency)
+
+	list := p.Roster().List
+	for _, l := range res.Latencies {
+		if l.Index < 0 || l.Index >= len(list) {
+			continue
+		}
+		p.Metrics.NodeLatency(list[l.Index], 2, l.Duration)
+		levels.add(2, l.Duration)
+	}
+}
+
 // Sign the message with this node and aggregates with all child signatures (in structResponses)
 // Also aggregates the child bitmasks
 func (p *BlsCosi) generateSignature(responses ResponseMap) (BlsSignature, error) {
@@ -428,10 +632,14 @@ func (p *BlsCosi) generateSignature(responses ResponseMap) (BlsSignature, error)
 	}
 
 	// generate personal signature and append to other sigs
-	personalSig, err := p.Sign(p.suite, p.Private(), p.Msg)
+	msg := DomainMessage(p.Domain, p.Msg)
+	personalSig, err := p.Sign(p.suite, p.Private(), msg)
 	if err != nil {
 		return nil, err
 	}
+	if p.OnSigned != nil {
+		p.OnSigned(msg)
+	}
 
 	// even if there is only one, it is aggregated to include potential processing
 	// done during the aggregation
@@ -476,6 +684,9 @@ func (p *BlsCosi) makeAggregateResponse(suite pairing.Suite, publics []kyber.Poi
 	if err != nil {
 		return nil, err
 	}
+	// The BDN coefficients are already applied by the aggregate function of
+	// the subleaders and the root so that the final signature is a simple sum
+	// of the partial ones in both schemes.
 	finalSignature := suite.G1().Point()
 
 	for _, res := range responses {
@@ -508,3 +719,13 @@ func (p *BlsCosi) makeAggregateResponse(suite pairing.Suite, publics []kyber.Poi
 func aggregate(suite pairing.Suite, mask *sign.Mask, sigs [][]byte) ([]byte, error) {
 	return bls.AggregateSignatures(suite, sigs...)
 }
+
+// bdnAggregate aggregates the signatures using the coefficients of the BDN
+// signature scheme derived from the mask.
+func bdnAggregate(suite pairing.Suite, mask *sign.Mask, sigs [][]byte) ([]byte, error) {
+	sig, err := bdn.AggregateSignatures(suite, sigs, mask)
+	if err != nil {
+		return nil, err
+	}
+	return sig.MarshalBinary()
+}
@@ -11,6 +11,7 @@ import (
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 )
@@ -134,6 +135,87 @@ func runProtocol(nbrNodes, nbrSubTrees, threshold int) (BlsSignature, *onet.Rost
 	return sig, roster, nil
 }
 
+func TestProtocol_Bdn(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	defer local.CloseAll()
+	servers, roster, tree := local.GenTree(7, false)
+
+	services := local.GetServices(servers, testServiceID)
+
+	rootService := services[0].(*testService)
+	pi, err := rootService.CreateProtocol(DefaultProtocolName, tree)
+	require.NoError(t, err)
+
+	cosiProtocol := pi.(*BlsCosi)
+	cosiProtocol.CreateProtocol = rootService.CreateProtocol
+	cosiProtocol.Msg = []byte{0xFF}
+	cosiProtocol.Timeout = testTimeout
+	cosiProtocol.Threshold = 7
+	cosiProtocol.UseBdn()
+	require.NoError(t, cosiProtocol.SetNbrSubTree(2))
+	require.NoError(t, cosiProtocol.Start())
+
+	var sig BlsSignature
+	select {
+	case sig = <-cosiProtocol.FinalSignature:
+	case <-time.After(testTimeout * 2):
+		t.Fatal("didn't get the signature in time")
+	}
+
+	publics := roster.ServicePublics(testServiceName)
+	mask, err := sig.GetMask(testSuite, publics)
+	require.NoError(t, err)
+	require.Equal(t, 7, mask.CountEnabled())
+
+	aggPub, err := bdn.AggregatePublicKeys(testSuite, mask)
+	require.NoError(t, err)
+	lenSig := testSuite.G1().PointLen()
+	require.NoError(t, bdn.Verify(testSuite, aggPub, cosiProtocol.Msg, sig[:lenSig]))
+
+	// the coefficients make the signature invalid for the BLS scheme
+	require.Error(t, sig.Verify(testSuite, cosiProtocol.Msg, publics))
+}
+
+func TestProtocol_Metrics(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	defer local.CloseAll()
+	servers, _, tree := local.GenTree(7, false)
+
+	services := local.GetServices(servers, testServiceID)
+
+	rootService := services[0].(*testService)
+	pi, err := rootService.CreateProtocol(DefaultProtocolName, tree)
+	require.NoError(t, err)
+
+	recorder := NewLatencyRecorder()
+	cosiProtocol := pi.(*BlsCosi)
+	cosiProtocol.CreateProtocol = rootService.CreateProtocol
+	cosiProtocol.Msg = []byte{0xFF}
+	cosiProtocol.Timeout = testTimeout
+	cosiProtocol.Threshold = 7
+	cosiProtocol.Metrics = recorder
+	require.NoError(t, cosiProtocol.SetNbrSubTree(2))
+	require.NoError(t, cosiProtocol.Start())
+
+	_, err = getAndVerifySignature(cosiProtocol, cosiProtocol.Msg, sign.NewThresholdPolicy(7))
+	require.NoError(t, err)
+
+	levels := recorder.Levels()
+	require.Equal(t, 2, len(levels))
+	require.Equal(t, 1, levels[1].Count)
+	require.Equal(t, 1, levels[2].Count)
+	require.True(t, levels[1].Max >= levels[2].Max)
+
+	nodes := recorder.SlowestNodes()
+	require.Equal(t, 6, len(nodes))
+	for i, n := range nodes {
+		require.Equal(t, 1, n.Count)
+		if i > 0 {
+			require.True(t, nodes[i-1].Mean() >= n.Mean())
+		}
+	}
+}
+
 func TestQuickAnswerProtocol_2_1(t *testing.T) {
 	mask, err := runQuickAnswerProtocol(2, 1)
 	require.NoError(t, err)
@@ -288,6 +370,79 @@ func runProtocolFailingSubLeader(nbrNodes, nbrTrees int) error {
 	return nil
 }
 
+func TestProtocol_MinResponses(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	defer local.CloseAll()
+	servers, _, tree := local.GenTree(5, false)
+
+	services := local.GetServices(servers, testServiceID)
+
+	rootService := services[0].(*testService)
+	pi, err := rootService.CreateProtocol(DefaultProtocolName, tree)
+	require.NoError(t, err)
+
+	cosiProtocol := pi.(*BlsCosi)
+	cosiProtocol.CreateProtocol = rootService.CreateProtocol
+	cosiProtocol.Msg = []byte{0xFF}
+	cosiProtocol.Timeout = 2 * time.Second
+	cosiProtocol.SubleaderTimeout = time.Second
+	cosiProtocol.SubleaderFailures = 1
+	cosiProtocol.Threshold = 5
+	cosiProtocol.MinResponses = 3
+	require.NoError(t, cosiProtocol.SetNbrSubTree(1))
+
+	leaves := cosiProtocol.subTrees.GetLeaves()
+	for _, s := range servers {
+		for _, l := range leaves[:2] {
+			if s.ServerIdentity.ID.Equal(l.ID) {
+				s.Pause()
+			}
+		}
+	}
+
+	require.NoError(t, cosiProtocol.Start())
+
+	// the threshold cannot be reached but the signature is accepted with
+	// the minimum of responses
+	_, err = getAndVerifySignature(cosiProtocol, cosiProtocol.Msg, sign.NewThresholdPolicy(3))
+	require.NoError(t, err)
+}
+
+// Tests that a shut down protocol stops waiting for the responses and closes
+// the channel of the signature.
+func TestProtocol_Shutdown(t *testing.T) {
+	local := onet.NewLocalTest(testSuite)
+	defer local.CloseAll()
+	servers, _, tree := local.GenTree(5, false)
+
+	services := local.GetServices(servers, testServiceID)
+
+	rootService := services[0].(*testService)
+	pi, err := rootService.CreateProtocol(DefaultProtocolName, tree)
+	require.NoError(t, err)
+
+	cosiProtocol := pi.(*BlsCosi)
+	cosiProtocol.CreateProtocol = rootService.CreateProtocol
+	cosiProtocol.Msg = []byte{0xFF}
+	cosiProtocol.Timeout = testTimeout
+	cosiProtocol.Threshold = 5
+	require.NoError(t, cosiProtocol.SetNbrSubTree(1))
+
+	// the threshold can't be reached before the timeout
+	servers[4].Pause()
+	defer servers[4].Unpause()
+
+	require.NoError(t, cosiProtocol.Start())
+	require.NoError(t, cosiProtocol.Shutdown())
+
+	select {
+	case _, ok := <-cosiProtocol.FinalSignature:
+		require.False(t, ok)
+	case <-time.After(testTimeout / 2):
+		t.Fatal("protocol didn't stop")
+	}
+}
+
 // Tests that the protocol throws errors with invalid configurations
 func TestProtocol_IntegrityCheck(t *testing.T) {
 	local := onet.NewLocalTest(testSuite)
@@ -314,6 +469,16 @@ func TestProtocol_IntegrityCheck(t *testing.T) {
 	err = cosiProtocol.Start()
 	require.Error(t, err,
 		"protocol should throw an error if called without a message")
+
+	cosiProtocol.Msg = []byte{0xFF}
+	cosiProtocol.MinResponses = cosiProtocol.Threshold + 1
+	require.Error(t, cosiProtocol.checkIntegrity())
+	cosiProtocol.MinResponses = 0
+	cosiProtocol.SubleaderFailures = -1
+	require.Error(t, cosiProtocol.checkIntegrity())
+	cosiProtocol.SubleaderFailures = 0
+	cosiProtocol.SubleaderTimeout = -time.Second
+	require.Error(t, cosiProtocol.checkIntegrity())
 }
 
 func TestProtocol_AllFailing_5_1(t *testing.T) {
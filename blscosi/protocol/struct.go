@@ -1,10 +1,12 @@
 package protocol
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
 
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
@@ -30,6 +32,20 @@ func init() {
 // ResponseMap is the container used to store responses coming from the children.
 type ResponseMap map[int]*Response
 
+// DomainMessage returns the message actually signed for the given
+// domain-separation tag. The tag is prefixed with its length so that
+// signatures for one domain can't be valid for another one, whatever the
+// messages are. An empty tag leaves the message untouched.
+func DomainMessage(domain, msg []byte) []byte {
+	if len(domain) == 0 {
+		return msg
+	}
+	buf := make([]byte, 4, 4+len(domain)+len(msg))
+	binary.BigEndian.PutUint32(buf, uint32(len(domain)))
+	buf = append(buf, domain...)
+	return append(buf, msg...)
+}
+
 // BlsSignature contains the message and its aggregated signature.
 type BlsSignature []byte
 
@@ -122,6 +138,16 @@ type Announcement struct {
 	Nonce     []byte
 	Timeout   time.Duration
 	Threshold int
+	// Bdn asks the nodes to use the BDN signature scheme
+	Bdn bool
+	// Domain is the domain-separation tag of the signature
+	Domain []byte
+	// TraceID identifies the signature in the logs and the spans of the
+	// nodes.
+	TraceID tracing.ID `protobuf:"opt"`
+	// SpanID is the span of the subtree on the root, the parent of the spans
+	// of the subleader and the leaves.
+	SpanID tracing.SpanID `protobuf:"opt"`
 }
 
 // StructAnnouncement just contains Announcement and the data necessary to identify and
@@ -135,6 +161,9 @@ type StructAnnouncement struct {
 type Response struct {
 	Signature BlsSignature
 	Mask      []byte
+	// Latencies are the response times of the leaves measured by the
+	// subleader.
+	Latencies []Latency
 }
 
 // StructResponse just contains Response and the data necessary to identify and
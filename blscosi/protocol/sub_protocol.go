@@ -7,8 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
 	"go.dedis.ch/kyber/v3/sign/bls"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -22,8 +24,12 @@ type SubBlsCosi struct {
 	*onet.TreeNodeInstance
 	Msg            []byte
 	Data           []byte
+	Domain         []byte
+	TraceID        tracing.ID
+	SpanID         tracing.SpanID
 	Timeout        time.Duration
 	Threshold      int
+	bdn            bool
 	stoppedOnce    sync.Once
 	verificationFn VerificationFn
 	suite          *pairing.SuiteBn256
@@ -44,6 +50,10 @@ type SubBlsCosi struct {
 	Sign      SignFn
 	Verify    VerifyFn
 	Aggregate AggregateFn
+
+	// OnSigned, if not nil, is called with the message after the node signed
+	// it, including the domain-separation tag.
+	OnSigned func(msg []byte)
 }
 
 // NewDefaultSubProtocol is the default sub-protocol function used for registration
@@ -96,6 +106,15 @@ func NewSubBlsCosi(n *onet.TreeNodeInstance, vf VerificationFn, suite *pairing.S
 	return c, nil
 }
 
+// UseBdn makes the subprotocol sign and aggregate with the BDN signature
+// scheme.
+func (p *SubBlsCosi) UseBdn() {
+	p.bdn = true
+	p.Sign = bdn.Sign
+	p.Verify = bdn.Verify
+	p.Aggregate = bdnAggregate
+}
+
 // Dispatch runs the protocol for each node in the protocol acting according
 // to its type
 func (p *SubBlsCosi) Dispatch() error {
@@ -168,8 +187,14 @@ func (p *SubBlsCosi) waitAnnouncement(parent *onet.TreeNode) *Announcement {
 
 	p.Msg = a.Msg
 	p.Data = a.Data
+	p.Domain = a.Domain
+	p.TraceID = a.TraceID
+	p.SpanID = a.SpanID
 	p.Timeout = a.Timeout
 	p.Threshold = a.Threshold
+	if a.Bdn {
+		p.UseBdn()
+	}
 
 	return a
 }
@@ -190,12 +215,21 @@ func (p *SubBlsCosi) dispatchRoot() error {
 		return nil
 	}
 
+	span := tracing.StartSpan(p.TraceID, p.SpanID, p.Name()+".subtree",
+		"subleader", p.Root().Children[0].ServerIdentity, "nodes",
+		p.Tree().Size())
+	defer span.End(nil)
+
 	// Only one child anyway
 	err := p.SendToChildren(&Announcement{
 		Msg:       p.Msg,
 		Data:      p.Data,
 		Timeout:   p.Timeout,
 		Threshold: p.Threshold,
+		Bdn:       p.bdn,
+		Domain:    p.Domain,
+		TraceID:   p.TraceID,
+		SpanID:    span.ID(),
 	})
 	if err != nil {
 		// Only log what happened so we can try to finish the protocol
@@ -216,6 +250,7 @@ func (p *SubBlsCosi) dispatchRoot() error {
 		// to let the parent protocol take actions
 		log.Warnf("%s: timed out while waiting for subleader response while %s",
 			p.ServerIdentity(), p.Tree().Dump())
+		span.SetAttributes("timeout", true)
 		p.subleaderNotResponding <- true
 	}
 
@@ -231,17 +266,27 @@ func (p *SubBlsCosi) dispatchSubLeader() error {
 		return nil
 	}
 
+	span := tracing.StartSpan(a.TraceID, a.SpanID, p.Name()+".announcement",
+		"node", p.ServerIdentity(), "children", len(p.Children()))
+	var err error
+	defer func() { span.End(err) }()
+
 	// generate the challenge nonce for potential refusals
 	a.Nonce = make([]byte, 8)
-	_, err := rand.Read(a.Nonce)
+	_, err = rand.Read(a.Nonce)
 	if err != nil {
 		return err
 	}
+	// the leaves start their spans as children of this one
+	a.SpanID = span.ID()
 
 	errs := p.SendToChildrenInParallel(a)
 	if len(errs) > 0 {
 		log.Error(errs)
 	}
+	// the latencies of the children are sent to the root for its metrics
+	started := time.Now()
+	latencies := []Latency{}
 
 	responses := make(ResponseMap)
 	for _, c := range p.Children() {
@@ -278,8 +323,9 @@ func (p *SubBlsCosi) dispatchSubLeader() error {
 				if !ok {
 					log.Warnf("Got a message from an unknown node %v", reply.ServerIdentity.ID)
 				} else if r == nil {
-					if err := p.Verify(p.suite, public, p.Msg, reply.Signature); err == nil {
+					if err := p.Verify(p.suite, public, DomainMessage(p.Domain, p.Msg), reply.Signature); err == nil {
 						responses[pubIndex] = &reply.Response
+						latencies = append(latencies, Latency{pubIndex, time.Since(started)})
 						done++
 					}
 				} else {
@@ -298,6 +344,7 @@ func (p *SubBlsCosi) dispatchSubLeader() error {
 				if err := p.Verify(p.suite, public, a.Nonce, reply.Signature); err == nil {
 					// The child gives an empty signature as a mark of refusal
 					responses[pubIndex] = &Response{}
+					latencies = append(latencies, Latency{pubIndex, time.Since(started)})
 					done++
 				} else {
 					log.Warnf("Tentative to send a unsigned refusal from %v", reply.ServerIdentity.ID)
@@ -308,6 +355,7 @@ func (p *SubBlsCosi) dispatchSubLeader() error {
 		case <-timeout:
 			log.Lvlf3("Subleader reached timeout waiting for children"+
 				" responses: %v", p.ServerIdentity())
+			span.SetAttributes("timeout", true)
 			// Use whatever we received until then to try to finish
 			// the protocol
 			done = len(p.Children())
@@ -319,9 +367,11 @@ func (p *SubBlsCosi) dispatchSubLeader() error {
 		log.Error(err)
 		return err
 	}
+	r.Latencies = latencies
 
 	log.Lvlf3("Subleader %v sent its reply with mask %b", p.ServerIdentity(), r.Mask)
-	return p.SendToParent(r)
+	err = p.SendToParent(r)
+	return err
 }
 
 // dispatchLeaf prepares the signature and send it to the subleader
@@ -331,6 +381,10 @@ func (p *SubBlsCosi) dispatchLeaf() error {
 		return nil
 	}
 
+	span := tracing.StartSpan(a.TraceID, a.SpanID, p.Name()+".sign", "node",
+		p.ServerIdentity())
+	defer span.End(nil)
+
 	res := make(chan bool)
 	go p.makeVerification(res)
 
@@ -352,6 +406,7 @@ func (p *SubBlsCosi) dispatchLeaf() error {
 			}
 		} else {
 			log.Lvlf3("Leaf %v refused to sign", p.ServerIdentity())
+			span.SetAttributes("refused", true)
 			r, err = p.makeRefusal(a.Nonce)
 			if err != nil {
 				return err
@@ -370,10 +425,14 @@ func (p *SubBlsCosi) makeResponse() (*Response, error) {
 		return nil, err
 	}
 
-	sig, err := p.Sign(p.suite, p.Private(), p.Msg)
+	msg := DomainMessage(p.Domain, p.Msg)
+	sig, err := p.Sign(p.suite, p.Private(), msg)
 	if err != nil {
 		return nil, err
 	}
+	if p.OnSigned != nil {
+		p.OnSigned(msg)
+	}
 
 	return &Response{
 		Mask:      mask.Mask(),
@@ -0,0 +1,226 @@
+package blscosi
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Receipt is the proof kept by a node of a message it co-signed. It is signed
+// with the key of the server identity of the node.
+type Receipt struct {
+	// Hash is the SHA-256 hash of the message as signed, including the
+	// domain-separation tag. Only the hash is kept so that the size of the
+	// receipts doesn't depend on the messages.
+	Hash []byte
+	// Timestamp is when the message has been signed, in nanoseconds since
+	// the Unix epoch.
+	Timestamp int64
+	Signature []byte
+}
+
+// ReceiptsRequest asks a node for the receipts of the messages it signed
+// between From and To, in nanoseconds since the Unix epoch. The signature
+// must be on
+//
+//	sha256( "blscosi-receipts" | From | To | Timestamp )
+//
+// and verifiable with the public key of the server identity, so that only
+// the operator of the node can get them. Timestamp is when the request is
+// signed, which must be within AuthWindow of the clock of the node, and a
+// request is accepted only once.
+type ReceiptsRequest struct {
+	From      int64
+	To        int64
+	Signature []byte
+	Timestamp int64 `protobuf:"opt"`
+}
+
+// ReceiptsResponse contains the receipts in chronological order.
+type ReceiptsResponse struct {
+	Receipts []Receipt
+}
+
+// receiptsBucket is the name of the bucket storing the receipts.
+var receiptsBucket = []byte("blscosi-receipts")
+
+// defaultReceiptsTTL is how long the receipts are kept if the service doesn't
+// set ReceiptsTTL.
+const defaultReceiptsTTL = 90 * 24 * time.Hour
+
+// defaultMaxReceipts is how many receipts are kept if the service doesn't set
+// MaxReceipts.
+const defaultMaxReceipts = 1 << 20
+
+// receiptTag separates the signatures of the receipts from the other messages
+// signed with the key of the node.
+const receiptTag = "blscosi-signed-receipt"
+
+func (r *Receipt) digest() []byte {
+	h := sha256.New()
+	h.Write([]byte(receiptTag))
+	h.Write(r.Hash)
+	binary.Write(h, binary.BigEndian, r.Timestamp)
+	return h.Sum(nil)
+}
+
+// Verify checks the signature of the receipt with the public key of the
+// server identity of the node.
+func (r *Receipt) Verify(public kyber.Point) error {
+	return schnorr.Verify(cothority.Suite, public, r.digest(), r.Signature)
+}
+
+// receiptsTag separates the signatures of the receipts requests from the
+// other messages signed with the key of the node.
+const receiptsTag = "blscosi-receipts"
+
+// receiptsRange returns what must be signed to get the receipts.
+func receiptsRange(from, to, ts int64) []byte {
+	h := sha256.New()
+	h.Write([]byte(receiptsTag))
+	binary.Write(h, binary.BigEndian, from)
+	binary.Write(h, binary.BigEndian, to)
+	binary.Write(h, binary.BigEndian, ts)
+	return h.Sum(nil)
+}
+
+// receiptKey returns the key of the receipt in the database so that they are
+// sorted by time.
+func receiptKey(r *Receipt) []byte {
+	key := make([]byte, 8, 8+sha256.Size)
+	binary.BigEndian.PutUint64(key, uint64(r.Timestamp))
+	return append(key, r.digest()...)
+}
+
+// addReceipt creates and stores the receipt of a message signed by this
+// node. The receipts older than ReceiptsTTL and the oldest ones above
+// MaxReceipts are removed at the same time.
+func (s *Service) addReceipt(msg []byte) (*Receipt, error) {
+	hash := sha256.Sum256(msg)
+	r := &Receipt{
+		Hash:      hash[:],
+		Timestamp: time.Now().UnixNano(),
+	}
+	var err error
+	r.Signature, err = schnorr.Sign(cothority.Suite, s.ServerIdentity().GetPrivate(), r.digest())
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := protobuf.Encode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.receiptsLock.Lock()
+	defer s.receiptsLock.Unlock()
+	count := s.receiptsCount
+	err = s.receiptsDB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.receiptsBucket)
+		if count < 0 {
+			count = b.Stats().KeyN
+		}
+		if err := atrest.Put(b, s.receiptsBucket, receiptKey(r), buf); err != nil {
+			return err
+		}
+		count++
+		removed, err := s.pruneReceipts(b, count, r.Timestamp)
+		count -= removed
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.receiptsCount = count
+
+	return r, nil
+}
+
+// pruneReceipts removes the receipts older than ReceiptsTTL and the oldest
+// ones if there are more than MaxReceipts. It returns how many were removed.
+func (s *Service) pruneReceipts(b *bbolt.Bucket, count int, now int64) (int, error) {
+	ttl := s.ReceiptsTTL
+	if ttl <= 0 {
+		ttl = defaultReceiptsTTL
+	}
+	max := s.MaxReceipts
+	if max <= 0 {
+		max = defaultMaxReceipts
+	}
+	limit := now - ttl.Nanoseconds()
+
+	// The keys are removed once the cursor is done, as removing them while
+	// moving it can skip some.
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		if count-len(keys) <= max && int64(binary.BigEndian.Uint64(k[:8])) >= limit {
+			break
+		}
+		keys = append(keys, append([]byte{}, k...))
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// onSigned is given to the protocols to keep a receipt of every signature.
+func (s *Service) onSigned(msg []byte) {
+	if _, err := s.addReceipt(msg); err != nil {
+		log.Error("couldn't store the receipt:", err)
+	}
+}
+
+// Receipts returns the receipts of the messages signed by this node during
+// the requested period.
+func (s *Service) Receipts(req *ReceiptsRequest) (*ReceiptsResponse, error) {
+	err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public,
+		receiptsRange(req.From, req.To, req.Timestamp), req.Signature)
+	if err != nil {
+		return nil, errors.New("the request must be signed by the node")
+	}
+	if err := s.replays.check(req.Signature, time.Unix(0, req.Timestamp)); err != nil {
+		return nil, err
+	}
+	if req.To < req.From {
+		return nil, errors.New("the end of the period is before its start")
+	}
+
+	from := make([]byte, 8)
+	binary.BigEndian.PutUint64(from, uint64(req.From))
+	reply := &ReceiptsResponse{}
+	err = s.receiptsDB.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(s.receiptsBucket).Cursor()
+		for k, v := c.Seek(from); k != nil; k, v = c.Next() {
+			if int64(binary.BigEndian.Uint64(k[:8])) > req.To {
+				break
+			}
+			buf, err := atrest.Open(atrest.Location(s.receiptsBucket, k), v)
+			if err != nil {
+				return err
+			}
+			var r Receipt
+			if err := protobuf.Decode(buf, &r); err != nil {
+				return err
+			}
+			reply.Receipts = append(reply.Receipts, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
@@ -0,0 +1,130 @@
+package blscosi
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestService_Receipts(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	start := time.Now()
+	client := NewClient()
+	msg := []byte("hello blscosi service")
+	_, err := client.SignatureRequestWithDomain(roster, []byte("test"), msg)
+	require.NoError(t, err)
+
+	si := hosts[0].ServerIdentity
+	_, err = client.Receipts(si, key.NewKeyPair(cothority.Suite).Private, start, time.Now())
+	require.Error(t, err)
+
+	receipts, err := client.Receipts(si, si.GetPrivate(), start, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(receipts))
+	hash := sha256.Sum256(protocol.DomainMessage([]byte("test"), msg))
+	require.Equal(t, hash[:], receipts[0].Hash)
+
+	receipts, err = client.Receipts(si, si.GetPrivate(), time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(receipts))
+
+	// the receipt is returned with a partial signature when asked
+	service := hosts[1].Service(ServiceName).(*Service)
+	reply, err := service.PartialSignatureRequest(&PartialSignatureRequest{
+		Message: msg,
+		Roster:  roster,
+		Receipt: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, reply.Receipt)
+	require.NoError(t, reply.Receipt.Verify(hosts[1].ServerIdentity.Public))
+	hash = sha256.Sum256(msg)
+	require.Equal(t, hash[:], reply.Receipt.Hash)
+
+	// a receipts request can't be replayed
+	req := &ReceiptsRequest{From: start.UnixNano(), To: time.Now().UnixNano(),
+		Timestamp: time.Now().UnixNano()}
+	req.Signature, err = schnorr.Sign(cothority.Suite, si.GetPrivate(),
+		receiptsRange(req.From, req.To, req.Timestamp))
+	require.NoError(t, err)
+	service = hosts[0].Service(ServiceName).(*Service)
+	_, err = service.Receipts(req)
+	require.NoError(t, err)
+	_, err = service.Receipts(req)
+	require.Error(t, err)
+}
+
+func TestService_ReceiptsEdDSA(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	start := time.Now()
+	client := NewClient()
+	msg := []byte("hello blscosi service")
+	_, err := client.SignatureRequestEdDSA(roster, []byte("test"), msg)
+	require.NoError(t, err)
+
+	// every member keeps the receipt of the EdDSA signature
+	for _, h := range hosts {
+		si := h.ServerIdentity
+		receipts, err := client.Receipts(si, si.GetPrivate(), start, time.Now())
+		require.NoError(t, err)
+		require.NotEmpty(t, receipts)
+		hash := sha256.Sum256(protocol.DomainMessage([]byte("test"), msg))
+		require.Equal(t, hash[:], receipts[0].Hash)
+	}
+}
+
+func TestService_ReceiptsPruned(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(1, false)
+	defer local.CloseAll()
+
+	service := hosts[0].Service(ServiceName).(*Service)
+	service.MaxReceipts = 2
+	sign := func(msg string) {
+		_, err := service.PartialSignatureRequest(&PartialSignatureRequest{
+			Message: []byte(msg),
+			Roster:  roster,
+		})
+		require.NoError(t, err)
+	}
+	start := time.Now()
+	client := NewClient()
+	si := hosts[0].ServerIdentity
+
+	// only the last receipts are kept
+	sign("a")
+	sign("b")
+	sign("c")
+	receipts, err := client.Receipts(si, si.GetPrivate(), start, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(receipts))
+	hash := sha256.Sum256([]byte("c"))
+	require.Equal(t, hash[:], receipts[1].Hash)
+
+	// the receipts are removed once too old
+	service.ReceiptsTTL = time.Nanosecond
+	sign("d")
+	receipts, err = client.Receipts(si, si.GetPrivate(), start, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(receipts))
+
+	// messages that are too big are not signed
+	_, err = service.PartialSignatureRequest(&PartialSignatureRequest{
+		Message: make([]byte, maxMessageSize+1),
+		Roster:  roster,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package blscosi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// Statement is a message that has been collectively signed by a previous
+// roster.
+type Statement struct {
+	Message   []byte
+	Domain    []byte
+	Signature protocol.BlsSignature
+	Roster    *onet.Roster
+}
+
+// ResignEntry links the old signature of a statement to the signature of
+// the new roster.
+type ResignEntry struct {
+	Message      []byte
+	Domain       []byte
+	OldSignature protocol.BlsSignature
+	Signature    protocol.BlsSignature
+}
+
+// ResignMapping is the result of a campaign: the statements signed again by
+// the new roster. It can be published so that verifiers only need the keys of
+// the new roster.
+type ResignMapping struct {
+	Roster  *onet.Roster
+	Entries []ResignEntry
+}
+
+// ResignCampaign collects the signatures of a new roster for statements
+// signed by previous rosters.
+type ResignCampaign struct {
+	Client *Client
+	Roster *onet.Roster
+	// Parallel is the number of signature requests running at the same time.
+	// One request at a time is made when it is zero.
+	Parallel int
+	// Bdn tells that the old and the new signatures use the BDN scheme.
+	Bdn bool
+}
+
+// Run verifies the old signature of each statement and asks the new roster to
+// sign it again. Statements that fail are left out of the mapping and an
+// error listing them is returned together with the mapping of the others.
+func (rc *ResignCampaign) Run(statements []Statement) (*ResignMapping, error) {
+	if rc.Roster == nil || len(rc.Roster.List) == 0 {
+		return nil, errors.New("empty roster")
+	}
+	client := rc.Client
+	if client == nil {
+		client = NewClient()
+	}
+	parallel := rc.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	entries := make([]*ResignEntry, len(statements))
+	errs := make([]error, len(statements))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range statements {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			entries[i], errs[i] = rc.resign(client, statements[i])
+		}(i)
+	}
+	wg.Wait()
+
+	m := &ResignMapping{Roster: rc.Roster}
+	failed := []string{}
+	for i, e := range entries {
+		if errs[i] != nil {
+			log.Lvlf2("Couldn't resign statement %d: %v", i, errs[i])
+			failed = append(failed, fmt.Sprintf("statement %d: %v", i, errs[i]))
+			continue
+		}
+		m.Entries = append(m.Entries, *e)
+	}
+	if len(failed) > 0 {
+		return m, fmt.Errorf("%d statements failed: %v", len(failed), failed)
+	}
+	return m, nil
+}
+
+func (rc *ResignCampaign) resign(client *Client, st Statement) (*ResignEntry, error) {
+	if st.Roster == nil {
+		return nil, errors.New("missing the old roster")
+	}
+	msg := protocol.DomainMessage(st.Domain, st.Message)
+	if err := rc.verify(st.Signature, msg, st.Roster); err != nil {
+		return nil, fmt.Errorf("invalid old signature: %v", err)
+	}
+
+	reply, err := client.SignatureRequestWithDomain(rc.Roster, st.Domain, st.Message)
+	if err != nil {
+		return nil, err
+	}
+	if err := rc.verify(reply.Signature, msg, rc.Roster); err != nil {
+		return nil, fmt.Errorf("invalid new signature: %v", err)
+	}
+
+	return &ResignEntry{
+		Message:      st.Message,
+		Domain:       st.Domain,
+		OldSignature: st.Signature,
+		Signature:    reply.Signature,
+	}, nil
+}
+
+func (rc *ResignCampaign) verify(sig protocol.BlsSignature, msg []byte, roster *onet.Roster) error {
+	publics := roster.ServicePublics(ServiceName)
+	if rc.Bdn {
+		return bdnproto.BdnSignature(sig).Verify(suite, msg, publics)
+	}
+	return sig.Verify(suite, msg, publics)
+}
+
+// Lookup returns the entry of the statement with the given old signature, or
+// nil if it is not in the mapping.
+func (m *ResignMapping) Lookup(oldSig protocol.BlsSignature) *ResignEntry {
+	for i := range m.Entries {
+		if bytes.Equal(m.Entries[i].OldSignature, oldSig) {
+			return &m.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Encode returns the mapping in the protobuf format, ready to be published.
+func (m *ResignMapping) Encode() ([]byte, error) {
+	return protobuf.Encode(m)
+}
+
+// DecodeResignMapping parses a mapping created by Encode.
+func DecodeResignMapping(buf []byte) (*ResignMapping, error) {
+	m := &ResignMapping{}
+	err := protobuf.DecodeWithConstructors(buf, m, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
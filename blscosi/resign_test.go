@@ -0,0 +1,57 @@
+package blscosi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestResignCampaign_Run(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	_, roster, _ := local.GenTree(6, false)
+	defer local.CloseAll()
+
+	oldRoster := onet.NewRoster(roster.List[:3])
+	newRoster := onet.NewRoster(roster.List[3:])
+	client := NewClient()
+
+	statements := []Statement{}
+	for _, msg := range []string{"a", "b", "c"} {
+		reply, err := client.SignatureRequestWithDomain(oldRoster, []byte("test"), []byte(msg))
+		require.NoError(t, err)
+		statements = append(statements, Statement{
+			Message:   []byte(msg),
+			Domain:    []byte("test"),
+			Signature: reply.Signature,
+			Roster:    oldRoster,
+		})
+	}
+	// a statement with a wrong signature is left out
+	statements = append(statements, Statement{
+		Message:   []byte("d"),
+		Signature: statements[0].Signature,
+		Roster:    oldRoster,
+	})
+
+	rc := &ResignCampaign{Client: client, Roster: newRoster, Parallel: 2}
+	m, err := rc.Run(statements)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "statement 3:")
+	require.Equal(t, 3, len(m.Entries))
+
+	buf, err := m.Encode()
+	require.NoError(t, err)
+	m, err = DecodeResignMapping(buf)
+	require.NoError(t, err)
+
+	publics := m.Roster.ServicePublics(ServiceName)
+	for _, st := range statements[:3] {
+		e := m.Lookup(st.Signature)
+		require.NotNil(t, e)
+		require.NoError(t, e.Signature.Verify(testSuite,
+			protocol.DomainMessage(e.Domain, e.Message), publics))
+	}
+	require.Nil(t, m.Lookup([]byte{1}))
+}
@@ -3,15 +3,25 @@
 package blscosi
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"sync"
 	"time"
 
+	uuid "github.com/satori/go.uuid"
+	"go.dedis.ch/cothority/v3/admission"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign/bls"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
+	bbolt "go.etcd.io/bbolt"
 )
 
 const protocolTimeout = 20 * time.Second
@@ -24,10 +34,22 @@ var ServiceID onet.ServiceID
 // ServiceName is the name to refer to the CoSi service
 const ServiceName = "blsCoSiService"
 
+// admissionLimits bound the signatures a client asks for, on top of
+// RateLimit, when the conode enables the admission control.
+var admissionLimits = []admission.Limit{
+	{Endpoint: ServiceName + "/SignatureRequest", Rate: 5, Burst: 10,
+		Concurrent: 4},
+}
+
 func init() {
 	ServiceID, _ = onet.RegisterNewServiceWithSuite(ServiceName, suite, newCoSiService)
 	network.RegisterMessage(&SignatureRequest{})
 	network.RegisterMessage(&SignatureResponse{})
+	network.RegisterMessage(&PartialSignatureRequest{})
+	network.RegisterMessage(&PartialSignatureResponse{})
+	network.RegisterMessage(&ReceiptsRequest{})
+	network.RegisterMessage(&ReceiptsResponse{})
+	log.ErrFatal(admission.RegisterDefaults(admissionLimits...))
 }
 
 // Service is the service that handles collective signing operations
@@ -37,12 +59,69 @@ type Service struct {
 	Threshold int
 	NSubtrees int
 	Timeout   time.Duration
+	// Fanout is the maximum number of children of a subleader and is
+	// used instead of NSubtrees when it is set.
+	Fanout int
+	// TreeBuilder, if not nil, generates the subtrees of the protocol and
+	// takes precedence over Fanout and NSubtrees.
+	TreeBuilder protocol.TreeBuilderFn
+	// SubleaderTimeout, SubleaderFailures and MinResponses tune how the
+	// protocol handles failing subtrees. Default values of the protocol are
+	// used when they are zero.
+	SubleaderTimeout  time.Duration
+	SubleaderFailures int
+	MinResponses      int
+	// Bdn makes the service sign with the BDN signature scheme which is
+	// robust against rogue public-key attacks. The signatures must then be
+	// verified with bdnproto.BdnSignature.
+	Bdn bool
+	// Authorize, if not nil, is asked whether a request can be signed.
+	Authorize AuthorizeFn
+	// RateLimit is the number of requests per second a client can make, with
+	// bursts of up to RateBurst requests. Anonymous clients share the same
+	// limit. There is no limit when it is zero.
+	RateLimit float64
+	RateBurst int
+	// ReceiptsTTL is how long the receipts of the signatures are kept and
+	// MaxReceipts how many of them at most, the oldest being removed first.
+	// Default values are used when they are zero.
+	ReceiptsTTL time.Duration
+	MaxReceipts int
+
+	limiter        rateLimiter
+	replays        replayCache
+	receiptsDB     *bbolt.DB
+	receiptsBucket []byte
+	// receiptsLock protects receiptsCount, the number of stored receipts,
+	// which is -1 until the bucket is counted.
+	receiptsLock  sync.Mutex
+	receiptsCount int
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
 }
 
 // SignatureRequest is what the Cosi service is expected to receive from clients.
 type SignatureRequest struct {
 	Message []byte
 	Roster  *onet.Roster
+	// Domain is an optional domain-separation tag mixed into the signed
+	// message so that the signature can't be reused for another purpose.
+	Domain []byte
+	// Client and ClientSignature authenticate the request when the service
+	// only signs for some clients. They are nil for anonymous requests.
+	Client          kyber.Point
+	ClientSignature []byte
+	// EdDSA asks for a Schnorr collective signature that can be verified
+	// as an Ed25519 signature, see VerifyEdDSA, instead of a BLS one.
+	EdDSA bool
+	// ClientTime is when the client signed the request, in nanoseconds, so
+	// that the signature can't be replayed.
+	ClientTime int64 `protobuf:"opt"`
 }
 
 // SignatureResponse is what the Cosi service will reply to clients.
@@ -51,8 +130,162 @@ type SignatureResponse struct {
 	Signature protocol.BlsSignature
 }
 
+// PartialSignatureRequest asks a single node for its signature of the
+// message so that the client aggregates the signatures itself.
+type PartialSignatureRequest struct {
+	Message []byte
+	Roster  *onet.Roster
+	Domain  []byte
+	// Client and ClientSignature authenticate the request the same way as
+	// for a SignatureRequest.
+	Client          kyber.Point
+	ClientSignature []byte
+	// Receipt asks the node to return the receipt of its signature.
+	Receipt bool
+	// ClientTime is when the client signed the request, as for a
+	// SignatureRequest.
+	ClientTime int64 `protobuf:"opt"`
+}
+
+// PartialSignatureResponse contains the signature of the node and its index
+// in the roster of the request. Bdn tells which scheme must be used to
+// aggregate the signature.
+type PartialSignatureResponse struct {
+	Partial protocol.PartialSignature
+	Bdn     bool
+	Receipt *Receipt
+}
+
+// RequestID identifies an asynchronous signature request.
+type RequestID uuid.UUID
+
+// String returns the canonical representation of the ID.
+func (id RequestID) String() string {
+	return uuid.UUID(id).String()
+}
+
+// SignatureResult is sent on the results channel of an asynchronous signature
+// request. Either Response or Err is set.
+type SignatureResult struct {
+	ID       RequestID
+	Response *SignatureResponse
+	Err      error
+}
+
 // SignatureRequest treats external request to this service.
 func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, error) {
+	if req.EdDSA {
+		return s.eddsaSignature(req)
+	}
+
+	p, err := s.startProtocol(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for reply. This will always eventually return.
+	sig := <-p.FinalSignature
+	return s.makeResponse(req, sig), nil
+}
+
+// SignatureRequestAsync starts the collective signing of the request and
+// returns immediately with the ID of the request. The result is sent on the
+// results channel with the same ID, which lets the caller pipeline many
+// requests using a single channel. The results channel must be able to
+// receive, or be buffered, until the request finishes.
+//
+// When the context is done before the signature is produced, the protocol is
+// shut down and the error of the context is sent as the result.
+func (s *Service) SignatureRequestAsync(ctx context.Context, req *SignatureRequest,
+	results chan<- SignatureResult) (RequestID, error) {
+	id := RequestID(uuid.NewV4())
+
+	if err := ctx.Err(); err != nil {
+		return id, err
+	}
+
+	if req.EdDSA {
+		go func() {
+			res := SignatureResult{ID: id}
+			res.Response, res.Err = s.eddsaSignature(req)
+			results <- res
+		}()
+		return id, nil
+	}
+
+	p, err := s.startProtocol(req)
+	if err != nil {
+		return id, err
+	}
+
+	go func() {
+		res := SignatureResult{ID: id}
+		select {
+		case sig, ok := <-p.FinalSignature:
+			if ok {
+				res.Response = s.makeResponse(req, sig)
+			} else {
+				res.Err = errors.New("protocol stopped without a signature")
+			}
+		case <-ctx.Done():
+			log.Lvlf3("Signature request %v abandoned: %v", id, ctx.Err())
+			p.Shutdown()
+			res.Err = ctx.Err()
+		}
+		results <- res
+	}()
+
+	return id, nil
+}
+
+// PartialSignatureRequest signs the message with the key of this node
+// without contacting the other members of the roster. The leader is then not
+// needed to assemble the collective signature.
+func (s *Service) PartialSignatureRequest(req *PartialSignatureRequest) (*PartialSignatureResponse, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster provided")
+	}
+	err := s.checkRequest(req.Client, req.ClientSignature, req.ClientTime, req.Message, req.Domain, req.Roster)
+	if err != nil {
+		return nil, err
+	}
+	idx, _ := req.Roster.Search(s.ServerIdentity().ID)
+	if idx < 0 {
+		return nil, errors.New("we're not in the roster")
+	}
+
+	// BLS and BDN individual signatures are the same, only the aggregation
+	// differs.
+	msg := protocol.DomainMessage(req.Domain, req.Message)
+	sig, err := bls.Sign(s.suite, s.ServerIdentity().ServicePrivate(ServiceName), msg)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := s.addReceipt(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &PartialSignatureResponse{
+		Partial: protocol.PartialSignature{Index: idx, Signature: sig},
+		Bdn:     s.Bdn,
+	}
+	if req.Receipt {
+		reply.Receipt = receipt
+	}
+	return reply, nil
+}
+
+// startProtocol configures the BlsCosi protocol for the request and starts it.
+func (s *Service) startProtocol(req *SignatureRequest) (*protocol.BlsCosi, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster provided")
+	}
+	err := s.checkRequest(req.Client, req.ClientSignature, req.ClientTime, req.Message, req.Domain, req.Roster)
+	if err != nil {
+		return nil, err
+	}
+
 	// generate the tree
 	nNodes := len(req.Roster.List)
 	rooted := req.Roster.NewRosterWithRoot(s.ServerIdentity())
@@ -72,20 +305,39 @@ func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, erro
 	p := pi.(*protocol.BlsCosi)
 	p.CreateProtocol = s.CreateProtocol
 	p.Timeout = s.Timeout
+	if s.Bdn {
+		p.UseBdn()
+	}
 	p.Msg = req.Message
+	p.Domain = req.Domain
+	p.OnSigned = s.onSigned
 
 	// Threshold before the subtrees so that we can optimize situation
 	// like a threshold of one
 	if s.Threshold > 0 {
 		p.Threshold = s.Threshold
 	}
+	if s.SubleaderTimeout > 0 {
+		p.SubleaderTimeout = s.SubleaderTimeout
+	}
+	if s.SubleaderFailures > 0 {
+		p.SubleaderFailures = s.SubleaderFailures
+	}
+	if s.MinResponses > 0 {
+		p.MinResponses = s.MinResponses
+	}
 
-	if s.NSubtrees > 0 {
+	switch {
+	case s.TreeBuilder != nil:
+		err = p.SetTreeBuilder(s.TreeBuilder)
+	case s.Fanout > 0:
+		err = p.SetFanout(s.Fanout)
+	case s.NSubtrees > 0:
 		err = p.SetNbrSubTree(s.NSubtrees)
-		if err != nil {
-			p.Done()
-			return nil, err
-		}
+	}
+	if err != nil {
+		p.Done()
+		return nil, err
 	}
 
 	// start the protocol
@@ -94,14 +346,16 @@ func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, erro
 		return nil, err
 	}
 
-	// wait for reply. This will always eventually return.
-	sig := <-p.FinalSignature
+	return p, nil
+}
 
+// makeResponse creates the response of the request with the final signature.
+func (s *Service) makeResponse(req *SignatureRequest, sig []byte) *SignatureResponse {
 	// The hash is the message blscosi actually signs, we recompute it the
 	// same way as blscosi and then return it.
 	h := s.suite.Hash()
-	h.Write(req.Message)
-	return &SignatureResponse{h.Sum(nil), sig}, nil
+	h.Write(protocol.DomainMessage(req.Domain, req.Message))
+	return &SignatureResponse{h.Sum(nil), sig}
 }
 
 // NewProtocol is called on all nodes of a Tree (except the root, since it is
@@ -113,7 +367,12 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 	case protocol.DefaultProtocolName:
 		return protocol.NewDefaultProtocol(tn)
 	case protocol.DefaultSubProtocolName:
-		return protocol.NewDefaultSubProtocol(tn)
+		pi, err := protocol.NewDefaultSubProtocol(tn)
+		if err != nil {
+			return nil, err
+		}
+		pi.(*protocol.SubBlsCosi).OnSigned = s.onSigned
+		return pi, nil
 	}
 	return nil, errors.New("no such protocol " + tn.ProtocolName())
 }
@@ -123,12 +382,25 @@ func newCoSiService(c *onet.Context) (onet.Service, error) {
 		ServiceProcessor: onet.NewServiceProcessor(c),
 		suite:            suite,
 		Timeout:          protocolTimeout,
+		receiptsCount:    -1,
 	}
 
-	if err := s.RegisterHandler(s.SignatureRequest); err != nil {
+	s.receiptsDB, s.receiptsBucket = c.GetAdditionalBucket(receiptsBucket)
+	if n, err := atrest.SealBucket(s.receiptsDB, s.receiptsBucket); err != nil {
+		return nil, err
+	} else if n > 0 {
+		log.Lvlf1("Encrypted %d receipts", n)
+	}
+
+	if err := s.RegisterHandlers(s.SignatureRequest, s.PartialSignatureRequest,
+		s.Receipts); err != nil {
 		log.Error("couldn't register message:", err)
 		return nil, err
 	}
+	if err := s.registerEdDSA(); err != nil {
+		log.Error("couldn't register the EdDSA protocols:", err)
+		return nil, err
+	}
 
 	return s, nil
 }
@@ -1,9 +1,12 @@
 package blscosi
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
 	"go.dedis.ch/onet/v3"
@@ -68,3 +71,78 @@ func TestService_SignatureRequest(t *testing.T) {
 	// verify the response still
 	require.Nil(t, res.Signature.VerifyWithPolicy(testSuite, msg, publics, sign.NewThresholdPolicy(1)))
 }
+
+func TestService_SignatureRequestBdn(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(10, false)
+	defer local.CloseAll()
+
+	service := hosts[0].Service(ServiceName).(*Service)
+	service.Bdn = true
+	service.NSubtrees = 3
+
+	msg := []byte("hello bdn service")
+	buf, err := service.SignatureRequest(&SignatureRequest{
+		Roster:  roster,
+		Message: msg,
+	})
+	require.NoError(t, err)
+
+	publics := roster.ServicePublics(ServiceName)
+	res := buf.(*SignatureResponse)
+
+	require.NoError(t, bdnproto.BdnSignature(res.Signature).Verify(testSuite, msg, publics))
+	// the coefficients make the signature invalid for the BLS scheme
+	require.Error(t, res.Signature.Verify(testSuite, msg, publics))
+}
+
+func TestService_SignatureRequestAsync(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	service := hosts[0].Service(ServiceName).(*Service)
+	publics := roster.ServicePublics(ServiceName)
+
+	// pipeline a few requests on the same channel
+	results := make(chan SignatureResult, 3)
+	msgs := make(map[RequestID][]byte)
+	for _, msg := range []string{"first", "second", "third"} {
+		id, err := service.SignatureRequestAsync(context.Background(),
+			&SignatureRequest{Roster: roster, Message: []byte(msg)}, results)
+		require.NoError(t, err)
+		msgs[id] = []byte(msg)
+	}
+
+	for range msgs {
+		select {
+		case res := <-results:
+			require.NoError(t, res.Err)
+			msg, ok := msgs[res.ID]
+			require.True(t, ok)
+			require.NoError(t, res.Response.Signature.Verify(testSuite, msg, publics))
+		case <-time.After(protocolTimeout):
+			t.Fatal("didn't get the result in time")
+		}
+	}
+
+	// a cancelled context is refused
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := service.SignatureRequestAsync(ctx,
+		&SignatureRequest{Roster: roster, Message: []byte("cancelled")}, results)
+	require.Equal(t, context.Canceled, err)
+
+	// the deadline is reported on the results channel
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	service.Threshold = len(roster.List)
+	hosts[1].Pause()
+	id, err := service.SignatureRequestAsync(ctx,
+		&SignatureRequest{Roster: roster, Message: []byte("late")}, results)
+	require.NoError(t, err)
+	res := <-results
+	require.Equal(t, id, res.ID)
+	require.Equal(t, context.DeadlineExceeded, res.Err)
+	hosts[1].Unpause()
+}
@@ -12,6 +12,8 @@ import (
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/tracing"
+	"go.dedis.ch/cothority/v3/transport"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -37,6 +39,13 @@ type Client struct {
 	noncesSI map[uint64]*network.ServerIdentity
 	// Used for SendProtobufParallel. If it is nil, default values will be used.
 	options *onet.ParallelOptions
+	// Sends the requests to the roster instead of SendProtobufParallel if it
+	// is set.
+	transport *transport.Transport
+	// Sent with the transactions, if it is nil the nodes create one.
+	traceID tracing.ID
+	// Parent of the spans of the transactions, set when a node forwards them.
+	parentSpan tracing.SpanID
 }
 
 // NewClient instantiates a new ByzCoin client.
@@ -99,6 +108,12 @@ func (c *Client) UseNode(n int) error {
 	return nil
 }
 
+// UseTraceID sets the trace ID sent with the transactions, so that they can be
+// followed in the logs of the nodes.
+func (c *Client) UseTraceID(id tracing.ID) {
+	c.traceID = id
+}
+
 // DontContact adds the given serverIdentity to the list of nodes that will
 // not be contacted.
 func (c *Client) DontContact(si *network.ServerIdentity) {
@@ -108,6 +123,34 @@ func (c *Client) DontContact(si *network.ServerIdentity) {
 	c.options.IgnoreNodes = []*network.ServerIdentity{si}
 }
 
+// UseTransport sends the requests to the roster through the transport, one
+// node after the other with its retries and circuit breakers, instead of
+// asking the nodes in parallel. The nodes are still chosen with UseNode and
+// DontContact. A nil transport restores the parallel requests.
+func (c *Client) UseTransport(t *transport.Transport) {
+	c.transport = t
+}
+
+// Transport returns the transport set with UseTransport, or nil.
+func (c *Client) Transport() *transport.Transport {
+	return c.transport
+}
+
+// sendRoster sends the request to the nodes of the roster and decodes the
+// reply with the decoder, if it isn't nil.
+func (c *Client) sendRoster(msg, reply interface{},
+	decoder protobuf.Decoder) (*network.ServerIdentity, error) {
+	if c.transport != nil {
+		return c.transport.SendWithDecoder(c.Client,
+			transport.Order(c.Roster.List, c.options), msg, reply, decoder)
+	}
+	if decoder != nil {
+		return c.SendProtobufParallelWithDecoder(c.Roster.List, msg, reply,
+			c.options, decoder)
+	}
+	return c.SendProtobufParallel(c.Roster.List, msg, reply, c.options)
+}
+
 func newLedgerWithClient(msg *CreateGenesisBlock, c *Client) (*CreateGenesisBlockResponse, error) {
 	reply := &CreateGenesisBlockResponse{}
 	if err := c.SendProtobuf(msg.Roster.List[0], msg, reply); err != nil {
@@ -183,13 +226,15 @@ func (c *Client) AddTransactionAndWait(tx ClientTransaction, wait int) (*AddTxRe
 	latest := c.getLatestKnownBlock()
 
 	reply := &AddTxResponse{}
-	_, err := c.SendProtobufParallel(c.Roster.List, &AddTxRequest{
+	_, err := c.sendRoster(&AddTxRequest{
 		Version:       CurrentVersion,
 		SkipchainID:   c.ID,
 		Transaction:   tx,
 		InclusionWait: wait,
 		ProofFrom:     latest.Hash,
-	}, reply, c.options)
+		TraceID:       c.traceID,
+		SpanID:        c.parentSpan,
+	}, reply, nil)
 	if err != nil {
 		return nil, xerrors.Errorf("sending: %v", err)
 	}
@@ -296,7 +341,7 @@ func (c *Client) GetUpdates(keyVer []IDVersion, flags GetUpdatesFlags,
 		Flags:         flags,
 		LatestBlockID: latest,
 	}
-	_, err = c.SendProtobufParallel(c.Roster.List, req, rep, c.options)
+	_, err = c.sendRoster(req, rep, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get updates: %v", err)
 	}
@@ -337,7 +382,7 @@ func (c *Client) getProofRaw(key []byte, from, include *skipchain.SkipBlock) (*G
 	}
 
 	reply := &GetProofResponse{}
-	_, err := c.SendProtobufParallelWithDecoder(c.Roster.List, req, reply, c.options, decoder)
+	_, err := c.sendRoster(req, reply, decoder)
 	if err != nil {
 		return nil, xerrors.Errorf("sending: %+v", err)
 	}
@@ -392,12 +437,12 @@ func (c *Client) GetDeferredDataAfter(instrID InstanceID, barrier *skipchain.Ski
 // execute in the given darc.
 func (c *Client) CheckAuthorization(dID darc.ID, ids ...darc.Identity) ([]darc.Action, error) {
 	reply := &CheckAuthorizationResponse{}
-	_, err := c.SendProtobufParallel(c.Roster.List, &CheckAuthorization{
+	_, err := c.sendRoster(&CheckAuthorization{
 		Version:    CurrentVersion,
 		ByzCoinID:  c.ID,
 		DarcID:     dID,
 		Identities: ids,
-	}, reply, c.options)
+	}, reply, nil)
 	if err != nil {
 		return nil, xerrors.Errorf("request: %v", err)
 	}
@@ -621,8 +666,7 @@ func (c *Client) GetSignerCounters(ids ...string) (*GetSignerCountersResponse, e
 		SignerIDs:   ids,
 	}
 	var reply GetSignerCountersResponse
-	_, err := c.SendProtobufParallelWithDecoder(c.Roster.List, &req, &reply,
-		c.options, c.signerCounterDecoder)
+	_, err := c.sendRoster(&req, &reply, c.signerCounterDecoder)
 	return &reply, cothority.ErrorOrNil(err, "request failed")
 }
 
@@ -686,10 +730,18 @@ func (c *Client) ResolveInstanceID(darcID darc.ID, name string) (InstanceID, err
 	}
 	reply := ResolvedInstanceID{}
 
-	_, err := c.SendProtobufParallel(c.Roster.List, &req, &reply, c.options)
+	_, err := c.sendRoster(&req, &reply, nil)
 	return reply.InstanceID, cothority.ErrorOrNil(err, "request failed")
 }
 
+// GetContracts returns the contracts registered by a node of the roster,
+// with their description if they provide one.
+func (c *Client) GetContracts() (*GetContractsReply, error) {
+	reply := &GetContractsReply{}
+	_, err := c.sendRoster(&GetContracts{}, reply, nil)
+	return reply, cothority.ErrorOrNil(err, "request failed")
+}
+
 // WaitPropagation contacts all nodes in the cl.Roster until they all
 // have the same latest block. If there is an error when calling
 // `GetProof`, the error will be ignored. This helps when waiting
@@ -17,6 +17,7 @@ import (
 
 	"github.com/urfave/cli"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/onet/v3"
@@ -831,6 +832,12 @@ func (fb *fetchBlocks) addURL(url string) error {
 
 func (fb *fetchBlocks) openDB(name string) (*skipchain.SkipBlockDB,
 	*bbolt.DB, error) {
+	// The db of an encrypted conode needs the same key.
+	key, err := atrest.LoadKey()
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't load the db key: %+v", err)
+	}
+	atrest.SetKey(key)
 	db, err := bbolt.Open(name, 0600, nil)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("couldn't open db: %+v", err)
@@ -442,6 +442,49 @@ var cmds = cli.Commands{
 		},
 	},
 
+	{
+		Name:  "contracts",
+		Usage: "shows the contracts registered by a node",
+		Subcommands: cli.Commands{
+			{
+				Name:    "list",
+				Usage:   "list the contracts with their version and description",
+				Aliases: []string{"ls"},
+				Action:  contractsList,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.IntFlag{
+						Name:  "server",
+						Usage: "which server number from the roster to contact (default: -1 = random)",
+						Value: -1,
+					},
+				},
+			},
+			{
+				Name:      "describe",
+				Usage:     "show the commands and arguments of a contract",
+				ArgsUsage: "contractID",
+				Action:    contractsDescribe,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.IntFlag{
+						Name:  "server",
+						Usage: "which server number from the roster to contact (default: -1 = random)",
+						Value: -1,
+					},
+				},
+			},
+		},
+	},
+
 	{
 		Name:      "create",
 		Usage:     "create a ledger",
@@ -482,6 +525,50 @@ var cmds = cli.Commands{
 					},
 				},
 			},
+			{
+				Name:   "diff",
+				Usage:  "Show the rule changes between two versions of a DARC",
+				Action: darcDiff,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "darc",
+						Usage: "the darc to compare (admin darc by default)",
+					},
+					cli.Uint64Flag{
+						Name:  "from",
+						Usage: "the version of the darc to compare from (default: the previous version of --to)",
+					},
+					cli.Uint64Flag{
+						Name:  "to",
+						Usage: "the version of the darc to compare to (default: the latest version)",
+					},
+				},
+			},
+			{
+				Name:   "graph",
+				Usage:  "Output the delegation graph of a DARC in the DOT format of Graphviz",
+				Action: darcGraph,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "darc",
+						Usage: "the darc to start from (admin darc by default)",
+					},
+					cli.StringFlag{
+						Name:  "out",
+						Usage: "output file for the graph (default: stdout)",
+					},
+				},
+			},
 			{
 				Name:   "cdesc",
 				Usage:  "Edit the description of a DARC",
@@ -860,9 +947,10 @@ var cmds = cli.Commands{
 		Usage: "displays infos about an instance",
 		Subcommands: cli.Commands{
 			{
-				Name:   "get",
-				Usage:  "Display the content of an instance",
-				Action: getInstance,
+				Name:      "get",
+				Usage:     "Display the content of an instance",
+				ArgsUsage: "[instance id]",
+				Action:    getInstance,
 				Flags: []cli.Flag{
 					cli.StringFlag{
 						Name:   "bc",
@@ -871,12 +959,16 @@ var cmds = cli.Commands{
 					},
 					cli.StringFlag{
 						Name:  "instid, i",
-						Usage: "the instance id (required)",
+						Usage: "the instance id, instead of the argument",
 					},
 					cli.BoolFlag{
 						Name:  "hex",
 						Usage: "if set, the data of the instance is hex encoded",
 					},
+					cli.BoolFlag{
+						Name:  "raw",
+						Usage: "if set, the data of the instance is not decoded",
+					},
 				},
 			},
 		},
@@ -897,6 +989,37 @@ var cmds = cli.Commands{
 				Usage: "print the private and public key",
 			},
 		},
+		Subcommands: cli.Commands{
+			{
+				Name:    "list",
+				Usage:   "list the keys of the keystore",
+				Aliases: []string{"ls"},
+				Action:  keyList,
+			},
+			{
+				Name:      "export",
+				Usage:     "export a key, encrypted if a passphrase is given",
+				ArgsUsage: "identity",
+				Action:    keyExport,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "out",
+						Usage: "the file to write the key to (required)",
+					},
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "import a key file in the keystore",
+				ArgsUsage: "key-file",
+				Action:    keyImport,
+			},
+			{
+				Name:   "encrypt",
+				Usage:  "encrypt the plain keys of the keystore with the passphrase",
+				Action: keyEncrypt,
+			},
+		},
 	},
 
 	{
@@ -961,6 +1084,66 @@ var cmds = cli.Commands{
 		Action:    mint,
 	},
 
+	{
+		Name:  "proof",
+		Usage: "save and verify proofs of instances",
+		Subcommands: cli.Commands{
+			{
+				Name:   "get",
+				Usage:  "save the proof of an instance, from the genesis block",
+				Action: proofGet,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance id (required)",
+					},
+					cli.StringFlag{
+						Name:  "out",
+						Usage: "the file to write the proof to (required)",
+					},
+					cli.StringFlag{
+						Name:  "genesis",
+						Usage: "the file to write the genesis block to",
+					},
+				},
+			},
+			{
+				Name:      "verify",
+				Usage:     "verify a saved proof without contacting any node",
+				ArgsUsage: "proof-file",
+				Action:    proofVerify,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "genesis",
+						Usage: "the file of the genesis block, as saved by proof get",
+					},
+					cli.StringFlag{
+						Name:  "roster",
+						Usage: "the roster of the genesis block, instead of --genesis",
+					},
+					cli.StringFlag{
+						Name:  "bcid",
+						Usage: "the ByzCoin ID in hex, with --roster",
+					},
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config giving the ByzCoin ID, with --roster",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance id the proof must be about, to verify an absence",
+					},
+				},
+			},
+		},
+	},
+
 	{
 		Name:    "qr",
 		Usage:   "generates a QRCode containing the description of the BC Config",
@@ -1025,4 +1208,97 @@ var cmds = cli.Commands{
 			},
 		},
 	},
+
+	{
+		Name:  "tx",
+		Usage: "compose transactions",
+		Subcommands: cli.Commands{
+			{
+				Name:      "apply",
+				Usage:     "sign and send the instructions described in a YAML file as one transaction",
+				ArgsUsage: "file.yaml",
+				Action:    txApply,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+				},
+			},
+			{
+				Name:      "batch",
+				Usage:     "send the instructions of a CSV file, one per row, in as many transactions as needed",
+				ArgsUsage: "file.csv",
+				Action:    txBatch,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.IntFlag{
+						Name:  "chunk",
+						Value: 100,
+						Usage: "the maximum number of instructions per transaction",
+					},
+					cli.IntFlag{
+						Name:  "retries",
+						Value: 3,
+						Usage: "how many times a failed transaction is sent again",
+					},
+					cli.StringFlag{
+						Name:  "report",
+						Usage: "the file to write the CSV report to (default: stdout)",
+					},
+				},
+			},
+			{
+				Name:      "prepare",
+				Usage:     "write the unsigned transaction of the instructions described in a YAML file",
+				ArgsUsage: "file.yaml",
+				Action:    txPrepare,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "out",
+						Usage: "the file to write the transaction to (required)",
+					},
+				},
+			},
+			{
+				Name:      "sign",
+				Usage:     "add the signatures of a key to a prepared transaction, without going online",
+				ArgsUsage: "tx-file",
+				Action:    txSign,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "sign",
+						Usage: "the public key to sign with (required)",
+					},
+					cli.StringFlag{
+						Name:  "out",
+						Usage: "the file to write the signed transaction to (default: the same file)",
+					},
+				},
+			},
+			{
+				Name:      "send",
+				Usage:     "send a prepared transaction once it is fully signed",
+				ArgsUsage: "tx-file",
+				Action:    txSend,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+				},
+			},
+		},
+	},
 }
@@ -77,12 +77,17 @@ func LoadKeyFromString(id string) (*darc.Signer, error) {
 	return LoadSigner(fn)
 }
 
-// LoadSigner loads a signer from a file given by fn.
+// LoadSigner loads a signer from a file given by fn. If the file is
+// encrypted, it is decrypted with the passphrase of the keystore.
 func LoadSigner(fn string) (*darc.Signer, error) {
 	buf, err := ioutil.ReadFile(fn)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to read this path: '%s': %v", fn, err)
 	}
+	buf, err = decodeSigner(buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt '%s': %v", fn, err)
+	}
 
 	var signer darc.Signer
 	err = protobuf.DecodeWithConstructors(buf, &signer,
@@ -94,22 +99,23 @@ func LoadSigner(fn string) (*darc.Signer, error) {
 	return &signer, err
 }
 
-// SaveKey stores a signer in a file.
+// SaveKey stores a signer in a file. The file is encrypted if a passphrase
+// is configured for the keystore.
 func SaveKey(signer darc.Signer) error {
 	os.MkdirAll(ConfigPath, 0755)
 
 	fn := fmt.Sprintf("key-%s.cfg", signer.Identity())
 	fn = filepath.Join(ConfigPath, fn)
 
-	// perms = 0400 because there is key material inside this file.
-	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0400)
+	buf, err := EncodeSigner(signer)
 	if err != nil {
-		return xerrors.Errorf("could not write %v: %v", fn, err)
+		return err
 	}
 
-	buf, err := protobuf.Encode(&signer)
+	// perms = 0400 because there is key material inside this file.
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0400)
 	if err != nil {
-		return err
+		return xerrors.Errorf("could not write %v: %v", fn, err)
 	}
 	_, err = f.Write(buf)
 	if err != nil {
@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"golang.org/x/xerrors"
+)
+
+// RuleChangeKind tells how a rule differs between two versions of a DARC.
+type RuleChangeKind int
+
+const (
+	// RuleAdded is a rule only present in the newer DARC.
+	RuleAdded RuleChangeKind = iota
+	// RuleRemoved is a rule only present in the older DARC.
+	RuleRemoved
+	// RuleChanged is a rule present in both DARCs with a different
+	// expression.
+	RuleChanged
+)
+
+// RuleChange describes the difference of one rule between two versions of a
+// DARC. OldExpr is empty for added rules and NewExpr is empty for removed
+// rules.
+type RuleChange struct {
+	Kind    RuleChangeKind
+	Action  darc.Action
+	OldExpr string
+	NewExpr string
+}
+
+// String returns a one-line representation of the change, prefixed with "+"
+// for additions, "-" for removals and "~" for changes.
+func (rc RuleChange) String() string {
+	switch rc.Kind {
+	case RuleAdded:
+		return fmt.Sprintf("+ %s - \"%s\"", rc.Action, rc.NewExpr)
+	case RuleRemoved:
+		return fmt.Sprintf("- %s - \"%s\"", rc.Action, rc.OldExpr)
+	default:
+		return fmt.Sprintf("~ %s - \"%s\" => \"%s\"", rc.Action, rc.OldExpr,
+			rc.NewExpr)
+	}
+}
+
+// DarcDiff holds the differences between two versions of a DARC.
+type DarcDiff struct {
+	OldVersion     uint64
+	NewVersion     uint64
+	OldDescription string
+	NewDescription string
+	Rules          []RuleChange
+}
+
+// Empty returns true if both versions have the same description and rules.
+func (dd DarcDiff) Empty() bool {
+	return dd.OldDescription == dd.NewDescription && len(dd.Rules) == 0
+}
+
+// String returns a human readable representation of the differences, one
+// line per change.
+func (dd DarcDiff) String() string {
+	lines := []string{fmt.Sprintf("Version %d => %d", dd.OldVersion,
+		dd.NewVersion)}
+	if dd.OldDescription != dd.NewDescription {
+		lines = append(lines, fmt.Sprintf("~ description - \"%s\" => \"%s\"",
+			dd.OldDescription, dd.NewDescription))
+	}
+	for _, rc := range dd.Rules {
+		lines = append(lines, rc.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffDarcs compares the rules of two DARCs and returns the additions,
+// removals and changes needed to go from oldD to newD. The rule changes are
+// sorted by action.
+func DiffDarcs(oldD, newD *darc.Darc) DarcDiff {
+	dd := DarcDiff{
+		OldVersion:     oldD.Version,
+		NewVersion:     newD.Version,
+		OldDescription: string(oldD.Description),
+		NewDescription: string(newD.Description),
+		Rules:          []RuleChange{},
+	}
+
+	for _, rule := range oldD.Rules.List {
+		newExpr := newD.Rules.Get(rule.Action)
+		switch {
+		case newExpr == nil:
+			dd.Rules = append(dd.Rules, RuleChange{
+				Kind:    RuleRemoved,
+				Action:  rule.Action,
+				OldExpr: string(rule.Expr),
+			})
+		case string(newExpr) != string(rule.Expr):
+			dd.Rules = append(dd.Rules, RuleChange{
+				Kind:    RuleChanged,
+				Action:  rule.Action,
+				OldExpr: string(rule.Expr),
+				NewExpr: string(newExpr),
+			})
+		}
+	}
+	for _, rule := range newD.Rules.List {
+		if !oldD.Rules.Contains(rule.Action) {
+			dd.Rules = append(dd.Rules, RuleChange{
+				Kind:    RuleAdded,
+				Action:  rule.Action,
+				NewExpr: string(rule.Expr),
+			})
+		}
+	}
+
+	sort.SliceStable(dd.Rules, func(i, j int) bool {
+		return dd.Rules[i].Action < dd.Rules[j].Action
+	})
+	return dd
+}
+
+// GetDarcVersion returns the given version of a DARC as it has been stored on
+// the chain.
+func GetDarcVersion(cl *byzcoin.Client, id darc.ID, version uint64) (*darc.Darc, error) {
+	req := byzcoin.GetInstanceVersion{
+		SkipChainID: cl.ID,
+		InstanceID:  byzcoin.NewInstanceID(id),
+		Version:     version,
+	}
+	var reply byzcoin.GetInstanceVersionResponse
+	_, err := cl.SendProtobufParallel(cl.Roster.List, &req, &reply, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get version %d of darc %x: %v",
+			version, id, err)
+	}
+
+	sc := reply.StateChange
+	if sc.ContractID != byzcoin.ContractDarcID {
+		return nil, xerrors.Errorf("unexpected contract %v, expected a darc",
+			sc.ContractID)
+	}
+
+	d, err := darc.NewFromProtobuf(sc.Value)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode darc: %v", err)
+	}
+	return d, nil
+}
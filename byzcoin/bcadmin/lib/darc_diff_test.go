@@ -0,0 +1,65 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+)
+
+func TestDiffDarcs(t *testing.T) {
+	owner := darc.NewSignerEd25519(nil, nil)
+	other := darc.NewSignerEd25519(nil, nil)
+	rules := darc.InitRules([]darc.Identity{owner.Identity()},
+		[]darc.Identity{owner.Identity()})
+	require.NoError(t, rules.AddRule("spawn:value",
+		expression.Expr(owner.Identity().String())))
+	require.NoError(t, rules.AddRule("invoke:value.update",
+		expression.Expr(owner.Identity().String())))
+	d1 := darc.NewDarc(rules, []byte("first"))
+
+	diff := DiffDarcs(d1, d1.Copy())
+	require.True(t, diff.Empty())
+
+	d2 := d1.Copy()
+	require.NoError(t, d2.EvolveFrom(d1))
+	require.NoError(t, d2.Rules.DeleteRules("invoke:value.update"))
+	require.NoError(t, d2.Rules.UpdateRule("spawn:value",
+		expression.Expr(other.Identity().String())))
+	require.NoError(t, d2.Rules.AddRule("spawn:deferred",
+		expression.Expr(other.Identity().String())))
+	d2.Description = []byte("second")
+
+	diff = DiffDarcs(d1, d2)
+	require.False(t, diff.Empty())
+	require.Equal(t, uint64(0), diff.OldVersion)
+	require.Equal(t, uint64(1), diff.NewVersion)
+	require.Equal(t, "first", diff.OldDescription)
+	require.Equal(t, "second", diff.NewDescription)
+	require.Equal(t, []RuleChange{
+		{
+			Kind:    RuleRemoved,
+			Action:  "invoke:value.update",
+			OldExpr: owner.Identity().String(),
+		},
+		{
+			Kind:    RuleAdded,
+			Action:  "spawn:deferred",
+			NewExpr: other.Identity().String(),
+		},
+		{
+			Kind:    RuleChanged,
+			Action:  "spawn:value",
+			OldExpr: owner.Identity().String(),
+			NewExpr: other.Identity().String(),
+		},
+	}, diff.Rules)
+
+	// The reverse diff swaps additions and removals.
+	diff = DiffDarcs(d2, d1)
+	require.Equal(t, 3, len(diff.Rules))
+	require.Equal(t, RuleAdded, diff.Rules[0].Kind)
+	require.Equal(t, RuleRemoved, diff.Rules[1].Kind)
+	require.Equal(t, RuleChanged, diff.Rules[2].Kind)
+}
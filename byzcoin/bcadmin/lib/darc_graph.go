@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/darc"
+	"golang.org/x/xerrors"
+)
+
+// identityRe matches the identities of a rule expression, following the
+// grammar of the expression package.
+var identityRe = regexp.MustCompile(`(darc|ed25519|x509ec):[0-9a-fA-F]+|` +
+	`proxy:[0-9a-fA-F]+:[^ \n\t()]*|` +
+	`evm_contract:[0-9a-fA-F]+:0x[0-9a-fA-F]+|` +
+	`attr:[0-9a-zA-Z\-\_]+:[^ \n\t()]*`)
+
+// DarcGraph is the delegation graph of a DARC: the DARC itself and all the
+// DARCs its rules delegate to, directly or not.
+type DarcGraph struct {
+	// Darcs are the DARCs of the graph, in the order they were crawled,
+	// starting with the root.
+	Darcs []*darc.Darc
+}
+
+// CrawlDarcs builds the delegation graph of the root DARC, using get to fetch
+// the latest version of a DARC given its base ID.
+func CrawlDarcs(root darc.ID, get func(darc.ID) (*darc.Darc,
+	error)) (*DarcGraph, error) {
+	g := &DarcGraph{}
+	seen := map[string]bool{hex.EncodeToString(root): true}
+	queue := []darc.ID{root}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		d, err := get(id)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to get darc:%x: %v", id, err)
+		}
+		g.Darcs = append(g.Darcs, d)
+
+		for _, rule := range d.Rules.List {
+			for _, identity := range ruleIdentities(rule.Expr) {
+				if !strings.HasPrefix(identity, "darc:") {
+					continue
+				}
+				hexID := strings.ToLower(strings.TrimPrefix(identity, "darc:"))
+				if seen[hexID] {
+					continue
+				}
+				seen[hexID] = true
+
+				next, err := hex.DecodeString(hexID)
+				if err != nil {
+					return nil, xerrors.Errorf("invalid identity %s in "+
+						"darc:%x: %v", identity, d.GetBaseID(), err)
+				}
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// DOT returns the graph in the DOT language of Graphviz. Every DARC is a box
+// labelled with its description, every other identity an ellipse, and every
+// edge goes from a DARC to an identity and is labelled with the actions of
+// the rules the identity appears in.
+func (g DarcGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph darcs {\n")
+	b.WriteString("  rankdir=LR;\n")
+
+	darcs := map[string]bool{}
+	for _, d := range g.Darcs {
+		darcs[d.GetIdentityString()] = true
+	}
+
+	others := map[string]bool{}
+	var edges []string
+	for _, d := range g.Darcs {
+		from := d.GetIdentityString()
+		fmt.Fprintf(&b, "  %q [shape=box, label=%q];\n", from,
+			fmt.Sprintf("%s\n%s", d.Description, shortIdentity(from)))
+
+		actions := map[string][]string{}
+		for _, rule := range d.Rules.List {
+			for _, identity := range ruleIdentities(rule.Expr) {
+				actions[identity] = append(actions[identity],
+					string(rule.Action))
+			}
+		}
+
+		var targets []string
+		for identity := range actions {
+			targets = append(targets, identity)
+			if !darcs[identity] {
+				others[identity] = true
+			}
+		}
+		sort.Strings(targets)
+		for _, to := range targets {
+			edges = append(edges, fmt.Sprintf("  %q -> %q [label=%q];\n",
+				from, to, strings.Join(actions[to], "\n")))
+		}
+	}
+
+	var ids []string
+	for identity := range others {
+		ids = append(ids, identity)
+	}
+	sort.Strings(ids)
+	for _, identity := range ids {
+		fmt.Fprintf(&b, "  %q [shape=ellipse, label=%q];\n", identity,
+			shortIdentity(identity))
+	}
+
+	for _, edge := range edges {
+		b.WriteString(edge)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ruleIdentities returns the identities of an expression, without
+// duplicates and in the order they appear.
+func ruleIdentities(expr []byte) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range identityRe.FindAllString(string(expr), -1) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// shortIdentity truncates the key of an identity to keep the graph
+// readable.
+func shortIdentity(identity string) string {
+	parts := strings.SplitN(identity, ":", 2)
+	if len(parts) != 2 || len(parts[1]) <= 16 {
+		return identity
+	}
+	return parts[0] + ":" + parts[1][:16] + "…"
+}
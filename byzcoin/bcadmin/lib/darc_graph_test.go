@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"golang.org/x/xerrors"
+)
+
+func TestCrawlDarcs(t *testing.T) {
+	owner := darc.NewSignerEd25519(nil, nil)
+	user := darc.NewSignerEd25519(nil, nil)
+
+	userDarc := darc.NewDarc(darc.InitRules([]darc.Identity{user.Identity()},
+		[]darc.Identity{user.Identity()}), []byte("user"))
+	require.NoError(t, userDarc.Rules.AddRule("invoke:value.update",
+		expression.Expr(owner.Identity().String())))
+	// The user darc is referenced twice, but crawled once
+	rootDarc := darc.NewDarc(darc.InitRules([]darc.Identity{owner.Identity()},
+		[]darc.Identity{owner.Identity()}), []byte("root"))
+	require.NoError(t, rootDarc.Rules.AddRule("spawn:value",
+		expression.Expr(owner.Identity().String()+" | "+
+			userDarc.GetIdentityString())))
+	require.NoError(t, rootDarc.Rules.AddRule("invoke:value.update",
+		expression.Expr(userDarc.GetIdentityString())))
+
+	darcs := map[string]*darc.Darc{
+		string(rootDarc.GetBaseID()): rootDarc,
+		string(userDarc.GetBaseID()): userDarc,
+	}
+	get := func(id darc.ID) (*darc.Darc, error) {
+		d, ok := darcs[string(id)]
+		if !ok {
+			return nil, xerrors.New("unknown darc")
+		}
+		return d, nil
+	}
+
+	g, err := CrawlDarcs(rootDarc.GetBaseID(), get)
+	require.NoError(t, err)
+	require.Equal(t, []*darc.Darc{rootDarc, userDarc}, g.Darcs)
+
+	dot := g.DOT()
+	require.True(t, strings.HasPrefix(dot, "digraph darcs {"))
+	require.Contains(t, dot, `[shape=box, label="root\n`)
+	require.Contains(t, dot, `[shape=box, label="user\n`)
+	require.Equal(t, 2, strings.Count(dot, "[shape=ellipse"))
+	require.Contains(t, dot, `"`+rootDarc.GetIdentityString()+`" -> "`+
+		userDarc.GetIdentityString()+
+		`" [label="spawn:value\ninvoke:value.update"]`)
+	require.Contains(t, dot, `"`+rootDarc.GetIdentityString()+`" -> "`+
+		owner.Identity().String()+
+		`" [label="_evolve\n_sign\nspawn:value"]`)
+	require.Contains(t, dot, `"`+userDarc.GetIdentityString()+`" -> "`+
+		owner.Identity().String()+`" [label="invoke:value.update"]`)
+
+	// A missing darc fails the crawl
+	delete(darcs, string(userDarc.GetBaseID()))
+	_, err = CrawlDarcs(rootDarc.GetBaseID(), get)
+	require.Error(t, err)
+}
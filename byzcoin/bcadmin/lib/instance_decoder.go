@@ -0,0 +1,165 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/darc"
+	phcontracts "go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// InstanceDecoder returns a human readable description of the value of an
+// instance, in the "- Field:\n-- Subfield" style of the String methods of
+// ByzCoin.
+type InstanceDecoder func(value []byte) (string, error)
+
+var instanceDecoders = map[string]InstanceDecoder{
+	byzcoin.ContractConfigID:         decodeConfig,
+	byzcoin.ContractDarcID:           decodeDarc,
+	contracts.ContractCoinID:         decodeCoin,
+	calypso.ContractWriteID:          decodeWrite,
+	calypso.ContractReadID:           decodeRead,
+	phcontracts.ContractCredentialID: decodeCredential,
+}
+
+// RegisterInstanceDecoder sets the decoder of the instances of a contract,
+// replacing the previous one if any.
+func RegisterInstanceDecoder(contractID string, decoder InstanceDecoder) {
+	instanceDecoders[contractID] = decoder
+}
+
+// DecodeInstance returns the description of the value of an instance by the
+// decoder of its contract. It returns false if the contract has no decoder.
+func DecodeInstance(contractID string, value []byte) (string, bool, error) {
+	decoder, ok := instanceDecoders[contractID]
+	if !ok {
+		return "", false, nil
+	}
+	desc, err := decoder(value)
+	if err != nil {
+		return "", true, xerrors.Errorf("failed to decode %s instance: %v",
+			contractID, err)
+	}
+	return desc, true, nil
+}
+
+func decodeConfig(value []byte) (string, error) {
+	var config byzcoin.ChainConfig
+	err := protobuf.DecodeWithConstructors(value, &config,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return "", err
+	}
+	return config.String(), nil
+}
+
+func decodeDarc(value []byte) (string, error) {
+	d, err := darc.NewFromProtobuf(value)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+func decodeCoin(value []byte) (string, error) {
+	var coin byzcoin.Coin
+	err := protobuf.Decode(value, &coin)
+	if err != nil {
+		return "", err
+	}
+
+	res := new(strings.Builder)
+	res.WriteString("- Coin:\n")
+	fmt.Fprintf(res, "-- Name: %x\n", coin.Name[:])
+	fmt.Fprintf(res, "-- Value: %d\n", coin.Value)
+	return res.String(), nil
+}
+
+func decodeWrite(value []byte) (string, error) {
+	var write calypso.Write
+	err := protobuf.DecodeWithConstructors(value, &write,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return "", err
+	}
+
+	res := new(strings.Builder)
+	res.WriteString("- Write:\n")
+	fmt.Fprintf(res, "-- LTSID: %x\n", write.LTSID[:])
+	fmt.Fprintf(res, "-- Data: %d bytes\n", len(write.Data))
+	fmt.Fprintf(res, "-- ExtraData: %s\n", printable(write.ExtraData))
+	fmt.Fprintf(res, "-- U: %s\n", write.U)
+	fmt.Fprintf(res, "-- Ubar: %s\n", write.Ubar)
+	fmt.Fprintf(res, "-- E: %s\n", write.E)
+	fmt.Fprintf(res, "-- F: %s\n", write.F)
+	fmt.Fprintf(res, "-- C: %s\n", write.C)
+	if write.Cost.Value > 0 {
+		fmt.Fprintf(res, "-- Cost: %d of %x\n", write.Cost.Value,
+			write.Cost.Name[:])
+	}
+	return res.String(), nil
+}
+
+func decodeRead(value []byte) (string, error) {
+	var read calypso.Read
+	err := protobuf.DecodeWithConstructors(value, &read,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return "", err
+	}
+
+	res := new(strings.Builder)
+	res.WriteString("- Read:\n")
+	fmt.Fprintf(res, "-- Write: %x\n", read.Write[:])
+	fmt.Fprintf(res, "-- Xc: %s\n", read.Xc)
+	return res.String(), nil
+}
+
+func decodeCredential(value []byte) (string, error) {
+	var cred phcontracts.CredentialStruct
+	err := protobuf.Decode(value, &cred)
+	if err != nil {
+		return "", err
+	}
+
+	res := new(strings.Builder)
+	res.WriteString("- Credential:\n")
+	for _, c := range cred.Credentials {
+		fmt.Fprintf(res, "-- %s:\n", c.Name)
+		for _, a := range c.Attributes {
+			fmt.Fprintf(res, "--- %s: %s\n", a.Name, printable(a.Value))
+		}
+	}
+	if cred.Expires > 0 {
+		fmt.Fprintf(res, "-- Expires: %s\n",
+			time.Unix(cred.Expires, 0).UTC().Format(time.RFC3339))
+	}
+	if cred.Party != nil {
+		fmt.Fprintf(res, "-- Party: %x\n", cred.Party[:])
+	}
+	return res.String(), nil
+}
+
+// printable returns the bytes quoted if they are a printable string, else
+// hex encoded.
+func printable(buf []byte) string {
+	if !utf8.Valid(buf) {
+		return fmt.Sprintf("%x", buf)
+	}
+	for _, r := range string(buf) {
+		if !strconv.IsPrint(r) {
+			return fmt.Sprintf("%x", buf)
+		}
+	}
+	return strconv.Quote(string(buf))
+}
@@ -0,0 +1,65 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/darc"
+	phcontracts "go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/protobuf"
+)
+
+func TestDecodeInstance(t *testing.T) {
+	d := darc.NewDarc(darc.InitRules(nil, nil), []byte("genesis darc"))
+	buf, err := d.ToProto()
+	require.NoError(t, err)
+	desc, ok, err := DecodeInstance(byzcoin.ContractDarcID, buf)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Contains(t, desc, "genesis darc")
+
+	buf, err = protobuf.Encode(&byzcoin.Coin{Name: byzcoin.NewInstanceID(
+		[]byte("coin")), Value: 42})
+	require.NoError(t, err)
+	desc, ok, err = DecodeInstance(contracts.ContractCoinID, buf)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Contains(t, desc, "-- Value: 42\n")
+
+	buf, err = protobuf.Encode(&phcontracts.CredentialStruct{
+		Credentials: []phcontracts.Credential{{
+			Name: "public",
+			Attributes: []phcontracts.Attribute{
+				{Name: "alias", Value: []byte("alice")},
+				{Name: "ed25519", Value: []byte{0, 1, 2}},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	desc, ok, err = DecodeInstance(phcontracts.ContractCredentialID, buf)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Contains(t, desc, "--- alias: \"alice\"\n")
+	require.Contains(t, desc, "--- ed25519: 000102\n")
+
+	// Garbage fails to decode
+	_, ok, err = DecodeInstance(contracts.ContractCoinID, []byte{0xff})
+	require.Error(t, err)
+	require.True(t, ok)
+
+	// Contracts without a decoder are left to the caller
+	_, ok, err = DecodeInstance("unknown", buf)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	RegisterInstanceDecoder("unknown", func([]byte) (string, error) {
+		return "- Unknown\n", nil
+	})
+	defer delete(instanceDecoders, "unknown")
+	desc, ok, err = DecodeInstance("unknown", buf)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "- Unknown\n", desc)
+}
@@ -0,0 +1,272 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/xerrors"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase of the
+// keystore.
+const PassphraseEnv = "BC_PASSPHRASE"
+
+// PassphraseCmdEnv is the environment variable holding a shell command that
+// prints the passphrase of the keystore. It allows to keep the passphrase in
+// the keychain of the OS, e.g. with
+// `security find-generic-password -w -s bcadmin` on macOS or
+// `secret-tool lookup service bcadmin` on Linux.
+const PassphraseCmdEnv = "BC_PASSPHRASE_CMD"
+
+// keystoreMagic starts every encrypted key file, to tell them apart from the
+// plain ones.
+var keystoreMagic = []byte("bcadmin-keystore-v1\n")
+
+// Parameters of scrypt for new key files, as recommended for interactive
+// logins.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// GetPassphrase returns the passphrase of the keystore, or nil if none is
+// configured, in which case the keys are stored in clear. By default it is
+// read from PassphraseEnv or from the output of PassphraseCmdEnv. It can be
+// replaced by the CLIs, to prompt the user for example.
+var GetPassphrase = func() ([]byte, error) {
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		return []byte(pass), nil
+	}
+
+	cmd := os.Getenv(PassphraseCmdEnv)
+	if cmd == "" {
+		return nil, nil
+	}
+	out, err := exec.Command("sh", "-c", cmd).Output()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to run %s: %v", PassphraseCmdEnv,
+			err)
+	}
+	pass := bytes.TrimRight(out, "\r\n")
+	if len(pass) == 0 {
+		return nil, xerrors.Errorf("%s returned an empty passphrase",
+			PassphraseCmdEnv)
+	}
+	return pass, nil
+}
+
+// encryptedKey is the content of an encrypted key file, after the magic.
+type encryptedKey struct {
+	Salt       []byte
+	N, R, P    int
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// KeyInfo describes a key of the keystore.
+type KeyInfo struct {
+	// Identity is the identity of the key, as used in the --sign flags.
+	Identity  string
+	Encrypted bool
+}
+
+// ListKeys returns the keys stored in the ConfigPath, sorted by identity.
+func ListKeys() ([]KeyInfo, error) {
+	files, err := filepath.Glob(filepath.Join(ConfigPath, "key-*.cfg"))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list key files: %v", err)
+	}
+	sort.Strings(files)
+
+	keys := make([]KeyInfo, len(files))
+	for i, fn := range files {
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to read key file: %v", err)
+		}
+		name := filepath.Base(fn)
+		keys[i] = KeyInfo{
+			Identity: strings.TrimSuffix(strings.TrimPrefix(name, "key-"),
+				".cfg"),
+			Encrypted: IsEncryptedKey(buf),
+		}
+	}
+	return keys, nil
+}
+
+// IsEncryptedKey returns true if the content of a key file is encrypted.
+func IsEncryptedKey(buf []byte) bool {
+	return bytes.HasPrefix(buf, keystoreMagic)
+}
+
+// EncodeSigner returns the content of the key file of a signer, encrypted
+// if a passphrase is configured.
+func EncodeSigner(signer darc.Signer) ([]byte, error) {
+	buf, err := protobuf.Encode(&signer)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode signer: %v", err)
+	}
+
+	pass, err := GetPassphrase()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get passphrase: %v", err)
+	}
+	if pass == nil {
+		return buf, nil
+	}
+	return encryptKeyFile(buf, pass)
+}
+
+// EncryptKeys encrypts all the plain keys of the ConfigPath with the
+// passphrase, and returns the number of keys encrypted.
+func EncryptKeys() (int, error) {
+	pass, err := GetPassphrase()
+	if err != nil {
+		return 0, xerrors.Errorf("failed to get passphrase: %v", err)
+	}
+	if pass == nil {
+		return 0, xerrors.Errorf("no passphrase given, please set %s or %s",
+			PassphraseEnv, PassphraseCmdEnv)
+	}
+
+	keys, err := ListKeys()
+	if err != nil {
+		return 0, err
+	}
+	encrypted := 0
+	for _, key := range keys {
+		if key.Encrypted {
+			continue
+		}
+		fn := filepath.Join(ConfigPath, "key-"+key.Identity+".cfg")
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return encrypted, xerrors.Errorf("failed to read key file: %v",
+				err)
+		}
+		buf, err = encryptKeyFile(buf, pass)
+		if err != nil {
+			return encrypted, err
+		}
+		err = replaceKeyFile(fn, buf)
+		if err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+	return encrypted, nil
+}
+
+// decodeSigner decodes the content of a key file, decrypting it with the
+// passphrase if needed.
+func decodeSigner(buf []byte) ([]byte, error) {
+	if !IsEncryptedKey(buf) {
+		return buf, nil
+	}
+
+	pass, err := GetPassphrase()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get passphrase: %v", err)
+	}
+	if pass == nil {
+		return nil, xerrors.Errorf("key file is encrypted, please set %s or %s",
+			PassphraseEnv, PassphraseCmdEnv)
+	}
+	return decryptKeyFile(buf, pass)
+}
+
+func encryptKeyFile(buf, pass []byte) ([]byte, error) {
+	ek := encryptedKey{
+		Salt: make([]byte, 16),
+		N:    scryptN,
+		R:    scryptR,
+		P:    scryptP,
+	}
+	_, err := rand.Read(ek.Salt)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to pick a salt: %v", err)
+	}
+
+	aead, err := keystoreCipher(pass, ek)
+	if err != nil {
+		return nil, err
+	}
+	ek.Nonce = make([]byte, aead.NonceSize())
+	_, err = rand.Read(ek.Nonce)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to pick a nonce: %v", err)
+	}
+	ek.Ciphertext = aead.Seal(nil, ek.Nonce, buf, keystoreMagic)
+
+	ekBuf, err := protobuf.Encode(&ek)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode encrypted key: %v", err)
+	}
+	return append(append([]byte{}, keystoreMagic...), ekBuf...), nil
+}
+
+func decryptKeyFile(buf, pass []byte) ([]byte, error) {
+	var ek encryptedKey
+	err := protobuf.Decode(buf[len(keystoreMagic):], &ek)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode encrypted key: %v", err)
+	}
+
+	aead, err := keystoreCipher(pass, ek)
+	if err != nil {
+		return nil, err
+	}
+	if len(ek.Nonce) != aead.NonceSize() {
+		return nil, xerrors.New("invalid nonce in encrypted key")
+	}
+	plain, err := aead.Open(nil, ek.Nonce, ek.Ciphertext, keystoreMagic)
+	if err != nil {
+		return nil, xerrors.New("failed to decrypt key, wrong passphrase?")
+	}
+	return plain, nil
+}
+
+func keystoreCipher(pass []byte, ek encryptedKey) (cipher.AEAD, error) {
+	key, err := scrypt.Key(pass, ek.Salt, ek.N, ek.R, ek.P, scryptKeyLen)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to derive key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create GCM: %v", err)
+	}
+	return aead, nil
+}
+
+// replaceKeyFile atomically replaces a key file, which is read-only.
+func replaceKeyFile(fn string, buf []byte) error {
+	tmp := fn + ".tmp"
+	os.Remove(tmp)
+	err := ioutil.WriteFile(tmp, buf, 0400)
+	if err != nil {
+		return xerrors.Errorf("could not write %v: %v", tmp, err)
+	}
+	err = os.Rename(tmp, fn)
+	if err != nil {
+		os.Remove(tmp)
+		return xerrors.Errorf("could not replace %v: %v", fn, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func TestKeystore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldPath, oldGet := ConfigPath, GetPassphrase
+	defer func() { ConfigPath, GetPassphrase = oldPath, oldGet }()
+	ConfigPath = dir
+	var pass []byte
+	GetPassphrase = func() ([]byte, error) { return pass, nil }
+
+	// Without passphrase, the keys are stored in clear
+	plain := darc.NewSignerEd25519(nil, nil)
+	require.NoError(t, SaveKey(plain))
+	_, err = EncryptKeys()
+	require.Error(t, err)
+
+	pass = []byte("secret")
+	encrypted := darc.NewSignerEd25519(nil, nil)
+	require.NoError(t, SaveKey(encrypted))
+	buf, err := ioutil.ReadFile(filepath.Join(dir,
+		"key-"+encrypted.Identity().String()+".cfg"))
+	require.NoError(t, err)
+	require.True(t, IsEncryptedKey(buf))
+
+	keys, err := ListKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Contains(t, keys, KeyInfo{Identity: plain.Identity().String()})
+	require.Contains(t, keys, KeyInfo{Identity: encrypted.Identity().String(),
+		Encrypted: true})
+
+	signer, err := LoadKey(encrypted.Identity())
+	require.NoError(t, err)
+	require.Equal(t, encrypted.Ed25519.Secret.String(),
+		signer.Ed25519.Secret.String())
+
+	// The plain keys get encrypted
+	n, err := EncryptKeys()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	keys, err = ListKeys()
+	require.NoError(t, err)
+	for _, k := range keys {
+		require.True(t, k.Encrypted)
+	}
+	signer, err = LoadKey(plain.Identity())
+	require.NoError(t, err)
+	require.Equal(t, plain.Ed25519.Secret.String(),
+		signer.Ed25519.Secret.String())
+
+	// Encrypted keys can't be read with a wrong or missing passphrase
+	pass = []byte("wrong")
+	_, err = LoadKey(plain.Identity())
+	require.Error(t, err)
+	pass = nil
+	_, err = LoadKey(plain.Identity())
+	require.Error(t, err)
+}
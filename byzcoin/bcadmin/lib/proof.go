@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"io/ioutil"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ReadProof reads a proof saved by `bcadmin proof get`.
+func ReadProof(path string) (*byzcoin.Proof, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read proof: %v", err)
+	}
+	var p byzcoin.Proof
+	err = protobuf.DecodeWithConstructors(buf, &p,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode proof: %v", err)
+	}
+	return &p, nil
+}
+
+// ReadGenesis reads a genesis block saved by `bcadmin proof get --genesis`
+// and checks that its hash matches its content.
+func ReadGenesis(path string) (*skipchain.SkipBlock, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read genesis block: %v", err)
+	}
+	var sb skipchain.SkipBlock
+	err = protobuf.DecodeWithConstructors(buf, &sb,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode genesis block: %v", err)
+	}
+	if sb.Index != 0 {
+		return nil, xerrors.Errorf("block %d is not a genesis block", sb.Index)
+	}
+	if !sb.CalculateHash().Equal(sb.Hash) {
+		return nil, xerrors.New("hash of the genesis block doesn't match " +
+			"its content")
+	}
+	return &sb, nil
+}
+
+// VerifyProofFromGenesis verifies, without contacting any node, that the
+// links of the proof go from the genesis block to its latest block and that
+// its inclusion proof matches the latest block.
+func VerifyProofFromGenesis(p byzcoin.Proof, genesis *skipchain.SkipBlock) error {
+	err := p.VerifyFromBlock(genesis)
+	if err != nil {
+		return xerrors.Errorf("invalid proof: %v", err)
+	}
+	return nil
+}
+
+// VerifyProofFromRoster is like VerifyProofFromGenesis for a chain whose ID
+// and genesis roster are known instead of its genesis block.
+func VerifyProofFromRoster(p byzcoin.Proof, id skipchain.SkipBlockID,
+	roster *onet.Roster) error {
+	if len(p.Links) == 0 {
+		return xerrors.New("invalid proof: missing forward-links")
+	}
+	if !p.Links[0].To.Equal(id) {
+		return xerrors.New("invalid proof: it doesn't start at the genesis block")
+	}
+
+	// Don't change the links of the caller
+	p.Links = append([]skipchain.ForwardLink{}, p.Links...)
+	p.Links[0].NewRoster = roster
+	err := p.Verify(id)
+	if err != nil {
+		return xerrors.Errorf("invalid proof: %v", err)
+	}
+	// Without forward-links, the roster is only given by the genesis block
+	if len(p.Links) == 1 &&
+		(p.Latest.Roster == nil || p.Latest.Roster.ID != roster.ID) {
+		return xerrors.New("invalid proof: the roster of the genesis block " +
+			"is different")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/trie"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+func TestVerifyProof(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proof")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A chain with only a genesis block, whose trie holds one key
+	tr, err := trie.NewTrie(trie.NewMemDB(), []byte("nonce"))
+	require.NoError(t, err)
+	require.NoError(t, tr.Set([]byte("key"), []byte("value")))
+	ip, err := tr.GetProof([]byte("key"))
+	require.NoError(t, err)
+
+	kp := key.NewKeyPair(cothority.Suite)
+	roster := onet.NewRoster([]*network.ServerIdentity{
+		network.NewServerIdentity(kp.Public,
+			network.NewAddress(network.TLS, "127.0.0.1:7770")),
+	})
+	genesis := skipchain.NewSkipBlock()
+	genesis.Roster = roster
+	genesis.Data, err = protobuf.Encode(&byzcoin.DataHeader{
+		TrieRoot: tr.GetRoot(),
+	})
+	require.NoError(t, err)
+	genesis.Hash = genesis.CalculateHash()
+
+	p := byzcoin.Proof{
+		InclusionProof: *ip,
+		Latest:         *genesis,
+		Links: []skipchain.ForwardLink{{
+			From:      []byte{},
+			To:        genesis.Hash,
+			NewRoster: roster,
+		}},
+	}
+
+	proofFile := filepath.Join(dir, "proof.bin")
+	buf, err := protobuf.Encode(&p)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(proofFile, buf, 0600))
+	genesisFile := filepath.Join(dir, "genesis.bin")
+	buf, err = protobuf.Encode(genesis)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(genesisFile, buf, 0600))
+
+	p2, err := ReadProof(proofFile)
+	require.NoError(t, err)
+	sb, err := ReadGenesis(genesisFile)
+	require.NoError(t, err)
+	require.NoError(t, VerifyProofFromGenesis(*p2, sb))
+	require.NoError(t, VerifyProofFromRoster(*p2, genesis.Hash, roster))
+	require.True(t, p2.InclusionProof.Match([]byte("key")))
+
+	// Another roster or another chain is refused
+	other := onet.NewRoster([]*network.ServerIdentity{
+		network.NewServerIdentity(key.NewKeyPair(cothority.Suite).Public,
+			network.NewAddress(network.TLS, "127.0.0.1:7770")),
+	})
+	require.Error(t, VerifyProofFromRoster(*p2, genesis.Hash, other))
+	require.Error(t, VerifyProofFromRoster(*p2, []byte("other chain"), roster))
+	require.Equal(t, roster, p2.Links[0].NewRoster)
+
+	// A tampered trie doesn't match the block
+	require.NoError(t, tr.Set([]byte("key"), []byte("tampered")))
+	ip, err = tr.GetProof([]byte("key"))
+	require.NoError(t, err)
+	p2.InclusionProof = *ip
+	require.Error(t, VerifyProofFromGenesis(*p2, sb))
+
+	// Only genesis blocks matching their hash are read
+	genesis.Index = 1
+	buf, err = protobuf.Encode(genesis)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(genesisFile, buf, 0600))
+	_, err = ReadGenesis(genesisFile)
+	require.Error(t, err)
+	genesis.Index = 0
+	genesis.Data = []byte("tampered")
+	buf, err = protobuf.Encode(genesis)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(genesisFile, buf, 0600))
+	_, err = ReadGenesis(genesisFile)
+	require.Error(t, err)
+}
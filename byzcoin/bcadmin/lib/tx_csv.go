@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// ReadTxCSV reads a CSV file with one instruction per row into a TxFile, as
+// read by `bcadmin tx batch`. The first row is the header, which names the
+// columns:
+//
+//   - "instanceID", "action" and "contract" are required and "command" and
+//     "signers" are optional, with the same meaning as in a TxFileInstruction.
+//     The signers are separated by spaces.
+//   - every other column is an argument, named after the column. A column
+//     "name:hex", "name:uint64" or "name:file" gives the value of the
+//     argument "name" like a TxFileArg does, otherwise the value is a string.
+//     Empty cells are left out.
+func ReadTxCSV(path string) (*TxFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open csv file: %v", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read csv header: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, column := range header {
+		if seen[column] {
+			return nil, xerrors.Errorf("duplicate column '%s'", column)
+		}
+		seen[column] = true
+	}
+	for _, column := range []string{"instanceID", "action", "contract"} {
+		if !seen[column] {
+			return nil, xerrors.Errorf("missing column '%s'", column)
+		}
+	}
+
+	tf := &TxFile{dir: filepath.Dir(path)}
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("failed to read row %d: %v", row, err)
+		}
+
+		instr, err := csvInstruction(header, record)
+		if err != nil {
+			return nil, xerrors.Errorf("row %d: %v", row, err)
+		}
+		tf.Instructions = append(tf.Instructions, instr)
+	}
+	if len(tf.Instructions) == 0 {
+		return nil, xerrors.New("csv file has no instructions")
+	}
+
+	return tf, nil
+}
+
+// Slice returns the TxFile of the instructions from start to end, excluded.
+func (tf TxFile) Slice(start, end int) TxFile {
+	return TxFile{Instructions: tf.Instructions[start:end], dir: tf.dir}
+}
+
+func csvInstruction(header, record []string) (TxFileInstruction, error) {
+	var instr TxFileInstruction
+	for i, column := range header {
+		value := record[i]
+		switch column {
+		case "instanceID":
+			instr.InstanceID = value
+		case "action":
+			instr.Action = value
+		case "contract":
+			instr.Contract = value
+		case "command":
+			instr.Command = value
+		case "signers":
+			instr.Signers = strings.Fields(value)
+		default:
+			if value == "" {
+				continue
+			}
+			arg, err := csvArg(column, value)
+			if err != nil {
+				return instr, err
+			}
+			instr.Args = append(instr.Args, arg)
+		}
+	}
+
+	return instr, nil
+}
+
+func csvArg(column, value string) (TxFileArg, error) {
+	parts := strings.SplitN(column, ":", 2)
+	arg := TxFileArg{Name: parts[0]}
+	if len(parts) == 1 {
+		arg.String = &value
+		return arg, nil
+	}
+
+	switch parts[1] {
+	case "hex":
+		arg.Hex = &value
+	case "uint64":
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return arg, xerrors.Errorf("argument '%s': invalid uint64: %v",
+				arg.Name, err)
+		}
+		arg.Uint64 = &v
+	case "file":
+		arg.File = &value
+	default:
+		return arg, xerrors.Errorf("unknown type '%s' of column '%s'",
+			parts[1], column)
+	}
+	return arg, nil
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func TestReadTxCSV(t *testing.T) {
+	admin := darc.NewSignerEd25519(nil, nil)
+	other := darc.NewSignerEd25519(nil, nil)
+	adminID := admin.Identity().String()
+	otherID := other.Identity().String()
+
+	dir, err := ioutil.TempDir("", "tx_csv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "value.bin"),
+		[]byte{1, 2, 3}, 0600))
+
+	instanceID := fmt.Sprintf("%x", byzcoin.NewInstanceID([]byte("coin")).Slice())
+	csvFile := filepath.Join(dir, "tx.csv")
+	require.NoError(t, ioutil.WriteFile(csvFile, []byte(
+		"instanceID,action,contract,command,coins:uint64,data:file,value,signers\n"+
+			instanceID+",invoke,coin,mint,1000,,,\n"+
+			instanceID+",spawn,value,,,value.bin,hello,"+otherID+"\n"+
+			instanceID+",invoke,coin,mint,2000,,,"+adminID+" "+otherID+"\n"),
+		0600))
+
+	tf, err := ReadTxCSV(csvFile)
+	require.NoError(t, err)
+	require.Len(t, tf.Instructions, 3)
+	require.Equal(t, []string{adminID, otherID}, tf.SignerIDs(adminID))
+
+	counters := map[string]uint64{adminID: 5, otherID: 7}
+	instrs, err := tf.BuildInstructions(adminID, counters)
+	require.NoError(t, err)
+
+	coins := make([]byte, 8)
+	binary.LittleEndian.PutUint64(coins, 1000)
+	require.Equal(t, "mint", instrs[0].Invoke.Command)
+	require.Len(t, instrs[0].Invoke.Args, 1)
+	require.Equal(t, coins, instrs[0].Invoke.Args.Search("coins"))
+	require.Equal(t, []uint64{6}, instrs[0].SignerCounter)
+
+	require.Equal(t, "value", instrs[1].Spawn.ContractID)
+	require.Equal(t, []byte{1, 2, 3}, instrs[1].Spawn.Args.Search("data"))
+	require.Equal(t, []byte("hello"), instrs[1].Spawn.Args.Search("value"))
+	require.Equal(t, []uint64{8}, instrs[1].SignerCounter)
+
+	// A slice keeps the directory of the files
+	instrs, err = tf.Slice(1, 3).BuildInstructions(adminID, counters)
+	require.NoError(t, err)
+	require.Len(t, instrs, 2)
+	require.Equal(t, []byte{1, 2, 3}, instrs[0].Spawn.Args.Search("data"))
+	require.Equal(t, []uint64{6, 9}, instrs[1].SignerCounter)
+
+	for _, content := range []string{
+		// Missing column
+		"instanceID,action\n" + instanceID + ",spawn\n",
+		// Duplicate column
+		"instanceID,action,contract,value,value\n" +
+			instanceID + ",spawn,value,a,b\n",
+		// Unknown type
+		"instanceID,action,contract,value:int\n" +
+			instanceID + ",spawn,value,1\n",
+		// Invalid uint64
+		"instanceID,action,contract,coins:uint64\n" +
+			instanceID + ",invoke,coin,abc\n",
+		// Wrong number of fields
+		"instanceID,action,contract\n" + instanceID + ",spawn\n",
+		// No instructions
+		"instanceID,action,contract\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(csvFile, []byte(content), 0600))
+		_, err = ReadTxCSV(csvFile)
+		require.Error(t, err, content)
+	}
+}
@@ -0,0 +1,249 @@
+package lib
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"golang.org/x/xerrors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TxFile is the declarative description of a ClientTransaction, as read by
+// `bcadmin tx apply`. Its instructions are applied in order.
+type TxFile struct {
+	Instructions []TxFileInstruction `yaml:"instructions"`
+
+	// Directory the files of the arguments are relative to
+	dir string
+}
+
+// TxFileInstruction describes one instruction of a TxFile.
+type TxFileInstruction struct {
+	// InstanceID is the hex-encoded ID of the instance the instruction is
+	// sent to. A spawn is sent to the instance of a darc, whose ID is the
+	// darc base ID.
+	InstanceID string `yaml:"instanceID"`
+	// Action is "spawn", "invoke" or "delete".
+	Action   string `yaml:"action"`
+	Contract string `yaml:"contract"`
+	// Command is the command of an invoke.
+	Command string      `yaml:"command"`
+	Args    []TxFileArg `yaml:"args"`
+	// Signers are the identities signing the instruction. If empty, the
+	// default signer signs it.
+	Signers []string `yaml:"signers"`
+}
+
+// TxFileArg is an argument of a TxFileInstruction, whose value is given by
+// exactly one of String, Hex, Uint64 and File.
+type TxFileArg struct {
+	Name   string  `yaml:"name"`
+	String *string `yaml:"string"`
+	Hex    *string `yaml:"hex"`
+	// Uint64 is encoded in little-endian, as expected by the coin contract.
+	Uint64 *uint64 `yaml:"uint64"`
+	// File is the path of a file holding the value, relative to the TxFile.
+	File *string `yaml:"file"`
+}
+
+// ReadTxFile reads and parses a TxFile in YAML.
+func ReadTxFile(path string) (*TxFile, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read tx file: %v", err)
+	}
+
+	tf := &TxFile{}
+	err = yaml.UnmarshalStrict(buf, tf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to parse tx file: %v", err)
+	}
+	if len(tf.Instructions) == 0 {
+		return nil, xerrors.New("tx file has no instructions")
+	}
+	tf.dir = filepath.Dir(path)
+
+	return tf, nil
+}
+
+// SignerIDs returns the identities signing the instructions of the file,
+// without duplicates.
+func (tf TxFile) SignerIDs(defaultSigner string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, instr := range tf.Instructions {
+		for _, id := range instr.signers(defaultSigner) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
+
+// BuildInstructions builds the instructions of the file. The counters are the
+// last counters of the signers, which get the next ones in the order of the
+// instructions.
+func (tf TxFile) BuildInstructions(defaultSigner string,
+	counters map[string]uint64) (byzcoin.Instructions, error) {
+	counters = copyCounters(counters)
+
+	instrs := make(byzcoin.Instructions, len(tf.Instructions))
+	for i, desc := range tf.Instructions {
+		instr, err := desc.instruction(tf.dir)
+		if err != nil {
+			return nil, xerrors.Errorf("instruction %d: %v", i, err)
+		}
+
+		for _, id := range desc.signers(defaultSigner) {
+			identity, err := darc.ParseIdentity(id)
+			if err != nil {
+				return nil, xerrors.Errorf("instruction %d: invalid "+
+					"signer '%s': %v", i, id, err)
+			}
+			counter, ok := counters[id]
+			if !ok {
+				return nil, xerrors.Errorf("instruction %d: no counter for "+
+					"signer '%s'", i, id)
+			}
+			counter++
+			counters[id] = counter
+
+			instr.SignerIdentities = append(instr.SignerIdentities, identity)
+			instr.SignerCounter = append(instr.SignerCounter, counter)
+		}
+
+		instrs[i] = instr
+	}
+
+	return instrs, nil
+}
+
+// SignTxFile signs every instruction of a transaction built from a TxFile
+// with the signers it lists.
+func SignTxFile(tx *byzcoin.ClientTransaction,
+	signers map[string]darc.Signer) error {
+	digest := tx.Instructions.Hash()
+	for i := range tx.Instructions {
+		var instrSigners []darc.Signer
+		for _, id := range tx.Instructions[i].SignerIdentities {
+			signer, ok := signers[id.String()]
+			if !ok {
+				return xerrors.Errorf("instruction %d: missing signer '%s'",
+					i, id)
+			}
+			instrSigners = append(instrSigners, signer)
+		}
+
+		err := tx.Instructions[i].SignWith(digest, instrSigners...)
+		if err != nil {
+			return xerrors.Errorf("instruction %d: failed to sign: %v", i,
+				err)
+		}
+	}
+
+	return nil
+}
+
+func (desc TxFileInstruction) signers(defaultSigner string) []string {
+	if len(desc.Signers) == 0 {
+		return []string{defaultSigner}
+	}
+
+	return desc.Signers
+}
+
+func (desc TxFileInstruction) instruction(dir string) (byzcoin.Instruction,
+	error) {
+	var instr byzcoin.Instruction
+
+	id, err := hex.DecodeString(desc.InstanceID)
+	if err != nil || len(id) != 32 {
+		return instr, xerrors.Errorf("invalid instance ID '%s'",
+			desc.InstanceID)
+	}
+	instr.InstanceID = byzcoin.NewInstanceID(id)
+
+	if desc.Contract == "" {
+		return instr, xerrors.New("missing contract")
+	}
+
+	var args byzcoin.Arguments
+	for _, arg := range desc.Args {
+		value, err := arg.value(dir)
+		if err != nil {
+			return instr, xerrors.Errorf("argument '%s': %v", arg.Name, err)
+		}
+		args = append(args, byzcoin.Argument{Name: arg.Name, Value: value})
+	}
+
+	switch desc.Action {
+	case "spawn":
+		instr.Spawn = &byzcoin.Spawn{ContractID: desc.Contract, Args: args}
+	case "invoke":
+		if desc.Command == "" {
+			return instr, xerrors.New("missing command for invoke")
+		}
+		instr.Invoke = &byzcoin.Invoke{ContractID: desc.Contract,
+			Command: desc.Command, Args: args}
+	case "delete":
+		instr.Delete = &byzcoin.Delete{ContractID: desc.Contract}
+	default:
+		return instr, xerrors.Errorf("unknown action '%s', expected "+
+			"spawn, invoke or delete", desc.Action)
+	}
+
+	return instr, nil
+}
+
+func (arg TxFileArg) value(dir string) ([]byte, error) {
+	var values [][]byte
+	if arg.String != nil {
+		values = append(values, []byte(*arg.String))
+	}
+	if arg.Hex != nil {
+		buf, err := hex.DecodeString(*arg.Hex)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid hex value: %v", err)
+		}
+		values = append(values, buf)
+	}
+	if arg.Uint64 != nil {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, *arg.Uint64)
+		values = append(values, buf)
+	}
+	if arg.File != nil {
+		path := *arg.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to read file: %v", err)
+		}
+		values = append(values, buf)
+	}
+
+	if len(values) != 1 {
+		return nil, xerrors.New("exactly one of string, hex, uint64 and " +
+			"file must be given")
+	}
+
+	return values[0], nil
+}
+
+func copyCounters(counters map[string]uint64) map[string]uint64 {
+	c := make(map[string]uint64, len(counters))
+	for id, counter := range counters {
+		c[id] = counter
+	}
+
+	return c
+}
@@ -0,0 +1,135 @@
+package lib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func TestTxFile(t *testing.T) {
+	admin := darc.NewSignerEd25519(nil, nil)
+	other := darc.NewSignerEd25519(nil, nil)
+	adminID := admin.Identity().String()
+	otherID := other.Identity().String()
+
+	dir, err := ioutil.TempDir("", "tx_file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "value.bin"),
+		[]byte{1, 2, 3}, 0600))
+
+	instanceID := byzcoin.NewInstanceID([]byte("instance"))
+	yamlFile := filepath.Join(dir, "tx.yaml")
+	require.NoError(t, ioutil.WriteFile(yamlFile, []byte(fmt.Sprintf(`
+instructions:
+  - instanceID: %[1]x
+    action: spawn
+    contract: value
+    args:
+      - name: value
+        string: hello
+  - instanceID: %[1]x
+    action: invoke
+    contract: coin
+    command: mint
+    args:
+      - name: coins
+        uint64: 1000
+      - name: data
+        file: value.bin
+      - name: more
+        hex: abcd
+    signers: [ "%[2]s", "%[3]s" ]
+  - instanceID: %[1]x
+    action: delete
+    contract: value
+    signers: [ "%[3]s" ]
+`, instanceID.Slice(), adminID, otherID)), 0600))
+
+	tf, err := ReadTxFile(yamlFile)
+	require.NoError(t, err)
+	require.Equal(t, []string{adminID, otherID}, tf.SignerIDs(adminID))
+
+	counters := map[string]uint64{adminID: 5, otherID: 7}
+	instrs, err := tf.BuildInstructions(adminID, counters)
+	require.NoError(t, err)
+	require.Len(t, instrs, 3)
+	// The counters given are not modified
+	require.Equal(t, uint64(5), counters[adminID])
+
+	require.Equal(t, instanceID, instrs[0].InstanceID)
+	require.Equal(t, "value", instrs[0].Spawn.ContractID)
+	require.Equal(t, []byte("hello"), instrs[0].Spawn.Args.Search("value"))
+	require.Equal(t, []uint64{6}, instrs[0].SignerCounter)
+
+	require.Equal(t, "mint", instrs[1].Invoke.Command)
+	coins := make([]byte, 8)
+	binary.LittleEndian.PutUint64(coins, 1000)
+	require.Equal(t, coins, instrs[1].Invoke.Args.Search("coins"))
+	require.Equal(t, []byte{1, 2, 3}, instrs[1].Invoke.Args.Search("data"))
+	require.Equal(t, []byte{0xab, 0xcd}, instrs[1].Invoke.Args.Search("more"))
+	require.Equal(t, []uint64{7, 8}, instrs[1].SignerCounter)
+
+	require.Equal(t, "value", instrs[2].Delete.ContractID)
+	require.Equal(t, []darc.Identity{other.Identity()},
+		instrs[2].SignerIdentities)
+	require.Equal(t, []uint64{9}, instrs[2].SignerCounter)
+
+	// Every instruction is signed by its own signers
+	tx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion, instrs...)
+	require.Error(t, SignTxFile(&tx, map[string]darc.Signer{adminID: admin}))
+	require.NoError(t, SignTxFile(&tx, map[string]darc.Signer{
+		adminID: admin, otherID: other}))
+	require.Len(t, tx.Instructions[0].Signatures, 1)
+	require.Len(t, tx.Instructions[1].Signatures, 2)
+	require.Len(t, tx.Instructions[2].Signatures, 1)
+
+	// A signer without counter is rejected
+	_, err = tf.BuildInstructions(adminID, map[string]uint64{adminID: 5})
+	require.Error(t, err)
+}
+
+func TestTxFile_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tx_file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	id := signer.Identity().String()
+	counters := map[string]uint64{id: 0}
+	instanceID := fmt.Sprintf("%x", byzcoin.NewInstanceID(nil).Slice())
+
+	for _, instr := range []string{
+		// Unknown action
+		"{instanceID: " + instanceID + ", action: update, contract: value}",
+		// Invoke without command
+		"{instanceID: " + instanceID + ", action: invoke, contract: value}",
+		// Invalid instance ID
+		"{instanceID: abcd, action: spawn, contract: value}",
+		// Argument with two values
+		"{instanceID: " + instanceID + ", action: spawn, contract: value, " +
+			"args: [{name: value, string: a, hex: ab}]}",
+	} {
+		yamlFile := filepath.Join(dir, "tx.yaml")
+		require.NoError(t, ioutil.WriteFile(yamlFile,
+			[]byte("instructions: ["+instr+"]"), 0600))
+		tf, err := ReadTxFile(yamlFile)
+		require.NoError(t, err)
+		_, err = tf.BuildInstructions(id, counters)
+		require.Error(t, err, instr)
+	}
+
+	// Unknown fields are rejected
+	yamlFile := filepath.Join(dir, "tx.yaml")
+	require.NoError(t, ioutil.WriteFile(yamlFile,
+		[]byte("instructions: [{instance: abcd}]"), 0600))
+	_, err = ReadTxFile(yamlFile)
+	require.Error(t, err)
+}
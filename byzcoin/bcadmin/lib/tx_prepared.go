@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// PreparedTx is a transaction written unsigned by `bcadmin tx prepare`,
+// signed offline by `bcadmin tx sign` and sent by `bcadmin tx send`.
+type PreparedTx struct {
+	// ByzCoinID is the ID of the chain the transaction is prepared for.
+	ByzCoinID skipchain.SkipBlockID
+	// Version is the version of the instructions, which their hash depends
+	// on but which is not encoded with them.
+	Version     byzcoin.Version
+	Transaction byzcoin.ClientTransaction
+}
+
+// NewPreparedTx creates the unsigned transaction of the given instructions,
+// using the version of the latest block of the chain.
+func NewPreparedTx(cl *byzcoin.Client,
+	instrs byzcoin.Instructions) (*PreparedTx, error) {
+	tx, err := cl.CreateTransaction(instrs...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create transaction: %v", err)
+	}
+
+	var header byzcoin.DataHeader
+	err = protobuf.Decode(cl.Latest.Data, &header)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode block header: %v", err)
+	}
+
+	return &PreparedTx{
+		ByzCoinID:   cl.ID,
+		Version:     header.Version,
+		Transaction: tx,
+	}, nil
+}
+
+// ReadPreparedTx reads a PreparedTx from a file.
+func ReadPreparedTx(path string) (*PreparedTx, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read transaction file: %v", err)
+	}
+
+	ptx := &PreparedTx{}
+	err = protobuf.Decode(buf, ptx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode transaction file: %v",
+			err)
+	}
+	ptx.Transaction.Instructions.SetVersion(ptx.Version)
+
+	return ptx, nil
+}
+
+// Write writes the PreparedTx to a file.
+func (ptx PreparedTx) Write(path string) error {
+	buf, err := protobuf.Encode(&ptx)
+	if err != nil {
+		return xerrors.Errorf("failed to encode transaction: %v", err)
+	}
+
+	err = ioutil.WriteFile(path, buf, 0600)
+	if err != nil {
+		return xerrors.Errorf("failed to write transaction file: %v", err)
+	}
+
+	return nil
+}
+
+// Sign adds the signatures of the signer to all the instructions it is a
+// signer of, and returns the number of these instructions.
+func (ptx *PreparedTx) Sign(signer darc.Signer) (int, error) {
+	digest := ptx.Transaction.Instructions.Hash()
+	identity := signer.Identity()
+
+	signed := 0
+	for i := range ptx.Transaction.Instructions {
+		instr := &ptx.Transaction.Instructions[i]
+		if len(instr.Signatures) != len(instr.SignerIdentities) {
+			instr.Signatures = make([][]byte, len(instr.SignerIdentities))
+		}
+
+		found := false
+		for j, id := range instr.SignerIdentities {
+			if !id.Equal(&identity) {
+				continue
+			}
+			sig, err := signer.Sign(digest)
+			if err != nil {
+				return 0, xerrors.Errorf("failed to sign instruction %d: %v",
+					i, err)
+			}
+			instr.Signatures[j] = sig
+			found = true
+		}
+		if found {
+			signed++
+		}
+	}
+
+	if signed == 0 {
+		return 0, xerrors.Errorf("%s is not a signer of the transaction",
+			identity)
+	}
+
+	return signed, nil
+}
+
+// MissingSignatures lists the signers that have not signed their
+// instructions yet.
+func (ptx PreparedTx) MissingSignatures() []string {
+	var missing []string
+	for i, instr := range ptx.Transaction.Instructions {
+		for j, id := range instr.SignerIdentities {
+			if j >= len(instr.Signatures) || len(instr.Signatures[j]) == 0 {
+				missing = append(missing,
+					fmt.Sprintf("instruction %d: %s", i, id))
+			}
+		}
+	}
+
+	return missing
+}
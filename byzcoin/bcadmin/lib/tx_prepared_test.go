@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func TestPreparedTx(t *testing.T) {
+	a := darc.NewSignerEd25519(nil, nil)
+	b := darc.NewSignerEd25519(nil, nil)
+	c := darc.NewSignerEd25519(nil, nil)
+
+	instrs := byzcoin.Instructions{
+		{
+			InstanceID:       byzcoin.NewInstanceID([]byte("first")),
+			Spawn:            &byzcoin.Spawn{ContractID: "value"},
+			SignerIdentities: []darc.Identity{a.Identity()},
+			SignerCounter:    []uint64{1},
+		},
+		{
+			InstanceID:       byzcoin.NewInstanceID([]byte("second")),
+			Delete:           &byzcoin.Delete{ContractID: "value"},
+			SignerIdentities: []darc.Identity{a.Identity(), b.Identity()},
+			SignerCounter:    []uint64{2, 1},
+		},
+	}
+	ptx := PreparedTx{
+		ByzCoinID: []byte("byzcoin"),
+		Version:   byzcoin.CurrentVersion,
+		Transaction: byzcoin.NewClientTransaction(byzcoin.CurrentVersion,
+			instrs...),
+	}
+	require.Len(t, ptx.MissingSignatures(), 3)
+
+	dir, err := ioutil.TempDir("", "tx_prepared")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "tx.bin")
+
+	// Sign in two steps, reading the file back each time
+	require.NoError(t, ptx.Write(file))
+	read, err := ReadPreparedTx(file)
+	require.NoError(t, err)
+	signed, err := read.Sign(a)
+	require.NoError(t, err)
+	require.Equal(t, 2, signed)
+	require.Equal(t, []string{"instruction 1: " + b.Identity().String()},
+		read.MissingSignatures())
+
+	_, err = read.Sign(c)
+	require.Error(t, err)
+
+	require.NoError(t, read.Write(file))
+	read, err = ReadPreparedTx(file)
+	require.NoError(t, err)
+	require.Len(t, read.MissingSignatures(), 1)
+	signed, err = read.Sign(b)
+	require.NoError(t, err)
+	require.Equal(t, 1, signed)
+	require.Empty(t, read.MissingSignatures())
+
+	// The signatures are the ones of the whole transaction
+	require.Equal(t, []byte("byzcoin"), []byte(read.ByzCoinID))
+	digest := ptx.Transaction.Instructions.Hash()
+	require.Equal(t, digest, read.Transaction.Instructions.Hash())
+	for _, instr := range read.Transaction.Instructions {
+		for i, id := range instr.SignerIdentities {
+			require.NoError(t, id.Verify(digest, instr.Signatures[i]))
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -11,9 +12,11 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"golang.org/x/xerrors"
@@ -876,6 +879,86 @@ func key(c *cli.Context) error {
 	return err
 }
 
+// keyList prints the identities of the keys in the keystore, telling which
+// ones are still stored in clear.
+func keyList(c *cli.Context) error {
+	keys, err := lib.ListKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		log.Infof("No keys in %s", lib.ConfigPath)
+		return nil
+	}
+	for _, k := range keys {
+		state := "plain"
+		if k.Encrypted {
+			state = "encrypted"
+		}
+		log.Infof("%s (%s)", k.Identity, state)
+	}
+	return nil
+}
+
+// keyExport writes a key of the keystore to a file, encrypted with the
+// passphrase if one is configured.
+func keyExport(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return xerrors.New("please give the identity of the key to export")
+	}
+	out := c.String("out")
+	if out == "" {
+		return xerrors.New("--out flag is required")
+	}
+
+	signer, err := lib.LoadKeyFromString(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("couldn't load signer: %v", err)
+	}
+	buf, err := lib.EncodeSigner(*signer)
+	if err != nil {
+		return err
+	}
+	if !lib.IsEncryptedKey(buf) {
+		log.Warn("No passphrase given, the key is exported in clear")
+	}
+
+	err = ioutil.WriteFile(out, buf, 0600)
+	if err != nil {
+		return xerrors.Errorf("failed to write key: %v", err)
+	}
+	log.Infof("Exported %s to %s", signer.Identity(), out)
+	return nil
+}
+
+// keyImport adds a key file, encrypted or not, to the keystore.
+func keyImport(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return xerrors.New("please give the key file to import")
+	}
+
+	signer, err := lib.LoadSigner(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("couldn't load signer: %v", err)
+	}
+	err = lib.SaveKey(*signer)
+	if err != nil {
+		return err
+	}
+	log.Infof("Imported %s", signer.Identity())
+	return nil
+}
+
+// keyEncrypt encrypts the plain keys of the keystore with the passphrase.
+func keyEncrypt(c *cli.Context) error {
+	n, err := lib.EncryptKeys()
+	if err != nil {
+		return err
+	}
+	log.Infof("Encrypted %d keys", n)
+	return nil
+}
+
 func darcShow(c *cli.Context) error {
 	bcArg := c.String("bc")
 	if bcArg == "" {
@@ -901,6 +984,550 @@ func darcShow(c *cli.Context) error {
 	return err
 }
 
+// txApply reads a YAML description of instructions, resolves the counters
+// of their signers, signs them and sends them as one transaction.
+func txApply(c *cli.Context) error {
+	cfg, cl, tf, err := loadTxFile(c)
+	if err != nil {
+		return err
+	}
+
+	signers, err := loadTxSigners(tf.SignerIDs(cfg.AdminIdentity.String()))
+	if err != nil {
+		return err
+	}
+
+	instrs, err := txFileInstructions(cfg, cl, tf)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := cl.CreateTransaction(instrs...)
+	if err != nil {
+		return err
+	}
+
+	err = lib.SignTxFile(&ctx, signers)
+	if err != nil {
+		return err
+	}
+
+	return sendTx(c, cl, ctx)
+}
+
+// txBatch reads instructions from a CSV file, sends them in transactions of
+// at most --chunk instructions, retrying the failed transactions, and writes
+// the result of every row in a CSV report.
+func txBatch(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the CSV file of the instructions")
+	}
+
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	chunk := c.Int("chunk")
+	if chunk <= 0 {
+		return xerrors.New("--chunk must be positive")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	tf, err := lib.ReadTxCSV(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	defaultSigner := cfg.AdminIdentity.String()
+	ids := tf.SignerIDs(defaultSigner)
+	signers, err := loadTxSigners(ids)
+	if err != nil {
+		return err
+	}
+
+	// Leave room in the blocks for the transactions of the other clients
+	chainConfig, err := cl.GetChainConfig()
+	if err != nil {
+		return xerrors.Errorf("couldn't get chain config: %v", err)
+	}
+	maxSize := chainConfig.MaxBlockSize / 2
+
+	report := c.App.Writer
+	if out := c.String("report"); out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return xerrors.Errorf("failed to create report: %v", err)
+		}
+		defer f.Close()
+		report = f
+	}
+	w := csv.NewWriter(report)
+	err = w.Write([]string{"row", "status", "instanceID", "error"})
+	if err != nil {
+		return xerrors.Errorf("failed to write report: %v", err)
+	}
+
+	counters, err := getTxCounters(cl, ids)
+	if err != nil {
+		return err
+	}
+
+	build := func(start, end int) (byzcoin.ClientTransaction, error) {
+		instrs, err := tf.Slice(start, end).BuildInstructions(defaultSigner,
+			counters)
+		if err != nil {
+			return byzcoin.ClientTransaction{}, xerrors.Errorf(
+				"row %d: %v", start+1, err)
+		}
+		ctx, err := cl.CreateTransaction(instrs...)
+		if err != nil {
+			return byzcoin.ClientTransaction{}, err
+		}
+		err = lib.SignTxFile(&ctx, signers)
+		return ctx, err
+	}
+
+	failed, txs := 0, 0
+	for start := 0; start < len(tf.Instructions); {
+		end := start + chunk
+		if end > len(tf.Instructions) {
+			end = len(tf.Instructions)
+		}
+
+		ctx, err := build(start, end)
+		if err != nil {
+			return err
+		}
+		// Split the transactions that would not fit in a block
+		for end-start > 1 {
+			buf, err := protobuf.Encode(&ctx)
+			if err != nil {
+				return xerrors.Errorf("failed to encode transaction: %v", err)
+			}
+			if len(buf) <= maxSize {
+				break
+			}
+			end = start + (end-start)/2
+			ctx, err = build(start, end)
+			if err != nil {
+				return err
+			}
+		}
+
+		var sendErr error
+		for attempt := 0; attempt <= c.Int("retries"); attempt++ {
+			if attempt > 0 {
+				log.Warnf("Retrying rows %d to %d: %v", start+1, end, sendErr)
+				// The transaction might have been accepted despite the error,
+				// which the counters of the signers tell.
+				current, err := getTxCounters(cl, ids)
+				if err != nil {
+					sendErr = err
+					continue
+				}
+				if reflect.DeepEqual(current, nextTxCounters(counters, ctx)) {
+					sendErr = nil
+					break
+				}
+				counters = current
+				ctx, err = build(start, end)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, sendErr = cl.AddTransactionAndWait(ctx, 10)
+			if sendErr == nil {
+				break
+			}
+		}
+
+		if sendErr == nil {
+			counters = nextTxCounters(counters, ctx)
+			txs++
+		} else {
+			failed += end - start
+			counters, err = getTxCounters(cl, ids)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, instr := range ctx.Instructions {
+			id := instr.InstanceID
+			if instr.Spawn != nil {
+				id = instr.DeriveID("")
+			}
+			record := []string{strconv.Itoa(start + i + 1), "ok",
+				hex.EncodeToString(id.Slice()), ""}
+			if sendErr != nil {
+				record[1], record[3] = "failed", sendErr.Error()
+			}
+			err = w.Write(record)
+			if err != nil {
+				return xerrors.Errorf("failed to write report: %v", err)
+			}
+		}
+		w.Flush()
+
+		start = end
+	}
+	if err := w.Error(); err != nil {
+		return xerrors.Errorf("failed to write report: %v", err)
+	}
+
+	log.Infof("Applied %d of %d instructions in %d transactions",
+		len(tf.Instructions)-failed, len(tf.Instructions), txs)
+	if failed > 0 {
+		return xerrors.Errorf("%d instructions failed, see the report", failed)
+	}
+
+	return lib.WaitPropagation(c, cl)
+}
+
+// txPrepare reads a YAML description of instructions, resolves the counters
+// of their signers, and writes the unsigned transaction to a file.
+func txPrepare(c *cli.Context) error {
+	out := c.String("out")
+	if out == "" {
+		return xerrors.New("--out flag is required")
+	}
+
+	cfg, cl, tf, err := loadTxFile(c)
+	if err != nil {
+		return err
+	}
+
+	instrs, err := txFileInstructions(cfg, cl, tf)
+	if err != nil {
+		return err
+	}
+
+	ptx, err := lib.NewPreparedTx(cl, instrs)
+	if err != nil {
+		return err
+	}
+
+	err = ptx.Write(out)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Prepared %d instructions to be signed by:",
+		len(ptx.Transaction.Instructions))
+	for _, missing := range ptx.MissingSignatures() {
+		log.Info(missing)
+	}
+
+	return nil
+}
+
+// txSign adds the signatures of a key to a prepared transaction. It does not
+// need to be online.
+func txSign(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the file of the transaction")
+	}
+	file := c.Args().First()
+
+	sstr := c.String("sign")
+	if sstr == "" {
+		return xerrors.New("--sign flag is required")
+	}
+	signer, err := lib.LoadKeyFromString(sstr)
+	if err != nil {
+		return err
+	}
+
+	ptx, err := lib.ReadPreparedTx(file)
+	if err != nil {
+		return err
+	}
+
+	// Show what is signed, for a review on the offline host
+	log.Infof("Transaction for ByzCoin %x", ptx.ByzCoinID)
+	for _, instr := range ptx.Transaction.Instructions {
+		log.Info(instr.String())
+	}
+
+	signed, err := ptx.Sign(*signer)
+	if err != nil {
+		return err
+	}
+
+	out := c.String("out")
+	if out == "" {
+		out = file
+	}
+	err = ptx.Write(out)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Signed %d instructions", signed)
+	missing := ptx.MissingSignatures()
+	if len(missing) > 0 {
+		log.Info("Missing signatures:")
+		for _, m := range missing {
+			log.Info(m)
+		}
+	}
+
+	return nil
+}
+
+// txSend sends a prepared transaction, once all its signatures are there.
+func txSend(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the file of the transaction")
+	}
+
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	ptx, err := lib.ReadPreparedTx(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if !ptx.ByzCoinID.Equal(cl.ID) {
+		return xerrors.Errorf("transaction is prepared for ByzCoin %x, "+
+			"not %x", ptx.ByzCoinID, cl.ID)
+	}
+
+	missing := ptx.MissingSignatures()
+	if len(missing) > 0 {
+		return xerrors.Errorf("missing signatures: %s",
+			strings.Join(missing, ", "))
+	}
+
+	return sendTx(c, cl, ptx.Transaction)
+}
+
+// loadTxFile loads the ByzCoin config and the YAML file of the tx commands.
+func loadTxFile(c *cli.Context) (lib.Config, *byzcoin.Client, *lib.TxFile,
+	error) {
+	if c.NArg() < 1 {
+		return lib.Config{}, nil, nil,
+			xerrors.New("please give the YAML file of the transaction")
+	}
+
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return lib.Config{}, nil, nil, xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return lib.Config{}, nil, nil, err
+	}
+
+	tf, err := lib.ReadTxFile(c.Args().First())
+	if err != nil {
+		return lib.Config{}, nil, nil, err
+	}
+
+	return cfg, cl, tf, nil
+}
+
+// loadTxSigners loads the keys of the signers of the tx commands.
+func loadTxSigners(ids []string) (map[string]darc.Signer, error) {
+	signers := make(map[string]darc.Signer)
+	for _, id := range ids {
+		signer, err := lib.LoadKeyFromString(id)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to load key of '%s': %v", id,
+				err)
+		}
+		signers[signer.Identity().String()] = *signer
+	}
+
+	return signers, nil
+}
+
+// getTxCounters returns the last counters of the signers.
+func getTxCounters(cl *byzcoin.Client, ids []string) (map[string]uint64,
+	error) {
+	cReply, err := cl.GetSignerCounters(ids...)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get signer counters: %v", err)
+	}
+	counters := make(map[string]uint64)
+	for i, id := range ids {
+		counters[id] = cReply.Counters[i]
+	}
+
+	return counters, nil
+}
+
+// nextTxCounters returns the counters of the signers once the transaction
+// is accepted.
+func nextTxCounters(counters map[string]uint64,
+	ctx byzcoin.ClientTransaction) map[string]uint64 {
+	next := make(map[string]uint64)
+	for id, counter := range counters {
+		next[id] = counter
+	}
+	for _, instr := range ctx.Instructions {
+		for i, id := range instr.SignerIdentities {
+			next[id.String()] = instr.SignerCounter[i]
+		}
+	}
+
+	return next
+}
+
+// txFileInstructions builds the instructions of a YAML file with the next
+// counters of their signers.
+func txFileInstructions(cfg lib.Config, cl *byzcoin.Client,
+	tf *lib.TxFile) (byzcoin.Instructions, error) {
+	counters, err := getTxCounters(cl,
+		tf.SignerIDs(cfg.AdminIdentity.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	return tf.BuildInstructions(cfg.AdminIdentity.String(), counters)
+}
+
+// sendTx sends a signed transaction of the tx commands.
+func sendTx(c *cli.Context, cl *byzcoin.Client,
+	ctx byzcoin.ClientTransaction) error {
+	_, err := cl.AddTransactionAndWait(ctx, 10)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Applied %d instructions", len(ctx.Instructions))
+	for i, instr := range ctx.Instructions {
+		if instr.Spawn != nil {
+			log.Infof("Instruction %d spawned %x", i,
+				instr.DeriveID("").Slice())
+		}
+	}
+
+	return lib.WaitPropagation(c, cl)
+}
+
+// darcDiff fetches two versions of a darc and prints the rules that have
+// been added, removed or changed between them.
+func darcDiff(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	dstr := c.String("darc")
+	if dstr == "" {
+		dstr = cfg.AdminDarc.GetIdentityString()
+	}
+
+	latest, err := lib.GetDarcByString(cl, dstr)
+	if err != nil {
+		return err
+	}
+
+	to := latest.Version
+	if c.IsSet("to") {
+		to = c.Uint64("to")
+		if to > latest.Version {
+			return xerrors.Errorf("--to %d is bigger than the latest version %d",
+				to, latest.Version)
+		}
+	}
+
+	var from uint64
+	if c.IsSet("from") {
+		from = c.Uint64("from")
+	} else {
+		if to == 0 {
+			return xerrors.New("version 0 has no previous version, " +
+				"please set --from")
+		}
+		from = to - 1
+	}
+
+	oldD, err := lib.GetDarcVersion(cl, latest.GetBaseID(), from)
+	if err != nil {
+		return err
+	}
+	newD := latest
+	if to != latest.Version {
+		newD, err = lib.GetDarcVersion(cl, latest.GetBaseID(), to)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff := lib.DiffDarcs(oldD, newD)
+	if diff.Empty() {
+		log.Infof("No changes between version %d and %d", from, to)
+		return nil
+	}
+	log.Info(diff.String())
+	return nil
+}
+
+// darcGraph crawls a darc and all the darcs it delegates to, and prints
+// their delegation graph in the DOT language of Graphviz.
+func darcGraph(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	dstr := c.String("darc")
+	if dstr == "" {
+		dstr = cfg.AdminDarc.GetIdentityString()
+	}
+	root, err := lib.StringToDarcID(dstr)
+	if err != nil {
+		return err
+	}
+
+	graph, err := lib.CrawlDarcs(root, func(id darc.ID) (*darc.Darc, error) {
+		return lib.GetDarcByID(cl, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	out := c.String("out")
+	if out == "" {
+		_, err = fmt.Fprint(c.App.Writer, graph.DOT())
+		return err
+	}
+
+	err = ioutil.WriteFile(out, []byte(graph.DOT()), 0644)
+	if err != nil {
+		return xerrors.Errorf("failed to write graph: %v", err)
+	}
+	log.Infof("Wrote the graph of %d darcs to %s", len(graph.Darcs), out)
+	return nil
+}
+
 // "cDesc" stands for Change Description. This function allows one to edit the
 // description of a darc.
 func darcCdesc(c *cli.Context) error {
@@ -1746,6 +2373,245 @@ func resolveiid(c *cli.Context) error {
 	return nil
 }
 
+// contractsList prints the contracts registered by a node of the roster.
+func contractsList(c *cli.Context) error {
+	reply, err := getContracts(c)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Node runs ByzCoin version %d\n", reply.Version)
+	fmt.Fprintln(w, "CONTRACT\tVERSION\tDESCRIPTION")
+	for _, desc := range reply.Contracts {
+		version := desc.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", desc.ID, version, desc.Description)
+	}
+	return w.Flush()
+}
+
+// contractsDescribe prints the commands of a contract, with their arguments,
+// as described by the contract.
+func contractsDescribe(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the contract ID")
+	}
+	reply, err := getContracts(c)
+	if err != nil {
+		return err
+	}
+
+	var desc *byzcoin.ContractDescription
+	for i := range reply.Contracts {
+		if reply.Contracts[i].ID == c.Args().First() {
+			desc = &reply.Contracts[i]
+		}
+	}
+	if desc == nil {
+		return xerrors.Errorf("contract %s is not registered", c.Args().First())
+	}
+
+	out := new(strings.Builder)
+	fmt.Fprintf(out, "- Contract: %s\n", desc.ID)
+	if desc.Version != "" {
+		fmt.Fprintf(out, "-- Version: %s\n", desc.Version)
+	}
+	if desc.Description != "" {
+		fmt.Fprintf(out, "-- Description: %s\n", desc.Description)
+	}
+	if len(desc.Commands) == 0 {
+		out.WriteString("-- The contract doesn't describe its commands\n")
+	}
+	for _, cmd := range desc.Commands {
+		action := cmd.Action
+		if cmd.Name != "" {
+			action += ":" + cmd.Name
+		}
+		fmt.Fprintf(out, "-- %s: %s\n", action, cmd.Description)
+		for _, arg := range cmd.Args {
+			typ := arg.Type
+			if arg.Optional {
+				typ += ", optional"
+			}
+			fmt.Fprintf(out, "--- %s (%s): %s\n", arg.Name, typ,
+				arg.Description)
+		}
+	}
+	_, err = fmt.Fprint(c.App.Writer, out.String())
+	return err
+}
+
+// getContracts asks a node of the roster for its contracts, the one given
+// by --server if any.
+func getContracts(c *cli.Context) (*byzcoin.GetContractsReply, error) {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return nil, xerrors.New("--bc flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return nil, err
+	}
+	if sn := c.Int("server"); sn >= 0 {
+		err = cl.UseNode(sn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := cl.GetContracts()
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get the contracts: %v", err)
+	}
+	return reply, nil
+}
+
+// proofGet saves the proof of an instance, starting from the genesis block,
+// and optionally the genesis block, so that they can be verified offline with
+// proofVerify.
+func proofGet(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+	out := c.String("out")
+	if out == "" {
+		return xerrors.New("--out flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	instIDBuf, err := hex.DecodeString(c.String("instid"))
+	if err != nil || len(instIDBuf) == 0 {
+		return xerrors.New("please give the instance id in hex with --instid")
+	}
+
+	pr, err := cl.GetProof(instIDBuf)
+	if err != nil {
+		return xerrors.Errorf("couldn't get proof: %v", err)
+	}
+	buf, err := protobuf.Encode(&pr.Proof)
+	if err != nil {
+		return xerrors.Errorf("failed to encode proof: %v", err)
+	}
+	err = ioutil.WriteFile(out, buf, 0644)
+	if err != nil {
+		return xerrors.Errorf("failed to write proof: %v", err)
+	}
+	log.Infof("Wrote the proof of block %d to %s", pr.Proof.Latest.Index, out)
+
+	if genesis := c.String("genesis"); genesis != "" {
+		buf, err = protobuf.Encode(cl.Genesis)
+		if err != nil {
+			return xerrors.Errorf("failed to encode genesis block: %v", err)
+		}
+		err = ioutil.WriteFile(genesis, buf, 0644)
+		if err != nil {
+			return xerrors.Errorf("failed to write genesis block: %v", err)
+		}
+		log.Infof("Wrote the genesis block to %s", genesis)
+	}
+
+	return nil
+}
+
+// proofVerify verifies a proof saved by proofGet without contacting any node,
+// against a genesis block given by --genesis, or the ID of the chain and the
+// roster of its genesis block.
+func proofVerify(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the file of the proof")
+	}
+	p, err := lib.ReadProof(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var bcID skipchain.SkipBlockID
+	switch {
+	case c.String("genesis") != "":
+		genesis, err := lib.ReadGenesis(c.String("genesis"))
+		if err != nil {
+			return err
+		}
+		err = lib.VerifyProofFromGenesis(*p, genesis)
+		if err != nil {
+			return err
+		}
+		bcID = genesis.Hash
+	case c.String("roster") != "":
+		if c.String("bcid") != "" {
+			bcID, err = hex.DecodeString(c.String("bcid"))
+			if err != nil {
+				return xerrors.Errorf("failed to decode --bcid: %v", err)
+			}
+		} else if c.String("bc") != "" {
+			cfg, _, err := lib.LoadConfig(c.String("bc"))
+			if err != nil {
+				return err
+			}
+			bcID = cfg.ByzCoinID
+		} else {
+			return xerrors.New("please give the chain with --bcid or --bc")
+		}
+		roster, err := lib.ReadRoster(c.String("roster"))
+		if err != nil {
+			return err
+		}
+		err = lib.VerifyProofFromRoster(*p, bcID, roster)
+		if err != nil {
+			return err
+		}
+	default:
+		return xerrors.New("please give the genesis block with --genesis, " +
+			"or its roster with --roster")
+	}
+
+	var header byzcoin.DataHeader
+	err = protobuf.Decode(p.Latest.Data, &header)
+	if err != nil {
+		return xerrors.Errorf("couldn't decode the header: %v", err)
+	}
+
+	out := new(strings.Builder)
+	out.WriteString("- Proof:\n")
+	fmt.Fprintf(out, "-- ByzCoinID: %x\n", bcID)
+	fmt.Fprintf(out, "-- Block: %d, %s\n", p.Latest.Index,
+		time.Unix(0, header.Timestamp).UTC().Format(time.RFC3339))
+
+	if instID := c.String("instid"); instID != "" {
+		instIDBuf, err := hex.DecodeString(instID)
+		if err != nil {
+			return xerrors.Errorf("failed to decode --instid: %v", err)
+		}
+		if !p.InclusionProof.Match(instIDBuf) {
+			fmt.Fprintf(out, "-- Key: %x is absent\n", instIDBuf)
+			log.Info(out.String())
+			return nil
+		}
+	}
+
+	key, value, contractID, darcID, err := p.KeyValue()
+	if err != nil {
+		return xerrors.Errorf("proof has no instance, use --instid to "+
+			"verify an absence: %v", err)
+	}
+	fmt.Fprintf(out, "-- Key: %x\n", key)
+	fmt.Fprintf(out, "-- Value: %x\n", value)
+	fmt.Fprintf(out, "-- ContractID: %s\n", contractID)
+	fmt.Fprintf(out, "-- DarcID: %x\n", darcID)
+	log.Info(out.String())
+
+	return nil
+}
+
 // getInstance checks the proof at the given instance ID and prints the instance
 // if it is found
 func getInstance(c *cli.Context) error {
@@ -1793,15 +2659,23 @@ func getInstance(c *cli.Context) error {
 		return xerrors.Errorf("couldn't get value out of proof: %v", err)
 	}
 
-	instanceData := string(resultBuf)
+	instanceData := " " + string(resultBuf) + "\n"
 	if c.Bool("hex") {
-		instanceData = fmt.Sprintf("%x", instanceData)
+		instanceData = fmt.Sprintf(" %x\n", resultBuf)
+	} else if !c.Bool("raw") {
+		desc, ok, err := lib.DecodeInstance(contractID, resultBuf)
+		if err != nil {
+			return err
+		}
+		if ok {
+			instanceData = "\n" + indentDescription(desc)
+		}
 	}
 
 	out := new(strings.Builder)
 	out.WriteString("- Instance:\n")
 	fmt.Fprintf(out, "-- Key: %x\n", keyBuf)
-	fmt.Fprintf(out, "-- Value: %s\n", instanceData)
+	fmt.Fprintf(out, "-- Value:%s", instanceData)
 	fmt.Fprintf(out, "-- ContranctID: %s\n", contractID)
 	fmt.Fprintf(out, "-- DarcID: %x\n", darcID)
 	log.Info(out.String())
@@ -1809,6 +2683,18 @@ func getInstance(c *cli.Context) error {
 	return nil
 }
 
+// indentDescription nests a description in the "- Field:\n-- Subfield" style
+// two levels deeper.
+func indentDescription(desc string) string {
+	lines := strings.SplitAfter(desc, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "--" + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
 type configPrivate struct {
 	Owner darc.Signer
 }
@@ -41,6 +41,14 @@ main(){
     run testDarcAddDeferred
     run testDarcAddRuleMinimum
     run testRuleDarc
+    run testDarcDiff
+    run testDarcGraph
+    run testTxApply
+    run testTxOffline
+    run testTxBatch
+    run testProof
+    run testContracts
+    run testKeystore
     run testAddDarcFromOtherOne
     run testAddDarcWithOwner
     run testExpression
@@ -425,6 +433,199 @@ testRuleDarc(){
   testOK runBA darc rule --restricted -replace -rule _sign -identity "ed25519:abc | ed25519:aef" -darc "$ID" -sign "$KEY"
 }
 
+testDarcDiff(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+  ID=`cat ./darc_id.txt`
+  KEY=`cat ./darc_key.txt`
+  testFail runBA darc diff -darc "$ID"
+  testOK runBA darc rule -rule spawn:xxx -identity ed25519:abc -darc "$ID" -sign "$KEY"
+  testGrep "+ spawn:xxx - \"ed25519:abc\"" runBA0 darc diff -darc "$ID"
+  testOK runBA darc rule -replace -rule spawn:xxx -identity ed25519:aef -darc "$ID" -sign "$KEY"
+  testGrep "~ spawn:xxx - \"ed25519:abc\" => \"ed25519:aef\"" runBA0 darc diff -darc "$ID"
+  testOK runBA darc rule -delete -rule spawn:xxx -darc "$ID" -sign "$KEY"
+  testGrep "- spawn:xxx - \"ed25519:aef\"" runBA0 darc diff -darc "$ID"
+  testGrep "No changes" runBA0 darc diff -darc "$ID" -from 0 -to 3
+  testFail runBA darc diff -darc "$ID" -to 4
+}
+
+testDarcGraph(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+  ID=`cat ./darc_id.txt`
+  testOK runBA darc rule -rule spawn:xxx -identity "$ID"
+  testGrep "digraph darcs" runBA0 darc graph
+  testGrep "label=\"spawn:xxx\"" runBA0 darc graph
+  testOK runBA darc graph -out darcs.dot
+  testGrep "$ID" cat darcs.dot
+  testFail runBA darc graph -darc darc:abcd
+}
+
+testTxApply(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+  ID=`cat ./darc_id.txt`
+  KEY=`cat ./darc_key.txt`
+  testOK runBA darc rule -rule spawn:value -identity "$KEY" -darc "$ID" -sign "$KEY"
+
+  cat > tx.yaml <<EOF
+instructions:
+  - instanceID: ${ID#darc:}
+    action: spawn
+    contract: value
+    args:
+      - name: value
+        string: first
+    signers: [ "$KEY" ]
+  - instanceID: ${ID#darc:}
+    action: spawn
+    contract: value
+    args:
+      - name: value
+        string: second
+    signers: [ "$KEY" ]
+EOF
+  testGrep "Applied 2 instructions" runBA0 tx apply tx.yaml
+
+  # The admin identity is not allowed to spawn a value on the new darc, so
+  # none of the instructions is applied
+  sed -e '/signers/d' tx.yaml > tx_admin.yaml
+  testFail runBA tx apply tx_admin.yaml
+  testFail runBA tx apply missing.yaml
+}
+
+testTxOffline(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+  ID=`cat ./darc_id.txt`
+  KEY=`cat ./darc_key.txt`
+  testOK runBA darc rule -rule spawn:value -identity "$KEY" -darc "$ID" -sign "$KEY"
+
+  cat > tx.yaml <<EOF
+instructions:
+  - instanceID: ${ID#darc:}
+    action: spawn
+    contract: value
+    args:
+      - name: value
+        string: offline
+    signers: [ "$KEY" ]
+EOF
+  testOK runBA tx prepare -out tx.bin tx.yaml
+  testFail runBA tx send tx.bin
+  testFail runBA tx sign tx.bin
+  testGrep "Signed 1 instructions" runBA0 tx sign -sign "$KEY" tx.bin
+  testGrep "Applied 1 instructions" runBA0 tx send tx.bin
+}
+
+testTxBatch(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+  ID=`cat ./darc_id.txt`
+  KEY=`cat ./darc_key.txt`
+  testOK runBA darc rule -rule spawn:value -identity "$KEY" -darc "$ID" -sign "$KEY"
+
+  echo "instanceID,action,contract,value,signers" > tx.csv
+  for i in 1 2 3 4 5; do
+    echo "${ID#darc:},spawn,value,value $i,$KEY" >> tx.csv
+  done
+  testOK runBA tx batch -chunk 2 -report report.csv tx.csv
+  testGrep "5,ok" cat report.csv
+  testNGrep "failed" cat report.csv
+
+  # The admin identity is not allowed to spawn a value on the new darc
+  echo "instanceID,action,contract,value" > tx_admin.csv
+  echo "${ID#darc:},spawn,value,admin" >> tx_admin.csv
+  testFail runBA tx batch -retries 0 -report report.csv tx_admin.csv
+  testGrep "1,failed" cat report.csv
+  testFail runBA tx batch missing.csv
+}
+
+testProof(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testOK runBA darc add -out_id ./darc_id.txt -unrestricted
+  ID=`cat ./darc_id.txt`
+  testOK runBA proof get -i ${ID#darc:} -out proof.bin -genesis genesis.bin
+  testFail runBA proof get -i ${ID#darc:}
+
+  # No node is needed to verify the proof
+  pkill conode 2> /dev/null
+  testGrep "DarcID: ${ID#darc:}" runBA0 proof verify -genesis genesis.bin proof.bin
+  testGrep "is absent" runBA0 proof verify -genesis genesis.bin -i 1234 proof.bin
+  testFail runBA proof verify proof.bin
+  testFail runBA proof verify -genesis proof.bin proof.bin
+}
+
+testContracts(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  testGrep "coin" runBA0 contracts list
+  testGrep "darc" runBA0 contracts list -server 1
+  testGrep "invoke:update" runBA0 contracts describe value
+  testGrep "coins (uint64)" runBA0 contracts describe coin
+  testFail runBA contracts describe unknown
+  testFail runBA contracts describe
+}
+
+testKeystore(){
+  runCoBG 1 2 3
+  runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+  eval $SED
+  [ -z "$BC" ] && exit 1
+
+  KEY=`runBA0 key`
+  testGrep "$KEY (plain)" runBA0 key list
+  testFail runBA key encrypt
+  BC_PASSPHRASE=secret testOK runBA key encrypt
+  testGrep "$KEY (encrypted)" runBA0 key list
+  testNGrep "(plain)" runBA0 key list
+
+  # The admin key is now encrypted too
+  testFail runBA darc rule -rule spawn:xxx -identity "$KEY"
+  export BC_PASSPHRASE=wrong
+  testFail runBA darc rule -rule spawn:xxx -identity "$KEY"
+  export BC_PASSPHRASE=secret
+  testOK runBA darc rule -rule spawn:xxx -identity "$KEY"
+  export -n BC_PASSPHRASE
+  export BC_PASSPHRASE_CMD="echo secret"
+  testOK runBA darc rule -replace -rule spawn:xxx -identity "$KEY"
+
+  testFail runBA key export "$KEY"
+  testOK runBA key export -out key.bak "$KEY"
+  testOK rm -f config/key-$KEY.cfg
+  testNGrep "$KEY" runBA0 key list
+  testOK runBA key import key.bak
+  testGrep "$KEY (encrypted)" runBA0 key list
+  export -n BC_PASSPHRASE_CMD
+}
+
 testAddDarcFromOtherOne(){
   runCoBG 1 2 3
   runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
@@ -562,6 +763,11 @@ testInstructionGet() {
 
   testOK runBA0 instance get -i 0000000000000000000000000000000000000000000000000000000000000000
   testOK runBA0 instance get -i 0000000000000000000000000000000000000000000000000000000000000000 --hex
+
+  # The config is decoded, unless --raw is given
+  testGrep "BlockInterval: 500ms" runBA0 instance get 0000000000000000000000000000000000000000000000000000000000000000
+  testNGrep "BlockInterval" runBA0 instance get --raw 0000000000000000000000000000000000000000000000000000000000000000
+  testFail runBA instance get
 }
 
 main
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +16,7 @@ import (
 	"go.dedis.ch/cothority/v3/byzcoin/viewchange"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
@@ -88,6 +90,12 @@ type ContractWithRegistry interface {
 	SetRegistry(ReadOnlyContractRegistry)
 }
 
+// ContractDescriber is implemented by the contracts that describe the
+// instructions they accept, so that clients can discover them.
+type ContractDescriber interface {
+	Describe() ContractDescription
+}
+
 // ContractFn is the type signature of the instance factory functions which can be
 // registered with the ByzCoin service.
 type ContractFn func(in []byte) (Contract, error)
@@ -133,6 +141,34 @@ func (cr *contractRegistry) Search(contractID string) (ContractFn, bool) {
 	return fn, exists
 }
 
+// describe returns the descriptions of the contracts, sorted by ID. The
+// contracts are instantiated without data to get their description.
+func (cr *contractRegistry) describe() []ContractDescription {
+	cr.Lock()
+	ids := make([]string, 0, len(cr.registry))
+	fns := make(map[string]ContractFn, len(cr.registry))
+	for id, fn := range cr.registry {
+		ids = append(ids, id)
+		fns[id] = fn
+	}
+	cr.Unlock()
+	sort.Strings(ids)
+
+	descs := make([]ContractDescription, len(ids))
+	for i, id := range ids {
+		descs[i] = ContractDescription{ID: id}
+		c, err := fns[id](nil)
+		if err != nil {
+			continue
+		}
+		if d, ok := c.(ContractDescriber); ok {
+			descs[i] = d.Describe()
+			descs[i].ID = id
+		}
+	}
+	return descs
+}
+
 // Clone returns a copy of the registry and locks the source so that
 // static registration is not allowed anymore. This is to prevent
 // registration of a contract at runtime and limit it only to the
@@ -358,6 +394,12 @@ func (c *contractConfig) VerifyInstruction(rst ReadOnlyStateTrie, inst Instructi
 		return nil
 	}
 
+	// A key rotation is signed by the keys of the transition, which are
+	// verified by Invoke.
+	if inst.GetType() == InvokeType && inst.Invoke.Command == "rotate_key" {
+		return nil
+	}
+
 	err = inst.Verify(rst, msg)
 	return cothority.ErrorOrNil(err, "instruction verification failed")
 }
@@ -471,6 +513,7 @@ func (c *contractConfig) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []
 // Invoke offers the following functions:
 //   - Invoke:update_config
 //   - Invoke:view_change
+//   - Invoke:rotate_key
 //
 // Invoke:update_config should have the following input argument:
 //   - config ChainConfig
@@ -478,6 +521,9 @@ func (c *contractConfig) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []
 // Invoke:view_change sould have the following input arguments:
 //   - newview viewchange.NewViewReq
 //   - multisig []byte
+//
+// Invoke:rotate_key should have the following input argument:
+//   - transition keyrotation.Transition
 func (c *contractConfig) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
 	// Find the darcID for this instance.
 	var darcID darc.ID
@@ -510,31 +556,54 @@ func (c *contractConfig) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 		if err = newConfig.sanityCheck(oldConfig); err != nil {
 			return nil, nil, xerrors.Errorf("sanity check: %v", err)
 		}
-		var val []byte
-		val, _, _, _, err = rst.GetValues(darcID)
+		darcSc, err := viewChangeRuleSc(rst, darcID, newConfig.Roster)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("reading trie: %v", err)
+			return nil, nil, xerrors.Errorf("view_change rule: %v", err)
+		}
+		sc := StateChanges{
+			NewStateChange(Update, NewInstanceID(nil), ContractConfigID, configBuf, darcID),
+			darcSc,
+		}
+		return sc, coins, nil
+	case "rotate_key":
+		if rst.GetVersion() < VersionKeyRotation {
+			return nil, nil, xerrors.New("key rotation is not supported " +
+				"by this version of the chain")
 		}
-		var genesisDarc *darc.Darc
-		genesisDarc, err = darc.NewFromProtobuf(val)
+		var t keyrotation.Transition
+		err = protobuf.DecodeWithConstructors(inst.Invoke.Args.Search("transition"),
+			&t, network.DefaultConstructors(cothority.Suite))
 		if err != nil {
-			return nil, nil, xerrors.Errorf("decoding darc: %v", err)
+			return nil, nil, xerrors.Errorf("decoding transition: %v", err)
 		}
-		var rules []string
-		for _, p := range newConfig.Roster.Publics() {
-			rules = append(rules, "ed25519:"+p.String())
+		if err = t.Verify(); err != nil {
+			return nil, nil, xerrors.Errorf("invalid transition: %v", err)
 		}
-		genesisDarc.Rules.UpdateRule("invoke:"+ContractConfigID+".view_change", expression.InitOrExpr(rules...))
-		var genesisBuf []byte
-		genesisBuf, err = genesisDarc.ToProto()
+
+		config, err := rst.LoadConfig()
 		if err != nil {
-			return nil, nil, xerrors.Errorf("encoding darc: %v", err)
+			return nil, nil, xerrors.Errorf("reading trie: %v", err)
 		}
-		sc := StateChanges{
-			NewStateChange(Update, NewInstanceID(nil), ContractConfigID, configBuf, darcID),
-			NewStateChange(Update, NewInstanceID(darcID), ContractDarcID, genesisBuf, darcID),
+		switch t.Index(&config.Roster) {
+		case -1:
+			return nil, nil, xerrors.New("old key is not in the roster")
+		case 0:
+			return nil, nil, xerrors.New("the leader can't rotate its own key")
 		}
-		return sc, coins, nil
+		newRoster := t.Replace(&config.Roster)
+		if newRoster == nil {
+			return nil, nil, xerrors.New("new key is already in the roster")
+		}
+
+		sc, err := updateRosterScs(rst, darcID, *newRoster)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("roster scs: %v", err)
+		}
+		darcSc, err := viewChangeRuleSc(rst, darcID, *newRoster)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("view_change rule: %v", err)
+		}
+		return append(sc, darcSc), coins, nil
 	case "view_change":
 		var req viewchange.NewViewReq
 		err = protobuf.DecodeWithConstructors(inst.Invoke.Args.Search("newview"), &req, network.DefaultConstructors(cothority.Suite))
@@ -595,6 +664,30 @@ func updateRosterScs(rst ReadOnlyStateTrie, darcID darc.ID, newRoster onet.Roste
 	}, nil
 }
 
+// viewChangeRuleSc returns the update of the genesis darc letting the nodes
+// of the roster sign the view_change.
+func viewChangeRuleSc(rst ReadOnlyStateTrie, darcID darc.ID,
+	roster onet.Roster) (StateChange, error) {
+	val, _, _, _, err := rst.GetValues(darcID)
+	if err != nil {
+		return StateChange{}, xerrors.Errorf("reading trie: %v", err)
+	}
+	genesisDarc, err := darc.NewFromProtobuf(val)
+	if err != nil {
+		return StateChange{}, xerrors.Errorf("decoding darc: %v", err)
+	}
+	var rules []string
+	for _, p := range roster.Publics() {
+		rules = append(rules, "ed25519:"+p.String())
+	}
+	genesisDarc.Rules.UpdateRule("invoke:"+ContractConfigID+".view_change", expression.InitOrExpr(rules...))
+	genesisBuf, err := genesisDarc.ToProto()
+	if err != nil {
+		return StateChange{}, xerrors.Errorf("encoding darc: %v", err)
+	}
+	return NewStateChange(Update, NewInstanceID(darcID), ContractDarcID, genesisBuf, darcID), nil
+}
+
 // GetValueContract gets all the information in an instance, an error is
 // returned if the instance does not exist.
 func GetValueContract(st ReadOnlyStateTrie, key []byte) (value []byte, version uint64, contract string, darcID darc.ID, err error) {
@@ -47,6 +47,60 @@ type contractCoin struct {
 	byzcoin.Coin
 }
 
+// Describe implements the byzcoin.ContractDescriber interface.
+func (c *contractCoin) Describe() byzcoin.ContractDescription {
+	coins := byzcoin.ContractArgument{Name: "coins", Type: "uint64",
+		Description: "the number of coins"}
+	return byzcoin.ContractDescription{
+		Description: "an account holding coins of one type",
+		Commands: []byzcoin.ContractCommand{
+			{
+				Action:      "spawn",
+				Description: "create an empty account",
+				Args: []byzcoin.ContractArgument{
+					{Name: "coinID", Type: "bytes", Optional: true,
+						Description: "derives the instance ID of the account"},
+					{Name: "darcID", Type: "bytes", Optional: true,
+						Description: "the darc of the account, instead of " +
+							"the spawning one"},
+					{Name: "type", Type: "bytes", Optional: true,
+						Description: "the instance ID naming the coins"},
+				},
+			},
+			{
+				Action:      "invoke",
+				Name:        "mint",
+				Description: "add coins to the account",
+				Args:        []byzcoin.ContractArgument{coins},
+			},
+			{
+				Action:      "invoke",
+				Name:        "transfer",
+				Description: "send coins to another account",
+				Args: []byzcoin.ContractArgument{coins,
+					{Name: "destination", Type: "bytes",
+						Description: "the instance ID of the account"},
+				},
+			},
+			{
+				Action:      "invoke",
+				Name:        "fetch",
+				Description: "take coins out for the next instruction",
+				Args:        []byzcoin.ContractArgument{coins},
+			},
+			{
+				Action:      "invoke",
+				Name:        "store",
+				Description: "put the coins of the instruction in the account",
+			},
+			{
+				Action:      "delete",
+				Description: "delete the account if it is empty",
+			},
+		},
+	}
+}
+
 func (c *contractCoin) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
 	cout = coins
 
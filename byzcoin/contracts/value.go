@@ -28,6 +28,35 @@ func contractValueFromBytes(in []byte) (byzcoin.Contract, error) {
 	return &ContractValue{value: in}, nil
 }
 
+// Describe implements the byzcoin.ContractDescriber interface
+func (c ContractValue) Describe() byzcoin.ContractDescription {
+	value := byzcoin.ContractArgument{Name: "value", Type: "bytes",
+		Description: "the value stored in the instance"}
+	return byzcoin.ContractDescription{
+		Description: "stores a value",
+		Commands: []byzcoin.ContractCommand{
+			{
+				Action:      "spawn",
+				Description: "create an instance with a value",
+				Args: []byzcoin.ContractArgument{value,
+					{Name: "preID", Type: "bytes", Optional: true,
+						Description: "derives the instance ID"},
+				},
+			},
+			{
+				Action:      "invoke",
+				Name:        "update",
+				Description: "replace the value",
+				Args:        []byzcoin.ContractArgument{value},
+			},
+			{
+				Action:      "delete",
+				Description: "delete the instance",
+			},
+		},
+	}
+}
+
 // Spawn implements the byzcoin.Contract interface
 func (c ContractValue) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
 	cout = coins
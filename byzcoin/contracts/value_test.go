@@ -135,3 +135,31 @@ func TestValue_Invoke(t *testing.T) {
 
 	local.WaitDone(genesisMsg.BlockInterval)
 }
+
+func TestValue_Describe(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:value"}, signer.Identity())
+	require.NoError(t, err)
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	reply, err := cl.GetContracts()
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.CurrentVersion, reply.Version)
+	var value *byzcoin.ContractDescription
+	for i := range reply.Contracts {
+		if reply.Contracts[i].ID == ContractValueID {
+			value = &reply.Contracts[i]
+		}
+	}
+	require.NotNil(t, value)
+	require.Len(t, value.Commands, 3)
+	require.Equal(t, "update", value.Commands[1].Name)
+	require.Equal(t, "value", value.Commands[1].Args[0].Name)
+}
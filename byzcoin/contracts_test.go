@@ -10,6 +10,26 @@ func testContractFn(in []byte) (Contract, error) {
 	return nil, nil
 }
 
+type testDescribedContract struct {
+	BasicContract
+}
+
+func (testDescribedContract) Describe() ContractDescription {
+	return ContractDescription{
+		ID:      "ignored",
+		Version: "1.0",
+		Commands: []ContractCommand{{
+			Action: "invoke",
+			Name:   "update",
+			Args:   []ContractArgument{{Name: "value", Type: "bytes"}},
+		}},
+	}
+}
+
+func testDescribedContractFn(in []byte) (Contract, error) {
+	return testDescribedContract{}, nil
+}
+
 // Test basic usage of the registry.
 func TestContracts_Registry(t *testing.T) {
 	r := newContractRegistry()
@@ -36,3 +56,16 @@ func TestContracts_Registry(t *testing.T) {
 	require.Error(t, r.register("c", testContractFn, false))
 	require.NoError(t, r.register("c", testContractFn, true))
 }
+
+func TestContracts_Describe(t *testing.T) {
+	r := newContractRegistry()
+	require.NoError(t, r.register("b", testContractFn, false))
+	require.NoError(t, r.register("a", testDescribedContractFn, false))
+
+	descs := r.describe()
+	require.Len(t, descs, 2)
+	require.Equal(t, "a", descs[0].ID)
+	require.Equal(t, "1.0", descs[0].Version)
+	require.Len(t, descs[0].Commands, 1)
+	require.Equal(t, ContractDescription{ID: "b"}, descs[1])
+}
@@ -24,7 +24,7 @@ func init() {
 type Version int
 
 // CurrentVersion is what we're running now
-const CurrentVersion Version = VersionRollup
+const CurrentVersion Version = VersionRemoteAttest
 
 const (
 	// VersionInstructionHash is the first version and indicates that a new,
@@ -44,4 +44,23 @@ const (
 	// VersionRollup indicates that the followers send their transactions to
 	// the leader, instead of polling by the leader.
 	VersionRollup = 7
+	// VersionKeyRotation adds the rotate_key command to the config
+	// contract, replacing the key of a node of the roster.
+	VersionKeyRotation = 8
+	// VersionCredentialRenewal adds the renew command to the personhood
+	// credentials, which are re-encoded without their renewal when they are
+	// spawned or updated.
+	VersionCredentialRenewal = 9
+	// VersionBEvmPrecompile lets the EVM contracts of BEvm read the ByzCoin
+	// instances through a precompiled contract.
+	VersionBEvmPrecompile = 10
+	// VersionBEvmLogs stores the logs and the receipt of every BEvm
+	// transaction in the logs instance of its BEvm instance.
+	VersionBEvmLogs = 11
+	// VersionBEvmGas stores the gas configuration, the gas used in the block
+	// and the coin bridge with the state of the BEvm instances.
+	VersionBEvmGas = 12
+	// VersionRemoteAttest adds the attest command to the pop-parties, which
+	// adds the remote attendees to the party when it is finalized.
+	VersionRemoteAttest = 13
 )
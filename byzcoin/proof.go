@@ -8,9 +8,9 @@ import (
 	"golang.org/x/xerrors"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/chainverify"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/skipchain"
-	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 )
@@ -136,22 +136,28 @@ func (p Proof) Verify(sbID skipchain.SkipBlockID) error {
 	// before against the block with ID stored in the To field by the caller.
 	publics := p.Links[0].NewRoster.ServicePublics(skipchain.ServiceName)
 
-	for _, l := range p.Links[1:] {
-		if err = l.VerifyWithScheme(pairing.NewSuiteBn256(), publics, p.Latest.SignatureScheme); err != nil {
-			return cothority.WrapError(ErrorVerifySkipchain)
+	links := make([]chainverify.Link, len(p.Links)-1)
+	for i, l := range p.Links[1:] {
+		links[i] = chainverify.Link{
+			From: l.From,
+			To:   l.To,
+			Msg:  l.Signature.Msg,
+			Sig:  l.Signature.Sig,
 		}
-		if !l.From.Equal(sbID) {
-			return cothority.WrapError(ErrorVerifySkipchain)
-		}
-		sbID = l.To
 		if l.NewRoster != nil {
-			publics = l.NewRoster.ServicePublics(skipchain.ServiceName)
+			links[i].NewRosterID = l.NewRoster.ID[:]
+			links[i].NewPublics = l.NewRoster.ServicePublics(skipchain.ServiceName)
 		}
 	}
 
 	// Check that the given latest block matches the last forward link target
-	if !p.Latest.CalculateHash().Equal(sbID) {
+	err = chainverify.VerifyLinks(sbID, publics, links,
+		p.Latest.SignatureScheme, p.Latest.CalculateHash())
+	switch {
+	case xerrors.Is(err, chainverify.ErrLatest):
 		return cothority.WrapError(ErrorVerifyHash)
+	case err != nil:
+		return cothority.WrapError(ErrorVerifySkipchain)
 	}
 
 	return nil
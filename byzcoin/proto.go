@@ -6,6 +6,7 @@ import (
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/onet/v3"
 )
 
@@ -20,6 +21,7 @@ import (
 // type :InstanceID:bytes
 // type :Version:sint32
 // type :GetUpdatesFlags:uint64
+// type :tracing.ID:bytes
 // import "skipchain.proto";
 // import "onet.proto";
 // import "darc.proto";
@@ -114,6 +116,12 @@ type AddTxRequest struct {
 	// Current flags supported are:
 	//  - 1: leader check - don't propagate further
 	Flags int `protobuf:"opt"`
+	// TraceID identifies the request in the logs of the nodes. If it is
+	// empty, the node creates one.
+	TraceID tracing.ID `protobuf:"opt"`
+	// SpanID is the parent of the span of the request, when it is forwarded
+	// by another node.
+	SpanID tracing.SpanID `protobuf:"opt"`
 }
 
 // AddTxResponse is the reply after an AddTxRequest is finished.
@@ -124,6 +132,8 @@ type AddTxResponse struct {
 	Error string `protobuf:"opt"`
 	// Proof of the block with the transaction.
 	Proof *Proof `protobuf:"opt"`
+	// TraceID identifies the request in the logs of the nodes.
+	TraceID tracing.ID `protobuf:"opt"`
 }
 
 // GetProof returns the proof that the given key is in the trie.
@@ -482,6 +492,47 @@ type ResolvedInstanceID struct {
 	InstanceID InstanceID
 }
 
+// GetContracts asks a conode for the contracts it has registered.
+type GetContracts struct {
+}
+
+// GetContractsReply holds the contracts registered by the conode, sorted by
+// their ID.
+type GetContractsReply struct {
+	// Version is the version of the ByzCoin protocol of the conode.
+	Version   Version
+	Contracts []ContractDescription
+}
+
+// ContractDescription describes a contract. Only the ID is given for the
+// contracts that don't implement ContractDescriber.
+type ContractDescription struct {
+	ID          string
+	Version     string            `protobuf:"opt"`
+	Description string            `protobuf:"opt"`
+	Commands    []ContractCommand `protobuf:"opt"`
+}
+
+// ContractCommand describes an instruction accepted by a contract.
+type ContractCommand struct {
+	// Action is either spawn, invoke or delete.
+	Action string
+	// Name is the command of an invoke.
+	Name        string             `protobuf:"opt"`
+	Description string             `protobuf:"opt"`
+	Args        []ContractArgument `protobuf:"opt"`
+}
+
+// ContractArgument describes an argument of an instruction.
+type ContractArgument struct {
+	Name string
+	// Type tells how the value is encoded, like string, uint64 for a
+	// little-endian integer, or bytes.
+	Type        string `protobuf:"opt"`
+	Description string `protobuf:"opt"`
+	Optional    bool
+}
+
 // DebugRequest returns the list of all byzcoins if byzcoinid is empty, else it returns
 // a dump of all instances if byzcoinid is given and exists.
 type DebugRequest struct {
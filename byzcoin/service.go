@@ -8,7 +8,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
-	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -17,11 +16,19 @@ import (
 
 	"github.com/satori/go.uuid"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/admission"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/byzcoin/viewchange"
+	"go.dedis.ch/cothority/v3/clientauth"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/decode"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/tracing"
+	"go.dedis.ch/cothority/v3/wireversion"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/suites"
@@ -62,6 +69,19 @@ const noTimeout time.Duration = 0
 const viewChangeSubFtCosi = "viewchange_sub_ftcosi"
 const viewChangeFtCosi = "viewchange_ftcosi"
 
+// WireVersionTracing is the version of the messages in which the
+// transactions forwarded to the leader carry their trace and span IDs.
+const WireVersionTracing = 2
+
+// CurrentWireVersion is the version of the messages sent between the
+// conodes by the service, as exchanged by the wireversion package. It is
+// independent of the Version of the chains.
+const CurrentWireVersion = WireVersionTracing
+
+// OldestWireVersion is the oldest version of the messages the service can
+// still talk.
+const OldestWireVersion = wireversion.Legacy
+
 var viewChangeMsgID network.MessageTypeID
 
 // Is used in the tests to avoid premature update of the blocks
@@ -73,6 +93,12 @@ var ByzCoinID onet.ServiceID
 // Verify is the verifier ID for ByzCoin skipchains.
 var Verify = skipchain.VerifierID(uuid.NewV5(uuid.NamespaceURL, "ByzCoin"))
 
+// admissionLimits bound the transactions sent by a client, once the conode
+// enables the admission control.
+var admissionLimits = []admission.Limit{
+	{Endpoint: ServiceName + "/AddTransaction", Rate: 10, Burst: 50},
+}
+
 func init() {
 	var err error
 	ByzCoinID, err = onet.RegisterNewServiceWithSuite(ServiceName, pairingSuite, newService)
@@ -80,6 +106,7 @@ func init() {
 	network.RegisterMessages(&bcStorage{}, &DataHeader{}, &DataBody{})
 	viewChangeMsgID = network.RegisterMessage(&viewchange.InitReq{})
 	network.SetTCPDialTimeout(2 * time.Second)
+	log.ErrFatal(admission.RegisterDefaults(admissionLimits...))
 
 	err = RegisterGlobalContract(ContractConfigID, contractConfigFromBytes)
 	if err != nil {
@@ -179,6 +206,10 @@ var storageID = []byte("ByzCoin")
 // transaction is not set.
 const defaultInterval = 5 * time.Second
 
+// maxHeaderSize is the size of the biggest DataHeader a block can have, far
+// more than its hashes and fields need.
+const maxHeaderSize = 1 << 16
+
 // defaultMaxBlockSize is used when the config cannot be loaded.
 const defaultMaxBlockSize = 4 * 1e6
 
@@ -200,6 +231,93 @@ func (s *Service) GetProtocolVersion() Version {
 	return v
 }
 
+// ProtocolVersion returns the version of the Byzcoin protocol for the status
+// report.
+func (s *Service) ProtocolVersion() int {
+	return int(s.GetProtocolVersion())
+}
+
+// WireVersion implements wireversion.Versioner.
+func (s *Service) WireVersion() wireversion.Range {
+	return wireversion.Range{Current: CurrentWireVersion,
+		Oldest: OldestWireVersion}
+}
+
+// rotateKeyInclusionWait is how many blocks the node waits for its rotate_key
+// transaction to be included, so that the roster is rotated before it
+// restarts with the new key.
+const rotateKeyInclusionWait = 10
+
+// RotateKey implements keyrotation.Rotator. The node with the old key sends a
+// rotate_key transaction to every chain with it in the roster, and the other
+// nodes only return the rosters. The leader of a chain can't rotate its own
+// key, and has to let another node take over with a view-change first.
+func (s *Service) RotateKey(t *keyrotation.Transition) ([]*onet.Roster,
+	error) {
+	resp, err := s.GetAllByzCoinIDs(&GetAllByzCoinIDsRequest{})
+	if err != nil {
+		return nil, xerrors.Errorf("getting the chains: %v", err)
+	}
+	buf, err := protobuf.Encode(t)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding the transition: %v", err)
+	}
+
+	var rosters []*onet.Roster
+	var errs []string
+	for _, id := range resp.IDs {
+		st, err := s.GetReadOnlyStateTrie(id)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%x: getting trie: %v", id, err))
+			continue
+		}
+		config, err := st.LoadConfig()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%x: reading trie: %v", id, err))
+			continue
+		}
+		if t.Replace(&config.Roster) == nil {
+			continue
+		}
+		rosters = append(rosters, &config.Roster)
+		if !t.Old.Equal(s.ServerIdentity().Public) {
+			continue
+		}
+
+		ctx := NewClientTransaction(st.GetVersion(), Instruction{
+			InstanceID: ConfigInstanceID,
+			Invoke: &Invoke{
+				ContractID: ContractConfigID,
+				Command:    "rotate_key",
+				Args:       Arguments{{Name: "transition", Value: buf}},
+			},
+		})
+		_, err = s.AddTransaction(&AddTxRequest{
+			Version:       CurrentVersion,
+			SkipchainID:   id,
+			Transaction:   ctx,
+			InclusionWait: rotateKeyInclusionWait,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%x: %v", id, err))
+		}
+	}
+	if len(errs) > 0 {
+		return rosters, xerrors.Errorf("couldn't rotate the key: %s",
+			strings.Join(errs, "; "))
+	}
+	return rosters, nil
+}
+
+// Ready returns an error while the service is catching up with the chains it
+// follows, so that the node doesn't get requests it cannot answer yet.
+func (s *Service) Ready() error {
+	if s.catchingUpWG.isRunning() {
+		return xerrors.New("catching up with the chains")
+	}
+	return nil
+}
+
 // GetAllByzCoinIDs returns the list of Byzcoin chains known by the server.
 func (s *Service) GetAllByzCoinIDs(req *GetAllByzCoinIDsRequest) (*GetAllByzCoinIDsResponse, error) {
 	chains, err := s.skService().GetDB().GetSkipchains()
@@ -322,7 +440,7 @@ func (s *Service) CreateGenesisBlock(req *CreateGenesisBlock) (
 }
 
 func (s *Service) prepareTxResponse(req *AddTxRequest, tx *TxResult) (*AddTxResponse, error) {
-	resp := &AddTxResponse{Version: CurrentVersion}
+	resp := &AddTxResponse{Version: CurrentVersion, TraceID: req.TraceID}
 
 	errMsg, exists := s.txErrorBuf.get(tx.ClientTransaction.Instructions.HashWithSignatures())
 	if !tx.Accepted {
@@ -374,12 +492,29 @@ func (s *Service) prepareTxResponse(req *AddTxRequest, tx *TxResult) (*AddTxResp
 // it is sent to all other nodes.
 // Every node that cannot send it to the leader will request a viewChange.
 // If enough nodes fail to send it to the leader, a new leader will be elected.
-func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
+// The request is logged on every node it goes through with its trace ID,
+// which is created if the client didn't give one.
+func (s *Service) AddTransaction(req *AddTxRequest) (resp *AddTxResponse,
+	err error) {
 	if !s.tasks.add(1) {
 		return nil, xerrors.New("node is closed")
 	}
 	defer s.tasks.done()
 
+	req.TraceID = tracing.OrNew(req.TraceID)
+	tlog := tracing.NewLogger(req.TraceID, "service", ServiceName, "node",
+		s.ServerIdentity())
+	span := tracing.StartSpan(req.TraceID, req.SpanID,
+		ServiceName+".AddTransaction", "node", s.ServerIdentity(),
+		"instructions", len(req.Transaction.Instructions))
+	defer func() {
+		if err == nil && resp != nil && resp.Error != "" {
+			span.End(xerrors.New(resp.Error))
+		} else {
+			span.End(err)
+		}
+	}()
+
 	if len(req.Transaction.Instructions) == 0 {
 		return nil, xerrors.New("no transactions to add")
 	}
@@ -425,7 +560,8 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 	}
 
 	for i, instr := range req.Transaction.Instructions {
-		log.Lvlf2("Instruction[%d]: %s on instance ID %s", i, instr.Action(), instr.InstanceID.String())
+		tlog.Lvl2("got instruction", "index", i, "action", instr.Action(),
+			"instance", instr.InstanceID)
 	}
 
 	// Either send the transaction to the leader, or,
@@ -439,6 +575,7 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 	// in case it's the leader.
 	// Else it will race when creating the Hash...
 	ctxHash := req.Transaction.Instructions.Hash()
+	tlog = tlog.With("tx", fmt.Sprintf("%x", ctxHash))
 
 	interval, _, err := s.LoadBlockInfo(req.SkipchainID)
 	if err != nil {
@@ -455,18 +592,32 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 			s.txPipelinesMutex.Unlock()
 			return nil, xerrors.New("this pipeline is not available")
 		}
+		tlog.Lvl2("sending transaction to the pipeline")
 		txp.ctxChan <- req.Transaction
 		if header.Version < req.Version {
 			txp.needUpgrade <- req.Version
 		}
 		s.txPipelinesMutex.Unlock()
 	} else {
+		version, err := wireversion.ForNode(s.Context, ServiceName, leader)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't agree on the messages "+
+				"with the leader: %w", err)
+		}
+		tlog.Lvl2("forwarding transaction to the leader", "leader", leader,
+			"wireVersion", version)
 		leaderRoster := onet.NewRoster([]*network.ServerIdentity{leader})
-		_, err := NewClient(req.SkipchainID, *leaderRoster).
-			AddTransaction(req.Transaction)
+		cl := NewClient(req.SkipchainID, *leaderRoster)
+		// The leaders talking Legacy don't know the trace fields, they
+		// create a new trace for the transaction.
+		if version >= WireVersionTracing {
+			cl.UseTraceID(req.TraceID)
+			cl.parentSpan = span.ID()
+		}
+		_, err = cl.AddTransaction(req.Transaction)
 		if err != nil {
-			log.Lvlf2("root failed with %v - need to request a view-change",
-				err)
+			tlog.Lvl2("root failed - need to request a view-change",
+				"err", err)
 
 			var err error
 			originalRequest := req.Flags&1 == 0
@@ -526,6 +677,8 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 			select {
 			case notif := <-ch:
 				if tx := notif.getTx(ctxHash); tx != nil {
+					tlog.Lvl2("transaction is in a block", "block",
+						notif.block.Index, "accepted", tx.Accepted)
 					return s.prepareTxResponse(req, tx)
 				}
 
@@ -540,7 +693,7 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 			}
 		}
 	}
-	return &AddTxResponse{Version: CurrentVersion}, nil
+	return &AddTxResponse{Version: CurrentVersion, TraceID: req.TraceID}, nil
 }
 
 // GetProof searches for a key and returns a proof of the
@@ -921,24 +1074,24 @@ type leafNode struct {
 	Value  []byte
 }
 
-// ProcessClientRequest implements onet.Service. We override the version
-// we normally get from embedding onet.ServiceProcessor in order to
-// hook it and get a look at the http.Request.
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode, after restricting its admin endpoints to the loopback.
 func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
-	if path == "Debug" {
-		h, _, err := net.SplitHostPort(req.RemoteAddr)
-		if err != nil {
-			return nil, nil, xerrors.Errorf("invalid address: %v", err)
-		}
-		ip := net.ParseIP(h)
-
-		if !ip.IsLoopback() {
-			return nil, nil, xerrors.New("the 'debug'-endpoint is only allowed on loopback")
-		}
+	if path == "Debug" && !clientauth.IsLocal(req) {
+		return nil, nil, xerrors.New("the 'debug'-endpoint is only allowed on loopback")
 	}
+	reply, tunnel, err := clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+	return reply, tunnel, cothority.ErrorOrNil(err, "processing request")
+}
 
-	buf, stream, err := s.ServiceProcessor.ProcessClientRequest(req, path, buf)
-	return buf, stream, cothority.ErrorOrNil(err, "processing request")
+// GetContracts returns the contracts registered by the conode, with the
+// description of those implementing ContractDescriber.
+func (s *Service) GetContracts(req *GetContracts) (*GetContractsReply, error) {
+	return &GetContractsReply{
+		Version:   s.GetProtocolVersion(),
+		Contracts: s.contracts.describe(),
+	}, nil
 }
 
 // Debug can be used to dump things from a byzcoin service. If byzcoinID is nil, it will return all
@@ -1067,7 +1220,14 @@ func (s *Service) SetPropagationTimeout(p time.Duration) {
 // skipchain-service. Once the block has been created, we
 // inform all nodes to update their internal trie
 // to include the new transactions.
-func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx []TxResult) (*skipchain.SkipBlock, error) {
+func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx []TxResult) (_ *skipchain.SkipBlock, err error) {
+	// A block holds the transactions of many requests, so it is traced on
+	// its own.
+	span := tracing.StartSpan(tracing.NewID(), nil,
+		ServiceName+".createNewBlock", "node", s.ServerIdentity(), "txs",
+		len(tx))
+	defer func() { span.End(err) }()
+
 	var sb *skipchain.SkipBlock
 	var mr []byte
 	var sst *stagingStateTrie
@@ -1129,7 +1289,6 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 
 	// Create header of skipblock containing only hashes
 	var scs StateChanges
-	var err error
 	var txRes TxResults
 
 	// Determine new block timestamp.
@@ -1138,7 +1297,10 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 	timestamp := time.Now().UnixNano()
 
 	log.Lvl3("Creating state changes")
+	phase := span.Child("createStateChanges")
 	mr, txRes, scs, _ = s.createStateChanges(sst, scID, tx, noTimeout, version, timestamp)
+	phase.SetAttributes("stateChanges", len(scs))
+	phase.End(nil)
 	if len(txRes) == 0 {
 		return nil, xerrors.New("no transactions")
 	}
@@ -1171,6 +1333,8 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 	}
 
 	log.Lvlf3("Storing skipblock with %d transactions.", len(txRes))
+	phase = span.Child("storeSkipBlock")
+	defer func() { phase.End(err) }()
 	var ssbReply *skipchain.StoreSkipBlockReply
 
 	if sb.Roster.List[0].Equal(s.ServerIdentity()) {
@@ -2042,10 +2206,17 @@ func (s *Service) verifySkipBlock(newID []byte, newSB *skipchain.SkipBlock) bool
 		return false
 	}
 
+	// The transactions of a block are bounded by the maximum block size,
+	// their encoding and their results add less than as much.
+	_, maxsz, err := s.LoadBlockInfo(newSB.SkipChainID())
+	if err != nil {
+		log.Error(s.ServerIdentity(), err)
+		return false
+	}
 	var body DataBody
-	err = protobuf.Decode(newSB.Payload, &body)
+	err = decode.Protobuf(newSB.Payload, &body, 2*maxsz)
 	if err != nil {
-		log.Error("verifySkipblock: couldn't unmarshal body")
+		log.Error("verifySkipblock: couldn't unmarshal body:", err)
 		return false
 	}
 
@@ -2617,6 +2788,13 @@ func loadNonceFromTxs(txs TxResults) ([]byte, error) {
 // exported because we need it in tests, it should not be used in non-test code
 // outside of this package.
 func (s *Service) TestClose() {
+	s.Drain()
+}
+
+// Drain stops the polling of the transactions and the new blocks, and waits
+// for the running ones, with the skipchain service. The conode calls it
+// before closing the server.
+func (s *Service) Drain() {
 	if s.tasks.pause() {
 		s.skService().TestClose()
 		s.cleanupGoroutines()
@@ -2715,7 +2893,7 @@ func (s *Service) startAllChains() (chan struct{}, error) {
 			" has been closed before")
 	}
 
-	msg, err := s.Load(storageID)
+	msg, err := atrest.Load(s, ServiceName, storageID)
 	if err != nil {
 		return nil, xerrors.Errorf("loading storage: %v", err)
 	}
@@ -2895,7 +3073,7 @@ func (s *Service) hasByzCoinVerification(gen skipchain.SkipBlockID) bool {
 func (s *Service) save() {
 	s.storage.Lock()
 	defer s.storage.Unlock()
-	err := s.Save(storageID, s.storage)
+	err := atrest.Save(s, ServiceName, storageID, s.storage)
 	if err != nil {
 		log.Error(s.ServerIdentity(), "Couldn't save file:", err)
 	}
@@ -3015,7 +3193,7 @@ func (s *Service) repairStateTrie(from *skipchain.SkipBlock, st *stateTrie) erro
 
 func decodeBlockHeader(sb *skipchain.SkipBlock) (*DataHeader, error) {
 	var header DataHeader
-	if err := protobuf.Decode(sb.Data, &header); err != nil {
+	if err := decode.Protobuf(sb.Data, &header, maxHeaderSize); err != nil {
 		return nil, xerrors.Errorf("couldn't unmarshal: %v", err)
 	}
 
@@ -3061,6 +3239,7 @@ func newService(c *onet.Context) (onet.Service, error) {
 		s.GetAllInstanceVersion,
 		s.CheckStateChangeValidity,
 		s.ResolveInstanceID,
+		s.GetContracts,
 		s.Debug,
 		s.DebugRemove)
 	if err != nil {
@@ -3072,6 +3251,13 @@ func newService(c *onet.Context) (onet.Service, error) {
 	}
 	s.RegisterProcessorFunc(viewChangeMsgID, s.handleViewChangeReq)
 
+	n, err := atrest.SealNestedBuckets(s.stateChangeStorage.db, s.stateChangeStorage.bucket)
+	if err != nil {
+		return nil, xerrors.Errorf("sealing state changes: %v", err)
+	} else if n > 0 {
+		log.Lvlf1("Encrypted %d state changes", n)
+	}
+
 	if err := skipchain.RegisterVerification(c, Verify, s.verifySkipBlock); err != nil {
 		log.ErrFatal(err)
 	}
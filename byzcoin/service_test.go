@@ -20,9 +20,11 @@ import (
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/kyber/v3/sign/eddsa"
 	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -1808,6 +1810,38 @@ func TestService_SetConfigRosterReplace(t *testing.T) {
 	}
 }
 
+func TestService_RotateKey(t *testing.T) {
+	bArgs := defaultBCTArgs
+	bArgs.Nodes = 4
+	b := newBCTRun(t, &bArgs)
+	defer b.CloseAll()
+
+	kp := key.NewKeyPair(cothority.Suite)
+	tr, err := keyrotation.New(b.Servers[1].ServerIdentity.GetPrivate(),
+		kp.Private, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	rosters, err := b.Services[1].RotateKey(tr)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(rosters))
+
+	config, err := b.Services[0].LoadConfig(b.Genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Equal(t, len(b.Roster.List), len(config.Roster.List))
+	require.True(t, config.Roster.List[1].Public.Equal(kp.Public))
+	require.Equal(t, b.Roster.List[1].Address, config.Roster.List[1].Address)
+
+	log.Lvl1("The transition can't be applied twice")
+	_, err = b.Services[1].RotateKey(tr)
+	require.NoError(t, err)
+
+	log.Lvl1("The leader can't rotate its own key")
+	tr, err = keyrotation.New(b.Servers[0].ServerIdentity.GetPrivate(),
+		key.NewKeyPair(cothority.Suite).Private, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = b.Services[0].RotateKey(tr)
+	require.Error(t, err)
+}
+
 // Check consistency of the set of valid peers while replacing roster
 func TestService_CheckValidPeers(t *testing.T) {
 	b := newBCTRun(t, nil)
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"sync"
 
+	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/skipchain"
@@ -288,6 +290,11 @@ type stateTrie struct {
 // loadStateTrie loads an existing StateTrie, an error is returned if no trie
 // exists in db
 func loadStateTrie(db *bbolt.DB, bucket []byte) (*stateTrie, error) {
+	if n, err := atrest.SealBucket(db, bucket); err != nil {
+		return nil, xerrors.Errorf("sealing trie: %v", err)
+	} else if n > 0 {
+		log.Lvlf1("Encrypted %d nodes of the trie %x", n, bucket)
+	}
 	t, err := trie.LoadTrie(trie.NewDiskDB(db, bucket))
 	if err != nil {
 		return nil, xerrors.Errorf("loading trie: %v", err)
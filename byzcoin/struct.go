@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/protobuf"
@@ -382,6 +383,10 @@ func (s *stateChangeStorage) append(scs StateChanges, sb *skipchain.SkipBlock) e
 				return xerrors.Errorf("encoding: %v", err)
 			}
 
+			buf, err = atrest.Seal(atrest.Location(sb.SkipChainID(), key), buf)
+			if err != nil {
+				return xerrors.Errorf("sealing: %v", err)
+			}
 			err = b.Put(key, buf)
 			if err != nil {
 				return xerrors.Errorf("writing item: %v", err)
@@ -401,6 +406,17 @@ func (s *stateChangeStorage) append(scs StateChanges, sb *skipchain.SkipBlock) e
 	return cothority.ErrorOrNil(s.cleanByBlock(scs, sb), "cleaning")
 }
 
+// decodeEntry opens the value of the key in the bucket of the skipchain, if
+// it is sealed, and decodes it.
+func (s *stateChangeStorage) decodeEntry(sid skipchain.SkipBlockID, k, v []byte,
+	sce *StateChangeEntry) error {
+	buf, err := atrest.Open(atrest.Location(sid, k), v)
+	if err != nil {
+		return xerrors.Errorf("opening: %v", err)
+	}
+	return protobuf.Decode(buf, sce)
+}
+
 // This will return the list of state changes for the given instance
 func (s *stateChangeStorage) getAll(iid []byte, sid skipchain.SkipBlockID) (entries []StateChangeEntry, err error) {
 	s.Lock()
@@ -419,7 +435,7 @@ func (s *stateChangeStorage) getAll(iid []byte, sid skipchain.SkipBlockID) (entr
 		c := b.Cursor()
 		for k, v := c.Seek(iid); bytes.HasPrefix(k, iid); k, v = c.Next() {
 			var sce StateChangeEntry
-			err = protobuf.Decode(v, &sce)
+			err = s.decodeEntry(sid, k, v, &sce)
 			if err != nil {
 				return xerrors.Errorf("decoding: %v", err)
 			}
@@ -460,7 +476,7 @@ func (s *stateChangeStorage) getByVersion(iid []byte,
 		c := b.Cursor()
 		k, v := c.Seek(prefix)
 		if k != nil && bytes.HasPrefix(k, prefix) {
-			err := protobuf.Decode(v, &sce)
+			err := s.decodeEntry(sid, k, v, &sce)
 			if err != nil {
 				return xerrors.Errorf("decoding: %v", err)
 			}
@@ -495,7 +511,7 @@ func (s *stateChangeStorage) getByBlock(sid skipchain.SkipBlockID, idx int) (ent
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.HasSuffix(k, suffix.Bytes()) {
 				var sce StateChangeEntry
-				err = protobuf.Decode(v, &sce)
+				err = s.decodeEntry(sid, k, v, &sce)
 				if err != nil {
 					return xerrors.Errorf("decoding: %v", err)
 				}
@@ -534,7 +550,7 @@ func (s *stateChangeStorage) getLast(iid []byte, sid skipchain.SkipBlockID) (sce
 		k, v := c.Prev()
 
 		if bytes.HasPrefix(k, iid) {
-			err := protobuf.Decode(v, &sce)
+			err := s.decodeEntry(sid, k, v, &sce)
 			if err != nil {
 				return xerrors.Errorf("decoding: %v", err)
 			}
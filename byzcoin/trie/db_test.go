@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/atrest"
 	"golang.org/x/xerrors"
 )
 
@@ -101,3 +102,34 @@ func testMemAndDisk(t *testing.T, f func(*testing.T, DB)) {
 	defer delDiskDB(t, disk)
 	f(t, disk)
 }
+
+func TestDiskDB_OpenError(t *testing.T) {
+	defer atrest.SetKey(nil)
+	disk := newDiskDB(t)
+	defer delDiskDB(t, disk)
+
+	key1, err := atrest.GenerateSecret()
+	require.NoError(t, err)
+	k, err := atrest.NewKey(key1)
+	require.NoError(t, err)
+	atrest.SetKey(k)
+	require.NoError(t, disk.Update(func(b Bucket) error {
+		return b.Put([]byte("hello"), []byte("world"))
+	}))
+
+	// A value sealed with another key fails the transaction instead of
+	// being missing.
+	key2, err := atrest.GenerateSecret()
+	require.NoError(t, err)
+	k, err = atrest.NewKey(key2)
+	require.NoError(t, err)
+	atrest.SetKey(k)
+	err = disk.View(func(b Bucket) error {
+		if b.Get([]byte("hello")) != nil {
+			return xerrors.New("opened with the wrong key")
+		}
+		return nil
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "opening")
+}
@@ -1,6 +1,7 @@
 package trie
 
 import (
+	"go.dedis.ch/cothority/v3/atrest"
 	bbolt "go.etcd.io/bbolt"
 	"golang.org/x/xerrors"
 )
@@ -13,7 +14,8 @@ type diskDB struct {
 	bucket []byte
 }
 
-// NewDiskDB creates a new boltdb-backed database.
+// NewDiskDB creates a new boltdb-backed database. The values are sealed
+// with the key of the atrest package, if it is set.
 func NewDiskDB(db *bbolt.DB, bucket []byte) DB {
 	disk := diskDB{
 		db:     db,
@@ -24,21 +26,13 @@ func NewDiskDB(db *bbolt.DB, bucket []byte) DB {
 
 func (r *diskDB) Update(f func(Bucket) error) error {
 	return r.db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(r.bucket)
-		if b == nil {
-			return xerrors.New("bucket does not exist")
-		}
-		return f(&diskBucket{b})
+		return r.run(tx, f)
 	})
 }
 
 func (r *diskDB) View(f func(Bucket) error) error {
 	return r.db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(r.bucket)
-		if b == nil {
-			return xerrors.New("bucket does not exist")
-		}
-		return f(&diskBucket{b})
+		return r.run(tx, f)
 	})
 }
 
@@ -48,11 +42,7 @@ func (r *diskDB) View(f func(Bucket) error) error {
 // used after doing the dry-run, they should be copied.
 func (r *diskDB) UpdateDryRun(f func(Bucket) error) error {
 	err := r.db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(r.bucket)
-		if b == nil {
-			return xerrors.New("bucket does not exist")
-		}
-		if err := f(&diskBucket{b}); err != nil {
+		if err := r.run(tx, f); err != nil {
 			return err
 		}
 		return errDryRun
@@ -63,12 +53,31 @@ func (r *diskDB) UpdateDryRun(f func(Bucket) error) error {
 	return nil
 }
 
+// run calls f with the bucket of the transaction. If a value of the bucket
+// couldn't be opened, its error is returned instead of the one of f, as f
+// only saw a missing value.
+func (r *diskDB) run(tx *bbolt.Tx, f func(Bucket) error) error {
+	b := tx.Bucket(r.bucket)
+	if b == nil {
+		return xerrors.New("bucket does not exist")
+	}
+	db := &diskBucket{b: b, name: r.bucket}
+	err := f(db)
+	if db.err != nil {
+		return db.err
+	}
+	return err
+}
+
 func (r *diskDB) Close() error {
 	return r.db.Close()
 }
 
 type diskBucket struct {
-	b *bbolt.Bucket
+	b    *bbolt.Bucket
+	name []byte
+	// err is the first error of Get, returned by the transaction.
+	err error
 }
 
 func (r *diskBucket) Delete(k []byte) error {
@@ -76,13 +85,28 @@ func (r *diskBucket) Delete(k []byte) error {
 }
 
 func (r *diskBucket) Put(k, v []byte) error {
-	return r.b.Put(k, v)
+	return atrest.Put(r.b, r.name, k, v)
 }
 
+// Get returns nil if the value can't be opened, as the interface has no
+// error, and keeps the error so that the transaction fails with it.
 func (r *diskBucket) Get(k []byte) []byte {
-	return r.b.Get(k)
+	v, err := atrest.Get(r.b, r.name, k)
+	if err != nil {
+		if r.err == nil {
+			r.err = xerrors.Errorf("opening %x: %v", k, err)
+		}
+		return nil
+	}
+	return v
 }
 
 func (r *diskBucket) ForEach(f func(k, v []byte) error) error {
-	return r.b.ForEach(f)
+	return r.b.ForEach(func(k, v []byte) error {
+		v, err := atrest.Open(atrest.Location(r.name, k), v)
+		if err != nil {
+			return xerrors.Errorf("opening %x: %v", k, err)
+		}
+		return f(k, v)
+	})
 }
@@ -1,9 +1,7 @@
 package trie
 
 import (
-	"crypto/sha256"
-	"encoding/binary"
-
+	"go.dedis.ch/cothority/v3/chainverify"
 	"go.dedis.ch/protobuf"
 	"golang.org/x/xerrors"
 )
@@ -17,10 +15,7 @@ const (
 )
 
 func (n *interiorNode) hash() []byte {
-	h := sha256.New()
-	h.Write(n.Left)
-	h.Write(n.Right)
-	return h.Sum(nil)
+	return (*chainverify.InteriorNode)(n).Hash()
 }
 
 func (n *interiorNode) encode() ([]byte, error) {
@@ -60,15 +55,7 @@ func newEmptyNode(prefix []bool) emptyNode {
 }
 
 func (n *emptyNode) hash(nonce []byte) []byte {
-	h := sha256.New()
-	h.Write([]byte{byte(typeEmpty)})
-	h.Write(nonce)
-	h.Write(toByteSlice(n.Prefix))
-
-	lBuf := make([]byte, 4)
-	binary.LittleEndian.PutUint32(lBuf, uint32(len(n.Prefix)))
-	h.Write(lBuf)
-	return h.Sum(nil)
+	return (*chainverify.EmptyNode)(n).Hash(nonce)
 }
 
 func (n *emptyNode) encode() ([]byte, error) {
@@ -95,18 +82,7 @@ func decodeEmptyNode(buf []byte) (node emptyNode, err error) {
 }
 
 func (n *leafNode) hash(nonce []byte) []byte {
-	h := sha256.New()
-	h.Write([]byte{byte(typeLeaf)})
-	h.Write(nonce)
-	h.Write(toByteSlice(n.Prefix))
-
-	lBuf := make([]byte, 4)
-	binary.LittleEndian.PutUint32(lBuf, uint32(len(n.Prefix)))
-	h.Write(lBuf)
-
-	h.Write(n.Key)
-	h.Write(n.Value)
-	return h.Sum(nil)
+	return (*chainverify.LeafNode)(n).Hash(nonce)
 }
 
 func newLeafNode(prefix []bool, key []byte, value []byte) leafNode {
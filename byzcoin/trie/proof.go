@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 
+	"go.dedis.ch/cothority/v3/chainverify"
 	"golang.org/x/xerrors"
 )
 
@@ -26,37 +27,17 @@ func (p *Proof) Exists(key []byte) (bool, error) {
 		return false, xerrors.New("key is nil")
 	}
 
-	if len(p.Interiors) == 0 {
-		return false, xerrors.New("no interior nodes")
+	interiors := make([]chainverify.InteriorNode, len(p.Interiors))
+	for i, n := range p.Interiors {
+		interiors[i] = chainverify.InteriorNode(n)
 	}
-
-	bits := p.binSlice(key)
-	expectedHash := p.Interiors[0].hash() // first one is the root hash
-
-	var i int
-	for i = range p.Interiors {
-		if !bytes.Equal(expectedHash, p.Interiors[i].hash()) {
-			return false, xerrors.New("invalid hash chain")
-		}
-		if bits[i] {
-			expectedHash = p.Interiors[i].Left
-		} else {
-			expectedHash = p.Interiors[i].Right
-		}
-	}
-	if bytes.Equal(expectedHash, p.Leaf.hash(p.Nonce)) {
-		if !equal(bits[:i+1], p.Leaf.Prefix) {
-			return false, xerrors.New("invalid prefix in leaf node")
-		}
-		return bytes.Equal(p.Leaf.Key, key), nil
-	} else if bytes.Equal(expectedHash, p.Empty.hash(p.Nonce)) {
-		if !equal(bits[:i+1], p.Empty.Prefix) {
-			return false, xerrors.New("invalid prefix in empty node")
-		}
-		return false, nil
-	} else {
-		return false, xerrors.New("invalid edge node")
+	cp := chainverify.TrieProof{
+		Interiors: interiors,
+		Leaf:      chainverify.LeafNode(p.Leaf),
+		Empty:     chainverify.EmptyNode(p.Empty),
+		Nonce:     p.Nonce,
 	}
+	return cp.ExistsBits(key, p.binSlice(key))
 }
 
 // Match returns true if the proof is an existence proof for the given key, any
@@ -158,15 +139,3 @@ func (p *Proof) binSlice(buf []byte) []bool {
 	hashKey := sha256.Sum256(buf)
 	return toBinSlice(hashKey[:])
 }
-
-func equal(a []bool, b []bool) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
-}
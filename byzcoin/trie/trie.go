@@ -662,16 +662,6 @@ func toBinSlice(buf []byte) []bool {
 	return bits
 }
 
-func toByteSlice(bits []bool) []byte {
-	buf := make([]byte, (len(bits)+7)/8)
-	for i := 0; i < len(bits); i++ {
-		if bits[i] {
-			buf[i/8] |= (1 << 7) >> uint(i%8)
-		}
-	}
-	return buf
-}
-
 func clone(buf []byte) []byte {
 	if buf == nil {
 		return nil
@@ -10,7 +10,9 @@ import (
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/lightclient"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/transport"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
@@ -44,6 +46,13 @@ func NewClient(byzcoin *byzcoin.Client) *Client {
 		cothority.Suite, ServiceName)}
 }
 
+// UseTransport sends the requests of the client and of its ByzCoin client
+// through the transport. The DecryptKey requests fail over to the other nodes
+// of the ByzCoin roster, starting with the leader.
+func (c *Client) UseTransport(t *transport.Transport) {
+	c.bcClient.UseTransport(t)
+}
+
 // CreateLTS creates a random LTSID that can be used to reference the LTS group
 // created. It first sends a transaction to ByzCoin to spawn a LTS instance,
 // then it asks the Calypso cothority to start the DKG.
@@ -137,10 +146,74 @@ func (c *Client) Authorize(who *network.ServerIdentity, what skipchain.SkipBlock
 // given the public key information of the reader.
 func (c *Client) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error) {
 	reply = &DecryptKeyReply{}
+	if t := c.bcClient.Transport(); t != nil {
+		_, err = t.Send(c.c, transport.Order(c.bcClient.Roster.List,
+			&onet.ParallelOptions{DontShuffle: true}), dkr, reply)
+		return reply, cothority.ErrorOrNil(err, "sending DecryptKey message")
+	}
 	err = c.c.SendProtobuf(c.bcClient.Roster.List[0], dkr, reply)
 	return reply, cothority.ErrorOrNil(err, "sending DecryptKey message")
 }
 
+// DKGStatus asks every node of the roster for the progress of the DKG of the
+// LTS. The replies are in the order of the roster, with a nil reply and an
+// error for the nodes that couldn't be reached.
+func (c *Client) DKGStatus(r *onet.Roster, id byzcoin.InstanceID) ([]*DKGStatusReply, []error) {
+	replies := make([]*DKGStatusReply, len(r.List))
+	errs := make([]error, len(r.List))
+	for i, si := range r.List {
+		reply := &DKGStatusReply{}
+		err := c.c.SendProtobuf(si, &DKGStatus{LTSID: id}, reply)
+		if err != nil {
+			errs[i] = xerrors.Errorf("sending DKGStatus to %v: %v", si, err)
+			continue
+		}
+		replies[i] = reply
+	}
+	return replies, errs
+}
+
+// GetLTSCommits returns the public commitments of the distributed key of the
+// LTS as stored by the given node.
+func (c *Client) GetLTSCommits(si *network.ServerIdentity, id byzcoin.InstanceID) (*GetLTSCommitsReply, error) {
+	reply := &GetLTSCommitsReply{}
+	err := c.c.SendProtobuf(si, &GetLTSCommits{LTSID: id}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("sending GetLTSCommits message: %v", err)
+	}
+	return reply, nil
+}
+
+// RecoverLTS asks the node to get back its share of the LTS from the other
+// nodes of the LTS. It is only allowed on loopback, except if
+// COTHORITY_ALLOW_INSECURE_ADMIN is set.
+func (c *Client) RecoverLTS(si *network.ServerIdentity, proof *byzcoin.Proof) (*RecoverLTSReply, error) {
+	reply := &RecoverLTSReply{}
+	err := c.c.SendProtobuf(si, &RecoverLTS{Proof: *proof}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("sending RecoverLTS message: %v", err)
+	}
+	return reply, nil
+}
+
+// VerifyShare asks every node of the roster to check its share of the LTS
+// against the commitments. The replies are in the order of the roster, with
+// a nil reply and an error for the nodes that couldn't be reached.
+func (c *Client) VerifyShare(r *onet.Roster, id byzcoin.InstanceID, commits []kyber.Point) ([]*VerifyShareReply, []error) {
+	replies := make([]*VerifyShareReply, len(r.List))
+	errs := make([]error, len(r.List))
+	for i, si := range r.List {
+		reply := &VerifyShareReply{}
+		err := c.c.SendProtobuf(si, &VerifyShare{LTSID: id, Commits: commits}, reply)
+		if err != nil {
+			errs[i] = xerrors.Errorf("sending VerifyShare to %v: %v", si, err)
+			continue
+		}
+		replies[i] = reply
+	}
+	return replies, errs
+}
+
 // WaitProof calls the byzcoin client's wait proof
 func (c *Client) WaitProof(id byzcoin.InstanceID, interval time.Duration,
 	value []byte) (*byzcoin.Proof, error) {
@@ -291,16 +364,9 @@ func (c *Client) SpawnDarc(signer darc.Signer, signerCtr uint64,
 //   - key - the re-assembled key
 //   - err - a possible error when trying to recover the data from the point
 func (r *DecryptKeyReply) RecoverKey(xc kyber.Scalar) (key []byte, err error) {
-	xcInv := xc.Clone().Neg(xc)
-	XhatDec := r.X.Clone().Mul(xcInv, r.X)
-	Xhat := XhatDec.Clone().Add(r.XhatEnc, XhatDec)
-	XhatInv := Xhat.Clone().Neg(Xhat)
-
-	// Decrypt r.C to keyPointHat
-	XhatInv.Add(r.C, XhatInv)
-	key, err = XhatInv.Data()
+	key, err = lightclient.DecodeKey(r.X, r.C, r.XhatEnc, xc)
 	if err != nil {
-		err = xerrors.Errorf("extracting data from point: %v", err)
+		err = xerrors.Errorf("recovering key: %v", err)
 	}
 	return
 }
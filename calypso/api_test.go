@@ -2,6 +2,8 @@ package calypso
 
 import (
 	"encoding/binary"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -12,7 +14,9 @@ import (
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/cothority/v3/nat"
 	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
 )
 
 // Tests the client function CreateLTS
@@ -98,6 +102,52 @@ func TestClient_Authorize(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// The requests forwarded by the websocket front come from the loopback, but
+// must not be taken for the admin's.
+func TestClient_Authorize_Front(t *testing.T) {
+	defer func(old bool) { allowInsecureAdmin = old }(allowInsecureAdmin)
+	allowInsecureAdmin = false
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(3, true)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:dummy", "spawn:" + ContractLongTermSecretID},
+		signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 500 * time.Millisecond
+	c, _, err := byzcoin.NewLedger(msg, false)
+	require.NoError(t, err)
+
+	who := roster.List[0]
+	_, port, err := net.SplitHostPort(who.Address.NetworkAddress())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	f, err := nat.NewFront(&nat.WebSocketConfig{Listen: "127.0.0.1:0",
+		Prefix: "/conode"}, net.JoinHostPort("127.0.0.1", strconv.Itoa(p+1)),
+		false)
+	require.NoError(t, err)
+	defer f.Close()
+	front := &network.ServerIdentity{URL: "http://" + f.Addr().String() +
+		"/conode"}
+
+	ts := time.Now().Unix()
+	sigMsg := append(c.ID, make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(sigMsg[32:], uint64(ts))
+	sig, err := schnorr.Sign(cothority.Suite, who.GetPrivate(), sigMsg)
+	require.NoError(t, err)
+	auth := &Authorize{ByzCoinID: c.ID, Timestamp: ts, Signature: sig}
+
+	cl := NewClient(nil)
+	err = cl.c.SendProtobuf(front, auth, &AuthorizeReply{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only allowed on loopback")
+
+	require.NoError(t, cl.c.SendProtobuf(who, auth, &AuthorizeReply{}))
+}
+
 // TODO(jallen): Write TestClient_Reshare (and add api.go part too, I guess)
 
 // Tests the client api's AddRead, AddWrite, DecryptKey
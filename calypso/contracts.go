@@ -7,6 +7,7 @@ import (
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
@@ -224,8 +225,12 @@ func (c *contractLTS) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruc
 		return nil, nil, xerrors.Errorf("getting values: %v", err)
 	}
 
-	if inst.Invoke.Command != "reshare" {
-		return nil, nil, xerrors.New("can only reshare long-term secrets")
+	switch inst.Invoke.Command {
+	case "reshare":
+	case "rotate_key":
+		return c.rotateKey(rst, inst, curBuf, darcID, coins)
+	default:
+		return nil, nil, xerrors.New("can only reshare long-term secrets or rotate a key")
 	}
 	infoBuf := inst.Invoke.Args.Search("lts_instance_info")
 	if infoBuf == nil || len(infoBuf) == 0 {
@@ -254,6 +259,48 @@ func (c *contractLTS) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruc
 	return byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractLongTermSecretID, infoBuf, darcID)}, coins, nil
 }
 
+// VerifyInstruction skips the darc of the instance for the rotate_key
+// command, which is signed by the keys of the transition.
+func (c *contractLTS) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	if inst.GetType() == byzcoin.InvokeType && inst.Invoke.Command == "rotate_key" {
+		return nil
+	}
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// rotateKey replaces the old key of the transition in the roster of the LTS,
+// keeping the node at the same place so that its share stays valid.
+func (c *contractLTS) rotateKey(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, curBuf []byte, darcID darc.ID, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+	if rst.GetVersion() < byzcoin.VersionKeyRotation {
+		return nil, nil, xerrors.New("key rotation is not supported by this version of the chain")
+	}
+	var t keyrotation.Transition
+	err := protobuf.DecodeWithConstructors(inst.Invoke.Args.Search("transition"), &t, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("passed transition argument is invalid: %v", err)
+	}
+	if err = t.Verify(); err != nil {
+		return nil, nil, xerrors.Errorf("invalid transition: %v", err)
+	}
+
+	var info LtsInstanceInfo
+	err = protobuf.DecodeWithConstructors(curBuf, &info, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("current info is invalid: %v", err)
+	}
+	roster := t.Replace(&info.Roster)
+	if roster == nil {
+		return nil, nil, xerrors.New("old key is not in the roster, or the new one already is")
+	}
+	info.Roster = *roster
+	infoBuf, err := protobuf.Encode(&info)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("encoding info: %v", err)
+	}
+
+	return byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractLongTermSecretID, infoBuf, darcID)}, coins, nil
+}
+
 func intersectRosters(r1, r2 *onet.Roster) int {
 	res := 0
 	for _, x := range r2.List {
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3/log"
+	"golang.org/x/xerrors"
+)
+
+// documentKeyLen is the length of the AES key of a document, which must fit
+// in the point of a write instance.
+const documentKeyLen = 16
+
+// documentWrite encrypts a file with a fresh symmetric key, stores the
+// encrypted file in the data of a new write instance and the key encrypted
+// under the LTS public key. It prints the instance id of the write instance,
+// or sends it to STDOUT with the --export option.
+func documentWrite(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the file to write")
+	}
+	doc, err := ioutil.ReadFile(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("failed to read document: %v", err)
+	}
+
+	cfg, cl, signer, err := loadDocumentSigner(c)
+	if err != nil {
+		return err
+	}
+
+	dstr := c.String("darc")
+	if dstr == "" {
+		dstr = cfg.AdminDarc.GetIdentityString()
+	}
+	d, err := lib.GetDarcByString(cl, dstr)
+	if err != nil {
+		return err
+	}
+
+	instid, X, err := documentLTS(c, cfg)
+	if err != nil {
+		return err
+	}
+
+	key, data, err := encryptDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	write := calypso.NewWrite(cothority.Suite, byzcoin.NewInstanceID(instid),
+		d.GetBaseID(), X, key)
+	if write == nil {
+		return xerrors.New("got a nil write, this is due to a key that is " +
+			"too long to be embeded")
+	}
+	write.Data = data
+	write.ExtraData = []byte(c.String("extraData"))
+
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return xerrors.Errorf("getting signer counters: %v", err)
+	}
+
+	reply, err := calypso.NewClient(cl).AddWrite(write, *signer,
+		counters.Counters[0]+1, *d, 10)
+	if err != nil {
+		return xerrors.Errorf("failed to add write: %v", err)
+	}
+
+	err = lib.WaitPropagation(c, cl)
+	if err != nil {
+		return xerrors.Errorf("waiting for block propagation: %v", err)
+	}
+
+	iidStr := hex.EncodeToString(reply.InstanceID.Slice())
+	if c.Bool("export") {
+		_, err = io.WriteString(os.Stdout, iidStr)
+		if err != nil {
+			return xerrors.Errorf("failed to copy to stdout: %v", err)
+		}
+		return nil
+	}
+
+	log.Infof("Wrote an encrypted document of %d bytes. "+
+		"Its write instance id is:\n%s", len(doc), iidStr)
+
+	return nil
+}
+
+// documentRead spawns a read instance of a write instance for the signer,
+// asks the LTS to re-encrypt the key of the document to the signer, and
+// decrypts the document locally. The document is sent to STDOUT, or written
+// to the file given by --out.
+func documentRead(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the instance id of the write instance")
+	}
+	writeID, err := hex.DecodeString(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("failed to decode write instance id: %v", err)
+	}
+
+	_, cl, signer, err := loadDocumentSigner(c)
+	if err != nil {
+		return err
+	}
+
+	// needed to get the block interval for WaitProof
+	chainConfig, err := cl.GetChainConfig()
+	if err != nil {
+		return xerrors.Errorf("failed to get chain config: %v", err)
+	}
+	interval := chainConfig.BlockInterval * 10
+
+	ccl := calypso.NewClient(cl)
+	writeProof, err := ccl.WaitProof(byzcoin.NewInstanceID(writeID),
+		interval, nil)
+	if err != nil {
+		return xerrors.Errorf("couldn't get write proof: %v", err)
+	}
+	var write calypso.Write
+	err = writeProof.VerifyAndDecode(cothority.Suite, calypso.ContractWriteID,
+		&write)
+	if err != nil {
+		return xerrors.Errorf("didn't get a write instance: %v", err)
+	}
+
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return xerrors.Errorf("failed to get the signer counters: %v", err)
+	}
+
+	read, err := ccl.AddRead(writeProof, *signer, counters.Counters[0]+1, 10)
+	if err != nil {
+		return xerrors.Errorf("failed to add read: %v", err)
+	}
+	readProof, err := ccl.WaitProof(read.InstanceID, interval, nil)
+	if err != nil {
+		return xerrors.Errorf("couldn't get read proof: %v", err)
+	}
+
+	dkr, err := ccl.DecryptKey(&calypso.DecryptKey{Write: *writeProof,
+		Read: *readProof})
+	if err != nil {
+		return xerrors.Errorf("failed to get the re-encrypted key: %v", err)
+	}
+	log.Lvlf2("Re-encrypted the key with trace ID %s", dkr.TraceID)
+
+	xc, err := signer.GetPrivate()
+	if err != nil {
+		return xerrors.Errorf("failed to get private key: %v", err)
+	}
+	key, err := dkr.RecoverKey(xc)
+	if err != nil {
+		return xerrors.Errorf("failed to recover the key: %v", err)
+	}
+
+	doc, err := decryptDocument(key, write.Data)
+	if err != nil {
+		return err
+	}
+
+	out := c.String("out")
+	if out == "" {
+		_, err = os.Stdout.Write(doc)
+		if err != nil {
+			return xerrors.Errorf("failed to copy to stdout: %v", err)
+		}
+		return nil
+	}
+
+	err = ioutil.WriteFile(out, doc, 0600)
+	if err != nil {
+		return xerrors.Errorf("failed to write document: %v", err)
+	}
+	log.Infof("Decrypted the document to %s", out)
+
+	return nil
+}
+
+// documentLTS returns the instance ID and the public key of the LTS given by
+// its descriptor with --lts, or by --instid and --key.
+func documentLTS(c *cli.Context, cfg lib.Config) ([]byte, kyber.Point,
+	error) {
+	if ltsArg := c.String("lts"); ltsArg != "" {
+		desc, err := loadLTSDescriptor(ltsArg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !desc.ByzCoinID.Equal(cfg.ByzCoinID) {
+			return nil, nil, xerrors.Errorf("LTS is for ByzCoin %x",
+				desc.ByzCoinID)
+		}
+		return desc.InstanceID.Slice(), desc.X, nil
+	}
+
+	instidstr := c.String("instid")
+	if instidstr == "" {
+		return nil, nil, xerrors.New("please provide the LTS instance ID " +
+			"with --instid, or its descriptor with --lts")
+	}
+	instid, err := hex.DecodeString(instidstr)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode instance id: %v", err)
+	}
+
+	keyStr := c.String("key")
+	if keyStr == "" {
+		return nil, nil, xerrors.New("please provide the hex string public " +
+			"key with --key")
+	}
+	keyBuf, err := hex.DecodeString(keyStr)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode hex string key: %v",
+			err)
+	}
+	X := cothority.Suite.Point()
+	err = X.UnmarshalBinary(keyBuf)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to unmarshal key: %v", err)
+	}
+	return instid, X, nil
+}
+
+// loadDocumentSigner returns the ByzCoin config and client of the --bc config,
+// and the signer given by --sign, which is the admin by default.
+func loadDocumentSigner(c *cli.Context) (lib.Config, *byzcoin.Client,
+	*darc.Signer, error) {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return lib.Config{}, nil, nil, xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return lib.Config{}, nil, nil,
+			xerrors.Errorf("loading configuration: %v", err)
+	}
+
+	var signer *darc.Signer
+	sstr := c.String("sign")
+	if sstr == "" {
+		signer, err = lib.LoadKey(cfg.AdminIdentity)
+	} else {
+		signer, err = lib.LoadKeyFromString(sstr)
+	}
+	if err != nil {
+		return lib.Config{}, nil, nil,
+			xerrors.Errorf("failed to parse the signer: %v", err)
+	}
+
+	return cfg, cl, signer, nil
+}
+
+// encryptDocument encrypts a document with AES-GCM under a random key, and
+// returns the key and the nonce followed by the ciphertext.
+func encryptDocument(doc []byte) ([]byte, []byte, error) {
+	key := make([]byte, documentKeyLen)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to pick a key: %v", err)
+	}
+
+	aead, err := newDocumentCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to pick a nonce: %v", err)
+	}
+
+	return key, aead.Seal(nonce, nonce, doc, nil), nil
+}
+
+// decryptDocument is the inverse of encryptDocument.
+func decryptDocument(key, data []byte) ([]byte, error) {
+	aead, err := newDocumentCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, xerrors.New("encrypted document is too short")
+	}
+
+	nonce := data[:aead.NonceSize()]
+	doc, err := aead.Open(nil, nonce, data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt document: %v", err)
+	}
+	return doc, nil
+}
+
+func newDocumentCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create GCM: %v", err)
+	}
+	return aead, nil
+}
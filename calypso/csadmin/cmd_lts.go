@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ltsDescriptor holds everything needed to use an LTS, signed by the
+// identity that exported it, so that it can be shared between workstations.
+type ltsDescriptor struct {
+	InstanceID byzcoin.InstanceID
+	ByzCoinID  skipchain.SkipBlockID
+	// Roster is the roster of the LTS, which can differ from the one of
+	// ByzCoin.
+	Roster onet.Roster
+	// X is the public key of the LTS.
+	X kyber.Point
+	// Threshold is the number of nodes needed to re-encrypt a secret.
+	Threshold int
+	Signer    darc.Identity
+	Signature []byte
+}
+
+// hash returns the hash of the descriptor without its signature.
+func (d ltsDescriptor) hash() ([]byte, error) {
+	d.Signature = nil
+	buf, err := protobuf.Encode(&d)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode descriptor: %v", err)
+	}
+	h := sha256.Sum256(buf)
+	return h[:], nil
+}
+
+// ltsExport writes the descriptor of an LTS instance to a file, signed by
+// --sign. The public key and the threshold are given by the commitments of
+// its first node.
+func ltsExport(c *cli.Context) error {
+	out := c.String("out")
+	if out == "" {
+		return xerrors.New("--out flag is required")
+	}
+
+	cfg, cl, signer, err := loadDocumentSigner(c)
+	if err != nil {
+		return err
+	}
+	_, instid, ltsInfo, err := loadLTSInfo(c)
+	if err != nil {
+		return err
+	}
+
+	commits, err := calypso.NewClient(cl).GetLTSCommits(ltsInfo.Roster.List[0],
+		instid)
+	if err != nil {
+		return xerrors.Errorf("failed to get the commitments, did the DKG "+
+			"run? %v", err)
+	}
+	if len(commits.Commits) == 0 {
+		return xerrors.New("got no commitments")
+	}
+
+	desc := ltsDescriptor{
+		InstanceID: instid,
+		ByzCoinID:  cfg.ByzCoinID,
+		Roster:     ltsInfo.Roster,
+		X:          commits.Commits[0],
+		Threshold:  len(commits.Commits),
+		Signer:     signer.Identity(),
+	}
+	h, err := desc.hash()
+	if err != nil {
+		return err
+	}
+	desc.Signature, err = signer.Sign(h)
+	if err != nil {
+		return xerrors.Errorf("failed to sign descriptor: %v", err)
+	}
+
+	buf, err := protobuf.Encode(&desc)
+	if err != nil {
+		return xerrors.Errorf("failed to encode descriptor: %v", err)
+	}
+	err = ioutil.WriteFile(out, buf, 0644)
+	if err != nil {
+		return xerrors.Errorf("failed to write descriptor: %v", err)
+	}
+	log.Infof("Wrote the descriptor of LTS %x to %s", instid.Slice(), out)
+
+	return nil
+}
+
+// ltsImport checks the signature of a descriptor, by the identity given by
+// --signer, and, with --bc, that it matches the LTS instance and the
+// commitments of its nodes. As the descriptor can't vouch for itself, one of
+// them is required. The descriptor is then stored in the config folder, so
+// that `document write --lts` can use it.
+func ltsImport(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the file of the descriptor")
+	}
+	signer, bcArg := c.String("signer"), c.String("bc")
+	if signer == "" && bcArg == "" {
+		return xerrors.New("--signer or --bc is required to trust the " +
+			"descriptor")
+	}
+	desc, err := readLTSDescriptor(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if signer != "" && signer != desc.Signer.String() {
+		return xerrors.Errorf("descriptor is signed by %s", desc.Signer)
+	}
+
+	if bcArg != "" {
+		err = checkLTSDescriptor(bcArg, desc)
+		if err != nil {
+			return err
+		}
+	}
+
+	buf, err := protobuf.Encode(desc)
+	if err != nil {
+		return xerrors.Errorf("failed to encode descriptor: %v", err)
+	}
+	fn := ltsDescriptorPath(desc.InstanceID)
+	err = ioutil.WriteFile(fn, buf, 0644)
+	if err != nil {
+		return xerrors.Errorf("failed to save descriptor: %v", err)
+	}
+
+	xBuf, err := desc.X.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal X: %v", err)
+	}
+	log.Infof("Imported the LTS to %s:\n"+
+		"- ByzcoinID: %x\n- InstanceID: %x\n- X: %x\n- Threshold: %d of %d\n"+
+		"- Signer: %s", fn, desc.ByzCoinID, desc.InstanceID.Slice(), xBuf,
+		desc.Threshold, len(desc.Roster.List), desc.Signer)
+
+	return nil
+}
+
+// readLTSDescriptor reads a descriptor and verifies its signature.
+func readLTSDescriptor(fn string) (*ltsDescriptor, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read descriptor: %v", err)
+	}
+	var desc ltsDescriptor
+	err = protobuf.DecodeWithConstructors(buf, &desc,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode descriptor: %v", err)
+	}
+	if desc.X == nil || len(desc.Roster.List) == 0 || desc.Threshold <= 0 {
+		return nil, xerrors.New("incomplete descriptor")
+	}
+
+	h, err := desc.hash()
+	if err != nil {
+		return nil, err
+	}
+	err = desc.Signer.Verify(h, desc.Signature)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid signature of the descriptor: %v",
+			err)
+	}
+	return &desc, nil
+}
+
+// checkLTSDescriptor returns an error if the descriptor doesn't match the
+// chain of the ByzCoin config or the nodes of the LTS.
+func checkLTSDescriptor(bcArg string, desc *ltsDescriptor) error {
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return xerrors.Errorf("failed to load config: %v", err)
+	}
+	if !cfg.ByzCoinID.Equal(desc.ByzCoinID) {
+		return xerrors.Errorf("descriptor is for ByzCoin %x", desc.ByzCoinID)
+	}
+
+	resp, err := cl.GetProof(desc.InstanceID.Slice())
+	if err != nil {
+		return xerrors.Errorf("failed to get proof: %v", err)
+	}
+	err = resp.Proof.Verify(cfg.ByzCoinID)
+	if err != nil {
+		return xerrors.Errorf("invalid proof of the LTS instance: %v", err)
+	}
+	var ltsInfo calypso.LtsInstanceInfo
+	err = resp.Proof.VerifyAndDecode(cothority.Suite,
+		calypso.ContractLongTermSecretID, &ltsInfo)
+	if err != nil {
+		return xerrors.Errorf("failed to get the LTS instance: %v", err)
+	}
+	// The ID of a decoded roster isn't computed from its nodes, so the
+	// nodes are compared.
+	if !sameNodes(&ltsInfo.Roster, &desc.Roster) {
+		return xerrors.New("roster of the LTS instance changed")
+	}
+
+	commits, err := calypso.NewClient(cl).GetLTSCommits(ltsInfo.Roster.List[0],
+		desc.InstanceID)
+	if err != nil {
+		return xerrors.Errorf("failed to get the commitments: %v", err)
+	}
+	if len(commits.Commits) != desc.Threshold ||
+		!commits.Commits[0].Equal(desc.X) {
+		return xerrors.New("public key of the LTS changed")
+	}
+	return nil
+}
+
+// sameNodes returns whether both rosters have the same nodes in the same
+// order.
+func sameNodes(a, b *onet.Roster) bool {
+	if len(a.List) != len(b.List) {
+		return false
+	}
+	for i, si := range a.List {
+		if !si.Public.Equal(b.List[i].Public) ||
+			si.Address != b.List[i].Address {
+			return false
+		}
+	}
+	return true
+}
+
+// loadLTSDescriptor reads the descriptor of an imported LTS, given by its
+// file or by its instance ID. A file must be the one that was imported.
+func loadLTSDescriptor(arg string) (*ltsDescriptor, error) {
+	if _, err := os.Stat(arg); err == nil {
+		desc, err := readLTSDescriptor(arg)
+		if err != nil {
+			return nil, err
+		}
+		imported, err := readLTSDescriptor(ltsDescriptorPath(desc.InstanceID))
+		if err != nil {
+			return nil, xerrors.Errorf("the descriptor must be imported "+
+				"first: %v", err)
+		}
+		if !bytes.Equal(imported.Signature, desc.Signature) {
+			return nil, xerrors.New("the descriptor isn't the imported one")
+		}
+		return imported, nil
+	}
+	id, err := hex.DecodeString(arg)
+	if err != nil {
+		return nil, xerrors.Errorf("%s is neither a file nor an instance ID",
+			arg)
+	}
+	return readLTSDescriptor(ltsDescriptorPath(byzcoin.NewInstanceID(id)))
+}
+
+// ltsDescriptorPath returns where the descriptor of an LTS is imported.
+func ltsDescriptorPath(id byzcoin.InstanceID) string {
+	return filepath.Join(lib.ConfigPath,
+		"lts-"+hex.EncodeToString(id.Slice())+".cfg")
+}
@@ -53,6 +53,38 @@ var cmds = cli.Commands{
 					},
 				},
 			},
+			{
+				Name:   "status",
+				Usage:  "prints the progress of the DKG of an lts instance on every node",
+				Action: dkgStatus,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance id of the spawned LTS contract",
+					},
+				},
+			},
+			{
+				Name:   "verify",
+				Usage:  "checks the shares of an lts instance on every node against its public commitments",
+				Action: dkgVerify,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance id of the spawned LTS contract",
+					},
+				},
+			},
 		},
 	},
 	{
@@ -99,6 +131,120 @@ var cmds = cli.Commands{
 			},
 		},
 	},
+	{
+		Name:  "lts",
+		Usage: "share the descriptor of an LTS between workstations",
+		Subcommands: cli.Commands{
+			{
+				Name:   "export",
+				Usage:  "write the signed descriptor of an LTS to a file",
+				Action: ltsExport,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance id of the spawned LTS contract",
+					},
+					cli.StringFlag{
+						Name:  "sign, s",
+						Usage: "public key of the signing entity (default is the admin public key)",
+					},
+					cli.StringFlag{
+						Name:  "out, o",
+						Usage: "the file to write the descriptor to (required)",
+					},
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "check the descriptor of an LTS and store it in the config folder",
+				ArgsUsage: "<file>",
+				Action:    ltsImport,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "signer",
+						Usage: "the identity that must have signed the descriptor (required without --bc)",
+					},
+					cli.StringFlag{
+						Name:  "bc",
+						Usage: "the ByzCoin config to check the descriptor against the chain and the LTS nodes (required without --signer)",
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:  "document",
+		Usage: "encrypt and decrypt documents end-to-end with calypso",
+		Subcommands: cli.Commands{
+			{
+				Name:      "write",
+				Usage:     "encrypt a file and store it in a new write instance",
+				ArgsUsage: "<file>",
+				Action:    documentWrite,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "darc",
+						Usage: "DARC with the right to spawn a write instance (default is the admin DARC)",
+					},
+					cli.StringFlag{
+						Name:  "sign, s",
+						Usage: "public key of the signing entity (default is the admin public key)",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance ID of the LTS (required without --lts)",
+					},
+					cli.StringFlag{
+						Name:  "key",
+						Usage: "the public key of the LTS, as printed by \"dkg start\" (required without --lts)",
+					},
+					cli.StringFlag{
+						Name:  "lts",
+						Usage: "the descriptor of the LTS, as a file or the instance ID of an imported LTS",
+					},
+					cli.StringFlag{
+						Name:  "extraData, ed",
+						Usage: "public data stored in clear with the document",
+					},
+					cli.BoolFlag{
+						Name:  "export, x",
+						Usage: "exports the instance id to STDOUT",
+					},
+				},
+			},
+			{
+				Name:      "read",
+				Usage:     "request the key of a write instance and decrypt its document",
+				ArgsUsage: "<write instance id>",
+				Action:    documentRead,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "sign, s",
+						Usage: "public key of the reader, which signs the read request and decrypts the document (default is the admin public key)",
+					},
+					cli.StringFlag{
+						Name:  "out, o",
+						Usage: "the file to write the document to (default is STDOUT)",
+					},
+				},
+			},
+		},
+	},
 	{
 		Name:  "contract",
 		Usage: "Provides cli interface for contracts",
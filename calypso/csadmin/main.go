@@ -229,6 +229,109 @@ func dkgInfo(c *cli.Context) error {
 	return nil
 }
 
+// dkgStatus - prints the progress of the DKG of the lts on every node of its
+// roster, so that a node stalling the DKG can be found.
+func dkgStatus(c *cli.Context) error {
+	cl, instid, ltsInfo, err := loadLTSInfo(c)
+	if err != nil {
+		return err
+	}
+
+	replies, errs := calypso.NewClient(cl).DKGStatus(&ltsInfo.Roster, instid)
+	for i, si := range ltsInfo.Roster.List {
+		if errs[i] != nil {
+			log.Infof("%s: %v", si.Address, errs[i])
+			continue
+		}
+		r := replies[i]
+		log.Infof("%s: phase %s, deals %d/%d received, %d sent, "+
+			"%d responses, faulty nodes %v", si.Address, r.Phase,
+			r.DealsProcessed, r.ExpectedDeals, r.DealsSent,
+			r.ResponsesProcessed, r.Faulty)
+	}
+	return nil
+}
+
+// dkgVerify - gets the public commitments of the lts from the first node of
+// its roster and asks every node to check its share against them. It returns
+// an error if a share is invalid or a node is unreachable.
+func dkgVerify(c *cli.Context) error {
+	cl, instid, ltsInfo, err := loadLTSInfo(c)
+	if err != nil {
+		return err
+	}
+
+	ccl := calypso.NewClient(cl)
+	commits, err := ccl.GetLTSCommits(ltsInfo.Roster.List[0], instid)
+	if err != nil {
+		return xerrors.New("failed to get the commitments: " + err.Error())
+	}
+
+	invalid := 0
+	replies, errs := ccl.VerifyShare(&ltsInfo.Roster, instid, commits.Commits)
+	for i, si := range ltsInfo.Roster.List {
+		if errs[i] != nil {
+			log.Infof("%s: %v", si.Address, errs[i])
+			invalid++
+			continue
+		}
+		r := replies[i]
+		if !r.Valid {
+			log.Infof("%s: share %d is invalid: %s", si.Address, r.Index, r.Error)
+			invalid++
+			continue
+		}
+		log.Infof("%s: share %d is valid", si.Address, r.Index)
+	}
+	if invalid > 0 {
+		return xerrors.Errorf("%d of %d shares couldn't be verified", invalid,
+			len(ltsInfo.Roster.List))
+	}
+	return nil
+}
+
+// loadLTSInfo returns the ByzCoin client of the --bc config and the roster
+// of the LTS instance given by --instid.
+func loadLTSInfo(c *cli.Context) (*byzcoin.Client, byzcoin.InstanceID, *calypso.LtsInstanceInfo, error) {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("--bc flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("failed to load config: " + err.Error())
+	}
+
+	instidstr := c.String("instid")
+	if instidstr == "" {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("please provide an LTS instance ID with --instid")
+	}
+
+	instid, err := hex.DecodeString(instidstr)
+	if err != nil {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("failed to decode LTS instance id: " + err.Error())
+	}
+
+	resp, err := cl.GetProof(instid)
+	if err != nil {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("failed to get proof: " + err.Error())
+	}
+	val, cid, _, err := resp.Proof.Get(instid)
+	if err != nil {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("couldn't get values: " + err.Error())
+	}
+	if cid != calypso.ContractLongTermSecretID {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("given instanceID is not from an LTS contract")
+	}
+	var ltsInfo calypso.LtsInstanceInfo
+	err = protobuf.Decode(val, &ltsInfo)
+	if err != nil {
+		return nil, byzcoin.InstanceID{}, nil, xerrors.New("couldn't decode info: " + err.Error())
+	}
+	return cl, byzcoin.NewInstanceID(instid), &ltsInfo, nil
+}
+
 // reencrypt decrypts the encrypted secret of a write instance and re-encrypts
 // it under the specified key of the write instance. If the proofs of the write
 // and read instances are correct, it then outputs a DecryptKeyReply. With the
@@ -34,6 +34,8 @@ main(){
     run testContractRead
     run testReencrypt
     run testDecrypt
+    run testDocument
+    run testLTSDescriptor
     stopTest
 }
 
@@ -118,6 +120,14 @@ testDkgStart(){
 
     # Check the --export option
     testGrep "[0-9a-f]{64}$" runCA dkg start --instid "$LTS_ID" -x
+
+    # every node reports the DKG as done
+    testCountLines 3 runCA dkg status --instid "$LTS_ID"
+    testGrep "phase certified" runCA dkg status --instid "$LTS_ID"
+
+    # every share matches the commitments
+    testOK runCA dkg verify --instid "$LTS_ID"
+    testCountLines 3 runCA dkg verify --instid "$LTS_ID"
 }
 
 # rely on:
@@ -305,3 +315,100 @@ runBA(){
 }
 
 main
+
+# Rely on:
+# - csadmin contract lts spawn
+# - csadmin authorize
+# - csadmin dkg start
+testDocument(){
+    rm -f config/*
+    runCoBG 1 2 3
+    runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+    eval $SED
+    [ -z "$BC" ] && exit 1
+
+    # Create a DARC
+    testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+    ID=`cat ./darc_id.txt`
+    KEY=`cat ./darc_key.txt`
+    testOK runBA darc rule -rule "spawn:longTermSecret" --darc $ID --sign $KEY --identity $KEY
+    testOK runBA darc rule -rule "spawn:calypsoWrite" -darc $ID -sign $KEY -identity $KEY
+    testOK runBA darc rule -rule "spawn:calypsoRead" -darc $ID -sign $KEY -identity $KEY
+
+    # Spawn LTS
+    OUTRES=`runCA0 contract lts spawn --darc "$ID" --sign "$KEY"`
+    LTS_ID=`echo "$OUTRES" | sed -n '2p'` # must be at the second line
+    matchOK $LTS_ID ^[0-9a-f]{64}$
+
+    # Authorize nodes
+    bcID=$( ls config/bc-* | sed -e "s/.*bc-\(.*\).cfg/\1/" )
+    testOK runCA authorize co1/private.toml $bcID
+    testOK runCA authorize co2/private.toml $bcID
+    testOK runCA authorize co3/private.toml $bcID
+
+    # Creat LTS and save the public key
+    runCA0 dkg start --instid "$LTS_ID" -x > key.pub
+    PUB_KEY=`cat key.pub`
+    matchOK $PUB_KEY ^[0-9a-f]{64}$
+
+    # A document bigger than the secret of a write instance
+    head -c 10000 /dev/urandom > document.bin
+    testFail runCA document write --darc "$ID" --sign "$KEY" document.bin
+    testFail runCA document write --darc "$ID" --sign "$KEY" \
+        --instid "$LTS_ID" --key "$PUB_KEY" missing.bin
+    WRITE_ID=`runCA0 document write --darc "$ID" --sign "$KEY" \
+        --instid "$LTS_ID" --key "$PUB_KEY" -x document.bin`
+    matchOK $WRITE_ID ^[0-9a-f]{64}$
+
+    testOK runCA document read --sign "$KEY" --out document.dec $WRITE_ID
+    testOK cmp document.bin document.dec
+    runCA0 document read --sign "$KEY" $WRITE_ID > document.out
+    testOK cmp document.bin document.out
+
+    # The admin is not allowed to spawn a read instance
+    testFail runCA document read $WRITE_ID
+}
+
+testLTSDescriptor(){
+    rm -f config/*
+    runCoBG 1 2 3
+    runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+    eval $SED
+    [ -z "$BC" ] && exit 1
+
+    testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+    ID=`cat ./darc_id.txt`
+    KEY=`cat ./darc_key.txt`
+    testOK runBA darc rule -rule "spawn:longTermSecret" --darc $ID --sign $KEY --identity $KEY
+    testOK runBA darc rule -rule "spawn:calypsoWrite" -darc $ID -sign $KEY -identity $KEY
+
+    OUTRES=`runCA0 contract lts spawn --darc "$ID" --sign "$KEY"`
+    LTS_ID=`echo "$OUTRES" | sed -n '2p'` # must be at the second line
+    matchOK $LTS_ID ^[0-9a-f]{64}$
+
+    bcID=$( ls config/bc-* | sed -e "s/.*bc-\(.*\).cfg/\1/" )
+    testOK runCA authorize co1/private.toml $bcID
+    testOK runCA authorize co2/private.toml $bcID
+    testOK runCA authorize co3/private.toml $bcID
+
+    # No descriptor before the DKG
+    testFail runCA lts export --instid "$LTS_ID" --sign "$KEY" --out lts.bin
+    runCA0 dkg start --instid "$LTS_ID" -x > key.pub
+    PUB_KEY=`cat key.pub`
+    testOK runCA lts export --instid "$LTS_ID" --sign "$KEY" --out lts.bin
+
+    testFail runCA lts import --signer "$ID" lts.bin
+    testGrep "Threshold: 3 of 3" runCA0 lts import --signer "$KEY" --bc "$BC" lts.bin
+    testFail runCA lts import lts.bin
+    testGrep "X: $PUB_KEY" runCA0 lts import --bc "$BC" lts.bin
+
+    # A tampered descriptor is refused
+    cp lts.bin lts_tampered.bin
+    printf '\x42' | dd of=lts_tampered.bin bs=1 seek=40 conv=notrunc 2> /dev/null
+    testFail runCA lts import --signer "$KEY" lts_tampered.bin
+
+    echo "shared" > document.txt
+    WRITE_ID=`runCA0 document write --darc "$ID" --sign "$KEY" --lts "$LTS_ID" \
+        -x document.txt`
+    matchOK $WRITE_ID ^[0-9a-f]{64}$
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
 	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
@@ -35,7 +36,12 @@ type storage struct {
 func (s *Service) save() error {
 	s.storage.Lock()
 	defer s.storage.Unlock()
-	err := s.Save(storageKey, s.storage)
+	return s.saveLocked()
+}
+
+// saveLocked is save for the callers holding the lock of the storage.
+func (s *Service) saveLocked() error {
+	err := atrest.Save(s, ServiceName, storageKey, s.storage)
 	if err != nil {
 		log.Error("Couldn't save data:", err)
 		return xerrors.Errorf("saving data: %v", err)
@@ -82,7 +88,7 @@ func (s *Service) tryLoad() error {
 		}
 		return cothority.ErrorOrNil(s.SaveVersion(dbVersion), "saving version")
 	}
-	msg, err := s.Load(storageKey)
+	msg, err := atrest.Load(s, ServiceName, storageKey)
 	if err != nil {
 		return xerrors.Errorf("loading storage: %v", err)
 	}
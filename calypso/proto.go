@@ -3,12 +3,14 @@ package calypso
 import (
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3"
 )
 
 // PROTOSTART
 // type :skipchain.SkipBlockID:bytes
+// type :tracing.ID:bytes
 // package calypso;
 // import "byzcoin.proto";
 // import "onet.proto";
@@ -128,6 +130,9 @@ type DecryptKey struct {
 	Read byzcoin.Proof
 	// Write is the proof containing the write request.
 	Write byzcoin.Proof
+	// TraceID identifies the request in the logs of the nodes. If it is
+	// empty, the node creates one.
+	TraceID tracing.ID `protobuf:"opt"`
 }
 
 // DecryptKeyReply is returned if the service verified successfully that the
@@ -139,6 +144,8 @@ type DecryptKeyReply struct {
 	XhatEnc kyber.Point
 	// X is the aggregate public key of the LTS used.
 	X kyber.Point
+	// TraceID identifies the request in the logs of the nodes.
+	TraceID tracing.ID `protobuf:"opt"`
 }
 
 // GetLTSReply asks for the shared public key of the corresponding LTSID
@@ -147,6 +154,78 @@ type GetLTSReply struct {
 	LTSID byzcoin.InstanceID
 }
 
+// DKGStatus asks a node for the progress of the DKG of an LTS.
+type DKGStatus struct {
+	// LTSID is the id of the LTS instance.
+	LTSID byzcoin.InstanceID
+}
+
+// DKGStatusReply is the progress of the last DKG of an LTS on a node.
+type DKGStatusReply struct {
+	// Phase is the name of the phase the node is at.
+	Phase              string
+	DealsSent          int
+	DealsProcessed     int
+	ExpectedDeals      int
+	ResponsesProcessed int
+	// Faulty holds the roster indexes of the nodes the DKG aborted because
+	// of.
+	Faulty []int
+	// Evidence is the protobuf encoding of the dkg Evidence of the faulty
+	// nodes, signed by the node, so that it can be stored or used to remove
+	// them from the roster.
+	// optional
+	Evidence []byte
+}
+
+// GetLTSCommits asks a node for the public commitments of the distributed
+// key of an LTS.
+type GetLTSCommits struct {
+	// LTSID is the id of the LTS instance.
+	LTSID byzcoin.InstanceID
+}
+
+// GetLTSCommitsReply holds the public commitments of the distributed key. The
+// first one is the public key of the LTS.
+type GetLTSCommitsReply struct {
+	Commits []kyber.Point
+	// Roster holds the nodes of the LTS in the order of their shares.
+	// optional
+	Roster *onet.Roster
+}
+
+// VerifyShare asks a node to check its share of an LTS against the given
+// commitments.
+type VerifyShare struct {
+	// LTSID is the id of the LTS instance.
+	LTSID byzcoin.InstanceID
+	// Commits are the public commitments of the distributed key, usually
+	// returned by GetLTSCommits. If empty, the ones of the node are used.
+	Commits []kyber.Point
+}
+
+// VerifyShareReply tells whether the share of the node is consistent with
+// the commitments.
+type VerifyShareReply struct {
+	// Index is the index of the share of the node.
+	Index int
+	Valid bool
+	// Error is the reason why the share is not valid.
+	Error string
+}
+
+// RecoverLTS asks a node that lost its share of an LTS to get it back from
+// the other nodes of the LTS.
+type RecoverLTS struct {
+	Proof byzcoin.Proof
+}
+
+// RecoverLTSReply is returned once the node got back its share.
+type RecoverLTSReply struct {
+	// Index is the index of the recovered share.
+	Index int
+}
+
 // LtsInstanceInfo is the information stored in an LTS instance.
 type LtsInstanceInfo struct {
 	Roster onet.Roster
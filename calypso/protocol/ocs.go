@@ -12,10 +12,10 @@ import (
 
 	"go.dedis.ch/cothority/v3"
 	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/onet/v3"
-	"go.dedis.ch/onet/v3/log"
 	"golang.org/x/xerrors"
 )
 
@@ -43,10 +43,16 @@ type OCS struct {
 	// or 'false' if not enough shares have been collected.
 	Reencrypted chan bool
 	Uis         []*share.PubShare // re-encrypted shares
+	// TraceID identifies the request in the logs of all nodes. A new one is
+	// created by Start if it is not set.
+	TraceID tracing.ID
+	// SpanID is the parent of the span of the protocol, if any.
+	SpanID tracing.SpanID
 	// private fields
 	replies  []ReencryptReply
 	timeout  *time.Timer
 	doneOnce sync.Once
+	span     *tracing.Span
 }
 
 // NewOCS initialises the structure for use in one round
@@ -66,7 +72,11 @@ func NewOCS(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
 
 // Start asks all children to reply with a shared reencryption
 func (o *OCS) Start() error {
-	log.Lvl3("Starting Protocol")
+	o.TraceID = tracing.OrNew(o.TraceID)
+	o.span = tracing.StartSpan(o.TraceID, o.SpanID, o.Name(), "nodes",
+		len(o.List()), "threshold", o.Threshold)
+	tlog := o.logger(o.TraceID)
+	tlog.Lvl3("starting protocol")
 	if o.Shared == nil {
 		o.finish(false)
 		return xerrors.New("please initialize Shared first")
@@ -76,8 +86,10 @@ func (o *OCS) Start() error {
 		return xerrors.New("please initialize U first")
 	}
 	rc := &Reencrypt{
-		U:  o.U,
-		Xc: o.Xc,
+		U:       o.U,
+		Xc:      o.Xc,
+		TraceID: o.TraceID,
+		SpanID:  o.span.ID(),
 	}
 	if len(o.VerificationData) > 0 {
 		rc.VerificationData = &o.VerificationData
@@ -89,12 +101,12 @@ func (o *OCS) Start() error {
 		}
 	}
 	o.timeout = time.AfterFunc(1*time.Minute, func() {
-		log.Lvl1("OCS protocol timeout")
+		tlog.Lvl1("OCS protocol timeout")
 		o.finish(false)
 	})
 	errs := o.Broadcast(rc)
 	if len(errs) > (len(o.Roster().List)-1)/3 {
-		log.Errorf("Some nodes failed with error(s) %v", errs)
+		tlog.Error("some nodes failed", "errors", errs)
 		return xerrors.New("too many nodes failed in broadcast")
 	}
 	return nil
@@ -103,14 +115,25 @@ func (o *OCS) Start() error {
 // Reencrypt is received by every node to give his part of
 // the share
 func (o *OCS) reencrypt(r structReencrypt) error {
-	log.Lvl3(o.Name() + ": starting reencrypt")
+	tlog := o.logger(r.TraceID)
+	tlog.Lvl3("starting reencrypt")
+	span := tracing.StartSpan(r.TraceID, r.SpanID, o.Name()+".reencrypt",
+		"node", o.ServerIdentity())
+	defer span.End(nil)
 	defer o.Done()
 
+	if err := r.Reencrypt.wellFormed(); err != nil {
+		tlog.Lvl2("refused a malformed request", "err", err)
+		span.SetAttributes("refused", true)
+		return cothority.ErrorOrNil(o.SendToParent(&ReencryptReply{}),
+			"sending ReencryptReply to parent")
+	}
 	ui := o.getUI(r.U, r.Xc)
 
 	if o.Verify != nil {
 		if !o.Verify(&r.Reencrypt) {
-			log.Lvl2(o.ServerIdentity(), "refused to reencrypt")
+			tlog.Lvl2("refused to reencrypt")
+			span.SetAttributes("refused", true)
 			return cothority.ErrorOrNil(o.SendToParent(&ReencryptReply{}),
 				"sending ReencryptReply to parent")
 		}
@@ -139,13 +162,19 @@ func (o *OCS) reencrypt(r structReencrypt) error {
 // reencryptReply is the root-node waiting for all replies and generating
 // the reencryption key.
 func (o *OCS) reencryptReply(rr structReencryptReply) error {
+	tlog := o.logger(o.TraceID)
+	span := o.span.Child(o.Name()+".reencryptReply", "from",
+		rr.ServerIdentity)
+	defer span.End(nil)
 	if rr.ReencryptReply.Ui == nil {
-		log.Lvl2("Node", rr.ServerIdentity, "refused to reply")
-		o.Failures++
-		if o.Failures > len(o.Roster().List)-o.Threshold {
-			log.Lvl2(rr.ServerIdentity, "couldn't get enough shares")
-			o.finish(false)
-		}
+		tlog.Lvl2("node refused to reply", "from", rr.ServerIdentity)
+		o.replyFailed(tlog, span)
+		return nil
+	}
+	if err := rr.ReencryptReply.wellFormed(len(o.List())); err != nil {
+		tlog.Lvl1("got a malformed reply", "from", rr.ServerIdentity,
+			"err", err)
+		o.replyFailed(tlog, span)
 		return nil
 	}
 	o.replies = append(o.replies, rr.ReencryptReply)
@@ -173,7 +202,7 @@ func (o *OCS) reencryptReply(rr structReencryptReply) error {
 			if e.Equal(r.Ei) {
 				o.Uis[r.Ui.I] = r.Ui
 			} else {
-				log.Lvl1("Received invalid share from node", r.Ui.I)
+				tlog.Lvl1("received invalid share", "index", r.Ui.I)
 			}
 		}
 		o.finish(true)
@@ -189,6 +218,23 @@ func (o *OCS) reencryptReply(rr structReencryptReply) error {
 	return nil
 }
 
+// replyFailed counts a node that didn't give its share, and gives up once
+// there are not enough nodes left to reach the threshold.
+func (o *OCS) replyFailed(tlog tracing.Logger, span *tracing.Span) {
+	span.SetAttributes("refused", true)
+	o.Failures++
+	if o.Failures > len(o.Roster().List)-o.Threshold {
+		tlog.Lvl2("couldn't get enough shares")
+		o.finish(false)
+	}
+}
+
+// logger returns the logger of the request with the given trace ID.
+func (o *OCS) logger(id tracing.ID) tracing.Logger {
+	return tracing.NewLogger(id, "protocol", o.Name(), "node",
+		o.ServerIdentity())
+}
+
 func (o *OCS) getUI(U, Xc kyber.Point) *share.PubShare {
 	v := cothority.Suite.Point().Mul(o.Shared.V, U)
 	v.Add(v, cothority.Suite.Point().Mul(o.Shared.V, Xc))
@@ -199,7 +245,14 @@ func (o *OCS) getUI(U, Xc kyber.Point) *share.PubShare {
 }
 
 func (o *OCS) finish(result bool) {
-	o.timeout.Stop()
+	if o.timeout != nil {
+		o.timeout.Stop()
+	}
+	if result {
+		o.span.End(nil)
+	} else {
+		o.span.End(xerrors.New("reencryption failed"))
+	}
 	select {
 	case o.Reencrypted <- result:
 		// suceeded
@@ -5,10 +5,12 @@ OCS_struct holds all messages for the onchain-secret protocol.
 */
 
 import (
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
 )
 
 // NameOCS can be used from other packages to refer to this protocol.
@@ -33,6 +35,20 @@ type Reencrypt struct {
 	// VerificationData is optional and can be any slice of bytes, so that each
 	// node can verify if the reencryption request is valid or not.
 	VerificationData *[]byte
+	// TraceID identifies the request in the logs of the nodes.
+	TraceID tracing.ID `protobuf:"opt"`
+	// SpanID is the span of the protocol on the root, the parent of the
+	// spans of the nodes.
+	SpanID tracing.SpanID `protobuf:"opt"`
+}
+
+// wellFormed returns an error if the request misses a point, as sent by a
+// faulty or malicious root.
+func (r *Reencrypt) wellFormed() error {
+	if r.U == nil || r.Xc == nil {
+		return xerrors.New("missing U or Xc")
+	}
+	return nil
 }
 
 type structReencrypt struct {
@@ -47,6 +63,18 @@ type ReencryptReply struct {
 	Fi kyber.Scalar
 }
 
+// wellFormed returns an error if the reply misses a field or has the index
+// of a share outside of the n nodes.
+func (rr *ReencryptReply) wellFormed(n int) error {
+	if rr.Ui == nil || rr.Ui.V == nil || rr.Ei == nil || rr.Fi == nil {
+		return xerrors.New("missing share or proof")
+	}
+	if rr.Ui.I < 0 || rr.Ui.I >= n {
+		return xerrors.Errorf("share index %d out of range", rr.Ui.I)
+	}
+	return nil
+}
+
 type structReencryptReply struct {
 	*onet.TreeNode
 	ReencryptReply
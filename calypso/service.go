@@ -30,10 +30,11 @@ package calypso
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
-	"net"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -41,11 +42,18 @@ import (
 	"golang.org/x/xerrors"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/admission"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/calypso/protocol"
+	"go.dedis.ch/cothority/v3/clientauth"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/decode"
 	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/tracing"
+	"go.dedis.ch/cothority/v3/wireversion"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/share"
 	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
@@ -62,13 +70,34 @@ var calypsoID onet.ServiceID
 // ServiceName of the secret-management part of Calypso.
 const ServiceName = "Calypso"
 
+// CurrentWireVersion is the version of the messages sent between the
+// conodes by the service, as exchanged by the wireversion package.
+const CurrentWireVersion = wireversion.Legacy
+
+// OldestWireVersion is the oldest version of the messages the service can
+// still talk.
+const OldestWireVersion = wireversion.Legacy
+
 // dkgTimeout is how long the system waits for the DKG to finish
 const propagationTimeout = 20 * time.Second
 
 const calypsoReshareProto = "calypso_reshare_proto"
 
+// rotateKeyInclusionWait is how many blocks the node waits for the rotate_key
+// transactions of its LTSs to be included.
+const rotateKeyInclusionWait = 10
+
+// maxConfigSize is the size of the biggest configuration of a protocol, or
+// verification data of a re-encryption, a node accepts. They hold the proofs
+// of a few instances.
+const maxConfigSize = 1 << 20
+
 var allowInsecureAdmin = false
 
+// refreshInterval is how often the shares of the LTSs are refreshed, zero
+// disables the refresh. It is set with COTHORITY_CALYPSO_REFRESH.
+var refreshInterval time.Duration
+
 // Allows one to register custom MakeAttrInterpreters for the read request
 // verify.
 var readMakeAttrInterpreter = make([]makeAttrInterpreterWrapper, 0)
@@ -84,6 +113,12 @@ type makeAttrInterpreterWrapper struct {
 		inst byzcoin.Instruction) func(string) error
 }
 
+// admissionLimits bound the re-encryption requests of a client if the
+// conode enables the admission control.
+var admissionLimits = []admission.Limit{
+	{Endpoint: ServiceName + "/DecryptKey", Rate: 5, Burst: 20},
+}
+
 func init() {
 	var err error
 	_, err = onet.GlobalProtocolRegister(calypsoReshareProto, dkgprotocol.NewSetup)
@@ -91,6 +126,7 @@ func init() {
 	calypsoID, err = onet.RegisterNewService(ServiceName, newService)
 	log.ErrFatal(err)
 	network.RegisterMessages(&storage{}, &vData{})
+	log.ErrFatal(admission.RegisterDefaults(admissionLimits...))
 
 	// The loopback check makes Java testing not work, because Java client
 	// commands come from outside of the docker container. The Java testing
@@ -99,6 +135,12 @@ func init() {
 		log.Warn("COTHORITY_ALLOW_INSECURE_ADMIN is set; Calypso admin actions allowed from the public network.")
 		allowInsecureAdmin = true
 	}
+	if env := os.Getenv("COTHORITY_CALYPSO_REFRESH"); env != "" {
+		refreshInterval, err = time.ParseDuration(env)
+		if err != nil {
+			log.Error("invalid COTHORITY_CALYPSO_REFRESH:", err)
+		}
+	}
 
 	err = byzcoin.RegisterGlobalContract(ContractWriteID, contractWriteFromBytes)
 	if err != nil {
@@ -125,6 +167,15 @@ type Service struct {
 	genesisBlocksLock sync.Mutex
 	// for use by testing only
 	afterReshare func()
+	// for use by testing only, called once a node applied a refresh it
+	// didn't start
+	afterRefresh func()
+	// for use by testing only, makes the DKGs fail to start
+	failDKGStart error
+	// for use by testing only, makes the new DKGs of the node deterministic
+	dkgSeed []byte
+	// dkgs holds the last DKG of every LTS, indexed by the LTS ID
+	dkgs *dkgprotocol.Instances
 }
 
 // pubPoly is a serializable version of share.PubPoly
@@ -151,24 +202,95 @@ func AddReadAttrInterpreter(name string, interpreter func(c ContractWrite,
 	readMakeAttrInterpreter = append(readMakeAttrInterpreter, makeAttrInterpreterWrapper{name, interpreter})
 }
 
-// ProcessClientRequest implements onet.Service. We override the version
-// we normally get from embeddeding onet.ServiceProcessor in order to
-// hook it and get a look at the http.Request.
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode, after restricting its admin endpoints to the loopback.
 func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	if !allowInsecureAdmin && path == "Authorise" && !clientauth.IsLocal(req) {
+		return nil, nil, xerrors.New("authorise is only allowed on loopback")
+	}
+	if !allowInsecureAdmin && path == "RecoverLTS" && !clientauth.IsLocal(req) {
+		return nil, nil, xerrors.New("recovering an LTS is only allowed on loopback")
+	}
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
 
-	if !allowInsecureAdmin && path == "Authorise" {
-		h, _, err := net.SplitHostPort(req.RemoteAddr)
+// WireVersion implements wireversion.Versioner.
+func (s *Service) WireVersion() wireversion.Range {
+	return wireversion.Range{Current: CurrentWireVersion,
+		Oldest: OldestWireVersion}
+}
 
-		if err != nil {
-			return nil, nil, xerrors.Errorf("splitting host port: %v", err)
+// RotateKey implements keyrotation.Rotator. It replaces the old key in the
+// rosters of the LTSs of the node, keeping the shares at the same index. The
+// node with the old key also sends a rotate_key transaction to the LTS
+// instances, so that the next reshares use the new roster.
+func (s *Service) RotateKey(t *keyrotation.Transition) ([]*onet.Roster,
+	error) {
+	replies := make(map[byzcoin.InstanceID]*CreateLTSReply)
+	var rosters []*onet.Roster
+	s.storage.Lock()
+	for id, roster := range s.storage.Rosters {
+		rotated := t.Replace(roster)
+		if rotated == nil {
+			continue
+		}
+		rosters = append(rosters, roster)
+		s.storage.Rosters[id] = rotated
+		s.SetValidPeers(s.NewPeerSetID(id[:]), rotated.List)
+		if reply := s.storage.Replies[id]; reply != nil {
+			replies[id] = reply
 		}
-		ip := net.ParseIP(h)
-		if !ip.IsLoopback() {
+	}
+	s.storage.Unlock()
+	if len(rosters) == 0 {
+		return nil, nil
+	}
+	if err := s.save(); err != nil {
+		return rosters, xerrors.Errorf("saving data: %v", err)
+	}
+	if !t.Old.Equal(s.ServerIdentity().Public) {
+		return rosters, nil
+	}
 
-			return nil, nil, xerrors.New("authorise is only allowed on loopback")
+	bc, ok := s.Service(byzcoin.ServiceName).(*byzcoin.Service)
+	if !ok {
+		return rosters, xerrors.New("byzcoin service is not running")
+	}
+	buf, err := protobuf.Encode(t)
+	if err != nil {
+		return rosters, xerrors.Errorf("encoding the transition: %v", err)
+	}
+	var errs []string
+	for id, reply := range replies {
+		st, err := bc.GetReadOnlyStateTrie(reply.ByzCoinID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%x: getting trie: %v", id[:], err))
+			continue
+		}
+		ctx := byzcoin.NewClientTransaction(st.GetVersion(), byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractLongTermSecretID,
+				Command:    "rotate_key",
+				Args:       byzcoin.Arguments{{Name: "transition", Value: buf}},
+			},
+		})
+		_, err = bc.AddTransaction(&byzcoin.AddTxRequest{
+			Version:       byzcoin.CurrentVersion,
+			SkipchainID:   reply.ByzCoinID,
+			Transaction:   ctx,
+			InclusionWait: rotateKeyInclusionWait,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%x: %v", id[:], err))
 		}
 	}
-	return s.ServiceProcessor.ProcessClientRequest(req, path, buf)
+	if len(errs) > 0 {
+		return rosters, xerrors.Errorf("couldn't rotate the key: %s",
+			strings.Join(errs, "; "))
+	}
+	return rosters, nil
 }
 
 // Authorise adds a ByzCoinID to the list of authorized IDs. It can only be
@@ -218,7 +340,14 @@ func (s *Service) Authorize(req *Authorize) (*AuthorizeReply, error) {
 		}
 		msg := append(req.ByzCoinID, make([]byte, 8)...)
 		binary.LittleEndian.PutUint64(msg[32:], uint64(req.Timestamp))
-		err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public, msg, req.Signature)
+		// The signatures with a key the node rotated from are accepted
+		// during the grace window of the rotation.
+		var err error
+		for _, pub := range keyrotation.Keys(s.ServerIdentity().Public, time.Now()) {
+			if err = schnorr.Verify(cothority.Suite, pub, msg, req.Signature); err == nil {
+				break
+			}
+		}
 		if err != nil {
 			return nil, xerrors.Errorf("signature verification failed: %v", err)
 		}
@@ -282,14 +411,31 @@ func (s *Service) CreateLTS(req *CreateLTS) (reply *CreateLTSReply, err error) {
 		return nil, xerrors.Errorf("set dkg config: %v", err)
 	}
 	setupDKG.KeyPair = s.getKeyPair()
+	setupDKG.Reader = s.dkgReader()
+	if err := s.dkgs.Add(instID[:], setupDKG); err != nil {
+		setupDKG.Done()
+		return nil, xerrors.Errorf("adding dkg: %v", err)
+	}
+	// A DKG that didn't succeed must not keep the LTS from being retried.
+	defer func() {
+		if err != nil {
+			s.dkgs.Remove(instID[:])
+		}
+	}()
 
-	if err := pi.Start(); err != nil {
+	if err := s.startDKG(setupDKG); err != nil {
 		return nil, xerrors.Errorf("starting dkg protocol: %v", err)
 	}
 
 	log.Lvl3("Started DKG-protocol - waiting for done", len(roster.List))
 	select {
-	case <-setupDKG.Finished:
+	case ok := <-setupDKG.Finished:
+		if !ok {
+			if setupDKG.Evidence != nil {
+				log.Warn(s.ServerIdentity(), setupDKG.Evidence)
+			}
+			return nil, xerrors.Errorf("dkg failed: %v", setupDKG.Err)
+		}
 		shared, dks, err := setupDKG.SharedSecret()
 		if err != nil {
 			return nil, xerrors.Errorf("get aggregate public key: %v", err)
@@ -302,7 +448,9 @@ func (s *Service) CreateLTS(req *CreateLTS) (reply *CreateLTSReply, err error) {
 		s.storage.Lock()
 		s.storage.Shared[instID] = shared
 		s.storage.Polys[instID] = &pubPoly{s.Suite().Point().Base(), dks.Commits}
-		s.storage.Rosters[instID] = roster
+		// The shares are in the order of the tree, as stored by the other
+		// nodes.
+		s.storage.Rosters[instID] = tree.Roster
 		s.storage.Replies[instID] = reply
 		s.storage.DKS[instID] = dks
 		s.storage.Unlock()
@@ -312,7 +460,8 @@ func (s *Service) CreateLTS(req *CreateLTS) (reply *CreateLTSReply, err error) {
 		}
 		log.Lvlf2("%v Created LTS with ID: %v, pk %v", s.ServerIdentity(), instID, reply.X)
 	case <-time.After(propagationTimeout):
-		return nil, xerrors.New("new-dkg didn't finish in time")
+		return nil, xerrors.Errorf("new-dkg didn't finish in time, stuck in phase %v",
+			setupDKG.Status().Phase)
 	}
 	return
 }
@@ -320,7 +469,8 @@ func (s *Service) CreateLTS(req *CreateLTS) (reply *CreateLTSReply, err error) {
 // ReshareLTS starts a request to reshare the LTS. The new roster which holds
 // the new secret shares must exist in the proof specified by the request.
 // All hosts must be online in this step.
-func (s *Service) ReshareLTS(req *ReshareLTS) (*ReshareLTSReply, error) {
+func (s *Service) ReshareLTS(req *ReshareLTS) (resp *ReshareLTSReply,
+	err error) {
 	// Verify the request
 	roster, id, err := s.getLtsRoster(&req.Proof)
 	if err != nil {
@@ -405,14 +555,29 @@ func (s *Service) ReshareLTS(req *ReshareLTS) (*ReshareLTSReply, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("initializing dkg: %v", err)
 	}
-	if err := setupDKG.Start(); err != nil {
+	if err := s.dkgs.Add(id[:], setupDKG); err != nil {
+		setupDKG.Done()
+		return nil, xerrors.Errorf("adding dkg: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			s.dkgs.Remove(id[:])
+		}
+	}()
+	if err := s.startDKG(setupDKG); err != nil {
 		return nil, xerrors.Errorf("starting dkg: %v", err)
 	}
 	log.Lvl3(s.ServerIdentity(), "Started resharing DKG-protocol - waiting for done")
 
 	var pk kyber.Point
 	select {
-	case <-setupDKG.Finished:
+	case ok := <-setupDKG.Finished:
+		if !ok {
+			if setupDKG.Evidence != nil {
+				log.Warn(s.ServerIdentity(), setupDKG.Evidence)
+			}
+			return nil, xerrors.Errorf("resharing failed: %v", setupDKG.Err)
+		}
 		shared, dks, err := setupDKG.SharedSecret()
 		if err != nil {
 			return nil, xerrors.Errorf("getting shared secret: %v", err)
@@ -450,6 +615,20 @@ func (s *Service) ReshareLTS(req *ReshareLTS) (*ReshareLTSReply, error) {
 	return &ReshareLTSReply{}, nil
 }
 
+// startDKG starts the DKG of which the node is the root. If the DKG can't
+// reach the other nodes, its protocol instance is released.
+func (s *Service) startDKG(setupDKG *dkgprotocol.Setup) error {
+	err := s.failDKGStart
+	if err == nil {
+		err = setupDKG.Start()
+	}
+	if err != nil {
+		setupDKG.Done()
+		return err
+	}
+	return nil
+}
+
 // Private service endpoint that sets the valid peers according to the roster
 // in the provided proof.
 func (s *Service) updateValidPeers(req *updateValidPeers) (
@@ -527,10 +706,21 @@ func (s *Service) getLtsRoster(proof *byzcoin.Proof) (*onet.Roster, byzcoin.Inst
 // stored in ByzCoin.
 // Using the Read and the Write-instance, this method verifies that the
 // requests match and then re-encrypts the secret to the public key given
-// in the Read-instance.
+// in the Read-instance. The re-encryption is logged on every node with the
+// trace ID of the request, which is returned in the reply.
 func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error) {
-	reply = &DecryptKeyReply{}
-	log.Lvl2(s.ServerIdentity(), "Re-encrypt the key to the public key of the reader")
+	reply = &DecryptKeyReply{TraceID: tracing.OrNew(dkr.TraceID)}
+	tlog := tracing.NewLogger(reply.TraceID, "service", ServiceName, "node",
+		s.ServerIdentity())
+	tlog.Lvl2("re-encrypt the key to the public key of the reader")
+	span := tracing.StartSpan(reply.TraceID, nil, ServiceName+".DecryptKey",
+		"node", s.ServerIdentity())
+	defer func() {
+		if err != nil {
+			tlog.Lvl2("re-encryption failed", "err", err)
+		}
+		span.End(err)
+	}()
 
 	var read Read
 	if err := dkr.Read.VerifyAndDecode(cothority.Suite, ContractReadID, &read); err != nil {
@@ -575,12 +765,15 @@ func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error
 		return nil, xerrors.Errorf("failed to create ocs-protocol: %v", err)
 	}
 	ocsProto := pi.(*protocol.OCS)
+	ocsProto.TraceID = reply.TraceID
+	ocsProto.SpanID = span.ID()
 	ocsProto.U = write.U
 	verificationData := &vData{
 		Proof: dkr.Read,
 	}
 	ocsProto.Xc = read.Xc
-	log.Lvlf2("%v Public key is: %s", s.ServerIdentity(), ocsProto.Xc)
+	tlog.Lvl2("got the public key of the reader", "key", ocsProto.Xc,
+		"lts", id)
 	ocsProto.VerificationData, err = protobuf.Encode(verificationData)
 	if err != nil {
 		return nil,
@@ -600,7 +793,7 @@ func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error
 	ocsProto.Poly = share.NewPubPoly(s.Suite(), pp.B.Clone(), commits)
 	s.storage.Unlock()
 
-	log.Lvl3("Starting reencryption protocol")
+	tlog.Lvl3("starting reencryption protocol")
 	err = ocsProto.SetConfig(&onet.GenericConfig{Data: id.Slice()})
 	if err != nil {
 		return nil,
@@ -613,14 +806,14 @@ func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error
 	if !<-ocsProto.Reencrypted {
 		return nil, xerrors.New("reencryption got refused")
 	}
-	log.Lvl3("Reencryption protocol is done.")
+	tlog.Lvl3("reencryption protocol is done")
 	reply.XhatEnc, err = share.RecoverCommit(cothority.Suite, ocsProto.Uis,
 		threshold, nodes)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to recover commit: %v", err)
 	}
 	reply.C = write.C
-	log.Lvl3("Successfully reencrypted the key")
+	tlog.Lvl3("successfully reencrypted the key")
 	return
 }
 
@@ -640,6 +833,277 @@ func (s *Service) GetLTSReply(req *GetLTSReply) (*CreateLTSReply, error) {
 	}, nil
 }
 
+// DKGStatus returns the progress of the last DKG of the LTS on this node, so
+// that a stalled DKG can be traced to the nodes that are not progressing.
+func (s *Service) DKGStatus(req *DKGStatus) (*DKGStatusReply, error) {
+	setupDKG := s.dkgs.Get(req.LTSID[:])
+	if setupDKG == nil {
+		return nil, xerrors.Errorf("no DKG for this LTS: %v", req.LTSID)
+	}
+	p := setupDKG.Status()
+	reply := &DKGStatusReply{
+		Phase:              p.Phase.String(),
+		DealsSent:          p.DealsSent,
+		DealsProcessed:     p.DealsProcessed,
+		ExpectedDeals:      p.ExpectedDeals,
+		ResponsesProcessed: p.ResponsesProcessed,
+		Faulty:             p.Faulty,
+	}
+	if p.Phase == dkgprotocol.PhaseAborted && setupDKG.Evidence != nil {
+		buf, err := protobuf.Encode(setupDKG.Evidence)
+		if err != nil {
+			return nil, xerrors.Errorf("encoding evidence: %v", err)
+		}
+		reply.Evidence = buf
+	}
+	return reply, nil
+}
+
+// GetLTSCommits returns the public commitments of the distributed key of the
+// LTS, so that the shares of the nodes can be verified against them.
+func (s *Service) GetLTSCommits(req *GetLTSCommits) (*GetLTSCommitsReply, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	pp, ok := s.storage.Polys[req.LTSID]
+	if !ok {
+		return nil, xerrors.Errorf("didn't find this LTS: %v", req.LTSID)
+	}
+	reply := &GetLTSCommitsReply{Roster: s.storage.Rosters[req.LTSID]}
+	for _, c := range pp.Commits {
+		reply.Commits = append(reply.Commits, c.Clone())
+	}
+	return reply, nil
+}
+
+// RecoverLTS gets back the share of the LTS the node lost from the other
+// nodes of the LTS, with the dkg recovery protocol, which doesn't reconstruct
+// the secret. The share is only kept if it matches the commitments of the
+// other nodes.
+func (s *Service) RecoverLTS(req *RecoverLTS) (*RecoverLTSReply, error) {
+	if err := s.verifyProof(&req.Proof); err != nil {
+		return nil, xerrors.Errorf("verifying proof: %v", err)
+	}
+	roster, id, err := s.getLtsRoster(&req.Proof)
+	if err != nil {
+		return nil, xerrors.Errorf("get roster: %v", err)
+	}
+	s.storage.Lock()
+	_, ok := s.storage.Shared[id]
+	s.storage.Unlock()
+	if ok {
+		return nil, xerrors.New("the node already has a share of this LTS")
+	}
+
+	dkgRoster, err := s.fetchLTSRoster(roster, id)
+	if err != nil {
+		return nil, xerrors.Errorf("getting the roster of the shares: %v", err)
+	}
+	index, _ := dkgRoster.Search(s.ServerIdentity().ID)
+	if index < 0 {
+		return nil, xerrors.New("the node is not in the roster of the LTS")
+	}
+	tree := dkgRoster.NewRosterWithRoot(s.ServerIdentity()).GenerateStar()
+	pi, err := s.CreateProtocol(dkgprotocol.RecoverName, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating recover protocol: %v", err)
+	}
+	rec := pi.(*dkgprotocol.Recover)
+	rec.Index = index
+	rec.Timeout = propagationTimeout
+	err = rec.SetConfig(&onet.GenericConfig{Data: id[:]})
+	if err != nil {
+		rec.Done()
+		return nil, xerrors.Errorf("setting recover configuration: %v", err)
+	}
+	if err := rec.Start(); err != nil {
+		rec.Done()
+		return nil, xerrors.Errorf("starting recovery: %v", err)
+	}
+
+	// Finished is buffered, so the protocol doesn't block on it if we stop
+	// waiting. It is only sent if the recovery succeeded.
+	select {
+	case <-rec.Finished:
+	case <-time.After(2 * propagationTimeout):
+		return nil, xerrors.New("recovery didn't finish in time")
+	}
+
+	shared := &dkgprotocol.SharedSecret{
+		Index:   rec.Share.I,
+		V:       rec.Share.V,
+		X:       rec.Commits[0],
+		Commits: rec.Commits,
+	}
+	s.storage.Lock()
+	s.storage.Shared[id] = shared
+	s.storage.Polys[id] = &pubPoly{s.Suite().Point().Base(), rec.Commits}
+	s.storage.DKS[id] = &dkg.DistKeyShare{Commits: rec.Commits, Share: rec.Share}
+	s.storage.Rosters[id] = dkgRoster
+	s.storage.Replies[id] = &CreateLTSReply{
+		ByzCoinID:  req.Proof.Latest.SkipChainID(),
+		InstanceID: id,
+		X:          shared.X,
+	}
+	s.storage.Unlock()
+	if err := s.save(); err != nil {
+		return nil, xerrors.Errorf("saving recovered share: %v", err)
+	}
+	s.SetValidPeers(s.NewPeerSetID(id[:]), dkgRoster.List)
+	log.Lvlf2("%v recovered the share %d of LTS %v", s.ServerIdentity(),
+		index, id)
+	return &RecoverLTSReply{Index: index}, nil
+}
+
+// fetchLTSRoster asks the other nodes of the LTS for the roster of the LTS
+// in the order of the shares, which is the order of the tree of the DKG. The
+// roster must have the nodes of the LTS instance. The shareholders check the
+// index of the node anyway, so a wrong order only makes the recovery fail.
+func (s *Service) fetchLTSRoster(roster *onet.Roster, id byzcoin.InstanceID) (*onet.Roster, error) {
+	cl := onet.NewClient(cothority.Suite, ServiceName)
+	var errs []string
+	for _, si := range roster.List {
+		if si.Equal(s.ServerIdentity()) {
+			continue
+		}
+		var reply GetLTSCommitsReply
+		err := cl.SendProtobuf(si, &GetLTSCommits{LTSID: id}, &reply)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", si, err))
+			continue
+		}
+		if reply.Roster == nil || !sameNodes(reply.Roster, roster) {
+			errs = append(errs, fmt.Sprintf("%v: roster doesn't match the LTS", si))
+			continue
+		}
+		return reply.Roster, nil
+	}
+	return nil, xerrors.Errorf("no node sent the roster: %s",
+		strings.Join(errs, "; "))
+}
+
+// VerifyShare checks that the share of the LTS stored by this node is
+// consistent with the given commitments, or with the commitments of the node
+// if none are given.
+func (s *Service) VerifyShare(req *VerifyShare) (*VerifyShareReply, error) {
+	s.storage.Lock()
+	shared, ok := s.storage.Shared[req.LTSID]
+	if ok {
+		shared = shared.Clone()
+	}
+	s.storage.Unlock()
+	if !ok {
+		return nil, xerrors.Errorf("didn't find this LTS: %v", req.LTSID)
+	}
+
+	commits := req.Commits
+	if len(commits) == 0 {
+		commits = shared.Commits
+	}
+	reply := &VerifyShareReply{Index: shared.Index, Valid: true}
+	if err := shared.VerifyShare(s.Suite(), commits); err != nil {
+		log.Warnf("%v: share of LTS %v is invalid: %v", s.ServerIdentity(),
+			req.LTSID, err)
+		reply.Valid = false
+		reply.Error = err.Error()
+	}
+	return reply, nil
+}
+
+// refreshLTS re-randomizes the shares of the LTS with the dkg refresh
+// protocol. The node must be in the roster of the LTS.
+func (s *Service) refreshLTS(id byzcoin.InstanceID) error {
+	s.storage.Lock()
+	shared, ok := s.storage.Shared[id]
+	roster := s.storage.Rosters[id]
+	if ok {
+		shared = shared.Clone()
+	}
+	s.storage.Unlock()
+	if !ok || roster == nil {
+		return xerrors.Errorf("didn't find LTSID %v", id)
+	}
+
+	tree := roster.GenerateNaryTreeWithRoot(len(roster.List), s.ServerIdentity())
+	if tree == nil {
+		return xerrors.New("failed to generate tree -- root not in roster")
+	}
+	pi, err := s.CreateProtocol(dkgprotocol.RefreshName, tree)
+	if err != nil {
+		return xerrors.Errorf("creating refresh protocol: %v", err)
+	}
+	refresh := pi.(*dkgprotocol.Refresh)
+	refresh.Shared = shared
+	refresh.KeyPair = s.getKeyPair()
+	err = refresh.SetConfig(&onet.GenericConfig{Data: id[:]})
+	if err != nil {
+		return xerrors.Errorf("setting refresh configuration: %v", err)
+	}
+	if err := refresh.Start(); err != nil {
+		return xerrors.Errorf("starting refresh: %v", err)
+	}
+
+	// Finished is buffered, so the protocol doesn't block on it if we stop
+	// waiting.
+	select {
+	case ok := <-refresh.Finished:
+		if !ok {
+			return xerrors.New("refresh failed")
+		}
+	case <-time.After(propagationTimeout):
+		return xerrors.New("refresh didn't finish in time")
+	}
+	return s.applyRefresh(id, refresh.Refreshed)
+}
+
+// applyRefresh replaces the share of the LTS with the refreshed one. The
+// share is only replaced if it can be saved, so that the node never uses a
+// share it would lose at the next restart.
+func (s *Service) applyRefresh(id byzcoin.InstanceID, refreshed *dkgprotocol.SharedSecret) error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	oldShared, oldPoly := s.storage.Shared[id], s.storage.Polys[id]
+	s.storage.Shared[id] = refreshed
+	s.storage.Polys[id] = &pubPoly{s.Suite().Point().Base(), refreshed.Commits}
+	var oldDKS dkg.DistKeyShare
+	dks := s.storage.DKS[id]
+	if dks != nil {
+		oldDKS = *dks
+		dks.Share = &share.PriShare{I: refreshed.Index, V: refreshed.V}
+		dks.Commits = refreshed.Commits
+	}
+	if err := s.saveLocked(); err != nil {
+		s.storage.Shared[id], s.storage.Polys[id] = oldShared, oldPoly
+		if dks != nil {
+			*dks = oldDKS
+		}
+		return xerrors.Errorf("saving refreshed share: %v", err)
+	}
+	log.Lvlf2("%v refreshed the share of LTS %v", s.ServerIdentity(), id)
+	return nil
+}
+
+// refreshLoop refreshes the LTSs whose roster starts with this node every
+// refreshInterval.
+func (s *Service) refreshLoop() {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		var ids []byzcoin.InstanceID
+		s.storage.Lock()
+		for id, roster := range s.storage.Rosters {
+			if len(roster.List) > 0 && roster.List[0].Equal(s.ServerIdentity()) {
+				ids = append(ids, id)
+			}
+		}
+		s.storage.Unlock()
+		for _, id := range ids {
+			if err := s.refreshLTS(id); err != nil {
+				log.Error(s.ServerIdentity(), "couldn't refresh", id, err)
+			}
+		}
+	}
+}
+
 func (s *Service) getKeyPair() *key.Pair {
 	return &key.Pair{
 		Public:  s.ServerIdentity().ServicePublic(ServiceName),
@@ -652,8 +1116,11 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 	log.Lvl3(s.ServerIdentity(), tn.ProtocolName(), conf)
 	switch tn.ProtocolName() {
 	case dkgprotocol.Name:
+		if conf == nil {
+			return nil, xerrors.New("missing LTS config")
+		}
 		var cfg newLtsConfig
-		if err := protobuf.DecodeWithConstructors(conf.Data, &cfg, network.DefaultConstructors(cothority.Suite)); err != nil {
+		if err := decode.ProtobufWithConstructors(conf.Data, &cfg, maxConfigSize, network.DefaultConstructors(cothority.Suite)); err != nil {
 			return nil, xerrors.Errorf("decoding LTS config: %v", err)
 		}
 		if err := s.verifyProof(&cfg.Proof); err != nil {
@@ -671,6 +1138,10 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 		}
 		setupDKG := pi.(*dkgprotocol.Setup)
 		setupDKG.KeyPair = s.getKeyPair()
+		setupDKG.Reader = s.dkgReader()
+		if err := s.dkgs.Add(instID[:], setupDKG); err != nil {
+			return nil, xerrors.Errorf("adding dkg: %v", err)
+		}
 
 		go func(bcID skipchain.SkipBlockID, id byzcoin.InstanceID) {
 			<-setupDKG.Finished
@@ -699,8 +1170,11 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 		return pi, nil
 	case calypsoReshareProto:
 		// Decode and verify config
+		if conf == nil {
+			return nil, xerrors.New("missing config")
+		}
 		var cfg reshareLtsConfig
-		if err := protobuf.DecodeWithConstructors(conf.Data, &cfg, network.DefaultConstructors(cothority.Suite)); err != nil {
+		if err := decode.ProtobufWithConstructors(conf.Data, &cfg, maxConfigSize, network.DefaultConstructors(cothority.Suite)); err != nil {
 			return nil, xerrors.Errorf("decoding config: %v", err)
 		}
 		if err := s.verifyProof(&cfg.Proof); err != nil {
@@ -716,6 +1190,9 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 		}
 		setupDKG := pi.(*dkgprotocol.Setup)
 		setupDKG.KeyPair = s.getKeyPair()
+		if err := s.dkgs.Add(id[:], setupDKG); err != nil {
+			return nil, xerrors.Errorf("adding dkg: %v", err)
+		}
 
 		s.storage.Lock()
 		oldn := len(cfg.OldNodes)
@@ -812,10 +1289,96 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 		ocs.Shared = shared
 		ocs.Verify = s.verifyReencryption
 		return ocs, nil
+	case dkgprotocol.RecoverName:
+		id := byzcoin.NewInstanceID(conf.Data)
+		s.storage.Lock()
+		dks, ok := s.storage.DKS[id]
+		roster := s.storage.Rosters[id]
+		var priShare *share.PriShare
+		var commits []kyber.Point
+		if ok {
+			priShare = dks.PriShare()
+			commits = append(commits, dks.Commits...)
+		}
+		s.storage.Unlock()
+		if !ok || roster == nil {
+			return nil, fmt.Errorf("didn't find LTSID %v", id)
+		}
+		pi, err := dkgprotocol.NewRecover(tn)
+		if err != nil {
+			return nil, xerrors.Errorf("creating recover protocol: %v", err)
+		}
+		rec := pi.(*dkgprotocol.Recover)
+		rec.Share = priShare
+		rec.Commits = commits
+		rec.Roster = roster
+		// Only the node of the LTS at the index can get the share back, and
+		// only from nodes of the LTS.
+		rec.Verify = func(root *onet.TreeNode, index int, children []*onet.TreeNode) bool {
+			if index < 0 || index >= len(roster.List) ||
+				!roster.List[index].Equal(root.ServerIdentity) {
+				return false
+			}
+			for _, c := range children {
+				if i, _ := roster.Search(c.ServerIdentity.ID); i < 0 {
+					return false
+				}
+			}
+			return true
+		}
+		return rec, nil
+	case dkgprotocol.RefreshName:
+		id := byzcoin.NewInstanceID(conf.Data)
+		s.storage.Lock()
+		shared, ok := s.storage.Shared[id]
+		roster := s.storage.Rosters[id]
+		if ok {
+			shared = shared.Clone()
+		}
+		s.storage.Unlock()
+		if !ok || roster == nil {
+			return nil, fmt.Errorf("didn't find LTSID %v", id)
+		}
+		// All the shares must be refreshed at once.
+		if !sameNodes(roster, tn.Roster()) {
+			return nil, xerrors.New("the refresh doesn't include all of the LTS roster")
+		}
+		pi, err := dkgprotocol.NewRefresh(tn)
+		if err != nil {
+			return nil, xerrors.Errorf("creating refresh protocol: %v", err)
+		}
+		refresh := pi.(*dkgprotocol.Refresh)
+		refresh.Shared = shared
+		refresh.KeyPair = s.getKeyPair()
+		go func() {
+			if !<-refresh.Finished {
+				return
+			}
+			if err := s.applyRefresh(id, refresh.Refreshed); err != nil {
+				log.Error(err)
+			}
+			if s.afterRefresh != nil {
+				s.afterRefresh()
+			}
+		}()
+		return refresh, nil
 	}
 	return nil, nil
 }
 
+// sameNodes returns true if both rosters have the same nodes, in any order.
+func sameNodes(r1, r2 *onet.Roster) bool {
+	if len(r1.List) != len(r2.List) {
+		return false
+	}
+	for _, si := range r1.List {
+		if i, _ := r2.Search(si.ID); i < 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func pointInList(p1 kyber.Point, l []kyber.Point) bool {
 	for _, p2 := range l {
 		if p2.Equal(p1) {
@@ -828,8 +1391,11 @@ func pointInList(p1 kyber.Point, l []kyber.Point) bool {
 // verifyReencryption checks that the read and the write instances match.
 func (s *Service) verifyReencryption(rc *protocol.Reencrypt) bool {
 	err := func() error {
+		if rc.VerificationData == nil {
+			return xerrors.New("missing verification data")
+		}
 		var verificationData vData
-		err := protobuf.DecodeWithConstructors(*rc.VerificationData, &verificationData, network.DefaultConstructors(cothority.Suite))
+		err := decode.ProtobufWithConstructors(*rc.VerificationData, &verificationData, maxConfigSize, network.DefaultConstructors(cothority.Suite))
 		if err != nil {
 			return xerrors.Errorf("decoding verification data: %v", err)
 		}
@@ -854,12 +1420,22 @@ func (s *Service) verifyReencryption(rc *protocol.Reencrypt) bool {
 		return nil
 	}()
 	if err != nil {
-		log.Lvl2(s.ServerIdentity(), "wrong reencryption:", err)
+		tracing.NewLogger(rc.TraceID, "service", ServiceName, "node",
+			s.ServerIdentity()).Lvl2("wrong reencryption", "err", err)
 		return false
 	}
 	return true
 }
 
+// dkgReader returns the stream of the polynomial of a new DKG, which is nil
+// for a random one.
+func (s *Service) dkgReader() io.Reader {
+	if s.dkgSeed == nil {
+		return nil
+	}
+	return cothority.Suite.XOF(s.dkgSeed)
+}
+
 // newService receives the context that holds information about the node it's
 // running on. Saving and loading can be done using the context. The data will
 // be stored in memory for tests and simulations, and on disk for real deployments.
@@ -867,9 +1443,11 @@ func newService(c *onet.Context) (onet.Service, error) {
 	s := &Service{
 		ServiceProcessor: onet.NewServiceProcessor(c),
 		genesisBlocks:    make(map[string]*skipchain.SkipBlock),
+		dkgs:             dkgprotocol.NewInstances(),
 	}
 	if err := s.RegisterHandlers(s.CreateLTS, s.ReshareLTS, s.DecryptKey,
-		s.GetLTSReply, s.Authorise, s.Authorize, s.updateValidPeers); err != nil {
+		s.GetLTSReply, s.Authorise, s.Authorize, s.updateValidPeers,
+		s.DKGStatus, s.GetLTSCommits, s.VerifyShare, s.RecoverLTS); err != nil {
 		return nil, xerrors.New("couldn't register messages")
 	}
 	if err := s.tryLoad(); err != nil {
@@ -882,5 +1460,9 @@ func newService(c *onet.Context) (onet.Service, error) {
 		s.SetValidPeers(s.NewPeerSetID(ltsID[:]), roster.List)
 	}
 
+	if refreshInterval > 0 {
+		go s.refreshLoop()
+	}
+
 	return s, nil
 }
@@ -41,6 +41,163 @@ func TestService_CreateLTS(t *testing.T) {
 	}
 }
 
+// TestService_CreateLTS_Retry checks that an LTS whose DKG failed to start
+// can be created again.
+func TestService_CreateLTS_Retry(t *testing.T) {
+	s := newTS(t, 4)
+	defer s.closeAll(t)
+
+	proof := s.spawnLTS(t, 2)
+	s.services[0].failDKGStart = xerrors.New("unreachable nodes")
+	_, err := s.services[0].CreateLTS(&CreateLTS{Proof: *proof})
+	require.Error(t, err)
+
+	s.services[0].failDKGStart = nil
+	reply, err := s.services[0].CreateLTS(&CreateLTS{Proof: *proof})
+	require.NoError(t, err)
+	require.NotNil(t, reply.X)
+}
+
+func TestService_DKGStatus(t *testing.T) {
+	s := newTS(t, 4)
+	defer s.closeAll(t)
+
+	cl := NewClient(s.cl)
+	replies, errs := cl.DKGStatus(s.ltsRoster, s.ltsReply.InstanceID)
+	for i := range replies {
+		require.NoError(t, errs[i])
+		require.Equal(t, "certified", replies[i].Phase)
+		require.Equal(t, 3, replies[i].ExpectedDeals)
+		require.Equal(t, 3, replies[i].DealsProcessed)
+	}
+
+	_, errs = cl.DKGStatus(s.ltsRoster, byzcoin.NewInstanceID(nil))
+	require.Error(t, errs[0])
+}
+
+func TestService_VerifyShare(t *testing.T) {
+	s := newTS(t, 4)
+	defer s.closeAll(t)
+
+	cl := NewClient(s.cl)
+	id := s.ltsReply.InstanceID
+	commits, err := cl.GetLTSCommits(s.ltsRoster.List[0], id)
+	require.NoError(t, err)
+	require.True(t, commits.Commits[0].Equal(s.ltsReply.X))
+
+	replies, errs := cl.VerifyShare(s.ltsRoster, id, commits.Commits)
+	for i := range replies {
+		require.NoError(t, errs[i])
+		require.True(t, replies[i].Valid)
+	}
+
+	// a share that doesn't match the commitments anymore
+	srv := s.services[1]
+	srv.storage.Lock()
+	srv.storage.Shared[id].V = cothority.Suite.Scalar().Pick(cothority.Suite.RandomStream())
+	srv.storage.Unlock()
+	replies, errs = cl.VerifyShare(s.ltsRoster, id, commits.Commits)
+	for i := range replies {
+		require.NoError(t, errs[i])
+		require.Equal(t, !s.ltsRoster.List[i].Equal(srv.ServerIdentity()), replies[i].Valid)
+	}
+
+	_, err = cl.GetLTSCommits(s.ltsRoster.List[0], byzcoin.NewInstanceID(nil))
+	require.Error(t, err)
+}
+
+func TestService_RecoverLTS(t *testing.T) {
+	s := newTS(t, 4)
+	defer s.closeAll(t)
+
+	// the last node loses its share
+	id := s.ltsReply.InstanceID
+	srv := s.services[3]
+	srv.storage.Lock()
+	lost := srv.storage.Shared[id]
+	delete(srv.storage.Shared, id)
+	delete(srv.storage.Polys, id)
+	delete(srv.storage.DKS, id)
+	delete(srv.storage.Rosters, id)
+	delete(srv.storage.Replies, id)
+	srv.storage.Unlock()
+
+	proof, err := s.cl.GetProof(id[:])
+	require.NoError(t, err)
+	cl := NewClient(s.cl)
+	reply, err := cl.RecoverLTS(srv.ServerIdentity(), &proof.Proof)
+	require.NoError(t, err)
+	require.Equal(t, lost.Index, reply.Index)
+	srv.storage.Lock()
+	shared := srv.storage.Shared[id]
+	srv.storage.Unlock()
+	require.True(t, lost.V.Equal(shared.V))
+	require.True(t, lost.X.Equal(shared.X))
+
+	// a node with a share doesn't recover it
+	_, err = cl.RecoverLTS(srv.ServerIdentity(), &proof.Proof)
+	require.Error(t, err)
+}
+
+// TestService_DeterministicDKG checks that nodes with the same seeds create
+// the same LTS on every run.
+func TestService_DeterministicDKG(t *testing.T) {
+	var keys []kyber.Point
+	for run := 0; run < 2; run++ {
+		func() {
+			s := newTS(t, 4)
+			defer s.closeAll(t)
+			for i, srv := range s.services {
+				srv.dkgSeed = []byte{byte(i)}
+			}
+			reply := s.createLTS(t, 2)
+			require.False(t, reply.X.Equal(s.ltsReply.X))
+			keys = append(keys, reply.X)
+		}()
+	}
+	require.True(t, keys[0].Equal(keys[1]))
+}
+
+func TestService_RefreshLTS(t *testing.T) {
+	s := newTS(t, 4)
+	defer s.closeAll(t)
+
+	id := s.ltsReply.InstanceID
+	key1 := s.reconstructKey(t)
+	var oldV []kyber.Scalar
+	for _, srv := range s.services {
+		srv.storage.Lock()
+		oldV = append(oldV, srv.storage.Shared[id].V.Clone())
+		srv.storage.Unlock()
+	}
+
+	// the other nodes apply the refresh in the background
+	var wg sync.WaitGroup
+	wg.Add(len(s.services) - 1)
+	for _, srv := range s.services[1:] {
+		srv.afterRefresh = wg.Done
+	}
+	require.NoError(t, s.services[0].refreshLTS(id))
+	wg.Wait()
+
+	for i, srv := range s.services {
+		srv.storage.Lock()
+		require.False(t, srv.storage.Shared[id].V.Equal(oldV[i]))
+		srv.storage.Unlock()
+	}
+	require.True(t, key1.Equal(s.reconstructKey(t)))
+
+	// the LTS can still be used
+	secret := []byte("secret key")
+	write := s.addWriteAndWait(t, secret)
+	read := s.addReadAndWait(t, write, s.signer.Ed25519.Point)
+	dk, err := s.services[0].DecryptKey(&DecryptKey{Read: *read, Write: *write})
+	require.NoError(t, err)
+	keyCopy, err := dk.RecoverKey(s.signer.Ed25519.Secret)
+	require.NoError(t, err)
+	require.Equal(t, secret, keyCopy)
+}
+
 // Try to change the roster to a new roster that is disjoint, which
 // should result in an error.
 func TestService_ReshareLTS_Different(t *testing.T) {
@@ -394,7 +551,32 @@ func newTSWithExtras(t *testing.T, nodes int, extras int) ts {
 	s.signer = darc.NewSignerEd25519(nil, nil)
 	s.createGenesis(t)
 
-	// Create LTS instance
+	s.ltsReply = s.createLTS(t, 1)
+	return s
+}
+
+// createLTS spawns an LTS instance for the ltsRoster and runs its DKG.
+func (s *ts) createLTS(t *testing.T, ctr uint64) *CreateLTSReply {
+	proof := s.spawnLTS(t, ctr)
+
+	// Start DKG
+	reply, err := s.services[0].CreateLTS(&CreateLTS{
+		Proof: *proof,
+	})
+	require.NoError(t, err)
+
+	reply2 := CreateLTSReply{
+		ByzCoinID:  s.gbReply.Skipblock.SkipChainID(),
+		InstanceID: byzcoin.NewInstanceID(proof.InclusionProof.Key()),
+		X:          reply.X,
+	}
+
+	require.True(t, reply.InstanceID.Equal(reply2.InstanceID))
+	return reply
+}
+
+// spawnLTS spawns an LTS instance for the ltsRoster and returns its proof.
+func (s *ts) spawnLTS(t *testing.T, ctr uint64) *byzcoin.Proof {
 	ltsInstInfoBuf, err := protobuf.Encode(&LtsInstanceInfo{*s.ltsRoster})
 	require.NoError(t, err)
 	inst := byzcoin.Instruction{
@@ -408,7 +590,7 @@ func newTSWithExtras(t *testing.T, nodes int, extras int) ts {
 				},
 			},
 		},
-		SignerCounter: []uint64{1},
+		SignerCounter: []uint64{ctr},
 	}
 	tx, err := s.cl.CreateTransaction(inst)
 	require.NoError(t, err)
@@ -419,21 +601,7 @@ func newTSWithExtras(t *testing.T, nodes int, extras int) ts {
 	// Get the proof
 	proof, err := s.cl.WaitProof(tx.Instructions[0].DeriveID(""), s.genesisMsg.BlockInterval, nil)
 	require.NoError(t, err)
-
-	// Start DKG
-	s.ltsReply, err = s.services[0].CreateLTS(&CreateLTS{
-		Proof: *proof,
-	})
-	require.NoError(t, err)
-
-	reply2 := CreateLTSReply{
-		ByzCoinID:  s.gbReply.Skipblock.SkipChainID(),
-		InstanceID: tx.Instructions[0].DeriveID(""),
-		X:          s.ltsReply.X,
-	}
-
-	require.True(t, s.ltsReply.InstanceID.Equal(reply2.InstanceID))
-	return s
+	return proof
 }
 
 func (s *ts) createGenesis(t *testing.T) {
@@ -6,6 +6,7 @@ import (
 
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/lightclient"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/kyber/v3/xof/keccak"
@@ -15,7 +16,11 @@ import (
 func init() {
 	network.RegisterMessages(CreateLTS{}, CreateLTSReply{},
 		Authorize{}, AuthorizeReply{},
-		DecryptKey{}, DecryptKeyReply{})
+		DecryptKey{}, DecryptKeyReply{},
+		DKGStatus{}, DKGStatusReply{},
+		GetLTSCommits{}, GetLTSCommitsReply{},
+		VerifyShare{}, VerifyShareReply{},
+		RecoverLTS{}, RecoverLTSReply{})
 }
 
 type suite interface {
@@ -39,33 +44,12 @@ type suite interface {
 //   it containing the reader-darc. If it is nil then we failed to embed the
 //   key because it is too long to represent the key using a point.
 func NewWrite(suite suites.Suite, ltsid byzcoin.InstanceID, writeDarc darc.ID, X kyber.Point, key []byte) *Write {
-	wr := &Write{LTSID: ltsid}
-	r := suite.Scalar().Pick(suite.RandomStream())
-	C := suite.Point().Mul(r, X)
-	wr.U = suite.Point().Mul(r, nil)
-
-	// Create proof
-	if len(key) > suite.Point().EmbedLen() {
+	wk, err := lightclient.EncodeKey(suite, ltsid.Slice(), writeDarc, X, key)
+	if err != nil {
 		return nil
 	}
-	kp := suite.Point().Embed(key, suite.RandomStream())
-	wr.C = suite.Point().Add(C, kp)
-
-	gBar := suite.Point().Embed(ltsid.Slice(), keccak.New(ltsid.Slice()))
-	wr.Ubar = suite.Point().Mul(r, gBar)
-	s := suite.Scalar().Pick(suite.RandomStream())
-	w := suite.Point().Mul(s, nil)
-	wBar := suite.Point().Mul(s, gBar)
-	hash := sha256.New()
-	wr.C.MarshalTo(hash)
-	wr.U.MarshalTo(hash)
-	wr.Ubar.MarshalTo(hash)
-	w.MarshalTo(hash)
-	wBar.MarshalTo(hash)
-	hash.Write(writeDarc)
-	wr.E = suite.Scalar().SetBytes(hash.Sum(nil))
-	wr.F = suite.Scalar().Add(s, suite.Scalar().Mul(wr.E, r))
-	return wr
+	return &Write{U: wk.U, Ubar: wk.Ubar, E: wk.E, F: wk.F, C: wk.C,
+		LTSID: ltsid}
 }
 
 // CheckProof verifies that the write-request has actually been created with
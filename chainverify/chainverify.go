@@ -0,0 +1,187 @@
+// Package chainverify checks the blocks and the forward links of the
+// skipchains, and the proofs of the ByzCoin tries, from their hashes and keys
+// only. It doesn't depend on onet, so that the lightclient package checks the
+// proofs in WebAssembly with the same code as the skipchain and byzcoin
+// packages.
+//
+// For more information, please see
+// https://github.com/dedis/cothority/blob/master/chainverify/README.md.
+package chainverify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
+	"go.dedis.ch/kyber/v3/sign/bls"
+)
+
+// The signature schemes of the forward links, which are the
+// skipchain.BlsSignatureSchemeIndex and skipchain.BdnSignatureSchemeIndex.
+const (
+	BlsScheme = uint32(iota)
+	BdnScheme
+)
+
+// ErrLinks is wrapped in the errors of the forward links that don't lead from
+// the trusted block.
+var ErrLinks = errors.New("the forward links don't follow the trusted block")
+
+// ErrLatest is wrapped in the error returned when the last forward link
+// doesn't point to the latest block.
+var ErrLatest = errors.New("the last forward link doesn't point to the latest block")
+
+// Block holds the fixed fields of a skipblock, which are hashed to its ID.
+type Block struct {
+	Index         int
+	Height        int
+	MaximumHeight int
+	BaseHeight    int
+	BackLinkIDs   [][]byte
+	VerifierIDs   [][]byte
+	GenesisID     []byte
+	Data          []byte
+	// Publics are the marshalled keys of the nodes of the roster of the
+	// block, if it has one.
+	Publics         [][]byte
+	SignatureScheme uint32
+}
+
+// Hash returns the hash of the block, which is its ID.
+func (b *Block) Hash() []byte {
+	hash := sha256.New()
+	for _, i := range []int{b.Index, b.Height, b.MaximumHeight,
+		b.BaseHeight} {
+		binary.Write(hash, binary.LittleEndian, int32(i))
+	}
+	for _, bl := range b.BackLinkIDs {
+		hash.Write(bl)
+	}
+	for _, v := range b.VerifierIDs {
+		hash.Write(v)
+	}
+	hash.Write(b.GenesisID)
+	hash.Write(b.Data)
+	for _, pub := range b.Publics {
+		hash.Write(pub)
+	}
+	// For backwards compatibility, the signature scheme is only added to
+	// the hash when different from the previous default (== 0)
+	if b.SignatureScheme > 0 {
+		binary.Write(hash, binary.LittleEndian, b.SignatureScheme)
+	}
+	return hash.Sum(nil)
+}
+
+// LinkHash returns the message signed for a forward link, which is
+// sha256(from|to|newRosterID), the ID of the new roster being nil if the
+// roster doesn't change.
+func LinkHash(from, to, newRosterID []byte) []byte {
+	hash := sha256.New()
+	hash.Write(from)
+	hash.Write(to)
+	hash.Write(newRosterID)
+	return hash.Sum(nil)
+}
+
+// VerifySignature checks the collective signature of the message by the keys
+// of a roster, of which at most a third can be missing. The signature is
+// followed by the mask of the signers, or signed by all of them without one.
+func VerifySignature(suite pairing.Suite, publics []kyber.Point, msg,
+	sig []byte, scheme uint32) error {
+	if scheme != BlsScheme && scheme != BdnScheme {
+		return errors.New("unknown signature scheme")
+	}
+	if len(publics) == 0 {
+		return errors.New("no public keys")
+	}
+	lenSig := suite.G1().PointLen()
+	if len(sig) < lenSig {
+		return errors.New("invalid signature length")
+	}
+	mask, err := sign.NewMask(suite, publics, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't create the mask: %v", err)
+	}
+	if len(sig) == lenSig {
+		for i := 0; i < mask.Len(); i++ {
+			mask.SetBit(i, true)
+		}
+	} else if err := mask.SetMask(sig[lenSig:]); err != nil {
+		return fmt.Errorf("invalid mask: %v", err)
+	}
+
+	if scheme == BlsScheme {
+		aggPub := bls.AggregatePublicKeys(suite, mask.Participants()...)
+		err = bls.Verify(suite, aggPub, msg, sig[:lenSig])
+	} else {
+		var aggPub kyber.Point
+		aggPub, err = bdn.AggregatePublicKeys(suite, mask)
+		if err == nil {
+			err = bdn.Verify(suite, aggPub, msg, sig[:lenSig])
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("didn't get a valid signature: %v", err)
+	}
+
+	threshold := len(publics) - (len(publics)-1)/3
+	if !sign.NewThresholdPolicy(threshold).Check(mask) {
+		return fmt.Errorf("only %d of the %d nodes signed",
+			mask.CountEnabled(), len(publics))
+	}
+	return nil
+}
+
+// Link is a forward link from a block to a newer one, signed by the roster
+// of the first.
+type Link struct {
+	From []byte
+	To   []byte
+	// NewRosterID is the ID of the roster of the newer block, if it is not
+	// the one of the first.
+	NewRosterID []byte
+	// NewPublics are the service keys of the nodes of the new roster.
+	NewPublics []kyber.Point
+	// Msg and Sig are the signed message and the collective signature.
+	Msg []byte
+	Sig []byte
+}
+
+// VerifyLinks checks that the links lead from the trusted block, whose
+// roster has the given service keys, to the latest block, each one being
+// signed by the roster of the block it starts from. The errors wrap ErrLinks
+// or ErrLatest.
+func VerifyLinks(trusted []byte, publics []kyber.Point, links []Link,
+	scheme uint32, latest []byte) error {
+	suite := pairing.NewSuiteBn256()
+	id := trusted
+	for i, l := range links {
+		if !bytes.Equal(l.Msg, LinkHash(l.From, l.To, l.NewRosterID)) {
+			return fmt.Errorf("%w: wrong hash of link %d", ErrLinks, i)
+		}
+		if err := VerifySignature(suite, publics, l.Msg, l.Sig,
+			scheme); err != nil {
+			return fmt.Errorf("%w: link %d: %v", ErrLinks, i, err)
+		}
+		if !bytes.Equal(l.From, id) {
+			return fmt.Errorf("%w: link %d doesn't follow the previous one",
+				ErrLinks, i)
+		}
+		id = l.To
+		if l.NewPublics != nil {
+			publics = l.NewPublics
+		}
+	}
+
+	if !bytes.Equal(latest, id) {
+		return ErrLatest
+	}
+	return nil
+}
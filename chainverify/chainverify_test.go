@@ -0,0 +1,169 @@
+package chainverify
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+var testSuite = pairing.NewSuiteBn256()
+
+// genKeys returns n BLS key pairs.
+func genKeys(n int) ([]kyber.Scalar, []kyber.Point) {
+	privates := make([]kyber.Scalar, n)
+	publics := make([]kyber.Point, n)
+	for i := range privates {
+		privates[i], publics[i] = bls.NewKeyPair(testSuite, random.New())
+	}
+	return privates, publics
+}
+
+// signLink signs the link with the keys whose index is in signers, and
+// appends the mask of the signers to the signature.
+func signLink(t *testing.T, l *Link, privates []kyber.Scalar,
+	publics []kyber.Point, signers ...int) {
+	l.Msg = LinkHash(l.From, l.To, l.NewRosterID)
+	mask, err := sign.NewMask(testSuite, publics, nil)
+	require.NoError(t, err)
+	var sigs [][]byte
+	for _, i := range signers {
+		sig, err := bls.Sign(testSuite, privates[i], l.Msg)
+		require.NoError(t, err)
+		sigs = append(sigs, sig)
+		require.NoError(t, mask.SetBit(i, true))
+	}
+	agg, err := bls.AggregateSignatures(testSuite, sigs...)
+	require.NoError(t, err)
+	l.Sig, err = agg.MarshalBinary()
+	require.NoError(t, err)
+	l.Sig = append(l.Sig, mask.Mask()...)
+}
+
+func TestBlock_Hash(t *testing.T) {
+	b := Block{Index: 1, GenesisID: []byte("genesis"), Data: []byte("data"),
+		Publics: [][]byte{[]byte("a"), []byte("b")}}
+	h := b.Hash()
+	require.Len(t, h, sha256.Size)
+
+	// The default scheme isn't hashed, so that the old IDs don't change.
+	b.SignatureScheme = BlsScheme
+	require.Equal(t, h, b.Hash())
+	b.SignatureScheme = BdnScheme
+	require.NotEqual(t, h, b.Hash())
+	b.SignatureScheme = BlsScheme
+
+	b.Publics = b.Publics[:1]
+	require.NotEqual(t, h, b.Hash())
+}
+
+func TestLinkHash(t *testing.T) {
+	h := sha256.Sum256([]byte("fromto"))
+	require.Equal(t, h[:], LinkHash([]byte("from"), []byte("to"), nil))
+	require.NotEqual(t, h[:], LinkHash([]byte("from"), []byte("to"),
+		[]byte("roster")))
+}
+
+func TestVerifySignature(t *testing.T) {
+	privates, publics := genKeys(4)
+	l := Link{From: []byte("from"), To: []byte("to")}
+
+	signLink(t, &l, privates, publics, 0, 1, 2, 3)
+	require.NoError(t, VerifySignature(testSuite, publics, l.Msg, l.Sig,
+		BlsScheme))
+	// Without the mask, all the nodes must have signed.
+	lenSig := testSuite.G1().PointLen()
+	require.NoError(t, VerifySignature(testSuite, publics, l.Msg,
+		l.Sig[:lenSig], BlsScheme))
+
+	signLink(t, &l, privates, publics, 0, 1, 3)
+	require.NoError(t, VerifySignature(testSuite, publics, l.Msg, l.Sig,
+		BlsScheme))
+	require.Error(t, VerifySignature(testSuite, publics, l.Msg,
+		l.Sig[:lenSig], BlsScheme))
+
+	signLink(t, &l, privates, publics, 0, 1)
+	err := VerifySignature(testSuite, publics, l.Msg, l.Sig, BlsScheme)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only 2 of the 4 nodes signed")
+
+	require.Error(t, VerifySignature(testSuite, publics, []byte("other"),
+		l.Sig, BlsScheme))
+	require.Error(t, VerifySignature(testSuite, nil, l.Msg, l.Sig,
+		BlsScheme))
+	err = VerifySignature(testSuite, publics, l.Msg, l.Sig, 2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown signature scheme")
+}
+
+func TestVerifyLinks(t *testing.T) {
+	privates, publics := genKeys(4)
+	newPrivates, newPublics := genKeys(4)
+
+	// The second link changes the roster, which signs the third one.
+	links := []Link{
+		{From: []byte("a"), To: []byte("b")},
+		{From: []byte("b"), To: []byte("c"), NewRosterID: []byte("roster"),
+			NewPublics: newPublics},
+		{From: []byte("c"), To: []byte("d")},
+	}
+	signLink(t, &links[0], privates, publics, 0, 1, 2, 3)
+	signLink(t, &links[1], privates, publics, 0, 1, 2)
+	signLink(t, &links[2], newPrivates, newPublics, 1, 2, 3)
+	require.NoError(t, VerifyLinks([]byte("a"), publics, links, BlsScheme,
+		[]byte("d")))
+
+	err := VerifyLinks([]byte("a"), publics, links, BlsScheme, []byte("c"))
+	require.True(t, errors.Is(err, ErrLatest))
+	err = VerifyLinks([]byte("b"), publics, links, BlsScheme, []byte("d"))
+	require.True(t, errors.Is(err, ErrLinks))
+
+	// The old roster can't sign after the change.
+	signLink(t, &links[2], privates, publics, 0, 1, 2, 3)
+	err = VerifyLinks([]byte("a"), publics, links, BlsScheme, []byte("d"))
+	require.True(t, errors.Is(err, ErrLinks))
+
+	// The message must be the hash of the link.
+	signLink(t, &links[2], newPrivates, newPublics, 1, 2, 3)
+	links[2].To = []byte("e")
+	err = VerifyLinks([]byte("a"), publics, links, BlsScheme, []byte("e"))
+	require.True(t, errors.Is(err, ErrLinks))
+}
+
+func TestTrieProof_Exists(t *testing.T) {
+	key := []byte("key")
+	hashKey := sha256.Sum256(key)
+	bits := Bits(hashKey[:])
+
+	// A trie with a single interior node, the leaf of the key on one side
+	// and an empty node on the other.
+	nonce := []byte("nonce")
+	leaf := LeafNode{Prefix: bits[:1], Key: key, Value: []byte("value")}
+	empty := EmptyNode{Prefix: []bool{!bits[0]}}
+	root := InteriorNode{Left: leaf.Hash(nonce), Right: empty.Hash(nonce)}
+	if !bits[0] {
+		root.Left, root.Right = root.Right, root.Left
+	}
+	p := TrieProof{Interiors: []InteriorNode{root}, Leaf: leaf, Nonce: nonce}
+	require.Equal(t, root.Hash(), p.GetRoot())
+
+	ok, err := p.Exists(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	p.Leaf.Value = []byte("other")
+	_, err = p.Exists(key)
+	require.Error(t, err)
+	p.Leaf.Value = leaf.Value
+
+	_, err = p.Exists(nil)
+	require.Error(t, err)
+	_, err = (&TrieProof{}).Exists(key)
+	require.Error(t, err)
+}
@@ -0,0 +1,166 @@
+package chainverify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// The types of the nodes of a trie, which prefix their hashes, as in the trie
+// package.
+const (
+	typeEmpty = byte(2)
+	typeLeaf  = byte(3)
+)
+
+// TrieProof is the proof of the presence or the absence of a key in a
+// ByzCoin trie. It encodes as trie.Proof.
+type TrieProof struct {
+	Interiors []InteriorNode
+	Leaf      LeafNode
+	Empty     EmptyNode
+	Nonce     []byte
+}
+
+// InteriorNode is a node of the trie with two children.
+type InteriorNode struct {
+	Left  []byte
+	Right []byte
+}
+
+// EmptyNode is an empty leaf of the trie.
+type EmptyNode struct {
+	Prefix []bool
+}
+
+// LeafNode is a leaf of the trie holding a key.
+type LeafNode struct {
+	Prefix []bool
+	Key    []byte
+	Value  []byte
+}
+
+// Hash returns the hash of the node, which is its key in the trie.
+func (n *InteriorNode) Hash() []byte {
+	h := sha256.New()
+	h.Write(n.Left)
+	h.Write(n.Right)
+	return h.Sum(nil)
+}
+
+// Hash returns the hash of the node in the trie with the nonce.
+func (n *EmptyNode) Hash(nonce []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{typeEmpty})
+	h.Write(nonce)
+	writePrefix(h, n.Prefix)
+	return h.Sum(nil)
+}
+
+// Hash returns the hash of the node in the trie with the nonce.
+func (n *LeafNode) Hash(nonce []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{typeLeaf})
+	h.Write(nonce)
+	writePrefix(h, n.Prefix)
+	h.Write(n.Key)
+	h.Write(n.Value)
+	return h.Sum(nil)
+}
+
+// writePrefix writes the bits of the prefix, followed by its length.
+func writePrefix(w interface{ Write([]byte) (int, error) }, prefix []bool) {
+	buf := make([]byte, (len(prefix)+7)/8)
+	for i, bit := range prefix {
+		if bit {
+			buf[i/8] |= (1 << 7) >> uint(i%8)
+		}
+	}
+	w.Write(buf)
+	lBuf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(lBuf, uint32(len(prefix)))
+	w.Write(lBuf)
+}
+
+// GetRoot returns the root of the trie the proof is for.
+func (p *TrieProof) GetRoot() []byte {
+	if len(p.Interiors) == 0 {
+		return nil
+	}
+	return p.Interiors[0].Hash()
+}
+
+// Exists returns true if the proof shows the key is in the trie, false if it
+// shows it is absent, and an error if it is invalid. The path to the key is
+// given by the bits of its hash, as in the tries of ByzCoin.
+func (p *TrieProof) Exists(key []byte) (bool, error) {
+	if key == nil {
+		return false, errors.New("key is nil")
+	}
+	hashKey := sha256.Sum256(key)
+	return p.ExistsBits(key, Bits(hashKey[:]))
+}
+
+// ExistsBits is Exists for the path to the key given by its bits, for the
+// tries that don't hash their keys.
+func (p *TrieProof) ExistsBits(key []byte, bits []bool) (bool, error) {
+	if key == nil {
+		return false, errors.New("key is nil")
+	}
+	if len(p.Interiors) == 0 {
+		return false, errors.New("no interior nodes")
+	}
+	if len(p.Interiors) >= len(bits) {
+		return false, errors.New("too many interior nodes")
+	}
+
+	expected := p.Interiors[0].Hash()
+	var i int
+	for i = range p.Interiors {
+		if !bytes.Equal(expected, p.Interiors[i].Hash()) {
+			return false, errors.New("invalid hash chain")
+		}
+		if bits[i] {
+			expected = p.Interiors[i].Left
+		} else {
+			expected = p.Interiors[i].Right
+		}
+	}
+	switch {
+	case bytes.Equal(expected, p.Leaf.Hash(p.Nonce)):
+		if !equalBits(bits[:i+1], p.Leaf.Prefix) {
+			return false, errors.New("invalid prefix in leaf node")
+		}
+		return bytes.Equal(p.Leaf.Key, key), nil
+	case bytes.Equal(expected, p.Empty.Hash(p.Nonce)):
+		if !equalBits(bits[:i+1], p.Empty.Prefix) {
+			return false, errors.New("invalid prefix in empty node")
+		}
+		return false, nil
+	default:
+		return false, errors.New("invalid edge node")
+	}
+}
+
+// Bits returns the bits of the buffer, the most significant bit of each
+// byte first.
+func Bits(buf []byte) []bool {
+	bits := make([]bool, len(buf)*8)
+	for i := range bits {
+		bits[i] = (buf[i/8]<<uint(i%8))&(1<<7) > 0
+	}
+	return bits
+}
+
+func equalBits(a, b []bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
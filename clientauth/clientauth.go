@@ -0,0 +1,179 @@
+// Package clientauth restricts the websocket API of a conode to the clients
+// presenting a certificate signed by a given CA, and maps the certificates to
+// the services they can use.
+//
+// The conode loads a Config, which sets the TLS configuration of its
+// websocket and the Policy checked by Authorize, which clienthook.Process
+// calls for the requests of every service.
+package clientauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Any matches every common name in a Rule, and every service in its
+// Services.
+const Any = "*"
+
+// Config is the client authentication of a conode, as read from its TOML
+// file:
+//
+//	CA = "clients-ca.pem"
+//
+//	[[Rule]]
+//	CommonName = "bcadmin"
+//	Services = ["ByzCoin", "Skipchain"]
+//
+//	[[Rule]]
+//	CommonName = "*"
+//	Services = ["Status", "Calypso/DecryptKey"]
+type Config struct {
+	// CA is the PEM file of the certificates of the CAs signing the client
+	// certificates. A relative path is relative to the config file.
+	CA string
+	// Rule are the rules of the Policy.
+	Rule []Rule
+}
+
+// Rule gives the services a client certificate can use.
+type Rule struct {
+	// CommonName is the common name of the subject of the certificate, or
+	// Any for all the certificates signed by the CA.
+	CommonName string
+	// Services are the service names, like "ByzCoin", or the endpoints, like
+	// "ByzCoin/GetProof", that can be used. Any allows all of them.
+	Services []string
+}
+
+// LoadConfig reads the client authentication of a conode from a TOML file.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	_, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read client authentication: %v", err)
+	}
+	if cfg.CA == "" {
+		return nil, errors.New("the CA of the client certificates is missing")
+	}
+	if !filepath.IsAbs(cfg.CA) {
+		cfg.CA = filepath.Join(filepath.Dir(path), cfg.CA)
+	}
+	for i, r := range cfg.Rule {
+		if r.CommonName == "" {
+			return nil, fmt.Errorf("rule %d has no CommonName", i)
+		}
+	}
+	return cfg, nil
+}
+
+// Apply sets the TLS configuration of a websocket so that it requires a
+// client certificate signed by the CA. The configuration must already hold
+// the certificate of the websocket.
+func (cfg *Config) Apply(tlsConfig *tls.Config) error {
+	buf, err := ioutil.ReadFile(cfg.CA)
+	if err != nil {
+		return fmt.Errorf("couldn't read the CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return fmt.Errorf("no certificate found in %s", cfg.CA)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return nil
+}
+
+// Policy returns the policy of the rules.
+func (cfg *Config) Policy() *Policy {
+	return &Policy{Rules: cfg.Rule}
+}
+
+// Policy maps the client certificates to the services they can use. The
+// first rule matching the certificate applies, and a certificate matching no
+// rule can't use any service.
+type Policy struct {
+	Rules []Rule
+}
+
+// Allows returns true if the common name can use the endpoint of the
+// service.
+func (p *Policy) Allows(commonName, service, endpoint string) bool {
+	for _, r := range p.Rules {
+		if r.CommonName != Any && r.CommonName != commonName {
+			continue
+		}
+		for _, s := range r.Services {
+			if s == Any || s == service || s == service+"/"+endpoint {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+var policyMutex sync.RWMutex
+var policy *Policy
+
+// SetPolicy sets the policy checked by Authorize. A nil policy allows all the
+// requests, which is the default.
+func SetPolicy(p *Policy) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+	policy = p
+}
+
+// Authorize returns an error if the client of the request can't use the
+// endpoint of the service under the current policy. It is called by
+// clienthook.Process.
+func Authorize(req *http.Request, service, endpoint string) error {
+	policyMutex.RLock()
+	p := policy
+	policyMutex.RUnlock()
+	if p == nil {
+		return nil
+	}
+
+	if req == nil || req.TLS == nil || len(req.TLS.VerifiedChains) == 0 ||
+		len(req.TLS.VerifiedChains[0]) == 0 {
+		return errors.New("a verified client certificate is required")
+	}
+	cn := req.TLS.VerifiedChains[0][0].Subject.CommonName
+	endpoint = strings.TrimPrefix(endpoint, "/")
+	if !p.Allows(cn, service, endpoint) {
+		return fmt.Errorf("client %s is not allowed to use %s/%s", cn,
+			service, endpoint)
+	}
+	return nil
+}
+
+// IsLocal returns true if the client of the request runs on the host of the
+// conode: it connects from a loopback address, and the request wasn't
+// forwarded by a proxy. The proxies, like the websocket front of the conode,
+// connect from the loopback for all their clients, but add the
+// X-Forwarded-For header. The services check it for the endpoints restricted
+// to the admin of the conode.
+func IsLocal(req *http.Request) bool {
+	if req == nil {
+		return false
+	}
+	if _, ok := req.Header["X-Forwarded-For"]; ok {
+		return false
+	}
+	h, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	return net.ParseIP(h).IsLoopback()
+}
@@ -0,0 +1,134 @@
+package clientauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientauth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newCertificate(t, "ca")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+		0600))
+	fn := filepath.Join(dir, "clientauth.toml")
+	require.NoError(t, ioutil.WriteFile(fn, []byte(`CA = "ca.pem"
+
+[[Rule]]
+CommonName = "admin"
+Services = ["*"]
+`), 0600))
+
+	cfg, err := LoadConfig(fn)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "ca.pem"), cfg.CA)
+	require.Equal(t, []Rule{{CommonName: "admin", Services: []string{Any}}},
+		cfg.Rule)
+
+	tlsConfig := &tls.Config{}
+	require.NoError(t, cfg.Apply(tlsConfig))
+	require.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+	require.NotNil(t, tlsConfig.ClientCAs)
+
+	cfg.CA = fn
+	require.Error(t, cfg.Apply(&tls.Config{}))
+
+	require.NoError(t, ioutil.WriteFile(fn, []byte(`[[Rule]]
+CommonName = "admin"
+`), 0600))
+	_, err = LoadConfig(fn)
+	require.Error(t, err)
+}
+
+func TestPolicy_Allows(t *testing.T) {
+	p := &Policy{Rules: []Rule{
+		{CommonName: "admin", Services: []string{Any}},
+		{CommonName: "reader", Services: []string{"Calypso/DecryptKey"}},
+		{CommonName: "blocked"},
+		{CommonName: Any, Services: []string{"Status"}},
+	}}
+
+	require.True(t, p.Allows("admin", "ByzCoin", "AddTxRequest"))
+	require.True(t, p.Allows("reader", "Calypso", "DecryptKey"))
+	require.False(t, p.Allows("reader", "Calypso", "Authorise"))
+	require.False(t, p.Allows("reader", "Status", "Request"))
+	require.False(t, p.Allows("blocked", "Status", "Request"))
+	require.True(t, p.Allows("other", "Status", "Request"))
+	require.False(t, p.Allows("other", "ByzCoin", "GetProof"))
+	require.False(t, (&Policy{}).Allows("admin", "Status", "Request"))
+}
+
+func TestAuthorize(t *testing.T) {
+	defer SetPolicy(nil)
+
+	req := &http.Request{}
+	require.NoError(t, Authorize(req, "ByzCoin", "GetProof"))
+
+	SetPolicy(&Policy{Rules: []Rule{
+		{CommonName: "admin", Services: []string{"ByzCoin"}},
+	}})
+	require.Error(t, Authorize(req, "ByzCoin", "GetProof"))
+
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{
+		{newCertificate(t, "admin")},
+	}}
+	require.NoError(t, Authorize(req, "ByzCoin", "GetProof"))
+	require.Error(t, Authorize(req, "Calypso", "DecryptKey"))
+
+	req.TLS.VerifiedChains[0][0] = newCertificate(t, "other")
+	require.Error(t, Authorize(req, "ByzCoin", "GetProof"))
+}
+
+func TestIsLocal(t *testing.T) {
+	require.False(t, IsLocal(nil))
+	for addr, local := range map[string]bool{
+		"127.0.0.1:1234": true,
+		"[::1]:1234":     true,
+		"10.0.0.1:1234":  false,
+		"127.0.0.1":      false,
+	} {
+		req := &http.Request{RemoteAddr: addr, Header: http.Header{}}
+		require.Equal(t, local, IsLocal(req), addr)
+	}
+
+	// A proxy on the host forwards the requests of remote clients.
+	req := &http.Request{RemoteAddr: "127.0.0.1:1234", Header: http.Header{}}
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	require.False(t, IsLocal(req))
+}
+
+func newCertificate(t *testing.T, cn string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	buf, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey,
+		key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(buf)
+	require.NoError(t, err)
+	return cert
+}
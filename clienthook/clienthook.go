@@ -0,0 +1,46 @@
+// Package clienthook runs the checks of a conode on the requests of the
+// clients, before they reach the endpoints of its services.
+//
+// Every service overrides the ProcessClientRequest of its
+// onet.ServiceProcessor with Process, so that the client authentication, the
+// API tokens, the admission control and the tracing apply to all of its
+// endpoints in the same order.
+package clienthook
+
+import (
+	"net/http"
+
+	"go.dedis.ch/cothority/v3/admission"
+	"go.dedis.ch/cothority/v3/apitoken"
+	"go.dedis.ch/cothority/v3/clientauth"
+	"go.dedis.ch/cothority/v3/tracing"
+	"go.dedis.ch/onet/v3"
+)
+
+// ProcessFunc processes a request of a client, like the
+// ProcessClientRequest of an onet.ServiceProcessor.
+type ProcessFunc func(req *http.Request, path string, buf []byte) ([]byte,
+	*onet.StreamingTunnel, error)
+
+// Process checks the request of a client to the endpoint path of the
+// service with, in order, the client authentication, the API tokens and the
+// admission control of the conode, then gives it to next while tracing it.
+func Process(req *http.Request, service, path string, buf []byte,
+	next ProcessFunc) ([]byte, *onet.StreamingTunnel, error) {
+	if err := clientauth.Authorize(req, service, path); err != nil {
+		return nil, nil, err
+	}
+	if err := apitoken.Authorize(req, service, path); err != nil {
+		return nil, nil, err
+	}
+	release, err := admission.Admit(req, service, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	done := tracing.Request(req, service, path)
+	reply, tunnel, err := next(req, path, buf)
+	done(err)
+	return reply, tunnel, err
+}
@@ -0,0 +1,55 @@
+package clienthook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/apitoken"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestProcess(t *testing.T) {
+	defer apitoken.SetPolicy(nil)
+	kp := key.NewKeyPair(cothority.Suite)
+	apitoken.SetPolicy(&apitoken.Policy{Key: kp.Public,
+		Endpoints: []string{"ByzCoin/AddTransaction"}})
+
+	var called int
+	next := func(req *http.Request, path string, buf []byte) ([]byte,
+		*onet.StreamingTunnel, error) {
+		called++
+		return buf, nil, nil
+	}
+
+	// the endpoint without a token is not processed
+	req := &http.Request{Header: http.Header{}, RemoteAddr: "10.0.0.1:1234"}
+	_, _, err := Process(req, "ByzCoin", "AddTransaction", []byte{1}, next)
+	require.Error(t, err)
+	require.Equal(t, 0, called)
+
+	// the other endpoints are
+	reply, _, err := Process(req, "ByzCoin", "GetProof", []byte{1}, next)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, reply)
+	require.Equal(t, 1, called)
+
+	tok, err := apitoken.Issue(kp.Private, []string{"ByzCoin"},
+		time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	_, _, err = Process(req, "ByzCoin", "AddTransaction", []byte{1}, next)
+	require.NoError(t, err)
+	require.Equal(t, 2, called)
+
+	// the errors of the service are returned
+	_, _, err = Process(req, "ByzCoin", "GetProof", nil,
+		func(*http.Request, string, []byte) ([]byte, *onet.StreamingTunnel, error) {
+			return nil, nil, errors.New("failed")
+		})
+	require.EqualError(t, err, "failed")
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	cli "github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/backup"
+	"go.dedis.ch/cothority/v3/keyrotation"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/encoding"
+	"go.dedis.ch/onet/v3/app"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+var backupDestinationFlag = cli.StringFlag{
+	Name:   "destination",
+	EnvVar: "COTHORITY_BACKUP_DESTINATION",
+	Usage:  "directory or s3://bucket/prefix?region= URL of the snapshots",
+}
+
+var backupCommand = cli.Command{
+	Name:  "backup",
+	Usage: "List, verify and restore the snapshots of the database of this conode",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "List the snapshots of this conode",
+			Action: backupList,
+			Flags: []cli.Flag{
+				backupDestinationFlag,
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "list the snapshots of all the conodes",
+				},
+			},
+		},
+		{
+			Name:      "verify",
+			Usage:     "Check that a snapshot is complete and signed by this conode",
+			ArgsUsage: "[name], the latest snapshot by default",
+			Action:    backupVerify,
+			Flags:     []cli.Flag{backupDestinationFlag},
+		},
+		{
+			Name:      "restore",
+			Usage:     "Replace the database of the stopped conode by a verified snapshot",
+			ArgsUsage: "[name], the latest snapshot by default",
+			Action:    backupRestore,
+			Flags: []cli.Flag{
+				backupDestinationFlag,
+				cli.StringFlag{
+					Name:  "db",
+					Usage: "database file of the conode",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "replace an existing database file",
+				},
+			},
+		},
+	},
+}
+
+// transitionSuffix is added to the config file for the file of the last
+// transition of its key.
+const transitionSuffix = ".transition"
+
+// saveTransition writes the transition of the key of the config next to it,
+// so that the commands accept the old key without the database.
+func saveTransition(config string, t *keyrotation.Transition) error {
+	buf, err := protobuf.Encode(t)
+	if err != nil {
+		return fmt.Errorf("couldn't encode the transition: %v", err)
+	}
+	if err := ioutil.WriteFile(config+transitionSuffix, buf, 0600); err != nil {
+		return fmt.Errorf("couldn't save the transition: %v", err)
+	}
+	return nil
+}
+
+// loadTransition registers the transition saved next to the config, if any,
+// so that keyrotation.Keys returns the old key in its grace window.
+func loadTransition(config string) error {
+	buf, err := ioutil.ReadFile(config + transitionSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't read the transition: %v", err)
+	}
+	t := &keyrotation.Transition{}
+	err = protobuf.DecodeWithConstructors(buf, t,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return fmt.Errorf("couldn't decode the transition: %v", err)
+	}
+	if err := t.Verify(); err != nil {
+		return fmt.Errorf("invalid transition: %v", err)
+	}
+	keyrotation.Register(t)
+	return nil
+}
+
+// backupDestination returns the destination of the snapshots and the keys
+// of the conode of the config: its key, and the ones it replaced that are
+// still in their grace window.
+func backupDestination(c *cli.Context) (backup.Destination, []kyber.Point,
+	error) {
+	if c.String("destination") == "" {
+		return nil, nil, errors.New("missing the destination of the snapshots")
+	}
+	dst, err := backup.ParseDestination(c.String("destination"))
+	if err != nil {
+		return nil, nil, err
+	}
+	config := c.GlobalString("config")
+	conf, _, err := loadConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't load config: %v", err)
+	}
+	public, err := encoding.StringHexToPoint(cothority.Suite, conf.Public)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't parse the public key: %v", err)
+	}
+	if err := loadTransition(config); err != nil {
+		return nil, nil, err
+	}
+	return dst, keyrotation.Keys(public, time.Now()), nil
+}
+
+// backupManifest returns the manifest of the snapshot named in the
+// arguments, or of the latest snapshot of the conode.
+func backupManifest(c *cli.Context, dst backup.Destination,
+	keys []kyber.Point) (*backup.Manifest, error) {
+	if c.NArg() > 0 {
+		return backup.GetManifest(dst, c.Args().First())
+	}
+	list, err := backup.List(dst, keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no snapshot of this conode")
+	}
+	return list[len(list)-1], nil
+}
+
+func backupList(c *cli.Context) error {
+	dst, keys, err := backupDestination(c)
+	if err != nil {
+		return err
+	}
+	if c.Bool("all") {
+		keys = nil
+	}
+	list, err := backup.List(dst, keys)
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		fmt.Printf("%s\t%s\t%d bytes\n", m.Name,
+			time.Unix(m.Time, 0).Format(time.RFC3339), m.Size)
+	}
+	return nil
+}
+
+func backupVerify(c *cli.Context) error {
+	dst, keys, err := backupDestination(c)
+	if err != nil {
+		return err
+	}
+	m, err := backupManifest(c, dst, keys)
+	if err != nil {
+		return err
+	}
+	if err := backup.Download(dst, m, keys, ioutil.Discard); err != nil {
+		return fmt.Errorf("snapshot %s is invalid: %v", m.Name, err)
+	}
+	fmt.Fprintf(os.Stderr, "Snapshot %s is valid\n", m.Name)
+	return nil
+}
+
+func backupRestore(c *cli.Context) error {
+	if c.String("db") == "" {
+		return errors.New("missing the database file")
+	}
+	dst, keys, err := backupDestination(c)
+	if err != nil {
+		return err
+	}
+	m, err := backupManifest(c, dst, keys)
+	if err != nil {
+		return err
+	}
+	if err := backup.Restore(dst, m, keys, c.String("db"),
+		c.Bool("force")); err != nil {
+		return fmt.Errorf("couldn't restore %s: %v", m.Name, err)
+	}
+	fmt.Fprintf(os.Stderr, "Restored the snapshot %s of %s to %s\n", m.Name,
+		time.Unix(m.Time, 0).Format(time.RFC3339), c.String("db"))
+	return nil
+}
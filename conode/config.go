@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	cli "github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/app"
+)
+
+// configPath returns the path to read the config file from. If the file is
+// sealed, it is a pipe giving the config opened with the database key, so
+// that the private key is never written in plaintext, and done must be
+// called once it is read.
+func configPath(config string) (path string, sealed bool, done func(),
+	err error) {
+	buf, err := ioutil.ReadFile(config)
+	if err != nil {
+		return "", false, nil, err
+	}
+	if !atrest.IsSealed(buf) {
+		return config, false, func() {}, nil
+	}
+	key, err := atrest.LoadKey()
+	if err != nil {
+		return "", true, nil, fmt.Errorf("couldn't load the database key: %v",
+			err)
+	}
+	if key == nil {
+		return "", true, nil, atrest.ErrNoKey
+	}
+	plain, err := key.OpenConfig(buf)
+	if err != nil {
+		return "", true, nil, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", true, nil, err
+	}
+	go func() {
+		w.Write(plain)
+		w.Close()
+	}()
+	return fmt.Sprintf("/dev/fd/%d", r.Fd()), true, func() { r.Close() }, nil
+}
+
+// loadConfig loads the config file, sealed or not, and returns whether it
+// was sealed.
+func loadConfig(config string) (*app.CothorityConfig, bool, error) {
+	path, sealed, done, err := configPath(config)
+	if err != nil {
+		return nil, sealed, err
+	}
+	defer done()
+	conf, err := app.LoadCothority(path)
+	return conf, sealed, err
+}
+
+// parseConfig is app.ParseCothority for a config file sealed or not.
+func parseConfig(config string) (*app.CothorityConfig, *onet.Server, error) {
+	path, _, done, err := configPath(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+	return app.ParseCothority(path)
+}
+
+// saveConfig writes the config file, sealed with the database key if sealed
+// is true.
+func saveConfig(conf *app.CothorityConfig, config string, sealed bool) error {
+	if !sealed {
+		return conf.Save(config)
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(conf); err != nil {
+		return fmt.Errorf("couldn't encode the config: %v", err)
+	}
+	return writeSealedConfig(config, buf.Bytes())
+}
+
+// writeSealedConfig seals the plaintext config with the database key and
+// replaces the file with it.
+func writeSealedConfig(config string, plain []byte) error {
+	key, err := atrest.LoadKey()
+	if err != nil {
+		return fmt.Errorf("couldn't load the database key: %v", err)
+	}
+	if key == nil {
+		return errors.New("a database key is needed to seal the config, " +
+			"see 'conode dbkey'")
+	}
+	sealed, err := key.SealConfig(plain)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(config), ".private-*.toml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), config)
+}
+
+// sealConfig encrypts the config file, with the private key of the conode,
+// with the database key.
+func sealConfig(c *cli.Context) error {
+	config := c.GlobalString("config")
+	buf, err := ioutil.ReadFile(config)
+	if err != nil {
+		return err
+	}
+	if atrest.IsSealed(buf) {
+		return errors.New("the config is already sealed")
+	}
+	if _, err := app.LoadCothority(config); err != nil {
+		return fmt.Errorf("couldn't load config: %v", err)
+	}
+	if err := writeSealedConfig(config, buf); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Sealed %s with the database key\n", config)
+	return nil
+}
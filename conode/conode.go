@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
@@ -25,9 +26,13 @@ import (
 
 	cli "github.com/urfave/cli"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/apitoken"
+	"go.dedis.ch/cothority/v3/atrest"
 	_ "go.dedis.ch/cothority/v3/evoting/service"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	_ "go.dedis.ch/cothority/v3/skipchain"
 	status "go.dedis.ch/cothority/v3/status/service"
+	"go.dedis.ch/cothority/v3/tracing"
 	"go.dedis.ch/kyber/v3/util/encoding"
 	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3/app"
@@ -81,13 +86,92 @@ func main() {
 					Usage: "the description to use",
 					Value: "configured in non-interactive mode",
 				},
+				cli.StringFlag{
+					Name:  "listen",
+					Usage: "address to bind to, when it isn't the advertised host and port, like behind a NAT",
+				},
+				cli.StringFlag{
+					Name:  "url",
+					Usage: "public URL of the websocket, like https://example.org/conode behind a reverse proxy",
+				},
 			},
 		},
 		{
 			Name:   "server",
 			Usage:  "Start cothority server",
 			Action: runServer,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "grace",
+					Value: 10 * time.Second,
+					Usage: "how long to wait for the running requests and protocols when shutting down",
+				},
+				cli.StringFlag{
+					Name:   "otlp",
+					EnvVar: "OTEL_EXPORTER_OTLP_ENDPOINT",
+					Usage:  "OTLP/HTTP endpoint of an OpenTelemetry collector to send the tracing spans to, e.g. http://localhost:4318",
+				},
+				cli.StringFlag{
+					Name:  "client-auth",
+					Usage: "TOML file of the CA and the rules of the client certificates required by the websocket",
+				},
+				cli.StringFlag{
+					Name:  "network",
+					Usage: "TOML file of the websocket front and the outbound connections, for a conode behind a NAT or a reverse proxy",
+				},
+				cli.StringFlag{
+					Name:  "admission",
+					Usage: "TOML file of the limits of the rate, concurrency and quotas of the requests of the clients",
+				},
+				cli.BoolFlag{
+					Name:  "api-tokens",
+					Usage: "require a token issued with 'conode token' for the privileged endpoints",
+				},
+				cli.BoolFlag{
+					Name:  "seal-db",
+					Usage: "encrypt the values of the database written before the database key was set, which are refused otherwise",
+				},
+			},
+		},
+		{
+			Name:   "token",
+			Usage:  "Issue an API token for the privileged endpoints of this conode",
+			Action: issueToken,
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "scope",
+					Usage: "service, like Calypso, or endpoint, like Calypso/CreateLTS, the token can be used for, or * for all",
+				},
+				cli.DurationFlag{
+					Name:  "expiry",
+					Value: 24 * time.Hour,
+					Usage: "how long the token is valid",
+				},
+			},
+		},
+		{
+			Name:   "dbkey",
+			Usage:  "Generate a key to encrypt the databases, to give to the server in " + atrest.EnvKey,
+			Action: generateDBKey,
+		},
+		{
+			Name:   "seal-config",
+			Usage:  "Encrypt the config file, with the private key of the conode, with the database key of " + atrest.EnvKey,
+			Action: sealConfig,
+		},
+		{
+			Name:   "rotate",
+			Usage:  "Replace the identity key of this running conode in the rosters it is part of",
+			Action: rotateKey,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "grace",
+					Value: 72 * time.Hour,
+					Usage: "how long what was signed by the old key is still accepted, at most a week",
+				},
+			},
 		},
+		backupCommand,
 		{
 			Name:      "check",
 			Aliases:   []string{"c"},
@@ -150,7 +234,123 @@ func runServer(ctx *cli.Context) error {
 	if raiseFdLimit != nil {
 		raiseFdLimit()
 	}
-	app.RunServer(config)
+	if _, err := os.Stat(config); os.IsNotExist(err) {
+		return fmt.Errorf("configuration file does not exist: %s", config)
+	}
+	if endpoint := ctx.String("otlp"); endpoint != "" {
+		host, _ := os.Hostname()
+		stop, err := tracing.EnableOTLP(endpoint, DefaultName, "host.name",
+			host)
+		if err != nil {
+			return fmt.Errorf("couldn't enable tracing: %v", err)
+		}
+		defer stop()
+		log.Info("Sending the tracing spans to", endpoint)
+	}
+	atrest.SetMigrate(ctx.Bool("seal-db"))
+	return serve(config, ctx.String("client-auth"), ctx.String("network"),
+		ctx.String("admission"), ctx.Bool("api-tokens"), ctx.Duration("grace"))
+}
+
+// issueToken prints a new API token signed with the private key of the
+// conode.
+func issueToken(c *cli.Context) error {
+	scopes := c.StringSlice("scope")
+	if len(scopes) == 0 {
+		return errors.New("please give the scopes of the token with --scope")
+	}
+	conf, _, err := loadConfig(c.GlobalString("config"))
+	if err != nil {
+		return fmt.Errorf("couldn't load config: %v", err)
+	}
+	private, err := encoding.StringHexToScalar(cothority.Suite, conf.Private)
+	if err != nil {
+		return fmt.Errorf("couldn't parse the private key: %v", err)
+	}
+
+	expiry := time.Now().Add(c.Duration("expiry"))
+	tok, err := apitoken.Issue(private, scopes, expiry)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Token for %v valid until %s\n", scopes,
+		expiry.Format(time.RFC3339))
+	fmt.Println(tok)
+	return nil
+}
+
+// generateDBKey prints a new key for the encryption of the databases.
+func generateDBKey(c *cli.Context) error {
+	secret, err := atrest.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Keep this key safe: the databases can't be read "+
+		"without it\n")
+	fmt.Println(hex.EncodeToString(secret))
+	return nil
+}
+
+// rotateKey generates a new identity key for the conode, has the running
+// conode replace its old key by the new one in its rosters, and writes the
+// new key to the config. The old config is kept with a .old suffix.
+func rotateKey(c *cli.Context) error {
+	config := c.GlobalString("config")
+	conf, sealed, err := loadConfig(config)
+	if err != nil {
+		return fmt.Errorf("couldn't load config: %v", err)
+	}
+	si, err := conf.GetServerIdentity()
+	if err != nil {
+		return fmt.Errorf("couldn't get the identity: %v", err)
+	}
+	private, err := encoding.StringHexToScalar(cothority.Suite, conf.Private)
+	if err != nil {
+		return fmt.Errorf("couldn't parse the private key: %v", err)
+	}
+
+	kp := key.NewKeyPair(cothority.Suite)
+	t, err := keyrotation.New(private, kp.Private,
+		time.Now().Add(c.Duration("grace")))
+	if err != nil {
+		return err
+	}
+	reply, err := keyrotation.NewClient().Rotate(si, t)
+	if err != nil {
+		return fmt.Errorf("couldn't rotate the key: %v", err)
+	}
+	for _, res := range reply.Results {
+		if res.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %d rosters, error: %s\n", res.Service,
+				res.Rosters, res.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %d rosters\n", res.Service, res.Rosters)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Announced the new key to %d nodes\n",
+		reply.Announced)
+
+	if err := saveConfig(conf, config+".old", sealed); err != nil {
+		return fmt.Errorf("couldn't back up the config: %v", err)
+	}
+	if err := saveTransition(config, t); err != nil {
+		return err
+	}
+	conf.Public, err = encoding.PointToStringHex(cothority.Suite, kp.Public)
+	if err != nil {
+		return fmt.Errorf("couldn't encode the public key: %v", err)
+	}
+	conf.Private, err = encoding.ScalarToStringHex(cothority.Suite, kp.Private)
+	if err != nil {
+		return fmt.Errorf("couldn't encode the private key: %v", err)
+	}
+	if err := saveConfig(conf, config, sealed); err != nil {
+		return fmt.Errorf("couldn't save the config: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the new key to %s, the old one is accepted "+
+		"until %s.\nCopy the database of the conode to %s.db in its "+
+		"directory, then restart it to use the new key.\n", config,
+		time.Unix(t.NotAfter, 0).Format(time.RFC3339), conf.Public)
 	return nil
 }
 
@@ -224,12 +424,14 @@ func setup(c *cli.Context) error {
 		priv, _ := encoding.ScalarToStringHex(cothority.Suite, kp.Private)
 
 		conf := &app.CothorityConfig{
-			Suite:       cothority.Suite.String(),
-			Public:      pub,
-			Private:     priv,
-			Address:     serverBinding,
-			Description: c.String("description"),
-			Services:    app.GenerateServiceKeyPairs(),
+			Suite:         cothority.Suite.String(),
+			Public:        pub,
+			Private:       priv,
+			Address:       serverBinding,
+			ListenAddress: c.String("listen"),
+			Description:   c.String("description"),
+			URL:           c.String("url"),
+			Services:      app.GenerateServiceKeyPairs(),
 		}
 
 		out := c.GlobalString("config")
@@ -7,6 +7,7 @@ import (
 	_ "go.dedis.ch/cothority/v3/byzcoin"
 	_ "go.dedis.ch/cothority/v3/byzcoin/contracts"
 	_ "go.dedis.ch/cothority/v3/calypso"
+	_ "go.dedis.ch/cothority/v3/dkg/service"
 	_ "go.dedis.ch/cothority/v3/eventlog"
 	_ "go.dedis.ch/cothority/v3/personhood"
 )
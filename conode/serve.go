@@ -0,0 +1,383 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.dedis.ch/cothority/v3/admission"
+	"go.dedis.ch/cothority/v3/apitoken"
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/cothority/v3/clientauth"
+	"go.dedis.ch/cothority/v3/keyrotation"
+	"go.dedis.ch/cothority/v3/nat"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/app"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// serve runs the server described by the config file until it receives
+// SIGINT or SIGTERM. If clientAuth is set, it is the file of the client
+// authentication of the websocket. If netFile is set, it is the file of the
+// networking behind a NAT or a reverse proxy. If admissionFile is set, it is
+// the file of the limits of the requests of the clients. If apiTokens is
+// true, the privileged endpoints of the services require a token signed by
+// the conode. If the environment gives a database key, the databases are
+// encrypted with it. On SIGHUP, the files are read again and, if they
+// changed, the server is replaced by a new one using the new configuration.
+// Systemd is kept informed of the state of the server.
+func serve(config, clientAuth, netFile, admissionFile string, apiTokens bool,
+	grace time.Duration) error {
+	conf, sealed, err := loadConfig(config)
+	if err != nil {
+		return fmt.Errorf("couldn't load config: %v", err)
+	}
+	key, err := atrest.LoadKey()
+	if err != nil {
+		return fmt.Errorf("couldn't load the database key: %v", err)
+	}
+	if key != nil {
+		log.Lvl1("Encrypting the database")
+		if !sealed {
+			log.Warn("The config isn't encrypted, see 'conode seal-config'")
+		}
+	}
+	atrest.SetKey(key)
+	if atrest.Migrating() {
+		log.Lvl1("Sealing the plaintext values of the database")
+	}
+	auth, err := loadClientAuth(conf, clientAuth)
+	if err != nil {
+		return err
+	}
+	netConf, err := loadNetwork(auth, netFile)
+	if err != nil {
+		return err
+	}
+	admissionConf, err := loadAdmission(admissionFile)
+	if err != nil {
+		return err
+	}
+	server, err := newServer(config, auth, admissionConf, apiTokens)
+	if err != nil {
+		return err
+	}
+	stopNetwork, err := startNetwork(server, netConf)
+	if err != nil {
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	done := startServer(server)
+	for {
+		select {
+		case <-done:
+			return nil
+		case sig := <-sigs:
+			if sig != syscall.SIGHUP {
+				log.Lvlf1("Received %v, shutting down", sig)
+				notify(sdStopping)
+				stopNetwork()
+				return shutdown(server, done, grace)
+			}
+
+			newConf, _, err := loadConfig(config)
+			if err == nil {
+				// Check the identity now, as the current server must be
+				// closed before the new one can listen on its ports.
+				_, err = newConf.GetServerIdentity()
+			}
+			var newAuth *clientauth.Config
+			if err == nil {
+				newAuth, err = loadClientAuth(newConf, clientAuth)
+			}
+			var newNetConf *nat.Config
+			if err == nil {
+				newNetConf, err = loadNetwork(newAuth, netFile)
+			}
+			var newAdmissionConf *admission.Config
+			if err == nil {
+				newAdmissionConf, err = loadAdmission(admissionFile)
+			}
+			if err != nil {
+				log.Errorf("Couldn't reload %s, keeping the current "+
+					"configuration: %v", config, err)
+				continue
+			}
+			if reflect.DeepEqual(conf, newConf) &&
+				reflect.DeepEqual(auth, newAuth) &&
+				reflect.DeepEqual(netConf, newNetConf) &&
+				reflect.DeepEqual(admissionConf, newAdmissionConf) {
+				log.Lvl1("Configuration unchanged, nothing to reload")
+				continue
+			}
+
+			log.Lvl1("Configuration changed, restarting the server")
+			notify(sdReloading)
+			stopNetwork()
+			err = shutdown(server, done, grace)
+			if err != nil {
+				return err
+			}
+			conf, auth, netConf = newConf, newAuth, newNetConf
+			admissionConf = newAdmissionConf
+			server, err = newServer(config, auth, admissionConf, apiTokens)
+			if err != nil {
+				return err
+			}
+			stopNetwork, err = startNetwork(server, netConf)
+			if err != nil {
+				return err
+			}
+			done = startServer(server)
+		}
+	}
+}
+
+// newServer creates the server of the config file, with its services. If
+// auth is not nil, the clients of the websocket must authenticate with it. If
+// admissionConf is not nil, the requests of the clients are limited by it. If
+// apiTokens is true, the privileged endpoints require a token signed by the
+// conode.
+func newServer(config string, auth *clientauth.Config,
+	admissionConf *admission.Config, apiTokens bool) (*onet.Server, error) {
+	_, server, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse config: %v", err)
+	}
+
+	if apiTokens {
+		policy := &apitoken.Policy{
+			Key:       server.ServerIdentity.Public,
+			Endpoints: apitoken.DefaultEndpoints,
+			Keys:      keyrotation.Keys,
+		}
+		err = policy.Check(onet.ServiceFactory.RegisteredServiceNames())
+		if err != nil {
+			return nil, fmt.Errorf("invalid API tokens: %v", err)
+		}
+		apitoken.SetPolicy(policy)
+	} else {
+		apitoken.SetPolicy(nil)
+	}
+
+	if admissionConf != nil {
+		admission.SetPolicy(admissionConf.Policy())
+	} else {
+		admission.SetPolicy(nil)
+	}
+	admission.Resume()
+
+	if auth == nil {
+		clientauth.SetPolicy(nil)
+		return server, nil
+	}
+	server.WebSocket.Lock()
+	defer server.WebSocket.Unlock()
+	if server.WebSocket.TLSConfig == nil {
+		return nil, errors.New("the websocket doesn't use TLS")
+	}
+	err = auth.Apply(server.WebSocket.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't set client authentication: %v", err)
+	}
+	clientauth.SetPolicy(auth.Policy())
+	return server, nil
+}
+
+// loadClientAuth reads the client authentication file, if any. As the
+// clients authenticate with TLS, the websocket of the config must use TLS.
+func loadClientAuth(conf *app.CothorityConfig,
+	clientAuth string) (*clientauth.Config, error) {
+	if clientAuth == "" {
+		return nil, nil
+	}
+	if conf.WebSocketTLSCertificate == "" ||
+		conf.WebSocketTLSCertificateKey == "" {
+		return nil, errors.New("client authentication needs TLS for the " +
+			"websocket, please set WebSocketTLSCertificate and " +
+			"WebSocketTLSCertificateKey in the config")
+	}
+	return clientauth.LoadConfig(clientAuth)
+}
+
+// loadAdmission reads the admission control file, if any.
+func loadAdmission(admissionFile string) (*admission.Config, error) {
+	if admissionFile == "" {
+		return nil, nil
+	}
+	return admission.LoadConfig(admissionFile)
+}
+
+// loadNetwork reads the network file, if any. As the websocket front
+// terminates the TLS connections of the clients, it can't be used with
+// client authentication.
+func loadNetwork(auth *clientauth.Config, netFile string) (*nat.Config,
+	error) {
+	if netFile == "" {
+		return nil, nil
+	}
+	cfg, err := nat.LoadConfig(netFile)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.WebSocket != nil && auth != nil {
+		return nil, errors.New("client authentication doesn't work through " +
+			"the websocket front")
+	}
+	return cfg, nil
+}
+
+// startNetwork starts the websocket front and the keep-alive of the
+// connections of the network config, if any, and returns a function
+// stopping them.
+func startNetwork(server *onet.Server, cfg *nat.Config) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for _, s := range stops {
+			s()
+		}
+	}
+	if cfg == nil {
+		return stop, nil
+	}
+
+	if cfg.WebSocket != nil {
+		target, err := webSocketAddress(server.ServerIdentity)
+		if err != nil {
+			return nil, err
+		}
+		server.WebSocket.Lock()
+		targetTLS := server.WebSocket.TLSConfig != nil
+		server.WebSocket.Unlock()
+		front, err := nat.NewFront(cfg.WebSocket, target, targetTLS)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't start the websocket front: %v",
+				err)
+		}
+		log.Lvlf1("Websocket front on %s%s", front.Addr(), cfg.WebSocket.Prefix)
+		stops = append(stops, func() {
+			if err := front.Close(); err != nil {
+				log.Error("couldn't close the websocket front:", err)
+			}
+		})
+	}
+	if cfg.Outbound != nil {
+		s, ok := server.Service(nat.ServiceName).(*nat.Service)
+		if !ok {
+			return nil, errors.New("the NAT service is not running")
+		}
+		stops = append(stops, s.KeepConnections(cfg.Outbound.Interval()))
+	}
+	return stop, nil
+}
+
+// webSocketAddress returns the local address of the websocket of the
+// server, which listens on the port after the one of the server.
+func webSocketAddress(si *network.ServerIdentity) (string, error) {
+	_, port, err := net.SplitHostPort(si.Address.NetworkAddress())
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s: %v", si.Address, err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", fmt.Errorf("invalid port %s: %v", port, err)
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(p+1)), nil
+}
+
+// startServer starts the server and its supervision in the background, and
+// returns a channel closed once the server stopped.
+func startServer(server *onet.Server) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		server.Start()
+		close(done)
+	}()
+	go superviseServer(server, done)
+	return done
+}
+
+// notify sends a state to systemd, only logging the errors as the conode can
+// run without it.
+func notify(state string) {
+	if err := sdNotify(state); err != nil {
+		log.Error(err)
+	}
+}
+
+// shutdown stops accepting the requests of the clients and waits for the
+// running ones, then lets the services that implement drainer finish their
+// protocols, for at most grace in all. It then closes the server, which
+// closes its database.
+func shutdown(server *onet.Server, done <-chan struct{},
+	grace time.Duration) error {
+	deadline := time.Now().Add(grace)
+	if !admission.Drain(grace) {
+		log.Warnf("Client requests still running after %v, closing anyway",
+			grace)
+	} else if !drainServices(server, time.Until(deadline)) {
+		log.Warnf("Protocols still running after %v, closing anyway", grace)
+	}
+
+	err := server.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't close the server: %v", err)
+	}
+	<-done
+	closeServices(server)
+	log.Lvl1("Server closed")
+	return nil
+}
+
+// drainer is implemented by the services that can stop starting protocol
+// instances and wait for the running ones, as onet doesn't tell which ones
+// are running.
+type drainer interface {
+	Drain()
+}
+
+// drainServices drains the services of the server that implement drainer,
+// and returns false if they didn't finish before the timeout.
+func drainServices(server *onet.Server, timeout time.Duration) bool {
+	drained := make(chan struct{})
+	go func() {
+		for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+			if d, ok := server.Service(name).(drainer); ok {
+				d.Drain()
+			}
+		}
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// closer is implemented by the services that have goroutines to stop once
+// the server is closed.
+type closer interface {
+	Close()
+}
+
+// closeServices closes the services of the server that implement closer.
+func closeServices(server *onet.Server) {
+	for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+		if c, ok := server.Service(name).(closer); ok {
+			c.Close()
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"go.dedis.ch/cothority/v3/apitoken"
+	status "go.dedis.ch/cothority/v3/status/service"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// States sent to systemd, as described by sd_notify(3).
+const (
+	sdReady     = "READY=1"
+	sdReloading = "RELOADING=1"
+	sdStopping  = "STOPPING=1"
+	sdWatchdog  = "WATCHDOG=1"
+)
+
+// sdNotify sends a state to systemd. It does nothing if the conode is not
+// run by systemd with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if socket[0] == '@' {
+		// Abstract socket
+		addr.Name = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to systemd: %v", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return fmt.Errorf("couldn't notify systemd: %v", err)
+	}
+	return nil
+}
+
+// watchdogInterval returns the time after which systemd restarts the conode
+// if it didn't ping the watchdog, or 0 if the watchdog is disabled.
+func watchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" &&
+		pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(usec, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC: %s", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// superviseServer tells systemd that the conode is ready once the server is
+// alive, then pings the watchdog at half its interval as long as the server
+// stays alive. It returns when stop is closed, or once systemd is notified if
+// the watchdog is disabled.
+func superviseServer(server *onet.Server, stop <-chan struct{}) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+	interval, err := watchdogInterval()
+	if err != nil {
+		log.Error(err)
+	}
+	period := time.Second
+	if interval > 0 {
+		period = interval / 2
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	ready := false
+	for {
+		err := checkAlive(server, period)
+		switch {
+		case err != nil:
+			log.Warn("Conode is not alive:", err)
+		case !ready:
+			log.Lvl2("Conode is ready, notifying systemd")
+			notify(sdReady)
+			if interval == 0 {
+				return
+			}
+			ready = true
+		default:
+			notify(sdWatchdog)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAlive returns an error if the database of the server is not writable
+// or if its websocket doesn't answer a status request within the timeout.
+func checkAlive(server *onet.Server, timeout time.Duration) error {
+	st, ok := server.Service(status.ServiceName).(*status.Stat)
+	if !ok {
+		return errors.New("status service is not running")
+	}
+	if err := st.Alive(); err != nil {
+		return err
+	}
+	if requiresClientCert(server) ||
+		apitoken.Required(status.ServiceName, "Request") {
+		// The conode has no client certificate or token to ask itself.
+		return nil
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := status.NewClient().Request(server.ServerIdentity)
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		if err != nil {
+			return fmt.Errorf("websocket doesn't answer: %v", err)
+		}
+		return nil
+	case <-time.After(timeout):
+		return errors.New("websocket doesn't answer in time")
+	}
+}
+
+// requiresClientCert returns true if the websocket of the server only accepts
+// clients with a certificate.
+func requiresClientCert(server *onet.Server) bool {
+	server.WebSocket.Lock()
+	defer server.WebSocket.Unlock()
+	return server.WebSocket.TLSConfig != nil &&
+		server.WebSocket.TLSConfig.ClientAuth == tls.RequireAndVerifyClientCert
+}
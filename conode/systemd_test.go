@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSdNotify(t *testing.T) {
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	// Nothing to do without systemd
+	os.Unsetenv("NOTIFY_SOCKET")
+	require.NoError(t, sdNotify(sdReady))
+
+	dir, err := ioutil.TempDir("", "conode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram",
+		&net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", socket)
+	require.NoError(t, sdNotify(sdReady))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, sdReady, string(buf[:n]))
+
+	os.Setenv("NOTIFY_SOCKET", filepath.Join(dir, "missing"))
+	require.Error(t, sdNotify(sdReady))
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Unsetenv("WATCHDOG_USEC")
+	interval, err := watchdogInterval()
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), interval)
+
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	interval, err = watchdogInterval()
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, interval)
+
+	// The watchdog is meant for another process
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	interval, err = watchdogInterval()
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), interval)
+
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("WATCHDOG_USEC", "abc")
+	_, err = watchdogInterval()
+	require.Error(t, err)
+}
@@ -5,9 +5,11 @@ package service
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/satori/go.uuid"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/cothority/v3/cosi/protocol"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3"
@@ -34,6 +36,13 @@ type CoSi struct {
 	*onet.ServiceProcessor
 }
 
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (cs *CoSi) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		cs.ServiceProcessor.ProcessClientRequest)
+}
+
 // SignatureRequest is what the Cosi service is expected to receive from clients.
 type SignatureRequest struct {
 	Message []byte
@@ -0,0 +1,65 @@
+// Package decode holds the decoders of the messages received from other
+// nodes and clients. They refuse the buffers bigger than a limit before
+// decoding them, and turn the panics of malformed buffers into errors, so
+// that a malformed packet can neither crash nor exhaust the memory of a
+// conode.
+//
+// The fuzz package exercises these decoders with random input.
+package decode
+
+import (
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// MaxSize is the size of the biggest packet onet accepts. As every message is
+// smaller, it refuses nothing onet didn't: the callers give the size of the
+// biggest message they expect instead.
+var MaxSize = int(network.MaxPacketSize)
+
+// Protobuf decodes the buffer into the structure, if it is not bigger than
+// max bytes.
+func Protobuf(buf []byte, structPtr interface{}, max int) error {
+	return ProtobufWithConstructors(buf, structPtr, max, nil)
+}
+
+// ProtobufWithConstructors decodes the buffer into the structure with the
+// constructors of the interfaces, if it is not bigger than max bytes.
+func ProtobufWithConstructors(buf []byte, structPtr interface{}, max int,
+	cons protobuf.Constructors) (err error) {
+	if len(buf) > max {
+		return fmt.Errorf("buffer of %d bytes is bigger than %d", len(buf),
+			max)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed buffer: %v", r)
+		}
+	}()
+
+	if cons == nil {
+		return protobuf.Decode(buf, structPtr)
+	}
+	return protobuf.DecodeWithConstructors(buf, structPtr, cons)
+}
+
+// Message decodes a network message of one of the registered types, if it
+// is not bigger than max bytes, with the points and scalars of the suite of
+// the cothority.
+func Message(buf []byte, max int) (_ network.MessageTypeID,
+	_ network.Message, err error) {
+	if len(buf) > max {
+		return network.ErrorType, nil,
+			fmt.Errorf("message of %d bytes is bigger than %d", len(buf), max)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed message: %v", r)
+		}
+	}()
+
+	return network.Unmarshal(buf, cothority.Suite)
+}
@@ -0,0 +1,45 @@
+package decode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+type testMessage struct {
+	Name  string
+	Value []byte
+}
+
+func init() {
+	network.RegisterMessage(&testMessage{})
+}
+
+func TestProtobuf(t *testing.T) {
+	buf, err := protobuf.Encode(&testMessage{Name: "test",
+		Value: make([]byte, 100)})
+	require.NoError(t, err)
+
+	var msg testMessage
+	require.NoError(t, Protobuf(buf, &msg, MaxSize))
+	require.Equal(t, "test", msg.Name)
+	require.Error(t, Protobuf(buf, &msg, 100))
+	require.Error(t, Protobuf(buf[:len(buf)-1], &msg, MaxSize))
+	require.Error(t, Protobuf([]byte{0xff, 0xff, 0xff, 0xff}, &msg, MaxSize))
+}
+
+func TestMessage(t *testing.T) {
+	buf, err := network.Marshal(&testMessage{Name: "test"})
+	require.NoError(t, err)
+
+	_, msg, err := Message(buf, MaxSize)
+	require.NoError(t, err)
+	require.Equal(t, "test", msg.(*testMessage).Name)
+
+	_, _, err = Message(buf, len(buf)-1)
+	require.Error(t, err)
+	_, _, err = Message(buf[:10], MaxSize)
+	require.Error(t, err)
+}
@@ -0,0 +1,51 @@
+// Package dkg is the client side API for communicating with the dkg service,
+// which runs distributed key generations among conodes outside of any
+// application. The protocols themselves are in dkg/pedersen and dkg/rabin.
+package dkg
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
+)
+
+// ServiceName is the identifier of the service.
+const ServiceName = "DKG"
+
+// Client is a structure to communicate with the dkg service.
+type Client struct {
+	*onet.Client
+}
+
+// NewClient instantiates a new dkg.Client.
+func NewClient() *Client {
+	return &Client{Client: onet.NewClient(cothority.Suite, ServiceName)}
+}
+
+// CreateKey runs a DKG among the nodes of the roster, started by the first
+// node. The request is only accepted from localhost, unless the node is run
+// with COTHORITY_ALLOW_INSECURE_ADMIN.
+func (c *Client) CreateKey(roster *onet.Roster, id string, threshold uint32) (*CreateKeyReply, error) {
+	reply := &CreateKeyReply{}
+	err := c.SendProtobuf(roster.List[0], &CreateKey{
+		ID:        id,
+		Roster:    *roster,
+		Threshold: threshold,
+	}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("sending CreateKey message: %v", err)
+	}
+	return reply, nil
+}
+
+// GetKey returns the public information of the distributed key stored under
+// the ID by the node.
+func (c *Client) GetKey(si *network.ServerIdentity, id string) (*GetKeyReply, error) {
+	reply := &GetKeyReply{}
+	err := c.SendProtobuf(si, &GetKey{ID: id}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("sending GetKey message: %v", err)
+	}
+	return reply, nil
+}
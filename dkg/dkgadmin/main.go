@@ -0,0 +1,131 @@
+// dkgadmin runs a distributed key generation among conodes, which store
+// their share under a key ID, and prints the public key.
+package main
+
+import (
+	"os"
+
+	"github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3/dkg"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/app"
+	"go.dedis.ch/onet/v3/log"
+	"golang.org/x/xerrors"
+)
+
+var gitTag = "dev"
+
+func main() {
+	cliApp := cli.NewApp()
+	cliApp.Name = "dkgadmin"
+	cliApp.Usage = "Run distributed key generations among conodes"
+	cliApp.Version = gitTag
+	cliApp.Flags = []cli.Flag{
+		cli.IntFlag{
+			Name:  "debug, d",
+			Value: 0,
+			Usage: "debug-level: 1 for terse, 5 for maximal",
+		},
+	}
+	rosterFlag := cli.StringFlag{
+		Name:  "roster, r",
+		Value: "public.toml",
+		Usage: "the roster of the conodes, in a `FILE.toml`",
+	}
+	idFlag := cli.StringFlag{
+		Name:  "id",
+		Usage: "the ID under which the conodes store their share (required)",
+	}
+	cliApp.Commands = cli.Commands{
+		{
+			Name:    "create",
+			Usage:   "runs a DKG among the conodes of the roster and prints the public key",
+			Aliases: []string{"c"},
+			Action:  create,
+			Description: "The request is sent to the first conode of the roster and is only\n" +
+				"   accepted from localhost, unless the conode runs with\n" +
+				"   COTHORITY_ALLOW_INSECURE_ADMIN=true.",
+			Flags: []cli.Flag{
+				rosterFlag,
+				idFlag,
+				cli.UintFlag{
+					Name:  "threshold, t",
+					Usage: "number of conodes needed to use the key, 2/3 of the conodes by default",
+				},
+			},
+		},
+		{
+			Name:    "show",
+			Usage:   "prints the public key stored by every conode of the roster",
+			Aliases: []string{"s"},
+			Action:  show,
+			Flags:   []cli.Flag{rosterFlag, idFlag},
+		},
+	}
+	cliApp.Before = func(c *cli.Context) error {
+		log.SetDebugVisible(c.Int("debug"))
+		return nil
+	}
+	err := cliApp.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// create asks the first conode of the roster to run the DKG.
+func create(c *cli.Context) error {
+	roster, id, err := readArgs(c)
+	if err != nil {
+		return err
+	}
+	reply, err := dkg.NewClient().CreateKey(roster, id, uint32(c.Uint("threshold")))
+	if err != nil {
+		return xerrors.Errorf("creating the key: %v", err)
+	}
+	log.Infof("Created key %s among %d conodes", id, len(roster.List))
+	log.Infof("X: %s", reply.X)
+	return nil
+}
+
+// show prints the public key of every conode, which must all be the same.
+func show(c *cli.Context) error {
+	roster, id, err := readArgs(c)
+	if err != nil {
+		return err
+	}
+	cl := dkg.NewClient()
+	failed := 0
+	for _, si := range roster.List {
+		reply, err := cl.GetKey(si, id)
+		if err != nil {
+			log.Infof("%s: %v", si.Address, err)
+			failed++
+			continue
+		}
+		log.Infof("%s: share %d, X: %s", si.Address, reply.Index, reply.X)
+	}
+	if failed > 0 {
+		return xerrors.Errorf("%d conodes don't have the key", failed)
+	}
+	return nil
+}
+
+func readArgs(c *cli.Context) (*onet.Roster, string, error) {
+	id := c.String("id")
+	if id == "" {
+		return nil, "", xerrors.New("please give the key ID with --id")
+	}
+	f, err := os.Open(c.String("roster"))
+	if err != nil {
+		return nil, "", xerrors.Errorf("opening roster: %v", err)
+	}
+	defer f.Close()
+	group, err := app.ReadGroupDescToml(f)
+	if err != nil {
+		return nil, "", xerrors.Errorf("reading roster: %v", err)
+	}
+	if group.Roster == nil || len(group.Roster.List) == 0 {
+		return nil, "", xerrors.New("empty roster")
+	}
+	return group.Roster, id, nil
+}
@@ -0,0 +1,42 @@
+#!/usr/bin/env bash
+
+DBG_TEST=1
+DBG_SRV=2
+DBG_APP=2
+
+NBR_SERVERS=4
+NBR_SERVERS_GROUP=3
+
+. "../../libtest.sh"
+
+main(){
+    startTest
+    buildConode go.dedis.ch/cothority/v3/dkg/service
+    run testBuild
+    run testCreate
+    stopTest
+}
+
+testBuild(){
+    testOK runDA --help
+}
+
+testCreate(){
+    runCoBG 1 2 3
+    testFail runDA create --roster public.toml
+    testOK runDA create --roster public.toml --id test
+    testGrep "X: [0-9a-f]{64}" runDA create --roster public.toml --id test2
+    # a key ID can only be used once
+    testFail runDA create --roster public.toml --id test
+
+    # every conode holds a share of the key
+    testOK runDA show --roster public.toml --id test
+    testCountLines 3 runDA show --roster public.toml --id test
+    testFail runDA show --roster public.toml --id unknown
+}
+
+runDA(){
+    dbgRun ./dkgadmin -d $DBG_APP "$@"
+}
+
+main
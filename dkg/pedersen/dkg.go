@@ -1,15 +1,23 @@
 package pedersen
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/kyber/v3"
 	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
 
 	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
 )
@@ -17,6 +25,8 @@ import (
 // Name is the protocol identifier string.
 const Name = "Pedersen_DKG"
 
+const defaultPhaseTimeout = time.Minute
+
 func init() {
 	onet.GlobalProtocolRegister(Name, NewSetup)
 }
@@ -33,13 +43,58 @@ type Setup struct {
 	// KeyPair must be set by the caller, if this is a new DKG, then simply
 	// generate a new KeyPair.
 	KeyPair *key.Pair
+	// Storage, if not nil, keeps the state of the DKG so that the node can
+	// resume it after a restart. It is not used with NewDKG.
+	Storage Storage
+
+	// DealTimeout, ResponseTimeout and JustificationTimeout are the times
+	// given to the other nodes to send their deals, their responses and the
+	// justifications of the complaints. The wait at the end of the setup
+	// uses ResponseTimeout.
+	DealTimeout          time.Duration
+	ResponseTimeout      time.Duration
+	JustificationTimeout time.Duration
+	// Err is set when the DKG aborted, in which case false is sent on
+	// Finished. It is an *AbortError if some nodes misbehaved or didn't
+	// answer in time.
+	Err error
+	// Evidence is set with Err if some nodes misbehaved. It is signed by
+	// this node.
+	Evidence *Evidence
+	// Updates, if not nil, gets the progress of the DKG after every step.
+	Updates chan Progress
+	// Reader, if not nil, is the source of the secret polynomial of the
+	// node instead of a random stream. If every node gets the same stream on
+	// every run, the DKG produces the same distributed key, and the same
+	// shares if the nodes are in the same order in the tree. It is only
+	// meant for reproducible tests, as the secret is as strong as the
+	// streams.
+	Reader io.Reader
+	// InstanceID identifies the DKG for the service running it, for example
+	// the ID of an LTS. It is set by the root and sent to all nodes. If a
+	// node sets it before the protocol starts, it refuses to take part in a
+	// DKG with another ID.
+	InstanceID []byte
 
 	nodes   []*onet.TreeNode
 	publics []kyber.Point
 
+	state     *State
+	deals     map[int]*dkgpedersen.Deal
+	processed map[uint32]bool
+	responses []*Response
+	faulty    map[int]bool
+	responded map[[2]uint32]bool
+
+	misbehaviors map[int]*Misbehavior
+
+	progress     Progress
+	progressLock sync.Mutex
+
 	structStartDeal chan structStartDeal
 	structDeal      chan structDeal
 	structResponse  chan structResponse
+	structJustify   chan structJustification
 	structWaitSetup chan structWaitSetup
 	structWaitReply chan []structWaitReply
 
@@ -60,14 +115,22 @@ func CustomSetup(n *onet.TreeNodeInstance, suite vss.Suite, keypair *key.Pair) (
 		KeyPair:          keypair,
 		nodes:            n.List(),
 		suite:            suite,
+		processed:        make(map[uint32]bool),
+		faulty:           make(map[int]bool),
+		responded:        make(map[[2]uint32]bool),
+		misbehaviors:     make(map[int]*Misbehavior),
+
+		DealTimeout:          defaultPhaseTimeout,
+		ResponseTimeout:      defaultPhaseTimeout,
+		JustificationTimeout: defaultPhaseTimeout,
 	}
 
-	err := o.RegisterHandlers(o.childInit, o.rootStartDeal)
+	err := o.RegisterHandlers(o.childInit, o.rootStartDeal, o.allEnvelope)
 	if err != nil {
 		return nil, err
 	}
 	err = o.RegisterChannels(&o.structStartDeal, &o.structDeal, &o.structResponse,
-		&o.structWaitSetup, &o.structWaitReply)
+		&o.structJustify, &o.structWaitSetup, &o.structWaitReply)
 	if err != nil {
 		return nil, err
 	}
@@ -102,8 +165,9 @@ func NewSharedSecret(gen *dkgpedersen.DistKeyGenerator) (*SharedSecret, *dkgpede
 // Start sends the Announce-message to all children
 func (o *Setup) Start() error {
 	log.Lvl3("Starting Protocol")
+	o.setDefaultKeyPair()
 	// 1a - root asks children to send their public key
-	errs := o.Broadcast(&Init{Wait: o.Wait})
+	errs := o.Broadcast(&Init{Wait: o.Wait, InstanceID: o.InstanceID})
 	if len(errs) != 0 {
 		return fmt.Errorf("broadcast failed with error(s): %v", errs)
 	}
@@ -113,37 +177,101 @@ func (o *Setup) Start() error {
 // Dispatch takes care for channel-messages that need to be treated in the correct order.
 func (o *Setup) Dispatch() error {
 	defer o.Done()
-	err := o.allStartDeal(<-o.structStartDeal)
+	defer o.report(func(p *Progress) {
+		if p.Phase != PhaseCertified {
+			p.Phase = PhaseAborted
+		}
+	})
+	resumed, err := o.resume()
 	if err != nil {
 		return err
 	}
-	// TODO: "This will fail as soon as we start doing things with threshold.
-	//  " - nicolas
-	for i := 0; i < o.DKG.ExpectedDeals(); i++ {
-		err := o.allDeal(<-o.structDeal)
+	if !resumed {
+		select {
+		case ssd := <-o.structStartDeal:
+			err = o.allStartDeal(ssd)
+		case <-time.After(o.DealTimeout):
+			err = errors.New("didn't get the start of the DKG in time")
+		}
 		if err != nil {
 			return err
 		}
 	}
+
+	// Deal phase
+	timeout := time.After(o.DealTimeout)
+deals:
+	for len(o.processed) < o.DKG.ExpectedDeals() {
+		select {
+		case sd := <-o.structDeal:
+			if err := o.allDeal(sd); err != nil {
+				return err
+			}
+		case <-timeout:
+			log.Warn(o.Name(), "not all deals arrived in time")
+			break deals
+		}
+	}
+
+	// Response phase, the justifications of the complaints are processed as
+	// they come.
+	o.report(func(p *Progress) { p.Phase = PhaseResponse })
+	timeout = time.After(o.ResponseTimeout)
+responses:
 	for !o.DKG.Certified() {
-		err := o.allResponse(<-o.structResponse)
-		if err != nil && err.Error() != "vss: already existing response from same origin" {
-			return err
+		select {
+		case sr := <-o.structResponse:
+			if err := o.allResponse(sr); err != nil {
+				return err
+			}
+		case sj := <-o.structJustify:
+			o.allJustification(sj)
+		case <-timeout:
+			log.Warn(o.Name(), "not all responses arrived in time")
+			break responses
+		}
+	}
+
+	// Justification phase, the missing responses count as complaints and
+	// the dealers have a last chance to justify them.
+	if !o.DKG.Certified() {
+		o.report(func(p *Progress) { p.Phase = PhaseJustification })
+		o.DKG.SetTimeout()
+		timeout = time.After(o.JustificationTimeout)
+	justifications:
+		for !o.DKG.Certified() {
+			select {
+			case sj := <-o.structJustify:
+				o.allJustification(sj)
+			case <-timeout:
+				break justifications
+			}
 		}
 	}
 
+	if !o.DKG.Certified() {
+		return o.abort()
+	}
+	o.report(func(p *Progress) { p.Phase = PhaseCertified })
+
 	if o.Wait {
 		if o.IsRoot() {
 			o.SendToChildren(&WaitSetup{})
-			<-o.structWaitReply
+			select {
+			case <-o.structWaitReply:
+			case <-time.After(o.ResponseTimeout):
+				log.Warn(o.Name(), "not all nodes finished the setup in time")
+			}
 		} else {
 			<-o.structWaitSetup
 			o.SendToParent(&WaitReply{})
 		}
 	}
 
-	if !o.DKG.Certified() {
-		return errors.New("not certified")
+	if o.Storage != nil {
+		if err := o.Storage.DeleteState(o.stateID()); err != nil {
+			log.Error(o.Name(), "couldn't delete the DKG state:", err)
+		}
 	}
 
 	o.Finished <- true
@@ -154,12 +282,18 @@ func (o *Setup) Dispatch() error {
 func (o *Setup) childInit(i structInit) error {
 	o.Wait = i.Wait
 	log.Lvl3(o.Name(), o.Wait)
-	if o.KeyPair == nil {
-		log.Lvl3(o.ServerIdentity(), "using the network keypair as DKG keypair")
-		o.KeyPair = &key.Pair{
-			Public:  o.Public(),
-			Private: o.Private(),
+	if o.InstanceID != nil && !bytes.Equal(o.InstanceID, i.InstanceID) {
+		return fmt.Errorf("expected DKG instance %x, got %x", o.InstanceID,
+			i.InstanceID)
+	}
+	o.InstanceID = i.InstanceID
+	o.setDefaultKeyPair()
+	if o.portable() {
+		buf, err := o.KeyPair.Public.MarshalBinary()
+		if err != nil {
+			return err
 		}
+		return o.SendToParent(&InitReply{Key: buf})
 	}
 	return o.SendToParent(&InitReply{Public: o.KeyPair.Public})
 }
@@ -173,64 +307,472 @@ func (o *Setup) rootStartDeal(replies []structInitReply) error {
 			return errors.New("unknown serverIdentity")
 		}
 		o.publics[index] = r.Public
+		if len(r.Key) > 0 {
+			o.publics[index] = o.suite.Point()
+			if err := o.publics[index].UnmarshalBinary(r.Key); err != nil {
+				return err
+			}
+		}
 	}
 	return o.fullBroadcast(&StartDeal{
-		Publics:   o.publics,
-		Threshold: o.Threshold,
+		Publics:    o.publics,
+		Threshold:  o.Threshold,
+		InstanceID: o.InstanceID,
 	})
 }
 
 // Messages for both
 func (o *Setup) allStartDeal(ssd structStartDeal) error {
+	if !bytes.Equal(o.InstanceID, ssd.InstanceID) {
+		return fmt.Errorf("expected DKG instance %x, got %x", o.InstanceID,
+			ssd.InstanceID)
+	}
 	var err error
-	if o.NewDKG == nil {
+	switch {
+	case o.NewDKG != nil:
+		o.DKG, err = o.NewDKG()
+	case o.Storage != nil:
+		o.state = &State{
+			Publics:   ssd.Publics,
+			Threshold: ssd.Threshold,
+			Seed:      make([]byte, 32),
+		}
+		o.state.Longterm, err = o.KeyPair.Private.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if o.Reader != nil {
+			_, err = io.ReadFull(o.Reader, o.state.Seed)
+		} else {
+			random.Bytes(o.state.Seed, random.New())
+		}
+		if err == nil {
+			o.DKG, err = o.seededDKG()
+		}
+		if err == nil {
+			err = o.saveState()
+		}
+	case o.Reader != nil:
+		o.DKG, err = dkgpedersen.NewDistKeyHandler(&dkgpedersen.Config{
+			Suite:          o.suite,
+			Longterm:       o.KeyPair.Private,
+			NewNodes:       ssd.Publics,
+			Threshold:      int(ssd.Threshold),
+			Reader:         o.Reader,
+			UserReaderOnly: true,
+		})
+	default:
 		o.DKG, err = dkgpedersen.NewDistKeyGenerator(o.suite, o.KeyPair.Private,
 			ssd.Publics, int(ssd.Threshold))
-	} else {
-		o.DKG, err = o.NewDKG()
 	}
 	if err != nil {
 		return err
 	}
 	o.publics = ssd.Publics
-	deals, err := o.DKG.Deals()
+	o.deals, err = o.DKG.Deals()
 	if err != nil {
 		return err
 	}
-	for i, d := range deals {
-		if err := o.SendTo(o.nodes[i], &Deal{d}); err != nil {
+	for i, d := range o.deals {
+		if err := o.sendTo(o.nodes[i], &Deal{d}); err != nil {
 			return err
 		}
 	}
+	o.report(func(p *Progress) {
+		p.Phase = PhaseDeal
+		p.DealsSent = len(o.deals)
+		p.ExpectedDeals = o.DKG.ExpectedDeals()
+	})
 	return nil
 }
 
 func (o *Setup) allDeal(sd structDeal) error {
+	if d := sd.Deal.Deal; d == nil || d.Deal == nil {
+		log.Warn(o.Name(), "got a malformed deal from", sd.ServerIdentity)
+		return nil
+	}
+	// A node can only send its own deal, else it could get an honest
+	// dealer blamed or its deal dropped.
+	index := sd.Deal.Deal.Index
+	if int(index) != o.indexOfNode(sd.TreeNode) {
+		log.Warn(o.Name(), "got the deal of", index, "from",
+			sd.ServerIdentity)
+		return nil
+	}
+	if o.processed[index] {
+		// The dealer restarted and lost what it got while it was down.
+		return o.resendTo(sd.TreeNode)
+	}
+	if err := o.verifyDeal(sd.Deal.Deal); err != nil {
+		log.Warn(o.Name(), "got an unsigned deal from", sd.ServerIdentity,
+			err)
+		return nil
+	}
 	resp, err := o.DKG.ProcessDeal(sd.Deal.Deal)
 	if err != nil {
+		// An invalid deal disqualifies the dealer, but doesn't stop the
+		// node from processing the other deals.
 		log.Error(o.Name(), err)
-		return err
+		o.processed[index] = true
+		o.blame(Misbehavior{Index: int(index), Fault: FaultInvalidDeal,
+			Deal: sd.Deal.Deal})
+		return nil
+	}
+	o.processed[index] = true
+	o.report(func(p *Progress) { p.DealsProcessed++ })
+	o.responses = append(o.responses, &Response{resp})
+	if o.state != nil {
+		o.state.Deals = append(o.state.Deals, &sd.Deal)
+		if err := o.saveState(); err != nil {
+			return err
+		}
 	}
 	return o.fullBroadcast(&Response{resp})
 }
 
 func (o *Setup) allResponse(resp structResponse) error {
 	log.Lvl3(o.Name(), resp.ServerIdentity)
-	just, err := o.DKG.ProcessResponse(resp.Response.Response)
+	r := resp.Response.Response
+	if r == nil || r.Response == nil {
+		log.Warn(o.Name(), "got a malformed response from",
+			resp.ServerIdentity)
+		return nil
+	}
+	just, err := o.DKG.ProcessResponse(r)
 	if err != nil {
-		return err
+		if err.Error() == "vss: already existing response from same origin" {
+			return nil
+		}
+		// The response may refer to a deal that didn't arrive yet or that
+		// is invalid, it is not a reason to stop.
+		log.Warn(o.Name(), "invalid response:", err)
+		return nil
 	}
+	o.responded[[2]uint32{r.Index, r.Response.Index}] = true
+	o.report(func(p *Progress) { p.ResponsesProcessed++ })
 	if just != nil {
-		log.Warn(o.Name(), "Got a justification: ", just)
+		log.Lvl2(o.Name(), "sending a justification for a complaint of", r.Response.Index)
+		if err := o.fullBroadcast(&Justification{just}); err != nil {
+			log.Warn(o.Name(), "couldn't send the justification:", err)
+		}
+	}
+	if o.state != nil {
+		o.state.Responses = append(o.state.Responses, &resp.Response)
+		if err := o.saveState(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func (o *Setup) allJustification(sj structJustification) {
+	j := sj.Justification.Justification
+	if j == nil || j.Justification == nil {
+		return
+	}
+	if err := o.DKG.ProcessJustification(j); err != nil {
+		log.Warn(o.Name(), "invalid justification of", j.Index, err)
+		o.blame(Misbehavior{Index: int(j.Index),
+			Fault: FaultInvalidJustification, Justification: j})
+	}
+}
+
+// indexOfNode returns the index of the node in the DKG, or -1 if it is not
+// part of it.
+func (o *Setup) indexOfNode(tn *onet.TreeNode) int {
+	for i, n := range o.nodes {
+		if n.ID == tn.ID {
+			if i >= len(o.publics) {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// verifyDeal checks the signature of the dealer on the deal, so that a node
+// is only blamed for a deal it signed.
+func (o *Setup) verifyDeal(d *dkgpedersen.Deal) error {
+	if len(d.Signature) == 0 {
+		return errors.New("no signature")
+	}
+	msg, err := d.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return schnorr.Verify(o.suite, o.publics[d.Index], msg, d.Signature)
+}
+
+// abort finds out which nodes prevented the DKG from finishing.
+func (o *Setup) abort() error {
+	own := indexOf(o.publics, o.KeyPair.Public)
+	for i := range o.publics {
+		if i != own && !o.processed[uint32(i)] {
+			o.blame(Misbehavior{Index: i, Fault: FaultMissingDeal})
+		}
+	}
+	qual := make(map[int]bool)
+	for _, i := range o.DKG.QUAL() {
+		qual[i] = true
+	}
+	for i := range o.publics {
+		if !qual[i] {
+			o.blame(Misbehavior{Index: i, Fault: FaultDisqualified})
+		}
+	}
+	// A node that didn't respond to a deal of a correct dealer is faulty,
+	// too.
+	for d := range o.publics {
+		if o.faulty[d] {
+			continue
+		}
+		for v := range o.publics {
+			if v != d && v != own && !o.responded[[2]uint32{uint32(d), uint32(v)}] {
+				o.blame(Misbehavior{Index: v, Fault: FaultMissingResponse})
+			}
+		}
+	}
+	delete(o.faulty, own)
+
+	ae := &AbortError{}
+	for i := range o.faulty {
+		ae.Faulty = append(ae.Faulty, i)
+	}
+	sort.Ints(ae.Faulty)
+	if len(ae.Faulty) > 0 {
+		var ms []Misbehavior
+		for _, i := range ae.Faulty {
+			ms = append(ms, *o.misbehaviors[i])
+		}
+		var err error
+		ae.Evidence, err = evidence(o.TreeNodeInstance, o.InstanceID, ms)
+		if err != nil {
+			log.Error(o.Name(), "couldn't sign the evidence:", err)
+		}
+		o.Evidence = ae.Evidence
+	}
+	o.Err = ae
+	o.report(func(p *Progress) {
+		p.Phase = PhaseAborted
+		p.Faulty = ae.Faulty
+	})
+	o.Finished <- false
+	return ae
+}
+
+// resume rebuilds the DKG out of the stored state, if any, and asks the other
+// nodes to send again what they sent while this node was down.
+func (o *Setup) resume() (bool, error) {
+	if o.Storage == nil || o.NewDKG != nil {
+		return false, nil
+	}
+	st, err := o.Storage.LoadState(o.stateID())
+	if err != nil || st == nil {
+		return false, err
+	}
+	log.Lvl2(o.ServerIdentity(), "resuming the DKG")
+	if err := o.restoreKeyPair(st); err != nil {
+		return false, err
+	}
+
+	o.state = st
+	o.publics = st.Publics
+	o.DKG, err = o.seededDKG()
+	if err != nil {
+		return false, err
+	}
+	// The deals are the same as before the restart because they come out
+	// of the seed.
+	o.deals, err = o.DKG.Deals()
+	if err != nil {
+		return false, err
+	}
+	for _, d := range st.Deals {
+		resp, err := o.DKG.ProcessDeal(d.Deal)
+		if err != nil {
+			return false, err
+		}
+		o.processed[d.Deal.Index] = true
+		o.responses = append(o.responses, &Response{resp})
+	}
+	for _, r := range st.Responses {
+		_, err := o.DKG.ProcessResponse(r.Response)
+		if err != nil && err.Error() != "vss: already existing response from same origin" {
+			return false, err
+		}
+		o.responded[[2]uint32{r.Response.Index, r.Response.Response.Index}] = true
+	}
+
+	// The other nodes answer to a deal they already have with their own deal
+	// and responses.
+	for i, d := range o.deals {
+		if err := o.sendTo(o.nodes[i], &Deal{d}); err != nil {
+			log.Warn(o.Name(), "couldn't send the deal again:", err)
+		}
+	}
+	for _, r := range o.responses {
+		if err := o.fullBroadcast(r); err != nil {
+			log.Warn(o.Name(), "couldn't send the response again:", err)
+		}
+	}
+	o.report(func(p *Progress) {
+		p.Phase = PhaseDeal
+		p.DealsSent = len(o.deals)
+		p.ExpectedDeals = o.DKG.ExpectedDeals()
+		p.DealsProcessed = len(st.Deals)
+		p.ResponsesProcessed = len(st.Responses)
+	})
+	return true, nil
+}
+
+// restoreKeyPair uses the key pair stored in the state, so that the node
+// keeps the key the other nodes know, even if it is not the network one.
+func (o *Setup) restoreKeyPair(st *State) error {
+	if len(st.Longterm) == 0 {
+		// the state was stored before the key pair was part of it
+		o.setDefaultKeyPair()
+		return nil
+	}
+	private := o.suite.Scalar()
+	if err := private.UnmarshalBinary(st.Longterm); err != nil {
+		return fmt.Errorf("invalid key pair in the DKG state: %v", err)
+	}
+	o.KeyPair = &key.Pair{
+		Public:  o.suite.Point().Mul(private, nil),
+		Private: private,
+	}
+	return nil
+}
+
+// resendTo sends the deal for the node and all the responses of this node.
+func (o *Setup) resendTo(tn *onet.TreeNode) error {
+	for i, n := range o.nodes {
+		if n.ID == tn.ID && o.deals[i] != nil {
+			if err := o.sendTo(tn, &Deal{o.deals[i]}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range o.responses {
+		if err := o.SendTo(tn, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seededDKG creates the DKG using the seed of the state so that the same
+// secret polynomial is used after a restart.
+func (o *Setup) seededDKG() (*dkgpedersen.DistKeyGenerator, error) {
+	return dkgpedersen.NewDistKeyHandler(&dkgpedersen.Config{
+		Suite:          o.suite,
+		Longterm:       o.KeyPair.Private,
+		NewNodes:       o.state.Publics,
+		Threshold:      int(o.state.Threshold),
+		Reader:         o.suite.XOF(o.state.Seed),
+		UserReaderOnly: true,
+	})
+}
+
+func (o *Setup) saveState() error {
+	return o.Storage.SaveState(o.stateID(), o.state)
+}
+
+func (o *Setup) stateID() []byte {
+	id := o.Token().RoundID
+	return id[:]
+}
+
+// AbortError is the outcome of a DKG that couldn't finish. Faulty holds the
+// indexes, in the roster, of the nodes that misbehaved or didn't answer in
+// time, as seen by the node, and Evidence what each of them did.
+type AbortError struct {
+	Faulty   []int
+	Evidence *Evidence
+}
+
+func (ae *AbortError) Error() string {
+	return fmt.Sprintf("dkg aborted, faulty nodes: %v", ae.Faulty)
+}
+
 // Convenience functions
 func (o *Setup) fullBroadcast(msg interface{}) error {
+	msg, err := o.wrap(msg)
+	if err != nil {
+		return err
+	}
 	errs := o.Multicast(msg, o.nodes...)
 	if len(errs) != 0 {
 		return fmt.Errorf("multicast failed with error(s): %v", errs)
 	}
 	return nil
 }
+
+func (o *Setup) sendTo(tn *onet.TreeNode, msg interface{}) error {
+	msg, err := o.wrap(msg)
+	if err != nil {
+		return err
+	}
+	return o.SendTo(tn, msg)
+}
+
+// portable returns true if the suite of the DKG is not the one of the
+// network, in which case the messages holding points are sent in an
+// envelope, as onet can only decode the points of its own suite.
+func (o *Setup) portable() bool {
+	return o.suite.String() != o.Suite().String()
+}
+
+func (o *Setup) wrap(msg interface{}) (interface{}, error) {
+	if !o.portable() {
+		return msg, nil
+	}
+	buf, err := network.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{Data: buf}, nil
+}
+
+// allEnvelope decodes the message of the envelope with the suite of the DKG
+// and hands it over to Dispatch.
+func (o *Setup) allEnvelope(e structEnvelope) error {
+	_, msg, err := network.Unmarshal(e.Data, o.suite)
+	if err != nil {
+		return err
+	}
+	switch m := msg.(type) {
+	case *StartDeal:
+		o.structStartDeal <- structStartDeal{e.TreeNode, *m}
+	case *Deal:
+		o.structDeal <- structDeal{e.TreeNode, *m}
+	case *Response:
+		o.structResponse <- structResponse{e.TreeNode, *m}
+	case *Justification:
+		o.structJustify <- structJustification{e.TreeNode, *m}
+	default:
+		return fmt.Errorf("unexpected message in envelope: %T", msg)
+	}
+	return nil
+}
+
+// setDefaultKeyPair uses the network key pair as DKG key pair if none was
+// set. If the suite of the DKG is not the one of the network, a new key pair
+// is created instead, which is kept in the state of the DKG if there is a
+// Storage.
+func (o *Setup) setDefaultKeyPair() {
+	if o.KeyPair != nil {
+		return
+	}
+	if o.portable() {
+		log.Lvl3(o.ServerIdentity(), "creating a DKG keypair for", o.suite)
+		o.KeyPair = key.NewKeyPair(o.suite)
+		return
+	}
+	log.Lvl3(o.ServerIdentity(), "using the network keypair as DKG keypair")
+	o.KeyPair = &key.Pair{
+		Public:  o.Public(),
+		Private: o.Private(),
+	}
+}
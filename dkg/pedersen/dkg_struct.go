@@ -1,6 +1,8 @@
 package pedersen
 
 import (
+	"time"
+
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
@@ -11,7 +13,11 @@ import (
 func init() {
 	network.RegisterMessages(&SharedSecret{},
 		&Init{}, &InitReply{},
-		&StartDeal{}, &Deal{})
+		&StartDeal{}, &Deal{}, &Response{}, &Justification{}, &Envelope{},
+		&StartReshare{}, &ReshareDone{},
+		&RecoverInit{}, &RecoverIndex{}, &RecoverStart{}, &RecoverShare{},
+		&RefreshInit{}, &RefreshInitReply{}, &StartRefresh{}, &RefreshDeal{},
+		&RefreshDone{}, &RefreshApply{})
 }
 
 // SharedSecret represents the needed information to do shared encryption
@@ -41,7 +47,8 @@ func (ss *SharedSecret) Clone() *SharedSecret {
 // all nodes from the root-node. If Wait is true, at the end of the setup
 // an additional message is sent to wait for all nodes to be set up.
 type Init struct {
-	Wait bool
+	Wait       bool
+	InstanceID []byte
 }
 
 type structInit struct {
@@ -49,9 +56,11 @@ type structInit struct {
 	Init
 }
 
-// InitReply returns the public key of that node.
+// InitReply returns the public key of that node. If the DKG doesn't use the
+// suite of the network, the key is marshalled in Key instead.
 type InitReply struct {
 	Public kyber.Point
+	Key    []byte
 }
 
 type structInitReply struct {
@@ -61,8 +70,9 @@ type structInitReply struct {
 
 // StartDeal is used by the leader to initiate the Deals.
 type StartDeal struct {
-	Publics   []kyber.Point
-	Threshold uint32
+	Publics    []kyber.Point
+	Threshold  uint32
+	InstanceID []byte
 }
 
 type structStartDeal struct {
@@ -90,6 +100,17 @@ type structResponse struct {
 	Response
 }
 
+// Justification is sent to all other nodes by a dealer that got a complaint
+// about its deal.
+type Justification struct {
+	Justification *dkgpedersen.Justification
+}
+
+type structJustification struct {
+	*onet.TreeNode
+	Justification
+}
+
 // WaitSetup is only sent if Init.Wait == true
 type WaitSetup struct {
 }
@@ -106,3 +127,151 @@ type structWaitReply struct {
 	*onet.TreeNode
 	WaitReply
 }
+
+// StartReshare is sent by the root to all nodes to start the resharing. The
+// publics are the DKG public keys of the nodes of the tree, in the order of
+// the list of the tree.
+type StartReshare struct {
+	Publics      []kyber.Point
+	OldNodes     []kyber.Point
+	NewNodes     []kyber.Point
+	OldThreshold uint32
+	NewThreshold uint32
+	Commits      []kyber.Point
+	Timeout      time.Duration
+}
+
+type structStartReshare struct {
+	*onet.TreeNode
+	StartReshare
+}
+
+// ReshareDone is sent to the root once a node is done with the resharing.
+type ReshareDone struct{}
+
+type structReshareDone struct {
+	*onet.TreeNode
+	ReshareDone
+}
+
+// RecoverInit is sent by the root to the shareholders to ask for the
+// recovery of the share with the given index.
+type RecoverInit struct {
+	Index   uint32
+	Timeout time.Duration
+}
+
+type structRecoverInit struct {
+	*onet.TreeNode
+	RecoverInit
+}
+
+// RecoverIndex is the reply of a shareholder with the index of its share and
+// its public coefficients.
+type RecoverIndex struct {
+	Index   uint32
+	Commits []kyber.Point
+}
+
+type structRecoverIndex struct {
+	*onet.TreeNode
+	RecoverIndex
+}
+
+// RecoverStart is sent by the root with the indexes of the shares of the
+// shareholders, in the order of the children of the root.
+type RecoverStart struct {
+	Indexes []int
+}
+
+type structRecoverStart struct {
+	*onet.TreeNode
+	RecoverStart
+}
+
+// RecoverShare is the masked part of the share sent to the root.
+type RecoverShare struct {
+	V kyber.Scalar
+}
+
+type structRecoverShare struct {
+	*onet.TreeNode
+	RecoverShare
+}
+
+// Envelope holds a marshalled message whose points are not in the suite of
+// the network, like the ones of a DKG over a pairing suite.
+type Envelope struct {
+	Data []byte
+}
+
+type structEnvelope struct {
+	*onet.TreeNode
+	Envelope
+}
+
+// RefreshInit is sent by the root to start the refresh of the shares.
+type RefreshInit struct {
+	Timeout time.Duration
+}
+
+type structRefreshInit struct {
+	*onet.TreeNode
+	RefreshInit
+}
+
+// RefreshInitReply holds the key of a node and the index of its share.
+type RefreshInitReply struct {
+	Public kyber.Point
+	Index  uint32
+}
+
+type structRefreshInitReply struct {
+	*onet.TreeNode
+	RefreshInitReply
+}
+
+// StartRefresh is sent by the root with the keys and the share indexes of
+// the nodes, in the order of the list of the tree.
+type StartRefresh struct {
+	Publics []kyber.Point
+	Indexes []int
+}
+
+type structStartRefresh struct {
+	*onet.TreeNode
+	StartRefresh
+}
+
+// RefreshDeal holds the commitments of the polynomial of a node and the
+// share of the recipient, encrypted for its key.
+type RefreshDeal struct {
+	Commits []kyber.Point
+	Cipher  []byte
+}
+
+type structRefreshDeal struct {
+	*onet.TreeNode
+	RefreshDeal
+}
+
+// RefreshDone is sent to the root with the digest of the polynomials the
+// node added to its share.
+type RefreshDone struct {
+	Digest []byte
+}
+
+type structRefreshDone struct {
+	*onet.TreeNode
+	RefreshDone
+}
+
+// RefreshApply tells the nodes whether to use their new share.
+type RefreshApply struct {
+	OK bool
+}
+
+type structRefreshApply struct {
+	*onet.TreeNode
+	RefreshApply
+}
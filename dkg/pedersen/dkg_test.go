@@ -12,6 +12,8 @@ import (
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
+
+	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
 )
 
 func TestMain(m *testing.M) {
@@ -87,3 +89,271 @@ func setupCustomDKG(t *testing.T, nbrNodes int) {
 		t.Fatal("Didn't finish in time")
 	}
 }
+
+// silentSetup answers with its public key but never deals.
+type silentSetup struct {
+	*Setup
+}
+
+func (s *silentSetup) Dispatch() error {
+	<-s.structStartDeal
+	return nil
+}
+
+func TestSetup_Abort(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	srvs := local.GenServers(4)
+	roster := local.GenRosterFromHost(srvs...)
+
+	name := "test_dkg_abort"
+	for i, srv := range srvs {
+		silent := i == 3
+		_, err := srv.ProtocolRegister(name, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			if err != nil {
+				return nil, err
+			}
+			setup := pi.(*Setup)
+			setup.DealTimeout = time.Second
+			setup.ResponseTimeout = time.Second
+			setup.JustificationTimeout = time.Second
+			if silent {
+				return &silentSetup{setup}, nil
+			}
+			return setup, nil
+		})
+		require.NoError(t, err)
+	}
+
+	tree := roster.GenerateNaryTree(len(roster.List))
+	pi, err := local.CreateProtocol(name, tree)
+	require.NoError(t, err)
+	protocol := pi.(*Setup)
+	protocol.KeyPair = key.NewKeyPair(cothority.Suite)
+	require.NoError(t, protocol.Start())
+
+	select {
+	case ok := <-protocol.Finished:
+		require.False(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the DKG didn't abort in time")
+	}
+	ae, ok := protocol.Err.(*AbortError)
+	require.True(t, ok)
+	require.Contains(t, ae.Faulty, 3)
+	require.NotContains(t, ae.Faulty, 0)
+	_, _, err = protocol.SharedSecret()
+	require.Error(t, err)
+
+	// the evidence blames the silent node and is signed by the root
+	ev := protocol.Evidence
+	require.NotNil(t, ev)
+	require.Equal(t, ae.Evidence, ev)
+	require.NoError(t, ev.Verify())
+	require.True(t, ev.Reporter.Equal(roster.List[0]))
+	var blamed *Misbehavior
+	for i := range ev.Misbehaviors {
+		if ev.Misbehaviors[i].Index == 3 {
+			blamed = &ev.Misbehaviors[i]
+		}
+	}
+	require.NotNil(t, blamed)
+	require.Equal(t, FaultMissingDeal, blamed.Fault)
+	require.True(t, blamed.Node.Equal(tree.List()[3].ServerIdentity))
+
+	blamed.Fault = FaultInvalidDeal
+	require.Error(t, ev.Verify())
+}
+
+// forgingSetup sends a garbage deal in the name of the node 1 before taking
+// part in the DKG.
+type forgingSetup struct {
+	*Setup
+}
+
+func (s *forgingSetup) Dispatch() error {
+	ssd := <-s.structStartDeal
+	forged := &dkgpedersen.Deal{
+		Index: 1,
+		Deal: &vss.EncryptedDeal{
+			DHKey:     s.suite.Point().Pick(s.suite.RandomStream()),
+			Signature: []byte{1},
+			Nonce:     []byte{2},
+			Cipher:    []byte{3},
+		},
+		Signature: []byte{4},
+	}
+	for _, n := range s.nodes {
+		if err := s.sendTo(n, &Deal{forged}); err != nil {
+			return err
+		}
+	}
+	s.structStartDeal <- ssd
+	return s.Setup.Dispatch()
+}
+
+func TestSetup_ForgedDeal(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	srvs := local.GenServers(4)
+	roster := local.GenRosterFromHost(srvs...)
+
+	name := "test_dkg_forged"
+	for i, srv := range srvs {
+		forging := i == 3
+		_, err := srv.ProtocolRegister(name, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			if err != nil {
+				return nil, err
+			}
+			if forging {
+				return &forgingSetup{pi.(*Setup)}, nil
+			}
+			return pi, nil
+		})
+		require.NoError(t, err)
+	}
+
+	tree := roster.GenerateNaryTree(len(roster.List))
+	pi, err := local.CreateProtocol(name, tree)
+	require.NoError(t, err)
+	protocol := pi.(*Setup)
+	require.NoError(t, protocol.Start())
+
+	// the forged deal neither blames the node 1 nor drops its real deal
+	select {
+	case ok := <-protocol.Finished:
+		require.True(t, ok, "%v", protocol.Err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the DKG didn't finish in time")
+	}
+	require.Empty(t, protocol.faulty)
+}
+
+func TestSetup_Updates(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	_, _, tree := local.GenBigTree(4, 4, 4, true)
+
+	pi, err := local.CreateProtocol(Name, tree)
+	require.NoError(t, err)
+	protocol := pi.(*Setup)
+	protocol.KeyPair = key.NewKeyPair(cothority.Suite)
+	protocol.Updates = make(chan Progress, 100)
+	require.Equal(t, PhaseInit, protocol.Status().Phase)
+	require.NoError(t, protocol.Start())
+
+	select {
+	case <-protocol.Finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Didn't finish in time")
+	}
+	status := protocol.Status()
+	require.Equal(t, PhaseCertified, status.Phase)
+	require.Equal(t, 3, status.ExpectedDeals)
+	require.Equal(t, 3, status.DealsProcessed)
+
+	phases := []Phase{}
+	for len(protocol.Updates) > 0 {
+		p := <-protocol.Updates
+		if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+			phases = append(phases, p.Phase)
+		}
+	}
+	require.Equal(t, PhaseDeal, phases[0])
+	require.Equal(t, PhaseCertified, phases[len(phases)-1])
+}
+
+func TestSetup_Instances(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	_, _, tree := local.GenBigTree(4, 4, 4, true)
+
+	instances := NewInstances()
+	var setups []*Setup
+	for _, id := range []string{"lts1", "lts2"} {
+		pi, err := local.CreateProtocol(Name, tree)
+		require.NoError(t, err)
+		setup := pi.(*Setup)
+		setup.KeyPair = key.NewKeyPair(cothority.Suite)
+		require.NoError(t, instances.Add([]byte(id), setup))
+		setups = append(setups, setup)
+	}
+
+	// the first DKG of the instance is not finished yet
+	require.Error(t, instances.Add([]byte("lts1"), &Setup{}))
+
+	// both DKGs run at the same time
+	for _, setup := range setups {
+		require.NoError(t, setup.Start())
+	}
+	for _, setup := range setups {
+		select {
+		case ok := <-setup.Finished:
+			require.True(t, ok)
+		case <-time.After(10 * time.Second):
+			t.Fatal("Didn't finish in time")
+		}
+	}
+	require.Equal(t, []byte("lts2"), instances.Get([]byte("lts2")).InstanceID)
+	ss1, _, err := setups[0].SharedSecret()
+	require.NoError(t, err)
+	ss2, _, err := setups[1].SharedSecret()
+	require.NoError(t, err)
+	require.False(t, ss1.X.Equal(ss2.X))
+
+	// once finished, a new DKG can replace it
+	require.NoError(t, instances.Add([]byte("lts1"), &Setup{}))
+}
+
+func TestSetup_Reader(t *testing.T) {
+	var secrets []*SharedSecret
+	for run := 0; run < 2; run++ {
+		secrets = append(secrets, deterministicDKG(t, 4))
+	}
+	require.True(t, secrets[0].X.Equal(secrets[1].X))
+	require.True(t, secrets[0].V.Equal(secrets[1].V))
+	require.Equal(t, len(secrets[0].Commits), len(secrets[1].Commits))
+	for i := range secrets[0].Commits {
+		require.True(t, secrets[0].Commits[i].Equal(secrets[1].Commits[i]))
+	}
+}
+
+// deterministicDKG runs a DKG where the stream of every node only depends on
+// its index in the tree and returns the shared secret of the root.
+func deterministicDKG(t *testing.T, nbrNodes int) *SharedSecret {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	srvs := local.GenServers(nbrNodes)
+	roster := local.GenRosterFromHost(srvs...)
+
+	name := "test_dkg_reader"
+	for _, srv := range srvs {
+		_, err := srv.ProtocolRegister(name, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			if err != nil {
+				return nil, err
+			}
+			pi.(*Setup).Reader = cothority.Suite.XOF([]byte{byte(n.Index())})
+			return pi, nil
+		})
+		require.NoError(t, err)
+	}
+
+	tree := roster.GenerateNaryTree(nbrNodes)
+	pi, err := local.CreateProtocol(name, tree)
+	require.NoError(t, err)
+	protocol := pi.(*Setup)
+	protocol.Wait = true
+	require.NoError(t, protocol.Start())
+	select {
+	case ok := <-protocol.Finished:
+		require.True(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Didn't finish in time")
+	}
+	ss, _, err := protocol.SharedSecret()
+	require.NoError(t, err)
+	return ss
+}
@@ -0,0 +1,147 @@
+package pedersen
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+
+	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+)
+
+// Fault is the reason why a node is considered faulty in a DKG.
+type Fault int
+
+const (
+	// FaultMissingDeal means the deal of the node didn't arrive in time.
+	FaultMissingDeal Fault = iota
+	// FaultInvalidDeal means the node sent a deal that couldn't be
+	// processed.
+	FaultInvalidDeal
+	// FaultMissingResponse means the node didn't respond in time to the
+	// deal of a correct dealer.
+	FaultMissingResponse
+	// FaultInvalidJustification means the node didn't justify correctly a
+	// complaint against its deal.
+	FaultInvalidJustification
+	// FaultDisqualified means the deal of the node was not certified.
+	FaultDisqualified
+)
+
+func (f Fault) String() string {
+	switch f {
+	case FaultMissingDeal:
+		return "missing deal"
+	case FaultInvalidDeal:
+		return "invalid deal"
+	case FaultMissingResponse:
+		return "missing response"
+	case FaultInvalidJustification:
+		return "invalid justification"
+	case FaultDisqualified:
+		return "disqualified"
+	}
+	return "unknown"
+}
+
+// Misbehavior is what a node did wrong in a DKG. The deal or the
+// justification is included when it is the reason, as it is signed by the
+// faulty node.
+type Misbehavior struct {
+	// Index is the index of the node in the tree of the DKG.
+	Index         int
+	Node          *network.ServerIdentity
+	Fault         Fault
+	Deal          *dkgpedersen.Deal
+	Justification *dkgpedersen.Justification
+}
+
+// Evidence lists the misbehaviors a node saw in an aborted DKG, signed by
+// the conode key of the reporter. It can be stored by the service or used to
+// exclude nodes from the next rosters. As a node can lie about the others,
+// a service should only act on the evidence of enough reporters.
+type Evidence struct {
+	InstanceID   []byte
+	Reporter     *network.ServerIdentity
+	Misbehaviors []Misbehavior
+	Signature    []byte
+}
+
+// Verify checks the signature of the reporter.
+func (e *Evidence) Verify() error {
+	if e.Reporter == nil {
+		return errors.New("no reporter")
+	}
+	msg, err := e.hash()
+	if err != nil {
+		return err
+	}
+	return schnorr.Verify(cothority.Suite, e.Reporter.Public, msg, e.Signature)
+}
+
+// Faulty returns the servers that misbehaved.
+func (e *Evidence) Faulty() []*network.ServerIdentity {
+	var sis []*network.ServerIdentity
+	for _, m := range e.Misbehaviors {
+		sis = append(sis, m.Node)
+	}
+	return sis
+}
+
+func (e *Evidence) String() string {
+	s := fmt.Sprintf("evidence of %v:", e.Reporter)
+	for _, m := range e.Misbehaviors {
+		s += fmt.Sprintf(" %d (%v) %v;", m.Index, m.Node, m.Fault)
+	}
+	return s
+}
+
+func (e *Evidence) hash() ([]byte, error) {
+	buf, err := protobuf.Encode(&Evidence{
+		InstanceID:   e.InstanceID,
+		Reporter:     e.Reporter,
+		Misbehaviors: e.Misbehaviors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(buf)
+	return h[:], nil
+}
+
+// blame marks the node as faulty and keeps the first misbehavior seen for
+// it.
+func (o *Setup) blame(m Misbehavior) {
+	o.faulty[m.Index] = true
+	if _, ok := o.misbehaviors[m.Index]; ok {
+		return
+	}
+	if m.Index >= 0 && m.Index < len(o.nodes) {
+		m.Node = o.nodes[m.Index].ServerIdentity
+	}
+	o.misbehaviors[m.Index] = &m
+}
+
+// evidence signs the misbehaviors of the faulty nodes with the key of the
+// conode.
+func evidence(tni *onet.TreeNodeInstance, id []byte, ms []Misbehavior) (*Evidence, error) {
+	e := &Evidence{
+		InstanceID:   id,
+		Reporter:     tni.ServerIdentity(),
+		Misbehaviors: ms,
+	}
+	msg, err := e.hash()
+	if err != nil {
+		return nil, err
+	}
+	e.Signature, err = schnorr.Sign(cothority.Suite, tni.Private(), msg)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,51 @@
+package pedersen
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Instances keeps track of the DKGs a service runs at the same time, indexed
+// by their instance ID, so that the DKGs of different LTSs or beacons don't
+// get mixed up.
+type Instances struct {
+	sync.Mutex
+	setups map[string]*Setup
+}
+
+// NewInstances returns an empty set of DKG instances.
+func NewInstances() *Instances {
+	return &Instances{setups: make(map[string]*Setup)}
+}
+
+// Add sets the instance ID of the DKG and stores it. It returns an error if
+// another DKG with the same ID is still running. A DKG that finished is
+// replaced.
+func (is *Instances) Add(id []byte, setup *Setup) error {
+	is.Lock()
+	defer is.Unlock()
+	if old, ok := is.setups[string(id)]; ok && old != setup {
+		switch old.Status().Phase {
+		case PhaseCertified, PhaseAborted:
+		default:
+			return fmt.Errorf("a DKG for instance %x is already running", id)
+		}
+	}
+	setup.InstanceID = append([]byte{}, id...)
+	is.setups[string(id)] = setup
+	return nil
+}
+
+// Get returns the last DKG of the instance, or nil if there is none.
+func (is *Instances) Get(id []byte) *Setup {
+	is.Lock()
+	defer is.Unlock()
+	return is.setups[string(id)]
+}
+
+// Remove forgets about the DKG of the instance.
+func (is *Instances) Remove(id []byte) {
+	is.Lock()
+	defer is.Unlock()
+	delete(is.setups, string(id))
+}
@@ -0,0 +1,76 @@
+package pedersen
+
+// Phase is the step of the DKG a node is at.
+type Phase int
+
+const (
+	// PhaseInit is the phase until the root sent the public keys.
+	PhaseInit Phase = iota
+	// PhaseDeal is the phase where the node waits for the deals.
+	PhaseDeal
+	// PhaseResponse is the phase where the node waits for the responses.
+	PhaseResponse
+	// PhaseJustification is the phase where the node waits for the
+	// justifications of the missing responses and complaints.
+	PhaseJustification
+	// PhaseCertified means the distributed key is ready.
+	PhaseCertified
+	// PhaseAborted means the DKG couldn't finish.
+	PhaseAborted
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseInit:
+		return "init"
+	case PhaseDeal:
+		return "deal"
+	case PhaseResponse:
+		return "response"
+	case PhaseJustification:
+		return "justification"
+	case PhaseCertified:
+		return "certified"
+	case PhaseAborted:
+		return "aborted"
+	}
+	return "unknown"
+}
+
+// Progress is the state of the DKG on a node.
+type Progress struct {
+	Phase              Phase
+	DealsSent          int
+	DealsProcessed     int
+	ExpectedDeals      int
+	ResponsesProcessed int
+	// Faulty holds the roster indexes of the faulty nodes once the DKG
+	// aborted.
+	Faulty []int
+}
+
+// Status returns the current progress of the DKG on this node.
+func (o *Setup) Status() Progress {
+	o.progressLock.Lock()
+	defer o.progressLock.Unlock()
+	p := o.progress
+	p.Faulty = append([]int{}, o.progress.Faulty...)
+	return p
+}
+
+// report updates the progress and sends it to Updates, if it is set. An
+// update is dropped if the channel is full, so a slow reader never blocks
+// the DKG.
+func (o *Setup) report(update func(p *Progress)) {
+	o.progressLock.Lock()
+	update(&o.progress)
+	p := o.progress
+	o.progressLock.Unlock()
+	if o.Updates == nil {
+		return
+	}
+	select {
+	case o.Updates <- p:
+	default:
+	}
+}
@@ -0,0 +1,345 @@
+package pedersen
+
+import (
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// RecoverName is the protocol identifier string of the share recovery.
+const RecoverName = "Pedersen_DKG_Recover"
+
+const defaultRecoverTimeout = 30 * time.Second
+
+func init() {
+	onet.GlobalProtocolRegister(RecoverName, NewRecover)
+}
+
+// Recover lets the root, a node that missed the DKG or lost its share, get
+// its share back from the other shareholders, which are the children. Each
+// shareholder sends its share multiplied by its Lagrange coefficient and
+// blinded by masks shared with the other shareholders, so that the root only
+// learns the sum, which is its share, and the secret is never reconstructed.
+//
+// The masks of two shareholders are derived from the Diffie-Hellman key of
+// their conodes, so they are never sent. The shareholders check the request
+// of the root with Verify, and only take part if every child is the node of
+// Roster holding the share the root gives it, and if there are at least as
+// many children as the threshold.
+type Recover struct {
+	*onet.TreeNodeInstance
+	Finished chan bool
+
+	// Index is the index of the share to recover, it must be set by the
+	// root.
+	Index int
+	// Commits are the public coefficients of the distributed key. If the
+	// root doesn't set them, the ones of the shareholders are used, in which
+	// case they must all agree.
+	Commits []kyber.Point
+	// Timeout is the time given to the shareholders to answer.
+	Timeout time.Duration
+	// Share must be set on every shareholder before the protocol starts.
+	// Once the protocol is finished, it is the recovered share on the root.
+	Share *share.PriShare
+	// Roster must be set on every shareholder, it holds the nodes of the
+	// DKG, the share of index i being held by the i-th node. A root with
+	// children whose conode keys it controls could otherwise remove the
+	// masks of the other shareholders.
+	Roster *onet.Roster
+	// Verify must be set on every shareholder, it is called with the index
+	// requested by the root and the shareholders it chose. The shareholder
+	// refuses to take part if it isn't set or returns false, as the root
+	// could otherwise get the shares of other nodes.
+	Verify func(root *onet.TreeNode, index int, children []*onet.TreeNode) bool
+
+	indexes []int
+
+	structRecoverInit  chan structRecoverInit
+	structRecoverIndex chan []structRecoverIndex
+	structRecoverStart chan structRecoverStart
+	structRecoverShare chan structRecoverShare
+
+	suite vss.Suite
+}
+
+// NewRecover initialises the structure for use in one round of recovery.
+func NewRecover(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	return CustomRecover(n, cothority.Suite)
+}
+
+// CustomRecover initialises the structure with a custom suite.
+func CustomRecover(n *onet.TreeNodeInstance, suite vss.Suite) (onet.ProtocolInstance, error) {
+	o := &Recover{
+		TreeNodeInstance: n,
+		Finished:         make(chan bool, 1),
+		Timeout:          defaultRecoverTimeout,
+		suite:            suite,
+	}
+	err := o.RegisterChannels(&o.structRecoverInit, &o.structRecoverIndex,
+		&o.structRecoverStart, &o.structRecoverShare)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Start asks the shareholders for the index of their share.
+func (o *Recover) Start() error {
+	log.Lvl3("Starting share recovery")
+	if o.Index < 0 {
+		return errors.New("invalid index")
+	}
+	errs := o.Broadcast(&RecoverInit{Index: uint32(o.Index), Timeout: o.Timeout})
+	if len(errs) != 0 {
+		return fmt.Errorf("broadcast failed with error(s): %v", errs)
+	}
+	return nil
+}
+
+// Dispatch runs the root or the shareholder part of the protocol.
+func (o *Recover) Dispatch() error {
+	defer o.Done()
+	var err error
+	if o.IsRoot() {
+		err = o.dispatchRoot()
+	} else {
+		err = o.dispatchShareholder()
+	}
+	if err != nil {
+		return err
+	}
+	o.Finished <- true
+	return nil
+}
+
+func (o *Recover) dispatchRoot() error {
+	timeout := time.After(o.Timeout)
+
+	var replies []structRecoverIndex
+	select {
+	case replies = <-o.structRecoverIndex:
+	case <-timeout:
+		return errors.New("shareholders didn't send their index in time")
+	}
+
+	children := o.Children()
+	indexes := make([]int, len(children))
+	for i, c := range children {
+		found := false
+		for _, r := range replies {
+			if r.TreeNode.ID != c.ID {
+				continue
+			}
+			if err := o.checkCommits(r.Commits); err != nil {
+				return err
+			}
+			indexes[i] = int(r.Index)
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("missing the index of %v", c.ServerIdentity)
+		}
+	}
+	if len(o.Commits) == 0 {
+		return errors.New("no public coefficients")
+	}
+	if len(indexes) < len(o.Commits) {
+		return fmt.Errorf("%d shareholders but the threshold is %d",
+			len(indexes), len(o.Commits))
+	}
+
+	errs := o.Broadcast(&RecoverStart{Indexes: indexes})
+	if len(errs) != 0 {
+		return fmt.Errorf("broadcast failed with error(s): %v", errs)
+	}
+
+	sum := o.suite.Scalar().Zero()
+	for range children {
+		select {
+		case rs := <-o.structRecoverShare:
+			sum.Add(sum, rs.V)
+		case <-timeout:
+			return errors.New("shareholders didn't send their part in time")
+		}
+	}
+
+	// The share is only accepted if it matches the public polynomial.
+	pub := share.NewPubPoly(o.suite, nil, o.Commits)
+	expected := pub.Eval(o.Index).V
+	if !o.suite.Point().Mul(sum, nil).Equal(expected) {
+		return errors.New("the recovered share doesn't match the commitments")
+	}
+	o.Share = &share.PriShare{I: o.Index, V: sum}
+	return nil
+}
+
+func (o *Recover) dispatchShareholder() error {
+	var ri structRecoverInit
+	select {
+	case ri = <-o.structRecoverInit:
+	case <-time.After(o.Timeout):
+		return errors.New("didn't get the recovery request in time")
+	}
+	if ri.Timeout > 0 {
+		o.Timeout = ri.Timeout
+	}
+	timeout := time.After(o.Timeout)
+	o.Index = int(ri.Index)
+	if o.Share == nil {
+		return errors.New("no share to recover from")
+	}
+	if len(o.Commits) == 0 {
+		return errors.New("no public coefficients to check the recovery")
+	}
+	if o.Roster == nil {
+		return errors.New("no roster to check the shareholders")
+	}
+	if o.Verify == nil || !o.Verify(o.Root(), o.Index, o.Root().Children) {
+		return errors.New("recovery refused")
+	}
+	if o.Index == o.Share.I {
+		return errors.New("cannot recover the share of a shareholder")
+	}
+	err := o.SendToParent(&RecoverIndex{Index: uint32(o.Share.I), Commits: o.Commits})
+	if err != nil {
+		return err
+	}
+
+	var rs structRecoverStart
+	select {
+	case rs = <-o.structRecoverStart:
+	case <-timeout:
+		return errors.New("didn't get the start of the recovery in time")
+	}
+	o.indexes = rs.Indexes
+	if err := o.checkIndexes(); err != nil {
+		return err
+	}
+
+	lambda, err := o.lagrange()
+	if err != nil {
+		return err
+	}
+	v := o.suite.Scalar().Mul(lambda, o.Share.V)
+
+	// Of the two shareholders sharing a mask, the first child adds it and
+	// the second subtracts it, so the sum of the parts is the share of the
+	// root.
+	before := true
+	for _, c := range o.Root().Children {
+		if c.ID == o.TreeNode().ID {
+			before = false
+			continue
+		}
+		mask, err := o.mask(c)
+		if err != nil {
+			return err
+		}
+		if before {
+			v.Sub(v, mask)
+		} else {
+			v.Add(v, mask)
+		}
+	}
+
+	return o.SendToParent(&RecoverShare{V: v})
+}
+
+// checkIndexes makes sure the indexes sent by the root are distinct, one for
+// every child of the root which is the node of the roster holding that share,
+// and at least as many as the threshold, so that the root only gets back the
+// share it asked for.
+func (o *Recover) checkIndexes() error {
+	children := o.Root().Children
+	if len(o.indexes) != len(children) {
+		return fmt.Errorf("%d indexes for %d shareholders", len(o.indexes),
+			len(children))
+	}
+	if len(o.indexes) < len(o.Commits) || len(o.indexes) < 2 {
+		return fmt.Errorf("%d shareholders but the threshold is %d",
+			len(o.indexes), len(o.Commits))
+	}
+	seen := make(map[int]bool)
+	for i, idx := range o.indexes {
+		if idx < 0 || idx >= len(o.Roster.List) || idx == o.Index ||
+			seen[idx] {
+			return fmt.Errorf("invalid index %d in the list", idx)
+		}
+		seen[idx] = true
+		if children[i].ID == o.TreeNode().ID && idx != o.Share.I {
+			return errors.New("own index is not at its place in the list")
+		}
+		if !o.Roster.List[idx].Equal(children[i].ServerIdentity) {
+			return fmt.Errorf("%v doesn't hold the share %d",
+				children[i].ServerIdentity, idx)
+		}
+	}
+	return nil
+}
+
+// mask returns the mask this node shares with the other shareholder, from
+// the Diffie-Hellman key of their conodes and the round of the protocol.
+func (o *Recover) mask(other *onet.TreeNode) (kyber.Scalar, error) {
+	dh := cothority.Suite.Point().Mul(o.Private(), other.ServerIdentity.Public)
+	h := sha512.New()
+	if _, err := dh.MarshalTo(h); err != nil {
+		return nil, err
+	}
+	h.Write(o.Token().RoundID[:])
+	return o.suite.Scalar().SetBytes(h.Sum(nil)), nil
+}
+
+// lagrange returns the Lagrange coefficient of the share of this node to
+// evaluate the polynomial at the index of the root.
+func (o *Recover) lagrange() (kyber.Scalar, error) {
+	xi := o.suite.Scalar().SetInt64(int64(o.Share.I + 1))
+	xj := o.suite.Scalar().SetInt64(int64(o.Index + 1))
+	num := o.suite.Scalar().One()
+	den := o.suite.Scalar().One()
+	found := false
+	for _, idx := range o.indexes {
+		if idx == o.Share.I {
+			found = true
+			continue
+		}
+		xk := o.suite.Scalar().SetInt64(int64(idx + 1))
+		num.Mul(num, o.suite.Scalar().Sub(xj, xk))
+		den.Mul(den, o.suite.Scalar().Sub(xi, xk))
+	}
+	if !found {
+		return nil, errors.New("own index is not in the list")
+	}
+	if den.Equal(o.suite.Scalar().Zero()) {
+		return nil, errors.New("duplicate index in the list")
+	}
+	return num.Div(num, den), nil
+}
+
+// checkCommits makes sure the shareholders agree on the commitments.
+func (o *Recover) checkCommits(commits []kyber.Point) error {
+	if len(commits) == 0 {
+		return nil
+	}
+	if len(o.Commits) == 0 {
+		o.Commits = commits
+		return nil
+	}
+	if len(commits) != len(o.Commits) {
+		return errors.New("shareholders don't agree on the commitments")
+	}
+	for i := range commits {
+		if !commits[i].Equal(o.Commits[i]) {
+			return errors.New("shareholders don't agree on the commitments")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package pedersen
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+
+	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+)
+
+// recoverTest runs a DKG on 4 nodes and registers the recovery protocol, in
+// which the shareholders allow the recovery of the share of the first node.
+type recoverTest struct {
+	local  *onet.LocalTest
+	roster *onet.Roster
+	shares map[network.ServerIdentityID]*dkgpedersen.DistKeyShare
+	lost   *dkgpedersen.DistKeyShare
+}
+
+func newRecoverTest(t *testing.T) *recoverTest {
+	rt := &recoverTest{
+		local:  onet.NewLocalTest(cothority.Suite),
+		shares: make(map[network.ServerIdentityID]*dkgpedersen.DistKeyShare),
+	}
+	srvs := rt.local.GenServers(4)
+	rt.roster = rt.local.GenRosterFromHost(srvs...)
+
+	var lock sync.Mutex
+	setups := make(map[network.ServerIdentityID]*Setup)
+	for _, srv := range srvs {
+		_, err := srv.ProtocolRegister("test_dkg", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			lock.Lock()
+			setups[n.ServerIdentity().ID] = pi.(*Setup)
+			lock.Unlock()
+			return pi, err
+		})
+		require.NoError(t, err)
+		_, err = srv.ProtocolRegister("test_recover", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewRecover(n)
+			rec := pi.(*Recover)
+			lock.Lock()
+			if dks := rt.shares[n.ServerIdentity().ID]; dks != nil {
+				rec.Share = dks.PriShare()
+				rec.Commits = dks.Commits
+			}
+			lost := rt.lost
+			lock.Unlock()
+			rec.Roster = rt.roster
+			rec.Verify = func(root *onet.TreeNode, index int, _ []*onet.TreeNode) bool {
+				return root.ServerIdentity.Equal(rt.roster.List[0]) &&
+					index == lost.PriShare().I
+			}
+			return pi, err
+		})
+		require.NoError(t, err)
+	}
+
+	tree := rt.roster.GenerateNaryTree(len(rt.roster.List))
+	pi, err := rt.local.CreateProtocol("test_dkg", tree)
+	require.NoError(t, err)
+	setup := pi.(*Setup)
+	setup.Wait = true
+	setup.KeyPair = &key.Pair{
+		Public:  srvs[0].ServerIdentity.Public,
+		Private: srvs[0].ServerIdentity.GetPrivate(),
+	}
+	require.NoError(t, setup.Start())
+	select {
+	case <-setup.Finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("dkg didn't finish in time")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, si := range rt.roster.List {
+		dks, err := setups[si.ID].DKG.DistKeyShare()
+		require.NoError(t, err)
+		rt.shares[si.ID] = dks
+	}
+	// the first node loses its share
+	rt.lost = rt.shares[rt.roster.List[0].ID]
+	delete(rt.shares, rt.roster.List[0].ID)
+	return rt
+}
+
+func TestRecover(t *testing.T) {
+	rt := newRecoverTest(t)
+	defer rt.local.CloseAll()
+
+	tree := rt.roster.GenerateStar()
+	pi, err := rt.local.CreateProtocol("test_recover", tree)
+	require.NoError(t, err)
+	rec := pi.(*Recover)
+	rec.Index = rt.lost.PriShare().I
+	rec.Timeout = 10 * time.Second
+	require.NoError(t, rec.Start())
+	select {
+	case <-rec.Finished:
+	case <-time.After(20 * time.Second):
+		t.Fatal("recovery didn't finish in time")
+	}
+	require.Equal(t, rt.lost.PriShare().I, rec.Share.I)
+	require.True(t, rt.lost.PriShare().V.Equal(rec.Share.V))
+
+	// the recovered share works with the others to rec the secret
+	priShares := []*share.PriShare{rec.Share}
+	for _, si := range rt.roster.List[1:3] {
+		priShares = append(priShares, rt.shares[si.ID].PriShare())
+	}
+	secret, err := share.RecoverSecret(cothority.Suite, priShares, 3, 4)
+	require.NoError(t, err)
+	require.True(t, cothority.Suite.Point().Mul(secret, nil).Equal(rt.lost.Public()))
+}
+
+// maliciousRecover is a root starting the recovery with the shareholders of
+// its choice, such as fewer than the threshold or nodes whose keys it
+// controls, which would let it learn the shares of the others.
+type maliciousRecover struct {
+	*Recover
+	parts chan kyber.Scalar
+}
+
+func (m *maliciousRecover) Dispatch() error {
+	defer m.Done()
+	var replies []structRecoverIndex
+	select {
+	case replies = <-m.structRecoverIndex:
+	case <-time.After(m.Timeout):
+		return nil
+	}
+	indexes := make([]int, len(replies))
+	for i, c := range m.Children() {
+		for _, r := range replies {
+			if r.TreeNode.ID == c.ID {
+				indexes[i] = int(r.Index)
+			}
+		}
+	}
+	m.Broadcast(&RecoverStart{Indexes: indexes})
+	for range replies {
+		select {
+		case rs := <-m.structRecoverShare:
+			m.parts <- rs.V
+		case <-time.After(m.Timeout):
+			return nil
+		}
+	}
+	return nil
+}
+
+// registerMalicious registers the malicious root on the first node and the
+// shareholders on the others, which accept any request of the root. It
+// returns the channel of the parts the root gets.
+func (rt *recoverTest) registerMalicious(t *testing.T) chan kyber.Scalar {
+	parts := make(chan kyber.Scalar, len(rt.roster.List)+1)
+	_, err := rt.local.Servers[rt.roster.List[0].ID].ProtocolRegister(
+		"test_recover_malicious",
+		func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewRecover(n)
+			if err != nil {
+				return nil, err
+			}
+			return &maliciousRecover{Recover: pi.(*Recover), parts: parts}, nil
+		})
+	require.NoError(t, err)
+	for _, si := range rt.roster.List[1:] {
+		_, err := rt.local.Servers[si.ID].ProtocolRegister(
+			"test_recover_malicious",
+			func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+				pi, err := NewRecover(n)
+				rec := pi.(*Recover)
+				dks := rt.shares[n.ServerIdentity().ID]
+				rec.Share = dks.PriShare()
+				rec.Commits = dks.Commits
+				rec.Roster = rt.roster
+				rec.Verify = func(*onet.TreeNode, int, []*onet.TreeNode) bool { return true }
+				return pi, err
+			})
+		require.NoError(t, err)
+	}
+	return parts
+}
+
+func TestRecover_BelowThreshold(t *testing.T) {
+	rt := newRecoverTest(t)
+	defer rt.local.CloseAll()
+	parts := rt.registerMalicious(t)
+
+	// Two shareholders for a threshold of three.
+	roster := onet.NewRoster(rt.roster.List[:3])
+	pi, err := rt.local.CreateProtocol("test_recover_malicious",
+		roster.GenerateStar())
+	require.NoError(t, err)
+	m := pi.(*maliciousRecover)
+	m.Index = rt.lost.PriShare().I
+	m.Timeout = 2 * time.Second
+	require.NoError(t, m.Start())
+
+	select {
+	case <-parts:
+		t.Fatal("a shareholder sent its part below the threshold")
+	case <-time.After(3 * time.Second):
+	}
+}
+
+func TestRecover_Sybil(t *testing.T) {
+	rt := newRecoverTest(t)
+	defer rt.local.CloseAll()
+	parts := rt.registerMalicious(t)
+
+	// The root replaces the last shareholder with a node whose key it
+	// controls, which claims the share of the last shareholder.
+	sybil := rt.local.GenServers(1)[0]
+	_, err := sybil.ProtocolRegister("test_recover_malicious",
+		func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewRecover(n)
+			rec := pi.(*Recover)
+			dks := rt.shares[rt.roster.List[3].ID]
+			rec.Share = dks.PriShare()
+			rec.Commits = dks.Commits
+			rec.Roster = rt.roster
+			rec.Verify = func(*onet.TreeNode, int, []*onet.TreeNode) bool { return true }
+			return pi, err
+		})
+	require.NoError(t, err)
+
+	roster := onet.NewRoster(append(rt.roster.List[:3:3],
+		sybil.ServerIdentity))
+	pi, err := rt.local.CreateProtocol("test_recover_malicious",
+		roster.GenerateStar())
+	require.NoError(t, err)
+	m := pi.(*maliciousRecover)
+	m.Index = rt.lost.PriShare().I
+	m.Timeout = 2 * time.Second
+	require.NoError(t, m.Start())
+
+	select {
+	case <-parts:
+		t.Fatal("a shareholder sent its part next to a sybil")
+	case <-time.After(3 * time.Second):
+	}
+}
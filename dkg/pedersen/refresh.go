@@ -0,0 +1,348 @@
+package pedersen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/encrypt/ecies"
+	"go.dedis.ch/kyber/v3/share"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// RefreshName is the protocol identifier string of the share refresh.
+const RefreshName = "Pedersen_DKG_Refresh"
+
+const defaultRefreshTimeout = time.Minute
+
+func init() {
+	onet.GlobalProtocolRegister(RefreshName, NewRefresh)
+}
+
+// Refresh re-randomizes the shares of an existing distributed key without a
+// dealer. Every node shares a random polynomial whose constant term is zero
+// and adds the shares it gets from the others to its own share. The shared
+// secret and the public key stay the same, but the new shares cannot be
+// combined with the old ones, so an attacker has to get a threshold of
+// shares between two refreshes.
+//
+// All the nodes holding a share must be in the tree. The new shares are only
+// applied once the root checked that all nodes added the same polynomials.
+type Refresh struct {
+	*onet.TreeNodeInstance
+	Finished chan bool
+
+	// Shared must be set on every node before the protocol starts. Once the
+	// protocol is finished, Refreshed holds the new share.
+	Shared    *SharedSecret
+	Refreshed *SharedSecret
+	// KeyPair is used to encrypt the shares sent between the nodes. If it is
+	// nil, the network key pair is used.
+	KeyPair *key.Pair
+	// Timeout is the time given to the nodes to finish the refresh.
+	Timeout time.Duration
+
+	publics []kyber.Point
+	indexes []int
+
+	structRefreshInit      chan structRefreshInit
+	structRefreshInitReply chan []structRefreshInitReply
+	structStartRefresh     chan structStartRefresh
+	structRefreshDeal      chan structRefreshDeal
+	structRefreshDone      chan []structRefreshDone
+	structRefreshApply     chan structRefreshApply
+
+	suite vss.Suite
+}
+
+// NewRefresh initialises the structure for use in one round of refresh.
+func NewRefresh(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	return CustomRefresh(n, cothority.Suite, nil)
+}
+
+// CustomRefresh initialises the structure with a custom suite and a keypair.
+func CustomRefresh(n *onet.TreeNodeInstance, suite vss.Suite, keypair *key.Pair) (onet.ProtocolInstance, error) {
+	o := &Refresh{
+		TreeNodeInstance: n,
+		Finished:         make(chan bool, 1),
+		KeyPair:          keypair,
+		Timeout:          defaultRefreshTimeout,
+		suite:            suite,
+	}
+	err := o.RegisterChannels(&o.structRefreshInit, &o.structRefreshInitReply,
+		&o.structStartRefresh, &o.structRefreshDeal, &o.structRefreshDone,
+		&o.structRefreshApply)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Start asks all nodes for their key and the index of their share.
+func (o *Refresh) Start() error {
+	log.Lvl3("Starting share refresh")
+	if o.Shared == nil {
+		return errors.New("missing the shared secret")
+	}
+	errs := o.Broadcast(&RefreshInit{Timeout: o.Timeout})
+	if len(errs) != 0 {
+		return fmt.Errorf("broadcast failed with error(s): %v", errs)
+	}
+	return nil
+}
+
+// Dispatch runs the refresh on all nodes. Finished gets false if the refresh
+// failed.
+func (o *Refresh) Dispatch() (err error) {
+	defer o.Done()
+	defer func() {
+		if err != nil {
+			o.Finished <- false
+		}
+	}()
+	if o.Shared == nil {
+		return errors.New("missing the shared secret")
+	}
+	timeout := time.After(o.Timeout)
+
+	if o.IsRoot() {
+		if err := o.rootStart(timeout); err != nil {
+			return err
+		}
+	} else {
+		select {
+		case ri := <-o.structRefreshInit:
+			if ri.Timeout > 0 {
+				timeout = time.After(ri.Timeout)
+			}
+		case <-timeout:
+			return errors.New("didn't get the start of the refresh in time")
+		}
+		err := o.SendToParent(&RefreshInitReply{
+			Public: o.keyPair().Public,
+			Index:  uint32(o.Shared.Index),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var sr structStartRefresh
+	select {
+	case sr = <-o.structStartRefresh:
+	case <-timeout:
+		return errors.New("didn't get the keys of the nodes in time")
+	}
+	o.publics = sr.Publics
+	o.indexes = sr.Indexes
+	if len(o.publics) != len(o.List()) || len(o.indexes) != len(o.List()) {
+		return errors.New("wrong number of keys")
+	}
+
+	refreshed, digest, err := o.refresh(timeout)
+	if err != nil {
+		return err
+	}
+
+	if o.IsRoot() {
+		ok := true
+		if len(o.Children()) > 0 {
+			select {
+			case replies := <-o.structRefreshDone:
+				for _, r := range replies {
+					if !bytes.Equal(r.Digest, digest) {
+						log.Warn(o.ServerIdentity(), r.ServerIdentity,
+							"used other polynomials")
+						ok = false
+					}
+				}
+			case <-timeout:
+				log.Warn(o.ServerIdentity(), "not all nodes refreshed in time")
+				ok = false
+			}
+		}
+		errs := o.Broadcast(&RefreshApply{OK: ok})
+		if len(errs) != 0 {
+			return fmt.Errorf("broadcast failed with error(s): %v", errs)
+		}
+		if !ok {
+			return errors.New("the nodes didn't agree on the refresh")
+		}
+	} else {
+		if err := o.SendToParent(&RefreshDone{Digest: digest}); err != nil {
+			return err
+		}
+		select {
+		case ra := <-o.structRefreshApply:
+			if !ra.OK {
+				return errors.New("the refresh was cancelled by the root")
+			}
+		case <-timeout:
+			return errors.New("didn't get the end of the refresh in time")
+		}
+	}
+
+	o.Refreshed = refreshed
+	o.Finished <- true
+	return nil
+}
+
+func (o *Refresh) rootStart(timeout <-chan time.Time) error {
+	nodes := o.List()
+	o.publics = make([]kyber.Point, len(nodes))
+	o.indexes = make([]int, len(nodes))
+	var replies []structRefreshInitReply
+	if len(o.Children()) > 0 {
+		select {
+		case replies = <-o.structRefreshInitReply:
+		case <-timeout:
+			return errors.New("not all nodes sent their key in time")
+		}
+	}
+	for i, tn := range nodes {
+		if tn.ID == o.TreeNode().ID {
+			o.publics[i] = o.keyPair().Public
+			o.indexes[i] = o.Shared.Index
+			continue
+		}
+		for _, r := range replies {
+			if r.TreeNode.ID == tn.ID {
+				o.publics[i] = r.Public
+				o.indexes[i] = int(r.Index)
+			}
+		}
+		if o.publics[i] == nil {
+			return fmt.Errorf("missing the key of %v", tn.ServerIdentity)
+		}
+	}
+	errs := o.Multicast(&StartRefresh{Publics: o.publics, Indexes: o.indexes}, nodes...)
+	if len(errs) != 0 {
+		return fmt.Errorf("multicast failed with error(s): %v", errs)
+	}
+	return nil
+}
+
+// refresh shares a random polynomial with a zero constant term, adds the
+// shares of the other nodes and returns the new shared secret together with
+// a digest of all the polynomials that were added.
+func (o *Refresh) refresh(timeout <-chan time.Time) (*SharedSecret, []byte, error) {
+	t := len(o.Shared.Commits)
+	zero := o.suite.Scalar().Zero()
+	poly := share.NewPriPoly(o.suite, t, zero, o.suite.RandomStream())
+	_, ownCommits := poly.Commit(nil).Info()
+
+	nodes := o.List()
+	own := -1
+	for i, tn := range nodes {
+		if tn.ID == o.TreeNode().ID {
+			own = i
+			continue
+		}
+		buf, err := poly.Eval(o.indexes[i]).V.MarshalBinary()
+		if err != nil {
+			return nil, nil, err
+		}
+		cipher, err := ecies.Encrypt(o.suite, o.publics[i], buf, o.suite.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = o.SendTo(tn, &RefreshDeal{Commits: ownCommits, Cipher: cipher})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	commits := make([][]kyber.Point, len(nodes))
+	commits[own] = ownCommits
+	v := o.suite.Scalar().Add(o.Shared.V, poly.Eval(o.Shared.Index).V)
+	for range nodes[1:] {
+		var rd structRefreshDeal
+		select {
+		case rd = <-o.structRefreshDeal:
+		case <-timeout:
+			return nil, nil, errors.New("not all refresh deals arrived in time")
+		}
+		sender := -1
+		for i, tn := range nodes {
+			if tn.ID == rd.TreeNode.ID {
+				sender = i
+			}
+		}
+		if sender < 0 || commits[sender] != nil {
+			return nil, nil, errors.New("unexpected refresh deal")
+		}
+		s, err := o.checkDeal(rd.RefreshDeal)
+		if err != nil {
+			return nil, nil, fmt.Errorf("refresh deal of %v: %v",
+				rd.ServerIdentity, err)
+		}
+		commits[sender] = rd.Commits
+		v.Add(v, s)
+	}
+
+	pub := share.NewPubPoly(o.suite, nil, o.Shared.Commits)
+	h := sha256.New()
+	for _, c := range commits {
+		var err error
+		pub, err = pub.Add(share.NewPubPoly(o.suite, nil, c))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range c {
+			if _, err := p.MarshalTo(h); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	_, newCommits := pub.Info()
+	if !o.suite.Point().Mul(v, nil).Equal(pub.Eval(o.Shared.Index).V) {
+		return nil, nil, errors.New("the new share doesn't match the commitments")
+	}
+	return &SharedSecret{
+		Index:   o.Shared.Index,
+		V:       v,
+		X:       o.Shared.X,
+		Commits: newCommits,
+	}, h.Sum(nil), nil
+}
+
+// checkDeal decrypts the share of the deal and checks it against the
+// commitments, which must be of a polynomial with a zero constant term.
+func (o *Refresh) checkDeal(rd RefreshDeal) (kyber.Scalar, error) {
+	if len(rd.Commits) != len(o.Shared.Commits) {
+		return nil, errors.New("wrong degree")
+	}
+	if !rd.Commits[0].Equal(o.suite.Point().Null()) {
+		return nil, errors.New("the polynomial would change the secret")
+	}
+	buf, err := ecies.Decrypt(o.suite, o.keyPair().Private, rd.Cipher, o.suite.Hash)
+	if err != nil {
+		return nil, err
+	}
+	s := o.suite.Scalar()
+	if err := s.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+	expected := share.NewPubPoly(o.suite, nil, rd.Commits).Eval(o.Shared.Index).V
+	if !o.suite.Point().Mul(s, nil).Equal(expected) {
+		return nil, errors.New("the share doesn't match the commitments")
+	}
+	return s, nil
+}
+
+func (o *Refresh) keyPair() *key.Pair {
+	if o.KeyPair == nil {
+		o.KeyPair = &key.Pair{
+			Public:  o.Public(),
+			Private: o.Private(),
+		}
+	}
+	return o.KeyPair
+}
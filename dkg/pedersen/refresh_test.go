@@ -0,0 +1,101 @@
+package pedersen
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestRefresh(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	srvs := local.GenServers(4)
+	roster := local.GenRosterFromHost(srvs...)
+
+	var lock sync.Mutex
+	setups := make(map[network.ServerIdentityID]*Setup)
+	refreshes := make(map[network.ServerIdentityID]*Refresh)
+	shared := make(map[network.ServerIdentityID]*SharedSecret)
+	for _, srv := range srvs {
+		_, err := srv.ProtocolRegister("test_dkg", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			lock.Lock()
+			setups[n.ServerIdentity().ID] = pi.(*Setup)
+			lock.Unlock()
+			return pi, err
+		})
+		require.NoError(t, err)
+		_, err = srv.ProtocolRegister("test_refresh", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewRefresh(n)
+			lock.Lock()
+			pi.(*Refresh).Shared = shared[n.ServerIdentity().ID]
+			refreshes[n.ServerIdentity().ID] = pi.(*Refresh)
+			lock.Unlock()
+			return pi, err
+		})
+		require.NoError(t, err)
+	}
+
+	tree := roster.GenerateNaryTree(len(roster.List))
+	pi, err := local.CreateProtocol("test_dkg", tree)
+	require.NoError(t, err)
+	setup := pi.(*Setup)
+	setup.Wait = true
+	setup.KeyPair = &key.Pair{
+		Public:  srvs[0].ServerIdentity.Public,
+		Private: srvs[0].ServerIdentity.GetPrivate(),
+	}
+	require.NoError(t, setup.Start())
+	select {
+	case <-setup.Finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("dkg didn't finish in time")
+	}
+	oldShares := []*share.PriShare{}
+	for _, si := range roster.List {
+		ss, _, err := setups[si.ID].SharedSecret()
+		require.NoError(t, err)
+		shared[si.ID] = ss
+		oldShares = append(oldShares, &share.PriShare{I: ss.Index, V: ss.V})
+	}
+
+	pi, err = local.CreateProtocol("test_refresh", tree)
+	require.NoError(t, err)
+	refresh := pi.(*Refresh)
+	refresh.Timeout = 10 * time.Second
+	require.NoError(t, refresh.Start())
+	select {
+	case ok := <-refresh.Finished:
+		require.True(t, ok)
+	case <-time.After(20 * time.Second):
+		t.Fatal("refresh didn't finish in time")
+	}
+
+	newShares := []*share.PriShare{}
+	for _, si := range roster.List {
+		r := refreshes[si.ID]
+		require.NotNil(t, r.Refreshed)
+		require.False(t, r.Refreshed.V.Equal(shared[si.ID].V))
+		require.True(t, r.Refreshed.X.Equal(shared[si.ID].X))
+		require.True(t, r.Refreshed.Commits[0].Equal(shared[si.ID].Commits[0]))
+		newShares = append(newShares, &share.PriShare{I: r.Refreshed.Index, V: r.Refreshed.V})
+	}
+
+	secret := func(shares []*share.PriShare) *share.PriShare {
+		s, err := share.RecoverSecret(cothority.Suite, shares, 3, 4)
+		require.NoError(t, err)
+		return &share.PriShare{V: s}
+	}
+	require.True(t, secret(oldShares).V.Equal(secret(newShares).V))
+
+	// mixing old and new shares doesn't give the secret
+	mixed := []*share.PriShare{oldShares[0], oldShares[1], newShares[2]}
+	require.False(t, secret(oldShares).V.Equal(secret(mixed).V))
+}
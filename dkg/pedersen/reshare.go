@@ -0,0 +1,462 @@
+package pedersen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+
+	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+)
+
+// ReshareName is the protocol identifier string of the resharing.
+const ReshareName = "Pedersen_DKG_Reshare"
+
+const defaultReshareTimeout = time.Minute
+
+func init() {
+	onet.GlobalProtocolRegister(ReshareName, NewReshare)
+}
+
+// Reshare hands an existing distributed key over to a new set of nodes, or
+// to the same nodes with a new threshold, while keeping the same public key.
+// The tree must contain the union of the old and the new nodes. The nodes
+// that are only in the old set deal their share and stop, the nodes that are
+// only in the new set get a share out of the deals.
+type Reshare struct {
+	*onet.TreeNodeInstance
+	DKG      *dkgpedersen.DistKeyGenerator
+	Finished chan bool
+
+	// OldNodes and NewNodes are the DKG public keys of the nodes holding the
+	// key and of the nodes that will hold it. They and the thresholds must
+	// be set by the root. A threshold of zero is replaced by the default
+	// threshold of the number of nodes.
+	OldNodes     []kyber.Point
+	NewNodes     []kyber.Point
+	OldThreshold uint32
+	NewThreshold uint32
+	// Commits are the public coefficients of the distributed key, they must
+	// be set by the root.
+	Commits []kyber.Point
+	// Timeout is the time given to the nodes to finish the resharing.
+	Timeout time.Duration
+
+	// Share must be set on every old node before the protocol starts, it is
+	// the share of the distributed key.
+	Share *dkgpedersen.DistKeyShare
+	// KeyPair is the DKG key pair of the node. If it is nil, the network key
+	// pair is used.
+	KeyPair *key.Pair
+	// Misbehaviors lists the old nodes whose deal this node or another new
+	// node refused. They are skipped as long as the others reach the old
+	// threshold, else the resharing fails.
+	Misbehaviors []Misbehavior
+
+	publics   []kyber.Point
+	processed map[uint32]bool
+	responded map[[2]uint32]bool
+	blamed    map[uint32]bool
+
+	structStartReshare chan structStartReshare
+	structDeal         chan structDeal
+	structResponse     chan structResponse
+	structReshareDone  chan []structReshareDone
+
+	suite vss.Suite
+}
+
+// NewReshare initialises the structure for use in one round of resharing.
+func NewReshare(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	return CustomReshare(n, cothority.Suite, nil)
+}
+
+// CustomReshare initialises the structure with a custom suite and a keypair.
+func CustomReshare(n *onet.TreeNodeInstance, suite vss.Suite, keypair *key.Pair) (onet.ProtocolInstance, error) {
+	o := &Reshare{
+		TreeNodeInstance: n,
+		Finished:         make(chan bool, 1),
+		Timeout:          defaultReshareTimeout,
+		KeyPair:          keypair,
+		processed:        make(map[uint32]bool),
+		responded:        make(map[[2]uint32]bool),
+		blamed:           make(map[uint32]bool),
+		suite:            suite,
+	}
+
+	err := o.RegisterHandlers(o.childInit, o.rootStartReshare)
+	if err != nil {
+		return nil, err
+	}
+	err = o.RegisterChannels(&o.structStartReshare, &o.structDeal, &o.structResponse,
+		&o.structReshareDone)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// SharedSecret returns the new share of the node. It returns an error on
+// nodes that are only in the old set.
+func (o *Reshare) SharedSecret() (*SharedSecret, *dkgpedersen.DistKeyShare, error) {
+	if indexOf(o.NewNodes, o.keyPair().Public) < 0 {
+		return nil, nil, errors.New("not a member of the new nodes")
+	}
+	return NewSharedSecret(o.DKG)
+}
+
+// Start asks all the nodes for their DKG public key.
+func (o *Reshare) Start() error {
+	log.Lvl3("Starting resharing")
+	if len(o.OldNodes) == 0 || len(o.NewNodes) == 0 {
+		return errors.New("old and new nodes must be set")
+	}
+	if len(o.Commits) == 0 {
+		return errors.New("missing the public coefficients")
+	}
+	if o.OldThreshold == 0 {
+		o.OldThreshold = defaultThreshold(len(o.OldNodes))
+	}
+	if o.NewThreshold == 0 {
+		o.NewThreshold = defaultThreshold(len(o.NewNodes))
+	}
+	if int(o.OldThreshold) != len(o.Commits) {
+		return fmt.Errorf("old threshold is %d but there are %d coefficients",
+			o.OldThreshold, len(o.Commits))
+	}
+
+	errs := o.Broadcast(&Init{})
+	if len(errs) != 0 {
+		return fmt.Errorf("broadcast failed with error(s): %v", errs)
+	}
+	return nil
+}
+
+// Dispatch processes the deals and the responses until the node is done with
+// the resharing.
+func (o *Reshare) Dispatch() error {
+	defer o.Done()
+
+	var ssr structStartReshare
+	select {
+	case ssr = <-o.structStartReshare:
+	case <-time.After(o.Timeout):
+		return errors.New("didn't get the start of the resharing in time")
+	}
+	if err := o.allStartReshare(ssr); err != nil {
+		return err
+	}
+
+	timeout := time.After(o.Timeout)
+	pub := o.keyPair().Public
+	isNew := indexOf(o.NewNodes, pub) >= 0
+	oidx := indexOf(o.OldNodes, pub)
+	done := func() bool {
+		if !isNew {
+			// a node only in the old set waits for every new node to
+			// respond to its deal
+			return o.respondedTo(uint32(oidx))
+		}
+		if len(o.processed) < o.DKG.ExpectedDeals() {
+			return false
+		}
+		if o.DKG.Certified() {
+			return true
+		}
+		// The deals of the blamed nodes are never certified: the others
+		// are enough once every new node responded to them.
+		for i := range o.OldNodes {
+			if !o.blamed[uint32(i)] && !o.respondedTo(uint32(i)) {
+				return false
+			}
+		}
+		if len(o.blamed) == 0 {
+			return false
+		}
+		o.DKG.SetTimeout()
+		return o.DKG.Certified()
+	}
+
+	for !done() {
+		select {
+		case sd := <-o.structDeal:
+			if !isNew {
+				log.Warn(o.ServerIdentity(), "got a deal but isn't a new node")
+				continue
+			}
+			if err := o.allDeal(sd); err != nil {
+				return err
+			}
+		case sr := <-o.structResponse:
+			r := sr.Response.Response
+			if r == nil || r.Response == nil {
+				log.Warn(o.Name(), "got a malformed response from",
+					sr.ServerIdentity)
+				continue
+			}
+			if !isNew && int(r.Index) != oidx {
+				continue
+			}
+			if err := o.allResponse(r); err != nil {
+				return err
+			}
+		case <-timeout:
+			return errors.New("resharing didn't finish in time")
+		}
+	}
+
+	if o.IsRoot() {
+		if len(o.Children()) > 0 {
+			select {
+			case <-o.structReshareDone:
+			case <-timeout:
+				// The blamed nodes may never finish.
+				log.Warn(o.Name(), "not all nodes finished the resharing in time")
+			}
+		}
+	} else if err := o.SendToParent(&ReshareDone{}); err != nil {
+		return err
+	}
+
+	o.Finished <- true
+	return nil
+}
+
+// Children reactions
+func (o *Reshare) childInit(i structInit) error {
+	return o.SendToParent(&InitReply{Public: o.keyPair().Public})
+}
+
+// Root-node messages
+func (o *Reshare) rootStartReshare(replies []structInitReply) error {
+	nodes := o.List()
+	publics := make([]kyber.Point, len(nodes))
+	for i, tn := range nodes {
+		if tn.ID == o.TreeNode().ID {
+			publics[i] = o.keyPair().Public
+		}
+		for _, r := range replies {
+			if tn.ID == r.TreeNode.ID {
+				publics[i] = r.Public
+			}
+		}
+	}
+	return o.fullBroadcast(&StartReshare{
+		Publics:      publics,
+		OldNodes:     o.OldNodes,
+		NewNodes:     o.NewNodes,
+		OldThreshold: o.OldThreshold,
+		NewThreshold: o.NewThreshold,
+		Commits:      o.Commits,
+		Timeout:      o.Timeout,
+	})
+}
+
+// Messages for both
+func (o *Reshare) allStartReshare(ssr structStartReshare) error {
+	o.publics = ssr.Publics
+	o.OldNodes = ssr.OldNodes
+	o.NewNodes = ssr.NewNodes
+	o.OldThreshold = ssr.OldThreshold
+	o.NewThreshold = ssr.NewThreshold
+	o.Commits = ssr.Commits
+	if ssr.Timeout > 0 {
+		o.Timeout = ssr.Timeout
+	}
+
+	pub := o.keyPair().Public
+	isOld := indexOf(o.OldNodes, pub) >= 0
+	if isOld && o.Share == nil {
+		return errors.New("old node without a share")
+	}
+	if !isOld && indexOf(o.NewNodes, pub) < 0 {
+		return errors.New("neither an old nor a new node")
+	}
+
+	c := &dkgpedersen.Config{
+		Suite:        o.suite,
+		Longterm:     o.keyPair().Private,
+		OldNodes:     o.OldNodes,
+		NewNodes:     o.NewNodes,
+		Threshold:    int(o.NewThreshold),
+		OldThreshold: int(o.OldThreshold),
+	}
+	if isOld {
+		c.Share = o.Share
+	} else {
+		c.PublicCoeffs = o.Commits
+	}
+
+	var err error
+	o.DKG, err = dkgpedersen.NewDistKeyHandler(c)
+	if err != nil {
+		return err
+	}
+	if !isOld {
+		return nil
+	}
+
+	deals, err := o.DKG.Deals()
+	if err != nil {
+		return err
+	}
+	for i, d := range deals {
+		idx := indexOf(o.publics, o.NewNodes[i])
+		if idx < 0 {
+			return fmt.Errorf("new node %d is not in the tree", i)
+		}
+		if err := o.SendTo(o.List()[idx], &Deal{d}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allDeal processes the deal of an old node. As in the DKG, an invalid deal
+// blames the dealer instead of stopping the resharing.
+func (o *Reshare) allDeal(sd structDeal) error {
+	d := sd.Deal.Deal
+	if d == nil || d.Deal == nil {
+		log.Warn(o.Name(), "got a malformed deal from", sd.ServerIdentity)
+		return nil
+	}
+	// A node can only send its own deal, else it could get an honest
+	// dealer blamed.
+	if int(d.Index) >= len(o.OldNodes) ||
+		o.treeIndex(o.OldNodes[d.Index]) != o.treeIndexOfNode(sd.TreeNode) {
+		log.Warn(o.Name(), "got the deal of", d.Index, "from",
+			sd.ServerIdentity)
+		return nil
+	}
+	if o.processed[d.Index] {
+		return nil
+	}
+	o.processed[d.Index] = true
+
+	resp, err := o.DKG.ProcessDeal(d)
+	if err != nil {
+		log.Error(o.Name(), err)
+		return o.blame(d.Index, FaultInvalidDeal, d)
+	}
+	if resp.Response.Status == vss.StatusComplaint {
+		if err := o.blame(d.Index, FaultInvalidDeal, d); err != nil {
+			return err
+		}
+	}
+	return o.fullBroadcast(&Response{resp})
+}
+
+// allResponse processes the response of a new node to a deal. A complaint
+// disqualifies the dealer, as the resharing has no justification phase.
+func (o *Reshare) allResponse(r *dkgpedersen.Response) error {
+	key := [2]uint32{r.Index, r.Response.Index}
+	if o.responded[key] {
+		return nil
+	}
+	_, err := o.DKG.ProcessResponse(r)
+	if err != nil && err.Error() != "vss: already existing response from same origin" {
+		// The response may refer to a deal that is invalid, it is not a
+		// reason to stop.
+		log.Warn(o.Name(), "invalid response:", err)
+		return nil
+	}
+	o.responded[key] = true
+	if r.Response.Status == vss.StatusComplaint {
+		return o.blame(r.Index, FaultDisqualified, nil)
+	}
+	return nil
+}
+
+// respondedTo returns true if every new node responded to the deal of the
+// old node, except the old node itself that processes its own deal.
+func (o *Reshare) respondedTo(dealer uint32) bool {
+	for i, pub := range o.NewNodes {
+		if pub.Equal(o.OldNodes[dealer]) {
+			continue
+		}
+		if !o.responded[[2]uint32{dealer, uint32(i)}] {
+			return false
+		}
+	}
+	return true
+}
+
+// blame records the misbehavior of an old node, and returns an error if the
+// deals of the other old nodes can't reach the old threshold anymore.
+func (o *Reshare) blame(dealer uint32, fault Fault,
+	deal *dkgpedersen.Deal) error {
+	if o.blamed[dealer] {
+		return nil
+	}
+	o.blamed[dealer] = true
+	m := Misbehavior{Index: o.treeIndex(o.OldNodes[dealer]), Fault: fault,
+		Deal: deal}
+	if m.Index >= 0 {
+		m.Node = o.List()[m.Index].ServerIdentity
+	}
+	log.Warnf("%s: old node %d is faulty: %s", o.Name(), dealer, fault)
+	o.Misbehaviors = append(o.Misbehaviors, m)
+
+	if valid := len(o.OldNodes) - len(o.blamed); valid < int(o.OldThreshold) {
+		return fmt.Errorf("only %d valid deals for an old threshold of %d",
+			valid, o.OldThreshold)
+	}
+	return nil
+}
+
+// treeIndex returns the index in the tree of the node with the DKG key, or
+// -1.
+func (o *Reshare) treeIndex(pub kyber.Point) int {
+	return indexOf(o.publics, pub)
+}
+
+// treeIndexOfNode returns the index of the node in the tree.
+func (o *Reshare) treeIndexOfNode(tn *onet.TreeNode) int {
+	for i, n := range o.List() {
+		if n.ID == tn.ID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Convenience functions
+func (o *Reshare) keyPair() *key.Pair {
+	if o.KeyPair == nil {
+		log.Lvl3(o.ServerIdentity(), "using the network keypair as DKG keypair")
+		o.KeyPair = &key.Pair{
+			Public:  o.Public(),
+			Private: o.Private(),
+		}
+	}
+	return o.KeyPair
+}
+
+func (o *Reshare) fullBroadcast(msg interface{}) error {
+	errs := o.Multicast(msg, o.List()...)
+	if len(errs) != 0 {
+		return fmt.Errorf("multicast failed with error(s): %v", errs)
+	}
+	return nil
+}
+
+// defaultThreshold returns the number of shares needed to tolerate (n-1)/3
+// faulty nodes.
+func defaultThreshold(n int) uint32 {
+	return uint32(n - (n-1)/3)
+}
+
+func indexOf(publics []kyber.Point, pub kyber.Point) int {
+	for i, p := range publics {
+		if p.Equal(pub) {
+			return i
+		}
+	}
+	return -1
+}
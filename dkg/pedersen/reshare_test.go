@@ -0,0 +1,134 @@
+package pedersen
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+
+	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+)
+
+func TestReshare(t *testing.T) {
+	testReshare(t, -1)
+}
+
+// An old node dealing from a wrong share is blamed and skipped, the three
+// others being enough for the old threshold.
+func TestReshare_InvalidDeal(t *testing.T) {
+	testReshare(t, 1)
+}
+
+func testReshare(t *testing.T, faulty int) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	srvs := local.GenServers(7)
+	oldRoster := local.GenRosterFromHost(srvs[:4]...)
+	newRoster := local.GenRosterFromHost(srvs[2:]...)
+
+	var lock sync.Mutex
+	setups := make(map[network.ServerIdentityID]*Setup)
+	reshares := make(map[network.ServerIdentityID]*Reshare)
+	shares := make(map[network.ServerIdentityID]*dkgpedersen.DistKeyShare)
+	for _, srv := range srvs {
+		_, err := srv.ProtocolRegister("test_dkg", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			lock.Lock()
+			setups[n.ServerIdentity().ID] = pi.(*Setup)
+			lock.Unlock()
+			return pi, err
+		})
+		require.NoError(t, err)
+		_, err = srv.ProtocolRegister("test_reshare", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewReshare(n)
+			lock.Lock()
+			pi.(*Reshare).Share = shares[n.ServerIdentity().ID]
+			reshares[n.ServerIdentity().ID] = pi.(*Reshare)
+			lock.Unlock()
+			return pi, err
+		})
+		require.NoError(t, err)
+	}
+
+	// initial DKG with the old roster
+	tree := oldRoster.GenerateNaryTree(len(oldRoster.List))
+	pi, err := local.CreateProtocol("test_dkg", tree)
+	require.NoError(t, err)
+	setup := pi.(*Setup)
+	setup.Wait = true
+	setup.KeyPair = &key.Pair{
+		Public:  srvs[0].ServerIdentity.Public,
+		Private: srvs[0].ServerIdentity.GetPrivate(),
+	}
+	require.NoError(t, setup.Start())
+	select {
+	case <-setup.Finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("dkg didn't finish in time")
+	}
+
+	oldShares := []*share.PriShare{}
+	for _, si := range oldRoster.List {
+		dks, err := setups[si.ID].DKG.DistKeyShare()
+		require.NoError(t, err)
+		shares[si.ID] = dks
+		oldShares = append(oldShares, dks.PriShare())
+	}
+	dks := shares[oldRoster.List[0].ID]
+	if faulty >= 0 {
+		id := oldRoster.List[faulty].ID
+		bad := *shares[id]
+		bad.Share = &share.PriShare{I: bad.Share.I,
+			V: cothority.Suite.Scalar().Pick(cothority.Suite.RandomStream())}
+		shares[id] = &bad
+	}
+
+	// resharing to a roster of 5 nodes, 2 of them being old ones
+	union := local.GenRosterFromHost(srvs...)
+	tree = union.GenerateNaryTree(len(union.List))
+	pi, err = local.CreateProtocol("test_reshare", tree)
+	require.NoError(t, err)
+	reshare := pi.(*Reshare)
+	reshare.OldNodes = oldRoster.Publics()
+	reshare.NewNodes = newRoster.Publics()
+	reshare.Commits = dks.Commits
+	reshare.Timeout = 10 * time.Second
+	require.NoError(t, reshare.Start())
+	select {
+	case <-reshare.Finished:
+	case <-time.After(20 * time.Second):
+		t.Fatal("resharing didn't finish in time")
+	}
+
+	newShares := []*share.PriShare{}
+	for _, si := range newRoster.List {
+		ss, newDks, err := reshares[si.ID].SharedSecret()
+		require.NoError(t, err)
+		require.True(t, ss.X.Equal(dks.Public()))
+		newShares = append(newShares, newDks.PriShare())
+	}
+	_, _, err = reshares[oldRoster.List[0].ID].SharedSecret()
+	require.Error(t, err)
+	if faulty >= 0 {
+		for _, si := range newRoster.List {
+			ms := reshares[si.ID].Misbehaviors
+			require.Len(t, ms, 1)
+			require.Equal(t, faulty, ms[0].Index)
+			require.True(t, ms[0].Node.Equal(oldRoster.List[faulty]))
+		}
+	}
+
+	secret := func(shares []*share.PriShare, n int) kyber.Scalar {
+		s, err := share.RecoverSecret(cothority.Suite, shares, int(defaultThreshold(n)), n)
+		require.NoError(t, err)
+		return s
+	}
+	require.True(t, secret(oldShares, 4).Equal(secret(newShares, 5)))
+}
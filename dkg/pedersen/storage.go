@@ -0,0 +1,102 @@
+package pedersen
+
+import (
+	"errors"
+
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// State is what a node needs to resume a DKG after a restart. The seed
+// regenerates the secret polynomial of the node and Longterm is the private
+// key of the node in the DKG, so they must be protected like the shares.
+type State struct {
+	Publics   []kyber.Point
+	Threshold uint32
+	Seed      []byte
+	Deals     []*Deal
+	Responses []*Response
+	// Longterm is the encoding of the private key the DKG was started with,
+	// which is not the key of the network if the suites differ.
+	Longterm []byte `protobuf:"opt"`
+}
+
+// Storage keeps the state of the DKGs a node takes part in, indexed by the
+// round ID of the protocol.
+type Storage interface {
+	// SaveState stores the state of the DKG.
+	SaveState(id []byte, st *State) error
+	// LoadState returns the state of the DKG or nil if there is none.
+	LoadState(id []byte) (*State, error)
+	// DeleteState removes the state of the DKG once it is finished.
+	DeleteState(id []byte) error
+}
+
+type boltStorage struct {
+	db     *bbolt.DB
+	bucket []byte
+	suite  network.Suite
+}
+
+// NewBoltStorage returns a storage that keeps the states in the given bucket
+// of the database, like the ones returned by onet.Context.GetAdditionalBucket.
+// The suite is used to decode the points. The states are sealed with the key
+// of the atrest package, if it is set.
+func NewBoltStorage(db *bbolt.DB, bucket []byte, suite network.Suite) Storage {
+	return &boltStorage{db: db, bucket: bucket, suite: suite}
+}
+
+func (bs *boltStorage) SaveState(id []byte, st *State) error {
+	buf, err := protobuf.Encode(st)
+	if err != nil {
+		return err
+	}
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bs.bucket)
+		if b == nil {
+			return errors.New("missing bucket")
+		}
+		return atrest.Put(b, bs.bucket, id, buf)
+	})
+}
+
+func (bs *boltStorage) LoadState(id []byte) (*State, error) {
+	var buf []byte
+	err := bs.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bs.bucket)
+		if b == nil {
+			return errors.New("missing bucket")
+		}
+		v, err := atrest.Get(b, bs.bucket, id)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			buf = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil || buf == nil {
+		return nil, err
+	}
+
+	st := &State{}
+	err = protobuf.DecodeWithConstructors(buf, st, network.DefaultConstructors(bs.suite))
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (bs *boltStorage) DeleteState(id []byte) error {
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bs.bucket)
+		if b == nil {
+			return errors.New("missing bucket")
+		}
+		return b.Delete(id)
+	})
+}
@@ -0,0 +1,144 @@
+package pedersen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	bbolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) (Storage, func()) {
+	dir, err := ioutil.TempDir("", "dkg")
+	require.NoError(t, err)
+	db, err := bbolt.Open(filepath.Join(dir, "db"), 0600, nil)
+	require.NoError(t, err)
+	bucket := []byte("dkg")
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucket(bucket)
+		return err
+	}))
+	return NewBoltStorage(db, bucket, cothority.Suite), func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBoltStorage(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	st, err := storage.LoadState([]byte("abc"))
+	require.NoError(t, err)
+	require.Nil(t, st)
+
+	kp := key.NewKeyPair(cothority.Suite)
+	st = &State{
+		Publics:   []kyber.Point{kp.Public},
+		Threshold: 1,
+		Seed:      []byte{1, 2, 3},
+	}
+	require.NoError(t, storage.SaveState([]byte("abc"), st))
+	st2, err := storage.LoadState([]byte("abc"))
+	require.NoError(t, err)
+	require.True(t, st2.Publics[0].Equal(kp.Public))
+	require.Equal(t, st.Seed, st2.Seed)
+
+	require.NoError(t, storage.DeleteState([]byte("abc")))
+	st, err = storage.LoadState([]byte("abc"))
+	require.NoError(t, err)
+	require.Nil(t, st)
+}
+
+func TestSetup_Storage(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	servers := local.GenServers(4)
+	roster := local.GenRosterFromHost(servers...)
+
+	// every node needs its own storage as the states are indexed by the
+	// round of the protocol
+	storages := make([]Storage, len(servers))
+	for i, srv := range servers {
+		storage, cleanup := newTestStorage(t)
+		defer cleanup()
+		storages[i] = storage
+		_, err := srv.ProtocolRegister("test_dkg_storage", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewSetup(n)
+			if err != nil {
+				return nil, err
+			}
+			pi.(*Setup).Storage = storage
+			return pi, nil
+		})
+		require.NoError(t, err)
+	}
+
+	tree := roster.GenerateNaryTree(len(roster.List))
+	pi, err := local.CreateProtocol("test_dkg_storage", tree)
+	require.NoError(t, err)
+	setup := pi.(*Setup)
+	setup.Wait = true
+	setup.KeyPair = key.NewKeyPair(cothority.Suite)
+	require.NoError(t, setup.Start())
+
+	select {
+	case <-setup.Finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("dkg didn't finish in time")
+	}
+	_, _, err = setup.SharedSecret()
+	require.NoError(t, err)
+
+	// the state is removed once the DKG is done
+	st, err := storages[0].LoadState(setup.stateID())
+	require.NoError(t, err)
+	require.Nil(t, st)
+}
+
+func TestSetup_ResumeKeyPair(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	servers := local.GenServers(1)
+	roster := local.GenRosterFromHost(servers...)
+	tree := roster.GenerateNaryTree(1)
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	// the instances are not started so that the test drives them
+	newSetup := func(kp *key.Pair) *Setup {
+		tni, err := local.NewTreeNodeInstance(tree.Root, Name)
+		require.NoError(t, err)
+		pi, err := CustomSetup(tni, cothority.Suite, kp)
+		require.NoError(t, err)
+		pi.(*Setup).Storage = storage
+		return pi.(*Setup)
+	}
+
+	// the DKG is started with a key pair that is not the network one
+	setup := newSetup(key.NewKeyPair(cothority.Suite))
+	require.NoError(t, setup.allStartDeal(structStartDeal{StartDeal: StartDeal{
+		Publics:   []kyber.Point{setup.KeyPair.Public},
+		Threshold: 1,
+	}}))
+	st, err := storage.LoadState(setup.stateID())
+	require.NoError(t, err)
+	require.NotNil(t, st)
+
+	// after a restart, the new instance of the protocol has no key pair
+	restarted := newSetup(nil)
+	require.NoError(t, storage.SaveState(restarted.stateID(), st))
+	resumed, err := restarted.resume()
+	require.NoError(t, err)
+	require.True(t, resumed)
+	require.True(t, restarted.KeyPair.Public.Equal(setup.KeyPair.Public))
+	require.False(t, restarted.KeyPair.Public.Equal(servers[0].ServerIdentity.Public))
+	require.NotNil(t, restarted.DKG)
+}
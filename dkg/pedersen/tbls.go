@@ -0,0 +1,44 @@
+package pedersen
+
+import (
+	"errors"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+	"go.dedis.ch/onet/v3"
+
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+)
+
+// NewBlsSetup returns a Setup creating a distributed key in the G2 group of
+// the pairing suite, which can then be used for threshold BLS signatures
+// with SignShare and RecoverSignature. Every node creates a new DKG key pair
+// unless one is given before the protocol starts.
+func NewBlsSetup(n *onet.TreeNodeInstance, suite pairing.Suite) (onet.ProtocolInstance, error) {
+	g2, ok := suite.G2().(vss.Suite)
+	if !ok {
+		return nil, errors.New("the G2 group of the suite cannot be used for the DKG")
+	}
+	return CustomSetup(n, g2, nil)
+}
+
+// PubPoly returns the public polynomial of the distributed key.
+func (ss *SharedSecret) PubPoly(g kyber.Group) *share.PubPoly {
+	return share.NewPubPoly(g, nil, ss.Commits)
+}
+
+// SignShare returns the threshold BLS signature of the message with the
+// share of the node. The shared secret must come from a DKG in the G2 group
+// of the suite.
+func (ss *SharedSecret) SignShare(suite pairing.Suite, msg []byte) ([]byte, error) {
+	return tbls.Sign(suite, &share.PriShare{I: ss.Index, V: ss.V}, msg)
+}
+
+// RecoverSignature aggregates the signature shares into the BLS signature of
+// the distributed key, which verifies with bls.Verify and ss.X. Invalid
+// shares are ignored, n is the number of nodes of the DKG.
+func (ss *SharedSecret) RecoverSignature(suite pairing.Suite, msg []byte, sigs [][]byte, n int) ([]byte, error) {
+	return tbls.Recover(suite, ss.PubPoly(suite.G2()), msg, sigs, len(ss.Commits), n)
+}
@@ -0,0 +1,66 @@
+package pedersen
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestBlsSetup(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	srvs := local.GenServers(4)
+	roster := local.GenRosterFromHost(srvs...)
+	suite := bn256.NewSuite()
+
+	var lock sync.Mutex
+	setups := make(map[network.ServerIdentityID]*Setup)
+	for _, srv := range srvs {
+		_, err := srv.ProtocolRegister("test_bls_dkg", func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+			pi, err := NewBlsSetup(n, suite)
+			if err != nil {
+				return nil, err
+			}
+			lock.Lock()
+			setups[n.ServerIdentity().ID] = pi.(*Setup)
+			lock.Unlock()
+			return pi, nil
+		})
+		require.NoError(t, err)
+	}
+
+	tree := roster.GenerateNaryTree(len(roster.List))
+	pi, err := local.CreateProtocol("test_bls_dkg", tree)
+	require.NoError(t, err)
+	setup := pi.(*Setup)
+	setup.Wait = true
+	require.NoError(t, setup.Start())
+	select {
+	case ok := <-setup.Finished:
+		require.True(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("dkg didn't finish in time")
+	}
+
+	msg := []byte("threshold")
+	var sigs [][]byte
+	var ss *SharedSecret
+	for _, si := range roster.List {
+		ss, _, err = setups[si.ID].SharedSecret()
+		require.NoError(t, err)
+		sig, err := ss.SignShare(suite, msg)
+		require.NoError(t, err)
+		sigs = append(sigs, sig)
+	}
+
+	sig, err := ss.RecoverSignature(suite, msg, sigs[1:], len(roster.List))
+	require.NoError(t, err)
+	require.NoError(t, bls.Verify(suite, ss.X, msg, sig))
+}
@@ -0,0 +1,28 @@
+package pedersen
+
+import (
+	"errors"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+)
+
+// VerifyShare checks that the share of the shared secret is still consistent
+// with the public commitments of the distributed key, which are usually the
+// ones exported by another node. The first commitment must be the public key.
+func (ss *SharedSecret) VerifyShare(g kyber.Group, commits []kyber.Point) error {
+	if len(commits) == 0 {
+		return errors.New("no commitments")
+	}
+	if ss.X != nil && !commits[0].Equal(ss.X) {
+		return errors.New("the commitments are for another public key")
+	}
+	if ss.Index < 0 {
+		return errors.New("invalid index")
+	}
+	pub := share.NewPubPoly(g, nil, commits)
+	if !g.Point().Mul(ss.V, nil).Equal(pub.Eval(ss.Index).V) {
+		return errors.New("the share doesn't match the commitments")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package pedersen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/share"
+)
+
+func TestSharedSecret_VerifyShare(t *testing.T) {
+	suite := cothority.Suite
+	poly := share.NewPriPoly(suite, 3, nil, suite.RandomStream())
+	_, commits := poly.Commit(nil).Info()
+	sh := poly.Eval(2)
+	ss := &SharedSecret{Index: sh.I, V: sh.V, X: commits[0], Commits: commits}
+	require.NoError(t, ss.VerifyShare(suite, commits))
+
+	require.Error(t, ss.VerifyShare(suite, nil))
+
+	// a share changed on disk
+	bad := ss.Clone()
+	bad.V = suite.Scalar().Pick(suite.RandomStream())
+	require.Error(t, bad.VerifyShare(suite, commits))
+
+	// the commitments of another distributed key
+	other := share.NewPriPoly(suite, 3, nil, suite.RandomStream())
+	_, otherCommits := other.Commit(nil).Info()
+	require.Error(t, ss.VerifyShare(suite, otherCommits))
+	ss.X = nil
+	require.Error(t, ss.VerifyShare(suite, otherCommits))
+}
@@ -0,0 +1,291 @@
+// Package service runs the DKGs requested with the dkg client and stores the
+// share of the node under the ID of the key, so that the DKG can be used
+// without setting up Calypso.
+package service
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/cothority/v3/clientauth"
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/cothority/v3/decode"
+	"go.dedis.ch/cothority/v3/dkg"
+	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/cothority/v3/keyrotation"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// createTimeout is how long the node waits for the DKG to finish, which is
+// more than the timeouts of all the phases of the DKG.
+const createTimeout = 5 * time.Minute
+
+var storageKey = []byte("storage")
+
+var allowInsecureAdmin = false
+
+var serviceID onet.ServiceID
+
+func init() {
+	network.RegisterMessages(&storage{}, &keyConfig{})
+	var err error
+	serviceID, err = onet.RegisterNewService(dkg.ServiceName, newService)
+	log.ErrFatal(err)
+	if os.Getenv("COTHORITY_ALLOW_INSECURE_ADMIN") != "" {
+		log.Warn("COTHORITY_ALLOW_INSECURE_ADMIN is set; DKGs can be started from the public network.")
+		allowInsecureAdmin = true
+	}
+}
+
+// Service runs the DKGs and keeps the shares of the node.
+type Service struct {
+	*onet.ServiceProcessor
+	storage *storage
+	// dkgs holds the last DKG of every key, indexed by the key ID
+	dkgs *dkgprotocol.Instances
+}
+
+// storage holds the shares of the node and the rosters of the DKGs, indexed
+// by the key ID.
+type storage struct {
+	Shared  map[string]*dkgprotocol.SharedSecret
+	Rosters map[string]*onet.Roster
+
+	sync.Mutex
+}
+
+// keyConfig is sent by the root to the other nodes of the DKG.
+type keyConfig struct {
+	ID string
+}
+
+// maxKeyConfigSize is the size of the biggest keyConfig a node accepts.
+const maxKeyConfigSize = 1 << 10
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode, after restricting its admin endpoints to the loopback.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	if !allowInsecureAdmin && path == "CreateKey" && !clientauth.IsLocal(req) {
+		return nil, nil, xerrors.New("CreateKey is only allowed on loopback")
+	}
+	return clienthook.Process(req, dkg.ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// CreateKey runs a DKG among the nodes of the roster with this node as root
+// and returns the aggregate public key once all nodes stored their share.
+func (s *Service) CreateKey(req *dkg.CreateKey) (*dkg.CreateKeyReply, error) {
+	if req.ID == "" {
+		return nil, xerrors.New("empty key ID")
+	}
+	if s.hasKey(req.ID) {
+		return nil, xerrors.Errorf("key %s already exists", req.ID)
+	}
+	n := len(req.Roster.List)
+	if req.Threshold > uint32(n) {
+		return nil, xerrors.Errorf("threshold %d is bigger than the %d nodes",
+			req.Threshold, n)
+	}
+	tree := req.Roster.GenerateNaryTreeWithRoot(n, s.ServerIdentity())
+	if tree == nil {
+		return nil, xerrors.New("the node is not in the roster")
+	}
+	cfgBuf, err := protobuf.Encode(&keyConfig{ID: req.ID})
+	if err != nil {
+		return nil, xerrors.Errorf("encoding configuration: %v", err)
+	}
+
+	pi, err := s.CreateProtocol(dkgprotocol.Name, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating dkg protocol: %v", err)
+	}
+	setup := pi.(*dkgprotocol.Setup)
+	setup.Wait = true
+	setup.KeyPair = s.keyPair()
+	if req.Threshold > 0 {
+		setup.Threshold = req.Threshold
+	}
+	if err := setup.SetConfig(&onet.GenericConfig{Data: cfgBuf}); err != nil {
+		setup.Done()
+		return nil, xerrors.Errorf("setting dkg configuration: %v", err)
+	}
+	if err := s.dkgs.Add([]byte(req.ID), setup); err != nil {
+		setup.Done()
+		return nil, xerrors.Errorf("adding dkg: %v", err)
+	}
+	if err := setup.Start(); err != nil {
+		return nil, xerrors.Errorf("starting dkg: %v", err)
+	}
+
+	select {
+	case ok := <-setup.Finished:
+		if !ok {
+			return nil, xerrors.Errorf("dkg failed: %v", setup.Err)
+		}
+		shared, err := s.store(req.ID, setup)
+		if err != nil {
+			return nil, xerrors.Errorf("storing the share: %v", err)
+		}
+		log.Lvlf2("%v created key %s: %v", s.ServerIdentity(), req.ID, shared.X)
+		return &dkg.CreateKeyReply{X: shared.X}, nil
+	case <-time.After(createTimeout):
+		return nil, xerrors.Errorf("dkg didn't finish in time, stuck in phase %v",
+			setup.Status().Phase)
+	}
+}
+
+// GetKey returns the public information of a key the node holds a share of.
+func (s *Service) GetKey(req *dkg.GetKey) (*dkg.GetKeyReply, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	shared, ok := s.storage.Shared[req.ID]
+	if !ok {
+		return nil, xerrors.Errorf("didn't find key %s", req.ID)
+	}
+	shared = shared.Clone()
+	return &dkg.GetKeyReply{
+		X:       shared.X,
+		Commits: shared.Commits,
+		Roster:  *s.storage.Rosters[req.ID],
+		Index:   shared.Index,
+	}, nil
+}
+
+// NewProtocol sets up the DKG on the nodes that are not the root.
+func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfig) (onet.ProtocolInstance, error) {
+	if tn.ProtocolName() != dkgprotocol.Name {
+		return nil, nil
+	}
+	if conf == nil {
+		return nil, xerrors.New("missing dkg configuration")
+	}
+	var cfg keyConfig
+	if err := decode.Protobuf(conf.Data, &cfg, maxKeyConfigSize); err != nil {
+		return nil, xerrors.Errorf("decoding dkg configuration: %v", err)
+	}
+	if cfg.ID == "" || s.hasKey(cfg.ID) {
+		return nil, xerrors.Errorf("invalid or existing key ID %s", cfg.ID)
+	}
+
+	pi, err := dkgprotocol.NewSetup(tn)
+	if err != nil {
+		return nil, xerrors.Errorf("setting up dkg: %v", err)
+	}
+	setup := pi.(*dkgprotocol.Setup)
+	setup.KeyPair = s.keyPair()
+	if err := s.dkgs.Add([]byte(cfg.ID), setup); err != nil {
+		return nil, xerrors.Errorf("adding dkg: %v", err)
+	}
+	go func() {
+		if !<-setup.Finished {
+			log.Errorf("%v: dkg of key %s failed: %v", s.ServerIdentity(),
+				cfg.ID, setup.Err)
+			return
+		}
+		if _, err := s.store(cfg.ID, setup); err != nil {
+			log.Error(s.ServerIdentity(), "couldn't store the share:", err)
+		}
+	}()
+	return setup, nil
+}
+
+// store saves the share of the node once the DKG is finished. The roster of
+// the tree is stored, as the indexes of the shares follow its order.
+func (s *Service) store(id string, setup *dkgprotocol.Setup) (*dkgprotocol.SharedSecret, error) {
+	shared, _, err := setup.SharedSecret()
+	if err != nil {
+		return nil, xerrors.Errorf("getting shared secret: %v", err)
+	}
+	s.storage.Lock()
+	s.storage.Shared[id] = shared
+	s.storage.Rosters[id] = setup.Roster()
+	s.storage.Unlock()
+	return shared, s.save()
+}
+
+// RotateKey implements keyrotation.Rotator. It replaces the old key in the
+// rosters of the stored DKGs, so that the shares are still found for them.
+func (s *Service) RotateKey(t *keyrotation.Transition) ([]*onet.Roster,
+	error) {
+	var rosters []*onet.Roster
+	s.storage.Lock()
+	for id, roster := range s.storage.Rosters {
+		if rotated := t.Replace(roster); rotated != nil {
+			rosters = append(rosters, roster)
+			s.storage.Rosters[id] = rotated
+		}
+	}
+	s.storage.Unlock()
+	if len(rosters) == 0 {
+		return nil, nil
+	}
+	return rosters, s.save()
+}
+
+func (s *Service) hasKey(id string) bool {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	_, ok := s.storage.Shared[id]
+	return ok
+}
+
+func (s *Service) keyPair() *key.Pair {
+	return &key.Pair{
+		Public:  s.ServerIdentity().ServicePublic(dkg.ServiceName),
+		Private: s.ServerIdentity().ServicePrivate(dkg.ServiceName),
+	}
+}
+
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	return cothority.ErrorOrNil(atrest.Save(s, dkg.ServiceName, storageKey, s.storage), "saving data")
+}
+
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	defer func() {
+		if s.storage.Shared == nil {
+			s.storage.Shared = make(map[string]*dkgprotocol.SharedSecret)
+		}
+		if s.storage.Rosters == nil {
+			s.storage.Rosters = make(map[string]*onet.Roster)
+		}
+	}()
+	msg, err := atrest.Load(s, dkg.ServiceName, storageKey)
+	if err != nil {
+		return xerrors.Errorf("loading storage: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	var ok bool
+	s.storage, ok = msg.(*storage)
+	if !ok {
+		return xerrors.New("data of wrong type")
+	}
+	return nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+		dkgs:             dkgprotocol.NewInstances(),
+	}
+	if err := s.RegisterHandlers(s.CreateKey, s.GetKey); err != nil {
+		return nil, xerrors.Errorf("registering handlers: %v", err)
+	}
+	if err := s.tryLoad(); err != nil {
+		return nil, xerrors.Errorf("loading configuration: %v", err)
+	}
+	return s, nil
+}
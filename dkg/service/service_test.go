@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/dkg"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_CreateKey(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	servers, roster, _ := local.GenTree(4, true)
+	var services []*Service
+	for _, s := range local.GetServices(servers, serviceID) {
+		services = append(services, s.(*Service))
+	}
+
+	reply, err := services[0].CreateKey(&dkg.CreateKey{ID: "test", Roster: *roster})
+	require.NoError(t, err)
+
+	// the other nodes store their share once they finished the DKG
+	var shares []*share.PriShare
+	for _, s := range services {
+		var kr *dkg.GetKeyReply
+		for i := 0; i < 10; i++ {
+			kr, err = s.GetKey(&dkg.GetKey{ID: "test"})
+			if err == nil {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		require.NoError(t, err)
+		require.True(t, kr.X.Equal(reply.X))
+		require.Equal(t, 4, len(kr.Roster.List))
+		s.storage.Lock()
+		shared := s.storage.Shared["test"]
+		shares = append(shares, &share.PriShare{I: shared.Index, V: shared.V})
+		s.storage.Unlock()
+	}
+	secret, err := share.RecoverSecret(cothority.Suite, shares, 3, 4)
+	require.NoError(t, err)
+	require.True(t, cothority.Suite.Point().Mul(secret, nil).Equal(reply.X))
+
+	_, err = services[0].CreateKey(&dkg.CreateKey{ID: "test", Roster: *roster})
+	require.Error(t, err)
+	_, err = services[0].CreateKey(&dkg.CreateKey{ID: "test2", Roster: *roster,
+		Threshold: 5})
+	require.Error(t, err)
+	_, err = services[0].GetKey(&dkg.GetKey{ID: "test2"})
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package dkg
+
+import (
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessages(CreateKey{}, CreateKeyReply{})
+	network.RegisterMessages(GetKey{}, GetKeyReply{})
+}
+
+// CreateKey asks the node to run a DKG among the nodes of the roster. Every
+// node stores its share under the ID.
+type CreateKey struct {
+	ID     string      // ID under which the shares are stored.
+	Roster onet.Roster // Roster of the DKG, which must hold the node.
+	// Threshold is the number of nodes needed to use the key, the default
+	// of the DKG is used if it is 0.
+	Threshold uint32
+}
+
+// CreateKeyReply holds the distributed key.
+type CreateKeyReply struct {
+	X kyber.Point // X is the aggregate public key.
+}
+
+// GetKey asks the node for a distributed key it holds a share of.
+type GetKey struct {
+	ID string
+}
+
+// GetKeyReply holds the public information of a distributed key.
+type GetKeyReply struct {
+	X       kyber.Point   // X is the aggregate public key.
+	Commits []kyber.Point // Commits are the public commitments of the key.
+	Roster  onet.Roster   // Roster of the DKG.
+	Index   int           // Index is the share index of the node.
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"sync"
+	"time"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/cothority/v3/transport"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
@@ -39,6 +41,25 @@ func NewClient(ol *byzcoin.Client) *Client {
 	}
 }
 
+// UseTransport sends the requests of the client, and the transactions of its
+// ByzCoin client, through the transport. The searches and exports then fail
+// over from the leader to the other nodes of the roster.
+func (c *Client) UseTransport(t *transport.Transport) {
+	c.ByzCoin.UseTransport(t)
+	c.sc.UseTransport(t)
+}
+
+// sendRoster sends the request to the leader of the roster, or through the
+// transport of the ByzCoin client if it has one.
+func (c *Client) sendRoster(msg, reply interface{}) error {
+	if t := c.ByzCoin.Transport(); t != nil {
+		_, err := t.Send(c.c, transport.Order(c.ByzCoin.Roster.List,
+			&onet.ParallelOptions{DontShuffle: true}), msg, reply)
+		return err
+	}
+	return c.c.SendProtobuf(c.ByzCoin.Roster.List[0], msg, reply)
+}
+
 // Create creates a new event log. This method is synchronous: it will only
 // return once the new eventlog has been committed into the ledger (or after
 // a timeout). Upon non-error return, c.Instance will be correctly set.
@@ -130,6 +151,101 @@ func (c *Client) LogAndWait(numInterval int, ev ...Event) ([]LogID, error) {
 	return keys, nil
 }
 
+// SetSchema sets the JSON schema that the content of the events of the topic
+// must validate, an empty schema removes it. The signers need the
+// "invoke:eventlog.schema" permission. The events already logged are not
+// checked.
+func (c *Client) SetSchema(topic string, schema []byte) error {
+	if c.signerCtrs == nil {
+		c.RefreshSignerCounters()
+	}
+
+	instr := byzcoin.Instruction{
+		InstanceID: c.Instance,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contractName,
+			Command:    schemaCmd,
+			Args: []byzcoin.Argument{
+				{Name: "topic", Value: []byte(topic)},
+				{Name: "schema", Value: schema},
+			},
+		},
+		SignerCounter: c.nextCtrs(),
+	}
+	tx, err := c.ByzCoin.CreateTransaction(instr)
+	if err != nil {
+		return err
+	}
+	if err := tx.FillSignersAndSignWith(c.Signers...); err != nil {
+		return err
+	}
+	if _, err := c.ByzCoin.AddTransactionAndWait(tx, 10); err != nil {
+		return err
+	}
+	c.incrementCtrs()
+	return nil
+}
+
+// SetRetention sets the retention policy of the event log, a zero Retention
+// removes it. The signers need the "invoke:eventlog.retention" permission.
+// The leader of the chain then regularly prunes the events that are out of
+// the policy.
+func (c *Client) SetRetention(r Retention) error {
+	if c.signerCtrs == nil {
+		c.RefreshSignerCounters()
+	}
+
+	buf, err := protobuf.Encode(&r)
+	if err != nil {
+		return err
+	}
+	instr := byzcoin.Instruction{
+		InstanceID: c.Instance,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contractName,
+			Command:    retentionCmd,
+			Args:       []byzcoin.Argument{{Name: "retention", Value: buf}},
+		},
+		SignerCounter: c.nextCtrs(),
+	}
+	tx, err := c.ByzCoin.CreateTransaction(instr)
+	if err != nil {
+		return err
+	}
+	if err := tx.FillSignersAndSignWith(c.Signers...); err != nil {
+		return err
+	}
+	if _, err := c.ByzCoin.AddTransactionAndWait(tx, 10); err != nil {
+		return err
+	}
+	c.incrementCtrs()
+	return nil
+}
+
+// Prune removes the events that are out of the retention policy of the event
+// log now, without waiting for the leader to do it. The signers need the
+// "invoke:eventlog.prune" permission.
+func (c *Client) Prune() error {
+	if c.signerCtrs == nil {
+		c.RefreshSignerCounters()
+	}
+
+	instr := pruneInstruction(c.Instance, time.Now().UnixNano())
+	instr.SignerCounter = c.nextCtrs()
+	tx, err := c.ByzCoin.CreateTransaction(instr)
+	if err != nil {
+		return err
+	}
+	if err := tx.FillSignersAndSignWith(c.Signers...); err != nil {
+		return err
+	}
+	if _, err := c.ByzCoin.AddTransactionAndWait(tx, 10); err != nil {
+		return err
+	}
+	c.incrementCtrs()
+	return nil
+}
+
 // GetEvent asks the service to retrieve an event.
 func (c *Client) GetEvent(key []byte) (*Event, error) {
 	reply, err := c.ByzCoin.GetProofFromLatest(key)
@@ -206,7 +322,28 @@ func (c *Client) Search(req *SearchRequest) (*SearchResponse, error) {
 	req.Instance = c.Instance
 
 	reply := &SearchResponse{}
-	if err := c.c.SendProtobuf(c.ByzCoin.Roster.List[0], req, reply); err != nil {
+	if err := c.sendRoster(req, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Export asks the conode for a signed export of the events of the event log
+// logged between the blocks with the indexes from and to, inclusive. If to is
+// negative, it exports until the latest block. The export is verified before
+// it is returned.
+func (c *Client) Export(from, to int) (*Export, error) {
+	req := &ExportRequest{
+		Instance: c.Instance,
+		ID:       c.ByzCoin.ID,
+		From:     from,
+		To:       to,
+	}
+	reply := &Export{}
+	if err := c.sendRoster(req, reply); err != nil {
+		return nil, err
+	}
+	if err := reply.Verify(c.ByzCoin.ID); err != nil {
 		return nil, err
 	}
 	return reply, nil
@@ -242,6 +379,32 @@ func (c *Client) StreamEvents(handler StreamHandler) error {
 	return c.ByzCoin.StreamTransactions(h)
 }
 
+// SubscribeEvents is a blocking call where it calls the handler on every new
+// event of the event log that matches the topic filters of req, until the
+// connection is closed or the server stops. Contrary to StreamEvents, the
+// conode filters the events, so only the matching ones are sent. The ID and
+// Instance fields of req will be filled in from c.
+func (c *Client) SubscribeEvents(req *SubscribeEvents, handler StreamHandler) error {
+	req.ID = c.ByzCoin.ID
+	req.Instance = c.Instance
+
+	conn, err := c.c.Stream(c.ByzCoin.Roster.List[0], req)
+	if err != nil {
+		handler(Event{}, nil, err)
+		return err
+	}
+	for {
+		reply := SubscribeEventsReply{}
+		if err := conn.ReadMessage(&reply); err != nil {
+			handler(Event{}, nil, err)
+			return nil
+		}
+		for _, event := range reply.Events {
+			handler(event, reply.BlockID, nil)
+		}
+	}
+}
+
 // StreamEventsFrom is a blocking call where it calls the handler on even new
 // event from (inclusive) the given block ID until the connection is closed or
 // the server stops.
@@ -310,25 +473,39 @@ type blockOrErr struct {
 
 // handleBlocks calls the handler on the events of the block
 func handleBlocks(handler StreamHandler, sb *skipchain.SkipBlock) error {
+	err := forEachEvent(sb, func(_ byzcoin.Instruction, event *Event, err error) {
+		if err != nil {
+			handler(Event{}, nil, err)
+			return
+		}
+		handler(*event, sb.Hash, nil)
+	})
+	if err != nil {
+		handler(Event{}, nil, err)
+	}
+	return err
+}
+
+// forEachEvent calls f on the events logged by the accepted transactions of
+// the block, with the instruction that logged them.
+func forEachEvent(sb *skipchain.SkipBlock, f func(instr byzcoin.Instruction, event *Event, err error)) error {
 	var err error
 	var header byzcoin.DataHeader
 	err = protobuf.DecodeWithConstructors(sb.Data, &header, network.DefaultConstructors(cothority.Suite))
 	if err != nil {
-		err = errors.New("could not unmarshal header while streaming events " + err.Error())
-		handler(Event{}, nil, err)
-		return err
+		return errors.New("could not unmarshal header while streaming events " + err.Error())
 	}
 
 	var body byzcoin.DataBody
 	err = protobuf.DecodeWithConstructors(sb.Payload, &body, network.DefaultConstructors(cothority.Suite))
 	if err != nil {
-		err = errors.New("could not unmarshal body while streaming events " + err.Error())
-		handler(Event{}, nil, err)
-		return err
+		return errors.New("could not unmarshal body while streaming events " + err.Error())
 	}
 
 	for _, tx := range body.TxResults {
 		if tx.Accepted {
+			// The version is needed to derive the IDs of the events.
+			tx.ClientTransaction.Instructions.SetVersion(header.Version)
 			for _, instr := range tx.ClientTransaction.Instructions {
 				if instr.Invoke == nil {
 					continue
@@ -342,10 +519,10 @@ func handleBlocks(handler StreamHandler, sb *skipchain.SkipBlock) error {
 				}
 				event := &Event{}
 				if err := protobuf.Decode(eventBuf, event); err != nil {
-					handler(Event{}, nil, errors.New("could not decode the event "+err.Error()))
+					f(instr, nil, errors.New("could not decode the event "+err.Error()))
 					continue
 				}
-				handler(*event, sb.Hash, nil)
+				f(instr, event, nil)
 			}
 		}
 	}
@@ -3,6 +3,7 @@ package eventlog
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -11,10 +12,14 @@ import (
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 )
 
@@ -319,6 +324,283 @@ func TestClient_Search(t *testing.T) {
 	require.False(t, resp.Truncated)
 }
 
+func TestClient_SearchTopicPrefix(t *testing.T) {
+	s, c := newSer(t)
+	leader := s.services[0]
+	defer s.close()
+
+	err := c.Create()
+	require.NoError(t, err)
+	waitForKey(t, leader.omni, c.ByzCoin.ID, c.Instance.Slice(), testBlockInterval)
+
+	tm0 := time.Now().UnixNano()
+	topics := []string{"app.web", "app.db", "sys.kernel", "app.web", "application"}
+	events := make([]Event, len(topics))
+	for i, topic := range topics {
+		events[i] = Event{Topic: topic, Content: "prefix test", When: tm0 + int64(i)}
+	}
+	_, err = c.Log(events...)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		leader.waitForBlock(c.ByzCoin.ID)
+		if err = leader.checkBuckets(c.Instance, c.ByzCoin.ID, len(events)); err == nil {
+			break
+		}
+	}
+
+	resp, err := c.Search(&SearchRequest{TopicPrefix: "app."})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(resp.Events))
+	for _, e := range resp.Events {
+		require.True(t, strings.HasPrefix(e.Topic, "app."))
+	}
+
+	resp, err = c.Search(&SearchRequest{TopicPrefix: "app"})
+	require.NoError(t, err)
+	require.Equal(t, 4, len(resp.Events))
+
+	// Combined with a time range, which excludes the first event.
+	resp, err = c.Search(&SearchRequest{TopicPrefix: "app.", From: tm0 + 1,
+		To: tm0 + 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(resp.Events))
+
+	// Combined with a topic.
+	resp, err = c.Search(&SearchRequest{TopicPrefix: "app.", Topic: "sys.kernel"})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(resp.Events))
+
+	// Several topics in one search.
+	resp, err = c.Search(&SearchRequest{Topics: &TopicFilter{Or: []TopicFilter{
+		{Topic: "app.db"}, {Prefix: "sys."}}}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(resp.Events))
+
+	resp, err = c.Search(&SearchRequest{Topics: &TopicFilter{And: []TopicFilter{
+		{Prefix: "app"}, {Not: &TopicFilter{Topic: "app.web"}}}}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(resp.Events))
+}
+
+func TestClient_SearchCursor(t *testing.T) {
+	s, c := newSer(t)
+	leader := s.services[0]
+	defer s.close()
+
+	err := c.Create()
+	require.NoError(t, err)
+	waitForKey(t, leader.omni, c.ByzCoin.ID, c.Instance.Slice(), testBlockInterval)
+
+	tm0 := time.Now().UnixNano()
+	logCount := 12
+	events := make([]Event, logCount)
+	for ct := range events {
+		events[ct] = Event{Topic: "page", Content: fmt.Sprintf("event %d", ct), When: tm0 + int64(ct)}
+	}
+	_, err = c.Log(events...)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		leader.waitForBlock(c.ByzCoin.ID)
+		if err = leader.checkBuckets(c.Instance, c.ByzCoin.ID, logCount); err == nil {
+			break
+		}
+	}
+
+	sm := searchMax
+	searchMax = 5
+	defer func() { searchMax = sm }()
+
+	seen := make(map[string]bool)
+	req := &SearchRequest{}
+	for page := 0; ; page++ {
+		require.True(t, page < 4, "too many pages")
+		resp, err := c.Search(req)
+		require.NoError(t, err)
+		for _, e := range resp.Events {
+			require.False(t, seen[e.Content], "duplicate event %s", e.Content)
+			seen[e.Content] = true
+		}
+		if !resp.Truncated {
+			require.Nil(t, resp.Cursor)
+			break
+		}
+		require.NotNil(t, resp.Cursor)
+
+		if page == 0 {
+			// An event logged between two pages is after the first
+			// page and must not shift the next ones.
+			_, err = c.Log(NewEvent("page", "late event"))
+			require.NoError(t, err)
+			leader.waitForBlock(c.ByzCoin.ID)
+			leader.waitForBlock(c.ByzCoin.ID)
+		}
+		req = &SearchRequest{Cursor: resp.Cursor}
+	}
+	require.Equal(t, logCount, len(seen))
+	require.False(t, seen["late event"])
+
+	_, err = c.Search(&SearchRequest{Cursor: []byte("not a cursor")})
+	require.Error(t, err)
+}
+
+func TestClient_Retention(t *testing.T) {
+	s, c := newSer(t)
+	leader := s.services[0]
+	defer s.close()
+
+	err := c.Create()
+	require.NoError(t, err)
+	waitForKey(t, leader.omni, c.ByzCoin.ID, c.Instance.Slice(), testBlockInterval)
+
+	// Log two batches in two different buckets.
+	var ids []LogID
+	for _, topic := range []string{"old", "new"} {
+		events := make([]Event, 5)
+		for i := range events {
+			events[i] = NewEvent(topic, fmt.Sprintf("%s event %d", topic, i))
+		}
+		batch, err := c.Log(events...)
+		require.NoError(t, err)
+		ids = append(ids, batch...)
+		time.Sleep(bucketMaxAge + time.Second)
+	}
+	leader.waitForBlock(c.ByzCoin.ID)
+
+	// Without a policy, nothing is pruned.
+	require.Error(t, c.Prune())
+	leader.pruneAll()
+	leader.waitForBlock(c.ByzCoin.ID)
+	resp, err := c.Search(&SearchRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 10, len(resp.Events))
+
+	require.NoError(t, c.SetRetention(Retention{MaxCount: 5}))
+	leader.pruneAll()
+	for i := 0; i < 10; i++ {
+		leader.waitForBlock(c.ByzCoin.ID)
+		resp, err = c.Search(&SearchRequest{})
+		require.NoError(t, err)
+		if len(resp.Events) == 5 {
+			break
+		}
+	}
+	require.Equal(t, 5, len(resp.Events))
+	for _, e := range resp.Events {
+		require.Equal(t, "new", e.Topic)
+	}
+	_, err = c.GetEvent(ids[0])
+	require.Error(t, err)
+	_, err = c.GetEvent(ids[len(ids)-1])
+	require.NoError(t, err)
+
+	// New events still go in the kept buckets.
+	_, err = c.Log(NewEvent("new", "after pruning"))
+	require.NoError(t, err)
+	require.NoError(t, c.Prune())
+	resp, err = c.Search(&SearchRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 6, len(resp.Events))
+
+	// Only the signers with the permission can prune.
+	other := NewClient(c.ByzCoin)
+	other.Instance = c.Instance
+	other.Signers = []darc.Signer{darc.NewSignerEd25519(nil, nil)}
+	require.Error(t, other.Prune())
+
+	// The leader skips the event logs it can't prune.
+	v, err := leader.omni.GetReadOnlyStateTrie(c.ByzCoin.ID)
+	require.NoError(t, err)
+	require.True(t, canPrune(v, c.Instance,
+		darc.NewIdentityEd25519(leader.ServerIdentity().Public)))
+	require.False(t, canPrune(v, c.Instance, other.Signers[0].Identity()))
+
+	// Removing the policy stops the pruning.
+	require.NoError(t, c.SetRetention(Retention{}))
+	require.Error(t, c.Prune())
+}
+
+func TestClient_Schema(t *testing.T) {
+	s, c := newSer(t)
+	leader := s.services[0]
+	defer s.close()
+
+	require.NoError(t, c.Create())
+	waitForKey(t, leader.omni, c.ByzCoin.ID, c.Instance.Slice(), testBlockInterval)
+
+	require.Error(t, c.SetSchema("login", []byte(`{"type": "object", "pattern": "x"}`)))
+	schema := `{"type": "object", "required": ["user"], "properties": {"user": {"type": "string"}}}`
+	require.NoError(t, c.SetSchema("login", []byte(schema)))
+
+	_, err := c.Log(NewEvent("login", `{"user": "alice", "from": "lab"}`))
+	require.NoError(t, err)
+	_, err = c.Log(NewEvent("login", `{"from": "lab"}`))
+	require.Error(t, err)
+	_, err = c.Log(NewEvent("login", "not json"))
+	require.Error(t, err)
+	// The other topics are not checked.
+	_, err = c.Log(NewEvent("logout", "not json"))
+	require.NoError(t, err)
+
+	require.NoError(t, c.SetSchema("login", nil))
+	_, err = c.Log(NewEvent("login", "not json"))
+	require.NoError(t, err)
+
+	leader.waitForBlock(c.ByzCoin.ID)
+	resp, err := c.Search(&SearchRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(resp.Events))
+}
+
+func TestClient_Export(t *testing.T) {
+	s, c := newSer(t)
+	leader := s.services[0]
+	defer s.close()
+
+	require.NoError(t, c.Create())
+	waitForKey(t, leader.omni, c.ByzCoin.ID, c.Instance.Slice(), testBlockInterval)
+
+	events := []Event{NewEvent("audit", "first"), NewEvent("audit", "second")}
+	ids, err := c.Log(events...)
+	require.NoError(t, err)
+	leader.waitForBlock(c.ByzCoin.ID)
+
+	ex, err := c.Export(0, -1)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(ex.Events))
+	for i, e := range ex.Events {
+		require.Equal(t, events[i], e.Event)
+		require.Equal(t, []byte(ids[i]), e.Key)
+		require.True(t, e.Block > 0)
+	}
+	require.Equal(t, 0, len(ex.Pruned))
+	require.NoError(t, ex.Verify(c.ByzCoin.ID, s.roster))
+	require.Error(t, ex.Verify(skipchain.SkipBlockID("another chain"), s.roster))
+
+	// An export signed by a key outside of the roster is refused, even if
+	// it vouches for itself.
+	outside := key.NewKeyPair(tSuite)
+	forged := *ex
+	forged.Signer = network.NewServerIdentity(outside.Public, s.roster.List[0].Address)
+	msg, err := forged.hash()
+	require.NoError(t, err)
+	forged.Signature, err = schnorr.Sign(cothority.Suite, outside.Private, msg)
+	require.NoError(t, err)
+	require.Error(t, forged.Verify(c.ByzCoin.ID, s.roster))
+	require.Error(t, ex.Verify(c.ByzCoin.ID, nil))
+
+	// The export of the genesis block only is empty.
+	empty, err := c.Export(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(empty.Events))
+
+	// Changing an event breaks the signature.
+	ex.Events[0].Event.Content = "forged"
+	require.Error(t, ex.Verify(c.ByzCoin.ID, s.roster))
+
+	_, err = c.Export(3, 1)
+	require.Error(t, err)
+}
+
 func TestClient_StreamEvents(t *testing.T) {
 	s, c := newSer(t)
 	leader := s.services[0]
@@ -381,6 +663,49 @@ func TestClient_StreamEvents(t *testing.T) {
 	require.NoError(t, c.Close())
 }
 
+func TestClient_SubscribeEvents(t *testing.T) {
+	s, c := newSer(t)
+	leader := s.services[0]
+	defer s.close()
+
+	require.NoError(t, c.Create())
+	waitForKey(t, leader.omni, c.ByzCoin.ID, c.Instance.Slice(), testBlockInterval)
+
+	received := make(chan Event, 10)
+	h := func(e Event, sb []byte, err error) {
+		if err != nil {
+			return
+		}
+		require.NotNil(t, sb)
+		received <- e
+	}
+	go func() {
+		require.NoError(t, c.SubscribeEvents(&SubscribeEvents{TopicPrefix: "app."}, h))
+	}()
+	// Give the subscription the time to be set up.
+	time.Sleep(time.Second)
+
+	_, err := c.Log(NewEvent("app.web", "request"), NewEvent("sys", "boot"),
+		NewEvent("app.db", "query"))
+	require.NoError(t, err)
+
+	for _, content := range []string{"request", "query"} {
+		select {
+		case e := <-received:
+			require.Equal(t, content, e.Content)
+		case <-time.After(testBlockInterval + time.Second):
+			require.Fail(t, "didn't get the event "+content)
+		}
+	}
+	select {
+	case e := <-received:
+		require.Fail(t, "got an unexpected event "+e.Content)
+	case <-time.After(testBlockInterval):
+	}
+
+	require.NoError(t, c.Close())
+}
+
 func TestClient_StreamEventsFrom(t *testing.T) {
 	s, c := newSer(t)
 	leader := s.services[0]
@@ -529,6 +854,9 @@ type ser struct {
 }
 
 func (s *ser) close() {
+	for _, svc := range s.services {
+		svc.Close()
+	}
 	s.local.CloseAll()
 }
 
@@ -546,7 +874,19 @@ func newSer(t *testing.T) (*ser, *Client) {
 
 	var err error
 	s.req, err = byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, s.roster,
-		[]string{"spawn:" + contractName, "invoke:" + contractName + "." + logCmd, "_name:" + contractName}, s.owner.Identity())
+		[]string{"spawn:" + contractName, "invoke:" + contractName + "." + logCmd,
+			"invoke:" + contractName + "." + retentionCmd, "invoke:" + contractName + "." + schemaCmd,
+			"_name:" + contractName}, s.owner.Identity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The conodes prune the event logs with their own key.
+	pruners := []string{s.owner.Identity().String()}
+	for _, si := range s.roster.List {
+		pruners = append(pruners, darc.NewIdentityEd25519(si.Public).String())
+	}
+	err = s.req.GenesisDarc.Rules.AddRule(darc.Action("invoke:"+contractName+"."+pruneCmd),
+		expression.InitOrExpr(pruners...))
 	if err != nil {
 		t.Fatal(err)
 	}
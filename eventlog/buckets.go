@@ -2,6 +2,8 @@ package eventlog
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 
 	"go.dedis.ch/cothority/v3/byzcoin"
@@ -10,16 +12,67 @@ import (
 
 var errIndexMissing = errors.New("index does not exist")
 
+// The Bloom filter over the topics of a bucket has topicFilterSize bytes and
+// every topic sets topicFilterHashes bits. With up to a hundred different
+// topics in a bucket, a search for another topic reads it less than 1% of the
+// time.
+const topicFilterSize = 256
+const topicFilterHashes = 4
+
 type bucket struct {
 	Start     int64
 	Prev      []byte
 	EventRefs [][]byte
+	// Topics is a Bloom filter over the topics of the events. It is empty
+	// for the buckets created before the filter existed.
+	Topics []byte
 }
 
 func (b bucket) isFirst() bool {
 	return len(b.Prev) == 0
 }
 
+// addEvent adds the event to the bucket and its topic to the filter. A
+// bucket that has events but no filter is left without one, as it cannot
+// know the topics of the events it already has.
+func (b *bucket) addEvent(id []byte, topic string) {
+	if len(b.Topics) == 0 && len(b.EventRefs) == 0 {
+		b.Topics = make([]byte, topicFilterSize)
+	}
+	b.EventRefs = append(b.EventRefs, id)
+	if len(b.Topics) == 0 {
+		return
+	}
+	for _, bit := range topicBits(topic, len(b.Topics)) {
+		b.Topics[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// mayHaveTopic returns false if no event of the bucket has the topic. It can
+// return true even if no event has it.
+func (b bucket) mayHaveTopic(topic string) bool {
+	if len(b.Topics) == 0 {
+		return len(b.EventRefs) > 0
+	}
+	for _, bit := range topicBits(topic, len(b.Topics)) {
+		if b.Topics[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// topicBits returns the positions of the bits of the topic in a filter of
+// size bytes.
+func topicBits(topic string, size int) []uint32 {
+	h := sha256.Sum256([]byte(topic))
+	bits := make([]uint32, topicFilterHashes)
+	for i := range bits {
+		bits[i] = binary.LittleEndian.Uint32(h[4*i:]) % uint32(size*8)
+	}
+	return bits
+}
+
 type eventLog struct {
 	Instance byzcoin.InstanceID
 	v        byzcoin.ReadOnlyStateTrie
@@ -0,0 +1,34 @@
+package eventlog
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Topics(t *testing.T) {
+	b := &bucket{}
+	require.False(t, b.mayHaveTopic("a"))
+
+	for i := 0; i < 100; i++ {
+		b.addEvent([]byte{byte(i)}, fmt.Sprintf("topic%d", i))
+	}
+	require.Equal(t, topicFilterSize, len(b.Topics))
+	for i := 0; i < 100; i++ {
+		require.True(t, b.mayHaveTopic(fmt.Sprintf("topic%d", i)))
+	}
+	misses := 0
+	for i := 0; i < 1000; i++ {
+		if !b.mayHaveTopic(fmt.Sprintf("other%d", i)) {
+			misses++
+		}
+	}
+	require.True(t, misses > 950, "too many false positives: %d", 1000-misses)
+
+	// A bucket with events but without a filter must always be read.
+	legacy := &bucket{EventRefs: [][]byte{{1}}}
+	legacy.addEvent([]byte{2}, "a")
+	require.Nil(t, legacy.Topics)
+	require.True(t, legacy.mayHaveTopic("b"))
+}
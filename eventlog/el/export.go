@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	cli "github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/eventlog"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// jsonExport is the format of the export files. The events are readable,
+// and Export holds the signed export with the proofs of the events, so that
+// the file can be verified by itself.
+type jsonExport struct {
+	ByzCoinID  string      `json:"byzcoin_id"`
+	EventLogID string      `json:"eventlog_id"`
+	From       int         `json:"from_block"`
+	To         int         `json:"to_block"`
+	Events     []jsonEvent `json:"events"`
+	Pruned     []string    `json:"pruned,omitempty"`
+	Signer     string      `json:"signer"`
+	Export     []byte      `json:"export"`
+}
+
+type jsonEvent struct {
+	Block   int    `json:"block"`
+	Key     string `json:"key"`
+	When    string `json:"when"`
+	Topic   string `json:"topic"`
+	Content string `json:"content"`
+}
+
+func newJSONExport(ex *eventlog.Export) (*jsonExport, error) {
+	buf, err := protobuf.Encode(ex)
+	if err != nil {
+		return nil, err
+	}
+	je := &jsonExport{
+		ByzCoinID:  hex.EncodeToString(ex.ID),
+		EventLogID: hex.EncodeToString(ex.Instance.Slice()),
+		From:       ex.From,
+		To:         ex.To,
+		Events:     []jsonEvent{},
+		Signer:     ex.Signer.Public.String(),
+		Export:     buf,
+	}
+	for _, e := range ex.Events {
+		je.Events = append(je.Events, jsonEvent{
+			Block:   e.Block,
+			Key:     hex.EncodeToString(e.Key),
+			When:    time.Unix(0, e.Event.When).UTC().Format(time.RFC3339Nano),
+			Topic:   e.Event.Topic,
+			Content: e.Event.Content,
+		})
+	}
+	for _, key := range ex.Pruned {
+		je.Pruned = append(je.Pruned, hex.EncodeToString(key))
+	}
+	return je, nil
+}
+
+func export(c *cli.Context) error {
+	cl, err := getClient(c, false)
+	if err != nil {
+		return err
+	}
+	e := c.String("el")
+	if e == "" {
+		return errors.New("--el is required")
+	}
+	eb, err := hex.DecodeString(e)
+	if err != nil {
+		return err
+	}
+	cl.Instance = byzcoin.NewInstanceID(eb)
+
+	ex, err := cl.Export(c.Int("from"), c.Int("to"))
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out := c.String("out"); out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch c.String("format") {
+	case "json":
+		je, err := newJSONExport(ex)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(je)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"block", "key", "when", "topic", "content"}); err != nil {
+			return err
+		}
+		for _, e := range ex.Events {
+			err := cw.Write([]string{
+				strconv.Itoa(e.Block),
+				hex.EncodeToString(e.Key),
+				time.Unix(0, e.Event.When).UTC().Format(time.RFC3339Nano),
+				e.Event.Topic,
+				e.Event.Content,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %s", c.String("format"))
+	}
+}
+
+func verify(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("please give the export file")
+	}
+	cl, err := getClient(c, false)
+	if err != nil {
+		return err
+	}
+
+	buf, err := ioutil.ReadFile(c.Args().First())
+	if err != nil {
+		return err
+	}
+	var je jsonExport
+	if err := json.Unmarshal(buf, &je); err != nil {
+		return err
+	}
+	ex := &eventlog.Export{}
+	err = protobuf.DecodeWithConstructors(je.Export, ex, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return err
+	}
+	if err := ex.Verify(cl.ByzCoin.ID, &cl.ByzCoin.Roster); err != nil {
+		return err
+	}
+
+	// The readable part of the file must be the one of the signed export.
+	expected, err := newJSONExport(ex)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(expected, &je) {
+		return errors.New("the events of the file are not the ones of the signed export")
+	}
+	log.Infof("%d events of blocks %d to %d verified, signed by %v", len(ex.Events), ex.From, ex.To, ex.Signer)
+	return nil
+}
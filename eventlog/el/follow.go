@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"go.dedis.ch/cothority/v3/eventlog"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// The events must be logged at most 30 seconds after their time, so the
+// events committed once a subscription started are at most that old. The
+// history of a search keeps track of the events newer than followWindow to
+// skip them if the subscription sends them again.
+const followWindow = time.Minute
+
+type followed struct {
+	event eventlog.Event
+	err   error
+}
+
+// subscribe starts a subscription to the new events of the event log that
+// match the topics, and returns the channel where they are sent.
+func subscribe(cl *eventlog.Client, topics *eventlog.TopicFilter) <-chan followed {
+	ch := make(chan followed, 100)
+	go func() {
+		defer close(ch)
+		cl.SubscribeEvents(&eventlog.SubscribeEvents{Topics: topics},
+			func(ev eventlog.Event, _ []byte, err error) {
+				ch <- followed{ev, err}
+			})
+	}()
+	return ch
+}
+
+// follow prints the events of the subscription, except the ones of seen
+// that were already printed, until ct events are printed or the connection
+// is closed. With ct at 0, there is no limit.
+func follow(sub <-chan followed, seen map[eventlog.Event]bool, ct int) error {
+	for f := range sub {
+		if f.err != nil {
+			return f.err
+		}
+		if seen[f.event] {
+			delete(seen, f.event)
+			continue
+		}
+		printEvent(f.event)
+		if ct != 0 {
+			ct--
+			if ct == 0 {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func printEvent(x eventlog.Event) {
+	const tsFormat = "2006-01-02 15:04:05"
+	log.Infof("%v\t%v\t%v", time.Unix(0, x.When).Format(tsFormat), x.Topic, x.Content)
+}
@@ -137,6 +137,10 @@ var cmds = cli.Commands{
 				Usage: "wait for block inclusion (default: do not wait)",
 				Value: 0,
 			},
+			cli.BoolFlag{
+				Name:  "follow, f",
+				Usage: "print the events of the topic as they are committed, until interrupted",
+			},
 		},
 		Action: doLog,
 	},
@@ -157,7 +161,11 @@ var cmds = cli.Commands{
 			},
 			cli.StringFlag{
 				Name:  "topic, t",
-				Usage: "limit results to logs with this topic",
+				Usage: "limit results to logs with this topic, or with this prefix if it ends with '*', several topics can be separated by commas",
+			},
+			cli.StringSliceFlag{
+				Name:  "not",
+				Usage: "exclude the logs with this topic, or with this prefix if it ends with '*'",
 			},
 			cli.IntFlag{
 				Name:  "count, c",
@@ -175,9 +183,124 @@ var cmds = cli.Commands{
 				Name:  "for",
 				Usage: "return events for this long after the from time (when for is given, to is ignored)",
 			},
+			cli.BoolFlag{
+				Name:  "all, a",
+				Usage: "fetch the following pages of a truncated search",
+			},
+			cli.BoolFlag{
+				Name:  "follow, f",
+				Usage: "after the search, print the new matching events as they are committed, until interrupted",
+			},
 		},
 		Action: search,
 	},
+	{
+		Name:  "export",
+		Usage: "export the events of a range of blocks with their proofs",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "bc",
+				EnvVar: "BC",
+				Usage:  "the ByzCoin config",
+			},
+			cli.StringFlag{
+				Name:   "el",
+				EnvVar: "EL",
+				Usage:  "the eventlog id, from \"el create\"",
+			},
+			cli.IntFlag{
+				Name:  "from",
+				Usage: "the index of the first block",
+			},
+			cli.IntFlag{
+				Name:  "to",
+				Usage: "the index of the last block, -1 for the latest one",
+				Value: -1,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Usage: "json for a file that can be verified, csv for a spreadsheet",
+				Value: "json",
+			},
+			cli.StringFlag{
+				Name:  "out, o",
+				Usage: "the output file (default: stdout)",
+			},
+		},
+		Action: export,
+	},
+	{
+		Name:      "verify",
+		Usage:     "verify the signature and the proofs of a json export",
+		ArgsUsage: "export.json",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "bc",
+				EnvVar: "BC",
+				Usage:  "the ByzCoin config",
+			},
+		},
+		Action: verify,
+	},
+	{
+		Name:  "retention",
+		Usage: "set the retention policy of an event log",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "sign",
+				Usage: "the ed25519 private key that will sign the transaction",
+			},
+			cli.StringFlag{
+				Name:   "bc",
+				EnvVar: "BC",
+				Usage:  "the ByzCoin config",
+			},
+			cli.StringFlag{
+				Name:   "el",
+				EnvVar: "EL",
+				Usage:  "the eventlog id, from \"el create\"",
+			},
+			cli.DurationFlag{
+				Name:  "max-age",
+				Usage: "prune the events older than this (default: no limit)",
+			},
+			cli.Int64Flag{
+				Name:  "max-count",
+				Usage: "prune the events beyond this number of latest events (default: no limit)",
+			},
+			cli.BoolFlag{
+				Name:  "prune",
+				Usage: "prune the event log now",
+			},
+		},
+		Action: retention,
+	},
+	{
+		Name:      "schema",
+		Usage:     "set the JSON schema of the content of the events of a topic",
+		ArgsUsage: "[schema.json]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "sign",
+				Usage: "the ed25519 private key that will sign the transaction",
+			},
+			cli.StringFlag{
+				Name:   "bc",
+				EnvVar: "BC",
+				Usage:  "the ByzCoin config",
+			},
+			cli.StringFlag{
+				Name:   "el",
+				EnvVar: "EL",
+				Usage:  "the eventlog id, from \"el create\"",
+			},
+			cli.StringFlag{
+				Name:  "topic, t",
+				Usage: "the topic of the events",
+			},
+		},
+		Action: schema,
+	},
 	{
 		Name:    "key",
 		Usage:   "generates a new keypair and prints the public key in the stdout",
@@ -339,10 +462,24 @@ func doLog(c *cli.Context) error {
 	content := c.String("content")
 	w := c.Int("wait")
 
+	// The events of the topic are printed while they are logged, and after
+	// until the connection is closed.
+	done := make(chan error, 1)
+	if c.Bool("follow") {
+		sub := subscribe(cl, &eventlog.TopicFilter{Topic: t})
+		go func() {
+			done <- follow(sub, nil, 0)
+		}()
+	} else {
+		close(done)
+	}
+
 	// Content is set, so one shot log.
 	if content != "" {
-		_, err := cl.LogAndWait(w, eventlog.NewEvent(t, content))
-		return err
+		if _, err := cl.LogAndWait(w, eventlog.NewEvent(t, content)); err != nil {
+			return err
+		}
+		return <-done
 	}
 
 	// Content is empty, so read from stdin.
@@ -353,7 +490,67 @@ func doLog(c *cli.Context) error {
 			return err
 		}
 	}
-	return bcadminlib.WaitPropagation(c, cl.ByzCoin)
+	if err := bcadminlib.WaitPropagation(c, cl.ByzCoin); err != nil {
+		return err
+	}
+	return <-done
+}
+
+func retention(c *cli.Context) error {
+	cl, err := getClient(c, true)
+	if err != nil {
+		return err
+	}
+	e := c.String("el")
+	if e == "" {
+		return errors.New("--el is required")
+	}
+	eb, err := hex.DecodeString(e)
+	if err != nil {
+		return err
+	}
+	cl.Instance = byzcoin.NewInstanceID(eb)
+
+	err = cl.SetRetention(eventlog.Retention{
+		MaxAge:   int64(c.Duration("max-age")),
+		MaxCount: c.Int64("max-count"),
+	})
+	if err != nil {
+		return err
+	}
+	if c.Bool("prune") {
+		return cl.Prune()
+	}
+	return nil
+}
+
+func schema(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return errors.New("please give at most one schema file")
+	}
+	cl, err := getClient(c, true)
+	if err != nil {
+		return err
+	}
+	e := c.String("el")
+	if e == "" {
+		return errors.New("--el is required")
+	}
+	eb, err := hex.DecodeString(e)
+	if err != nil {
+		return err
+	}
+	cl.Instance = byzcoin.NewInstanceID(eb)
+
+	// Without a file, the schema of the topic is removed.
+	var buf []byte
+	if c.NArg() == 1 {
+		buf, err = ioutil.ReadFile(c.Args().First())
+		if err != nil {
+			return err
+		}
+	}
+	return cl.SetSchema(c.String("topic"), buf)
 }
 
 var none = time.Unix(0, 0)
@@ -376,8 +573,21 @@ func parseTime(in string) (time.Time, error) {
 }
 
 func search(c *cli.Context) error {
-	req := &eventlog.SearchRequest{
-		Topic: c.String("topic"),
+	req := &eventlog.SearchRequest{}
+	var filters []eventlog.TopicFilter
+	if t := c.String("topic"); t != "" {
+		var or []eventlog.TopicFilter
+		for _, topic := range strings.Split(t, ",") {
+			or = append(or, topicFilter(topic))
+		}
+		filters = append(filters, eventlog.TopicFilter{Or: or})
+	}
+	for _, topic := range c.StringSlice("not") {
+		f := topicFilter(topic)
+		filters = append(filters, eventlog.TopicFilter{Not: &f})
+	}
+	if len(filters) > 0 {
+		req.Topics = &eventlog.TopicFilter{And: filters}
 	}
 
 	f := c.String("from")
@@ -419,29 +629,63 @@ func search(c *cli.Context) error {
 	}
 	cl.Instance = byzcoin.NewInstanceID(eb)
 
-	resp, err := cl.Search(req)
-	if err != nil {
-		return err
+	ct := c.Int("count")
+
+	// Subscribe before searching, so that the events committed during the
+	// search are not missed.
+	var sub <-chan followed
+	var seen map[eventlog.Event]bool
+	var since int64
+	if c.Bool("follow") {
+		if req.To != 0 {
+			return errors.New("--follow cannot be used with --to or --for")
+		}
+		since = time.Now().Add(-followWindow).UnixNano()
+		seen = make(map[eventlog.Event]bool)
+		sub = subscribe(cl, req.Topics)
 	}
 
-	ct := c.Int("count")
+	for {
+		resp, err := cl.Search(req)
+		if err != nil {
+			return err
+		}
 
-	for _, x := range resp.Events {
-		const tsFormat = "2006-01-02 15:04:05"
-		log.Infof("%v\t%v\t%v", time.Unix(0, x.When).Format(tsFormat), x.Topic, x.Content)
+		for _, x := range resp.Events {
+			printEvent(x)
+			if seen != nil && x.When >= since {
+				seen[x] = true
+			}
 
-		if ct != 0 {
-			ct--
-			if ct == 0 {
-				break
+			if ct != 0 {
+				ct--
+				if ct == 0 {
+					return nil
+				}
 			}
 		}
+
+		if !resp.Truncated {
+			break
+		}
+		if !c.Bool("all") && sub == nil {
+			return cli.NewExitError("", 1)
+		}
+		req.Cursor = resp.Cursor
 	}
+	if sub == nil {
+		return nil
+	}
+	return follow(sub, seen, ct)
+}
 
-	if resp.Truncated {
-		return cli.NewExitError("", 1)
+// topicFilter returns the filter of a topic given on the command line, the
+// topics ending with '*' are prefixes.
+func topicFilter(topic string) eventlog.TopicFilter {
+	if strings.HasSuffix(topic, "*") {
+		return eventlog.TopicFilter{Prefix: strings.TrimSuffix(topic, "*")}
 	}
-	return nil
+	return eventlog.TopicFilter{Topic: topic}
 }
 
 func login(c *cli.Context) error {
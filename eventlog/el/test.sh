@@ -39,6 +39,8 @@ testEventLog(){
 	testOK ./bcadmin -c . darc rule -rule spawn:eventlog -identity "$KEY"
 	./bcadmin debug counters bc*cfg key*cfg
 	testOK ./bcadmin -c . darc rule -rule invoke:eventlog.log -identity "$KEY"
+	testOK ./bcadmin -c . darc rule -rule invoke:eventlog.retention -identity "$KEY"
+	testOK ./bcadmin -c . darc rule -rule invoke:eventlog.schema -identity "$KEY"
 
 	runGrepSed "export EL=" "" $el create -sign "$KEY"
 	eval "$SED"
@@ -52,11 +54,39 @@ testEventLog(){
 
 	testGrep "abc" $el search -t test
 	testCountLines 13 $el search
+	testCountLines 10 $el search -t 'seq*'
+	testCountLines 11 $el search -t 'seq*,test'
+	testCountLines 12 $el search -not test
 
 	testCountLines 0 $el search -t test -from '0s ago'
 	# The first form of relative date is for MacOS, the second for Linux.
 	testCountLines 0 $el search -t test -from '1h ago' -to `date -v -1d +%Y-%m-%d || date -d yesterday +%Y-%m-%d`
 	testCountLines 1 $el search -t test -to `date -v +1d +%Y-%m-%d || date -d tomorrow +%Y-%m-%d`
+
+	testOK $el export -o export.json
+	testOK $el verify export.json
+	testGrep "abc" $el export -format csv
+	testCountLines 14 $el export -format csv
+	sed -i.bak 's/"content": "abc"/"content": "xyz"/' export.json
+	testFail $el verify export.json
+
+	testOK $el retention -max-age 24h -prune -sign "$KEY"
+	testCountLines 13 $el search
+	testOK $el retention -sign "$KEY"
+	testFail $el retention -prune -sign "$KEY"
+
+	echo '{"type": "object", "required": ["user"]}' > login.json
+	testOK $el schema -t login -sign "$KEY" login.json
+	testOK $el log -t login -c '{"user": "alice"}' -w 10 -sign "$KEY"
+	testFail $el log -t login -c 'bob' -w 10 -sign "$KEY"
+	testOK $el schema -t login -sign "$KEY"
+	testOK $el log -t login -c 'bob' -w 10 -sign "$KEY"
+	testCountLines 2 $el search -t login
+
+	testFail $el search -follow -for 1h
+	( sleep 2; $el log -t test -c 'followed' -w 10 -sign "$KEY" ) &
+	testCountLines 2 $el search -t test -follow -count 2
+	wait
 }
 
 main
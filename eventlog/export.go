@@ -0,0 +1,184 @@
+package eventlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+// This should be a const, but we want to be able to hack it from tests.
+var exportMaxBlocks = 1000
+
+// Export returns the events logged in the event log by a range of blocks,
+// with the proofs that they are in the latest state, signed by this conode.
+func (s *Service) Export(req *ExportRequest) (*Export, error) {
+	if req.ID.IsNull() {
+		return nil, errors.New("skipchain ID required")
+	}
+
+	db := s.skipchain.GetDB()
+	latest, err := db.GetLatestByID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	to := req.To
+	if to < 0 || to > latest.Index {
+		to = latest.Index
+	}
+	if req.From < 0 || req.From > to {
+		return nil, errors.New("invalid block range")
+	}
+	if to-req.From >= exportMaxBlocks {
+		return nil, fmt.Errorf("cannot export more than %d blocks at once", exportMaxBlocks)
+	}
+
+	path, err := db.GetProofFromIndex(req.ID, req.From)
+	if err != nil {
+		return nil, err
+	}
+	ex := &Export{
+		Instance: req.Instance,
+		ID:       req.ID,
+		From:     req.From,
+		To:       to,
+	}
+	for sb := path[len(path)-1]; ; {
+		if err := s.exportBlock(ex, sb); err != nil {
+			return nil, err
+		}
+		if sb.Index >= to || len(sb.ForwardLink) == 0 {
+			break
+		}
+		next := sb.ForwardLink[0].To
+		if sb = db.GetByID(next); sb == nil {
+			return nil, fmt.Errorf("block %x not found", next)
+		}
+	}
+
+	ex.Signer = s.ServerIdentity()
+	msg, err := ex.hash()
+	if err != nil {
+		return nil, err
+	}
+	ex.Signature, err = schnorr.Sign(cothority.Suite, s.ServerIdentity().GetPrivate(), msg)
+	if err != nil {
+		return nil, err
+	}
+	return ex, nil
+}
+
+// exportBlock adds the events of the event log in the block to the export.
+func (s *Service) exportBlock(ex *Export, sb *skipchain.SkipBlock) error {
+	var proofErr error
+	err := forEachEvent(sb, func(instr byzcoin.Instruction, ev *Event, err error) {
+		if err != nil || proofErr != nil || !instr.InstanceID.Equal(ex.Instance) {
+			return
+		}
+		key := instr.DeriveID("").Slice()
+		resp, err := s.omni.GetProof(&byzcoin.GetProof{
+			Version: byzcoin.CurrentVersion,
+			Key:     key,
+			ID:      ex.ID,
+		})
+		if err != nil {
+			proofErr = err
+			return
+		}
+		if !resp.Proof.InclusionProof.Match(key) {
+			ex.Pruned = append(ex.Pruned, key)
+			return
+		}
+		ex.Events = append(ex.Events, ExportedEvent{
+			Event: *ev,
+			Block: sb.Index,
+			Key:   key,
+			Proof: resp.Proof,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return proofErr
+}
+
+// Verify checks that the export is signed by a conode of the roster, and that
+// all its events are in the state of the chain id. The roster must come from
+// a source the caller trusts, like the configuration of the ledger, because
+// the signer in the export only tells which of its conodes signed it.
+func (ex *Export) Verify(id skipchain.SkipBlockID, roster *onet.Roster) error {
+	if !ex.ID.Equal(id) {
+		return errors.New("the export is not from this chain")
+	}
+	if ex.Signer == nil {
+		return errors.New("no signer")
+	}
+	var public kyber.Point
+	if roster != nil {
+		for _, si := range roster.List {
+			if si.Public.Equal(ex.Signer.Public) {
+				public = si.Public
+			}
+		}
+	}
+	if public == nil {
+		return fmt.Errorf("the signer %v is not in the roster", ex.Signer)
+	}
+	msg, err := ex.hash()
+	if err != nil {
+		return err
+	}
+	if err := schnorr.Verify(cothority.Suite, public, msg, ex.Signature); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	for _, e := range ex.Events {
+		if e.Block < ex.From || e.Block > ex.To {
+			return fmt.Errorf("event %x is not in the block range", e.Key)
+		}
+		if err := e.Proof.Verify(id); err != nil {
+			return fmt.Errorf("invalid proof of event %x: %v", e.Key, err)
+		}
+		key, buf, cid, _, err := e.Proof.KeyValue()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(key, e.Key) || cid != contractName {
+			return fmt.Errorf("the proof of event %x is for another key", e.Key)
+		}
+		var ev Event
+		if err := protobuf.Decode(buf, &ev); err != nil {
+			return err
+		}
+		if ev != e.Event {
+			return fmt.Errorf("event %x is not the one of the proof", e.Key)
+		}
+	}
+	return nil
+}
+
+// hash returns the digest of the export without the signature.
+func (ex *Export) hash() ([]byte, error) {
+	buf, err := protobuf.Encode(&Export{
+		Instance: ex.Instance,
+		ID:       ex.ID,
+		From:     ex.From,
+		To:       ex.To,
+		Events:   ex.Events,
+		Pruned:   ex.Pruned,
+		Signer:   ex.Signer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(buf)
+	return h[:], nil
+}
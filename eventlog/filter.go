@@ -0,0 +1,85 @@
+package eventlog
+
+import (
+	"errors"
+	"strings"
+)
+
+// topicFilterMaxSize is the maximum number of terms of a TopicFilter, so
+// that a search cannot keep the conode busy evaluating a huge expression.
+const topicFilterMaxSize = 64
+
+// matches returns true if the topic matches the filter.
+func (f *TopicFilter) matches(topic string) bool {
+	switch {
+	case f.Topic != "":
+		return topic == f.Topic
+	case f.Prefix != "":
+		return strings.HasPrefix(topic, f.Prefix)
+	case len(f.And) > 0:
+		for i := range f.And {
+			if !f.And[i].matches(topic) {
+				return false
+			}
+		}
+		return true
+	case len(f.Or) > 0:
+		for i := range f.Or {
+			if f.Or[i].matches(topic) {
+				return true
+			}
+		}
+		return false
+	case f.Not != nil:
+		return !f.Not.matches(topic)
+	}
+	return true
+}
+
+// mayMatch returns false if no event of the bucket can match the filter,
+// using the Bloom filter of the bucket. Only the exact topics can be looked up
+// in it, so prefixes and negations may always match.
+func (f *TopicFilter) mayMatch(b *bucket) bool {
+	switch {
+	case f.Topic != "":
+		return b.mayHaveTopic(f.Topic)
+	case len(f.And) > 0:
+		for i := range f.And {
+			if !f.And[i].mayMatch(b) {
+				return false
+			}
+		}
+		return true
+	case len(f.Or) > 0:
+		for i := range f.Or {
+			if f.Or[i].mayMatch(b) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// check returns an error if the filter has more than topicFilterMaxSize
+// terms.
+func (f *TopicFilter) check() error {
+	if f.size() > topicFilterMaxSize {
+		return errors.New("the topic filter is too big")
+	}
+	return nil
+}
+
+func (f *TopicFilter) size() int {
+	n := 1
+	for i := range f.And {
+		n += f.And[i].size()
+	}
+	for i := range f.Or {
+		n += f.Or[i].size()
+	}
+	if f.Not != nil {
+		n += f.Not.size()
+	}
+	return n
+}
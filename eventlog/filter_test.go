@@ -0,0 +1,34 @@
+package eventlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicFilter_Matches(t *testing.T) {
+	f := &TopicFilter{And: []TopicFilter{
+		{Prefix: "app."},
+		{Not: &TopicFilter{Or: []TopicFilter{{Topic: "app.debug"}, {Topic: "app.trace"}}}},
+	}}
+	require.True(t, f.matches("app.web"))
+	require.False(t, f.matches("app.debug"))
+	require.False(t, f.matches("app.trace"))
+	require.False(t, f.matches("sys"))
+	require.True(t, (&TopicFilter{}).matches("anything"))
+
+	b := &bucket{}
+	b.addEvent([]byte{1}, "app.web")
+	require.True(t, f.mayMatch(b))
+	or := &TopicFilter{Or: []TopicFilter{{Topic: "sys"}, {Topic: "auth"}}}
+	require.False(t, or.mayMatch(b))
+	b.addEvent([]byte{2}, "auth")
+	require.True(t, or.mayMatch(b))
+
+	require.NoError(t, f.check())
+	big := &TopicFilter{}
+	for i := 0; i < topicFilterMaxSize; i++ {
+		big.Or = append(big.Or, TopicFilter{Topic: "a"})
+	}
+	require.Error(t, big.check())
+}
@@ -12,6 +12,8 @@ func init() {
 	network.RegisterMessages(
 		&Event{},
 		&SearchRequest{}, &SearchResponse{},
+		&SubscribeEvents{}, &SubscribeEventsReply{},
+		&ExportRequest{}, &Export{},
 	)
 }
 
@@ -30,6 +32,8 @@ func NewEvent(topic, content string) Event {
 // type :byzcoin.InstanceID:bytes
 //
 // package eventlog;
+// import "byzcoin.proto";
+// import "onet.proto";
 //
 // option java_package = "ch.epfl.dedis.lib.proto";
 // option java_outer_classname = "EventLogProto";
@@ -51,15 +55,110 @@ type SearchRequest struct {
 	From int64
 	// Return events where When is <= To.
 	To int64
+	// Return events where Event.Topic starts with TopicPrefix, if
+	// TopicPrefix != "". For instance "app." matches "app.web" and "app.db".
+	// optional
+	TopicPrefix string
+	// Continue a truncated search from the Cursor of its response. The other
+	// fields must be the same as the ones of the first request, To is taken
+	// from the cursor.
+	// optional
+	Cursor []byte
+	// Return events whose topic matches Topics, if it is set.
+	// optional
+	Topics *TopicFilter
+}
+
+// TopicFilter is a boolean expression over the topic of an event, so that a
+// single search can look for several topics. Exactly one of its fields should
+// be set, an empty filter matches all the topics. For instance,
+// {And: [{Prefix: "app."}, {Not: {Topic: "app.debug"}}]} matches all the
+// topics starting with "app." but "app.debug".
+type TopicFilter struct {
+	// optional
+	Topic string
+	// optional
+	Prefix string
+	And    []TopicFilter
+	Or     []TopicFilter
+	// optional
+	Not *TopicFilter
 }
 
 // SearchResponse is the reply to LogRequest.
 type SearchResponse struct {
 	Events []Event
 	// Events does not contain all the results. The caller should formulate
-	// a new SearchRequest to continue searching, for instance by setting
-	// From to the time of the last received event.
+	// a new SearchRequest to continue searching, by setting Cursor.
 	Truncated bool
+	// Opaque position after the last event of Events, only set if the
+	// response is truncated.
+	// optional
+	Cursor []byte
+}
+
+// SubscribeEvents asks the conode to stream the events of the event log as
+// their blocks are committed. Topic and TopicPrefix filter the events like in
+// SearchRequest.
+type SubscribeEvents struct {
+	Instance byzcoin.InstanceID
+	ID       skipchain.SkipBlockID
+	// optional
+	Topic string
+	// optional
+	TopicPrefix string
+	// optional
+	Topics *TopicFilter
+}
+
+// SubscribeEventsReply holds the matching events of a new block.
+type SubscribeEventsReply struct {
+	BlockID skipchain.SkipBlockID
+	Events  []Event
+}
+
+// ExportRequest asks the conode for a signed export of the events logged in
+// the event log by the blocks with an index between From and To, inclusive.
+// To < 0 means until the latest block.
+type ExportRequest struct {
+	Instance byzcoin.InstanceID
+	ID       skipchain.SkipBlockID
+	From     int
+	To       int
+}
+
+// Export holds the events logged in a range of blocks with the proofs that
+// they are in the state of ByzCoin, signed by the conode that made it. It can
+// be verified by anybody knowing the ID of the chain.
+type Export struct {
+	Instance byzcoin.InstanceID
+	ID       skipchain.SkipBlockID
+	From     int
+	To       int
+	Events   []ExportedEvent
+	// Pruned are the keys of the events of the range that were removed by
+	// the retention policy, so they have no proof.
+	Pruned    [][]byte
+	Signer    *network.ServerIdentity
+	Signature []byte
+}
+
+// ExportedEvent is an event of an export, with the index of the block that
+// logged it, its key and the proof of the key in the state of ByzCoin.
+type ExportedEvent struct {
+	Event Event
+	Block int
+	Key   []byte
+	Proof byzcoin.Proof
+}
+
+// Retention is the retention policy of an event log, set with the
+// "retention" command. Events older than MaxAge nanoseconds, or beyond the
+// MaxCount latest events, are pruned. Zero means no limit. Events are
+// pruned by whole buckets, so a few more events than the limits can be kept.
+type Retention struct {
+	MaxAge   int64
+	MaxCount int64
 }
 
 // Event is sent to create an event log. When should be set using the UnixNano() method
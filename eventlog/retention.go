@@ -0,0 +1,383 @@
+package eventlog
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+const retentionCmd = "retention"
+const pruneCmd = "prune"
+
+// This should be a const, but we want to be able to hack it from tests.
+var pruneInterval = time.Minute
+
+// pruneMaxBuckets is the maximum number of buckets removed by one prune
+// instruction, so that pruning a big event log doesn't create a huge block.
+// The pruning job removes the rest in the following rounds.
+var pruneMaxBuckets = 100
+
+// retentionRegistry lists the event logs of a chain that have a retention
+// policy, so that the pruning job doesn't need to walk the whole trie.
+type retentionRegistry struct {
+	Instances [][]byte
+}
+
+// registryID is the key of the retention registry in the trie.
+func registryID() []byte {
+	h := sha256.Sum256([]byte("eventlog-retention-registry"))
+	return h[:]
+}
+
+// retentionID is the key of the retention policy of the event log in the
+// trie.
+func retentionID(iid byzcoin.InstanceID) []byte {
+	h := sha256.New()
+	h.Write([]byte("eventlog-retention"))
+	h.Write(iid.Slice())
+	return h.Sum(nil)
+}
+
+func (r Retention) isNull() bool {
+	return r.MaxAge <= 0 && r.MaxCount <= 0
+}
+
+// getValue returns the value stored at key, or nil if the key is not set.
+func getValue(v byzcoin.ReadOnlyStateTrie, key []byte) ([]byte, error) {
+	p, err := v.GetProof(key)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Match(key) {
+		return nil, nil
+	}
+	v0, _, _, _, err := v.GetValues(key)
+	return v0, err
+}
+
+func (e eventLog) getRetention() (*Retention, error) {
+	v0, err := getValue(e.v, retentionID(e.Instance))
+	if err != nil {
+		return nil, err
+	}
+	if v0 == nil {
+		return nil, nil
+	}
+	r := &Retention{}
+	if err := protobuf.Decode(v0, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func getRegistry(v byzcoin.ReadOnlyStateTrie) (*retentionRegistry, error) {
+	v0, err := getValue(v, registryID())
+	if err != nil {
+		return nil, err
+	}
+	reg := &retentionRegistry{}
+	if v0 == nil {
+		return reg, nil
+	}
+	if err := protobuf.Decode(v0, reg); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// setRetention returns the state changes storing the retention policy of
+// the event log and updating the registry. A null policy removes it.
+func (e eventLog) setRetention(r Retention, darcID darc.ID) (byzcoin.StateChanges, error) {
+	var sc byzcoin.StateChanges
+	old, err := e.getRetention()
+	if err != nil {
+		return nil, err
+	}
+	rid := retentionID(e.Instance)
+	switch {
+	case r.isNull() && old == nil:
+		return nil, nil
+	case r.isNull():
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, byzcoin.NewInstanceID(rid), contractName, nil, darcID))
+	default:
+		buf, err := protobuf.Encode(&r)
+		if err != nil {
+			return nil, err
+		}
+		action := byzcoin.Update
+		if old == nil {
+			action = byzcoin.Create
+		}
+		sc = append(sc, byzcoin.NewStateChange(action, byzcoin.NewInstanceID(rid), contractName, buf, darcID))
+	}
+
+	reg, err := getRegistry(e.v)
+	if err != nil {
+		return nil, err
+	}
+	action := byzcoin.Update
+	if len(reg.Instances) == 0 {
+		action = byzcoin.Create
+	}
+	var instances [][]byte
+	for _, iid := range reg.Instances {
+		if !byzcoin.NewInstanceID(iid).Equal(e.Instance) {
+			instances = append(instances, iid)
+		}
+	}
+	if !r.isNull() {
+		instances = append(instances, e.Instance.Slice())
+	}
+	reg.Instances = instances
+	if len(reg.Instances) == 0 {
+		return append(sc, byzcoin.NewStateChange(byzcoin.Remove, byzcoin.NewInstanceID(registryID()), contractName, nil, darcID)), nil
+	}
+	buf, err := protobuf.Encode(reg)
+	if err != nil {
+		return nil, err
+	}
+	return append(sc, byzcoin.NewStateChange(action, byzcoin.NewInstanceID(registryID()), contractName, buf, darcID)), nil
+}
+
+// prune returns the state changes removing the oldest buckets of the event
+// log, and their events, that are out of the retention policy at time now.
+// Only whole buckets are removed: all the events of a bucket are older than
+// the start of the next one. The latest bucket is never removed.
+func (e eventLog) prune(r Retention, now int64, darcID darc.ID) (byzcoin.StateChanges, error) {
+	id, b, err := e.getLatestBucket()
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	// Walk the whole chain, from the latest to the first bucket.
+	var buckets []*bucket
+	var bids [][]byte
+	for {
+		buckets = append(buckets, b)
+		bids = append(bids, id)
+		if b.isFirst() {
+			break
+		}
+		id = b.Prev
+		b, err = e.getBucketByID(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Find the latest bucket that can be removed: either the next bucket
+	// started before the maximum age, or the later buckets hold enough
+	// events. Then all the buckets before it can be removed too.
+	first := len(buckets)
+	var count int64
+	for i := 1; i < len(buckets); i++ {
+		count += int64(len(buckets[i-1].EventRefs))
+		if r.MaxAge > 0 && buckets[i-1].Start <= now-r.MaxAge ||
+			r.MaxCount > 0 && count >= r.MaxCount {
+			first = i
+			break
+		}
+	}
+	if first == len(buckets) {
+		return nil, nil
+	}
+	if len(buckets)-first > pruneMaxBuckets {
+		first = len(buckets) - pruneMaxBuckets
+	}
+
+	var sc byzcoin.StateChanges
+	for i := first; i < len(buckets); i++ {
+		for _, ref := range buckets[i].EventRefs {
+			sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, byzcoin.NewInstanceID(ref), contractName, nil, darcID))
+		}
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, byzcoin.NewInstanceID(bids[i]), contractName, nil, darcID))
+	}
+
+	// The oldest kept bucket becomes the catch-all bucket for the events
+	// that come in late.
+	kept := buckets[first-1]
+	kept.Start = 0
+	kept.Prev = nil
+	buf, err := protobuf.Encode(kept)
+	if err != nil {
+		return nil, err
+	}
+	return append(sc, byzcoin.NewStateChange(byzcoin.Update, byzcoin.NewInstanceID(bids[first-1]), contractName, buf, darcID)), nil
+}
+
+// pruneInstruction returns the unsigned instruction pruning the event log at
+// time now.
+func pruneInstruction(iid byzcoin.InstanceID, now int64) byzcoin.Instruction {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(now))
+	return byzcoin.Instruction{
+		InstanceID: iid,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contractName,
+			Command:    pruneCmd,
+			Args:       []byzcoin.Argument{{Name: "now", Value: buf}},
+		},
+	}
+}
+
+// pruneTime returns the time argument of a prune instruction. Like the time
+// of an event, it must not be in the future.
+func pruneTime(inst byzcoin.Instruction) (int64, error) {
+	buf := inst.Invoke.Args.Search("now")
+	if len(buf) != 8 {
+		return 0, errors.New("expected a named argument of \"now\"")
+	}
+	now := int64(binary.LittleEndian.Uint64(buf))
+	if time.Unix(0, now).After(time.Now().Add(5 * time.Second)) {
+		return 0, errors.New("prune time is too far in the future")
+	}
+	return now, nil
+}
+
+// canPrune returns true if the darc of the event log allows the identity to
+// prune it.
+func canPrune(v byzcoin.ReadOnlyStateTrie, iid byzcoin.InstanceID, id darc.Identity) bool {
+	_, _, _, darcID, err := v.GetValues(iid.Slice())
+	if err != nil {
+		return false
+	}
+	d, err := v.LoadDarc(darcID)
+	if err != nil {
+		return false
+	}
+	expr := d.Rules.Get(darc.Action("invoke:" + contractName + "." + pruneCmd))
+	if expr == nil {
+		return false
+	}
+	getDarc := func(str string, latest bool) *darc.Darc {
+		if !strings.HasPrefix(str, "darc:") {
+			return nil
+		}
+		id, err := hex.DecodeString(str[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := v.LoadDarc(id)
+		if err != nil {
+			return nil
+		}
+		return d
+	}
+	return darc.EvalExpr(expr, getDarc, id.String()) == nil
+}
+
+// pruneLoop prunes the event logs of the chains led by this node every
+// pruneInterval, until the service is closed.
+func (s *Service) pruneLoop() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.pruneAll()
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+func (s *Service) pruneAll() {
+	reply, err := s.omni.GetAllByzCoinIDs(&byzcoin.GetAllByzCoinIDsRequest{})
+	if err != nil {
+		log.Error(s.ServerIdentity(), "couldn't get the chains:", err)
+		return
+	}
+	for _, id := range reply.IDs {
+		if err := s.pruneChain(id); err != nil {
+			log.Error(s.ServerIdentity(), "couldn't prune the event logs of", id, err)
+		}
+	}
+}
+
+// pruneChain sends a transaction for each event log of the chain that is out
+// of its retention policy and allows this node to prune it. Only the leader
+// sends them. A rejected transaction only fails its own event log, but as
+// the following ones then have a wrong counter, they wait for the next
+// round.
+func (s *Service) pruneChain(id skipchain.SkipBlockID) error {
+	latest, err := s.skipchain.GetDB().GetLatestByID(id)
+	if err != nil {
+		return err
+	}
+	if len(latest.Roster.List) == 0 || !latest.Roster.List[0].Equal(s.ServerIdentity()) {
+		return nil
+	}
+
+	v, err := s.omni.GetReadOnlyStateTrie(id)
+	if err != nil {
+		return err
+	}
+	reg, err := getRegistry(v)
+	if err != nil {
+		return err
+	}
+
+	// The owner of the event log allows the conodes to prune it by giving
+	// them the "invoke:eventlog.prune" permission.
+	signer := darc.NewSignerEd25519(s.ServerIdentity().Public, s.ServerIdentity().GetPrivate())
+
+	now := time.Now().UnixNano()
+	var instrs []byzcoin.Instruction
+	for _, iid := range reg.Instances {
+		el := eventLog{Instance: byzcoin.NewInstanceID(iid), v: v}
+		if !canPrune(v, el.Instance, signer.Identity()) {
+			log.Lvlf3("%v is not allowed to prune %x", s.ServerIdentity(), iid)
+			continue
+		}
+		r, err := el.getRetention()
+		if err != nil || r == nil {
+			return fmt.Errorf("retention of %x not found: %v", iid, err)
+		}
+		sc, err := el.prune(*r, now, nil)
+		if err != nil {
+			return err
+		}
+		if len(sc) > 0 {
+			instrs = append(instrs, pruneInstruction(el.Instance, now))
+		}
+	}
+	if len(instrs) == 0 {
+		return nil
+	}
+
+	ctrs, err := s.omni.GetSignerCounters(&byzcoin.GetSignerCounters{
+		SignerIDs:   []string{signer.Identity().String()},
+		SkipchainID: id,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Lvlf2("%v prunes %d event logs", s.ServerIdentity(), len(instrs))
+	for i, instr := range instrs {
+		instr.SignerCounter = []uint64{ctrs.Counters[0] + uint64(i) + 1}
+		tx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion, instr)
+		if err := tx.FillSignersAndSignWith(signer); err != nil {
+			return err
+		}
+		_, err = s.omni.AddTransaction(&byzcoin.AddTxRequest{
+			Version:     byzcoin.CurrentVersion,
+			SkipchainID: id,
+			Transaction: tx,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't prune %x: %v", instr.InstanceID.Slice(), err)
+		}
+	}
+	return nil
+}
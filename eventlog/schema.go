@@ -0,0 +1,221 @@
+package eventlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"unicode/utf8"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+const schemaCmd = "schema"
+
+// jsonSchema is the subset of JSON schema used to validate the content of the
+// events of a topic. The schemas using other keywords are refused, so that a
+// schema never accepts more than its author expects.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema"`
+	Title                string                 `json:"title"`
+	Description          string                 `json:"description"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+}
+
+// parseSchema decodes the JSON schema and returns an error if it uses
+// keywords or types that are not supported.
+func parseSchema(buf []byte) (*jsonSchema, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+	s := &jsonSchema{}
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	if err := s.check(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return s, nil
+}
+
+func (s *jsonSchema) check() error {
+	switch s.Type {
+	case "", "object", "array", "string", "number", "integer", "boolean", "null":
+	default:
+		return fmt.Errorf("unknown type %s", s.Type)
+	}
+	for _, p := range s.Properties {
+		if p == nil {
+			return fmt.Errorf("empty property")
+		}
+		if err := p.check(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check()
+	}
+	return nil
+}
+
+// validate returns an error if the content of an event is not a JSON document
+// matching the schema.
+func (s *jsonSchema) validate(content string) error {
+	var v interface{}
+	if err := json.Unmarshal([]byte(content), &v); err != nil {
+		return fmt.Errorf("content is not JSON: %v", err)
+	}
+	return s.validateValue("content", v)
+}
+
+func (s *jsonSchema) validateValue(path string, v interface{}) error {
+	if !s.hasType(v) {
+		return fmt.Errorf("%s is not of type %s", path, s.Type)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is not one of the allowed values", path)
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s misses the property %s", path, name)
+			}
+		}
+		for name, value := range v {
+			p, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s has the unknown property %s", path, name)
+				}
+				continue
+			}
+			if err := p.validateValue(path+"."+name, value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validateValue(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		l := utf8.RuneCountInString(v)
+		if s.MinLength != nil && l < *s.MinLength || s.MaxLength != nil && l > *s.MaxLength {
+			return fmt.Errorf("%s has a wrong length", path)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum || s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s is out of range", path)
+		}
+	}
+	return nil
+}
+
+func (s *jsonSchema) hasType(v interface{}) bool {
+	switch s.Type {
+	case "":
+		return true
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+// schemaID is the key of the schema of the topic of the event log in the
+// trie.
+func schemaID(iid byzcoin.InstanceID, topic string) []byte {
+	h := sha256.New()
+	h.Write([]byte("eventlog-schema"))
+	h.Write(iid.Slice())
+	h.Write([]byte(topic))
+	return h.Sum(nil)
+}
+
+// setSchema returns the state changes storing the schema of the topic, an
+// empty schema removes it.
+func (e eventLog) setSchema(topic string, schema []byte, darcID darc.ID) (byzcoin.StateChanges, error) {
+	key := schemaID(e.Instance, topic)
+	old, err := getValue(e.v, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema) == 0 {
+		if old == nil {
+			return nil, nil
+		}
+		return byzcoin.StateChanges{
+			byzcoin.NewStateChange(byzcoin.Remove, byzcoin.NewInstanceID(key), contractName, nil, darcID),
+		}, nil
+	}
+
+	if _, err := parseSchema(schema); err != nil {
+		return nil, err
+	}
+	action := byzcoin.Update
+	if old == nil {
+		action = byzcoin.Create
+	}
+	return byzcoin.StateChanges{
+		byzcoin.NewStateChange(action, byzcoin.NewInstanceID(key), contractName, schema, darcID),
+	}, nil
+}
+
+// checkSchema returns an error if the topic of the event has a schema and
+// the content of the event doesn't validate.
+func (e eventLog) checkSchema(ev *Event) error {
+	buf, err := getValue(e.v, schemaID(e.Instance, ev.Topic))
+	if err != nil || buf == nil {
+		return err
+	}
+	s, err := parseSchema(buf)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(ev.Content); err != nil {
+		return fmt.Errorf("event of topic %s: %v", ev.Topic, err)
+	}
+	return nil
+}
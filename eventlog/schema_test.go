@@ -0,0 +1,40 @@
+package eventlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	_, err := parseSchema([]byte(`{"type": "object", "oneOf": []}`))
+	require.Error(t, err)
+	_, err = parseSchema([]byte(`{"type": "date"}`))
+	require.Error(t, err)
+	_, err = parseSchema([]byte(`not json`))
+	require.Error(t, err)
+
+	s, err := parseSchema([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"required": ["level", "count"],
+		"additionalProperties": false,
+		"properties": {
+			"level": {"enum": ["info", "error"]},
+			"count": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"type": "string", "maxLength": 3}}
+		}
+	}`))
+	require.NoError(t, err)
+
+	require.NoError(t, s.validate(`{"level": "info", "count": 2}`))
+	require.NoError(t, s.validate(`{"level": "error", "count": 0, "tags": ["a", "bc"]}`))
+	require.Error(t, s.validate(`not json`))
+	require.Error(t, s.validate(`["level"]`))
+	require.Error(t, s.validate(`{"level": "info"}`))
+	require.Error(t, s.validate(`{"level": "debug", "count": 2}`))
+	require.Error(t, s.validate(`{"level": "info", "count": 1.5}`))
+	require.Error(t, s.validate(`{"level": "info", "count": -1}`))
+	require.Error(t, s.validate(`{"level": "info", "count": 2, "tags": ["abcd"]}`))
+	require.Error(t, s.validate(`{"level": "info", "count": 2, "other": true}`))
+}
@@ -1,11 +1,17 @@
 package eventlog
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/protobuf"
@@ -42,7 +48,24 @@ func init() {
 type Service struct {
 	*onet.ServiceProcessor
 	omni         *byzcoin.Service
+	skipchain    *skipchain.Service
 	bucketMaxAge time.Duration
+	// closing stops the pruning of the event logs once the service is
+	// closed.
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// Close stops the pruning of the event logs.
+func (s *Service) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
 }
 
 const defaultBlockInterval = 5 * time.Second
@@ -56,6 +79,22 @@ func (s *Service) Search(req *SearchRequest) (*SearchResponse, error) {
 		return nil, errors.New("skipchain ID required")
 	}
 
+	if req.Topics != nil {
+		if err := req.Topics.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	var cur *searchCursor
+	if len(req.Cursor) > 0 {
+		cur = &searchCursor{}
+		if err := protobuf.Decode(req.Cursor, cur); err != nil {
+			return nil, fmt.Errorf("invalid cursor: %v", err)
+		}
+		// Keep the upper bound of the first page, so that events logged
+		// in the meantime don't shift the pages.
+		req.To = cur.To
+	}
 	if req.To == 0 {
 		req.To = time.Now().UnixNano()
 	}
@@ -113,28 +152,63 @@ func (s *Service) Search(req *SearchRequest) (*SearchResponse, error) {
 		}
 	}
 
+	// Skip the buckets that were already searched by the previous pages.
+	// Buckets are never split and events are only appended to them, so the
+	// position of an event in its bucket never changes.
+	first, firstRef := len(buckets)-1, 0
+	if cur != nil {
+		first = -1
+		for i := range bids {
+			if bytes.Equal(bids[i], cur.Bucket) {
+				first = i
+				break
+			}
+		}
+		if first < 0 || cur.Event < 0 || cur.Event > len(buckets[first].EventRefs) {
+			return nil, errors.New("invalid cursor: bucket not in the search range")
+		}
+		firstRef = cur.Event
+	}
+
 	reply := &SearchResponse{}
 
 	// Process the time buckets from earliest to latest so that
 	// if we truncate, it is the latest events that are not returned,
-	// so that they can set req.From = resp.Events[len(resp.Events)-1].When.
+	// so that they can be fetched with the cursor of the reply.
 filter:
-	for i := len(buckets) - 1; i >= 0; i-- {
+	for i := first; i >= 0; i-- {
 		b := buckets[i]
-		for _, e := range b.EventRefs {
+		if req.Topic != "" && !b.mayHaveTopic(req.Topic) ||
+			req.Topics != nil && !req.Topics.mayMatch(b) {
+			// The filter of the bucket tells that no event has
+			// the topic, so there is no need to read them.
+			continue
+		}
+		refs := b.EventRefs
+		if i == first {
+			refs = refs[firstRef:]
+		}
+		for j, e := range refs {
 			ev, err := getEventByID(v, e)
 			if err != nil {
 				log.Errorf("bucket %x points to event %x, but the event was not found: %v", bids[i], e, err)
 				return nil, err
 			}
 
-			if req.From <= ev.When && ev.When < req.To {
-				if req.Topic == "" || req.Topic == ev.Topic {
-					reply.Events = append(reply.Events, *ev)
-					if len(reply.Events) >= searchMax {
-						reply.Truncated = true
-						break filter
+			if req.matches(ev) {
+				reply.Events = append(reply.Events, *ev)
+				if len(reply.Events) >= searchMax {
+					reply.Truncated = true
+					next := len(b.EventRefs) - len(refs) + j + 1
+					reply.Cursor, err = protobuf.Encode(&searchCursor{
+						To:     req.To,
+						Bucket: bids[i],
+						Event:  next,
+					})
+					if err != nil {
+						return nil, err
 					}
+					break filter
 				}
 			}
 		}
@@ -143,6 +217,93 @@ filter:
 	return reply, nil
 }
 
+// searchCursor is the position of a truncated search, sent to the client as
+// an opaque SearchResponse.Cursor.
+type searchCursor struct {
+	// To is the upper bound of the first page.
+	To int64
+	// Bucket is the ID of the bucket holding the next event.
+	Bucket []byte
+	// Event is the index of the next event in Bucket.EventRefs.
+	Event int
+}
+
+// matches returns true if the event is in the time range and has the topic
+// of the request.
+func (req *SearchRequest) matches(ev *Event) bool {
+	if ev.When < req.From || ev.When >= req.To {
+		return false
+	}
+	return matchesTopic(ev, req.Topic, req.TopicPrefix, req.Topics)
+}
+
+// matchesTopic returns true if the event has the topic, if it is set, starts
+// with the prefix and matches the filter, if it is set.
+func matchesTopic(ev *Event, topic, prefix string, f *TopicFilter) bool {
+	if topic != "" && topic != ev.Topic {
+		return false
+	}
+	if f != nil && !f.matches(ev.Topic) {
+		return false
+	}
+	return strings.HasPrefix(ev.Topic, prefix)
+}
+
+// SubscribeEvents streams the events of the event log that match the topic
+// filters, block by block, until the client closes the connection.
+func (s *Service) SubscribeEvents(req *SubscribeEvents) (chan *SubscribeEventsReply, chan bool, error) {
+	if req.ID.IsNull() {
+		return nil, nil, errors.New("skipchain ID required")
+	}
+	if req.Topics != nil {
+		if err := req.Topics.check(); err != nil {
+			return nil, nil, err
+		}
+	}
+	blocks, stopBlocks, err := s.omni.StreamTransactions(&byzcoin.StreamingRequest{ID: req.ID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outChan := make(chan *SubscribeEventsReply)
+	stopChan := make(chan bool)
+	go func() {
+		defer close(stopBlocks)
+		for {
+			select {
+			case <-stopChan:
+				return
+			case resp, ok := <-blocks:
+				if !ok {
+					// The byzcoin service is closing, so we force the
+					// streaming connection to stop as well.
+					close(outChan)
+					return
+				}
+				reply := &SubscribeEventsReply{BlockID: resp.Block.Hash}
+				err := forEachEvent(resp.Block, func(instr byzcoin.Instruction, ev *Event, err error) {
+					if err == nil && instr.InstanceID.Equal(req.Instance) && matchesTopic(ev, req.Topic, req.TopicPrefix, req.Topics) {
+						reply.Events = append(reply.Events, *ev)
+					}
+				})
+				if err != nil {
+					log.Error(s.ServerIdentity(), "couldn't read the events of block", resp.Block.Hash, err)
+					continue
+				}
+				if len(reply.Events) == 0 {
+					continue
+				}
+				select {
+				case outChan <- reply:
+				case <-stopChan:
+					return
+				}
+			}
+		}
+	}()
+	return outChan, stopChan, nil
+}
+
 func decodeAndCheckEvent(coll byzcoin.ReadOnlyStateTrie, eventBuf []byte) (*Event, error) {
 	// Check the timestamp of the event: it should never be in the future,
 	// and it should not be more than 30 seconds in the past. (Why 30 sec
@@ -179,7 +340,42 @@ func (c *contract) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instructio
 	if cid != contractName {
 		return nil, nil, fmt.Errorf("expected contract ID to be \"%s\" but got \"%s\"", contractName, cid)
 	}
-	if inst.Invoke.Command != logCmd {
+	el := &eventLog{Instance: inst.InstanceID, v: rst}
+	switch inst.Invoke.Command {
+	case logCmd:
+	case retentionCmd:
+		var r Retention
+		if err := protobuf.Decode(inst.Invoke.Args.Search("retention"), &r); err != nil {
+			return nil, nil, fmt.Errorf("decoding retention: %v", err)
+		}
+		// Make sure it's an event log and not the retention data.
+		if _, _, err := el.getLatestBucket(); err != nil {
+			return nil, nil, err
+		}
+		sc, err = el.setRetention(r, darcID)
+		return
+	case schemaCmd:
+		if _, _, err := el.getLatestBucket(); err != nil {
+			return nil, nil, err
+		}
+		topic := string(inst.Invoke.Args.Search("topic"))
+		sc, err = el.setSchema(topic, inst.Invoke.Args.Search("schema"), darcID)
+		return
+	case pruneCmd:
+		now, err := pruneTime(inst)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, err := el.getRetention()
+		if err != nil {
+			return nil, nil, err
+		}
+		if r == nil {
+			return nil, nil, errors.New("this event log has no retention policy")
+		}
+		sc, err = el.prune(*r, now, darcID)
+		return sc, cout, err
+	default:
 		return nil, nil, fmt.Errorf("invalid command, got \"%s\" but need \"%s\"", inst.Invoke.Command, logCmd)
 	}
 
@@ -192,6 +388,9 @@ func (c *contract) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instructio
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := el.checkSchema(event); err != nil {
+		return nil, nil, err
+	}
 
 	// Even though this is an invoke, we'll use the Spawn convention,
 	// since the new event is essentially being spawned on this eventlog.
@@ -212,7 +411,6 @@ func (c *contract) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instructio
 	// For now: buckets are allowed to grow as big as needed (but the previous
 	// rule prevents buckets from getting too big by timing them out).
 
-	el := &eventLog{Instance: inst.InstanceID, v: rst}
 	bID, b, err := el.getLatestBucket()
 	if err != nil {
 		return nil, nil, err
@@ -261,9 +459,9 @@ func (c *contract) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instructio
 			Start: event.When,
 			// It links to the previous latest bucket, or to the catch-all bucket
 			// if there was no previous bucket.
-			Prev:      bID,
-			EventRefs: [][]byte{eventID.Slice()},
+			Prev: bID,
 		}
+		newb.addEvent(eventID.Slice(), event.Topic)
 		buf, err := protobuf.Encode(newb)
 		if err != nil {
 			return nil, nil, err
@@ -275,7 +473,7 @@ func (c *contract) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instructio
 	} else {
 		// Otherwise just add into whatever bucket we found, no matter how
 		// many are already there. (Splitting buckets is hard and not important to us.)
-		b.EventRefs = append(b.EventRefs, eventID.Slice())
+		b.addEvent(eventID.Slice(), event.Topic)
 		bucketBuf, err := protobuf.Encode(b)
 		if err != nil {
 			return nil, nil, err
@@ -326,10 +524,18 @@ func newService(c *onet.Context) (onet.Service, error) {
 	s := &Service{
 		ServiceProcessor: onet.NewServiceProcessor(c),
 		omni:             c.Service(byzcoin.ServiceName).(*byzcoin.Service),
+		skipchain:        c.Service(skipchain.ServiceName).(*skipchain.Service),
+		closing:          make(chan struct{}),
 	}
-	if err := s.RegisterHandlers(s.Search); err != nil {
+	if err := s.RegisterHandlers(s.Search, s.Export); err != nil {
 		log.ErrFatal(err, "Couldn't register messages")
 	}
+	if err := s.RegisterStreamingHandlers(s.SubscribeEvents); err != nil {
+		log.ErrFatal(err, "Couldn't register streaming messages")
+	}
+	if pruneInterval > 0 {
+		go s.pruneLoop()
+	}
 	return s, nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/app"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
 )
 
 var (
@@ -35,6 +37,7 @@ var (
 	argDumpElection = flag.Bool("dumpelection", false, "Dump the current election config for the election specified with -id.")
 	argJSON         = flag.Bool("json", false, "Dump in json mode.")
 	argLoad         = flag.String("load", "", "Load the specified json file to modify the election specified with -id.")
+	argExport       = flag.String("export", "", "Export the proofs of the decrypted election specified with -id to the specified file, for evoting-verify.")
 )
 
 func main() {
@@ -127,6 +130,31 @@ func main() {
 		return
 	}
 
+	if *argExport != "" {
+		id, err := hex.DecodeString(*argID)
+		if err != nil {
+			log.Fatal("id decode", err)
+		}
+		reply := &evoting.GetBundleReply{}
+		client := onet.NewClient(cothority.Suite, evoting.ServiceName)
+		if err = client.SendProtobuf(roster.List[0], &evoting.GetBundle{ID: id}, reply); err != nil {
+			log.Fatal("get bundle request: ", err)
+		}
+		if err = reply.Bundle.Verify(); err != nil {
+			log.Fatal("the bundle does not verify: ", err)
+		}
+
+		buf, err := protobuf.Encode(reply.Bundle)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = ioutil.WriteFile(*argExport, buf, 0644); err != nil {
+			log.Fatal("cannot write the bundle: ", err)
+		}
+		log.Infof("Exported %d ballots to %s", len(reply.Bundle.Ballots), *argExport)
+		return
+	}
+
 	if *argLoad != "" {
 		id, err := hex.DecodeString(*argID)
 		if err != nil {
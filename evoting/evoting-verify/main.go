@@ -0,0 +1,131 @@
+// This is an offline verifier for the bundles exported by evoting-admin. It
+// checks the shuffle and decryption proofs of an election and prints the
+// result, without contacting any conode.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/evoting/lib"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: evoting-verify [-code code] bundle")
+		flag.PrintDefaults()
+	}
+	code := flag.String("code", "", "tracking code of a ballot that must be counted")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		log.Fatal("the bundle file is required")
+	}
+
+	bundle, err := readBundle(flag.Arg(0))
+	if err != nil {
+		log.Fatal("cannot read the bundle: ", err)
+	}
+	if err := bundle.Verify(); err != nil {
+		printTrustees(bundle)
+		log.Fatal("verification failed: ", err)
+	}
+
+	e := bundle.Election
+	cast, err := bundle.CastBallots()
+	if err != nil {
+		log.Fatal("verification failed: ", err)
+	}
+	fmt.Printf("Election %x verified: %d ballots (%d replaced), %d shuffles and %d partial decryptions\n",
+		e.ID, len(bundle.Ballots), len(cast)-len(bundle.Ballots), len(bundle.Mixes), len(bundle.Partials))
+	if *code != "" {
+		found := false
+		for _, ballot := range bundle.Ballots {
+			if ballot.TrackingCode() == strings.ToUpper(*code) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Fatal("the ballot with tracking code ", *code, " is not counted")
+		}
+		fmt.Printf("The ballot with tracking code %s is counted\n", *code)
+	}
+	printResult(e, bundle.Points)
+}
+
+// printTrustees prints the nodes whose partial decryption is not valid.
+func printTrustees(b *lib.Bundle) {
+	if b.Election == nil || b.Election.Roster == nil || len(b.Mixes) == 0 || len(b.Election.Commits) == 0 {
+		return
+	}
+	for _, c := range b.Election.CheckPartials(b.Partials, b.Mixes[len(b.Mixes)-1]) {
+		if !c.Valid {
+			_, node := b.Election.Roster.Search(c.NodeID)
+			fmt.Printf("Trustee %v: %s\n", node, c.Error)
+		}
+	}
+}
+
+func readBundle(path string) (*lib.Bundle, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &lib.Bundle{}
+	err = protobuf.DecodeWithConstructors(buf, bundle, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// printResult prints the number of votes of the candidates, or the rounds of
+// the count of a ranked-choice election.
+func printResult(e *lib.Election, points []kyber.Point) {
+	ballots := make([][]uint32, 0, len(points))
+	invalid := 0
+	for _, p := range points {
+		ballot, err := lib.DecodeBallot(p)
+		if err != nil {
+			invalid++
+			continue
+		}
+		ballots = append(ballots, ballot)
+	}
+
+	if e.Type == lib.RankedChoice {
+		tally := lib.STV(e.Candidates, e.Seats, ballots)
+		fmt.Printf("Quota: %v\n", float64(tally.Quota)/lib.VoteUnit)
+		for i, r := range tally.Rounds {
+			fmt.Printf("Round %d:\n", i+1)
+			for j, c := range e.Candidates {
+				fmt.Printf("  %v: %v\n", c, float64(r.Counts[j])/lib.VoteUnit)
+			}
+			fmt.Printf("  exhausted: %v\n", float64(r.Exhausted)/lib.VoteUnit)
+			fmt.Printf("  elected: %v, excluded: %v\n", r.Elected, r.Eliminated)
+		}
+		fmt.Printf("Elected: %v\n", tally.Elected)
+		fmt.Printf("Invalid: %d\n", invalid+tally.Invalid)
+		return
+	}
+
+	votes := make(map[uint32]int)
+	for _, ballot := range ballots {
+		for _, c := range ballot {
+			votes[c]++
+		}
+	}
+	for _, c := range e.Candidates {
+		fmt.Printf("%v: %d\n", c, votes[c])
+	}
+	fmt.Printf("Invalid: %d\n", invalid)
+}
@@ -0,0 +1,242 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/onet/v3/network"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+// Bundle holds the public data of a decrypted election, so that anybody can
+// check offline that its ballots were correctly shuffled and decrypted. The
+// blocks of the election skipchain prove that the ballots are the ones cast
+// in the election, whose ID is the hash of the genesis block.
+type Bundle struct {
+	Election *Election              // Election holds the roster, the DKG key and commitments.
+	Ballots  []*Ballot              // Ballots are the encrypted ballots, the last one of each voter.
+	Mixes    []*Mix                 // Mixes are the shuffles of the nodes with their proofs.
+	Partials []*Partial             // Partials are the partial decryptions of the nodes with their proofs.
+	Points   []kyber.Point          // Points are the reconstructed plaintexts of the ballots.
+	Blocks   []*skipchain.SkipBlock // Blocks are the blocks of the election skipchain, from the genesis to the latest.
+}
+
+// Verify checks the blocks of the election skipchain, that the ballots are
+// the last ballot cast in them by each voter, all the shuffle and decryption
+// proofs of the bundle, and that the plaintexts are reconstructed from the
+// partial decryptions.
+func (b *Bundle) Verify() error {
+	e := b.Election
+	if e == nil || e.Roster == nil {
+		return errors.New("bundle error: no election")
+	}
+	if len(e.Commits) == 0 || !e.Commits[0].Equal(e.Key) {
+		return errors.New("bundle error: the DKG commitments don't match the election key")
+	}
+	threshold := 2*len(e.Roster.List)/3 + 1
+
+	cast, err := b.CastBallots()
+	if err != nil {
+		return err
+	}
+	last := LastBallots(cast)
+	if len(last) != len(b.Ballots) {
+		return errors.New("bundle error: the ballots are not the last cast ones")
+	}
+	for i := range last {
+		if last[i].User != b.Ballots[i].User || !last[i].Alpha.Equal(b.Ballots[i].Alpha) ||
+			!last[i].Beta.Equal(b.Ballots[i].Beta) {
+			return fmt.Errorf("bundle error: ballot %d is not the last cast one", i)
+		}
+	}
+
+	if len(b.Mixes) < threshold {
+		return errors.New("bundle error: election not shuffled")
+	}
+	seen := make(map[network.ServerIdentityID]bool)
+	x, y := Split(b.Ballots)
+	for i, mix := range b.Mixes {
+		if _, node := e.Roster.Search(mix.NodeID); node == nil || seen[mix.NodeID] {
+			return fmt.Errorf("bundle error: mix %d is not from another node of the roster", i)
+		}
+		seen[mix.NodeID] = true
+		v, w := Split(mix.Ballots)
+		if err := Verify(mix.Proof, e.Key, x, y, v, w); err != nil {
+			return fmt.Errorf("bundle error: invalid shuffle %d: %v", i, err)
+		}
+		x, y = v, w
+	}
+
+	if len(b.Partials) < threshold {
+		return errors.New("bundle error: election not decrypted")
+	}
+	seen = make(map[network.ServerIdentityID]bool)
+	for i, p := range b.Partials {
+		if seen[p.NodeID] {
+			return fmt.Errorf("bundle error: partial %d is from the same node as another", i)
+		}
+		seen[p.NodeID] = true
+		if err := e.VerifyPartial(p, b.Mixes[len(b.Mixes)-1]); err != nil {
+			return fmt.Errorf("bundle error: partial %d: %v", i, err)
+		}
+	}
+
+	points, err := e.Reconstruct(b.Partials)
+	if err != nil {
+		return err
+	}
+	if len(points) != len(b.Points) {
+		return errors.New("bundle error: wrong number of plaintexts")
+	}
+	for i := range points {
+		if !points[i].Equal(b.Points[i]) {
+			return fmt.Errorf("bundle error: plaintext %d is not the reconstructed one", i)
+		}
+	}
+	return nil
+}
+
+// CastBallots checks that the blocks of the bundle are the election
+// skipchain, each one with a valid forward link to the next one, and returns
+// all the ballots cast in them, including the ones replaced by a later ballot
+// of the same user. The election of the blocks must have the key and the DKG
+// commitments of the bundle.
+func (b *Bundle) CastBallots() ([]*Ballot, error) {
+	e := b.Election
+	if len(b.Blocks) == 0 || !b.Blocks[0].Hash.Equal(e.ID) {
+		return nil, errors.New("bundle error: the blocks don't start at the genesis of the election")
+	}
+
+	var election *Election
+	ballots := make([]*Ballot, 0)
+	for i, block := range b.Blocks {
+		if !block.Hash.Equal(block.CalculateHash()) {
+			return nil, fmt.Errorf("bundle error: wrong hash of block %d", i)
+		}
+		if i+1 < len(b.Blocks) {
+			if len(block.ForwardLink) == 0 || !block.ForwardLink[0].From.Equal(block.Hash) ||
+				!block.ForwardLink[0].To.Equal(b.Blocks[i+1].Hash) {
+				return nil, fmt.Errorf("bundle error: block %d has no forward link to the next one", i)
+			}
+			if err := block.VerifyForwardSignatures(); err != nil {
+				return nil, fmt.Errorf("bundle error: block %d: %v", i, err)
+			}
+		} else if len(block.ForwardLink) > 0 {
+			return nil, errors.New("bundle error: the last block is not the latest one")
+		}
+
+		transaction := UnmarshalTransaction(block.Data)
+		if transaction == nil {
+			continue
+		}
+		if transaction.Election != nil {
+			election = transaction.Election
+		}
+		if transaction.Ballot != nil {
+			ballots = append(ballots, transaction.Ballot)
+		}
+	}
+
+	if election == nil || election.Key == nil || !election.Key.Equal(e.Key) ||
+		len(election.Commits) != len(e.Commits) {
+		return nil, errors.New("bundle error: the election key is not the one of the skipchain")
+	}
+	for i := range e.Commits {
+		if !election.Commits[i].Equal(e.Commits[i]) {
+			return nil, errors.New("bundle error: the DKG commitments are not the ones of the skipchain")
+		}
+	}
+	return ballots, nil
+}
+
+// TrusteeCheck is the result of the check of the partial decryption of one
+// node of the roster.
+type TrusteeCheck struct {
+	NodeID network.ServerIdentityID // NodeID is the node of the roster.
+	Valid  bool                     // Valid is true if all the proofs of its partial are correct.
+	Error  string                   // Error tells why the partial is not valid.
+}
+
+// CheckPartials checks the partial decryption of every node of the roster
+// against the last mix, so that a malformed share points to its node. A node
+// without a partial is not valid, even if enough nodes decrypted the ballots.
+func (e *Election) CheckPartials(partials []*Partial, mix *Mix) []*TrusteeCheck {
+	checks := make([]*TrusteeCheck, len(e.Roster.List))
+	for i, node := range e.Roster.List {
+		checks[i] = &TrusteeCheck{NodeID: node.ID, Error: "no partial decryption"}
+		for _, p := range partials {
+			if !p.NodeID.Equal(node.ID) {
+				continue
+			}
+			if err := e.VerifyPartial(p, mix); err != nil {
+				checks[i].Error = err.Error()
+			} else {
+				checks[i].Valid = true
+				checks[i].Error = ""
+			}
+			break
+		}
+	}
+	return checks
+}
+
+// VerifyPartial checks the proofs that the partial holds the decryptions of
+// the ballots of the mix with the DKG share of its node.
+func (e *Election) VerifyPartial(p *Partial, mix *Mix) error {
+	if len(e.Commits) == 0 {
+		return errors.New("the election has no DKG commitments")
+	}
+	i, node := e.Roster.Search(p.NodeID)
+	if node == nil {
+		return errors.New("the partial is not from a node of the roster")
+	}
+	if len(p.Points) != len(mix.Ballots) || len(p.Proofs) != len(mix.Ballots) {
+		return errors.New("wrong number of points or proofs in the partial")
+	}
+
+	public := share.NewPubPoly(cothority.Suite, nil, e.Commits).Eval(i).V
+	base := cothority.Suite.Point().Base()
+	for j, ballot := range mix.Ballots {
+		// The point is beta - v * alpha, with v the share of the node.
+		shared := cothority.Suite.Point().Sub(ballot.Beta, p.Points[j])
+		if p.Proofs[j] == nil {
+			return fmt.Errorf("missing proof of point %d", j)
+		}
+		if err := p.Proofs[j].Verify(cothority.Suite, base, ballot.Alpha, public, shared); err != nil {
+			return fmt.Errorf("invalid proof of point %d: %v", j, err)
+		}
+	}
+	return nil
+}
+
+// Reconstruct recovers the plaintexts of the ballots from the partial
+// decryptions of the nodes, using Lagrange interpolation.
+func (e *Election) Reconstruct(partials []*Partial) ([]kyber.Point, error) {
+	if len(partials) == 0 {
+		return nil, errors.New("no partials")
+	}
+	points := make([]kyber.Point, 0)
+
+	n := len(e.Roster.List)
+	for i := 0; i < len(partials[0].Points); i++ {
+		shares := make([]*share.PubShare, n)
+		for _, partial := range partials {
+			j, node := e.Roster.Search(partial.NodeID)
+			if node == nil || i >= len(partial.Points) {
+				return nil, errors.New("invalid partial")
+			}
+			shares[j] = &share.PubShare{I: j, V: partial.Points[i]}
+		}
+
+		message, err := share.RecoverCommit(cothority.Suite, shares, 2*n/3+1, n)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, message)
+	}
+	return points, nil
+}
@@ -0,0 +1,196 @@
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+// genBundle generates the bundle of an election of n nodes, shuffled and
+// decrypted by all of them.
+func genBundle(t *testing.T, n int) *Bundle {
+	dkgs, err := DKGSimulate(n, 2*n/3+1)
+	require.NoError(t, err)
+
+	list := make([]*network.ServerIdentity, n)
+	for i := range list {
+		_, X := RandomKeyPair()
+		list[i] = network.NewServerIdentity(X, network.NewAddress(network.Local, fmt.Sprintf("localhost:%d", 2000+i)))
+	}
+	secret, err := NewSharedSecret(dkgs[0])
+	require.NoError(t, err)
+	e := &Election{Roster: onet.NewRoster(list), Key: secret.X, Commits: secret.Commits}
+
+	box := genBox(e.Key, 3)
+	mixes := box.genMix(e.Key, n)
+	for i, mix := range mixes {
+		mix.NodeID = list[i].ID
+	}
+	partials := make([]*Partial, n)
+	for i, gen := range dkgs {
+		secret, err := NewSharedSecret(gen)
+		require.NoError(t, err)
+		points, proofs, err := mixes[n-1].Decrypt(secret.V)
+		require.NoError(t, err)
+		partials[i] = &Partial{Points: points, Proofs: proofs, NodeID: list[secret.Index].ID}
+	}
+	points, err := e.Reconstruct(partials)
+	require.NoError(t, err)
+	// The first voter cast a ballot before the counted one.
+	cast := append(genBox(e.Key, 1).Ballots, box.Ballots...)
+	blocks := genChain(t, e, cast)
+	return &Bundle{Election: e, Ballots: box.Ballots, Mixes: mixes, Partials: partials, Points: points, Blocks: blocks}
+}
+
+// genChain generates the blocks of an election skipchain holding the
+// election and the cast ballots, signed by a roster of one node, and sets the
+// ID of the election.
+func genChain(t *testing.T, e *Election, cast []*Ballot) []*skipchain.SkipBlock {
+	suite := pairing.NewSuiteBn256()
+	kp := key.NewKeyPair(suite)
+	roster := onet.NewRoster([]*network.ServerIdentity{network.NewServerIdentity(kp.Public,
+		network.NewAddress(network.Local, "localhost:3000"))})
+
+	genesis := skipchain.NewSkipBlock()
+	genesis.Roster = roster
+	genesis.Hash = genesis.CalculateHash()
+	e.ID = genesis.Hash
+
+	blocks := []*skipchain.SkipBlock{genesis}
+	transactions := []*Transaction{NewTransaction(e, 0)}
+	for _, ballot := range cast {
+		transactions = append(transactions, NewTransaction(ballot, ballot.User))
+	}
+	for _, transaction := range transactions {
+		data, err := protobuf.Encode(transaction)
+		require.NoError(t, err)
+		block := skipchain.NewSkipBlock()
+		block.Roster = roster
+		block.Index = len(blocks)
+		block.Data = data
+		block.Hash = block.CalculateHash()
+		linkBlocks(t, blocks[len(blocks)-1], block, kp.Private)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// linkBlocks adds the forward link to the next block, signed by the roster
+// of one node.
+func linkBlocks(t *testing.T, from, to *skipchain.SkipBlock, private kyber.Scalar) {
+	fl := skipchain.NewForwardLink(from, to)
+	sig, err := bls.Sign(pairing.NewSuiteBn256(), private, fl.Hash())
+	require.NoError(t, err)
+	fl.Signature = byzcoinx.FinalSignature{Msg: fl.Hash(), Sig: sig}
+	from.ForwardLink = []*skipchain.ForwardLink{fl}
+}
+
+func TestBundle_Verify(t *testing.T) {
+	b := genBundle(t, 3)
+	require.NoError(t, b.Verify())
+
+	var plaintexts []byte
+	for _, p := range b.Points {
+		data, err := p.Data()
+		require.NoError(t, err)
+		plaintexts = append(plaintexts, data...)
+	}
+	assert.ElementsMatch(t, []byte{0, 1, 2}, plaintexts)
+
+	b.Points[0], b.Points[1] = b.Points[1], b.Points[0]
+	assert.Error(t, b.Verify())
+
+	b = genBundle(t, 3)
+	b.Partials[0].Points[0] = cothority.Suite.Point().Pick(random.New())
+	assert.Error(t, b.Verify())
+
+	b = genBundle(t, 3)
+	b.Partials[1].Proofs[0], b.Partials[1].Proofs[1] = b.Partials[1].Proofs[1], b.Partials[1].Proofs[0]
+	assert.Error(t, b.Verify())
+
+	b = genBundle(t, 3)
+	b.Mixes = b.Mixes[1:]
+	assert.Error(t, b.Verify())
+
+	b = genBundle(t, 3)
+	b.Election.Commits = nil
+	assert.Error(t, b.Verify())
+
+	// Only the last ballot of a voter counts.
+	b = genBundle(t, 3)
+	cast, err := b.CastBallots()
+	require.NoError(t, err)
+	require.Len(t, cast, 4)
+	b.Ballots[0] = cast[0]
+	assert.Error(t, b.Verify())
+}
+
+func TestBundle_VerifyBlocks(t *testing.T) {
+	// The blocks must go up to the latest one.
+	b := genBundle(t, 3)
+	b.Blocks = b.Blocks[:len(b.Blocks)-1]
+	assert.Error(t, b.Verify())
+
+	// A ballot can't be added without the signature of the roster.
+	b = genBundle(t, 3)
+	kp := key.NewKeyPair(pairing.NewSuiteBn256())
+	data, err := protobuf.Encode(NewTransaction(genBox(b.Election.Key, 1).Ballots[0], 0))
+	require.NoError(t, err)
+	last := b.Blocks[len(b.Blocks)-1]
+	forged := skipchain.NewSkipBlock()
+	forged.Roster = last.Roster
+	forged.Index = last.Index + 1
+	forged.Data = data
+	forged.Hash = forged.CalculateHash()
+	linkBlocks(t, last, forged, kp.Private)
+	b.Blocks = append(b.Blocks, forged)
+	assert.Error(t, b.Verify())
+
+	// A ballot can't be changed in its block.
+	b = genBundle(t, 3)
+	b.Blocks[2].Data = b.Blocks[3].Data
+	assert.Error(t, b.Verify())
+	b.Blocks[2].Hash = b.Blocks[2].CalculateHash()
+	assert.Error(t, b.Verify())
+
+	// The blocks must be the skipchain of the election.
+	b = genBundle(t, 3)
+	b.Election.ID = b.Blocks[1].Hash
+	assert.Error(t, b.Verify())
+}
+
+func TestElection_CheckPartials(t *testing.T) {
+	b := genBundle(t, 4)
+	mix := b.Mixes[len(b.Mixes)-1]
+	for _, c := range b.Election.CheckPartials(b.Partials, mix) {
+		assert.True(t, c.Valid)
+	}
+
+	// A malformed share and a missing partial point to their nodes.
+	b.Partials[1].Points[0] = cothority.Suite.Point().Pick(random.New())
+	checks := b.Election.CheckPartials(b.Partials[1:], mix)
+	for i, c := range checks {
+		assert.Equal(t, b.Election.Roster.List[i].ID, c.NodeID)
+		switch c.NodeID {
+		case b.Partials[0].NodeID, b.Partials[1].NodeID:
+			assert.False(t, c.Valid)
+			assert.NotEmpty(t, c.Error)
+		default:
+			assert.True(t, c.Valid)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package lib
 import (
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/proof"
+	"go.dedis.ch/kyber/v3/proof/dleq"
 	"go.dedis.ch/kyber/v3/share/dkg/rabin"
 	"go.dedis.ch/kyber/v3/shuffle"
 	"go.dedis.ch/kyber/v3/util/random"
@@ -57,6 +58,25 @@ type Partial struct {
 
 	NodeID    network.ServerIdentityID // NodeID is the node having signed the partial
 	Signature []byte                   // Signature of the public key
+
+	Proofs []*dleq.Proof // Proofs that the points are decrypted with the DKG share of the node.
+}
+
+// Decrypt partially decrypts the ballots of the mix with the share of a node,
+// and proves for each ballot that it used the share of the DKG commitments.
+func (m *Mix) Decrypt(secret kyber.Scalar) ([]kyber.Point, []*dleq.Proof, error) {
+	points := make([]kyber.Point, len(m.Ballots))
+	proofs := make([]*dleq.Proof, len(m.Ballots))
+	base := cothority.Suite.Point().Base()
+	for i, ballot := range m.Ballots {
+		proof, _, _, err := dleq.NewDLEQProof(cothority.Suite, base, ballot.Alpha, secret)
+		if err != nil {
+			return nil, nil, err
+		}
+		points[i] = Decrypt(secret, ballot.Alpha, ballot.Beta)
+		proofs[i] = proof
+	}
+	return points, proofs, nil
 }
 
 // genPartials generates partial decryptions for a given list of shared secrets.
@@ -12,6 +12,7 @@ import (
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
 
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/skipchain"
 )
 
@@ -27,8 +28,20 @@ const (
 	Decrypted
 )
 
+// BallotType is the kind of ballots of an election.
+type BallotType uint32
+
+const (
+	// MultipleChoice ballots hold up to MaxChoices candidates, in any order.
+	MultipleChoice BallotType = iota
+	// RankedChoice ballots hold up to MaxChoices candidates by order of
+	// preference. They are counted with the single transferable vote.
+	RankedChoice
+)
+
 func init() {
-	network.RegisterMessages(Election{}, Ballot{}, Box{}, Mix{}, Partial{})
+	network.RegisterMessages(Election{}, Ballot{}, Box{}, Mix{}, Partial{}, Tally{}, Round{}, Bundle{}, Eligibility{},
+		Group{}, Turnout{}, GroupTurnout{}, TrusteeCheck{})
 }
 
 // Election is the base object for a voting procedure. It is stored
@@ -58,6 +71,17 @@ type Election struct {
 
 	Voted        skipchain.SkipBlockID // Voted denotes if a user has already cast a ballot for this election.
 	MoreInfoLang map[string]string     // MoreInfoLang, is MoreInfo, but as a lang-code/value map. MoreInfoLang should be used in preference to MoreInfo.
+
+	Type  BallotType // Type of the ballots, MultipleChoice by default.
+	Seats int        // Seats is the number of candidates elected by a RankedChoice election, 1 if not set.
+
+	Commits []kyber.Point // Commits of the DKG, to check the partial decryptions of the nodes.
+
+	Eligibility *Eligibility // Eligibility replaces Users with a darc expression, if set.
+
+	Groups []*Group // Groups of voters whose turnout is reported separately.
+
+	AutoFinalize bool // AutoFinalize shuffles and decrypts the election at End, without an admin.
 }
 
 // Footer denotes the fields for the election footer
@@ -177,17 +201,39 @@ func (e *Election) setStage(s *skipchain.Service) error {
 
 // Box accumulates all the ballots while only keeping the last ballot for each user.
 func (e *Election) Box(s *skipchain.Service) (*Box, error) {
+	box, _, err := e.box(s)
+	return box, err
+}
+
+// box returns the box with the latest block of the election skipchain.
+func (e *Election) box(s *skipchain.Service) (*Box, *skipchain.SkipBlock, error) {
+	ballots, block, err := e.ballots(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Box{Ballots: LastBallots(ballots)}, block, nil
+}
+
+// Ballots returns all the ballots cast in the election, in the order of the
+// skipchain, including the ones replaced by a later ballot of the same user.
+func (e *Election) Ballots(s *skipchain.Service) ([]*Ballot, error) {
+	ballots, _, err := e.ballots(s)
+	return ballots, err
+}
+
+// ballots returns all the ballots of the election skipchain with its latest
+// block.
+func (e *Election) ballots(s *skipchain.Service) ([]*Ballot, *skipchain.SkipBlock, error) {
 	search, err := s.GetSingleBlockByIndex(
 		&skipchain.GetSingleBlockByIndex{
 			Genesis: e.ID,
 			Index:   0,
 		})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	block := search.SkipBlock
 
-	// Use map to only included a user's last ballot.
 	ballots := make([]*Ballot, 0)
 	for {
 		transaction := UnmarshalTransaction(block.Data)
@@ -203,19 +249,38 @@ func (e *Election) Box(s *skipchain.Service) (*Box, error) {
 				ID: block.ForwardLink[0].To,
 			})
 	}
+	return ballots, block, nil
+}
 
-	// Reverse ballot list
-	for i, j := 0, len(ballots)-1; i < j; i, j = i+1, j-1 {
-		ballots[i], ballots[j] = ballots[j], ballots[i]
+// Blocks returns the blocks of the election skipchain, from the genesis to
+// the latest one.
+func (e *Election) Blocks(s *skipchain.Service) ([]*skipchain.SkipBlock, error) {
+	block := s.GetDB().GetByID(e.ID)
+	if block == nil {
+		return nil, errors.New("no genesis block for the election")
 	}
 
-	// Only keep last casted ballot per user
+	blocks := []*skipchain.SkipBlock{block}
+	for len(block.ForwardLink) > 0 {
+		block = s.GetDB().GetByID(block.ForwardLink[0].To)
+		if block == nil {
+			return nil, errors.New("missing block of the election skipchain")
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// LastBallots only keeps the last ballot of each user, so that a voter can
+// cast a ballot again and only the latest one counts. The ballots stay in
+// the order in which they were cast.
+func LastBallots(ballots []*Ballot) []*Ballot {
 	mapping := make(map[uint32]bool)
 	unique := make([]*Ballot, 0)
-	for _, ballot := range ballots {
-		if _, found := mapping[ballot.User]; !found {
-			unique = append(unique, ballot)
-			mapping[ballot.User] = true
+	for i := len(ballots) - 1; i >= 0; i-- {
+		if !mapping[ballots[i].User] {
+			unique = append(unique, ballots[i])
+			mapping[ballots[i].User] = true
 		}
 	}
 
@@ -223,7 +288,7 @@ func (e *Election) Box(s *skipchain.Service) (*Box, error) {
 	for i, j := 0, len(unique)-1; i < j; i, j = i+1, j-1 {
 		unique[i], unique[j] = unique[j], unique[i]
 	}
-	return &Box{Ballots: unique}, nil
+	return unique
 }
 
 // Mixes returns all mixes created by the roster conodes.
@@ -317,6 +382,25 @@ func (e *Election) Partials(s *skipchain.Service) ([]*Partial, error) {
 	return partials, nil
 }
 
+// checkType returns an error if the ballots of the election cannot be of
+// its type. A ballot is a single embedded point, holding the candidates as
+// 3-byte little-endian ids, so it can only hold a few of them.
+func (e *Election) checkType() error {
+	switch e.Type {
+	case MultipleChoice:
+	case RankedChoice:
+		if max := cothority.Suite.Point().EmbedLen() / 3; e.MaxChoices > max {
+			return fmt.Errorf("a ranked ballot holds at most %d candidates", max)
+		}
+		if e.Seats < 0 || e.Seats > len(e.Candidates) {
+			return errors.New("invalid number of seats")
+		}
+	default:
+		return fmt.Errorf("unknown ballot type %d", e.Type)
+	}
+	return nil
+}
+
 // IsUser checks if a given user is a registered voter for the election.
 func (e *Election) IsUser(user uint32) bool {
 	for _, u := range e.Users {
@@ -343,6 +427,8 @@ func (e *Election) String() string {
 	printLang(str, e.Subtitle)
 	fmt.Fprintf(str, "Candidates: %v\n", e.Candidates)
 	fmt.Fprintf(str, "MaxChoices: %v\n", e.MaxChoices)
+	fmt.Fprintf(str, "Type: %v\n", e.Type)
+	fmt.Fprintf(str, "Seats: %v\n", e.Seats)
 	fmt.Fprintf(str, "MoreInfo: %v\n", e.MoreInfo)
 	fmt.Fprintf(str, "MoreInfoLang:\n")
 	printLang(str, e.MoreInfoLang)
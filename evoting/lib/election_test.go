@@ -17,3 +17,25 @@ func TestIsCreator(t *testing.T) {
 	assert.True(t, e.IsCreator(0))
 	assert.False(t, e.IsCreator(1))
 }
+
+func TestCheckType(t *testing.T) {
+	e := &Election{Candidates: []uint32{1, 2, 3}, MaxChoices: 3}
+	assert.NoError(t, e.checkType())
+	e.Type = RankedChoice
+	e.Seats = 2
+	assert.NoError(t, e.checkType())
+	e.Seats = 4
+	assert.Error(t, e.checkType())
+	e.Seats = 1
+	e.MaxChoices = 20
+	assert.Error(t, e.checkType())
+	e.Type = 5
+	assert.Error(t, e.checkType())
+}
+
+func TestLastBallots(t *testing.T) {
+	b := []*Ballot{{User: 1}, {User: 2}, {User: 1}, {User: 3}, {User: 2}}
+	last := LastBallots(b)
+	assert.Equal(t, []*Ballot{b[2], b[3], b[4]}, last)
+	assert.Empty(t, LastBallots(nil))
+}
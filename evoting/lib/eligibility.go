@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.dedis.ch/kyber/v3"
+
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+// Eligibility defines the voters of an election with a darc expression
+// instead of the static Users list. A user is a voter if the expression is
+// true for VoterIdentity, the user as authenticated by the master key. The
+// expression can delegate to darcs of a ByzCoin chain with "darc:" terms,
+// whose "_sign" rules are then voter rolls that can be managed on chain.
+// When the first ballot is cast, the voters are frozen into Users.
+type Eligibility struct {
+	ByzCoinID  skipchain.SkipBlockID // ByzCoinID is the chain holding the darcs of the expression.
+	Expression expression.Expr       // Expression must be true for the identity of a voter.
+	Frozen     bool                  // Frozen is set once the voters are copied to Users.
+}
+
+// VoterIdentity returns the darc identity of the user, as authenticated by
+// the server of the master key.
+func VoterIdentity(key kyber.Point, user uint32) string {
+	return darc.NewIdentityProxy(&darc.SignerProxy{
+		Data:   strconv.FormatUint(uint64(user), 10),
+		Public: key,
+	}).String()
+}
+
+// check returns an error if the expression cannot be parsed.
+func (el *Eligibility) check() error {
+	if el == nil || el.Frozen {
+		return nil
+	}
+	if len(el.Expression) == 0 {
+		return errors.New("empty eligibility expression")
+	}
+	_, err := expression.Evaluate(expression.InitParser(func(string) bool { return false }), el.Expression)
+	return err
+}
+
+// IsVoter checks if a given user is a voter of the election. Until they are
+// frozen, the voters are given by the eligibility expression, with the darcs
+// of getDarc.
+func (e *Election) IsVoter(user uint32, getDarc darc.GetDarc) bool {
+	if e.Eligibility == nil || e.Eligibility.Frozen {
+		return e.IsUser(user)
+	}
+	return darc.EvalExpr(e.Eligibility.Expression, getDarc, VoterIdentity(e.MasterKey, user)) == nil
+}
+
+// Voters returns the sorted list of the users for whom the eligibility
+// expression is true. The only possible voters are the identities of the
+// master key found in the expression and in the darcs it delegates to.
+func (el *Eligibility) Voters(key kyber.Point, getDarc darc.GetDarc) ([]uint32, error) {
+	ids := make(map[string]bool)
+	if err := collectIdentities(el.Expression, getDarc, make(map[string]bool), ids); err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("proxy:%v:", key)
+	voters := make([]uint32, 0)
+	for id := range ids {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		user, err := strconv.ParseUint(strings.TrimPrefix(id, prefix), 10, 32)
+		if err != nil || VoterIdentity(key, uint32(user)) != id {
+			continue
+		}
+		if darc.EvalExpr(el.Expression, getDarc, id) == nil {
+			voters = append(voters, uint32(user))
+		}
+	}
+	sort.Slice(voters, func(i, j int) bool { return voters[i] < voters[j] })
+	return voters, nil
+}
+
+// collectIdentities adds all the identities of the expression to ids,
+// following the delegations to darcs.
+func collectIdentities(expr expression.Expr, getDarc darc.GetDarc, visited, ids map[string]bool) error {
+	var darcs []string
+	parser := expression.InitParser(func(s string) bool {
+		if strings.HasPrefix(s, "darc:") {
+			darcs = append(darcs, s)
+		} else {
+			ids[s] = true
+		}
+		return false
+	})
+	if _, err := expression.Evaluate(parser, expr); err != nil {
+		return err
+	}
+
+	for _, s := range darcs {
+		if visited[s] {
+			continue
+		}
+		visited[s] = true
+		d := getDarc(s, true)
+		if d == nil {
+			return fmt.Errorf("unable to get the darc %s", s)
+		}
+		if err := collectIdentities(d.Rules.GetSignExpr(), getDarc, visited, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+)
+
+func TestEligibility(t *testing.T) {
+	key := cothority.Suite.Point().Pick(random.New())
+	other := cothority.Suite.Point().Pick(random.New())
+	proxy := func(user uint32) darc.Identity {
+		return darc.NewIdentityProxy(&darc.SignerProxy{Data: strconv.Itoa(int(user)), Public: key})
+	}
+	owner := darc.NewIdentityEd25519(other)
+
+	// The roll of the faculty delegates to the roll of a lab.
+	lab := darc.NewDarc(darc.InitRules([]darc.Identity{owner},
+		[]darc.Identity{proxy(111111), proxy(222222)}), []byte("lab"))
+	faculty := darc.NewDarc(darc.InitRules([]darc.Identity{owner},
+		[]darc.Identity{darc.NewIdentityDarc(lab.GetBaseID()), proxy(333333), owner,
+			darc.NewIdentityProxy(&darc.SignerProxy{Data: "444444", Public: other})}), []byte("faculty"))
+	getDarc := darc.DarcsToGetDarcs([]*darc.Darc{lab, faculty})
+
+	e := &Election{
+		MasterKey: key,
+		Users:     []uint32{555555},
+		Eligibility: &Eligibility{
+			Expression: expression.InitOrExpr(darc.NewIdentityDarc(faculty.GetBaseID()).String(),
+				VoterIdentity(key, 555555)),
+		},
+	}
+	require.NoError(t, e.Eligibility.check())
+	for _, user := range []uint32{111111, 222222, 333333, 555555} {
+		require.True(t, e.IsVoter(user, getDarc))
+	}
+	require.False(t, e.IsVoter(444444, getDarc))
+	require.False(t, e.IsVoter(666666, getDarc))
+
+	voters, err := e.Eligibility.Voters(key, getDarc)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{111111, 222222, 333333, 555555}, voters)
+
+	// Once frozen, only the users are voters.
+	e.Eligibility.Frozen = true
+	require.True(t, e.IsVoter(555555, getDarc))
+	require.False(t, e.IsVoter(111111, getDarc))
+
+	_, err = (&Eligibility{Expression: []byte("darc:00")}).Voters(key, getDarc)
+	require.Error(t, err)
+	require.Error(t, (&Eligibility{}).check())
+	require.Error(t, (&Eligibility{Expression: []byte("(a | b")}).check())
+}
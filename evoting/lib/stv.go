@@ -0,0 +1,184 @@
+package lib
+
+import (
+	"errors"
+	"sort"
+
+	"go.dedis.ch/kyber/v3"
+)
+
+// VoteUnit is the number of units of a vote in a Tally. Like in the Scottish
+// STV rules, the transferred votes are truncated to 5 decimals, so that
+// every verifier finds exactly the same rounds.
+const VoteUnit = 100000
+
+// Tally is the count of a RankedChoice election with the single transferable
+// vote. Anybody can check it by counting the reconstructed ballots with STV.
+type Tally struct {
+	Quota   int64    // Quota is the Droop quota of votes to be elected, in units.
+	Rounds  []*Round // Rounds are the intermediate counts.
+	Elected []uint32 // Elected are the candidates in the order of election.
+	Invalid int      // Invalid is the number of ballots without a valid candidate.
+}
+
+// Round is one stage of the count.
+type Round struct {
+	Counts     []int64  // Counts of the continuing candidates, in the order of Election.Candidates, in units.
+	Exhausted  int64    // Exhausted is the value of the ballots without continuing candidate, in units.
+	Elected    []uint32 // Elected are the candidates elected at the end of the round.
+	Eliminated []uint32 // Eliminated are the candidates excluded at the end of the round.
+}
+
+// DecodeBallot returns the candidates of a reconstructed ballot, in the order
+// of the voter.
+func DecodeBallot(p kyber.Point) ([]uint32, error) {
+	data, err := p.Data()
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%3 != 0 {
+		return nil, errors.New("ballot is not a list of candidates")
+	}
+	candidates := make([]uint32, len(data)/3)
+	for i := range candidates {
+		candidates[i] = uint32(data[3*i]) | uint32(data[3*i+1])<<8 | uint32(data[3*i+2])<<16
+	}
+	return candidates, nil
+}
+
+type stvState int
+
+const (
+	continuing stvState = iota
+	elected
+	excluded
+)
+
+type paper struct {
+	prefs  []int // prefs are the indexes of the candidates.
+	weight int64
+}
+
+// STV counts the ranked ballots to elect seats candidates and returns all the
+// rounds of the count. The unknown and repeated candidates of a ballot are
+// ignored. At every round, the candidates reaching the quota are elected and
+// their surplus is transferred to the next preferences of their ballots.
+// Otherwise the candidate with the fewest votes is excluded, ties being
+// broken by the previous rounds, then against the candidate listed last.
+// With one seat, this is instant-runoff voting.
+func STV(candidates []uint32, seats int, ballots [][]uint32) *Tally {
+	if seats < 1 {
+		seats = 1
+	}
+	index := make(map[uint32]int)
+	for i, c := range candidates {
+		index[c] = i
+	}
+
+	t := &Tally{}
+	var papers []*paper
+	for _, b := range ballots {
+		seen := make(map[int]bool)
+		p := &paper{weight: VoteUnit}
+		for _, c := range b {
+			i, ok := index[c]
+			if !ok || seen[i] {
+				continue
+			}
+			seen[i] = true
+			p.prefs = append(p.prefs, i)
+		}
+		if len(p.prefs) == 0 {
+			t.Invalid++
+			continue
+		}
+		papers = append(papers, p)
+	}
+	t.Quota = (int64(len(papers))/int64(seats+1) + 1) * VoteUnit
+
+	state := make([]stvState, len(candidates))
+	elect := func(r *Round, i int) {
+		state[i] = elected
+		r.Elected = append(r.Elected, candidates[i])
+		t.Elected = append(t.Elected, candidates[i])
+	}
+	// lower returns true if candidate a is excluded before candidate b.
+	lower := func(a, b int) bool {
+		for i := len(t.Rounds) - 1; i >= 0; i-- {
+			if ca, cb := t.Rounds[i].Counts[a], t.Rounds[i].Counts[b]; ca != cb {
+				return ca < cb
+			}
+		}
+		return a > b
+	}
+
+	for len(t.Elected) < seats {
+		r := &Round{Counts: make([]int64, len(candidates))}
+		piles := make([][]*paper, len(candidates))
+		for _, p := range papers {
+			top := -1
+			for _, i := range p.prefs {
+				if state[i] == continuing {
+					top = i
+					break
+				}
+			}
+			if top < 0 {
+				r.Exhausted += p.weight
+				continue
+			}
+			r.Counts[top] += p.weight
+			piles[top] = append(piles[top], p)
+		}
+		t.Rounds = append(t.Rounds, r)
+
+		var cont []int
+		for i := range candidates {
+			if state[i] == continuing {
+				cont = append(cont, i)
+			}
+		}
+		if len(cont) == 0 {
+			break
+		}
+		if len(cont) <= seats-len(t.Elected) {
+			for _, i := range cont {
+				elect(r, i)
+			}
+			break
+		}
+
+		var reached []int
+		for _, i := range cont {
+			if r.Counts[i] >= t.Quota {
+				reached = append(reached, i)
+			}
+		}
+		if len(reached) > 0 {
+			sort.SliceStable(reached, func(a, b int) bool {
+				return r.Counts[reached[a]] > r.Counts[reached[b]]
+			})
+			if left := seats - len(t.Elected); len(reached) > left {
+				reached = reached[:left]
+			}
+			for _, i := range reached {
+				elect(r, i)
+				surplus := r.Counts[i] - t.Quota
+				for _, p := range piles[i] {
+					p.weight = p.weight * surplus / r.Counts[i]
+				}
+			}
+			continue
+		}
+
+		lowest := cont[0]
+		for _, i := range cont[1:] {
+			if lower(i, lowest) {
+				lowest = i
+			}
+		}
+		state[lowest] = excluded
+		r.Eliminated = append(r.Eliminated, candidates[lowest])
+	}
+	return t
+}
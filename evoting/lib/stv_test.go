@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+func TestDecodeBallot(t *testing.T) {
+	secret := cothority.Suite.Scalar().Pick(random.New())
+	public := cothority.Suite.Point().Mul(secret, nil)
+
+	K, C := Encrypt(public, []byte{0x40, 0xe2, 0x01, 0x41, 0xe2, 0x01})
+	candidates, err := DecodeBallot(Decrypt(secret, K, C))
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{123456, 123457}, candidates)
+
+	K, C = Encrypt(public, []byte{1, 2})
+	_, err = DecodeBallot(Decrypt(secret, K, C))
+	assert.Error(t, err)
+}
+
+func TestSTV(t *testing.T) {
+	a, b, c := uint32(1), uint32(2), uint32(3)
+	candidates := []uint32{a, b, c}
+	repeat := func(n int, ballot ...uint32) [][]uint32 {
+		ballots := make([][]uint32, n)
+		for i := range ballots {
+			ballots[i] = ballot
+		}
+		return ballots
+	}
+
+	// Instant runoff: c is excluded and its votes elect b.
+	var ballots [][]uint32
+	ballots = append(ballots, repeat(4, a, b)...)
+	ballots = append(ballots, repeat(3, b)...)
+	ballots = append(ballots, repeat(2, c, b, c)...)
+	ballots = append(ballots, []uint32{4}, nil)
+	tally := STV(candidates, 0, ballots)
+	assert.Equal(t, 2, tally.Invalid)
+	assert.Equal(t, int64(5*VoteUnit), tally.Quota)
+	assert.Equal(t, []uint32{b}, tally.Elected)
+	require.Equal(t, 2, len(tally.Rounds))
+	assert.Equal(t, []int64{4 * VoteUnit, 3 * VoteUnit, 2 * VoteUnit}, tally.Rounds[0].Counts)
+	assert.Equal(t, []uint32{c}, tally.Rounds[0].Eliminated)
+	assert.Equal(t, []int64{4 * VoteUnit, 5 * VoteUnit, 0}, tally.Rounds[1].Counts)
+
+	// Two seats: the surplus of a is truncated, so b has fewer votes than c.
+	ballots = append(repeat(6, a, b), []uint32{b})
+	ballots = append(ballots, repeat(2, c)...)
+	tally = STV(candidates, 2, ballots)
+	assert.Equal(t, int64(4*VoteUnit), tally.Quota)
+	assert.Equal(t, []uint32{a, c}, tally.Elected)
+	require.Equal(t, 3, len(tally.Rounds))
+	assert.Equal(t, []uint32{a}, tally.Rounds[0].Elected)
+	assert.Equal(t, []int64{0, 199998, 2 * VoteUnit}, tally.Rounds[1].Counts)
+	assert.Equal(t, []uint32{b}, tally.Rounds[1].Eliminated)
+	assert.Equal(t, int64(199998+VoteUnit), tally.Rounds[2].Exhausted)
+
+	// Ties are broken against the candidate listed last.
+	tally = STV(candidates, 1, [][]uint32{{a}, {b}, {c}})
+	assert.Equal(t, []uint32{c}, tally.Rounds[0].Eliminated)
+}
@@ -0,0 +1,39 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"sort"
+	"strings"
+)
+
+// codeEncoding avoids the characters that are easily confused.
+var codeEncoding = base32.NewEncoding("ABCDEFGHJKLMNPQRSTUVWXYZ23456789").WithPadding(base32.NoPadding)
+
+// TrackingCode returns the code given to the voter of the ballot. It is
+// derived from the ciphertext only, so it reveals nothing about the vote,
+// and the voter can look for it in the codes published with the result to
+// check that the ballot was included.
+func (b *Ballot) TrackingCode() string {
+	h := sha256.New()
+	h.Write([]byte("evoting-tracking-code"))
+	b.Alpha.MarshalTo(h)
+	b.Beta.MarshalTo(h)
+	code := codeEncoding.EncodeToString(h.Sum(nil)[:10])
+
+	groups := make([]string, 0, 4)
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:i+4])
+	}
+	return strings.Join(groups, "-")
+}
+
+// Codes returns the sorted tracking codes of the ballots of the box.
+func (b *Box) Codes() []string {
+	codes := make([]string, len(b.Ballots))
+	for i, ballot := range b.Ballots {
+		codes[i] = ballot.TrackingCode()
+	}
+	sort.Strings(codes)
+	return codes
+}
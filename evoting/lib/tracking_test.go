@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+func TestBallot_TrackingCode(t *testing.T) {
+	box := genBox(cothority.Suite.Point().Pick(random.New()), 3)
+	code := box.Ballots[0].TrackingCode()
+	require.Len(t, code, 19)
+	require.Equal(t, code, box.Ballots[0].TrackingCode())
+	require.NotEqual(t, code, box.Ballots[1].TrackingCode())
+
+	codes := box.Codes()
+	require.Len(t, codes, 3)
+	require.True(t, sort.StringsAreSorted(codes))
+	require.Contains(t, codes, code)
+}
@@ -177,6 +177,12 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		if election.End < time.Now().Unix() {
 			return errors.New("open error: invalid end date")
 		}
+		if err := election.checkType(); err != nil {
+			return fmt.Errorf("open error: %v", err)
+		}
+		if err := election.Eligibility.check(); err != nil {
+			return fmt.Errorf("open error: invalid eligibility: %v", err)
+		}
 
 		master, err := GetMaster(s, election.Master)
 		if err != nil {
@@ -235,6 +241,9 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		if !election.IsCreator(t.User) {
 			return errors.New("shuffle error: user is not election creator")
 		}
+		if election.AutoFinalize && time.Now().Before(time.Unix(election.End, 0)) {
+			return errors.New("shuffle error: election is not closed yet")
+		}
 
 		// verify proposer
 		_, proposer := election.Roster.Search(t.Mix.NodeID)
@@ -331,6 +340,12 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		if err != nil {
 			return err
 		}
+
+		// The elections opened before the commitments were stored cannot
+		// check the proofs.
+		if len(election.Commits) > 0 {
+			return election.VerifyPartial(t.Partial, mixes[len(mixes)-1])
+		}
 		return nil
 	}
 	return errors.New("transaction error: empty transaction")
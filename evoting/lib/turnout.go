@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+// Group is a named subset of the voters, for which the turnout is reported
+// separately.
+type Group struct {
+	Name  string   // Name of the group.
+	Users []uint32 // Users are the scipers of the members of the group.
+}
+
+// Turnout is the number of voters who cast a ballot in an election, at a
+// given block of its skipchain. It reveals no more than the box, where the
+// user of every ballot is public, and anybody can check it against the box
+// of the same block.
+type Turnout struct {
+	Block   skipchain.SkipBlockID // Block is the latest block of the count.
+	Index   int                   // Index of Block in the election skipchain.
+	Voters  int                   // Voters is the number of ballots in the box.
+	Groups  []*GroupTurnout       // Groups holds the turnout of each group of the election.
+	BoxHash []byte                // BoxHash is the hash of the ballots in the box.
+}
+
+// GroupTurnout is the turnout of a group of voters.
+type GroupTurnout struct {
+	Name   string // Name of the group.
+	Voters int    // Voters is the number of members who cast a ballot.
+	Users  int    // Users is the number of members of the group.
+}
+
+// Hash returns the hash of the ballots in the box.
+func (b *Box) Hash() []byte {
+	h := sha256.New()
+	for _, ballot := range b.Ballots {
+		binary.Write(h, binary.LittleEndian, ballot.User)
+		ballot.Alpha.MarshalTo(h)
+		ballot.Beta.MarshalTo(h)
+	}
+	return h.Sum(nil)
+}
+
+// Turnout counts the voters of the box at the latest block of the election
+// skipchain.
+func (e *Election) Turnout(s *skipchain.Service) (*Turnout, error) {
+	box, block, err := e.box(s)
+	if err != nil {
+		return nil, err
+	}
+	t := e.count(box)
+	t.Block = block.Hash
+	t.Index = block.Index
+	return t, nil
+}
+
+// count returns the turnout of the box, without its block.
+func (e *Election) count(box *Box) *Turnout {
+	voted := make(map[uint32]bool)
+	for _, ballot := range box.Ballots {
+		voted[ballot.User] = true
+	}
+
+	t := &Turnout{Voters: len(box.Ballots), BoxHash: box.Hash()}
+	for _, g := range e.Groups {
+		gt := &GroupTurnout{Name: g.Name, Users: len(g.Users)}
+		for _, user := range g.Users {
+			if voted[user] {
+				gt.Voters++
+			}
+		}
+		t.Groups = append(t.Groups, gt)
+	}
+	return t
+}
+
+// Verify checks that the turnout is the one of the box, which must have been
+// retrieved at the same block.
+func (t *Turnout) Verify(e *Election, box *Box) error {
+	c := e.count(box)
+	if !bytes.Equal(t.BoxHash, c.BoxHash) {
+		return errors.New("turnout error: box hash mismatch")
+	}
+	if t.Voters != c.Voters {
+		return fmt.Errorf("turnout error: %d voters instead of %d", t.Voters, c.Voters)
+	}
+	if len(t.Groups) != len(c.Groups) {
+		return errors.New("turnout error: wrong number of groups")
+	}
+	for i, g := range c.Groups {
+		if t.Groups[i] == nil || *t.Groups[i] != *g {
+			return fmt.Errorf("turnout error: wrong turnout of group %s", g.Name)
+		}
+	}
+	return nil
+}
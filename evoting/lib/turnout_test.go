@@ -0,0 +1,31 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+func TestTurnout_Verify(t *testing.T) {
+	box := genBox(cothority.Suite.Point().Pick(random.New()), 5)
+	e := &Election{Groups: []*Group{
+		{Name: "a", Users: []uint32{0, 3, 7}},
+		{Name: "b", Users: []uint32{8}},
+	}}
+
+	turnout := e.count(box)
+	require.Equal(t, 5, turnout.Voters)
+	require.Equal(t, []*GroupTurnout{{"a", 2, 3}, {"b", 0, 1}}, turnout.Groups)
+	require.NoError(t, turnout.Verify(e, box))
+
+	turnout.Groups[1].Voters = 1
+	require.Error(t, turnout.Verify(e, box))
+	turnout.Groups[1].Voters = 0
+
+	// A ballot replaced in the box changes its hash.
+	box.Ballots[2] = genBox(cothority.Suite.Point().Pick(random.New()), 3).Ballots[2]
+	require.Error(t, turnout.Verify(e, box))
+}
@@ -8,12 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"runtime"
 	"strconv"
 	"sync"
 	"time"
 
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
@@ -22,6 +24,9 @@ import (
 
 	"github.com/go-ldap/ldap/v3"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
 	dkgprotocol "go.dedis.ch/cothority/v3/dkg/rabin"
 	"go.dedis.ch/cothority/v3/evoting"
 	"go.dedis.ch/cothority/v3/evoting/lib"
@@ -46,22 +51,46 @@ var serviceID onet.ServiceID
 var storageKey = []byte("storage")
 var dbVersion = 1
 
+// finalizeWorkers is the number of shuffle and decrypt operations of
+// different elections that the leader runs at the same time.
+var finalizeWorkers = runtime.NumCPU()
+
+// finalizeRetry is the delay before trying again to shuffle and decrypt an
+// election with AutoFinalize.
+var finalizeRetry = time.Minute
+
 // Service is the core structure of the application.
 type Service struct {
 	*onet.ServiceProcessor
 
 	skipchain *skipchain.Service
+	byzcoin   *byzcoin.Service
 
 	mutex         sync.Mutex
-	finalizeMutex sync.Mutex // used for protecting shuffle and decrypt operations
+	finalizeMutex sync.Mutex // used for protecting finalizeLocks
+	freezeMutex   sync.Mutex // used for freezing the voters only once
 	storage       *storage
 
+	// finalizeLocks serialize the shuffle and decrypt operations of each election.
+	finalizeLocks map[string]*sync.Mutex
+	// workers bounds the number of shuffle and decrypt operations running at once.
+	workers chan struct{}
+	// timers finalize the elections with AutoFinalize at their end.
+	timers map[string]*time.Timer
+
 	sciperMu    sync.Mutex
 	sciperCache []cacheEntry
 
 	pin string // pin is the current service number.
 }
 
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, evoting.ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
 // Storage saves the shared secrets and stages for each election on disk.
 type storage struct {
 	Roster  *onet.Roster
@@ -181,11 +210,17 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 		cur.End = req.Election.End
 		cur.Theme = req.Election.Theme
 		cur.Footer = req.Election.Footer
+		cur.Type = req.Election.Type
+		cur.Seats = req.Election.Seats
+		cur.Eligibility = req.Election.Eligibility
+		cur.Groups = req.Election.Groups
+		cur.AutoFinalize = req.Election.AutoFinalize
 
 		transaction := lib.NewTransaction(cur, req.User)
 		if _, err := lib.Store(s.skipchain, req.Election.ID, transaction, s.ServerIdentity().GetPrivate()); err != nil {
 			return nil, err
 		}
+		s.scheduleFinalize(cur)
 		return &evoting.OpenReply{ID: cur.ID, Key: cur.Key}, nil
 	}
 
@@ -219,6 +254,7 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 		req.Election.Roster = master.Roster
 		req.Election.Key = secret.X
 		req.Election.MasterKey = master.Key
+		req.Election.Commits = secret.Commits
 		req.Election.Creator = req.User
 
 		transaction := lib.NewTransaction(req.Election, req.User)
@@ -231,6 +267,7 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 		if _, err := lib.Store(s.skipchain, master.ID, transaction, s.ServerIdentity().GetPrivate()); err != nil {
 			return nil, err
 		}
+		s.scheduleFinalize(req.Election)
 
 		s.mutex.Lock()
 		s.storage.Secrets[genesis.Short()] = secret
@@ -393,13 +430,92 @@ func (s *Service) Cast(req *evoting.Cast) (*evoting.CastReply, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: %v", req.ID, req.User, err)
 	}
+	if err := s.freezeVoters(election); err != nil {
+		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: %v", req.ID, req.User, err)
+	}
 
 	transaction := lib.NewTransaction(req.Ballot, req.User)
 	skipblockID, err := lib.Store(s.skipchain, req.ID, transaction, s.ServerIdentity().GetPrivate())
 	if err != nil {
 		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: %v", req.ID, req.User, err)
 	}
-	return &evoting.CastReply{ID: skipblockID}, nil
+	return &evoting.CastReply{ID: skipblockID, Code: req.Ballot.TrackingCode()}, nil
+}
+
+// freezeVoters stores the voters of an election with an eligibility
+// expression as its list of users, once the election has started. The voter
+// rolls cannot be changed on ByzCoin afterwards, so every node verifies the
+// ballots against the same users.
+func (s *Service) freezeVoters(election *lib.Election) error {
+	if election.Eligibility == nil || election.Eligibility.Frozen ||
+		time.Now().Before(time.Unix(election.Start, 0)) {
+		return nil
+	}
+	s.freezeMutex.Lock()
+	defer s.freezeMutex.Unlock()
+
+	cur, err := lib.GetElection(s.skipchain, election.ID, false, 0)
+	if err != nil {
+		return err
+	}
+	if cur.Eligibility.Frozen {
+		*election = *cur
+		return nil
+	}
+	getDarc, err := s.darcGetter(cur.Eligibility.ByzCoinID)
+	if err != nil {
+		return err
+	}
+	voters, err := cur.Eligibility.Voters(cur.MasterKey, getDarc)
+	if err != nil {
+		return fmt.Errorf("could not resolve the voters: %v", err)
+	}
+	cur.Users = voters
+	cur.Eligibility.Frozen = true
+
+	transaction := lib.NewTransaction(cur, cur.Creator)
+	if _, err := lib.Store(s.skipchain, cur.ID, transaction, s.ServerIdentity().GetPrivate()); err != nil {
+		return err
+	}
+	*election = *cur
+	return nil
+}
+
+// isVoter checks if the user is a voter of the election, following its
+// eligibility expression if it is not frozen yet.
+func (s *Service) isVoter(election *lib.Election, user uint32) bool {
+	if election.Eligibility == nil || election.Eligibility.Frozen {
+		return election.IsUser(user)
+	}
+	getDarc, err := s.darcGetter(election.Eligibility.ByzCoinID)
+	if err != nil {
+		log.Errorf("could not get the darcs of election %x: %v", election.ID, err)
+		return false
+	}
+	return election.IsVoter(user, getDarc)
+}
+
+// darcGetter returns the function to load the latest darcs of a ByzCoin
+// chain known by this node.
+func (s *Service) darcGetter(id skipchain.SkipBlockID) (darc.GetDarc, error) {
+	st, err := s.byzcoin.GetReadOnlyStateTrie(id)
+	if err != nil {
+		return nil, err
+	}
+	return func(str string, latest bool) *darc.Darc {
+		if len(str) < 5 || string(str[0:5]) != "darc:" {
+			return nil
+		}
+		darcID, err := hex.DecodeString(str[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := st.LoadDarc(darcID)
+		if err != nil {
+			return nil
+		}
+		return d
+	}, nil
 }
 
 // GetElections message handler. Return all elections in which the given user participates.
@@ -429,7 +545,7 @@ func (s *Service) GetElections(req *evoting.GetElections) (*evoting.GetElections
 				return nil, err
 			}
 			// Check if user is a voter or election creator.
-			if election.IsUser(req.User) || election.IsCreator(req.User) {
+			if s.isVoter(election, req.User) || election.IsCreator(req.User) {
 				// Filter the election by Stage. 0 denotes no filtering.
 				if req.Stage == 0 || req.Stage == election.Stage {
 					elections = append(elections, election)
@@ -458,6 +574,21 @@ func (s *Service) GetBox(req *evoting.GetBox) (*evoting.GetBoxReply, error) {
 	return &evoting.GetBoxReply{Box: box, Election: election}, nil
 }
 
+// GetTurnout message handler to count the voters of an election, for
+// dashboards. The count can be checked against the box of the same block.
+func (s *Service) GetTurnout(req *evoting.GetTurnout) (*evoting.GetTurnoutReply, error) {
+	election, err := lib.GetElection(s.skipchain, req.ID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	turnout, err := election.Turnout(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	return &evoting.GetTurnoutReply{Turnout: turnout}, nil
+}
+
 // GetMixes message handler. It is the caller's responsibility to check the proof
 // in any Mix before relying on it.
 func (s *Service) GetMixes(req *evoting.GetMixes) (*evoting.GetMixesReply, error) {
@@ -487,10 +618,35 @@ func (s *Service) GetPartials(req *evoting.GetPartials) (*evoting.GetPartialsRep
 	return &evoting.GetPartialsReply{Partials: partials}, nil
 }
 
+// VerifyPartials message handler to check the decryption proofs of every
+// node of the election roster. Observers that don't trust this node can run
+// lib.Election.CheckPartials on the replies of GetMixes and GetPartials.
+func (s *Service) VerifyPartials(req *evoting.VerifyPartials) (*evoting.VerifyPartialsReply, error) {
+	election, err := lib.GetElection(s.skipchain, req.ID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(election.Commits) == 0 {
+		return nil, errors.New("verify error: the election has no DKG commitments")
+	}
+
+	mixes, err := election.Mixes(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	if len(mixes) == 0 {
+		return nil, errors.New("verify error: election not shuffled")
+	}
+	partials, err := election.Partials(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	checks := election.CheckPartials(partials, mixes[len(mixes)-1])
+	return &evoting.VerifyPartialsReply{Trustees: checks}, nil
+}
+
 // Shuffle message handler. Initiate shuffle protocol.
 func (s *Service) Shuffle(req *evoting.Shuffle) (*evoting.ShuffleReply, error) {
-	s.finalizeMutex.Lock()
-	defer s.finalizeMutex.Unlock()
 	if !s.leader() {
 		return nil, errOnlyLeader
 	}
@@ -504,14 +660,24 @@ func (s *Service) Shuffle(req *evoting.Shuffle) (*evoting.ShuffleReply, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := s.shuffle(election, req.User); err != nil {
+		return nil, err
+	}
+	return &evoting.ShuffleReply{}, nil
+}
+
+// shuffle runs the shuffle protocol for the election on behalf of the user,
+// until enough nodes have shuffled the ballots.
+func (s *Service) shuffle(election *lib.Election, user uint32) error {
+	defer s.lockElection(election.ID)()
 
 	// create a roster excluding nodes that have already participated
 	mixes, err := election.Mixes(s.skipchain)
 	if len(mixes) > 2*len(election.Roster.List)/3 {
-		return &evoting.ShuffleReply{}, nil
+		return nil
 	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 	participated := make(map[string]bool)
 	for _, mix := range mixes {
@@ -536,38 +702,36 @@ func (s *Service) Shuffle(req *evoting.Shuffle) (*evoting.ShuffleReply, error) {
 	rooted := onet.NewRoster(filtered)
 	tree := rooted.GenerateNaryTree(1)
 	if tree == nil {
-		return nil, errors.New("failed to generate tree")
+		return errors.New("failed to generate tree")
 	}
 
 	hasParticipated, _ := participated[election.Roster.List[0].ID.String()]
 	instance, _ := s.CreateProtocol(protocol.NameShuffle, tree)
 	protoShuffle := instance.(*protocol.Shuffle)
-	protoShuffle.User = req.User
+	protoShuffle.User = user
 	protoShuffle.Election = election
 	protoShuffle.Skipchain = s.skipchain
 	protoShuffle.LeaderParticipates = !hasParticipated
 
 	config, _ := network.Marshal(&synchronizer{
-		ID:   req.ID,
-		User: req.User,
+		ID:   election.ID,
+		User: user,
 	})
 	protoShuffle.SetConfig(&onet.GenericConfig{Data: config})
 	if err = protoShuffle.Start(); err != nil {
-		return nil, err
+		return err
 	}
 	select {
 	case err := <-protoShuffle.Finished:
-		return &evoting.ShuffleReply{}, err
+		return err
 	case <-time.After(timeout):
 		protoShuffle.HandleTerminate(protocol.MessageTerminate{})
-		return nil, errors.New("shuffle error, protocol timeout")
+		return errors.New("shuffle error, protocol timeout")
 	}
 }
 
 // Decrypt message handler. Initiate decryption protocol.
 func (s *Service) Decrypt(req *evoting.Decrypt) (*evoting.DecryptReply, error) {
-	s.finalizeMutex.Lock()
-	defer s.finalizeMutex.Unlock()
 	if !s.leader() {
 		return nil, errOnlyLeader
 	}
@@ -581,18 +745,28 @@ func (s *Service) Decrypt(req *evoting.Decrypt) (*evoting.DecryptReply, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := s.decrypt(election, req.User); err != nil {
+		return nil, err
+	}
+	return &evoting.DecryptReply{}, nil
+}
+
+// decrypt runs the decrypt protocol for the shuffled election on behalf of
+// the user.
+func (s *Service) decrypt(election *lib.Election, user uint32) error {
+	defer s.lockElection(election.ID)()
 
 	mixes, err := election.Mixes(s.skipchain)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if len(mixes) < 2*len(election.Roster.List)/3+1 {
-		return nil, errors.New("decrypt error: election not shuffled")
+		return errors.New("decrypt error: election not shuffled")
 	}
 
 	partials, err := election.Partials(s.skipchain)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	participated := make(map[string]bool)
@@ -618,31 +792,31 @@ func (s *Service) Decrypt(req *evoting.Decrypt) (*evoting.DecryptReply, error) {
 	rooted := onet.NewRoster(filtered)
 	tree := rooted.GenerateNaryTree(1)
 	if tree == nil {
-		return nil, errors.New("error while generating tree")
+		return errors.New("error while generating tree")
 	}
 
 	instance, _ := s.CreateProtocol(protocol.NameDecrypt, tree)
 	protoDecrypt := instance.(*protocol.Decrypt)
-	protoDecrypt.User = req.User
+	protoDecrypt.User = user
 	protoDecrypt.Secret = s.secret(election.ID)
 	protoDecrypt.Election = election
 	protoDecrypt.Skipchain = s.skipchain
 	protoDecrypt.LeaderParticipates = !participated[s.ServerIdentity().ID.String()]
 
 	config, _ := network.Marshal(&synchronizer{
-		ID:   req.ID,
-		User: req.User,
+		ID:   election.ID,
+		User: user,
 	})
 	protoDecrypt.SetConfig(&onet.GenericConfig{Data: config})
 	if err = protoDecrypt.Start(); err != nil {
-		return nil, err
+		return err
 	}
 	select {
 	case <-protoDecrypt.Finished:
-		return &evoting.DecryptReply{}, nil
+		return nil
 	case <-time.After(timeout):
 		protoDecrypt.HandleTerminate(protocol.MessageTerminateDecrypt{})
-		return nil, errors.New("decrypt error, protocol timeout")
+		return errors.New("decrypt error, protocol timeout")
 	}
 }
 
@@ -664,25 +838,83 @@ func (s *Service) Reconstruct(req *evoting.Reconstruct) (*evoting.ReconstructRep
 		return nil, errors.New("reconstruct error, election not closed yet")
 	}
 
-	points := make([]kyber.Point, 0)
+	points, err := election.Reconstruct(partials)
+	if err != nil {
+		return nil, err
+	}
+	box, err := election.Box(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	return &evoting.ReconstructReply{Points: points, Codes: box.Codes()}, nil
+}
 
-	n := len(election.Roster.List)
-	for i := 0; i < len(partials[0].Points); i++ {
-		shares := make([]*share.PubShare, n)
-		for _, partial := range partials {
-			j, _ := election.Roster.Search(partial.NodeID)
-			shares[j] = &share.PubShare{I: j, V: partial.Points[i]}
-		}
+// GetBundle message handler. Return all the public data of a decrypted
+// election, so that it can be verified offline.
+func (s *Service) GetBundle(req *evoting.GetBundle) (*evoting.GetBundleReply, error) {
+	election, err := lib.GetElection(s.skipchain, req.ID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	if election.Stage != lib.Decrypted {
+		return nil, errors.New("bundle error: election not decrypted yet")
+	}
+
+	box, err := election.Box(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	mixes, err := election.Mixes(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	partials, err := election.Partials(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	points, err := election.Reconstruct(partials)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := election.Blocks(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	return &evoting.GetBundleReply{Bundle: &lib.Bundle{
+		Election: election,
+		Ballots:  box.Ballots,
+		Mixes:    mixes,
+		Partials: partials,
+		Points:   points,
+		Blocks:   blocks,
+	}}, nil
+}
 
-		log.Lvl3("Recovering commits", i)
-		message, err := share.RecoverCommit(cothority.Suite, shares, 2*n/3+1, n)
+// Tally message handler. Count the reconstructed ballots of a ranked-choice
+// election with the single transferable vote. The ballots that don't decode
+// to a list of candidates are counted as invalid.
+func (s *Service) Tally(req *evoting.Tally) (*evoting.TallyReply, error) {
+	election, err := lib.GetElection(s.skipchain, req.ID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	if election.Type != lib.RankedChoice {
+		return nil, errors.New("tally error: election is not ranked-choice")
+	}
+
+	reconstructed, err := s.Reconstruct(&evoting.Reconstruct{ID: req.ID})
+	if err != nil {
+		return nil, err
+	}
+	ballots := make([][]uint32, len(reconstructed.Points))
+	for i, p := range reconstructed.Points {
+		ballots[i], err = lib.DecodeBallot(p)
 		if err != nil {
-			return nil, err
+			log.Lvl2("Invalid ballot:", err)
 		}
-		points = append(points, message)
 	}
-
-	return &evoting.ReconstructReply{Points: points}, nil
+	tally := lib.STV(election.Candidates, election.Seats, ballots)
+	return &evoting.TallyReply{Tally: tally, Codes: reconstructed.Codes}, nil
 }
 
 // NewProtocol hooks non-root nodes into created protocols.
@@ -815,6 +1047,108 @@ func (s *Service) leader() bool {
 	return s.ServerIdentity().Equal(s.storage.Roster.List[0])
 }
 
+// lockElection waits until no other shuffle or decrypt operation runs for
+// the election, and for a free worker, so that the operations of different
+// elections run in parallel up to finalizeWorkers. It returns the function
+// to release them.
+func (s *Service) lockElection(id skipchain.SkipBlockID) func() {
+	s.finalizeMutex.Lock()
+	lock, ok := s.finalizeLocks[id.Short()]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.finalizeLocks[id.Short()] = lock
+	}
+	s.finalizeMutex.Unlock()
+
+	lock.Lock()
+	s.workers <- struct{}{}
+	return func() {
+		<-s.workers
+		lock.Unlock()
+	}
+}
+
+// scheduleAll schedules the finalization of all the elections of the master
+// skipchain.
+func (s *Service) scheduleAll() {
+	s.mutex.Lock()
+	id := s.storage.Master
+	s.mutex.Unlock()
+	if id.IsNull() {
+		return
+	}
+
+	master, err := lib.GetMaster(s.skipchain, id)
+	if err != nil {
+		log.Error("cannot schedule the elections:", err)
+		return
+	}
+	links, err := master.Links(s.skipchain)
+	if err != nil {
+		log.Error("cannot schedule the elections:", err)
+		return
+	}
+	for _, l := range links {
+		election, err := lib.GetElection(s.skipchain, l.ID, false, 0)
+		if err != nil {
+			log.Errorf("cannot schedule election %x: %v", l.ID, err)
+			continue
+		}
+		s.scheduleFinalize(election)
+	}
+}
+
+// scheduleFinalize sets the timer to shuffle and decrypt the election at its
+// end, if it has AutoFinalize, replacing the previous one.
+func (s *Service) scheduleFinalize(election *lib.Election) {
+	s.finalizeMutex.Lock()
+	defer s.finalizeMutex.Unlock()
+
+	key := election.ID.Short()
+	if timer, ok := s.timers[key]; ok {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+	if !election.AutoFinalize || election.Stage == lib.Decrypted {
+		return
+	}
+	id := election.ID
+	s.timers[key] = time.AfterFunc(time.Until(time.Unix(election.End, 0)), func() {
+		s.finalize(id)
+	})
+}
+
+// finalize shuffles and decrypts the election on behalf of its creator. If
+// this fails, it tries again after finalizeRetry.
+func (s *Service) finalize(id skipchain.SkipBlockID) {
+	if !s.leader() {
+		return
+	}
+
+	election, err := lib.GetElection(s.skipchain, id, false, 0)
+	if err == nil && election.Stage == lib.Running {
+		log.Lvlf2("Shuffling election %x", id)
+		if err = s.shuffle(election, election.Creator); err == nil {
+			election, err = lib.GetElection(s.skipchain, id, false, 0)
+		}
+	}
+	if err == nil && election.Stage == lib.Shuffled {
+		log.Lvlf2("Decrypting election %x", id)
+		err = s.decrypt(election, election.Creator)
+	}
+
+	s.finalizeMutex.Lock()
+	defer s.finalizeMutex.Unlock()
+	if err != nil {
+		log.Errorf("cannot finalize election %x: %v", id, err)
+		s.timers[id.Short()] = time.AfterFunc(finalizeRetry, func() {
+			s.finalize(id)
+		})
+		return
+	}
+	delete(s.timers, id.Short())
+}
+
 // secret returns the shared secret for a given election.
 func (s *Service) secret(id skipchain.SkipBlockID) *lib.SharedSecret {
 	s.mutex.Lock()
@@ -827,7 +1161,7 @@ func (s *Service) secret(id skipchain.SkipBlockID) *lib.SharedSecret {
 func (s *Service) save() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if err := s.Save(storageKey, s.storage); err != nil {
+	if err := atrest.Save(s, evoting.ServiceName, storageKey, s.storage); err != nil {
 		log.Error(err)
 	}
 	if err := s.SaveVersion(dbVersion); err != nil {
@@ -840,7 +1174,7 @@ func (s *Service) load() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	blob, err := s.Load(storageKey)
+	blob, err := atrest.Load(s, evoting.ServiceName, storageKey)
 	if err != nil {
 		return err
 	} else if blob == nil {
@@ -874,6 +1208,11 @@ func new(context *onet.Context) (onet.Service, error) {
 			Secrets: make(map[string]*lib.SharedSecret),
 		},
 		skipchain: context.Service(skipchain.ServiceName).(*skipchain.Service),
+		byzcoin:   context.Service(byzcoin.ServiceName).(*byzcoin.Service),
+
+		finalizeLocks: make(map[string]*sync.Mutex),
+		workers:       make(chan struct{}, finalizeWorkers),
+		timers:        make(map[string]*time.Timer),
 	}
 
 	service.RegisterHandlers(
@@ -883,11 +1222,15 @@ func new(context *onet.Context) (onet.Service, error) {
 		service.Cast,
 		service.GetElections,
 		service.GetBox,
+		service.GetTurnout,
 		service.GetMixes,
 		service.Shuffle,
 		service.GetPartials,
+		service.VerifyPartials,
 		service.Decrypt,
 		service.Reconstruct,
+		service.Tally,
+		service.GetBundle,
 		service.LookupSciper,
 	)
 	skipchain.RegisterVerification(context, lib.TransactionVerifierID, service.verify)
@@ -899,6 +1242,7 @@ func new(context *onet.Context) (onet.Service, error) {
 	if err := service.load(); err != nil {
 		return nil, err
 	}
+	service.scheduleAll()
 
 	log.Lvl1("Pin:", service.pin)
 	return service, nil
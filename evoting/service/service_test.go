@@ -3,6 +3,7 @@ package service
 import (
 	"flag"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -98,6 +99,7 @@ func TestService(t *testing.T) {
 		Roster:  roster,
 		Start:   yesterday.Unix(),
 		End:     tomorrow.Unix(),
+		Groups:  []*lib.Group{{Name: "staff", Users: []uint32{idUser1, idAdmin}}},
 	}
 
 	// Try to create a new election on server[1], should fail.
@@ -242,10 +244,19 @@ func TestService(t *testing.T) {
 	}
 	// User votes
 	log.Lvl1("Casting votes for correct users")
-	vote(idUser1, bufCand1)
+	code := vote(idUser1, bufCand1).Code
 	vote(idUser2, bufCand1)
 	vote(idUser3, bufCand2)
 
+	// The turnout matches the box.
+	turnout, err := s0.GetTurnout(&evoting.GetTurnout{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.Equal(t, 3, turnout.Turnout.Voters)
+	require.Equal(t, &lib.GroupTurnout{Name: "staff", Voters: 1, Users: 2}, turnout.Turnout.Groups[0])
+	box, err = s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.NoError(t, turnout.Turnout.Verify(box.Election, box.Box))
+
 	// Try to decrypt before shuffling; will fail
 	_, err = s0.Decrypt(&evoting.Decrypt{
 		ID:        replyOpen.ID,
@@ -301,6 +312,8 @@ func TestService(t *testing.T) {
 		ID: replyOpen.ID,
 	})
 	require.NoError(t, err)
+	require.Len(t, reconstructReply.Codes, 3)
+	require.Contains(t, reconstructReply.Codes, code)
 
 	for _, p := range reconstructReply.Points {
 		log.Lvl2("Point is:", p.String())
@@ -1118,3 +1131,172 @@ func TestLookupSciper(t *testing.T) {
 	require.Equal(t, reply.FullName, "Bryan Alexander Ford")
 	require.Equal(t, reply.Email, "bryan.ford@epfl.ch")
 }
+
+func TestTally(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodeKP := key.NewKeyPair(cothority.Suite)
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	// Set a lower timeout for the tests
+	sc0.SetPropTimeout(defaultTimeout)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:    s0.pin,
+		Roster: roster,
+		Key:    nodeKP.Public,
+		Admins: []uint32{idAdmin},
+	})
+	require.NoError(t, err)
+	idAdminSig := generateSignature(nodeKP.Private, replyLink.ID, idAdmin)
+
+	elec := &lib.Election{
+		Creator:    idAdmin,
+		Users:      []uint32{idUser1, idUser2, idUser3, idAdmin},
+		Candidates: []uint32{idCand1, idCand2},
+		MaxChoices: 10,
+		Type:       lib.RankedChoice,
+		Start:      yesterday.Unix(),
+		End:        tomorrow.Unix(),
+	}
+	// A ranked ballot cannot hold 10 candidates.
+	_, err = s0.Open(&evoting.Open{ID: replyLink.ID, Election: elec, User: idAdmin, Signature: idAdminSig})
+	require.Error(t, err)
+	elec.MaxChoices = 2
+	replyOpen, err := s0.Open(&evoting.Open{ID: replyLink.ID, Election: elec, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+
+	vote := func(user uint32, bufCand []byte) {
+		k, c := lib.Encrypt(replyOpen.Key, bufCand)
+		_, err := s0.Cast(&evoting.Cast{
+			ID:        replyOpen.ID,
+			Ballot:    &lib.Ballot{User: user, Alpha: k, Beta: c},
+			User:      user,
+			Signature: generateSignature(nodeKP.Private, replyLink.ID, user),
+		})
+		require.NoError(t, err)
+		require.Nil(t, local.WaitDone(time.Second))
+	}
+	vote(idUser1, append(bufCand2, bufCand1...))
+	vote(idUser2, append(bufCand1, bufCand2...))
+	vote(idUser3, bufCand1)
+
+	_, err = s0.Tally(&evoting.Tally{ID: replyOpen.ID})
+	require.Error(t, err)
+
+	_, err = s0.Shuffle(&evoting.Shuffle{ID: replyOpen.ID, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+	require.Nil(t, local.WaitDone(time.Second))
+	_, err = s0.Decrypt(&evoting.Decrypt{ID: replyOpen.ID, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+	require.Nil(t, local.WaitDone(defaultTimeout))
+
+	reply, err := s0.Tally(&evoting.Tally{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.Equal(t, []uint32{idCand1}, reply.Tally.Elected)
+	require.Equal(t, 1, len(reply.Tally.Rounds))
+	require.Equal(t, []int64{2 * lib.VoteUnit, lib.VoteUnit}, reply.Tally.Rounds[0].Counts)
+
+	bundle, err := s0.GetBundle(&evoting.GetBundle{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.NoError(t, bundle.Bundle.Verify())
+	require.Equal(t, 3, len(bundle.Bundle.Points))
+
+	verified, err := s0.VerifyPartials(&evoting.VerifyPartials{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.Equal(t, len(roster.List), len(verified.Trustees))
+	for _, c := range verified.Trustees {
+		require.True(t, c.Valid, c.Error)
+	}
+}
+
+func TestLockElection(t *testing.T) {
+	s := &Service{finalizeLocks: make(map[string]*sync.Mutex), workers: make(chan struct{}, 2)}
+	locked := func(id skipchain.SkipBlockID) <-chan func() {
+		ch := make(chan func(), 1)
+		go func() { ch <- s.lockElection(id) }()
+		return ch
+	}
+
+	// Different elections run in parallel.
+	unlockA := <-locked(skipchain.SkipBlockID{1})
+	unlockB := <-locked(skipchain.SkipBlockID{2})
+
+	// The same election waits for the first operation.
+	waitA := locked(skipchain.SkipBlockID{1})
+	// No worker is left for a third election.
+	waitC := locked(skipchain.SkipBlockID{3})
+	select {
+	case <-waitA:
+		t.Fatal("locked the same election twice")
+	case <-waitC:
+		t.Fatal("used more workers than available")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlockB()
+	unlockC := <-waitC
+	unlockA()
+	(<-waitA)()
+	unlockC()
+}
+
+func TestAutoFinalize(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodeKP := key.NewKeyPair(cothority.Suite)
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	// Set a lower timeout for the tests
+	sc0.SetPropTimeout(defaultTimeout)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:    s0.pin,
+		Roster: roster,
+		Key:    nodeKP.Public,
+		Admins: []uint32{idAdmin},
+	})
+	require.NoError(t, err)
+	idAdminSig := generateSignature(nodeKP.Private, replyLink.ID, idAdmin)
+
+	elec := &lib.Election{
+		Creator:      idAdmin,
+		Users:        []uint32{idUser1, idUser2},
+		Start:        yesterday.Unix(),
+		End:          time.Now().Add(5 * time.Second).Unix(),
+		AutoFinalize: true,
+	}
+	replyOpen, err := s0.Open(&evoting.Open{ID: replyLink.ID, Election: elec, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+
+	k, c := lib.Encrypt(replyOpen.Key, bufCand1)
+	_, err = s0.Cast(&evoting.Cast{
+		ID:        replyOpen.ID,
+		Ballot:    &lib.Ballot{User: idUser1, Alpha: k, Beta: c},
+		User:      idUser1,
+		Signature: generateSignature(nodeKP.Private, replyLink.ID, idUser1),
+	})
+	require.NoError(t, err)
+
+	// The leader shuffles and decrypts the election once it is closed.
+	for i := 0; ; i++ {
+		box, err := s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+		require.NoError(t, err)
+		if box.Election.Stage == lib.Decrypted {
+			break
+		}
+		require.True(t, i < 60, "election not decrypted")
+		time.Sleep(time.Second)
+	}
+	require.Nil(t, local.WaitDone(defaultTimeout))
+
+	reply, err := s0.Reconstruct(&evoting.Reconstruct{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.Points))
+}
@@ -21,7 +21,11 @@ func init() {
 	network.RegisterMessages(GetBox{}, GetBoxReply{})
 	network.RegisterMessages(GetMixes{}, GetMixesReply{})
 	network.RegisterMessages(GetPartials{}, GetPartialsReply{})
+	network.RegisterMessages(VerifyPartials{}, VerifyPartialsReply{})
 	network.RegisterMessages(Reconstruct{}, ReconstructReply{})
+	network.RegisterMessages(Tally{}, TallyReply{})
+	network.RegisterMessages(GetTurnout{}, GetTurnoutReply{})
+	network.RegisterMessages(GetBundle{}, GetBundleReply{})
 }
 
 // LookupSciper takes a SCIPER number and looks up the full name.
@@ -81,7 +85,8 @@ type Cast struct {
 
 // CastReply message.
 type CastReply struct {
-	ID skipchain.SkipBlockID // Hash of the block storing the transaction
+	ID   skipchain.SkipBlockID // Hash of the block storing the transaction
+	Code string                // Code is the tracking code of the ballot.
 }
 
 // Shuffle message.
@@ -133,6 +138,16 @@ type GetBoxReply struct {
 	Election *lib.Election // The current config of the election.
 }
 
+// GetTurnout message.
+type GetTurnout struct {
+	ID skipchain.SkipBlockID // ID of the election skipchain.
+}
+
+// GetTurnoutReply message.
+type GetTurnoutReply struct {
+	Turnout *lib.Turnout // Turnout of the election at its latest block.
+}
+
 // GetMixes message.
 type GetMixes struct {
 	ID skipchain.SkipBlockID // ID of the election skipchain.
@@ -153,6 +168,16 @@ type GetPartialsReply struct {
 	Partials []*lib.Partial // Partials from all conodes.
 }
 
+// VerifyPartials message.
+type VerifyPartials struct {
+	ID skipchain.SkipBlockID // ID of the election skipchain.
+}
+
+// VerifyPartialsReply message.
+type VerifyPartialsReply struct {
+	Trustees []*lib.TrusteeCheck // Trustees holds the check of each node of the roster.
+}
+
 // Reconstruct message.
 type Reconstruct struct {
 	ID skipchain.SkipBlockID // ID of the election skipchain.
@@ -161,6 +186,28 @@ type Reconstruct struct {
 // ReconstructReply message.
 type ReconstructReply struct {
 	Points []kyber.Point // Points are the decrypted plaintexts.
+	Codes  []string      // Codes are the tracking codes of the counted ballots.
+}
+
+// Tally message.
+type Tally struct {
+	ID skipchain.SkipBlockID // ID of the election skipchain.
+}
+
+// TallyReply message.
+type TallyReply struct {
+	Tally *lib.Tally // Tally holds all the rounds of the count.
+	Codes []string   // Codes are the tracking codes of the counted ballots.
+}
+
+// GetBundle message.
+type GetBundle struct {
+	ID skipchain.SkipBlockID // ID of the election skipchain.
+}
+
+// GetBundleReply message.
+type GetBundleReply struct {
+	Bundle *lib.Bundle // Bundle holds the proofs of the election.
 }
 
 // Ping message.
@@ -10,6 +10,15 @@ import (
 	"go.dedis.ch/onet/v3/network"
 )
 
+// signingKeys returns the key pair the node signs with: the given key if
+// it is set, else the key pair of the node.
+func signingKeys(s cosi.Suite, n *onet.TreeNodeInstance, key kyber.Scalar) (kyber.Scalar, kyber.Point) {
+	if key == nil {
+		return n.Private(), n.Public()
+	}
+	return key, s.Point().Mul(key, nil)
+}
+
 // aggregateCommitments returns an aggregated commitment and an aggregated mask
 func aggregateCommitments(s cosi.Suite, publics []kyber.Point,
 	structCommitments []StructCommitment) (kyber.Point, *cosi.Mask, error) {
@@ -0,0 +1,19 @@
+package protocol
+
+import (
+	"time"
+
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Metrics is notified by the root of the latencies measured in the tree. It
+// has the same methods as the one of blscosi so that the same implementation,
+// like blscosi's LatencyRecorder, can be used for both protocols.
+type Metrics interface {
+	// NodeLatency is called with the time a subleader (level 1) took to
+	// send its commitment and its response, including the time it waited
+	// for its leaves.
+	NodeLatency(si *network.ServerIdentity, level int, latency time.Duration)
+	// LevelLatency is called with the latency of the slowest subleader.
+	LevelLatency(level int, latency time.Duration)
+}
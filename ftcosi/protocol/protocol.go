@@ -55,8 +55,15 @@ type FtCosi struct {
 	Timeout        time.Duration
 	Threshold      int
 	FinalSignature chan []byte
-
-	publics         []kyber.Point
+	// Metrics, if not nil, is given the latencies of the subleaders.
+	Metrics Metrics
+	// Publics are the keys the nodes sign with, in the order of the roster.
+	Publics []kyber.Point
+	// SigningKey, if not nil, is the key the node signs with instead of
+	// its private key. Publics must then hold the matching keys.
+	SigningKey kyber.Scalar
+
+	commitLatencies map[*SubFtCosi]time.Duration
 	stoppedOnce     sync.Once
 	subProtocols    []*SubFtCosi
 	startChan       chan bool
@@ -83,7 +90,8 @@ func NewFtCosi(n *onet.TreeNodeInstance, vf VerificationFn, subProtocolName stri
 		TreeNodeInstance: n,
 		FinalSignature:   make(chan []byte, 1),
 		Data:             make([]byte, 0),
-		publics:          n.Roster().Publics(),
+		Publics:          n.Roster().Publics(),
+		commitLatencies:  make(map[*SubFtCosi]time.Duration),
 		startChan:        make(chan bool, 1),
 		verificationFn:   vf,
 		subProtocolName:  subProtocolName,
@@ -179,7 +187,8 @@ func (p *FtCosi) Dispatch() error {
 	// add own commitment
 	var personalCommitment kyber.Point
 	secret, personalCommitment = cosi.Commit(p.suite)
-	personalMask, err := cosi.NewMask(p.suite, p.publics, p.Public())
+	private, public := signingKeys(p.suite, p.TreeNodeInstance, p.SigningKey)
+	personalMask, err := cosi.NewMask(p.suite, p.Publics, public)
 	if err != nil {
 		p.FinalSignature <- nil
 		return err
@@ -189,7 +198,7 @@ func (p *FtCosi) Dispatch() error {
 	commitments = append(commitments, personalStructCommitment)
 
 	// generate own aggregated commitment
-	commitment, finalMask, err := aggregateCommitments(p.suite, p.publics, commitments)
+	commitment, finalMask, err := aggregateCommitments(p.suite, p.Publics, commitments)
 	if err != nil {
 		p.FinalSignature <- nil
 		return err
@@ -203,6 +212,7 @@ func (p *FtCosi) Dispatch() error {
 	}
 
 	// send challenge to every subprotocol
+	challengeSent := time.Now()
 	for _, coSiProtocol := range runningSubProtocols {
 		subProtocol := coSiProtocol
 		subProtocol.ChannelChallenge <- StructChallenge{coSiProtocol.Root(), Challenge{
@@ -217,6 +227,7 @@ func (p *FtCosi) Dispatch() error {
 	errChan := make(chan error, len(runningSubProtocols))
 	var responsesMut sync.Mutex
 	var responsesWg sync.WaitGroup
+	var slowest time.Duration
 	responsesWg.Add(len(runningSubProtocols))
 	for i, cosiSubProtocol := range runningSubProtocols {
 		go func(i int, subProto *SubFtCosi) {
@@ -225,6 +236,13 @@ func (p *FtCosi) Dispatch() error {
 			case response := <-subProto.subResponse:
 				responsesMut.Lock()
 				responses = append(responses, response)
+				if p.Metrics != nil {
+					latency := p.commitLatencies[subProto] + time.Since(challengeSent)
+					p.Metrics.NodeLatency(subProto.Root().Children[0].ServerIdentity, 1, latency)
+					if latency > slowest {
+						slowest = latency
+					}
+				}
 				responsesMut.Unlock()
 			case <-time.After(p.Timeout):
 				// This should never happen, as the subProto should return before that
@@ -234,6 +252,9 @@ func (p *FtCosi) Dispatch() error {
 		}(i, cosiSubProtocol)
 	}
 	responsesWg.Wait()
+	if p.Metrics != nil && len(runningSubProtocols) > 0 {
+		p.Metrics.LevelLatency(1, slowest)
+	}
 
 	// check errors if any
 	close(errChan)
@@ -247,7 +268,7 @@ func (p *FtCosi) Dispatch() error {
 	}
 
 	// generate own response
-	personalResponse, err := cosi.Response(p.suite, p.Private(), secret, cosiChallenge)
+	personalResponse, err := cosi.Response(p.suite, private, secret, cosiChallenge)
 	if err != nil {
 		p.FinalSignature <- nil
 		return fmt.Errorf("error while generating own response: %s", err)
@@ -332,7 +353,7 @@ func (p *FtCosi) startSubProtocol(tree *onet.Tree) (*SubFtCosi, error) {
 	}
 
 	cosiSubProtocol := pi.(*SubFtCosi)
-	cosiSubProtocol.Publics = p.publics
+	cosiSubProtocol.Publics = p.Publics
 	cosiSubProtocol.Msg = p.Msg
 	cosiSubProtocol.Data = p.Data
 	// We allow for one subleader failure during the commit phase, and thus
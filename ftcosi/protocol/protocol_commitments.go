@@ -18,6 +18,7 @@ func (p *FtCosi) collectCommitments(trees []*onet.Tree,
 	type commitmentProtocol struct {
 		structCommitment StructCommitment
 		subProtocol      *SubFtCosi
+		latency          time.Duration
 	}
 
 	commitmentsChan := make(chan commitmentProtocol, 2*len(subProtocols))
@@ -31,6 +32,7 @@ func (p *FtCosi) collectCommitments(trees []*onet.Tree,
 		go func(i int, subProtocol *SubFtCosi) {
 			defer closingWg.Done()
 			timeout := time.After(p.Timeout / 2)
+			started := time.Now()
 			for {
 				select {
 				case <-closingChan:
@@ -68,8 +70,9 @@ func (p *FtCosi) collectCommitments(trees []*onet.Tree,
 						return
 					}
 					subProtocols[i] = subProtocol
+					started = time.Now()
 				case com := <-subProtocol.subCommitment:
-					commitmentsChan <- commitmentProtocol{com, subProtocol}
+					commitmentsChan <- commitmentProtocol{com, subProtocol, time.Since(started)}
 					timeout = make(chan time.Time) // deactivate timeout
 				case <-timeout:
 					errChan <- fmt.Errorf("(subprotocol %v) didn't get commitment after timeout %v", i, p.Timeout)
@@ -80,7 +83,7 @@ func (p *FtCosi) collectCommitments(trees []*onet.Tree,
 	}
 
 	// handle answers from all parallel threads
-	sharedMask, err := cosi.NewMask(p.suite, p.publics, nil)
+	sharedMask, err := cosi.NewMask(p.suite, p.Publics, nil)
 	if err != nil {
 		close(closingChan)
 		return nil, nil, err
@@ -96,9 +99,10 @@ func (p *FtCosi) collectCommitments(trees []*onet.Tree,
 				// If there is a commitment, add to map.
 				// This assumes that the last commit of a subtree is the biggest one.
 				commitmentsMap[com.subProtocol] = com.structCommitment
+				p.commitLatencies[com.subProtocol] = com.latency
 
 				// check if threshold is reachable
-				if sumRefusals(commitmentsMap) > len(p.publics)-p.Threshold {
+				if sumRefusals(commitmentsMap) > len(p.Publics)-p.Threshold {
 					// we assume the root accepts the proposal
 					thresholdReachable = false
 				}
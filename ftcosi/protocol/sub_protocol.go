@@ -16,7 +16,10 @@ import (
 // SubFtCosi holds the different channels used to receive the different protocol messages.
 type SubFtCosi struct {
 	*onet.TreeNodeInstance
-	Publics        []kyber.Point
+	Publics []kyber.Point
+	// SigningKey, if not nil, is the key the node signs with instead of
+	// its private key. Publics must then hold the matching keys.
+	SigningKey     kyber.Scalar
 	Msg            []byte
 	Data           []byte
 	Timeout        time.Duration
@@ -407,12 +410,13 @@ loop:
 	}
 
 	// add own response if in mask
-	isInMask, err := challengeMask.KeyEnabled(p.Public())
+	private, public := signingKeys(p.suite, p.TreeNodeInstance, p.SigningKey)
+	isInMask, err := challengeMask.KeyEnabled(public)
 	if err != nil {
 		return fmt.Errorf("error in checking a key presence in the challenge mask: %s", err)
 	}
 	if isInMask {
-		personalResponse, err := cosi.Response(p.suite, p.Private(), secret, challenge.CoSiChallenge)
+		personalResponse, err := cosi.Response(p.suite, private, secret, challenge.CoSiChallenge)
 		if err != nil {
 			return fmt.Errorf("error while generating own response: %s", err)
 		}
@@ -518,7 +522,8 @@ func (p *SubFtCosi) getCommitment(accepts bool) (kyber.Scalar, StructCommitment,
 	if accepts {
 		secret, structCommitment.CoSiCommitment = cosi.Commit(p.suite)
 		var personalMask *cosi.Mask
-		personalMask, err = cosi.NewMask(p.suite, p.Publics, p.Public())
+		_, public := signingKeys(p.suite, p.TreeNodeInstance, p.SigningKey)
+		personalMask, err = cosi.NewMask(p.suite, p.Publics, public)
 		if err != nil {
 			return secret, StructCommitment{}, err
 		}
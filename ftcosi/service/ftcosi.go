@@ -5,9 +5,11 @@ package service
 import (
 	"errors"
 	"math"
+	"net/http"
 	"time"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/cothority/v3/ftcosi/protocol"
 	"go.dedis.ch/kyber/v3/sign/cosi"
 	"go.dedis.ch/onet/v3"
@@ -35,6 +37,13 @@ type Service struct {
 	suite cosi.Suite
 }
 
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
 // SignatureRequest is what the Cosi service is expected to receive from clients.
 type SignatureRequest struct {
 	Message []byte
@@ -0,0 +1,146 @@
+package fuzz
+
+import (
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso/protocol"
+	"go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	dkgpedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// Corpus returns valid inputs for every target, to start the fuzzing from.
+func Corpus() (map[string][][]byte, error) {
+	msgs, err := corpusMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	corpus := make(map[string][][]byte)
+	add := func(target string, msg interface{}) error {
+		buf, err := protobuf.Encode(msg)
+		if err != nil {
+			return fmt.Errorf("couldn't encode %T: %v", msg, err)
+		}
+		corpus[target] = append(corpus[target], buf)
+		return nil
+	}
+	for _, msg := range msgs {
+		var target string
+		switch msg.(type) {
+		case *skipchain.SkipBlock:
+			target = "SkipBlock"
+		case *byzcoin.ClientTransaction:
+			target = "ClientTransaction"
+		case *byzcoin.DataHeader:
+			target = "BlockHeader"
+		case *byzcoin.DataBody:
+			target = "BlockBody"
+		case *protocol.Reencrypt, *protocol.ReencryptReply:
+			target = "OCS"
+		default:
+			target = "DKG"
+		}
+		if err := add(target, msg); err != nil {
+			return nil, err
+		}
+
+		buf, err := network.Marshal(msg)
+		if err != nil {
+			// Only the registered messages are sent by themselves.
+			continue
+		}
+		corpus["Message"] = append(corpus["Message"], buf)
+	}
+	return corpus, nil
+}
+
+// corpusMessages returns a valid message of every type of the targets.
+func corpusMessages() ([]interface{}, error) {
+	kps := make([]*key.Pair, 3)
+	publics := make([]kyber.Point, len(kps))
+	sis := make([]*network.ServerIdentity, len(kps))
+	for i := range kps {
+		kps[i] = key.NewKeyPair(cothority.Suite)
+		publics[i] = kps[i].Public
+		sis[i] = network.NewServerIdentity(kps[i].Public,
+			network.NewAddress(network.TLS, fmt.Sprintf("127.0.0.1:%d",
+				7770+2*i)))
+	}
+
+	sb := skipchain.NewSkipBlock()
+	sb.Roster = onet.NewRoster(sis)
+	sb.Data = []byte("data")
+	sb.BackLinkIDs = []skipchain.SkipBlockID{make([]byte, 32)}
+	sb.Hash = sb.CalculateHash()
+
+	ctx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion,
+		byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID([]byte("instance")),
+			Invoke: &byzcoin.Invoke{
+				ContractID: "value",
+				Command:    "update",
+				Args: byzcoin.Arguments{{Name: "value",
+					Value: []byte("value")}},
+			},
+			SignerCounter: []uint64{1},
+		})
+	header := &byzcoin.DataHeader{
+		TrieRoot:              make([]byte, 32),
+		ClientTransactionHash: make([]byte, 32),
+		StateChangesHash:      make([]byte, 32),
+		Timestamp:             1,
+		Version:               byzcoin.CurrentVersion,
+	}
+	body := &byzcoin.DataBody{TxResults: byzcoin.TxResults{
+		{ClientTransaction: ctx, Accepted: true},
+	}}
+
+	verification := []byte("verification")
+	reencrypt := &protocol.Reencrypt{
+		U:                publics[0],
+		Xc:               publics[1],
+		VerificationData: &verification,
+	}
+	reencryptReply := &protocol.ReencryptReply{
+		Ui: &share.PubShare{I: 1, V: publics[2]},
+		Ei: cothority.Suite.Scalar().One(),
+		Fi: cothority.Suite.Scalar().One(),
+	}
+
+	dkgs := make([]*dkgpedersen.DistKeyGenerator, len(kps))
+	for i, kp := range kps {
+		var err error
+		dkgs[i], err = dkgpedersen.NewDistKeyGenerator(cothority.Suite,
+			kp.Private, publics, 2)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create the DKG: %v", err)
+		}
+	}
+	deals, err := dkgs[0].Deals()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the deals: %v", err)
+	}
+	resp, err := dkgs[1].ProcessDeal(deals[1])
+	if err != nil {
+		return nil, fmt.Errorf("couldn't process the deal: %v", err)
+	}
+
+	return []interface{}{
+		sb, &ctx, header, body,
+		reencrypt, reencryptReply,
+		&pedersen.Init{Wait: true, InstanceID: []byte("instance")},
+		&pedersen.InitReply{Public: publics[0]},
+		&pedersen.StartDeal{Publics: publics, Threshold: 2},
+		&pedersen.Deal{Deal: deals[1]},
+		&pedersen.Response{Response: resp},
+	}, nil
+}
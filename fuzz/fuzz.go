@@ -0,0 +1,100 @@
+// Package fuzz holds the fuzzing targets of the decoding of the messages the
+// conodes receive from the network: every registered network message, and
+// the skipblocks, client transactions, OCS and DKG messages on their own.
+//
+// The targets call the decoders of protobuf and onet directly, without the
+// size limit and the recover of the decode package, so that the fuzzer sees
+// their panics. They have the signature of go-fuzz, and return 1 for the
+// inputs that decode, so that the fuzzer prefers them:
+//
+//	go-fuzz-build -func SkipBlock go.dedis.ch/cothority/v3/fuzz
+//	go-fuzz -bin fuzz-fuzz.zip -workdir skipblock
+//
+// With Go 1.18 or later, they also run with the native fuzzing:
+//
+//	go test -fuzz FuzzSkipBlock ./fuzz
+package fuzz
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso/protocol"
+	"go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+
+	// Register the messages of the other services.
+	_ "go.dedis.ch/cothority/v3/calypso"
+	_ "go.dedis.ch/cothority/v3/eventlog"
+	_ "go.dedis.ch/cothority/v3/personhood"
+	_ "go.dedis.ch/cothority/v3/status/service"
+)
+
+// Targets are the fuzzing targets by name.
+var Targets = map[string]func([]byte) int{
+	"Message":           Message,
+	"SkipBlock":         SkipBlock,
+	"ClientTransaction": ClientTransaction,
+	"BlockHeader":       BlockHeader,
+	"BlockBody":         BlockBody,
+	"OCS":               OCS,
+	"DKG":               DKG,
+}
+
+// Message decodes any registered network message, as onet does for every
+// packet.
+func Message(data []byte) int {
+	_, msg, err := network.Unmarshal(data, cothority.Suite)
+	if err != nil || msg == nil {
+		return 0
+	}
+	return 1
+}
+
+// SkipBlock decodes a skipblock, as sent by the other nodes of a roster.
+func SkipBlock(data []byte) int {
+	return decodes(data, &skipchain.SkipBlock{})
+}
+
+// ClientTransaction decodes a transaction, as sent by the clients of
+// ByzCoin.
+func ClientTransaction(data []byte) int {
+	return decodes(data, &byzcoin.ClientTransaction{})
+}
+
+// BlockHeader decodes the data of a ByzCoin block.
+func BlockHeader(data []byte) int {
+	return decodes(data, &byzcoin.DataHeader{})
+}
+
+// BlockBody decodes the payload of a ByzCoin block.
+func BlockBody(data []byte) int {
+	return decodes(data, &byzcoin.DataBody{})
+}
+
+// OCS decodes the messages of the re-encryption protocol.
+func OCS(data []byte) int {
+	return decodes(data, &protocol.Reencrypt{}) |
+		decodes(data, &protocol.ReencryptReply{})
+}
+
+// DKG decodes the messages of the distributed key generation.
+func DKG(data []byte) int {
+	return decodes(data, &pedersen.Init{}) |
+		decodes(data, &pedersen.InitReply{}) |
+		decodes(data, &pedersen.StartDeal{}) |
+		decodes(data, &pedersen.Deal{}) |
+		decodes(data, &pedersen.Response{}) |
+		decodes(data, &pedersen.Justification{})
+}
+
+// decodes returns 1 if the data decodes into the structure.
+func decodes(data []byte, structPtr interface{}) int {
+	err := protobuf.DecodeWithConstructors(data, structPtr,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return 0
+	}
+	return 1
+}
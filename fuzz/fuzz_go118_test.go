@@ -0,0 +1,36 @@
+//go:build go1.18
+// +build go1.18
+
+package fuzz
+
+import (
+	"testing"
+)
+
+func fuzzTarget(f *testing.F, name string) {
+	corpus, err := Corpus()
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, seed := range corpus[name] {
+		f.Add(seed)
+	}
+	target := Targets[name]
+	f.Fuzz(func(t *testing.T, data []byte) {
+		target(data)
+	})
+}
+
+func FuzzMessage(f *testing.F) { fuzzTarget(f, "Message") }
+
+func FuzzSkipBlock(f *testing.F) { fuzzTarget(f, "SkipBlock") }
+
+func FuzzClientTransaction(f *testing.F) { fuzzTarget(f, "ClientTransaction") }
+
+func FuzzBlockHeader(f *testing.F) { fuzzTarget(f, "BlockHeader") }
+
+func FuzzBlockBody(f *testing.F) { fuzzTarget(f, "BlockBody") }
+
+func FuzzOCS(f *testing.F) { fuzzTarget(f, "OCS") }
+
+func FuzzDKG(f *testing.F) { fuzzTarget(f, "DKG") }
@@ -0,0 +1,63 @@
+package fuzz
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// corpusEnv is the directory where TestCorpus writes the corpus, in the
+// layout of go-fuzz.
+const corpusEnv = "FUZZ_CORPUS"
+
+// TestTargets runs the targets on the corpus and on truncated and mutated
+// copies of it, which must not panic.
+func TestTargets(t *testing.T) {
+	corpus, err := Corpus()
+	require.NoError(t, err)
+
+	rnd := rand.New(rand.NewSource(1))
+	for name, target := range Targets {
+		require.NotEmpty(t, corpus[name], name)
+		for _, seed := range corpus[name] {
+			require.Equal(t, 1, target(seed), name)
+
+			for i := range seed {
+				target(seed[:i])
+			}
+			for i := 0; i < 1000; i++ {
+				mutated := append([]byte{}, seed...)
+				for j := 0; j < 1+rnd.Intn(4); j++ {
+					mutated[rnd.Intn(len(mutated))] = byte(rnd.Intn(256))
+				}
+				target(mutated)
+			}
+		}
+	}
+}
+
+// TestCorpus writes the corpus to the directory given by FUZZ_CORPUS, with
+// a workdir per target, to start go-fuzz from it.
+func TestCorpus(t *testing.T) {
+	dir := os.Getenv(corpusEnv)
+	if dir == "" {
+		t.Skip("set " + corpusEnv + " to write the corpus")
+	}
+	corpus, err := Corpus()
+	require.NoError(t, err)
+
+	for name, seeds := range corpus {
+		cd := filepath.Join(dir, name, "corpus")
+		require.NoError(t, os.MkdirAll(cd, 0755))
+		for _, seed := range seeds {
+			fn := filepath.Join(cd, fmt.Sprintf("%x", sha256.Sum256(seed)))
+			require.NoError(t, ioutil.WriteFile(fn, seed, 0644))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package keyrotation
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Client sends the transitions to the KeyRotation service of a conode.
+type Client struct {
+	*onet.Client
+}
+
+// NewClient returns a client for the KeyRotation service.
+func NewClient() *Client {
+	return &Client{Client: onet.NewClient(cothority.Suite, ServiceName)}
+}
+
+// Rotate gives the transition of its key to the conode, which must still
+// run with the old key.
+func (c *Client) Rotate(dst *network.ServerIdentity, t *Transition) (
+	*RotateReply, error) {
+	reply := &RotateReply{}
+	err := c.SendProtobuf(dst, &Rotate{Transition: *t}, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
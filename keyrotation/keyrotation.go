@@ -0,0 +1,232 @@
+// Package keyrotation lets a conode replace its identity key by a new one
+// without leaving the rosters it is part of.
+//
+// The conode signs a Transition from its old key to its new key with both
+// keys, and gives it to its KeyRotation service while it still runs with the
+// old key. The service asks every service implementing Rotator to replace
+// the old key in its rosters, and announces the transition to the nodes of
+// these rosters, so that they update their copies too:
+//
+//   - ByzCoin replaces the key in the roster of the ledgers with a
+//     config.rotate_key instruction
+//   - Skipchain adds a block with the new roster to the other skipchains
+//     led by the nodes
+//   - Calypso replaces the key in the roster of the LTSs, and the DKG service
+//     in the rosters of its keys
+//
+// The leader of a chain can't rotate its own key, as it would have to sign
+// the new block with it: another node has to become the leader first.
+//
+// The conode then restarts with the new key. Until the end of the grace
+// window of the transition, what was signed by the old key, like the API
+// tokens, is still accepted: Keys returns both.
+package keyrotation
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// signatureTag separates the signatures of the transitions from the other
+// signatures of the keys of the conodes.
+const signatureTag = "cothority key rotation"
+
+// MaxGrace is the longest grace window of a transition. The old key may be
+// the reason of the rotation, so it must not be accepted for long: the
+// transitions with a longer window are rejected.
+const MaxGrace = 7 * 24 * time.Hour
+
+// Transition replaces the identity key Old of a conode by New. It is signed
+// by both keys.
+type Transition struct {
+	Old kyber.Point
+	New kyber.Point
+	// NotAfter is the end of the grace window, in seconds since the epoch.
+	NotAfter     int64
+	OldSignature []byte
+	NewSignature []byte
+}
+
+// Rotator is implemented by the services keeping rosters, to replace the old
+// key of a rotated conode by its new key.
+type Rotator interface {
+	// RotateKey applies the transition to the rosters of the service on
+	// this node, and returns the rosters that held the old key.
+	RotateKey(t *Transition) ([]*onet.Roster, error)
+}
+
+// New returns the transition from the old key to the new key, signed by both
+// of them, with a grace window ending at notAfter, at most MaxGrace from now.
+func New(oldKey, newKey kyber.Scalar, notAfter time.Time) (*Transition, error) {
+	if notAfter.After(time.Now().Add(MaxGrace)) {
+		return nil, fmt.Errorf("the grace window is longer than %s", MaxGrace)
+	}
+	t := &Transition{
+		Old:      cothority.Suite.Point().Mul(oldKey, nil),
+		New:      cothority.Suite.Point().Mul(newKey, nil),
+		NotAfter: notAfter.Unix(),
+	}
+	if t.Old.Equal(t.New) {
+		return nil, errors.New("the new key is the old key")
+	}
+	msg, err := t.message()
+	if err != nil {
+		return nil, err
+	}
+	t.OldSignature, err = schnorr.Sign(cothority.Suite, oldKey, msg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't sign with the old key: %v", err)
+	}
+	t.NewSignature, err = schnorr.Sign(cothority.Suite, newKey, msg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't sign with the new key: %v", err)
+	}
+	return t, nil
+}
+
+// message returns the hash signed by both keys.
+func (t *Transition) message() ([]byte, error) {
+	h := sha256.New()
+	h.Write([]byte(signatureTag))
+	for _, p := range []kyber.Point{t.Old, t.New} {
+		buf, err := p.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal key: %v", err)
+		}
+		h.Write(buf)
+	}
+	var notAfter [8]byte
+	binary.LittleEndian.PutUint64(notAfter[:], uint64(t.NotAfter))
+	h.Write(notAfter[:])
+	return h.Sum(nil), nil
+}
+
+// Verify returns an error if the transition is not signed by both keys.
+func (t *Transition) Verify() error {
+	if t.Old == nil || t.New == nil {
+		return errors.New("missing key")
+	}
+	if t.Old.Equal(t.New) {
+		return errors.New("the new key is the old key")
+	}
+	msg, err := t.message()
+	if err != nil {
+		return err
+	}
+	if err := schnorr.Verify(cothority.Suite, t.Old, msg,
+		t.OldSignature); err != nil {
+		return fmt.Errorf("wrong signature of the old key: %v", err)
+	}
+	if err := schnorr.Verify(cothority.Suite, t.New, msg,
+		t.NewSignature); err != nil {
+		return fmt.Errorf("wrong signature of the new key: %v", err)
+	}
+	return nil
+}
+
+// CheckGrace returns an error if the grace window of the transition is over
+// at the given time, or if it ends more than MaxGrace after it.
+func (t *Transition) CheckGrace(now time.Time) error {
+	if now.Unix() >= t.NotAfter {
+		return errors.New("the grace window of the transition is over")
+	}
+	if t.NotAfter > now.Add(MaxGrace).Unix() {
+		return fmt.Errorf("the grace window of the transition is longer than %s",
+			MaxGrace)
+	}
+	return nil
+}
+
+// InGrace returns true if the old key is still accepted at the given time.
+func (t *Transition) InGrace(now time.Time) bool {
+	return t.CheckGrace(now) == nil
+}
+
+// Index returns the index of the old key in the roster, or -1.
+func (t *Transition) Index(r *onet.Roster) int {
+	for i, si := range r.List {
+		if si.Public.Equal(t.Old) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Replace returns a copy of the roster with the identity of the old key
+// replaced by one with the new key, at the same place and with the same
+// address and service keys. It returns nil if the old key is not in the
+// roster, or if the new key already is.
+func (t *Transition) Replace(r *onet.Roster) *onet.Roster {
+	i := t.Index(r)
+	if i < 0 {
+		return nil
+	}
+	for _, si := range r.List {
+		if si.Public.Equal(t.New) {
+			return nil
+		}
+	}
+	old := r.List[i]
+	si := network.NewServerIdentity(t.New, old.Address)
+	si.Description = old.Description
+	si.URL = old.URL
+	si.ServiceIdentities = old.ServiceIdentities
+
+	list := make([]*network.ServerIdentity, len(r.List))
+	copy(list, r.List)
+	list[i] = si
+	return onet.NewRoster(list)
+}
+
+var (
+	transitionsMutex sync.RWMutex
+	transitions      []*Transition
+)
+
+// Register adds a verified transition to the ones known by Keys.
+func Register(t *Transition) {
+	transitionsMutex.Lock()
+	defer transitionsMutex.Unlock()
+	for _, known := range transitions {
+		if known.Old.Equal(t.Old) && known.New.Equal(t.New) {
+			return
+		}
+	}
+	transitions = append(transitions, t)
+}
+
+// Keys returns the key and the keys it replaced that are still in their
+// grace window, for the checks of the signatures of a conode.
+func Keys(key kyber.Point, now time.Time) []kyber.Point {
+	transitionsMutex.RLock()
+	defer transitionsMutex.RUnlock()
+	keys := []kyber.Point{key}
+	// A key rotated twice in the grace window also accepts the first one.
+	for i := 0; i < len(keys); i++ {
+		for _, t := range transitions {
+			if t.New.Equal(keys[i]) && t.InGrace(now) && !contains(keys, t.Old) {
+				keys = append(keys, t.Old)
+			}
+		}
+	}
+	return keys
+}
+
+func contains(keys []kyber.Point, key kyber.Point) bool {
+	for _, k := range keys {
+		if k.Equal(key) {
+			return true
+		}
+	}
+	return false
+}
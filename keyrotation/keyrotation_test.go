@@ -0,0 +1,123 @@
+package keyrotation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestTransition_Verify(t *testing.T) {
+	old := key.NewKeyPair(cothority.Suite)
+	nw := key.NewKeyPair(cothority.Suite)
+	tr, err := New(old.Private, nw.Private, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, tr.Old.Equal(old.Public))
+	require.True(t, tr.New.Equal(nw.Public))
+	require.NoError(t, tr.Verify())
+
+	_, err = New(old.Private, old.Private, time.Now())
+	require.Error(t, err)
+
+	// Any change of the transition breaks a signature.
+	forged := *tr
+	forged.NotAfter++
+	require.Error(t, forged.Verify())
+	forged = *tr
+	forged.New = key.NewKeyPair(cothority.Suite).Public
+	require.Error(t, forged.Verify())
+	forged = *tr
+	forged.OldSignature = tr.NewSignature
+	require.Error(t, forged.Verify())
+	forged = *tr
+	forged.Old = nil
+	require.Error(t, forged.Verify())
+}
+
+func TestTransition_CheckGrace(t *testing.T) {
+	old := key.NewKeyPair(cothority.Suite)
+	nw := key.NewKeyPair(cothority.Suite)
+	now := time.Now()
+	_, err := New(old.Private, nw.Private, now.Add(MaxGrace+time.Hour))
+	require.Error(t, err)
+
+	tr, err := New(old.Private, nw.Private, now.Add(MaxGrace))
+	require.NoError(t, err)
+	require.NoError(t, tr.CheckGrace(now))
+	require.True(t, tr.InGrace(now))
+	require.Error(t, tr.CheckGrace(now.Add(MaxGrace)))
+
+	// A transition signed with a longer window is never accepted.
+	tr.NotAfter = now.Add(MaxGrace + time.Hour).Unix()
+	require.Error(t, tr.CheckGrace(now))
+	require.False(t, tr.InGrace(now))
+}
+
+func TestTransition_Replace(t *testing.T) {
+	kps := make([]*key.Pair, 3)
+	list := make([]*network.ServerIdentity, len(kps))
+	for i := range kps {
+		kps[i] = key.NewKeyPair(cothority.Suite)
+		list[i] = network.NewServerIdentity(kps[i].Public,
+			network.NewAddress(network.TLS, "127.0.0.1:7770"))
+		list[i].Description = "node"
+	}
+	roster := onet.NewRoster(list)
+
+	nw := key.NewKeyPair(cothority.Suite)
+	tr, err := New(kps[1].Private, nw.Private, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, tr.Index(roster))
+
+	rotated := tr.Replace(roster)
+	require.NotNil(t, rotated)
+	require.Equal(t, -1, tr.Index(rotated))
+	require.Equal(t, len(roster.List), len(rotated.List))
+	require.True(t, rotated.List[0].Equal(roster.List[0]))
+	require.True(t, rotated.List[1].Public.Equal(nw.Public))
+	require.Equal(t, roster.List[1].Address, rotated.List[1].Address)
+	require.Equal(t, "node", rotated.List[1].Description)
+	require.True(t, rotated.List[2].Equal(roster.List[2]))
+	require.False(t, rotated.ID.Equal(roster.ID))
+	// The original roster is left unchanged.
+	require.True(t, roster.List[1].Public.Equal(kps[1].Public))
+
+	require.Nil(t, tr.Replace(rotated))
+	both := onet.NewRoster(append(roster.List, rotated.List[1]))
+	require.Nil(t, tr.Replace(both))
+}
+
+func TestKeys(t *testing.T) {
+	first := key.NewKeyPair(cothority.Suite)
+	second := key.NewKeyPair(cothority.Suite)
+	third := key.NewKeyPair(cothority.Suite)
+	now := time.Now()
+
+	t1, err := New(first.Private, second.Private, now.Add(time.Hour))
+	require.NoError(t, err)
+	t2, err := New(second.Private, third.Private, now.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	require.Len(t, Keys(third.Public, now), 1)
+	Register(t1)
+	Register(t2)
+	Register(t2)
+	keys := Keys(third.Public, now)
+	require.Len(t, keys, 3)
+	require.True(t, keys[0].Equal(third.Public))
+	require.True(t, keys[1].Equal(second.Public))
+	require.True(t, keys[2].Equal(first.Public))
+
+	// After the first grace window, only the second key is accepted.
+	keys = Keys(third.Public, now.Add(90*time.Minute))
+	require.Len(t, keys, 2)
+	require.True(t, keys[1].Equal(second.Public))
+	require.Len(t, Keys(third.Public, now.Add(3*time.Hour)), 1)
+
+	// The new key doesn't accept the keys that replaced it.
+	require.Len(t, Keys(first.Public, now), 1)
+}
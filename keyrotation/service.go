@@ -0,0 +1,205 @@
+package keyrotation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// ServiceName is the name of the service applying the transitions.
+const ServiceName = "KeyRotation"
+
+var storageKey = []byte("storage")
+
+func init() {
+	_, err := onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+}
+
+// Service applies the transitions of the keys of the conodes to the services
+// of this node, and keeps them for their grace window.
+type Service struct {
+	*onet.ServiceProcessor
+	storage *storage
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// storage holds the transitions known by the node, in their grace window.
+type storage struct {
+	Transitions []*Transition
+
+	sync.Mutex
+}
+
+// Rotate applies the transition of the key of this node to its services and
+// announces it to the nodes of the rosters they updated. It must be called
+// while the node still runs with the old key, which then restarts with the
+// new one.
+func (s *Service) Rotate(req *Rotate) (*RotateReply, error) {
+	t := &req.Transition
+	if err := t.Verify(); err != nil {
+		return nil, fmt.Errorf("invalid transition: %v", err)
+	}
+	if !t.Old.Equal(s.ServerIdentity().Public) {
+		return nil, errors.New("the transition is not for the key of this node")
+	}
+	if err := t.CheckGrace(time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.add(t); err != nil {
+		return nil, err
+	}
+
+	reply := &RotateReply{}
+	nodes := make(map[network.ServerIdentityID]*network.ServerIdentity)
+	for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+		r, ok := s.Service(name).(Rotator)
+		if !ok {
+			continue
+		}
+		rosters, err := r.RotateKey(t)
+		res := Result{Service: name, Rosters: len(rosters)}
+		if err != nil {
+			log.Errorf("%s couldn't rotate the key: %v", name, err)
+			res.Error = err.Error()
+		}
+		reply.Results = append(reply.Results, res)
+		for _, roster := range rosters {
+			for _, si := range roster.List {
+				if !si.Public.Equal(t.Old) && !si.Public.Equal(t.New) {
+					nodes[si.ID] = si
+				}
+			}
+		}
+	}
+
+	for _, si := range nodes {
+		if err := s.SendRaw(si, &Announce{Transition: *t}); err != nil {
+			log.Warnf("Couldn't announce the new key to %s: %v", si, err)
+			continue
+		}
+		reply.Announced++
+	}
+	return reply, nil
+}
+
+// handleAnnounce applies the transition of another node to the services of
+// this node.
+func (s *Service) handleAnnounce(env *network.Envelope) error {
+	ann, ok := env.Msg.(*Announce)
+	if !ok {
+		return errors.New("didn't get an Announce message")
+	}
+	t := &ann.Transition
+	if err := t.Verify(); err != nil {
+		return fmt.Errorf("invalid transition from %s: %v", env.ServerIdentity,
+			err)
+	}
+	if !t.InGrace(time.Now()) || s.knows(t) {
+		return nil
+	}
+	if err := s.add(t); err != nil {
+		return err
+	}
+
+	for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+		if r, ok := s.Service(name).(Rotator); ok {
+			if _, err := r.RotateKey(t); err != nil {
+				log.Errorf("%s couldn't rotate the key of %s: %v", name,
+					env.ServerIdentity, err)
+			}
+		}
+	}
+	return nil
+}
+
+// knows returns true if the transition is already stored.
+func (s *Service) knows(t *Transition) bool {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	for _, known := range s.storage.Transitions {
+		if known.Old.Equal(t.Old) && known.New.Equal(t.New) {
+			return true
+		}
+	}
+	return false
+}
+
+// add stores the transition, drops the ones out of their grace window, and
+// registers it for Keys.
+func (s *Service) add(t *Transition) error {
+	Register(t)
+	s.storage.Lock()
+	now := time.Now()
+	list := []*Transition{t}
+	for _, known := range s.storage.Transitions {
+		if known.InGrace(now) &&
+			!(known.Old.Equal(t.Old) && known.New.Equal(t.New)) {
+			list = append(list, known)
+		}
+	}
+	s.storage.Transitions = list
+	s.storage.Unlock()
+	return s.save()
+}
+
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	if err := atrest.Save(s, ServiceName, storageKey, s.storage); err != nil {
+		return fmt.Errorf("couldn't save the transitions: %v", err)
+	}
+	return nil
+}
+
+// tryLoad loads the transitions and registers the ones still in their grace
+// window, so that the old key is accepted after the restart.
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	msg, err := atrest.Load(s, ServiceName, storageKey)
+	if err != nil {
+		return fmt.Errorf("couldn't load the transitions: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	st, ok := msg.(*storage)
+	if !ok {
+		return errors.New("data of wrong type")
+	}
+	s.storage = st
+	for _, t := range s.storage.Transitions {
+		if t.InGrace(time.Now()) {
+			Register(t)
+		}
+	}
+	return nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+	}
+	if err := s.tryLoad(); err != nil {
+		return nil, err
+	}
+	if err := s.RegisterHandlers(s.Rotate); err != nil {
+		return nil, errors.New("couldn't register handlers: " + err.Error())
+	}
+	s.RegisterProcessorFunc(announceMsgID, s.handleAnnounce)
+	return s, nil
+}
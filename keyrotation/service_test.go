@@ -0,0 +1,108 @@
+package keyrotation
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+const testServiceName = "KeyRotationTest"
+
+func init() {
+	_, err := onet.RegisterNewService(testServiceName,
+		func(c *onet.Context) (onet.Service, error) {
+			return &testService{ServiceProcessor: onet.NewServiceProcessor(c),
+				rotated: make(chan *onet.Roster, 1)}, nil
+		})
+	log.ErrFatal(err)
+}
+
+// testService keeps a roster and replaces the old keys in it.
+type testService struct {
+	*onet.ServiceProcessor
+	sync.Mutex
+	roster  *onet.Roster
+	rotated chan *onet.Roster
+}
+
+func (ts *testService) RotateKey(t *Transition) ([]*onet.Roster, error) {
+	ts.Lock()
+	defer ts.Unlock()
+	rotated := t.Replace(ts.roster)
+	if rotated == nil {
+		return nil, nil
+	}
+	old := ts.roster
+	ts.roster = rotated
+	ts.rotated <- rotated
+	return []*onet.Roster{old}, nil
+}
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_Rotate(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	servers, roster, _ := local.GenTree(3, false)
+	for _, server := range servers {
+		server.Service(testServiceName).(*testService).roster = roster
+	}
+	s := servers[0].Service(ServiceName).(*Service)
+
+	nw := key.NewKeyPair(cothority.Suite)
+	tr, err := New(servers[0].ServerIdentity.GetPrivate(), nw.Private,
+		time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	reply, err := s.Rotate(&Rotate{Transition: *tr})
+	require.NoError(t, err)
+	require.Equal(t, 2, reply.Announced)
+	var found bool
+	for _, res := range reply.Results {
+		if res.Service == testServiceName {
+			found = true
+			require.Equal(t, 1, res.Rosters)
+			require.Empty(t, res.Error)
+		}
+	}
+	require.True(t, found)
+
+	// The other nodes apply the announced transition.
+	for _, server := range servers {
+		select {
+		case rotated := <-server.Service(testServiceName).(*testService).rotated:
+			require.True(t, rotated.List[0].Public.Equal(nw.Public))
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "didn't rotate the roster of", server)
+		}
+	}
+	keys := Keys(nw.Public, time.Now())
+	require.Len(t, keys, 2)
+	require.True(t, keys[1].Equal(servers[0].ServerIdentity.Public))
+
+	// The transition is kept for after the restart.
+	require.NoError(t, s.tryLoad())
+	require.Len(t, s.storage.Transitions, 1)
+
+	// Only the node of the old key can start the rotation.
+	tr, err = New(servers[1].ServerIdentity.GetPrivate(), nw.Private,
+		time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = s.Rotate(&Rotate{Transition: *tr})
+	require.Error(t, err)
+
+	// And only in the grace window.
+	tr, err = New(servers[0].ServerIdentity.GetPrivate(), nw.Private,
+		time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	_, err = s.Rotate(&Rotate{Transition: *tr})
+	require.Error(t, err)
+}
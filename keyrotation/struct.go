@@ -0,0 +1,39 @@
+package keyrotation
+
+import (
+	"go.dedis.ch/onet/v3/network"
+)
+
+var announceMsgID network.MessageTypeID
+
+func init() {
+	network.RegisterMessages(&Rotate{}, &RotateReply{}, &storage{})
+	announceMsgID = network.RegisterMessage(&Announce{})
+}
+
+// Rotate asks the conode of the old key of the transition to apply it to the
+// rosters of its services, and to announce it to the other nodes.
+type Rotate struct {
+	Transition Transition
+}
+
+// RotateReply holds the result of every service implementing Rotator.
+type RotateReply struct {
+	Results []Result
+	// Announced is the number of nodes the transition was sent to.
+	Announced int
+}
+
+// Result is what a service did with the transition.
+type Result struct {
+	Service string
+	// Rosters is the number of rosters that held the old key.
+	Rosters int
+	Error   string `protobuf:"opt"`
+}
+
+// Announce gives a transition to the nodes sharing a roster with the rotated
+// conode.
+type Announce struct {
+	Transition Transition
+}
@@ -0,0 +1,126 @@
+package lightclient
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/xof/keccak"
+)
+
+// WriteKey is the key of a document encrypted for a LTS, with the proof that
+// the writer knows it, bound to the darc of the write instance.
+type WriteKey struct {
+	U    kyber.Point
+	Ubar kyber.Point
+	E    kyber.Scalar
+	F    kyber.Scalar
+	C    kyber.Point
+}
+
+// EncodeKey encrypts the key under the public key X of the LTS with the
+// given ID. It fails if the key is too long to be embedded in a point.
+func EncodeKey(suite suites.Suite, ltsID, writeDarc []byte, X kyber.Point,
+	key []byte) (*WriteKey, error) {
+	if len(key) > suite.Point().EmbedLen() {
+		return nil, fmt.Errorf("key of %d bytes is too long to embed, the "+
+			"limit is %d", len(key), suite.Point().EmbedLen())
+	}
+
+	wk := &WriteKey{}
+	r := suite.Scalar().Pick(suite.RandomStream())
+	C := suite.Point().Mul(r, X)
+	wk.U = suite.Point().Mul(r, nil)
+	kp := suite.Point().Embed(key, suite.RandomStream())
+	wk.C = suite.Point().Add(C, kp)
+
+	gBar := suite.Point().Embed(ltsID, keccak.New(ltsID))
+	wk.Ubar = suite.Point().Mul(r, gBar)
+	s := suite.Scalar().Pick(suite.RandomStream())
+	w := suite.Point().Mul(s, nil)
+	wBar := suite.Point().Mul(s, gBar)
+	hash := sha256.New()
+	for _, p := range []kyber.Point{wk.C, wk.U, wk.Ubar, w, wBar} {
+		if _, err := p.MarshalTo(hash); err != nil {
+			return nil, fmt.Errorf("couldn't hash the proof: %v", err)
+		}
+	}
+	hash.Write(writeDarc)
+	wk.E = suite.Scalar().SetBytes(hash.Sum(nil))
+	wk.F = suite.Scalar().Add(s, suite.Scalar().Mul(wk.E, r))
+	return wk, nil
+}
+
+// DecodeKey recovers the key of a document from the answer of the LTS with
+// the aggregate public key X, which re-encrypted it for the private key xc.
+func DecodeKey(X, C, XhatEnc kyber.Point, xc kyber.Scalar) ([]byte, error) {
+	xcInv := xc.Clone().Neg(xc)
+	XhatDec := X.Clone().Mul(xcInv, X)
+	Xhat := XhatDec.Clone().Add(XhatEnc, XhatDec)
+	XhatInv := Xhat.Clone().Neg(Xhat)
+
+	XhatInv.Add(C, XhatInv)
+	key, err := XhatInv.Data()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't extract the key from the point: %v",
+			err)
+	}
+	return key, nil
+}
+
+// Write is the value of a Calypso write instance, as calypso.Write, with the
+// points and scalars encoded. The price of the reads isn't supported.
+type Write struct {
+	Data      []byte
+	U         []byte
+	Ubar      []byte
+	E         []byte
+	F         []byte
+	C         []byte
+	ExtraData []byte `protobuf:"opt"`
+	LTSID     InstanceID
+}
+
+// NewWrite returns the write instance of the document, whose encrypted data
+// is given, with its key encrypted for the LTS.
+func NewWrite(suite suites.Suite, ltsID InstanceID, writeDarc []byte,
+	X kyber.Point, key, data []byte) (*Write, error) {
+	wk, err := EncodeKey(suite, ltsID[:], writeDarc, X, key)
+	if err != nil {
+		return nil, err
+	}
+	bufs, err := marshal(wk.U, wk.Ubar, wk.E, wk.F, wk.C)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode the write: %v", err)
+	}
+	return &Write{Data: data, U: bufs[0], Ubar: bufs[1], E: bufs[2],
+		F: bufs[3], C: bufs[4], LTSID: ltsID}, nil
+}
+
+func marshal(ms ...encoding.BinaryMarshaler) ([][]byte, error) {
+	bufs := make([][]byte, len(ms))
+	for i, m := range ms {
+		var err error
+		if bufs[i], err = m.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return bufs, nil
+}
+
+// RecoverKey recovers the key of the document for the private key xc of the
+// reader.
+func (r *DecryptKeyReply) RecoverKey(suite kyber.Group,
+	xc kyber.Scalar) ([]byte, error) {
+	var points [3]kyber.Point
+	for i, buf := range [][]byte{r.X, r.C, r.XhatEnc} {
+		points[i] = suite.Point()
+		if err := points[i].UnmarshalBinary(buf); err != nil {
+			return nil, errors.New("invalid point in the reply")
+		}
+	}
+	return DecodeKey(points[0], points[1], points[2], xc)
+}
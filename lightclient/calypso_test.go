@@ -0,0 +1,60 @@
+package lightclient_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/lightclient"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+func TestEncodeDecodeKey(t *testing.T) {
+	lts := key.NewKeyPair(cothority.Suite)
+	reader := key.NewKeyPair(cothority.Suite)
+	ltsID := lightclient.InstanceID{1, 2, 3}
+	writeDarc := []byte("write darc")
+	secret := []byte("symmetric key")
+
+	wr, err := lightclient.NewWrite(cothority.Suite, ltsID, writeDarc,
+		lts.Public, secret, []byte("encrypted data"))
+	require.NoError(t, err)
+
+	// The nodes decode the write and check its proof.
+	buf, err := protobuf.Encode(wr)
+	require.NoError(t, err)
+	var cwr calypso.Write
+	require.NoError(t, protobuf.DecodeWithConstructors(buf, &cwr,
+		network.DefaultConstructors(cothority.Suite)))
+	require.Equal(t, byzcoin.InstanceID(ltsID), cwr.LTSID)
+	require.NoError(t, cwr.CheckProof(cothority.Suite, writeDarc))
+	require.Error(t, cwr.CheckProof(cothority.Suite, []byte("other darc")))
+
+	// The LTS re-encrypts the key for the reader, as the OCS protocol does.
+	XhatEnc := cothority.Suite.Point().Mul(lts.Private, cwr.U)
+	XhatEnc.Add(XhatEnc, cothority.Suite.Point().Mul(reader.Private,
+		lts.Public))
+	buf, err = protobuf.Encode(&calypso.DecryptKeyReply{C: cwr.C,
+		XhatEnc: XhatEnc, X: lts.Public})
+	require.NoError(t, err)
+	var reply lightclient.DecryptKeyReply
+	require.NoError(t, protobuf.Decode(buf, &reply))
+
+	recovered, err := reply.RecoverKey(cothority.Suite, reader.Private)
+	require.NoError(t, err)
+	require.Equal(t, secret, recovered)
+
+	recovered, err = reply.RecoverKey(cothority.Suite,
+		cothority.Suite.Scalar().Pick(cothority.Suite.RandomStream()))
+	if err == nil {
+		require.NotEqual(t, secret, recovered)
+	}
+
+	_, err = lightclient.EncodeKey(cothority.Suite, ltsID[:], writeDarc,
+		lts.Public, make([]byte, 64))
+	require.Error(t, err)
+}
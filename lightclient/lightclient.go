@@ -0,0 +1,69 @@
+// Package lightclient holds the cryptographic helpers a client needs to take
+// part in the ByzCoin and Calypso flows, without the onet and bbolt
+// dependencies of the services, so that it compiles to WebAssembly:
+//
+//   - EncodeKey and DecodeKey encrypt the key of a Calypso document for a LTS
+//     and recover it from the answer to a DecryptKey request
+//   - Proof checks a ByzCoin proof from a trusted block down to the value of
+//     a key
+//   - ClientTransaction builds and signs the transactions with darc signers
+//
+// The structures encode like the ones of the byzcoin, skipchain and calypso
+// packages, so the requests can be sent as is to the websocket of a conode.
+// The wasm directory holds the WebAssembly module and its JS wrapper.
+package lightclient
+
+// CurrentVersion is the version of the ByzCoin messages the structures
+// encode, which must be byzcoin.CurrentVersion.
+const CurrentVersion = 13
+
+// GetProof asks a node for the proof of a key, as byzcoin.GetProof.
+type GetProof struct {
+	Version          int
+	Key              []byte
+	ID               []byte
+	MustContainBlock []byte `protobuf:"opt"`
+}
+
+// GetProofResponse is the answer to GetProof, as byzcoin.GetProofResponse.
+type GetProofResponse struct {
+	Version int
+	Proof   Proof
+}
+
+// AddTxRequest sends a transaction to a ledger, as byzcoin.AddTxRequest.
+type AddTxRequest struct {
+	Version       int
+	SkipchainID   []byte
+	Transaction   ClientTransaction
+	InclusionWait int    `protobuf:"opt"`
+	ProofFrom     []byte `protobuf:"opt"`
+	Flags         int    `protobuf:"opt"`
+	TraceID       []byte `protobuf:"opt"`
+	SpanID        []byte `protobuf:"opt"`
+}
+
+// AddTxResponse is the answer to AddTxRequest. The proof of the block of the
+// transaction is only set if it waited for its inclusion.
+type AddTxResponse struct {
+	Version int
+	Error   string `protobuf:"opt"`
+	Proof   *Proof `protobuf:"opt"`
+	TraceID []byte `protobuf:"opt"`
+}
+
+// DecryptKey asks the LTS to re-encrypt the key of a document for the reader,
+// as calypso.DecryptKey.
+type DecryptKey struct {
+	Read    Proof
+	Write   Proof
+	TraceID []byte `protobuf:"opt"`
+}
+
+// DecryptKeyReply is the answer to DecryptKey, with the points encoded.
+type DecryptKeyReply struct {
+	C       []byte
+	XhatEnc []byte
+	X       []byte
+	TraceID []byte `protobuf:"opt"`
+}
@@ -0,0 +1,252 @@
+package lightclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/chainverify"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/protobuf"
+)
+
+// skipchainService is the name of the service whose keys sign the forward
+// links.
+const skipchainService = "Skipchain"
+
+// ErrVerify is wrapped in the errors of the proofs that don't verify.
+var ErrVerify = errors.New("proof verification failed")
+
+// Roster is the roster of a skipblock, as onet.Roster, with the keys encoded.
+type Roster struct {
+	ID        []byte
+	List      []*ServerIdentity
+	Aggregate []byte
+}
+
+// ServerIdentity is a node of a roster, as network.ServerIdentity.
+type ServerIdentity struct {
+	Public            []byte
+	ServiceIdentities []ServiceIdentity
+	ID                []byte
+	Address           string
+	Description       string
+	// The private key of network.ServerIdentity is never sent, but it
+	// keeps its field number, so the blank field keeps the URL at the same
+	// place in the encoding.
+	_   []byte
+	URL string `protobuf:"opt"`
+}
+
+// ServiceIdentity is the key of a node for a service, as
+// network.ServiceIdentity.
+type ServiceIdentity struct {
+	Name   string
+	Suite  string
+	Public []byte
+}
+
+// servicePublics returns the keys of the nodes for the service, or their
+// main key if they don't have one for it, decoded as points of the suite.
+func (ro *Roster) servicePublics(suite kyber.Group,
+	name string) ([]kyber.Point, error) {
+	publics := make([]kyber.Point, len(ro.List))
+	for i, si := range ro.List {
+		buf := si.Public
+		for _, srvid := range si.ServiceIdentities {
+			if srvid.Name == name {
+				buf = srvid.Public
+				break
+			}
+		}
+		publics[i] = suite.Point()
+		if err := publics[i].UnmarshalBinary(buf); err != nil {
+			return nil, fmt.Errorf("invalid key of %s for %s: %v",
+				si.Address, name, err)
+		}
+	}
+	return publics, nil
+}
+
+// SkipBlock is a block of a skipchain, as skipchain.SkipBlock.
+type SkipBlock struct {
+	Index           int
+	Height          int
+	MaximumHeight   int
+	BaseHeight      int
+	BackLinkIDs     [][]byte
+	VerifierIDs     [][]byte
+	GenesisID       []byte
+	Data            []byte
+	Roster          *Roster
+	Hash            []byte
+	ForwardLink     []*ForwardLink
+	Payload         []byte `protobuf:"opt"`
+	SignatureScheme uint32
+}
+
+// CalculateHash returns the hash of the fixed fields of the block, which is
+// its ID.
+func (sb *SkipBlock) CalculateHash() []byte {
+	b := chainverify.Block{
+		Index:           sb.Index,
+		Height:          sb.Height,
+		MaximumHeight:   sb.MaximumHeight,
+		BaseHeight:      sb.BaseHeight,
+		BackLinkIDs:     sb.BackLinkIDs,
+		VerifierIDs:     sb.VerifierIDs,
+		GenesisID:       sb.GenesisID,
+		Data:            sb.Data,
+		SignatureScheme: sb.SignatureScheme,
+	}
+	if sb.Roster != nil {
+		for _, si := range sb.Roster.List {
+			b.Publics = append(b.Publics, si.Public)
+		}
+	}
+	return b.Hash()
+}
+
+// ForwardLink is a link from a block to a newer one, signed by the roster of
+// the first, as skipchain.ForwardLink.
+type ForwardLink struct {
+	From      []byte
+	To        []byte
+	NewRoster *Roster
+	Signature ForwardLinkSignature
+}
+
+// ForwardLinkSignature is the collective signature of a forward link, as
+// byzcoinx.FinalSignature.
+type ForwardLinkSignature struct {
+	Msg []byte
+	Sig []byte
+}
+
+// TrieProof is the proof of the presence or the absence of a key in the
+// state of a ledger, as trie.Proof.
+type TrieProof = chainverify.TrieProof
+
+// InteriorNode is a node of the trie with two children.
+type InteriorNode = chainverify.InteriorNode
+
+// EmptyNode is an empty leaf of the trie.
+type EmptyNode = chainverify.EmptyNode
+
+// LeafNode is a leaf of the trie holding a key.
+type LeafNode = chainverify.LeafNode
+
+// Proof is the proof of a key in a ledger, from the genesis block to the
+// latest block, as byzcoin.Proof.
+type Proof struct {
+	InclusionProof TrieProof
+	Latest         SkipBlock
+	Links          []ForwardLink
+}
+
+// dataHeader is the header of a ByzCoin block, as byzcoin.DataHeader.
+type dataHeader struct {
+	TrieRoot              []byte
+	ClientTransactionHash []byte
+	StateChangesHash      []byte
+	Timestamp             int64
+	Version               int `protobuf:"opt"`
+}
+
+// stateChangeBody is the value of a key in the trie, as
+// byzcoin.StateChangeBody.
+type stateChangeBody struct {
+	StateAction int
+	ContractID  string
+	Value       []byte
+	Version     uint64
+	DarcID      []byte
+}
+
+// VerifyFromBlock checks the proof from a block the caller trusts, like the
+// genesis block of the ledger, whose hash it has checked. The first link of
+// the proof is replaced by the roster of the block, and the others must be
+// signed from it down to the latest block, whose trie root must be the one of
+// the inclusion proof.
+func (p *Proof) VerifyFromBlock(trusted *SkipBlock) error {
+	if trusted.Roster == nil {
+		return fmt.Errorf("%w: trusted block has no roster", ErrVerify)
+	}
+	if len(p.Links) == 0 {
+		return fmt.Errorf("%w: missing forward links", ErrVerify)
+	}
+
+	var header dataHeader
+	if err := protobuf.Decode(p.Latest.Data, &header); err != nil {
+		return fmt.Errorf("%w: couldn't decode the header: %v", ErrVerify,
+			err)
+	}
+	if !bytes.Equal(p.InclusionProof.GetRoot(), header.TrieRoot) {
+		return fmt.Errorf("%w: wrong trie root", ErrVerify)
+	}
+
+	suite := pairing.NewSuiteBn256()
+	publics, err := trusted.Roster.servicePublics(suite.G2(),
+		skipchainService)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerify, err)
+	}
+	links := make([]chainverify.Link, len(p.Links)-1)
+	for i, l := range p.Links[1:] {
+		links[i] = chainverify.Link{
+			From: l.From,
+			To:   l.To,
+			Msg:  l.Signature.Msg,
+			Sig:  l.Signature.Sig,
+		}
+		if l.NewRoster != nil {
+			links[i].NewRosterID = l.NewRoster.ID
+			links[i].NewPublics, err = l.NewRoster.servicePublics(
+				suite.G2(), skipchainService)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrVerify, err)
+			}
+		}
+	}
+	err = chainverify.VerifyLinks(trusted.Hash, publics, links,
+		p.Latest.SignatureScheme, p.Latest.CalculateHash())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerify, err)
+	}
+	return nil
+}
+
+// KeyValue returns the key of the proof with its value, the contract of its
+// instance and the darc controlling it. The caller must check the key is the
+// one it asked for, with VerifyKey.
+func (p *Proof) KeyValue() (key, value []byte, contractID string,
+	darcID []byte, err error) {
+	if len(p.InclusionProof.Leaf.Key) == 0 {
+		err = errors.New("empty key")
+		return
+	}
+	var body stateChangeBody
+	if err = protobuf.Decode(p.InclusionProof.Leaf.Value, &body); err != nil {
+		err = fmt.Errorf("couldn't decode the value: %v", err)
+		return
+	}
+	return p.InclusionProof.Leaf.Key, body.Value, body.ContractID,
+		body.DarcID, nil
+}
+
+// VerifyKey checks the proof from the trusted block, and returns an error if
+// it doesn't show that the key is in the ledger.
+func (p *Proof) VerifyKey(trusted *SkipBlock, key []byte) error {
+	if err := p.VerifyFromBlock(trusted); err != nil {
+		return err
+	}
+	ok, err := p.InclusionProof.Exists(key)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerify, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: key is absent", ErrVerify)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package lightclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/lightclient"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+// decodeAs encodes the message with protobuf and decodes it in the other.
+func decodeAs(t *testing.T, msg, other interface{}) {
+	buf, err := protobuf.Encode(msg)
+	require.NoError(t, err)
+	require.NoError(t, protobuf.DecodeWithConstructors(buf, other,
+		network.DefaultConstructors(cothority.Suite)))
+}
+
+func TestProof_VerifyKey(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(3, true)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:" + byzcoin.ContractDarcID}, signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 500 * time.Millisecond
+	cl, gen, err := byzcoin.NewLedger(msg, false)
+	require.NoError(t, err)
+
+	// Spawn a darc with a transaction signed by the light client.
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{signer.Identity()},
+		[]darc.Identity{signer.Identity()}), []byte("light"))
+	darcBuf, err := d.ToProto()
+	require.NoError(t, err)
+	var gdID lightclient.InstanceID
+	copy(gdID[:], msg.GenesisDarc.GetBaseID())
+	tx := lightclient.ClientTransaction{Instructions: []lightclient.Instruction{{
+		InstanceID: gdID,
+		Spawn: &lightclient.Spawn{ContractID: byzcoin.ContractDarcID,
+			Args: []lightclient.Argument{{Name: "darc", Value: darcBuf}}},
+		SignerCounter: []uint64{1},
+	}}}
+	require.NoError(t, tx.FillSignersAndSignWith(signer))
+
+	var req byzcoin.AddTxRequest
+	decodeAs(t, &lightclient.AddTxRequest{
+		Version:       lightclient.CurrentVersion,
+		SkipchainID:   cl.ID,
+		Transaction:   tx,
+		InclusionWait: 10,
+	}, &req)
+	_, err = cl.AddTransactionAndWait(req.Transaction, 10)
+	require.NoError(t, err)
+
+	key := d.GetBaseID()
+	bresp, err := cl.GetProof(key)
+	require.NoError(t, err)
+	require.True(t, len(bresp.Proof.Links) > 1)
+
+	var resp lightclient.GetProofResponse
+	decodeAs(t, bresp, &resp)
+	var genesis lightclient.SkipBlock
+	decodeAs(t, gen.Skipblock, &genesis)
+	require.Equal(t, []byte(cl.ID), genesis.CalculateHash())
+
+	require.NoError(t, resp.Proof.VerifyKey(&genesis, key))
+	k, v, contractID, _, err := resp.Proof.KeyValue()
+	require.NoError(t, err)
+	require.Equal(t, key, k)
+	require.Equal(t, darcBuf, v)
+	require.Equal(t, byzcoin.ContractDarcID, contractID)
+
+	require.Error(t, resp.Proof.VerifyKey(&genesis, []byte("absent")))
+
+	// The latest block isn't the one the links point to.
+	var tampered lightclient.GetProofResponse
+	decodeAs(t, bresp, &tampered)
+	tampered.Proof.Latest.Index++
+	require.Error(t, tampered.Proof.VerifyKey(&genesis, key))
+
+	// The value isn't the one in the trie.
+	decodeAs(t, bresp, &tampered)
+	tampered.Proof.InclusionProof.Leaf.Value = []byte("tampered")
+	require.Error(t, tampered.Proof.VerifyKey(&genesis, key))
+
+	// The trusted block isn't the first of the links.
+	other := genesis
+	other.Hash = resp.Proof.Latest.Hash
+	require.Error(t, resp.Proof.VerifyKey(&other, key))
+
+	// The proofs are sent to the LTS as they are.
+	var dk calypso.DecryptKey
+	decodeAs(t, &lightclient.DecryptKey{Read: resp.Proof, Write: resp.Proof},
+		&dk)
+	require.Equal(t, bresp.Proof.Latest.Hash, dk.Read.Latest.Hash)
+	require.NoError(t, dk.Write.Verify(cl.ID))
+}
+
+func TestGetProof_Encoding(t *testing.T) {
+	var gp byzcoin.GetProof
+	decodeAs(t, &lightclient.GetProof{Version: lightclient.CurrentVersion,
+		Key: []byte("key"), ID: []byte("id")}, &gp)
+	require.Equal(t, byzcoin.Version(lightclient.CurrentVersion), gp.Version)
+	require.Equal(t, []byte("key"), gp.Key)
+	require.Equal(t, []byte("id"), []byte(gp.ID))
+}
@@ -0,0 +1,180 @@
+package lightclient
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+// InstanceID is the ID of an instance in a ledger.
+type InstanceID [32]byte
+
+// Argument is a named argument of an instruction.
+type Argument struct {
+	Name  string
+	Value []byte
+}
+
+// Spawn creates a new instance of the contract.
+type Spawn struct {
+	ContractID string
+	Args       []Argument
+}
+
+// Invoke calls a command of an instance.
+type Invoke struct {
+	ContractID string
+	Command    string
+	Args       []Argument
+}
+
+// Delete removes an instance.
+type Delete struct {
+	ContractID string
+	Args       []Argument
+}
+
+// Instruction is an instruction of a transaction, as byzcoin.Instruction.
+// Exactly one of Spawn, Invoke and Delete must be set.
+type Instruction struct {
+	InstanceID       InstanceID
+	Spawn            *Spawn
+	Invoke           *Invoke
+	Delete           *Delete
+	SignerCounter    []uint64
+	SignerIdentities []darc.Identity
+	Signatures       [][]byte
+}
+
+// Hash returns the hash of the instruction without its signatures, as the
+// nodes compute it for the current version.
+func (instr Instruction) Hash() []byte {
+	h := sha256.New()
+	h.Write(instr.InstanceID[:])
+	var args []Argument
+	switch {
+	case instr.Spawn != nil:
+		h.Write([]byte{0})
+		h.Write([]byte(instr.Spawn.ContractID))
+		args = instr.Spawn.Args
+	case instr.Invoke != nil:
+		h.Write([]byte{1})
+		h.Write([]byte(instr.Invoke.ContractID))
+		h.Write([]byte(instr.Invoke.Command))
+		args = instr.Invoke.Args
+	case instr.Delete != nil:
+		h.Write([]byte{2})
+		h.Write([]byte(instr.Delete.ContractID))
+	}
+	for _, a := range args {
+		writeLen(h, uint64(len(a.Name)))
+		h.Write([]byte(a.Name))
+		writeLen(h, uint64(len(a.Value)))
+		h.Write(a.Value)
+	}
+
+	for _, ctr := range instr.SignerCounter {
+		writeLen(h, ctr)
+	}
+	for _, id := range instr.SignerIdentities {
+		buf := id.GetPublicBytes()
+		writeLen(h, uint64(len(buf)))
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+func writeLen(h hash.Hash, l uint64) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, l)
+	h.Write(buf)
+}
+
+// DeriveID returns the ID of an instance created by the instruction, which
+// is the one of a spawned instance for an empty what.
+func (instr Instruction) DeriveID(what string) InstanceID {
+	var b [4]byte
+	h := sha256.New()
+	h.Write(instr.Hash())
+	binary.LittleEndian.PutUint32(b[:], uint32(len(instr.Signatures)))
+	h.Write(b[:])
+	for _, sig := range instr.Signatures {
+		binary.LittleEndian.PutUint32(b[:], uint32(len(sig)))
+		h.Write(b[:])
+		h.Write(sig)
+	}
+	h.Write([]byte(what))
+
+	var id InstanceID
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// valid returns an error if the instruction doesn't have exactly one action.
+func (instr Instruction) valid() error {
+	n := 0
+	for _, set := range []bool{instr.Spawn != nil, instr.Invoke != nil,
+		instr.Delete != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("instruction must have exactly one of spawn, " +
+			"invoke and delete")
+	}
+	return nil
+}
+
+// ClientTransaction is a transaction sent by a client, as
+// byzcoin.ClientTransaction.
+type ClientTransaction struct {
+	Instructions []Instruction
+}
+
+// Hash returns the hash the signers of the instructions sign.
+func (tx *ClientTransaction) Hash() []byte {
+	h := sha256.New()
+	for _, instr := range tx.Instructions {
+		h.Write(instr.Hash())
+	}
+	return h.Sum(nil)
+}
+
+// FillSignersAndSignWith sets the signers of all the instructions, whose
+// counters must be set already, and signs them.
+func (tx *ClientTransaction) FillSignersAndSignWith(
+	signers ...darc.Signer) error {
+	ids := make([]darc.Identity, len(signers))
+	for i, signer := range signers {
+		ids[i] = signer.Identity()
+	}
+	for i := range tx.Instructions {
+		if err := tx.Instructions[i].valid(); err != nil {
+			return fmt.Errorf("instruction %d: %v", i, err)
+		}
+		if len(tx.Instructions[i].SignerCounter) != len(signers) {
+			return fmt.Errorf("instruction %d has %d counters for %d "+
+				"signers", i, len(tx.Instructions[i].SignerCounter),
+				len(signers))
+		}
+		tx.Instructions[i].SignerIdentities = ids
+	}
+
+	digest := tx.Hash()
+	for i := range tx.Instructions {
+		tx.Instructions[i].Signatures = make([][]byte, len(signers))
+		for j, signer := range signers {
+			sig, err := signer.Sign(digest)
+			if err != nil {
+				return fmt.Errorf("couldn't sign: %v", err)
+			}
+			tx.Instructions[i].Signatures[j] = sig
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package lightclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+func TestClientTransaction_Sign(t *testing.T) {
+	require.Equal(t, int(byzcoin.CurrentVersion), CurrentVersion)
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	tx := ClientTransaction{Instructions: []Instruction{{
+		InstanceID: InstanceID{1, 2, 3},
+		Spawn: &Spawn{ContractID: "value",
+			Args: []Argument{{Name: "value", Value: []byte("a value")}}},
+		SignerCounter: []uint64{1},
+	}, {
+		InstanceID: InstanceID{4, 5, 6},
+		Invoke: &Invoke{ContractID: "value", Command: "update",
+			Args: []Argument{{Name: "value", Value: []byte("another")}}},
+		SignerCounter: []uint64{2},
+	}, {
+		InstanceID:    InstanceID{7, 8, 9},
+		Delete:        &Delete{ContractID: "value"},
+		SignerCounter: []uint64{3},
+	}}}
+	require.NoError(t, tx.FillSignersAndSignWith(signer))
+
+	buf, err := protobuf.Encode(&tx)
+	require.NoError(t, err)
+	var btx byzcoin.ClientTransaction
+	require.NoError(t, protobuf.DecodeWithConstructors(buf, &btx,
+		network.DefaultConstructors(cothority.Suite)))
+	btx.Instructions.SetVersion(byzcoin.CurrentVersion)
+
+	require.Equal(t, btx.Instructions.Hash(), tx.Hash())
+	for i, instr := range btx.Instructions {
+		require.Equal(t, instr.Hash(), tx.Instructions[i].Hash())
+		require.Equal(t, instr.DeriveID("").Slice(),
+			tx.Instructions[i].DeriveID("")[:])
+		id := signer.Identity()
+		require.NoError(t, id.Verify(tx.Hash(), instr.Signatures[0]))
+	}
+
+	// The byzcoin transaction encodes like the light one.
+	rebuf, err := protobuf.Encode(&btx)
+	require.NoError(t, err)
+	require.Equal(t, buf, rebuf)
+}
+
+func TestClientTransaction_Invalid(t *testing.T) {
+	signer := darc.NewSignerEd25519(nil, nil)
+	tx := ClientTransaction{Instructions: []Instruction{{
+		SignerCounter: []uint64{1},
+	}}}
+	require.Error(t, tx.FillSignersAndSignWith(signer))
+
+	tx.Instructions[0].Delete = &Delete{ContractID: "value"}
+	tx.Instructions[0].SignerCounter = nil
+	require.Error(t, tx.FillSignersAndSignWith(signer))
+}
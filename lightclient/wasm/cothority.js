@@ -0,0 +1,67 @@
+// Wrapper of the WebAssembly module of the light client. It needs the
+// wasm_exec.js of the Go release the module was built with:
+//
+//   <script src="wasm_exec.js"></script>
+//   <script src="cothority.js"></script>
+//
+//   const c = await cothority.load("cothority.wasm");
+//   const reply = await c.send(url, "ByzCoin", "GetProof",
+//       c.getProof(byzcoinID, key));
+//   const value = c.verifyProof(reply, genesis, byzcoinID, key);
+(function (global) {
+  "use strict";
+
+  // unwrap returns the value of the result of a function of the module, or
+  // throws its error.
+  function unwrap(result) {
+    if (result.error !== undefined) {
+      throw new Error(result.error);
+    }
+    return result.value;
+  }
+
+  // send sends the encoded message to the service of the conode at url, like
+  // ws://127.0.0.1:7771, and resolves with the encoded reply.
+  function send(url, service, message, bytes) {
+    return new Promise(function (resolve, reject) {
+      const ws = new WebSocket(url.replace(/\/$/, "") + "/" + service + "/" +
+        message);
+      ws.binaryType = "arraybuffer";
+      ws.onopen = function () {
+        ws.send(bytes);
+      };
+      ws.onmessage = function (event) {
+        ws.close(1000);
+        resolve(new Uint8Array(event.data));
+      };
+      ws.onclose = function (event) {
+        if (event.code !== 1000) {
+          reject(new Error("connection closed: " + event.reason));
+        }
+      };
+      ws.onerror = function () {
+        reject(new Error("couldn't connect to " + url));
+      };
+    });
+  }
+
+  // load fetches and starts the module, and resolves with its functions.
+  async function load(url) {
+    const go = new Go();
+    const source = await fetch(url);
+    const result = await WebAssembly.instantiateStreaming(source,
+      go.importObject);
+    go.run(result.instance);
+
+    const mod = global.cothorityWasm;
+    const api = { send: send };
+    Object.keys(mod).forEach(function (name) {
+      api[name] = function () {
+        return unwrap(mod[name].apply(null, arguments));
+      };
+    });
+    return api;
+  }
+
+  global.cothority = { load: load };
+})(typeof window !== "undefined" ? window : globalThis);
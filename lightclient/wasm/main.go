@@ -0,0 +1,365 @@
+//go:build js && wasm
+// +build js,wasm
+
+// The wasm command is the WebAssembly module of the light client. It
+// registers a global cothorityWasm object, whose functions return an object
+// with the result in value, or the reason of the failure in error. The byte
+// arguments and results are Uint8Array.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o cothority.wasm ./lightclient/wasm
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/lightclient"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+func main() {
+	js.Global().Set("cothorityWasm", js.ValueOf(map[string]interface{}{
+		"newWrite":        wrap(newWrite),
+		"recoverKey":      wrap(recoverKey),
+		"verifyProof":     wrap(verifyProof),
+		"signTransaction": wrap(signTransaction),
+		"identity":        wrap(identity),
+		"getProof":        wrap(getProof),
+		"addTxRequest":    wrap(addTxRequest),
+		"decryptKey":      wrap(decryptKey),
+	}))
+	// The functions must stay available as long as the page is.
+	select {}
+}
+
+// wrap turns the function in a JS function returning {value} or {error}.
+func wrap(f func(args []js.Value) (interface{}, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		value, err := f(args)
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		return map[string]interface{}{"value": value}
+	})
+}
+
+func argBytes(args []js.Value, i int) ([]byte, error) {
+	if i >= len(args) || args[i].Type() != js.TypeObject {
+		return nil, fmt.Errorf("argument %d must be a Uint8Array", i)
+	}
+	buf := make([]byte, args[i].Get("length").Int())
+	js.CopyBytesToGo(buf, args[i])
+	return buf, nil
+}
+
+func argsBytes(args []js.Value, n int) ([][]byte, error) {
+	bufs := make([][]byte, n)
+	for i := range bufs {
+		var err error
+		if bufs[i], err = argBytes(args, i); err != nil {
+			return nil, err
+		}
+	}
+	return bufs, nil
+}
+
+func jsBytes(buf []byte) js.Value {
+	arr := js.Global().Get("Uint8Array").New(len(buf))
+	js.CopyBytesToJS(arr, buf)
+	return arr
+}
+
+func encode(msg interface{}) (interface{}, error) {
+	buf, err := protobuf.Encode(msg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode the message: %v", err)
+	}
+	return jsBytes(buf), nil
+}
+
+// newWrite(ltsID, writeDarc, X, key, data) returns the encoded value of a
+// Calypso write instance.
+func newWrite(args []js.Value) (interface{}, error) {
+	bufs, err := argsBytes(args, 5)
+	if err != nil {
+		return nil, err
+	}
+	var ltsID lightclient.InstanceID
+	if len(bufs[0]) != len(ltsID) {
+		return nil, errors.New("invalid LTS ID")
+	}
+	copy(ltsID[:], bufs[0])
+	X := cothority.Suite.Point()
+	if err := X.UnmarshalBinary(bufs[2]); err != nil {
+		return nil, fmt.Errorf("invalid LTS key: %v", err)
+	}
+	wr, err := lightclient.NewWrite(cothority.Suite, ltsID, bufs[1], X,
+		bufs[3], bufs[4])
+	if err != nil {
+		return nil, err
+	}
+	return encode(wr)
+}
+
+// recoverKey(reply, private) returns the key of a document from the encoded
+// DecryptKeyReply.
+func recoverKey(args []js.Value) (interface{}, error) {
+	bufs, err := argsBytes(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	var reply lightclient.DecryptKeyReply
+	if err := protobuf.Decode(bufs[0], &reply); err != nil {
+		return nil, fmt.Errorf("couldn't decode the reply: %v", err)
+	}
+	xc := cothority.Suite.Scalar()
+	if err := xc.UnmarshalBinary(bufs[1]); err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	key, err := reply.RecoverKey(cothority.Suite, xc)
+	if err != nil {
+		return nil, err
+	}
+	return jsBytes(key), nil
+}
+
+// verifyProof(response, genesis, byzcoinID, key) checks the encoded
+// GetProofResponse from the encoded genesis block of the ledger, and returns
+// the value of the key with its contract and darc.
+func verifyProof(args []js.Value) (interface{}, error) {
+	bufs, err := argsBytes(args, 4)
+	if err != nil {
+		return nil, err
+	}
+	var resp lightclient.GetProofResponse
+	if err := protobuf.Decode(bufs[0], &resp); err != nil {
+		return nil, fmt.Errorf("couldn't decode the response: %v", err)
+	}
+	var genesis lightclient.SkipBlock
+	if err := protobuf.Decode(bufs[1], &genesis); err != nil {
+		return nil, fmt.Errorf("couldn't decode the genesis block: %v", err)
+	}
+	if !bytes.Equal(genesis.CalculateHash(), bufs[2]) {
+		return nil, errors.New("genesis block isn't the one of the ledger")
+	}
+	genesis.Hash = bufs[2]
+
+	if err := resp.Proof.VerifyKey(&genesis, bufs[3]); err != nil {
+		return nil, err
+	}
+	_, value, contractID, darcID, err := resp.Proof.KeyValue()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"value":      jsBytes(value),
+		"contractID": contractID,
+		"darcID":     jsBytes(darcID),
+	}, nil
+}
+
+// jsonTransaction is the transaction given to signTransaction, with the
+// bytes in hex.
+type jsonTransaction struct {
+	Instructions []struct {
+		InstanceID string
+		Spawn      *jsonAction
+		Invoke     *jsonAction
+		Delete     *jsonAction
+		Counters   []uint64
+	}
+}
+
+type jsonAction struct {
+	ContractID string
+	Command    string
+	Args       []struct {
+		Name  string
+		Value string
+	}
+}
+
+func (a *jsonAction) args() ([]lightclient.Argument, error) {
+	args := make([]lightclient.Argument, len(a.Args))
+	for i, arg := range a.Args {
+		value, err := hex.DecodeString(arg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %v", arg.Name, err)
+		}
+		args[i] = lightclient.Argument{Name: arg.Name, Value: value}
+	}
+	return args, nil
+}
+
+func (jtx *jsonTransaction) transaction() (*lightclient.ClientTransaction,
+	error) {
+	tx := &lightclient.ClientTransaction{
+		Instructions: make([]lightclient.Instruction, len(jtx.Instructions)),
+	}
+	for i, ji := range jtx.Instructions {
+		instr := &tx.Instructions[i]
+		id, err := hex.DecodeString(ji.InstanceID)
+		if err != nil || len(id) != len(instr.InstanceID) {
+			return nil, fmt.Errorf("invalid instance ID in instruction %d", i)
+		}
+		copy(instr.InstanceID[:], id)
+		instr.SignerCounter = ji.Counters
+
+		var args []lightclient.Argument
+		for _, a := range []*jsonAction{ji.Spawn, ji.Invoke, ji.Delete} {
+			if a != nil {
+				if args, err = a.args(); err != nil {
+					return nil, fmt.Errorf("instruction %d: %v", i, err)
+				}
+			}
+		}
+		if ji.Spawn != nil {
+			instr.Spawn = &lightclient.Spawn{ContractID: ji.Spawn.ContractID,
+				Args: args}
+		}
+		if ji.Invoke != nil {
+			instr.Invoke = &lightclient.Invoke{
+				ContractID: ji.Invoke.ContractID,
+				Command:    ji.Invoke.Command, Args: args}
+		}
+		if ji.Delete != nil {
+			instr.Delete = &lightclient.Delete{
+				ContractID: ji.Delete.ContractID, Args: args}
+		}
+	}
+	return tx, nil
+}
+
+func signer(buf []byte) (darc.Signer, error) {
+	private := cothority.Suite.Scalar()
+	if err := private.UnmarshalBinary(buf); err != nil {
+		return darc.Signer{}, fmt.Errorf("invalid private key: %v", err)
+	}
+	public := cothority.Suite.Point().Mul(private, nil)
+	return darc.NewSignerEd25519(public, private), nil
+}
+
+// signTransaction(json, ...privates) signs the transaction described in
+// JSON with the Ed25519 private keys, and returns it encoded with the IDs of
+// the instances its instructions spawn.
+func signTransaction(args []js.Value) (interface{}, error) {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return nil, errors.New("argument 0 must be the JSON of the transaction")
+	}
+	var jtx jsonTransaction
+	if err := json.Unmarshal([]byte(args[0].String()), &jtx); err != nil {
+		return nil, fmt.Errorf("couldn't decode the transaction: %v", err)
+	}
+	tx, err := jtx.transaction()
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]darc.Signer, len(args)-1)
+	for i := range signers {
+		buf, err := argBytes(args, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if signers[i], err = signer(buf); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.FillSignersAndSignWith(signers...); err != nil {
+		return nil, err
+	}
+
+	buf, err := protobuf.Encode(tx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode the transaction: %v", err)
+	}
+	ids := make([]interface{}, len(tx.Instructions))
+	for i, instr := range tx.Instructions {
+		id := instr.DeriveID("")
+		ids[i] = jsBytes(id[:])
+	}
+	return map[string]interface{}{
+		"transaction": jsBytes(buf),
+		"hash":        jsBytes(tx.Hash()),
+		"instanceIDs": ids,
+	}, nil
+}
+
+// identity(private) returns the darc identity of the Ed25519 private key.
+func identity(args []js.Value) (interface{}, error) {
+	buf, err := argBytes(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	s, err := signer(buf)
+	if err != nil {
+		return nil, err
+	}
+	return s.Identity().String(), nil
+}
+
+// getProof(byzcoinID, key) returns the encoded request of the proof of
+// the key.
+func getProof(args []js.Value) (interface{}, error) {
+	bufs, err := argsBytes(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return encode(&lightclient.GetProof{
+		Version: lightclient.CurrentVersion,
+		Key:     bufs[1],
+		ID:      bufs[0],
+	})
+}
+
+// addTxRequest(byzcoinID, transaction, wait) returns the encoded request
+// adding the transaction returned by signTransaction, which waits for the
+// given number of blocks for its inclusion.
+func addTxRequest(args []js.Value) (interface{}, error) {
+	bufs, err := argsBytes(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	var tx lightclient.ClientTransaction
+	err = protobuf.DecodeWithConstructors(bufs[1], &tx,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode the transaction: %v", err)
+	}
+	req := &lightclient.AddTxRequest{
+		Version:     lightclient.CurrentVersion,
+		SkipchainID: bufs[0],
+		Transaction: tx,
+	}
+	if len(args) > 2 {
+		req.InclusionWait = args[2].Int()
+	}
+	return encode(req)
+}
+
+// decryptKey(readResponse, writeResponse) returns the encoded request for the
+// key of a document, from the encoded GetProofResponses of the read and
+// write instances.
+func decryptKey(args []js.Value) (interface{}, error) {
+	bufs, err := argsBytes(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	var read, write lightclient.GetProofResponse
+	if err := protobuf.Decode(bufs[0], &read); err != nil {
+		return nil, fmt.Errorf("couldn't decode the read proof: %v", err)
+	}
+	if err := protobuf.Decode(bufs[1], &write); err != nil {
+		return nil, fmt.Errorf("couldn't decode the write proof: %v", err)
+	}
+	return encode(&lightclient.DecryptKey{Read: read.Proof,
+		Write: write.Proof})
+}
@@ -0,0 +1,89 @@
+package messaging
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/golang/snappy"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// compressionSnappy marks data compressed with snappy.
+const compressionSnappy = "snappy"
+
+// supportedCompressions are sent in the replies of the propagation, so that
+// the root only compresses the data of the nodes that can decompress it.
+var supportedCompressions = []string{compressionSnappy}
+
+// compressionThreshold is the size from which the propagated data is
+// compressed.
+var compressionThreshold = 16 * 1024
+
+// compressionPeers are the nodes that replied to a propagation with snappy
+// in their supported compressions.
+var compressionPeers = struct {
+	sync.Mutex
+	snappy map[network.ServerIdentityID]bool
+}{snappy: make(map[network.ServerIdentityID]bool)}
+
+// addCompressionPeer stores whether the node can decompress snappy data.
+func addCompressionPeer(si *network.ServerIdentity, compressions []string) {
+	supported := false
+	for _, c := range compressions {
+		if c == compressionSnappy {
+			supported = true
+		}
+	}
+	compressionPeers.Lock()
+	compressionPeers.snappy[si.ID] = supported
+	compressionPeers.Unlock()
+}
+
+// canDecompress returns true if the node told it can decompress snappy data.
+func canDecompress(si *network.ServerIdentity) bool {
+	compressionPeers.Lock()
+	defer compressionPeers.Unlock()
+	return compressionPeers.snappy[si.ID]
+}
+
+// compress returns a copy of sd with the data compressed, or nil if the data
+// is too small or doesn't get smaller.
+func compress(sd *PropagateSendData) *PropagateSendData {
+	if sd.Compression != "" || len(sd.Data) < compressionThreshold {
+		return nil
+	}
+	data := snappy.Encode(nil, sd.Data)
+	if len(data) >= len(sd.Data) {
+		return nil
+	}
+	compressed := *sd
+	compressed.Data = data
+	compressed.Compression = compressionSnappy
+	return &compressed
+}
+
+// decompress replaces the data of sd by its decompressed version. The data
+// can't get bigger than a network packet, so that a small message can't make
+// the node allocate a lot of memory.
+func decompress(sd *PropagateSendData) error {
+	switch sd.Compression {
+	case "":
+		return nil
+	case compressionSnappy:
+		n, err := snappy.DecodedLen(sd.Data)
+		if err != nil {
+			return errors.New("couldn't decompress: " + err.Error())
+		}
+		if n > int(network.MaxPacketSize) {
+			return errors.New("decompressed data is too big")
+		}
+		data, err := snappy.Decode(nil, sd.Data)
+		if err != nil {
+			return errors.New("couldn't decompress: " + err.Error())
+		}
+		sd.Data = data
+		sd.Compression = ""
+		return nil
+	}
+	return errors.New("unknown compression " + sd.Compression)
+}
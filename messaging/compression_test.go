@@ -0,0 +1,71 @@
+package messaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestCompress(t *testing.T) {
+	require.Nil(t, compress(&PropagateSendData{Data: []byte("small")}))
+
+	data := bytes.Repeat([]byte("block"), compressionThreshold)
+	sd := &PropagateSendData{Data: data, Timeout: time.Second}
+	compressed := compress(sd)
+	require.NotNil(t, compressed)
+	require.Equal(t, compressionSnappy, compressed.Compression)
+	require.True(t, len(compressed.Data) < len(data))
+	require.Equal(t, "", sd.Compression)
+
+	require.NoError(t, decompress(compressed))
+	require.Equal(t, data, compressed.Data)
+	require.Equal(t, "", compressed.Compression)
+
+	require.Error(t, decompress(&PropagateSendData{Compression: "unknown"}))
+
+	// The header of the snappy data announces more than a packet.
+	header := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(header, uint64(network.MaxPacketSize)+1)
+	require.Error(t, decompress(&PropagateSendData{Compression: compressionSnappy,
+		Data: header[:n]}))
+}
+
+// Propagates a large message twice: the first time the root learns that the
+// nodes support the compression, the second time it uses it.
+func TestPropagation_Compression(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	servers, el, _ := local.GenTree(4, true)
+
+	msg := &propagateMsg{bytes.Repeat([]byte("block"), compressionThreshold)}
+	received := make(chan bool, 2*len(servers))
+	propFuncs := make([]PropagationFunc, len(servers))
+	for n, server := range servers {
+		pc := &PC{server, local.Overlays[server.ServerIdentity.ID]}
+		var err error
+		propFuncs[n], err = NewPropagationFunc(pc, "PropagateCompressed",
+			func(m network.Message) error {
+				received <- bytes.Equal(msg.Data, m.(*propagateMsg).Data)
+				return nil
+			}, 0)
+		require.NoError(t, err)
+	}
+
+	for round := 0; round < 2; round++ {
+		children, err := propFuncs[0](el, msg, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, len(servers), children)
+		for range servers {
+			require.True(t, <-received)
+		}
+		for _, server := range servers[1:] {
+			require.True(t, canDecompress(server.ServerIdentity))
+		}
+	}
+}
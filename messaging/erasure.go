@@ -0,0 +1,162 @@
+package messaging
+
+import (
+	"errors"
+	"sort"
+)
+
+// The erasure code splits data into k shards and encodes them into n chunks,
+// any k of which give back the data. Chunk i holds the evaluations at x = i
+// of the polynomials whose coefficients are the bytes of the shards, in
+// GF(2^8).
+
+var gfExp [512]byte
+var gfLog [256]int
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-gfLog[a]]
+}
+
+// gfPow returns x^e, with 0^0 = 1.
+func gfPow(x byte, e int) byte {
+	r := byte(1)
+	for i := 0; i < e; i++ {
+		r = gfMul(r, x)
+	}
+	return r
+}
+
+// erasureEncode returns n chunks of the data, any k of which can decode it.
+func erasureEncode(data []byte, k, n int) ([][]byte, error) {
+	if k < 1 || n < k || n > 256 {
+		return nil, errors.New("invalid erasure code parameters")
+	}
+	size := (len(data) + k - 1) / k
+	if size == 0 {
+		size = 1
+	}
+	padded := make([]byte, k*size)
+	copy(padded, data)
+
+	chunks := make([][]byte, n)
+	for i := range chunks {
+		chunks[i] = make([]byte, size)
+		for j := 0; j < k; j++ {
+			coef := gfPow(byte(i), j)
+			shard := padded[j*size : (j+1)*size]
+			for pos, b := range shard {
+				chunks[i][pos] ^= gfMul(coef, b)
+			}
+		}
+	}
+	return chunks, nil
+}
+
+// erasureDecode returns the data of the given length from k of the chunks,
+// indexed by their number.
+func erasureDecode(chunks map[int][]byte, k, length int) ([]byte, error) {
+	if len(chunks) < k {
+		return nil, errors.New("not enough chunks")
+	}
+	var indexes []int
+	for i := range chunks {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	indexes = indexes[:k]
+	size := len(chunks[indexes[0]])
+	for _, i := range indexes {
+		if len(chunks[i]) != size {
+			return nil, errors.New("chunks of different sizes")
+		}
+	}
+	if k*size < length {
+		return nil, errors.New("chunks too small for the length")
+	}
+
+	inv, err := gfInvertVandermonde(indexes)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, k*size)
+	for j := 0; j < k; j++ {
+		shard := data[j*size : (j+1)*size]
+		for r, i := range indexes {
+			coef := inv[j][r]
+			for pos, b := range chunks[i] {
+				shard[pos] ^= gfMul(coef, b)
+			}
+		}
+	}
+	return data[:length], nil
+}
+
+// gfInvertVandermonde returns the inverse of the matrix of the rows
+// (1, x, x^2, ...) for the given x, using Gauss-Jordan elimination.
+func gfInvertVandermonde(xs []int) ([][]byte, error) {
+	k := len(xs)
+	m := make([][]byte, k)
+	inv := make([][]byte, k)
+	for r, x := range xs {
+		m[r] = make([]byte, k)
+		inv[r] = make([]byte, k)
+		inv[r][r] = 1
+		for j := 0; j < k; j++ {
+			m[r][j] = gfPow(byte(x), j)
+		}
+	}
+	for col := 0; col < k; col++ {
+		pivot := -1
+		for r := col; r < k; r++ {
+			if m[r][col] != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, errors.New("singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		scale := gfInv(m[col][col])
+		for j := 0; j < k; j++ {
+			m[col][j] = gfMul(m[col][j], scale)
+			inv[col][j] = gfMul(inv[col][j], scale)
+		}
+		for r := 0; r < k; r++ {
+			if r == col || m[r][col] == 0 {
+				continue
+			}
+			f := m[r][col]
+			for j := 0; j < k; j++ {
+				m[r][j] ^= gfMul(f, m[col][j])
+				inv[r][j] ^= gfMul(f, inv[col][j])
+			}
+		}
+	}
+	// inv is the inverse of the matrix mapping the coefficients to
+	// the evaluations, so row j gives coefficient j.
+	return inv, nil
+}
@@ -0,0 +1,322 @@
+package messaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessage(ErasureChunk{})
+	network.RegisterMessage(ErasureAck{})
+}
+
+// ErasureChunk is one of the erasure-coded chunks of the data. The root sends
+// one chunk to every other node, which relays it to all the other nodes.
+type ErasureChunk struct {
+	// Hash is the sha256 of the data.
+	Hash []byte
+	// Length is the length of the data.
+	Length int
+	// ChunkHashes are the sha256 of all the chunks.
+	ChunkHashes [][]byte
+	// Index of the chunk.
+	Index int
+	Chunk []byte
+	// Timeout of the broadcast.
+	Timeout time.Duration
+	// Relayed is false for the chunk sent by the root.
+	Relayed bool
+	// Signature of the root on the hashes, the length and the timeout, so
+	// that a relaying node can't change them.
+	Signature []byte
+}
+
+// metadata returns the hash the root signs: the hashes, the length and the
+// timeout of the broadcast of round.
+func (c *ErasureChunk) metadata(round string) []byte {
+	h := sha256.New()
+	h.Write([]byte("erasure-broadcast"))
+	for _, field := range append([][]byte{[]byte(round), c.Hash}, c.ChunkHashes...) {
+		binary.Write(h, binary.LittleEndian, uint32(len(field)))
+		h.Write(field)
+	}
+	binary.Write(h, binary.LittleEndian, int64(c.Length))
+	binary.Write(h, binary.LittleEndian, int64(c.Timeout))
+	return h.Sum(nil)
+}
+
+// ErasureAck is sent to the root once the data is decoded and stored.
+type ErasureAck struct{}
+
+// ErasureBroadcast sends data to all the nodes, with the root only sending
+// one chunk to every other node. Any k = n - 1 - thresh chunks give back the
+// data, so the root sends about n/k times the size of the data instead of n
+// times.
+type ErasureBroadcast struct {
+	*onet.TreeNodeInstance
+	onData   PropagationStore
+	onDoneCb func(int)
+	data     []byte
+	timeout  time.Duration
+	k        int
+
+	sync.Mutex
+	// index of the chunk of this node, -1 for the root.
+	index   int
+	first   *ErasureChunk
+	chunks  map[int][]byte
+	decoded bool
+	relayed bool
+	acks    int
+	done    bool
+}
+
+// NewErasureBroadcastFunc registers a new protocol name with the context c
+// and returns a PropagationFunc sending the data erasure-coded. f is called
+// on every node with the decoded data. Up to thresh nodes can fail, -1 uses
+// the default of len(n.Roster().List-1)/3. It is meant for large data like
+// state snapshots, for small data the propagation is faster.
+func NewErasureBroadcastFunc(c propagationContext, name string, f PropagationStore, thresh int) (PropagationFunc, error) {
+	pid, err := c.ProtocolRegister(name, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		t := thresh
+		if t == -1 {
+			t = protocol.DefaultFaultyThreshold(len(n.Roster().List))
+		}
+		p := &ErasureBroadcast{
+			TreeNodeInstance: n,
+			onData:           f,
+			timeout:          initialWait,
+			k:                len(n.List()) - 1 - t,
+			index:            -2,
+			chunks:           make(map[int][]byte),
+		}
+		if p.k < 1 {
+			if len(n.List()) > 1 {
+				return nil, errors.New("threshold too high for the roster")
+			}
+			p.k = 1
+		}
+		// The root is first in the list and has no chunk.
+		for i, tn := range n.List() {
+			if tn.ID.Equal(n.TreeNode().ID) {
+				p.index = i - 1
+			}
+		}
+		if p.index == -2 {
+			return nil, errors.New("didn't find my TreeNode in the Tree")
+		}
+		for _, h := range []interface{}{p.handleChunk, p.handleAck} {
+			if err := n.RegisterHandler(h); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	})
+	log.Lvl3("Registering new erasure broadcast for", c.ServerIdentity(),
+		name, pid)
+	return func(el *onet.Roster, msg network.Message, to time.Duration) (int, error) {
+		rooted := el.NewRosterWithRoot(c.ServerIdentity())
+		if rooted == nil {
+			return 0, errors.New("we're not in the roster")
+		}
+		tree := rooted.GenerateNaryTree(len(el.List))
+		if tree == nil {
+			return 0, errors.New("didn't find root in tree")
+		}
+		d, err := network.Marshal(msg)
+		if err != nil {
+			return 0, err
+		}
+		log.Lvl3(el.List[0].Address, "Starting to broadcast", reflect.TypeOf(msg))
+		pi, err := c.CreateProtocol(name, tree)
+		if err != nil {
+			return 0, err
+		}
+		p := pi.(*ErasureBroadcast)
+		p.data = d
+		if to > 0 {
+			p.timeout = to
+		}
+		done := make(chan int, 1)
+		p.onDoneCb = func(i int) { done <- i }
+		if err := p.Start(); err != nil {
+			return 0, err
+		}
+		return <-done, nil
+	}, err
+}
+
+// Start stores the data on the root and sends every node its chunk.
+func (p *ErasureBroadcast) Start() error {
+	n := len(p.List()) - 1
+	p.store(p.data)
+	time.AfterFunc(p.timeout, p.finish)
+	if n == 0 {
+		p.finish()
+		return nil
+	}
+	chunks, err := erasureEncode(p.data, p.k, n)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(p.data)
+	hashes := make([][]byte, n)
+	for i, c := range chunks {
+		h := sha256.Sum256(c)
+		hashes[i] = h[:]
+	}
+	meta := ErasureChunk{
+		Hash:        hash[:],
+		Length:      len(p.data),
+		ChunkHashes: hashes,
+		Timeout:     p.timeout,
+	}
+	meta.Signature, err = schnorr.Sign(cothority.Suite, p.Private(),
+		meta.metadata(p.Token().RoundID.String()))
+	if err != nil {
+		return err
+	}
+	for i, tn := range p.List()[1:] {
+		chunk := meta
+		chunk.Index = i
+		chunk.Chunk = chunks[i]
+		err := p.SendTo(tn, &chunk)
+		if err != nil {
+			log.Lvl2("Couldn't send chunk to", tn.ServerIdentity, err)
+		}
+	}
+	return nil
+}
+
+func (p *ErasureBroadcast) handleChunk(msg struct {
+	*onet.TreeNode
+	ErasureChunk
+}) error {
+	p.Lock()
+	if p.first == nil {
+		err := schnorr.Verify(cothority.Suite, p.Root().ServerIdentity.Public,
+			msg.metadata(p.Token().RoundID.String()), msg.Signature)
+		if err != nil {
+			p.Unlock()
+			return errors.New("the chunk is not signed by the root: " + err.Error())
+		}
+		first := msg.ErasureChunk
+		p.first = &first
+		timeout := msg.Timeout
+		if timeout <= 0 {
+			timeout = initialWait
+		}
+		time.AfterFunc(timeout, p.finish)
+	}
+	if !bytes.Equal(p.first.Hash, msg.Hash) || msg.Index < 0 ||
+		msg.Index >= len(p.first.ChunkHashes) {
+		p.Unlock()
+		return errors.New("got a chunk of other data")
+	}
+	if h := sha256.Sum256(msg.Chunk); !bytes.Equal(h[:], p.first.ChunkHashes[msg.Index]) {
+		p.Unlock()
+		return errors.New("got a wrong chunk")
+	}
+	p.chunks[msg.Index] = msg.Chunk
+
+	relay := !msg.Relayed && msg.Index == p.index && !p.relayed
+	if relay {
+		p.relayed = true
+	}
+	var data []byte
+	if !p.decoded && len(p.chunks) >= p.k {
+		d, err := erasureDecode(p.chunks, p.k, p.first.Length)
+		if h := sha256.Sum256(d); err == nil && bytes.Equal(h[:], p.first.Hash) {
+			p.decoded = true
+			data = d
+		} else {
+			log.Lvl2(p.ServerIdentity(), "couldn't decode the data:", err)
+		}
+	}
+	p.Unlock()
+
+	if relay {
+		relayed := msg.ErasureChunk
+		relayed.Relayed = true
+		for _, tn := range p.List() {
+			if tn.ID.Equal(p.Root().ID) || tn.ID.Equal(p.TreeNode().ID) {
+				continue
+			}
+			if err := p.SendTo(tn, &relayed); err != nil {
+				log.Lvl2("Couldn't relay chunk to", tn.ServerIdentity, err)
+			}
+		}
+	}
+	if data != nil {
+		p.store(data)
+		if err := p.SendToParent(&ErasureAck{}); err != nil {
+			log.Lvl2("Couldn't send the ack:", err)
+		}
+	}
+
+	p.Lock()
+	finished := p.decoded && p.relayed
+	p.Unlock()
+	if finished {
+		p.finish()
+	}
+	return nil
+}
+
+func (p *ErasureBroadcast) handleAck(msg struct {
+	*onet.TreeNode
+	ErasureAck
+}) error {
+	p.Lock()
+	p.acks++
+	finished := p.acks == len(p.List())-1
+	p.Unlock()
+	if finished {
+		p.finish()
+	}
+	return nil
+}
+
+// store calls onData with the decoded data.
+func (p *ErasureBroadcast) store(data []byte) {
+	if p.onData == nil {
+		return
+	}
+	_, netMsg, err := network.Unmarshal(data, p.Suite())
+	if err != nil {
+		log.Lvlf2("Unmarshal failed with %v", err)
+		return
+	}
+	if err := p.onData(netMsg); err != nil {
+		log.Lvlf2("Broadcast callback failed: %v", err)
+	}
+}
+
+// finish ends the protocol once. On the root, it calls onDoneCb with the
+// number of nodes that stored the data, including the root.
+func (p *ErasureBroadcast) finish() {
+	p.Lock()
+	if p.done {
+		p.Unlock()
+		return
+	}
+	p.done = true
+	acks := p.acks
+	p.Unlock()
+	if p.IsRoot() && p.onDoneCb != nil {
+		p.onDoneCb(acks + 1)
+	}
+	p.Done()
+}
@@ -0,0 +1,107 @@
+package messaging
+
+import (
+	"bytes"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestErasureCode(t *testing.T) {
+	for _, n := range []int{1, 2, 4, 7, 20} {
+		k := n - (n-1)/3
+		data := make([]byte, 1000+n)
+		rand.Read(data)
+		chunks, err := erasureEncode(data, k, n)
+		require.NoError(t, err)
+		for trial := 0; trial < 10; trial++ {
+			subset := make(map[int][]byte)
+			for _, i := range rand.Perm(n)[:k] {
+				subset[i] = chunks[i]
+			}
+			decoded, err := erasureDecode(subset, k, len(data))
+			require.NoError(t, err)
+			require.Equal(t, data, decoded)
+		}
+		if k > 1 {
+			_, err := erasureDecode(map[int][]byte{1: chunks[1]}, k, len(data))
+			require.Error(t, err)
+		}
+	}
+	_, err := erasureEncode([]byte{1}, 3, 2)
+	require.Error(t, err)
+}
+
+func TestErasureBroadcast(t *testing.T) {
+	for _, failures := range []int{0, 1} {
+		local := onet.NewLocalTest(tSuite)
+		servers, el, _ := local.GenTree(5, true)
+
+		msg := &propagateMsg{bytes.Repeat([]byte("snapshot"), 10000)}
+		var recvCount int
+		var iMut sync.Mutex
+		funcs := make([]PropagationFunc, len(servers))
+		for n, server := range servers {
+			pc := &PC{server, local.Overlays[server.ServerIdentity.ID]}
+			var err error
+			funcs[n], err = NewErasureBroadcastFunc(pc, "ErasureBroadcast",
+				func(m network.Message) error {
+					require.Equal(t, msg.Data, m.(*propagateMsg).Data)
+					iMut.Lock()
+					recvCount++
+					iMut.Unlock()
+					return nil
+				}, -1)
+			require.NoError(t, err)
+		}
+		for k := 0; k < failures; k++ {
+			require.NoError(t, servers[len(servers)-1-k].Close())
+		}
+
+		replies, err := funcs[0](el, msg, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, len(servers)-failures, replies)
+		iMut.Lock()
+		require.Equal(t, len(servers)-failures, recvCount)
+		iMut.Unlock()
+		local.CloseAll()
+	}
+}
+
+func TestErasureChunk_Metadata(t *testing.T) {
+	root := key.NewKeyPair(tSuite)
+	chunk := ErasureChunk{
+		Hash:        []byte("hash"),
+		Length:      100,
+		ChunkHashes: [][]byte{[]byte("a"), []byte("b")},
+		Timeout:     time.Second,
+	}
+	sig, err := schnorr.Sign(tSuite, root.Private, chunk.metadata("round"))
+	require.NoError(t, err)
+	require.NoError(t, schnorr.Verify(tSuite, root.Public,
+		chunk.metadata("round"), sig))
+
+	// A relaying node can't change the metadata or reuse it in another round.
+	require.Error(t, schnorr.Verify(tSuite, root.Public,
+		chunk.metadata("other round"), sig))
+	forged := chunk
+	forged.Length = 1 << 30
+	require.Error(t, schnorr.Verify(tSuite, root.Public,
+		forged.metadata("round"), sig))
+	forged = chunk
+	forged.Timeout = 0
+	require.Error(t, schnorr.Verify(tSuite, root.Public,
+		forged.metadata("round"), sig))
+	forged = chunk
+	forged.ChunkHashes = [][]byte{[]byte("ab")}
+	require.Error(t, schnorr.Verify(tSuite, root.Public,
+		forged.metadata("round"), sig))
+}
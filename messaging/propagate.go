@@ -17,6 +17,7 @@ import (
 func init() {
 	network.RegisterMessage(PropagateSendData{})
 	network.RegisterMessage(PropagateReply{})
+	network.RegisterMessage(PropagateGossip{})
 }
 
 // How long to wait before timing out on waiting for the time-out.
@@ -37,8 +38,17 @@ type Propagate struct {
 		*onet.TreeNode
 		PropagateReply
 	}
+	ChannelGossip chan struct {
+		*onet.TreeNode
+		PropagateGossip
+	}
 
 	allowedFailures int
+	// acked are the children that replied.
+	acked []*network.ServerIdentity
+	// gossip is the number of children that forward the data to each child
+	// the root couldn't reach, zero disables the gossip.
+	gossip int
 	sync.Mutex
 	closing chan bool
 }
@@ -50,11 +60,28 @@ type PropagateSendData struct {
 	// How long the root will wait for the children before
 	// timing out.
 	Timeout time.Duration
+	// Gossip tells the children to wait for the PropagateGossip of the root
+	// before stopping.
+	Gossip bool
+	// Compression of the data, empty if it is not compressed. It is only
+	// used for the nodes that told they support it.
+	Compression string
 }
 
 // PropagateReply is sent from the children back to the root
 type PropagateReply struct {
 	Level int
+	// Compressions are the compressions of the data that the node supports.
+	Compressions []string
+}
+
+// PropagateGossip is sent by the root to the children it could reach, so that
+// they forward the data to the children it couldn't reach.
+type PropagateGossip struct {
+	// Data is sent to the missing children, without Gossip.
+	Data PropagateSendData
+	// Missing are the roster indexes of the children to send the data to.
+	Missing []int
 }
 
 // PropagationFunc starts the propagation protocol and blocks until all children
@@ -63,6 +90,74 @@ type PropagateReply struct {
 // stored the new value or an error if the protocol couldn't start.
 type PropagationFunc func(el *onet.Roster, msg network.Message, timeout time.Duration) (int, error)
 
+// AckPropagationFunc is like PropagationFunc, but it returns which nodes
+// acknowledged having stored the new value.
+type AckPropagationFunc func(el *onet.Roster, msg network.Message, timeout time.Duration) (*PropagationResult, error)
+
+// PropagationResult holds the nodes of a propagation that acknowledged the
+// data and the ones that didn't.
+type PropagationResult struct {
+	// Acked are the nodes that stored the data, starting with the root.
+	Acked []*network.ServerIdentity
+	// Failed are the nodes of the roster that didn't acknowledge the data.
+	Failed []*network.ServerIdentity
+
+	propagate AckPropagationFunc
+	roster    *onet.Roster
+	msg       network.Message
+	timeout   time.Duration
+}
+
+func newPropagationResult(propagate AckPropagationFunc, ro *onet.Roster, msg network.Message,
+	to time.Duration, acked []*network.ServerIdentity) *PropagationResult {
+	res := &PropagationResult{
+		Acked:     acked,
+		propagate: propagate,
+		roster:    ro,
+		msg:       msg,
+		timeout:   to,
+	}
+	for _, si := range ro.List {
+		if !containsServer(acked, si) {
+			res.Failed = append(res.Failed, si)
+		}
+	}
+	return res
+}
+
+// Retry propagates the data again, to the whole roster or only to the nodes
+// that failed. It returns the result for the whole roster.
+func (r *PropagationResult) Retry(onlyFailed bool) (*PropagationResult, error) {
+	if !onlyFailed {
+		return r.propagate(r.roster, r.msg, r.timeout)
+	}
+	if len(r.Failed) == 0 || len(r.Acked) == 0 {
+		return r, nil
+	}
+	// The root needs to be in the roster of the propagation.
+	res, err := r.propagate(onet.NewRoster(append([]*network.ServerIdentity{r.Acked[0]},
+		r.Failed...)), r.msg, r.timeout)
+	if err != nil {
+		return nil, err
+	}
+	acked := append([]*network.ServerIdentity{}, r.Acked...)
+	for _, si := range res.Acked {
+		if !containsServer(acked, si) {
+			acked = append(acked, si)
+		}
+	}
+	return newPropagationResult(r.propagate, r.roster, r.msg, r.timeout, acked), nil
+}
+
+func containsServer(list []*network.ServerIdentity, si *network.ServerIdentity) bool {
+	for _, s := range list {
+		if s.Equal(si) {
+			return true
+		}
+	}
+	return false
+}
+
 // PropagationStore is the function that will store the new data.
 type PropagationStore func(network.Message) error
 
@@ -73,26 +168,81 @@ type propagationContext interface {
 	CreateProtocol(name string, t *onet.Tree) (onet.ProtocolInstance, error)
 }
 
+// PropagationConfig holds the parameters of the propagation that depend on
+// the latency requirements of the calling service.
+type PropagationConfig struct {
+	// Threshold is the number of nodes per subtree that can fail to respond,
+	// -1 for the default of len(n.Roster().List-1)/3.
+	Threshold int
+	// Timeout is used when the PropagationFunc is called with a zero
+	// timeout.
+	Timeout time.Duration
+	// Retries is how many times the data is sent again to the nodes that
+	// didn't reply.
+	Retries int
+	// Fanout enables the gossip if it is positive, see
+	// NewGossipPropagationFunc.
+	Fanout int
+}
+
 // NewPropagationFunc registers a new protocol name with the context c and will
 // set f as handler for every new instance of that protocol.
 // The protocol will fail if more than thresh nodes per subtree fail to respond.
 // If thresh == -1, the threshold defaults to len(n.Roster().List-1)/3. Thus, for a roster of
 // 5, t = int(4/3) = 1, e.g. 1 node out of the 5 can fail.
 func NewPropagationFunc(c propagationContext, name string, f PropagationStore, thresh int) (PropagationFunc, error) {
+	return NewPropagationFuncConfig(c, name, f, PropagationConfig{Threshold: thresh})
+}
+
+// NewGossipPropagationFunc is like NewPropagationFunc, but if the root cannot
+// send the data to some children, it asks fanout of the other children to
+// forward it to each of them. This helps on lossy networks, where the root
+// might not reach a node that its peers can reach.
+func NewGossipPropagationFunc(c propagationContext, name string, f PropagationStore, thresh, fanout int) (PropagationFunc, error) {
+	if fanout <= 0 {
+		return nil, errors.New("the fanout of the gossip must be positive")
+	}
+	return NewPropagationFuncConfig(c, name, f, PropagationConfig{Threshold: thresh, Fanout: fanout})
+}
+
+// NewPropagationFuncConfig is like NewPropagationFunc, with all the
+// parameters of the propagation given by the calling service.
+func NewPropagationFuncConfig(c propagationContext, name string, f PropagationStore, conf PropagationConfig) (PropagationFunc, error) {
+	ack, err := NewAckPropagationFunc(c, name, f, conf)
+	if err != nil {
+		return nil, err
+	}
+	return func(el *onet.Roster, msg network.Message, to time.Duration) (int, error) {
+		res, err := ack(el, msg, to)
+		if err != nil {
+			return 0, err
+		}
+		return len(res.Acked), nil
+	}, nil
+}
+
+// NewAckPropagationFunc is like NewPropagationFuncConfig, but the
+// propagation returns which nodes acknowledged the data. The retries of the
+// configuration only send the data to the nodes that didn't.
+func NewAckPropagationFunc(c propagationContext, name string, f PropagationStore, conf PropagationConfig) (AckPropagationFunc, error) {
+	if conf.Retries < 0 || conf.Fanout < 0 || conf.Timeout < 0 {
+		return nil, errors.New("negative propagation parameter")
+	}
 	pid, err := c.ProtocolRegister(name, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
 		// Make a local copy in order to avoid a data race.
-		t := thresh
+		t := conf.Threshold
 		if t == -1 {
 			t = protocol.DefaultFaultyThreshold(len(n.Roster().List))
 		}
 		p := &Propagate{
-			sd:               &PropagateSendData{[]byte{}, initialWait},
+			sd:               &PropagateSendData{Data: []byte{}, Timeout: initialWait, Gossip: conf.Fanout > 0},
 			TreeNodeInstance: n,
 			onData:           f,
 			allowedFailures:  t,
+			gossip:           conf.Fanout,
 			closing:          make(chan bool),
 		}
-		for _, h := range []interface{}{&p.ChannelSD, &p.ChannelReply} {
+		for _, h := range []interface{}{&p.ChannelSD, &p.ChannelReply, &p.ChannelGossip} {
 			if err := p.RegisterChannel(h); err != nil {
 				return nil, err
 			}
@@ -101,10 +251,11 @@ func NewPropagationFunc(c propagationContext, name string, f PropagationStore, t
 	})
 	log.Lvl3("Registering new propagation for", c.ServerIdentity(),
 		name, pid)
-	return func(el *onet.Roster, msg network.Message, to time.Duration) (int, error) {
+	var ack AckPropagationFunc
+	ack = func(el *onet.Roster, msg network.Message, to time.Duration) (*PropagationResult, error) {
 		rooted := el.NewRosterWithRoot(c.ServerIdentity())
 		if rooted == nil {
-			return 0, errors.New("we're not in the roster")
+			return nil, errors.New("we're not in the roster")
 		}
 		// Make a star (tree with height 1)
 		// TODO: it would be nice to search for a nice method to convert a
@@ -113,22 +264,38 @@ func NewPropagationFunc(c propagationContext, name string, f PropagationStore, t
 		// most of the nodes appear more than once.
 		tree := rooted.GenerateNaryTree(len(el.List))
 		if tree == nil {
-			return 0, errors.New("Didn't find root in tree")
+			return nil, errors.New("Didn't find root in tree")
+		}
+		if to == 0 {
+			to = conf.Timeout
 		}
 		log.Lvl3(el.List[0].Address, "Starting to propagate", reflect.TypeOf(msg))
 		pi, err := c.CreateProtocol(name, tree)
 		if err != nil {
-			return 0, err
+			return nil, err
+		}
+		acked, err := propagateStartAndWait(pi, msg, to, f)
+		if err != nil {
+			return nil, err
+		}
+		return newPropagationResult(ack, el, msg, to, acked), nil
+	}
+	return func(el *onet.Roster, msg network.Message, to time.Duration) (*PropagationResult, error) {
+		res, err := ack(el, msg, to)
+		for try := 0; err == nil && try < conf.Retries && len(res.Failed) > 0; try++ {
+			log.Lvl2(c.ServerIdentity(), "Only got", len(res.Acked), "out of",
+				len(el.List), "replies, trying again")
+			res, err = res.Retry(true)
 		}
-		return propagateStartAndWait(pi, msg, to, f)
+		return res, err
 	}, err
 }
 
 // Separate function for testing
-func propagateStartAndWait(pi onet.ProtocolInstance, msg network.Message, to time.Duration, f PropagationStore) (int, error) {
+func propagateStartAndWait(pi onet.ProtocolInstance, msg network.Message, to time.Duration, f PropagationStore) ([]*network.ServerIdentity, error) {
 	d, err := network.Marshal(msg)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	protocol := pi.(*Propagate)
 	protocol.Lock()
@@ -140,13 +307,13 @@ func propagateStartAndWait(pi onet.ProtocolInstance, msg network.Message, to tim
 	protocol.onDoneCb = func(i int) { done <- i }
 	protocol.Unlock()
 	if err = protocol.Start(); err != nil {
-		return 0, err
+		return nil, err
 	}
 	select {
-	case replies := <-done:
-		return replies, nil
+	case <-done:
+		return protocol.Acked(), nil
 	case <-protocol.closing:
-		return 0, nil
+		return nil, nil
 	}
 }
 
@@ -182,13 +349,18 @@ func (p *Propagate) Dispatch() error {
 		select {
 		case msg := <-p.ChannelSD:
 			if gotSendData {
-				log.Error("already got msg")
+				// Expected with the gossip, where several nodes might
+				// forward the data.
+				log.Lvl2("already got msg")
 				continue
 			}
 			gotSendData = true
 			log.Lvl3(p.ServerIdentity(), "Got data from",
 				msg.ServerIdentity, "and setting timeout to", msg.Timeout)
 			p.sd.Timeout = msg.Timeout
+			if err := decompress(&msg.PropagateSendData); err != nil {
+				return err
+			}
 			if p.onData != nil {
 				_, netMsg, err := network.Unmarshal(msg.Data, p.Suite())
 				if err != nil {
@@ -202,13 +374,20 @@ func (p *Propagate) Dispatch() error {
 			}
 			if !p.IsRoot() || p.Tree().Size() == 1 {
 				log.Lvl3(p.ServerIdentity(), "Sending to parent")
-				if err := p.SendToParent(&PropagateReply{}); err != nil {
+				err := p.SendToParent(&PropagateReply{Compressions: supportedCompressions})
+				if err != nil {
 					return err
 				}
-				process = false
+				// Wait for the root to tell which nodes to forward the
+				// data to.
+				process = msg.Gossip && !p.IsRoot()
+			}
+			if msg.Gossip && p.IsRoot() {
+				p.sendWithGossip(&msg.PropagateSendData)
+				continue
 			}
 			log.Lvl3(p.ServerIdentity(), "Sending to children", p.Children())
-			if errs = p.SendToChildrenInParallel(&msg.PropagateSendData); len(errs) != 0 {
+			if _, errs = p.sendToChildren(&msg.PropagateSendData); len(errs) != 0 {
 				errsStr := make([]string, len(errs))
 				for i, e := range errs {
 					errsStr[i] = e.Error()
@@ -218,15 +397,20 @@ func (p *Propagate) Dispatch() error {
 					return errors.New(strings.Join(errsStr, "\n"))
 				}
 			}
-		case <-p.ChannelReply:
+		case msg := <-p.ChannelReply:
 			if !gotSendData {
 				log.Error("got response before send")
 				continue
 			}
+			addCompressionPeer(msg.ServerIdentity, msg.Compressions)
+			p.Lock()
+			p.acked = append(p.acked, msg.ServerIdentity)
+			p.Unlock()
 			received++
 			log.Lvl4(p.ServerIdentity(), "received:", received, subtreeCount)
 			if !p.IsRoot() {
-				if err := p.SendToParent(&PropagateReply{}); err != nil {
+				err := p.SendToParent(&PropagateReply{Compressions: supportedCompressions})
+				if err != nil {
 					return err
 				}
 			}
@@ -234,6 +418,19 @@ func (p *Propagate) Dispatch() error {
 			if received == subtreeCount-len(errs) && received >= subtreeCount-p.allowedFailures {
 				process = false
 			}
+		case msg := <-p.ChannelGossip:
+			for _, tn := range p.List() {
+				for _, missing := range msg.Missing {
+					if tn.RosterIndex != missing {
+						continue
+					}
+					log.Lvl3(p.ServerIdentity(), "Forwarding to", tn.ServerIdentity)
+					if err := p.SendTo(tn, &msg.Data); err != nil {
+						log.Lvl2("Couldn't forward the data:", err)
+					}
+				}
+			}
+			process = false
 		case <-time.After(timeout):
 			if received+1 < subtreeCount-p.allowedFailures {
 				_, _, err := network.Unmarshal(p.sd.Data, p.Suite())
@@ -250,6 +447,87 @@ func (p *Propagate) Dispatch() error {
 	return nil
 }
 
+// sendWithGossip sends the data to the children, and asks the children that
+// got it to forward it to the ones that didn't. The root then waits for the
+// replies of all the children, up to the timeout.
+func (p *Propagate) sendWithGossip(sd *PropagateSendData) {
+	children := p.Children()
+	failed, _ := p.sendToChildren(sd)
+
+	var reached []*onet.TreeNode
+	var missing []int
+	for i, tn := range children {
+		if failed[i] {
+			missing = append(missing, tn.RosterIndex)
+		} else {
+			reached = append(reached, tn)
+		}
+	}
+	forward := *sd
+	forward.Gossip = false
+	for i, targets := range gossipTargets(missing, len(reached), p.gossip) {
+		// Also sent without missing children, so that the children stop.
+		err := p.SendTo(reached[i], &PropagateGossip{Data: forward, Missing: targets})
+		if err != nil {
+			log.Lvl2("Couldn't send the gossip:", err)
+		}
+	}
+}
+
+// sendToChildren sends the data to all the children in parallel, compressed
+// for the ones that support it. It returns which children failed, and the
+// errors.
+func (p *Propagate) sendToChildren(sd *PropagateSendData) ([]bool, []error) {
+	children := p.Children()
+	compressed := compress(sd)
+	failed := make([]bool, len(children))
+	var errs []error
+	var errsMutex sync.Mutex
+	var wg sync.WaitGroup
+	for i, tn := range children {
+		msg := sd
+		if compressed != nil && canDecompress(tn.ServerIdentity) {
+			msg = compressed
+		}
+		wg.Add(1)
+		go func(i int, tn *onet.TreeNode, msg *PropagateSendData) {
+			defer wg.Done()
+			if err := p.SendTo(tn, msg); err != nil {
+				log.Lvl2("Error while sending to child:", err)
+				errsMutex.Lock()
+				failed[i] = true
+				errs = append(errs, err)
+				errsMutex.Unlock()
+			}
+		}(i, tn, msg)
+	}
+	wg.Wait()
+	return failed, errs
+}
+
+// gossipTargets spreads the missing children over the reached ones, so that
+// each missing child gets the data from fanout of them.
+func gossipTargets(missing []int, reached, fanout int) [][]int {
+	targets := make([][]int, reached)
+	if fanout > reached {
+		fanout = reached
+	}
+	for j, m := range missing {
+		for k := 0; k < fanout; k++ {
+			i := (j + k) % reached
+			targets[i] = append(targets[i], m)
+		}
+	}
+	return targets
+}
+
+// Acked returns the nodes that acknowledged the data, starting with the root.
+func (p *Propagate) Acked() []*network.ServerIdentity {
+	p.Lock()
+	defer p.Unlock()
+	return append([]*network.ServerIdentity{p.ServerIdentity()}, p.acked...)
+}
+
 // RegisterOnDone takes a function that will be called once the data has been
 // sent to the whole tree. It receives the number of nodes that replied
 // successfully to the propagation.
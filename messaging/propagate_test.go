@@ -26,11 +26,114 @@ func init() {
 func TestPropagation(t *testing.T) {
 	propagate(t,
 		[]int{3, 10, 14, 4, 8, 8},
-		[]int{0, 0, 0, 1, 3, 6})
+		[]int{0, 0, 0, 1, 3, 6}, 0)
 }
 
-// Tests an n-node system
-func propagate(t *testing.T, nbrNodes, nbrFailures []int) {
+func TestPropagation_Gossip(t *testing.T) {
+	propagate(t,
+		[]int{3, 10, 4, 8},
+		[]int{0, 0, 1, 3}, 2)
+}
+
+// Checks that the data is sent again to a node that doesn't reply, using the
+// timeout of the configuration.
+func TestPropagation_Config(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	servers, el, _ := local.GenTree(4, true)
+
+	msg := &propagateMsg{[]byte("propagate")}
+	var recvCount int
+	var iMut sync.Mutex
+	conf := PropagationConfig{Threshold: 1, Timeout: 500 * time.Millisecond, Retries: 2}
+	propFuncs := make([]PropagationFunc, len(servers))
+	for n, server := range servers {
+		pc := &PC{server, local.Overlays[server.ServerIdentity.ID]}
+		var err error
+		propFuncs[n], err = NewPropagationFuncConfig(pc, "PropagateConfig",
+			func(m network.Message) error {
+				iMut.Lock()
+				recvCount++
+				iMut.Unlock()
+				return nil
+			}, conf)
+		require.NoError(t, err)
+	}
+	_, err := NewPropagationFuncConfig(&PC{servers[0], nil}, "PropagateWrong",
+		nil, PropagationConfig{Retries: -1})
+	require.Error(t, err)
+
+	require.NoError(t, servers[3].Close())
+	children, err := propFuncs[0](el, msg, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, children)
+	// The root stores the data again at every retry.
+	iMut.Lock()
+	require.Equal(t, 3+conf.Retries, recvCount)
+	iMut.Unlock()
+}
+
+func TestPropagationResult_Retry(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	servers, el, _ := local.GenTree(4, true)
+
+	msg := &propagateMsg{[]byte("propagate")}
+	stored := make(map[network.ServerIdentityID]int)
+	var iMut sync.Mutex
+	propFuncs := make([]AckPropagationFunc, len(servers))
+	for n, server := range servers {
+		pc := &PC{server, local.Overlays[server.ServerIdentity.ID]}
+		id := server.ServerIdentity.ID
+		var err error
+		propFuncs[n], err = NewAckPropagationFunc(pc, "PropagateAck",
+			func(m network.Message) error {
+				iMut.Lock()
+				stored[id]++
+				iMut.Unlock()
+				return nil
+			}, PropagationConfig{Threshold: 1})
+		require.NoError(t, err)
+	}
+
+	servers[3].Pause()
+	res, err := propFuncs[0](el, msg, 500*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res.Acked))
+	require.True(t, res.Acked[0].Equal(el.List[0]))
+	require.Equal(t, 1, len(res.Failed))
+	require.True(t, res.Failed[0].Equal(el.List[3]))
+
+	servers[3].Unpause()
+	res, err = res.Retry(true)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(res.Acked))
+	require.Empty(t, res.Failed)
+	// Only the root and the paused node got the data again, the paused node
+	// might also get the first propagation once it runs again.
+	iMut.Lock()
+	require.Equal(t, 2, stored[el.List[0].ID])
+	require.Equal(t, 1, stored[el.List[1].ID])
+	require.Equal(t, 1, stored[el.List[2].ID])
+	require.True(t, stored[el.List[3].ID] >= 1)
+	iMut.Unlock()
+	local.Check = onet.CheckNone
+}
+
+func TestGossipTargets(t *testing.T) {
+	targets := gossipTargets([]int{5, 6, 7}, 2, 2)
+	require.Equal(t, [][]int{{5, 6, 7}, {5, 6, 7}}, targets)
+
+	targets = gossipTargets([]int{5, 6, 7}, 3, 1)
+	require.Equal(t, [][]int{{5}, {6}, {7}}, targets)
+
+	targets = gossipTargets(nil, 2, 1)
+	require.Equal(t, [][]int{nil, nil}, targets)
+	require.Empty(t, gossipTargets([]int{5}, 0, 1))
+}
+
+// Tests an n-node system, with the gossip if fanout > 0
+func propagate(t *testing.T, nbrNodes, nbrFailures []int, fanout int) {
 	for i, n := range nbrNodes {
 		local := onet.NewLocalTest(tSuite)
 		servers, el, _ := local.GenTree(n, true)
@@ -43,19 +146,24 @@ func propagate(t *testing.T, nbrNodes, nbrFailures []int) {
 		var err error
 		for n, server := range servers {
 			pc := &PC{server, local.Overlays[server.ServerIdentity.ID]}
-			propFuncs[n], err = NewPropagationFunc(pc,
-				"Propagate",
-				func(m network.Message) error {
-					if bytes.Equal(msg.Data, m.(*propagateMsg).Data) {
-						iMut.Lock()
-						recvCount++
-						iMut.Unlock()
-						return nil
-					}
-
-					t.Error("Didn't receive correct data")
-					return errors.New("Didn't receive correct data")
-				}, nbrFailures[i])
+			store := func(m network.Message) error {
+				if bytes.Equal(msg.Data, m.(*propagateMsg).Data) {
+					iMut.Lock()
+					recvCount++
+					iMut.Unlock()
+					return nil
+				}
+
+				t.Error("Didn't receive correct data")
+				return errors.New("Didn't receive correct data")
+			}
+			if fanout > 0 {
+				propFuncs[n], err = NewGossipPropagationFunc(pc,
+					"PropagateGossip", store, nbrFailures[i], fanout)
+			} else {
+				propFuncs[n], err = NewPropagationFunc(pc,
+					"Propagate", store, nbrFailures[i])
+			}
 			require.NoError(t, err)
 		}
 
@@ -0,0 +1,97 @@
+package nat
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Front accepts the requests of the clients under a path prefix and forwards
+// them, with the prefix removed, to the websocket of the conode. The
+// websocket connections are upgraded through it.
+type Front struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewFront starts the front of the config, forwarding to the websocket
+// listening on the target address, which uses TLS if targetTLS is true.
+func NewFront(cfg *WebSocketConfig, target string,
+	targetTLS bool) (*Front, error) {
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't listen on %s: %v", cfg.Listen, err)
+	}
+	if cfg.TLSCertificate != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertificate,
+			cfg.TLSCertificateKey)
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("couldn't load the certificate: %v", err)
+		}
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{
+			cert}})
+	}
+
+	f := &Front{
+		listener: ln,
+		server: &http.Server{Handler: frontHandler(cfg.Prefix, target,
+			targetTLS)},
+	}
+	go func() {
+		err := f.server.Serve(ln)
+		if err != http.ErrServerClosed {
+			log.Error("websocket front stopped:", err)
+		}
+	}()
+	return f, nil
+}
+
+// Addr returns the address the front listens on.
+func (f *Front) Addr() net.Addr {
+	return f.listener.Addr()
+}
+
+// Close stops the front and closes its connections.
+func (f *Front) Close() error {
+	return f.server.Close()
+}
+
+// frontHandler forwards the requests under the prefix to the target. The
+// other requests are not found. The reverse proxy adds the address of the
+// client to the X-Forwarded-For header, so that the services don't take the
+// forwarded requests for local ones.
+func frontHandler(prefix, target string, targetTLS bool) http.Handler {
+	backend := &url.URL{Scheme: "http", Host: target}
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+	if targetTLS {
+		backend.Scheme = "https"
+		// The certificate of the websocket is for the public name of the
+		// conode, not for the local address, and the connection doesn't
+		// leave the host.
+		proxy.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		if len(path) == len(r.URL.Path) && prefix != "" ||
+			!strings.HasPrefix(path, "/") || path == "/" {
+			http.NotFound(w, r)
+			return
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		proxy.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,73 @@
+package nat
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// upgradeBackend answers the upgrades with the path of the request, and the
+// other requests with their path.
+func upgradeBackend(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") != "websocket" {
+		fmt.Fprint(w, r.URL.Path)
+		return
+	}
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n\r\n" + r.URL.Path)
+	buf.Flush()
+}
+
+func TestFront(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(upgradeBackend))
+	defer backend.Close()
+
+	f, err := NewFront(&WebSocketConfig{Listen: "127.0.0.1:0",
+		Prefix: "/conode"}, strings.TrimPrefix(backend.URL, "http://"), false)
+	require.NoError(t, err)
+	defer f.Close()
+	front := "http://" + f.Addr().String()
+
+	for path, want := range map[string]int{
+		"/conode/Status/Request": http.StatusOK,
+		"/Status/Request":        http.StatusNotFound,
+		"/conodeX/Status":        http.StatusNotFound,
+		"/conode/":               http.StatusNotFound,
+	} {
+		resp, err := http.Get(front + path)
+		require.NoError(t, err)
+		buf, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, want, resp.StatusCode, path)
+		if want == http.StatusOK {
+			require.Equal(t, "/Status/Request", string(buf))
+		}
+	}
+
+	conn, err := net.Dial("tcp", f.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /conode/ByzCoin/GetProof HTTP/1.1\r\n"+
+		"Host: conode\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	buf := make([]byte, len("/ByzCoin/GetProof"))
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "/ByzCoin/GetProof", string(buf))
+}
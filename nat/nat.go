@@ -0,0 +1,117 @@
+// Package nat lets a conode run behind a NAT or a reverse proxy.
+//
+// The conode advertises the Address and the URL of its private.toml, and
+// binds to its ListenAddress when it is set, so that the addresses it
+// advertises can differ from the ones it listens on. On top of that, the
+// conode loads a Config, which can:
+//
+//   - run a front for the websocket, accepting the requests of the clients
+//     for all the services under a path prefix, on port 443 for example, and
+//     forwarding them to the websocket of the conode
+//   - keep connections open to the nodes of its rosters, for a conode that
+//     can't be reached from outside: the other nodes send it their protocol
+//     messages over the connections it opened, as onet reuses the open
+//     connections
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultKeepAlive is how often the connections are checked when the config
+// doesn't say.
+const DefaultKeepAlive = 30 * time.Second
+
+// Config is the networking of a conode, as read from its TOML file:
+//
+//	[WebSocket]
+//	Listen = ":443"
+//	Prefix = "/conode"
+//	TLSCertificate = "fullchain.pem"
+//	TLSCertificateKey = "privkey.pem"
+//
+//	[Outbound]
+//	KeepAlive = "30s"
+type Config struct {
+	// WebSocket is the front of the websocket, if any.
+	WebSocket *WebSocketConfig
+	// Outbound is set if the conode can't be reached by the other nodes.
+	Outbound *OutboundConfig
+}
+
+// WebSocketConfig is the front of the websocket.
+type WebSocketConfig struct {
+	// Listen is the address the front listens on, like ":443".
+	Listen string
+	// Prefix is the path the requests of the clients start with, like
+	// "/conode", which must be the path of the URL of the conode.
+	Prefix string
+	// TLSCertificate and TLSCertificateKey are the PEM files of the
+	// certificate of the front. Without them, the front doesn't use TLS. A
+	// relative path is relative to the config file.
+	TLSCertificate    string
+	TLSCertificateKey string
+}
+
+// OutboundConfig keeps the connections to the other nodes open.
+type OutboundConfig struct {
+	// KeepAlive is how often the connections are checked, like "30s".
+	KeepAlive string
+
+	interval time.Duration
+}
+
+// Interval returns how often the connections are checked.
+func (oc *OutboundConfig) Interval() time.Duration {
+	if oc.interval == 0 {
+		return DefaultKeepAlive
+	}
+	return oc.interval
+}
+
+// LoadConfig reads the networking of a conode from a TOML file.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	_, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the network config: %v", err)
+	}
+	if cfg.WebSocket == nil && cfg.Outbound == nil {
+		return nil, errors.New("the network config has neither WebSocket " +
+			"nor Outbound")
+	}
+
+	if ws := cfg.WebSocket; ws != nil {
+		if ws.Listen == "" {
+			return nil, errors.New("the websocket front has no Listen address")
+		}
+		ws.Prefix = "/" + strings.Trim(ws.Prefix, "/")
+		if ws.Prefix == "/" {
+			ws.Prefix = ""
+		}
+		if (ws.TLSCertificate == "") != (ws.TLSCertificateKey == "") {
+			return nil, errors.New("the websocket front needs both " +
+				"TLSCertificate and TLSCertificateKey")
+		}
+		for _, file := range []*string{&ws.TLSCertificate,
+			&ws.TLSCertificateKey} {
+			if *file != "" && !filepath.IsAbs(*file) {
+				*file = filepath.Join(filepath.Dir(path), *file)
+			}
+		}
+	}
+
+	if oc := cfg.Outbound; oc != nil && oc.KeepAlive != "" {
+		oc.interval, err = time.ParseDuration(oc.KeepAlive)
+		if err != nil || oc.interval <= 0 {
+			return nil, fmt.Errorf("invalid KeepAlive %q", oc.KeepAlive)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,62 @@
+package nat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nat")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "network.toml")
+	write := func(s string) {
+		require.NoError(t, ioutil.WriteFile(fn, []byte(s), 0600))
+	}
+
+	write(`[WebSocket]
+Listen = ":443"
+Prefix = "conode/"
+TLSCertificate = "cert.pem"
+TLSCertificateKey = "/etc/key.pem"
+
+[Outbound]
+KeepAlive = "10s"
+`)
+	cfg, err := LoadConfig(fn)
+	require.NoError(t, err)
+	require.Equal(t, "/conode", cfg.WebSocket.Prefix)
+	require.Equal(t, filepath.Join(dir, "cert.pem"), cfg.WebSocket.TLSCertificate)
+	require.Equal(t, "/etc/key.pem", cfg.WebSocket.TLSCertificateKey)
+	require.Equal(t, 10*time.Second, cfg.Outbound.Interval())
+
+	write(`[WebSocket]
+Listen = ":443"
+Prefix = "/"
+`)
+	cfg, err = LoadConfig(fn)
+	require.NoError(t, err)
+	require.Equal(t, "", cfg.WebSocket.Prefix)
+	require.Nil(t, cfg.Outbound)
+
+	write("[Outbound]\n")
+	cfg, err = LoadConfig(fn)
+	require.NoError(t, err)
+	require.Equal(t, DefaultKeepAlive, cfg.Outbound.Interval())
+
+	for _, bad := range []string{
+		"",
+		"[WebSocket]\nPrefix = \"/conode\"\n",
+		"[WebSocket]\nListen = \":443\"\nTLSCertificate = \"cert.pem\"\n",
+		"[Outbound]\nKeepAlive = \"often\"\n",
+	} {
+		write(bad)
+		_, err = LoadConfig(fn)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,115 @@
+package nat
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// ServiceName is the name of the service keeping the connections open.
+const ServiceName = "NAT"
+
+func init() {
+	_, err := onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+}
+
+// Service keeps the connections of a conode that can't be reached open, and
+// receives the KeepAlive messages of the other nodes.
+type Service struct {
+	*onet.ServiceProcessor
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// KeepConnections sends a KeepAlive to every node of the rosters of the
+// skipchains known by this node at every interval, which opens the
+// connections that were closed. It returns a function stopping it.
+func (s *Service) KeepConnections(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.keepAlive()
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// keepAlive sends a KeepAlive to all the peers at once.
+func (s *Service) keepAlive() {
+	var wg sync.WaitGroup
+	for _, si := range s.peers() {
+		wg.Add(1)
+		go func(si *network.ServerIdentity) {
+			defer wg.Done()
+			if err := s.SendRaw(si, &KeepAlive{}); err != nil {
+				log.Lvlf2("%s: couldn't reach %s: %v", s.ServerIdentity(), si,
+					err)
+			}
+		}(si)
+	}
+	wg.Wait()
+}
+
+// peers returns the other nodes of the rosters of the latest blocks of the
+// skipchains, which include the ByzCoin ledgers.
+func (s *Service) peers() []*network.ServerIdentity {
+	sc, ok := s.Service(skipchain.ServiceName).(*skipchain.Service)
+	if !ok {
+		return nil
+	}
+	chains, err := sc.GetDB().GetSkipchains()
+	if err != nil {
+		log.Error(s.ServerIdentity(), "couldn't get the skipchains:", err)
+		return nil
+	}
+
+	seen := make(map[network.ServerIdentityID]bool)
+	var peers []*network.ServerIdentity
+	for _, sb := range chains {
+		if sb.Roster == nil {
+			continue
+		}
+		if _, me := sb.Roster.Search(s.ServerIdentity().ID); me == nil {
+			continue
+		}
+		for _, si := range sb.Roster.List {
+			if si.Equal(s.ServerIdentity()) || seen[si.ID] {
+				continue
+			}
+			seen[si.ID] = true
+			peers = append(peers, si)
+		}
+	}
+	return peers
+}
+
+// handleKeepAlive ignores the message, which only keeps the connection open.
+func (s *Service) handleKeepAlive(env *network.Envelope) error {
+	return nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{ServiceProcessor: onet.NewServiceProcessor(c)}
+	s.RegisterProcessorFunc(keepAliveMsgID, s.handleKeepAlive)
+	return s, nil
+}
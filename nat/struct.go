@@ -0,0 +1,11 @@
+package nat
+
+import (
+	"go.dedis.ch/onet/v3/network"
+)
+
+var keepAliveMsgID = network.RegisterMessage(&KeepAlive{})
+
+// KeepAlive is sent by a conode that can't be reached, so that its
+// connections to the other nodes stay open.
+type KeepAlive struct{}
@@ -0,0 +1,75 @@
+package personhood
+
+import (
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"golang.org/x/xerrors"
+)
+
+// CredentialVerifyPresentation fetches the credential from byzcoin and
+// verifies that the presentation answers the challenge, that its holder can
+// sign for the darc of the credential, and that its disclosures are committed
+// to in the credential. If fresh is true, the credential must also be renewed
+// and not expired. The verifier must pick a new random challenge for every
+// presentation.
+func CredentialVerifyPresentation(cl *byzcoin.Client, credIID byzcoin.InstanceID,
+	challenge []byte, p contracts.Presentation, fresh bool) error {
+	proof, err := cl.GetProof(credIID.Slice())
+	if err != nil {
+		return err
+	}
+	var cs contracts.CredentialStruct
+	err = proof.Proof.VerifyAndDecode(cothority.Suite, contracts.ContractCredentialID, &cs)
+	if err != nil {
+		return err
+	}
+	if fresh && !cs.Fresh(time.Now()) {
+		return xerrors.New("the credential is not fresh")
+	}
+	if err := p.Verify(cs, credIID, challenge); err != nil {
+		return err
+	}
+
+	_, _, _, darcID, err := proof.Proof.KeyValue()
+	if err != nil {
+		return err
+	}
+	d := getDarc(cl, darcID)
+	if d == nil {
+		return xerrors.New("couldn't get the darc of the credential")
+	}
+	getDarcStr := func(s string, latest bool) *darc.Darc {
+		if !strings.HasPrefix(s, "darc:") {
+			return nil
+		}
+		id, err := hex.DecodeString(s[5:])
+		if err != nil {
+			return nil
+		}
+		return getDarc(cl, id)
+	}
+	holder := darc.NewIdentityEd25519(p.Holder).String()
+	if err := darc.EvalExpr(d.Rules.GetSignExpr(), getDarcStr, holder); err != nil {
+		return xerrors.Errorf("the holder can't sign for the credential: %v", err)
+	}
+	return nil
+}
+
+// getDarc returns the latest version of the darc, or nil if it isn't found.
+func getDarc(cl *byzcoin.Client, id darc.ID) *darc.Darc {
+	proof, err := cl.GetProof(id)
+	if err != nil {
+		return nil
+	}
+	var d darc.Darc
+	if err := proof.Proof.VerifyAndDecode(cothority.Suite, byzcoin.ContractDarcID, &d); err != nil {
+		return nil
+	}
+	return &d
+}
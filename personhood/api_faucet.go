@@ -0,0 +1,76 @@
+package personhood
+
+import (
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// FaucetGet returns the current state of a faucet instance.
+func FaucetGet(cl *byzcoin.Client, faucetIID byzcoin.InstanceID) (*contracts.FaucetStruct, error) {
+	fs, _, err := faucetGet(cl, faucetIID)
+	return fs, err
+}
+
+// FaucetClaim claims the coins of the current epoch for the attendee of the
+// keypair, and sends them to the account.
+func FaucetClaim(cl *byzcoin.Client, faucetIID byzcoin.InstanceID, kp key.Pair,
+	atts contracts.Attendees, account byzcoin.InstanceID) error {
+	fs, index, err := faucetGet(cl, faucetIID)
+	if err != nil {
+		return err
+	}
+	// The claim goes into the next block.
+	epoch := uint64(index+1) / fs.EpochBlocks
+
+	var lrs []byte
+	for i, p := range atts.Keys {
+		if p.Equal(kp.Public) {
+			lrs = anon.Sign(&contracts.SuiteBlake2s{}, contracts.FaucetClaim(account), atts.Keys,
+				contracts.FaucetScope(faucetIID, epoch), i, kp.Private)
+			break
+		}
+	}
+	if lrs == nil {
+		return xerrors.New("didn't find public key of keypair in attendees")
+	}
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID: faucetIID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contracts.ContractFaucetID,
+			Command:    "claim",
+			Args: byzcoin.Arguments{
+				{Name: "account", Value: account[:]},
+				{Name: "lrs", Value: lrs},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cl.AddTransactionAndWait(ctx, 5)
+	return err
+}
+
+// faucetGet returns the faucet and the index of the latest block.
+func faucetGet(cl *byzcoin.Client, faucetIID byzcoin.InstanceID) (*contracts.FaucetStruct, int, error) {
+	proof, err := cl.GetProof(faucetIID.Slice())
+	if err != nil {
+		return nil, 0, err
+	}
+	_, value, cID, _, err := proof.Proof.KeyValue()
+	if err != nil {
+		return nil, 0, err
+	}
+	if cID != contracts.ContractFaucetID {
+		return nil, 0, xerrors.New("given faucetIID is not of contract-type Faucet")
+	}
+	var fs contracts.FaucetStruct
+	if err := protobuf.Decode(value, &fs); err != nil {
+		return nil, 0, err
+	}
+	return &fs, proof.Proof.Latest.Index, nil
+}
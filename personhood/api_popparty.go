@@ -10,6 +10,7 @@ import (
 	"go.dedis.ch/cothority/v3/personhood/contracts"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
@@ -150,6 +151,55 @@ func PopPartyFinalizeDetailed(
 	return cl.AddTransactionAndWait(ctx, 5)
 }
 
+// PopPartyAttest is a method to be called by a remote attendee, instead of
+// having their public key scanned. During the scanning, an organizer verifies
+// the liveness of the attendee, for example in a video call, and signs the
+// contracts.RemoteAttestation message. When the party is finalized, the
+// attendee is added to the attendees.
+func PopPartyAttest(
+	cl *byzcoin.Client,
+	popIID byzcoin.InstanceID,
+	kp key.Pair,
+	org darc.Identity,
+	orgSig []byte,
+) error {
+	msg, err := contracts.RemoteAttestation(popIID, kp.Public)
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.Sign(cothority.Suite, kp.Private, msg)
+	if err != nil {
+		return err
+	}
+	keyBuf, err := kp.Public.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	orgBuf, err := protobuf.Encode(&org)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID: popIID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contracts.ContractPopPartyID,
+			Command:    "attest",
+			Args: byzcoin.Arguments{
+				{Name: "attendee", Value: keyBuf},
+				{Name: "attendeeSig", Value: sig},
+				{Name: "organizer", Value: orgBuf},
+				{Name: "organizerSig", Value: orgSig},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cl.AddTransactionAndWait(ctx, 5)
+	return err
+}
+
 // PopPartyMine is a method to be called by an outside client. It collects the reward for a given
 // attendee of the party. For convenience, this can be called with some of the arguments being 'nil'.
 //
@@ -0,0 +1,140 @@
+package personhood
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ReputationGet returns the current state of a reputation instance.
+func ReputationGet(cl *byzcoin.Client, repIID byzcoin.InstanceID) (*contracts.ReputationStruct, error) {
+	proof, err := cl.GetProof(repIID.Slice())
+	if err != nil {
+		return nil, err
+	}
+	_, value, cID, _, err := proof.Proof.KeyValue()
+	if err != nil {
+		return nil, err
+	}
+	if cID != contracts.ContractReputationID {
+		return nil, xerrors.New("given repIID is not of contract-type Reputation")
+	}
+	var rs contracts.ReputationStruct
+	if err := protobuf.Decode(value, &rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// ReputationPoints returns the reputation of the member with the given tag.
+func ReputationPoints(cl *byzcoin.Client, repIID byzcoin.InstanceID, tag []byte) (uint64, error) {
+	rs, err := ReputationGet(cl, repIID)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range rs.Members {
+		if bytes.Equal(m.Tag, tag) {
+			return m.Points, nil
+		}
+	}
+	return 0, xerrors.New("no member with this tag")
+}
+
+// ReputationRegister registers an attendee of the party of the reputation
+// instance and returns their tag, which identifies them to the other
+// members.
+func ReputationRegister(cl *byzcoin.Client, repIID byzcoin.InstanceID, kp key.Pair,
+	atts contracts.Attendees) ([]byte, error) {
+	lrs, err := reputationSign(repIID, kp, atts, contracts.ReputationRegister())
+	if err != nil {
+		return nil, err
+	}
+	tag, err := anon.Verify(&contracts.SuiteBlake2s{}, contracts.ReputationRegister(), atts.Keys, repIID[:], lrs)
+	if err != nil {
+		return nil, err
+	}
+	err = reputationInvoke(cl, repIID, "register", byzcoin.Arguments{{Name: "lrs", Value: lrs}})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// ReputationGrant gives points to the member with the 'to' tag, on behalf
+// of the registered attendee of the keypair.
+func ReputationGrant(cl *byzcoin.Client, repIID byzcoin.InstanceID, kp key.Pair,
+	atts contracts.Attendees, to []byte, points uint64) error {
+	rs, err := ReputationGet(cl, repIID)
+	if err != nil {
+		return err
+	}
+	tag, err := ReputationTag(repIID, kp, atts)
+	if err != nil {
+		return err
+	}
+	var grants = -1
+	for _, m := range rs.Members {
+		if bytes.Equal(m.Tag, tag) {
+			grants = int(m.Grants)
+			break
+		}
+	}
+	if grants < 0 {
+		return xerrors.New("the keypair is not registered")
+	}
+
+	lrs, err := reputationSign(repIID, kp, atts, contracts.ReputationGrant(to, points, uint64(grants)))
+	if err != nil {
+		return err
+	}
+	pointsBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(pointsBuf, points)
+	return reputationInvoke(cl, repIID, "grant", byzcoin.Arguments{
+		{Name: "lrs", Value: lrs},
+		{Name: "from", Value: tag},
+		{Name: "to", Value: to},
+		{Name: "points", Value: pointsBuf},
+	})
+}
+
+// ReputationTag returns the tag of the attendee of the keypair in the
+// reputation instance. It is the same for all the signatures of the
+// attendee on this instance, but cannot be linked to their public key.
+func ReputationTag(repIID byzcoin.InstanceID, kp key.Pair, atts contracts.Attendees) ([]byte, error) {
+	lrs, err := reputationSign(repIID, kp, atts, contracts.ReputationRegister())
+	if err != nil {
+		return nil, err
+	}
+	return anon.Verify(&contracts.SuiteBlake2s{}, contracts.ReputationRegister(), atts.Keys, repIID[:], lrs)
+}
+
+func reputationSign(repIID byzcoin.InstanceID, kp key.Pair, atts contracts.Attendees, msg []byte) ([]byte, error) {
+	for i, p := range atts.Keys {
+		if p.Equal(kp.Public) {
+			return anon.Sign(&contracts.SuiteBlake2s{}, msg, atts.Keys, repIID[:], i, kp.Private), nil
+		}
+	}
+	return nil, xerrors.New("didn't find public key of keypair in attendees")
+}
+
+func reputationInvoke(cl *byzcoin.Client, repIID byzcoin.InstanceID, cmd string, args byzcoin.Arguments) error {
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID: repIID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: contracts.ContractReputationID,
+			Command:    cmd,
+			Args:       args,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cl.AddTransactionAndWait(ctx, 5)
+	return err
+}
@@ -6,12 +6,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"strings"
+	"time"
 
 	"golang.org/x/xerrors"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
 
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
@@ -22,6 +24,9 @@ import (
 // ContractCredentialID denotes a contract that holds an identity with all its attributes.
 var ContractCredentialID = "credential"
 
+// CredentialValidity is how long a credential stays fresh after a renewal.
+const CredentialValidity = 365 * 24 * time.Hour
+
 // ContractCredentialFromBytes returns a credential-contract given a slice of bytes, or an error if something
 // went wrong.
 func ContractCredentialFromBytes(in []byte) (byzcoin.Contract, error) {
@@ -83,26 +88,37 @@ func (c *ContractCredential) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.I
 	}
 	log.Lvlf3("Spawning Credential to %x", ca.Slice())
 
-	var ciBuf []byte
-	if ciBuf = inst.Spawn.Args.Search("credential"); ciBuf == nil {
-		ciBuf, err = protobuf.Encode(&c.CredentialStruct)
-		if err != nil {
-			return nil, nil, errors.New("couldn't encode CredentialInstance: " + err.Error())
-		}
-	} else {
+	ciBuf := inst.Spawn.Args.Search("credential")
+	if ciBuf != nil {
 		err = protobuf.Decode(ciBuf, &c.CredentialStruct)
 		if err != nil {
 			return nil, nil, errors.New("got wrong credential data: " + err.Error())
 		}
 	}
+	// Before the renewals, the credential is stored as it was given.
+	renewals := rst.GetVersion() >= byzcoin.VersionCredentialRenewal
+	if renewals {
+		// Only a renewal can make a credential fresh.
+		c.Expires, c.Party, c.PartyTime = 0, nil, 0
+	}
+	if ciBuf == nil || renewals {
+		ciBuf, err = protobuf.Encode(&c.CredentialStruct)
+		if err != nil {
+			return nil, nil, errors.New("couldn't encode CredentialInstance: " + err.Error())
+		}
+	}
 	sc = []byzcoin.StateChange{
 		byzcoin.NewStateChange(byzcoin.Create, ca, ContractCredentialID, ciBuf, darcID),
 	}
 	return
 }
 
-// Invoke has the following command:
-//  - update to change the credential
+// Invoke has the following commands:
+//  - update to change the credential, except for its renewal
+//  - recover to change the darc of the credential with the signatures of its trustees
+//  - renew to make the credential fresh for CredentialValidity with the 'signature' of
+//    the attendee 'public' of the finalized pop-party 'party', which must be newer than
+//    the party of the last renewal
 func (c *ContractCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
 	cout = coins
 
@@ -114,12 +130,21 @@ func (c *ContractCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.
 
 	switch inst.Invoke.Command {
 	case "update":
-		// update overwrites the credential information
+		// update overwrites the credential information, but keeps the renewal
+		renewal := c.CredentialStruct
 		credBuf := inst.Invoke.Args.Search("credential")
+		c.CredentialStruct = CredentialStruct{}
 		err = protobuf.Decode(credBuf, &c.CredentialStruct)
 		if err != nil {
 			return nil, nil, errors.New("got wrong credential data: " + err.Error())
 		}
+		if rst.GetVersion() >= byzcoin.VersionCredentialRenewal {
+			c.Expires, c.Party, c.PartyTime = renewal.Expires, renewal.Party, renewal.PartyTime
+			credBuf, err = protobuf.Encode(&c.CredentialStruct)
+			if err != nil {
+				return nil, nil, errors.New("couldn't encode credential: " + err.Error())
+			}
+		}
 
 		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
 			ContractCredentialID, credBuf, darcID))
@@ -225,8 +250,61 @@ func (c *ContractCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.
 		}
 		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, byzcoin.NewInstanceID(newDarc.GetBaseID()),
 			byzcoin.ContractDarcID, newDarcBuf, newDarc.GetBaseID()))
+	case "renew":
+		if rst.GetVersion() < byzcoin.VersionCredentialRenewal {
+			return nil, nil, errors.New("renewals need a newer version of byzcoin")
+		}
+		partyID := byzcoin.NewInstanceID(inst.Invoke.Args.Search("party"))
+		party, err := getFinalizedParty(rst, partyID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.Party != nil && party.Description.DateTime <= c.PartyTime {
+			return nil, nil, errors.New("need a newer party than the last renewal")
+		}
+		public := cothority.Suite.Point()
+		pubBuf := inst.Invoke.Args.Search("public")
+		if err = public.UnmarshalBinary(pubBuf); err != nil {
+			return nil, nil, errors.New("wrong 'public' argument: " + err.Error())
+		}
+		// An attendee renews a single credential per party.
+		usedID := credentialRenewalID(partyID, pubBuf)
+		if _, _, _, _, err := rst.GetValues(usedID[:]); err == nil {
+			return nil, nil, errors.New("the attendee already renewed a credential at this party")
+		}
+		attended := false
+		for _, att := range party.Attendees.Keys {
+			if att.Equal(public) {
+				attended = true
+				break
+			}
+		}
+		if !attended {
+			return nil, nil, errors.New("'public' is not an attendee of the party")
+		}
+		err = schnorr.Verify(cothority.Suite, public,
+			CredentialRenewal(inst.InstanceID, partyID), inst.Invoke.Args.Search("signature"))
+		if err != nil {
+			return nil, nil, errors.New("wrong attendee signature: " + err.Error())
+		}
+		tr, ok := rst.(byzcoin.TimeReader)
+		if !ok {
+			return nil, nil, errors.New("couldn't get the time of the block")
+		}
+		c.Expires = tr.GetCurrentBlockTimestamp()/1e9 + int64(CredentialValidity/time.Second)
+		c.Party = &partyID
+		c.PartyTime = party.Description.DateTime
+		credBuf, err := protobuf.Encode(&c.CredentialStruct)
+		if err != nil {
+			return nil, nil, errors.New("couldn't encode credential: " + err.Error())
+		}
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractCredentialID, credBuf, darcID),
+			byzcoin.NewStateChange(byzcoin.Create, usedID,
+				ContractCredentialID, inst.InstanceID[:], nil))
+
 	default:
-		err = errors.New("credential contract can only 'update'")
+		err = errors.New("credential contract can only 'update', 'recover' or 'renew'")
 		return
 	}
 	return
@@ -271,6 +349,59 @@ func NewInstructionCredentialSpawn(dst byzcoin.InstanceID, did darc.ID,
 	return
 }
 
+// NewInstructionCredentialRenew returns an instruction that is ready to be
+// sent to byzcoin to renew a credential with the attendee kp of the party.
+// It still needs the signature of the darc of the credential.
+func NewInstructionCredentialRenew(credIID, party byzcoin.InstanceID,
+	kp *key.Pair) (inst byzcoin.Instruction, err error) {
+	pubBuf, err := kp.Public.MarshalBinary()
+	if err != nil {
+		return
+	}
+	sig, err := schnorr.Sign(cothority.Suite, kp.Private, CredentialRenewal(credIID, party))
+	if err != nil {
+		return
+	}
+	inst.InstanceID = credIID
+	inst.Invoke = &byzcoin.Invoke{
+		ContractID: ContractCredentialID,
+		Command:    "renew",
+		Args: byzcoin.Arguments{
+			newArg("party", party[:]),
+			newArg("public", pubBuf),
+			newArg("signature", sig),
+		},
+	}
+	return
+}
+
+// CredentialRenewal returns the message an attendee of the party signs to
+// renew the credential.
+func CredentialRenewal(credIID, party byzcoin.InstanceID) []byte {
+	h := sha256.New()
+	h.Write([]byte("credential-renewal"))
+	h.Write(credIID[:])
+	h.Write(party[:])
+	return h.Sum(nil)
+}
+
+// credentialRenewalID returns the ID of the instance recording that the
+// attendee public of the party renewed a credential. It has no darc, so it
+// can't be changed, and holds the ID of the renewed credential.
+func credentialRenewalID(party byzcoin.InstanceID, public []byte) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write([]byte("credential-renewal-used"))
+	h.Write(party[:])
+	h.Write(public)
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+// Fresh returns true if the credential has been renewed and is not expired
+// at the given time.
+func (cs CredentialStruct) Fresh(now time.Time) bool {
+	return cs.Expires > now.Unix()
+}
+
 func getDarc(rst byzcoin.ReadOnlyStateTrie, darcID darc.ID) (*darc.Darc, error) {
 	darcBuf, _, cid, _, err := rst.GetValues(darcID)
 	if err != nil {
@@ -2,10 +2,14 @@ package contracts
 
 import (
 	"testing"
+	"time"
 
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/protobuf"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
 )
 
@@ -26,3 +30,166 @@ func TestContractCredential_Spawn(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, cred, cred2)
 }
+
+// rostTime is a ROSTSimul with the timestamp of the current block.
+type rostTime struct {
+	*byzcoin.ROSTSimul
+	timestamp int64
+}
+
+func (r *rostTime) GetCurrentBlockTimestamp() int64 {
+	return r.timestamp
+}
+
+// Renews a credential with attendances to newer and newer parties.
+func TestContractCredential_Renew(t *testing.T) {
+	rost := byzcoin.NewROSTSimul()
+	d, err := rost.CreateBasicDarc(nil, "credential")
+	require.NoError(t, err)
+	attendee := key.NewKeyPair(cothority.Suite)
+	credIID := byzcoin.NewInstanceID([]byte("credential"))
+	cred := CredentialStruct{Expires: 1e10}
+	require.NoError(t, rost.CreateSCB(byzcoin.Create, ContractCredentialID, credIID, &cred, d.GetBaseID()))
+
+	// Spawning doesn't keep the renewal of the argument.
+	inst, err := NewInstructionCredentialSpawn(byzcoin.NewInstanceID(nil), d.GetBaseID(),
+		byzcoin.NewInstanceID(nil), cred)
+	require.NoError(t, err)
+	scs, _, err := (&ContractCredential{}).Spawn(rost, inst, nil)
+	require.NoError(t, err)
+	cc := &ContractCredential{}
+	require.NoError(t, protobuf.Decode(scs[0].Value, &cc.CredentialStruct))
+	require.False(t, cc.Fresh(time.Now()))
+
+	// Before the renewals, the credential is stored as it is given.
+	rost.Version = byzcoin.VersionKeyRotation
+	scs, _, err = (&ContractCredential{}).Spawn(rost, inst, nil)
+	require.NoError(t, err)
+	require.Equal(t, inst.Spawn.Args.Search("credential"), scs[0].Value)
+	rost.Version = byzcoin.CurrentVersion
+
+	newParty := func(name string, dateTime uint64, state int) byzcoin.InstanceID {
+		id := byzcoin.NewInstanceID([]byte(name))
+		pps := PopPartyStruct{State: state, Description: PopDesc{DateTime: dateTime},
+			Attendees: Attendees{Keys: []kyber.Point{attendee.Public}}}
+		require.NoError(t, rost.CreateSCB(byzcoin.Create, ContractPopPartyID, id, &pps, d.GetBaseID()))
+		return id
+	}
+	now := time.Now()
+	rt := &rostTime{ROSTSimul: rost, timestamp: now.UnixNano()}
+	renew := func(party byzcoin.InstanceID, kp *key.Pair) error {
+		inst, err := NewInstructionCredentialRenew(credIID, party, kp)
+		require.NoError(t, err)
+		scs, _, err := cc.Invoke(rt, inst, nil)
+		if err == nil {
+			_, err = rost.StoreAllToReplica(scs)
+		}
+		return err
+	}
+
+	scanning := newParty("scanning", 1, ScanningState)
+	require.Error(t, renew(scanning, attendee))
+	party1 := newParty("party1", 1, FinalizedState)
+	require.Error(t, renew(party1, key.NewKeyPair(cothority.Suite)))
+	require.NoError(t, renew(party1, attendee))
+	require.True(t, cc.Fresh(now))
+	require.False(t, cc.Fresh(now.Add(CredentialValidity)))
+
+	// The update keeps the renewal.
+	update, err := protobuf.Encode(&CredentialStruct{})
+	require.NoError(t, err)
+	_, _, err = cc.Invoke(rt, byzcoin.Instruction{
+		InstanceID: credIID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCredentialID,
+			Command:    "update",
+			Args:       byzcoin.Arguments{newArg("credential", update)},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.True(t, cc.Fresh(now))
+
+	// The next renewal needs a newer party.
+	rt.timestamp = now.Add(CredentialValidity).UnixNano()
+	require.Error(t, renew(party1, attendee))
+	party2 := newParty("party2", 2, FinalizedState)
+	require.NoError(t, renew(party2, attendee))
+	require.True(t, cc.Fresh(now.Add(CredentialValidity)))
+
+	// The attendee can't renew another credential at the same party.
+	otherIID := byzcoin.NewInstanceID([]byte("other credential"))
+	require.NoError(t, rost.CreateSCB(byzcoin.Create, ContractCredentialID, otherIID,
+		&CredentialStruct{}, d.GetBaseID()))
+	inst, err = NewInstructionCredentialRenew(otherIID, party2, attendee)
+	require.NoError(t, err)
+	_, _, err = (&ContractCredential{}).Invoke(rt, inst, nil)
+	require.Error(t, err)
+
+	// Older versions of byzcoin don't know about renewals.
+	rost.Version = byzcoin.VersionKeyRotation
+	require.Error(t, renew(newParty("party3", 3, FinalizedState), attendee))
+}
+
+// Discloses some attributes of a credential.
+func TestCredentialStruct_VerifyDisclosures(t *testing.T) {
+	adult := NewDisclosure("personhood", "over18", []byte{1})
+	party := NewDisclosure("personhood", "party", []byte("partyX"))
+	name := NewDisclosure("public", "name", []byte("Alice"))
+	cs := CredentialStruct{}
+	cs.Commit(adult, party, name)
+	require.Equal(t, 3, len(cs.Commitments))
+
+	require.NoError(t, cs.VerifyDisclosures([]Disclosure{adult, party}))
+	require.NoError(t, cs.VerifyDisclosures(nil))
+
+	forged := adult
+	forged.Value = []byte{0}
+	require.Error(t, cs.VerifyDisclosures([]Disclosure{forged}))
+	moved := name
+	moved.Name = "nickname"
+	require.Error(t, cs.VerifyDisclosures([]Disclosure{moved}))
+	unsalted := NewDisclosure("personhood", "over18", []byte{1})
+	unsalted.Salt = nil
+	cs.Commit(unsalted)
+	require.Error(t, cs.VerifyDisclosures([]Disclosure{unsalted}))
+
+	// The commitments survive the encoding of the credential.
+	buf, err := protobuf.Encode(&cs)
+	require.NoError(t, err)
+	var cs2 CredentialStruct
+	require.NoError(t, protobuf.Decode(buf, &cs2))
+	require.NoError(t, cs2.VerifyDisclosures([]Disclosure{adult, party, name}))
+}
+
+// Presents some attributes of a credential to a verifier.
+func TestPresentation_Verify(t *testing.T) {
+	adult := NewDisclosure("personhood", "over18", []byte{1})
+	name := NewDisclosure("public", "name", []byte("Alice"))
+	cs := CredentialStruct{}
+	cs.Commit(adult, name)
+	credIID := byzcoin.NewInstanceID([]byte("credential"))
+	holder := key.NewKeyPair(cothority.Suite)
+	challenge := []byte("a fresh challenge of the verifier")
+
+	p, err := NewPresentation(credIID, challenge, []Disclosure{adult}, holder)
+	require.NoError(t, err)
+	require.NoError(t, p.Verify(cs, credIID, challenge))
+
+	// The presentation can't be replayed to another verifier, or for another
+	// credential.
+	require.Error(t, p.Verify(cs, credIID, []byte("the challenge of another verifier")))
+	require.Error(t, p.Verify(cs, byzcoin.NewInstanceID([]byte("other")), challenge))
+	require.Error(t, p.Verify(cs, credIID, nil))
+
+	// The disclosures can't be changed.
+	changed := p
+	changed.Disclosures = []Disclosure{name}
+	require.Error(t, changed.Verify(cs, credIID, challenge))
+
+	// Somebody who got the disclosures can't present them for the holder.
+	stolen, err := NewPresentation(credIID, challenge, []Disclosure{adult},
+		key.NewKeyPair(cothority.Suite))
+	require.NoError(t, err)
+	stolen.Holder = holder.Public
+	require.Error(t, stolen.Verify(cs, credIID, challenge))
+}
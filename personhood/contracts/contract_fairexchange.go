@@ -0,0 +1,364 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+// ContractFairExchangeID denotes a contract that lets two players exchange
+// moves fairly: the first one commits to a move, the second one plays in the
+// clear, and the first one reveals. A Payoff chosen at spawn decides how the
+// stakes are shared, so applications only have to write their payoff to get
+// the commit, reveal and timeout of the exchange.
+var ContractFairExchangeID = "fairExchange"
+
+// Payoff is the logic of an application on top of a fair exchange.
+type Payoff interface {
+	// VerifySecond returns an error if the move of the second player is not
+	// valid.
+	VerifySecond(second []byte) error
+	// Payout returns the coins each player gets out of the pot, given the
+	// revealed move of the first player and the move of the second player.
+	// Both shares must add up to the pot.
+	Payout(first, second []byte, pot uint64) (toFirst, toSecond uint64, err error)
+}
+
+var payoffs = map[string]Payoff{}
+
+// RegisterPayoff makes a payoff available to the fair exchanges under the
+// given ID. It must be called from an init function, so that all the nodes
+// know the same payoffs before they start.
+func RegisterPayoff(id string, p Payoff) error {
+	if _, exists := payoffs[id]; exists {
+		return errors.New("payoff " + id + " already registered")
+	}
+	payoffs[id] = p
+	return nil
+}
+
+// ContractFairExchange embeds the BasicContract to verify the spawning darc.
+type ContractFairExchange struct {
+	byzcoin.BasicContract
+	FairExchangeStruct
+}
+
+// ContractFairExchangeFromBytes returns a ContractFairExchange given a slice
+// of bytes.
+func ContractFairExchangeFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractFairExchange{}
+	err := protobuf.Decode(in, &c.FairExchangeStruct)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal FairExchangeStruct: " + err.Error())
+	}
+	return c, nil
+}
+
+// VerifyInstruction overrides the basic VerifyInstruction for the invoke
+// commands, because the second player is not in the darc, and everybody can
+// reveal or time out an exchange: the coins always go to the accounts of the
+// players.
+func (c ContractFairExchange) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	if inst.GetType() == byzcoin.InvokeType {
+		if c.Done {
+			return errors.New("this exchange has already finished")
+		}
+		return nil
+	}
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Spawn creates a new fair exchange with the stake of the first player in
+// the input coins. The following arguments are needed:
+//  - payoff is the ID of a registered Payoff
+//  - hash is the sha256 of the move of the first player
+//  - account is the coin instance that receives the coins of the first player
+//  - timeout is the number of blocks each player has to do their next step
+func (c ContractFairExchange) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get darc: " + err.Error())
+	}
+
+	c.Payoff = string(inst.Spawn.Args.Search("payoff"))
+	if _, ok := payoffs[c.Payoff]; !ok {
+		return nil, nil, errors.New("unknown payoff: " + c.Payoff)
+	}
+	c.FirstHash = inst.Spawn.Args.Search("hash")
+	if len(c.FirstHash) != sha256.Size {
+		return nil, nil, errors.New("need a hash of the first move")
+	}
+	timeoutBuf := inst.Spawn.Args.Search("timeout")
+	if len(timeoutBuf) != 8 {
+		return nil, nil, errors.New("need timeout argument")
+	}
+	c.Timeout = binary.LittleEndian.Uint64(timeoutBuf)
+	if len(coins) == 0 || coins[0].Value == 0 {
+		return nil, nil, errors.New("fair exchange needs some coins as input")
+	}
+	if coins[0].Value > math.MaxUint64/2 {
+		return nil, nil, errors.New("the stake doesn't fit twice in a coin")
+	}
+	c.Stake = coins[0]
+	c.FirstAccount, err = coinAccount(rst, inst.Spawn.Args.Search("account"), c.Stake.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	cout[0].Value = 0
+	c.Deadline = blockIndex(rst) + c.Timeout
+
+	buf, err := protobuf.Encode(&c.FairExchangeStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal FairExchangeStruct: " + err.Error())
+	}
+	ca, err := inst.DeriveIDArg("", "preID")
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get deriveID: %v", err)
+	}
+	scs = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Create, ca, ContractFairExchangeID, buf, darcID),
+	}
+	return
+}
+
+// Invoke uses the following commands:
+//  - second stores the 'move' of the second player, who puts the same stake as
+//    input coins, and gets the coins in 'account'
+//  - reveal takes the 'prehash' of the first move and pays out the pot with the
+//    payoff of the exchange
+//  - timeout refunds the first player if nobody played second, or gives the pot
+//    to the second player if the first one didn't reveal, once the deadline is
+//    passed
+func (c *ContractFairExchange) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get instance data: " + err.Error())
+	}
+	payoff, ok := payoffs[c.Payoff]
+	if !ok {
+		return nil, nil, errors.New("unknown payoff: " + c.Payoff)
+	}
+	index := blockIndex(rst)
+	pot := 2 * c.Stake.Value
+
+	switch inst.Invoke.Command {
+	case "second":
+		if c.Second != nil {
+			return nil, nil, errors.New("second player already played")
+		}
+		if index >= c.Deadline {
+			return nil, nil, errors.New("the exchange timed out")
+		}
+		move := inst.Invoke.Args.Search("move")
+		if len(move) == 0 {
+			return nil, nil, errors.New("need a move")
+		}
+		if err = payoff.VerifySecond(move); err != nil {
+			return nil, nil, errors.New("invalid move: " + err.Error())
+		}
+		if len(coins) == 0 || !coins[0].Name.Equal(c.Stake.Name) ||
+			coins[0].Value != c.Stake.Value {
+			return nil, nil, errors.New("input coins don't match the stake of the first player")
+		}
+		c.SecondAccount, err = coinAccount(rst, inst.Invoke.Args.Search("account"), c.Stake.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Both players are paid with separate updates of their account,
+		// so they can't share one.
+		if c.SecondAccount.Equal(c.FirstAccount) {
+			return nil, nil, errors.New("the second player needs another account than the first player")
+		}
+		cout[0].Value = 0
+		c.Second = move
+		c.Deadline = index + c.Timeout
+
+	case "reveal":
+		if c.Second == nil {
+			return nil, nil, errors.New("second player didn't play yet")
+		}
+		preHash := inst.Invoke.Args.Search("prehash")
+		fph := sha256.Sum256(preHash)
+		if !bytes.Equal(c.FirstHash, fph[:]) {
+			return nil, nil, errors.New("wrong prehash for first player")
+		}
+		toFirst, toSecond, err := payoff.Payout(preHash, c.Second, pot)
+		if err != nil {
+			return nil, nil, errors.New("couldn't compute payout: " + err.Error())
+		}
+		if toFirst > pot || toSecond != pot-toFirst {
+			return nil, nil, errors.New("payout doesn't add up to the pot")
+		}
+		scs, err = payCoin(rst, scs, c.FirstAccount, toFirst)
+		if err != nil {
+			return nil, nil, err
+		}
+		scs, err = payCoin(rst, scs, c.SecondAccount, toSecond)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.Done = true
+
+	case "timeout":
+		if index < c.Deadline {
+			return nil, nil, fmt.Errorf("cannot time out before block %d", c.Deadline)
+		}
+		if c.Second == nil {
+			scs, err = payCoin(rst, scs, c.FirstAccount, c.Stake.Value)
+		} else {
+			scs, err = payCoin(rst, scs, c.SecondAccount, pot)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		c.Done = true
+
+	default:
+		return nil, nil, errors.New("fair exchange can only 'second', 'reveal' or 'timeout'")
+	}
+
+	buf, err := protobuf.Encode(&c.FairExchangeStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal FairExchangeStruct: " + err.Error())
+	}
+	scs = append(scs, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractFairExchangeID, buf, darcID))
+	return
+}
+
+// payCoin adds the state change that credits value coins to the account.
+func payCoin(rst byzcoin.ReadOnlyStateTrie, scs []byzcoin.StateChange,
+	account byzcoin.InstanceID, value uint64) ([]byzcoin.StateChange, error) {
+	if value == 0 {
+		return scs, nil
+	}
+	val, _, _, accountDarc, err := rst.GetValues(account.Slice())
+	if err != nil {
+		return nil, errors.New("couldn't get account: " + err.Error())
+	}
+	var coin byzcoin.Coin
+	err = protobuf.Decode(val, &coin)
+	if err != nil {
+		return nil, errors.New("couldn't decode coin: " + err.Error())
+	}
+	if err = coin.SafeAdd(value); err != nil {
+		return nil, err
+	}
+	coinBuf, err := protobuf.Encode(&coin)
+	if err != nil {
+		return nil, errors.New("couldn't encode coin: " + err.Error())
+	}
+	return append(scs, byzcoin.NewStateChange(byzcoin.Update, account,
+		contracts.ContractCoinID, coinBuf, accountDarc)), nil
+}
+
+// coinAccount checks that the account is a coin instance of the given name.
+func coinAccount(rst byzcoin.ReadOnlyStateTrie, account []byte, name byzcoin.InstanceID) (byzcoin.InstanceID, error) {
+	if len(account) != 32 {
+		return byzcoin.InstanceID{}, errors.New("need a valid account")
+	}
+	val, _, cid, _, err := rst.GetValues(account)
+	if err != nil {
+		return byzcoin.InstanceID{}, errors.New("couldn't get account: " + err.Error())
+	}
+	if cid != contracts.ContractCoinID {
+		return byzcoin.InstanceID{}, errors.New("account is not of coin type")
+	}
+	var coin byzcoin.Coin
+	if err = protobuf.Decode(val, &coin); err != nil {
+		return byzcoin.InstanceID{}, errors.New("couldn't decode coin: " + err.Error())
+	}
+	if !coin.Name.Equal(name) {
+		return byzcoin.InstanceID{}, errors.New("account is not of the same type of coin")
+	}
+	return byzcoin.NewInstanceID(account), nil
+}
+
+// blockIndex returns the index of the block being built, or 0 if it is not
+// known.
+func blockIndex(rst byzcoin.ReadOnlyStateTrie) uint64 {
+	if index := rst.GetIndex(); index > 0 {
+		return uint64(index)
+	}
+	return 0
+}
+
+// PayoffRoPaSci is the rock-paper-scissors payoff: the first byte of each
+// move modulo 3 is the choice of the player, the winner takes the pot, and
+// a draw gives each player their stake back.
+type PayoffRoPaSci struct{}
+
+// VerifySecond accepts 1-byte moves.
+func (PayoffRoPaSci) VerifySecond(second []byte) error {
+	if len(second) != 1 {
+		return errors.New("need a 1-byte choice")
+	}
+	return nil
+}
+
+// Payout gives the pot to the winner.
+func (PayoffRoPaSci) Payout(first, second []byte, pot uint64) (uint64, uint64, error) {
+	if len(first) == 0 {
+		return 0, 0, errors.New("empty first move")
+	}
+	switch roPaSciWinner(int(first[0])%3, int(second[0])%3) {
+	case 1:
+		return pot, 0, nil
+	case 2:
+		return 0, pot, nil
+	}
+	return pot / 2, pot - pot/2, nil
+}
+
+// NewInstructionFairExchangeSpawn returns a new instruction that spawns a
+// fair exchange with the payoff and the hash of the prehash of the first
+// player, who gets their coins in account.
+func NewInstructionFairExchangeSpawn(did darc.ID, payoff string, prehash []byte,
+	account byzcoin.InstanceID, timeout uint64) byzcoin.Instruction {
+	hash := sha256.Sum256(prehash)
+	timeoutBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timeoutBuf, timeout)
+	return byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(did),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractFairExchangeID,
+			Args: byzcoin.Arguments{
+				newArg("payoff", []byte(payoff)),
+				newArg("hash", hash[:]),
+				newArg("account", account[:]),
+				newArg("timeout", timeoutBuf),
+			},
+		},
+	}
+}
+
+// NewInstructionFairExchangeInvoke returns a new instruction with the
+// command and arguments for the fair exchange instance.
+func NewInstructionFairExchangeInvoke(id byzcoin.InstanceID, cmd string,
+	args ...byzcoin.Argument) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: id,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractFairExchangeID,
+			Command:    cmd,
+			Args:       args,
+		},
+	}
+}
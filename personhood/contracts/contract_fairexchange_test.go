@@ -0,0 +1,151 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+// testFE holds two players with a coin account each, playing fair exchanges
+// of rock-paper-scissors.
+type testFE struct {
+	t     *testing.T
+	rost  *rostIndex
+	darc  byzcoin.InstanceID
+	coin1 byzcoin.InstanceID
+	coin2 byzcoin.InstanceID
+}
+
+func newTestFE(t *testing.T) *testFE {
+	tf := &testFE{t: t, rost: &rostIndex{ROSTSimul: byzcoin.NewROSTSimul()}}
+	d, err := tf.rost.CreateBasicDarc(nil, "fe")
+	require.NoError(t, err)
+	tf.darc = byzcoin.NewInstanceID(d.GetBaseID())
+	tf.coin1, err = tf.rost.CreateCoin("RoPaSci", 1000)
+	require.NoError(t, err)
+	tf.coin2, err = tf.rost.CreateCoin("RoPaSci", 1000)
+	require.NoError(t, err)
+	return tf
+}
+
+// spawn starts an exchange with the prehash of the first player.
+func (tf *testFE) spawn(prehash []byte) (*ContractFairExchange, byzcoin.InstanceID) {
+	_, stake, err := tf.rost.WithdrawCoin(tf.coin1, 100)
+	require.NoError(tf.t, err)
+	inst := NewInstructionFairExchangeSpawn(tf.darc[:], ContractRoPaSciID, prehash, tf.coin1, 10)
+	cfe := &ContractFairExchange{}
+	scs, cout, err := cfe.Spawn(tf.rost, inst, []byzcoin.Coin{stake})
+	require.NoError(tf.t, err)
+	require.Equal(tf.t, uint64(0), cout[0].Value)
+	tf.store(scs)
+	require.NoError(tf.t, protobuf.Decode(scs[0].Value, &cfe.FairExchangeStruct))
+	return cfe, scs[0].InstanceID
+}
+
+func (tf *testFE) invoke(cfe *ContractFairExchange, id byzcoin.InstanceID, coins []byzcoin.Coin,
+	cmd string, args ...byzcoin.Argument) error {
+	inst := NewInstructionFairExchangeInvoke(id, cmd, args...)
+	if err := cfe.VerifyInstruction(tf.rost, inst, nil); err != nil {
+		return err
+	}
+	scs, _, err := cfe.Invoke(tf.rost, inst, coins)
+	if err != nil {
+		return err
+	}
+	tf.store(scs)
+	return nil
+}
+
+func (tf *testFE) second(cfe *ContractFairExchange, id byzcoin.InstanceID, move byte) error {
+	_, stake, err := tf.rost.WithdrawCoin(tf.coin2, 100)
+	require.NoError(tf.t, err)
+	return tf.invoke(cfe, id, []byzcoin.Coin{stake}, "second",
+		newArg("move", []byte{move}), newArg("account", tf.coin2[:]))
+}
+
+func (tf *testFE) store(scs byzcoin.StateChanges) {
+	_, err := tf.rost.StoreAllToReplica(scs)
+	require.NoError(tf.t, err)
+}
+
+func (tf *testFE) balances() (uint64, uint64) {
+	c1, err := tf.rost.GetCoin(tf.coin1)
+	require.NoError(tf.t, err)
+	c2, err := tf.rost.GetCoin(tf.coin2)
+	require.NoError(tf.t, err)
+	return c1.Value, c2.Value
+}
+
+// Plays all the rock-paper-scissors games with the fair exchange.
+func TestContractFairExchange(t *testing.T) {
+	for move1 := 0; move1 <= 2; move1++ {
+		for move2 := 0; move2 <= 2; move2++ {
+			tf := newTestFE(t)
+			prehash := append([]byte{byte(move1)}, make([]byte, 31)...)
+			cfe, id := tf.spawn(prehash)
+			require.Error(t, tf.invoke(cfe, id, nil, "reveal", newArg("prehash", prehash)))
+			require.NoError(t, tf.second(cfe, id, byte(move2)))
+			require.Error(t, tf.invoke(cfe, id, nil, "reveal", newArg("prehash", []byte("wrong"))))
+			require.NoError(t, tf.invoke(cfe, id, nil, "reveal", newArg("prehash", prehash)))
+			require.True(t, cfe.Done)
+			require.Error(t, tf.invoke(cfe, id, nil, "timeout"))
+
+			b1, b2 := tf.balances()
+			switch roPaSciWinner(move1, move2) {
+			case 0:
+				require.Equal(t, uint64(1000), b1)
+				require.Equal(t, uint64(1000), b2)
+			case 1:
+				require.Equal(t, uint64(1100), b1)
+				require.Equal(t, uint64(900), b2)
+			case 2:
+				require.Equal(t, uint64(900), b1)
+				require.Equal(t, uint64(1100), b2)
+			}
+		}
+	}
+}
+
+// Refuses a second player paid to the account of the first player.
+func TestContractFairExchange_SameAccount(t *testing.T) {
+	tf := newTestFE(t)
+	cfe, id := tf.spawn(make([]byte, 32))
+	_, stake, err := tf.rost.WithdrawCoin(tf.coin2, 100)
+	require.NoError(t, err)
+	require.Error(t, tf.invoke(cfe, id, []byzcoin.Coin{stake}, "second",
+		newArg("move", []byte{0}), newArg("account", tf.coin1[:])))
+	require.Nil(t, cfe.Second)
+	require.NoError(t, tf.second(cfe, id, 0))
+}
+
+// Times out exchanges where a player doesn't do their step.
+func TestContractFairExchange_Timeout(t *testing.T) {
+	tf := newTestFE(t)
+	prehash := make([]byte, 32)
+
+	// Nobody plays second: the first player gets their stake back.
+	cfe, id := tf.spawn(prehash)
+	require.Error(t, tf.invoke(cfe, id, nil, "timeout"))
+	tf.rost.index = 10
+	require.Error(t, tf.second(cfe, id, 0))
+	require.NoError(t, tf.invoke(cfe, id, nil, "timeout"))
+	b1, _ := tf.balances()
+	require.Equal(t, uint64(1000), b1)
+
+	// The first player doesn't reveal: the second player gets the pot.
+	tf = newTestFE(t)
+	cfe, id = tf.spawn(prehash)
+	tf.rost.index = 5
+	require.NoError(t, tf.second(cfe, id, 1))
+	tf.rost.index = 14
+	require.Error(t, tf.invoke(cfe, id, nil, "timeout"))
+	tf.rost.index = 15
+	require.NoError(t, tf.invoke(cfe, id, nil, "timeout"))
+	b1, b2 := tf.balances()
+	require.Equal(t, uint64(900), b1)
+	require.Equal(t, uint64(1100), b2)
+	require.Error(t, tf.invoke(cfe, id, nil, "reveal", newArg("prehash", prehash)))
+}
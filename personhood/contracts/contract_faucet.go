@@ -0,0 +1,191 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+// ContractFaucetID denotes a contract that gives a fixed amount of coins to
+// every attendee of a finalized pop-party, once per epoch. The attendees
+// claim with linkable ring signatures scoped to the faucet and the epoch, so
+// a second claim in the same epoch has the same tag, but the claims cannot be
+// linked to the attendees nor to each other across epochs.
+var ContractFaucetID = "faucet"
+
+// ContractFaucet embeds the BasicContract to verify the spawning darc.
+type ContractFaucet struct {
+	byzcoin.BasicContract
+	FaucetStruct
+}
+
+// ContractFaucetFromBytes returns a ContractFaucet given a slice of bytes.
+func ContractFaucetFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractFaucet{}
+	err := protobuf.Decode(in, &c.FaucetStruct)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal FaucetStruct: " + err.Error())
+	}
+	return c, nil
+}
+
+// VerifyInstruction overrides the basic VerifyInstruction for the "fill" and
+// "claim" commands: everybody can fill the faucet, and the claims are
+// protected by a linkable ring signature of an attendee of the party.
+func (c ContractFaucet) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	if inst.GetType() == byzcoin.InvokeType {
+		log.Lvl2("not verifying darc for", inst.Invoke.Command)
+		return nil
+	}
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Spawn creates a new faucet with the input coins. The following arguments
+// are needed:
+//  - partyID holds the instance ID of a finalized pop-party
+//  - amount is how many coins an attendee gets per claim
+//  - epochBlocks is the length of an epoch in blocks
+func (c ContractFaucet) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get darc: " + err.Error())
+	}
+
+	c.Party = byzcoin.NewInstanceID(inst.Spawn.Args.Search("partyID"))
+	if _, err = getPartyAttendees(rst, c.Party); err != nil {
+		return nil, nil, err
+	}
+	amountBuf := inst.Spawn.Args.Search("amount")
+	epochBuf := inst.Spawn.Args.Search("epochBlocks")
+	if len(amountBuf) != 8 || len(epochBuf) != 8 {
+		return nil, nil, errors.New("need amount and epochBlocks arguments")
+	}
+	c.Amount = binary.LittleEndian.Uint64(amountBuf)
+	if c.Amount == 0 {
+		return nil, nil, errors.New("amount cannot be 0")
+	}
+	c.EpochBlocks = binary.LittleEndian.Uint64(epochBuf)
+	if c.EpochBlocks == 0 {
+		return nil, nil, errors.New("epochBlocks cannot be 0")
+	}
+	if len(coins) == 0 {
+		return nil, nil, errors.New("faucet needs coins as input for their type")
+	}
+	c.Balance = coins[0]
+	cout[0].Value = 0
+
+	buf, err := protobuf.Encode(&c.FaucetStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal FaucetStruct: " + err.Error())
+	}
+	ca, err := inst.DeriveIDArg("", "preID")
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get deriveID: %v", err)
+	}
+	scs = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Create, ca, ContractFaucetID, buf, darcID),
+	}
+	return
+}
+
+// Invoke uses the following commands:
+//  - fill adds the input coins to the balance of the faucet
+//  - claim pays Amount coins to 'account'. 'lrs' must be signed by an attendee
+//    of the party with the FaucetClaim message and the FaucetScope of the
+//    current epoch, and its tag must not have claimed yet in this epoch.
+func (c *ContractFaucet) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get instance data: " + err.Error())
+	}
+
+	switch inst.Invoke.Command {
+	case "fill":
+		if len(coins) == 0 || !coins[0].Name.Equal(c.Balance.Name) {
+			return nil, nil, errors.New("need input coins of the type of the faucet")
+		}
+		if err = c.Balance.SafeAdd(coins[0].Value); err != nil {
+			return nil, nil, err
+		}
+		cout[0].Value = 0
+
+	case "claim":
+		atts, err := getPartyAttendees(rst, c.Party)
+		if err != nil {
+			return nil, nil, err
+		}
+		account, err := coinAccount(rst, inst.Invoke.Args.Search("account"), c.Balance.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		epoch := blockIndex(rst) / c.EpochBlocks
+		tag, err := anon.Verify(&SuiteBlake2s{}, FaucetClaim(account), atts.Keys,
+			FaucetScope(inst.InstanceID, epoch), inst.Invoke.Args.Search("lrs"))
+		if err != nil {
+			return nil, nil, errors.New("error while verifying signature: " + err.Error())
+		}
+		if epoch != c.Epoch {
+			c.Epoch = epoch
+			c.Claims = nil
+		}
+		for _, claim := range c.Claims {
+			if bytes.Equal(claim, tag) {
+				return nil, nil, errors.New("already claimed in this epoch")
+			}
+		}
+		if err = c.Balance.SafeSub(c.Amount); err != nil {
+			return nil, nil, errors.New("the faucet is empty")
+		}
+		c.Claims = append(c.Claims, tag)
+		scs, err = payCoin(rst, scs, account, c.Amount)
+		if err != nil {
+			return nil, nil, err
+		}
+
+	default:
+		return nil, nil, errors.New("faucet can only 'fill' or 'claim'")
+	}
+
+	buf, err := protobuf.Encode(&c.FaucetStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal FaucetStruct: " + err.Error())
+	}
+	scs = append(scs, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractFaucetID, buf, darcID))
+	return
+}
+
+// FaucetClaim returns the message an attendee signs to claim coins for the
+// account.
+func FaucetClaim(account byzcoin.InstanceID) []byte {
+	h := sha256.New()
+	h.Write([]byte("claim"))
+	h.Write(account[:])
+	return h.Sum(nil)
+}
+
+// FaucetScope returns the scope of the linkable ring signatures of the
+// claims of the faucet in the epoch.
+func FaucetScope(faucetIID byzcoin.InstanceID, epoch uint64) []byte {
+	scope := make([]byte, len(faucetIID)+8)
+	copy(scope, faucetIID[:])
+	binary.LittleEndian.PutUint64(scope[len(faucetIID):], epoch)
+	return scope
+}
@@ -0,0 +1,100 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/util/key"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+// Fills a faucet and claims coins once per epoch.
+func TestContractFaucet(t *testing.T) {
+	rost := &rostIndex{ROSTSimul: byzcoin.NewROSTSimul()}
+	d, err := rost.CreateBasicDarc(nil, "faucet")
+	require.NoError(t, err)
+
+	kps := make([]*key.Pair, 2)
+	atts := Attendees{}
+	for i := range kps {
+		kps[i] = key.NewKeyPair(cothority.Suite)
+		atts.Keys = append(atts.Keys, kps[i].Public)
+	}
+	partyID := byzcoin.NewInstanceID([]byte("party"))
+	party := PopPartyStruct{State: FinalizedState, Attendees: atts}
+	require.NoError(t, rost.CreateSCB(byzcoin.Create, ContractPopPartyID, partyID, &party, d.GetBaseID()))
+	account, err := rost.CreateCoin("faucet", 0)
+	require.NoError(t, err)
+	coin, err := rost.GetCoin(account)
+	require.NoError(t, err)
+
+	cf := &ContractFaucet{}
+	scs, _, err := cf.Spawn(rost, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(d.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractFaucetID,
+			Args: byzcoin.Arguments{
+				newArg("partyID", partyID.Slice()),
+				newArg("amount", uint64Arg(10)),
+				newArg("epochBlocks", uint64Arg(10)),
+			},
+		},
+	}, []byzcoin.Coin{{Name: coin.Name, Value: 15}})
+	require.NoError(t, err)
+	_, err = rost.StoreAllToReplica(scs)
+	require.NoError(t, err)
+	faucetID := scs[0].InstanceID
+	c, err := ContractFaucetFromBytes(scs[0].Value)
+	require.NoError(t, err)
+	cf = c.(*ContractFaucet)
+
+	invoke := func(cmd string, coins []byzcoin.Coin, args ...byzcoin.Argument) error {
+		inst := byzcoin.Instruction{
+			InstanceID: faucetID,
+			Invoke:     &byzcoin.Invoke{ContractID: ContractFaucetID, Command: cmd, Args: args},
+		}
+		require.NoError(t, cf.VerifyInstruction(rost, inst, nil))
+		scs, _, err := cf.Invoke(rost, inst, coins)
+		if err == nil {
+			_, err = rost.StoreAllToReplica(scs)
+		}
+		return err
+	}
+	claim := func(kps []*key.Pair, i int) error {
+		epoch := uint64(rost.index) / cf.EpochBlocks
+		var keys []kyber.Point
+		for _, kp := range kps {
+			keys = append(keys, kp.Public)
+		}
+		lrs := anon.Sign(&SuiteBlake2s{}, FaucetClaim(account), keys,
+			FaucetScope(faucetID, epoch), i, kps[i].Private)
+		return invoke("claim", nil, newArg("account", account[:]), newArg("lrs", lrs))
+	}
+	balance := func() uint64 {
+		coin, err := rost.GetCoin(account)
+		require.NoError(t, err)
+		return coin.Value
+	}
+
+	require.NoError(t, claim(kps, 0))
+	require.Equal(t, uint64(10), balance())
+	require.Error(t, claim(kps, 0))
+	require.Error(t, claim([]*key.Pair{kps[1], key.NewKeyPair(cothority.Suite)}, 0))
+	require.Error(t, claim(kps, 1), "the faucet is empty")
+
+	require.Error(t, invoke("fill", []byzcoin.Coin{{Name: byzcoin.NewInstanceID(nil), Value: 10}}))
+	require.NoError(t, invoke("fill", []byzcoin.Coin{{Name: coin.Name, Value: 20}}))
+	require.NoError(t, claim(kps, 1))
+	require.Equal(t, uint64(20), balance())
+
+	// In the next epoch, everybody can claim again.
+	rost.index = 10
+	require.NoError(t, claim(kps, 0))
+	require.Error(t, claim(kps, 0))
+	require.Equal(t, uint64(30), balance())
+	require.Equal(t, uint64(5), cf.Balance.Value)
+}
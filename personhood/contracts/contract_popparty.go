@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/group/edwards25519"
 	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/xof/blake2xs"
 	"go.dedis.ch/onet/v3/log"
 
@@ -57,13 +59,22 @@ func ContractPopPartyFromBytes(in []byte) (byzcoin.Contract, error) {
 	return c, nil
 }
 
-// VerifyInstruction overrides the basic VerifyInstruction in case of a "mine" command, because this command
-// is not protected by a darc, but by a linkable ring signature.
+// VerifyInstruction overrides the basic VerifyInstruction in case of a
+// "mine" command, because this command is not protected by a darc, but by a
+// linkable ring signature. The same holds for the "attest" command, which is
+// protected by the signatures of the attendee and of an organizer.
 func (c ContractPopParty) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
 	if inst.GetType() == byzcoin.InvokeType && inst.Invoke.Command == "mine" {
 		log.Lvl2("not verifying darc for mining")
 		return nil
 	}
+	if inst.GetType() == byzcoin.InvokeType && inst.Invoke.Command == "attest" {
+		if rst.GetVersion() < byzcoin.VersionRemoteAttest {
+			return errors.New("remote attestations need a newer version of byzcoin")
+		}
+		log.Lvl2("not verifying darc for a remote attestation")
+		return nil
+	}
 	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
 }
 
@@ -145,6 +156,10 @@ func (sb SuiteBlake2s) XOF(key []byte) kyber.XOF {
 //  - barrier to activate the pop-party
 //  - finalize to store the attendees. If all organizers finalize using the same list of attendees,
 //    the party is finalized
+//  - attest to add a remote attendee, before the first finalization. 'attendee' holds the public key
+//    of the attendee, 'attendeeSig' its schnorr signature of the RemoteAttestation message,
+//    'organizer' the protobuf encoded darc-identity of an organizer who verified the liveness of
+//    the attendee, and 'organizerSig' its signature of the same message
 //  - addParty to add a new party to the list - not supported yet
 //  - mine to collect the reward. 'lrs' must hold a correct, unique linkable ring signature. If
 //    'coinIID' is set, this coin will be filled. Else 'newDarc' will be used to create a darc,
@@ -178,6 +193,7 @@ func (c *ContractPopParty) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.In
 		var atts Attendees
 		err = protobuf.DecodeWithConstructors(attBuf, &atts, network.DefaultConstructors(cothority.Suite))
 		log.Lvl2("Adding attendees:", atts.Keys)
+		atts.Keys = c.addRemote(atts.Keys)
 
 		alreadySigned := false
 		orgSigner := inst.SignerIdentities[0].String()
@@ -219,6 +235,20 @@ func (c *ContractPopParty) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.In
 			c.State = FinalizedState
 		}
 
+	case "attest":
+		if rst.GetVersion() < byzcoin.VersionRemoteAttest {
+			return nil, nil, errors.New("remote attestations need a newer version of byzcoin")
+		}
+		if c.State != ScanningState || len(c.Finalizations) > 0 {
+			return nil, nil, errors.New("can only attest remote attendees while scanning and before finalizing")
+		}
+		remote, err := c.verifyAttestation(rst, inst, darcID)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Lvl2("Adding remote attendee", remote.Key, "attested by", remote.Organizer)
+		c.Remote = append(c.Remote, *remote)
+
 	case "mine":
 		if c.State != FinalizedState {
 			return nil, nil, errors.New("cannot mine when party is not finalized")
@@ -304,6 +334,111 @@ func (c *ContractPopParty) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.In
 	return scs, coins, nil
 }
 
+// RemoteAttestation returns the message signed by a remote attendee and by
+// the organizer who verified their liveness.
+func RemoteAttestation(popIID byzcoin.InstanceID, key kyber.Point) ([]byte, error) {
+	keyBuf, err := key.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte("remote-attendee"))
+	h.Write(popIID[:])
+	h.Write(keyBuf)
+	return h.Sum(nil), nil
+}
+
+// verifyAttestation returns the remote attendee of an "attest" instruction,
+// if both the attendee and an organizer of the party signed it.
+func (c *ContractPopParty) verifyAttestation(rst byzcoin.ReadOnlyStateTrie,
+	inst byzcoin.Instruction, darcID darc.ID) (*RemoteAttendee, error) {
+	key := cothority.Suite.Point()
+	if err := key.UnmarshalBinary(inst.Invoke.Args.Search("attendee")); err != nil {
+		return nil, errors.New("couldn't unmarshal attendee: " + err.Error())
+	}
+	for _, att := range append(c.Attendees.Keys, c.remoteKeys()...) {
+		if att.Equal(key) {
+			return nil, errors.New("this attendee is already attested")
+		}
+	}
+	msg, err := RemoteAttestation(inst.InstanceID, key)
+	if err != nil {
+		return nil, err
+	}
+	err = schnorr.Verify(cothority.Suite, key, msg, inst.Invoke.Args.Search("attendeeSig"))
+	if err != nil {
+		return nil, errors.New("wrong attendee signature: " + err.Error())
+	}
+
+	var org darc.Identity
+	if err := protobuf.Decode(inst.Invoke.Args.Search("organizer"), &org); err != nil {
+		return nil, errors.New("couldn't unmarshal organizer: " + err.Error())
+	}
+	if err := org.Verify(msg, inst.Invoke.Args.Search("organizerSig")); err != nil {
+		return nil, errors.New("wrong organizer signature: " + err.Error())
+	}
+	loadDarc := func(id []byte) (*darc.Darc, error) {
+		value, _, _, _, err := rst.GetValues(id)
+		if err != nil {
+			return nil, err
+		}
+		return darc.NewFromProtobuf(value)
+	}
+	d, err := loadDarc(darcID)
+	if err != nil {
+		return nil, errors.New("couldn't get darc in charge: " + err.Error())
+	}
+	expr := d.Rules.Get("invoke:popParty.finalize")
+	if rst.GetVersion() < byzcoin.VersionPopParty {
+		expr = d.Rules.Get("invoke:finalize")
+	}
+	getDarc := func(s string, latest bool) *darc.Darc {
+		if !strings.HasPrefix(s, "darc:") {
+			return nil
+		}
+		id, err := hex.DecodeString(s[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := loadDarc(id)
+		if err != nil {
+			return nil
+		}
+		return d
+	}
+	if err := darc.EvalExpr(expr, getDarc, org.String()); err != nil {
+		return nil, errors.New("attestation is not signed by an organizer: " + err.Error())
+	}
+	return &RemoteAttendee{Key: key, Organizer: org.String()}, nil
+}
+
+// remoteKeys returns the public keys of the remote attendees.
+func (c *ContractPopParty) remoteKeys() []kyber.Point {
+	keys := make([]kyber.Point, len(c.Remote))
+	for i, r := range c.Remote {
+		keys[i] = r.Key
+	}
+	return keys
+}
+
+// addRemote appends the remote attendees that are not yet in keys, so that
+// they get the same final attendance as the scanned ones.
+func (c *ContractPopParty) addRemote(keys []kyber.Point) []kyber.Point {
+	for _, r := range c.remoteKeys() {
+		found := false
+		for _, k := range keys {
+			if k.Equal(r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			keys = append(keys, r)
+		}
+	}
+	return keys
+}
+
 // NewInstructionPoppartySpawn returns a new instruction that is ready to be
 // sent to byzcoin to spawn a new pop-party instance.
 func NewInstructionPoppartySpawn(dst byzcoin.InstanceID, did darc.ID,
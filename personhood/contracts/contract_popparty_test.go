@@ -4,10 +4,16 @@ import (
 	"testing"
 	"time"
 
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/protobuf"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 )
 
 // Creates a party, activates the barrier point, finalizes it, and mines the coins.
@@ -34,3 +40,85 @@ func TestContractPopParty(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, desc, pps.Description)
 }
+
+// Attests a remote attendee and finalizes the party with it.
+func TestContractPopParty_Attest(t *testing.T) {
+	rost := byzcoin.NewROSTSimul()
+	org := darc.NewSignerEd25519(nil, nil)
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{org.Identity()},
+		[]darc.Identity{org.Identity()}), []byte("pp"))
+	require.NoError(t, d.Rules.AddRule("invoke:popParty.finalize",
+		expression.Expr(org.Identity().String())))
+	require.NoError(t, rost.CreateSCB(byzcoin.Create, byzcoin.ContractDarcID,
+		byzcoin.NewInstanceID(d.GetBaseID()), d, nil))
+
+	cpp := &ContractPopParty{PopPartyStruct: PopPartyStruct{State: ScanningState, Organizers: 1}}
+	popIID := byzcoin.NewInstanceID([]byte("party"))
+	require.NoError(t, rost.CreateSCB(byzcoin.Create, ContractPopPartyID, popIID,
+		&cpp.PopPartyStruct, d.GetBaseID()))
+
+	attest := func(kp *key.Pair, signer darc.Signer) error {
+		msg, err := RemoteAttestation(popIID, kp.Public)
+		require.NoError(t, err)
+		sig, err := schnorr.Sign(cothority.Suite, kp.Private, msg)
+		require.NoError(t, err)
+		orgSig, err := signer.Sign(msg)
+		require.NoError(t, err)
+		keyBuf, err := kp.Public.MarshalBinary()
+		require.NoError(t, err)
+		id := signer.Identity()
+		orgBuf, err := protobuf.Encode(&id)
+		require.NoError(t, err)
+		inst := byzcoin.Instruction{
+			InstanceID: popIID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractPopPartyID,
+				Command:    "attest",
+				Args: byzcoin.Arguments{
+					{Name: "attendee", Value: keyBuf},
+					{Name: "attendeeSig", Value: sig},
+					{Name: "organizer", Value: orgBuf},
+					{Name: "organizerSig", Value: orgSig},
+				},
+			},
+		}
+		if err := cpp.VerifyInstruction(rost, inst, nil); err != nil {
+			return err
+		}
+		_, _, err = cpp.Invoke(rost, inst, nil)
+		return err
+	}
+
+	remote := key.NewKeyPair(cothority.Suite)
+	// The ledgers of an older version don't know the command.
+	rost.Version = byzcoin.VersionBEvmGas
+	require.Error(t, attest(remote, org))
+	require.Equal(t, 0, len(cpp.Remote))
+	rost.Version = byzcoin.CurrentVersion
+
+	require.Error(t, attest(remote, darc.NewSignerEd25519(nil, nil)))
+	require.NoError(t, attest(remote, org))
+	require.Error(t, attest(remote, org))
+	require.Equal(t, 1, len(cpp.Remote))
+
+	scanned := key.NewKeyPair(cothority.Suite)
+	attBuf, err := protobuf.Encode(&Attendees{Keys: []kyber.Point{scanned.Public}})
+	require.NoError(t, err)
+	_, _, err = cpp.Invoke(rost, byzcoin.Instruction{
+		InstanceID: popIID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractPopPartyID,
+			Command:    "finalize",
+			Args:       byzcoin.Arguments{{Name: "attendees", Value: attBuf}},
+		},
+		SignerIdentities: []darc.Identity{org.Identity()},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, FinalizedState, cpp.State)
+	require.Equal(t, 2, len(cpp.Attendees.Keys))
+	require.True(t, cpp.Attendees.Keys[0].Equal(scanned.Public))
+	require.True(t, cpp.Attendees.Keys[1].Equal(remote.Public))
+
+	// No more attestations once finalized.
+	require.Error(t, attest(key.NewKeyPair(cothority.Suite), org))
+}
@@ -0,0 +1,245 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+// ContractReputationID denotes a contract that counts the reputation points
+// the attendees of a finalized pop-party grant each other. As every attendee
+// is known by the tag of their linkable ring signatures, and a party has one
+// key per person, nobody can create more identities to grant themselves
+// points.
+var ContractReputationID = "reputation"
+
+// ContractReputation embeds the BasicContract to verify the spawning darc.
+type ContractReputation struct {
+	byzcoin.BasicContract
+	ReputationStruct
+}
+
+// ContractReputationFromBytes returns a ContractReputation given a slice of
+// bytes.
+func ContractReputationFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractReputation{}
+	err := protobuf.Decode(in, &c.ReputationStruct)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal ReputationStruct: " + err.Error())
+	}
+	return c, nil
+}
+
+// VerifyInstruction overrides the basic VerifyInstruction for the "register"
+// and "grant" commands, because they are not protected by a darc, but by a
+// linkable ring signature of an attendee of the party.
+func (c ContractReputation) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	if inst.GetType() == byzcoin.InvokeType {
+		log.Lvl2("not verifying darc for", inst.Invoke.Command)
+		return nil
+	}
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Spawn creates a new reputation contract. The following arguments are
+// needed:
+//  - partyID holds the instance ID of a finalized pop-party
+//  - epochBlocks is the length of an epoch in blocks
+//  - limit is how many points an attendee can grant during an epoch
+func (c ContractReputation) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get darc: " + err.Error())
+	}
+
+	c.Party = byzcoin.NewInstanceID(inst.Spawn.Args.Search("partyID"))
+	if _, err = getPartyAttendees(rst, c.Party); err != nil {
+		return nil, nil, err
+	}
+	epochBuf := inst.Spawn.Args.Search("epochBlocks")
+	limitBuf := inst.Spawn.Args.Search("limit")
+	if len(epochBuf) != 8 || len(limitBuf) != 8 {
+		return nil, nil, errors.New("need epochBlocks and limit arguments")
+	}
+	c.EpochBlocks = binary.LittleEndian.Uint64(epochBuf)
+	if c.EpochBlocks == 0 {
+		return nil, nil, errors.New("epochBlocks cannot be 0")
+	}
+	c.Limit = binary.LittleEndian.Uint64(limitBuf)
+
+	buf, err := protobuf.Encode(&c.ReputationStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal ReputationStruct: " + err.Error())
+	}
+	ca, err := inst.DeriveIDArg("", "preID")
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get deriveID: %v", err)
+	}
+	scs = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Create, ca, ContractReputationID, buf, darcID),
+	}
+	return
+}
+
+// Invoke uses the following commands:
+//  - register to add the attendee who signed 'lrs' with the ReputationRegister message
+//  - grant to give 'points' to the attendee with the 'to' tag. 'lrs' must be signed by
+//    the registered attendee with the 'from' tag with the ReputationGrant message. An
+//    attendee can grant at most Limit points per epoch, and none to themselves.
+func (c *ContractReputation) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get instance data: " + err.Error())
+	}
+	atts, err := getPartyAttendees(rst, c.Party)
+	if err != nil {
+		return nil, nil, err
+	}
+	lrs := inst.Invoke.Args.Search("lrs")
+	if lrs == nil {
+		return nil, nil, errors.New("need lrs argument")
+	}
+
+	switch inst.Invoke.Command {
+	case "register":
+		tag, err := anon.Verify(&SuiteBlake2s{}, ReputationRegister(), atts.Keys, inst.InstanceID[:], lrs)
+		if err != nil {
+			return nil, nil, errors.New("error while verifying signature: " + err.Error())
+		}
+		if c.member(tag) != nil {
+			return nil, nil, errors.New("this attendee is already registered")
+		}
+		c.Members = append(c.Members, ReputationMember{Tag: tag})
+
+	case "grant":
+		to := inst.Invoke.Args.Search("to")
+		pointsBuf := inst.Invoke.Args.Search("points")
+		if len(pointsBuf) != 8 {
+			return nil, nil, errors.New("need points argument")
+		}
+		points := binary.LittleEndian.Uint64(pointsBuf)
+		recipient := c.member(to)
+		if recipient == nil {
+			return nil, nil, errors.New("the recipient is not registered")
+		}
+
+		granter := c.member(inst.Invoke.Args.Search("from"))
+		if granter == nil {
+			return nil, nil, errors.New("the granter is not registered")
+		}
+		msg := ReputationGrant(to, points, granter.Grants)
+		tag, err := anon.Verify(&SuiteBlake2s{}, msg, atts.Keys, inst.InstanceID[:], lrs)
+		if err != nil {
+			return nil, nil, errors.New("error while verifying signature: " + err.Error())
+		}
+		if !bytes.Equal(tag, granter.Tag) {
+			return nil, nil, errors.New("the signature is not from the granter")
+		}
+		if granter == recipient {
+			return nil, nil, errors.New("cannot grant points to oneself")
+		}
+
+		epoch := uint64(0)
+		if index := rst.GetIndex(); index > 0 {
+			epoch = uint64(index) / c.EpochBlocks
+		}
+		if granter.Epoch != epoch {
+			granter.Epoch = epoch
+			granter.Granted = 0
+		}
+		if points > c.Limit-granter.Granted {
+			return nil, nil, fmt.Errorf("cannot grant more than %d points in this epoch", c.Limit)
+		}
+		if recipient.Points+points < recipient.Points {
+			return nil, nil, errors.New("reputation overflow")
+		}
+		granter.Granted += points
+		granter.Grants++
+		recipient.Points += points
+
+	default:
+		return nil, nil, errors.New("unknown command: " + inst.Invoke.Command)
+	}
+
+	buf, err := protobuf.Encode(&c.ReputationStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal ReputationStruct: " + err.Error())
+	}
+	scs = append(scs, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractReputationID, buf, darcID))
+	return scs, coins, nil
+}
+
+// member returns the member with the given tag, or nil.
+func (c *ContractReputation) member(tag []byte) *ReputationMember {
+	for i := range c.Members {
+		if bytes.Equal(c.Members[i].Tag, tag) {
+			return &c.Members[i]
+		}
+	}
+	return nil
+}
+
+// getPartyAttendees returns the attendees of a finalized pop-party.
+func getPartyAttendees(rst byzcoin.ReadOnlyStateTrie, party byzcoin.InstanceID) (*Attendees, error) {
+	pps, err := getFinalizedParty(rst, party)
+	if err != nil {
+		return nil, err
+	}
+	return &pps.Attendees, nil
+}
+
+// getFinalizedParty returns a finalized pop-party.
+func getFinalizedParty(rst byzcoin.ReadOnlyStateTrie, party byzcoin.InstanceID) (*PopPartyStruct, error) {
+	value, _, cid, _, err := rst.GetValues(party.Slice())
+	if err != nil {
+		return nil, errors.New("couldn't get party: " + err.Error())
+	}
+	if cid != ContractPopPartyID {
+		return nil, errors.New("partyID is not a pop-party")
+	}
+	var pps PopPartyStruct
+	err = protobuf.DecodeWithConstructors(value, &pps, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal party: " + err.Error())
+	}
+	if pps.State != FinalizedState {
+		return nil, errors.New("the party is not finalized")
+	}
+	return &pps, nil
+}
+
+// ReputationRegister returns the message an attendee signs to register.
+func ReputationRegister() []byte {
+	return []byte("register")
+}
+
+// ReputationGrant returns the message an attendee signs to grant points. It
+// includes the number of grants of the attendee, so that it cannot be
+// replayed.
+func ReputationGrant(to []byte, points uint64, grants uint64) []byte {
+	h := sha256.New()
+	h.Write([]byte("grant"))
+	h.Write(to)
+	binary.Write(h, binary.LittleEndian, points)
+	binary.Write(h, binary.LittleEndian, grants)
+	return h.Sum(nil)
+}
@@ -0,0 +1,131 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+// rostIndex is a ROSTSimul at a given block index.
+type rostIndex struct {
+	*byzcoin.ROSTSimul
+	index int
+}
+
+func (r *rostIndex) GetIndex() int {
+	return r.index
+}
+
+func uint64Arg(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, v)
+	return buf
+}
+
+// Registers attendees of a party and grants points, with the limits per
+// epoch.
+func TestContractReputation(t *testing.T) {
+	rost := &rostIndex{ROSTSimul: byzcoin.NewROSTSimul()}
+	d, err := rost.CreateBasicDarc(nil, "reputation")
+	require.NoError(t, err)
+
+	kps := make([]*key.Pair, 3)
+	atts := Attendees{}
+	for i := range kps {
+		kps[i] = key.NewKeyPair(cothority.Suite)
+		atts.Keys = append(atts.Keys, kps[i].Public)
+	}
+	partyID := byzcoin.NewInstanceID([]byte("party"))
+	party := PopPartyStruct{State: ScanningState, Attendees: atts}
+	require.NoError(t, rost.CreateSCB(byzcoin.Create, ContractPopPartyID, partyID, &party, d.GetBaseID()))
+
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(d.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractReputationID,
+			Args: byzcoin.Arguments{
+				newArg("partyID", partyID.Slice()),
+				newArg("epochBlocks", uint64Arg(10)),
+				newArg("limit", uint64Arg(5)),
+			},
+		},
+	}
+	cr := &ContractReputation{}
+	_, _, err = cr.Spawn(rost, spawn, nil)
+	require.Error(t, err, "the party is not finalized")
+
+	party.State = FinalizedState
+	require.NoError(t, rost.CreateSCB(byzcoin.Update, ContractPopPartyID, partyID, &party, d.GetBaseID()))
+	scs, _, err := cr.Spawn(rost, spawn, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(scs))
+	_, err = rost.StoreAllToReplica(scs)
+	require.NoError(t, err)
+	repID := scs[0].InstanceID
+	c, err := ContractReputationFromBytes(scs[0].Value)
+	require.NoError(t, err)
+	cr = c.(*ContractReputation)
+
+	invoke := func(cmd string, args ...byzcoin.Argument) error {
+		inst := byzcoin.Instruction{
+			InstanceID: repID,
+			Invoke:     &byzcoin.Invoke{ContractID: ContractReputationID, Command: cmd, Args: args},
+		}
+		require.NoError(t, cr.VerifyInstruction(rost, inst, nil))
+		_, _, err := cr.Invoke(rost, inst, nil)
+		return err
+	}
+	sign := func(i int, msg []byte) []byte {
+		return anon.Sign(&SuiteBlake2s{}, msg, atts.Keys, repID[:], i, kps[i].Private)
+	}
+	tags := make([][]byte, len(kps))
+	for i := range kps {
+		lrs := sign(i, ReputationRegister())
+		require.NoError(t, invoke("register", newArg("lrs", lrs)))
+		require.Error(t, invoke("register", newArg("lrs", lrs)))
+		tags[i], err = anon.Verify(&SuiteBlake2s{}, ReputationRegister(), atts.Keys, repID[:], lrs)
+		require.NoError(t, err)
+	}
+	grant := func(from, to int, points uint64) error {
+		msg := ReputationGrant(tags[to], points, cr.member(tags[from]).Grants)
+		return invoke("grant", newArg("lrs", sign(from, msg)), newArg("from", tags[from]),
+			newArg("to", tags[to]), newArg("points", uint64Arg(points)))
+	}
+
+	// Outsiders cannot sign for the party.
+	outsider := key.NewKeyPair(cothority.Suite)
+	lrs := anon.Sign(&SuiteBlake2s{}, ReputationRegister(), []kyber.Point{outsider.Public}, repID[:], 0, outsider.Private)
+	require.Error(t, invoke("register", newArg("lrs", lrs)))
+
+	require.NoError(t, grant(0, 1, 3))
+	require.NoError(t, grant(0, 2, 2))
+	require.Error(t, grant(0, 1, 1), "limit of the epoch")
+	require.Error(t, grant(1, 1, 1), "granting to oneself")
+	require.Error(t, grant(1, 0, 6), "more than the limit")
+	require.Equal(t, uint64(3), cr.member(tags[1]).Points)
+
+	// A signature with another granter's tag is rejected.
+	msg := ReputationGrant(tags[1], 1, cr.member(tags[2]).Grants)
+	require.Error(t, invoke("grant", newArg("lrs", sign(0, msg)), newArg("from", tags[2]),
+		newArg("to", tags[1]), newArg("points", uint64Arg(1))))
+
+	// A new epoch resets the limit.
+	rost.index = 10
+	require.NoError(t, grant(0, 1, 5))
+	require.Equal(t, uint64(8), cr.member(tags[1]).Points)
+	require.Equal(t, uint64(2), cr.member(tags[2]).Points)
+
+	buf, err := protobuf.Encode(&cr.ReputationStruct)
+	require.NoError(t, err)
+	var rs ReputationStruct
+	require.NoError(t, protobuf.Decode(buf, &rs))
+	require.Equal(t, 3, len(rs.Members))
+}
@@ -312,7 +312,7 @@ func (c *ContractRoPaSci) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Ins
 		}
 		var winner []byte
 		c.FirstPlayer = int(preHash[0]) % 3
-		switch (3 + c.FirstPlayer - c.SecondPlayer) % 3 {
+		switch roPaSciWinner(c.FirstPlayer, c.SecondPlayer) {
 		case 0:
 			log.Lvl2("draw - no winner")
 		case 1:
@@ -360,6 +360,12 @@ func (c *ContractRoPaSci) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Ins
 	return
 }
 
+// roPaSciWinner returns 1 or 2 for the player who wins with their choice, or 0
+// for a draw.
+func roPaSciWinner(first, second int) int {
+	return (3 + first - second) % 3
+}
+
 // NewInstructionRoPaSciInvokeSecond returns a new instruction that can be
 // sent to byzcoin for continuation of a rock-paper-sicssors game.
 func NewInstructionRoPaSciInvokeSecond(acc byzcoin.InstanceID,
@@ -218,6 +218,14 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 		if err != nil {
 			return nil, nil, err
 		}
+		if rst.GetVersion() >= byzcoin.VersionCredentialRenewal {
+			// Only a renewal can make a credential fresh.
+			cred.Expires, cred.Party, cred.PartyTime = 0, nil, 0
+			instBuf, err = protobuf.Encode(&cred)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 		darcID = inst.Spawn.Args.Search("darcID")
 		if !usePreID {
 			var credID []byte
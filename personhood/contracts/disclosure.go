@@ -0,0 +1,111 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// NewDisclosure returns the disclosure of an attribute with a new random
+// salt. The owner of the credential keeps it to present the attribute later,
+// and stores its Commitment in the credential.
+func NewDisclosure(credential, name string, value []byte) Disclosure {
+	return Disclosure{
+		Credential: credential,
+		Name:       name,
+		Value:      value,
+		Salt:       random.Bits(256, true, random.New()),
+	}
+}
+
+// Commitment returns the hash that hides the attribute in the credential.
+// Without the salt, it doesn't reveal the value of the attribute, not even
+// for a value that can be guessed, like an age.
+func (d Disclosure) Commitment() []byte {
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte(d.Credential), []byte(d.Name), d.Value, d.Salt} {
+		binary.Write(h, binary.LittleEndian, uint32(len(field)))
+		h.Write(field)
+	}
+	return h.Sum(nil)
+}
+
+// Commit adds the commitments of the disclosures to the credential.
+func (cs *CredentialStruct) Commit(ds ...Disclosure) {
+	for _, d := range ds {
+		cs.Commitments = append(cs.Commitments, d.Commitment())
+	}
+}
+
+// VerifyDisclosures returns an error if one of the disclosures is not
+// committed to in the credential. The verifier thus only learns the
+// disclosed attributes, and needs a proof of the credential instance to
+// trust its commitments.
+func (cs CredentialStruct) VerifyDisclosures(ds []Disclosure) error {
+	for _, d := range ds {
+		if len(d.Salt) < 16 {
+			return errors.New("salt of " + d.Credential + "/" + d.Name + " is too short")
+		}
+		commitment := d.Commitment()
+		found := false
+		for _, c := range cs.Commitments {
+			if bytes.Equal(c, commitment) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("no commitment for " + d.Credential + "/" + d.Name)
+		}
+	}
+	return nil
+}
+
+// NewPresentation returns the disclosures of the credential credIID signed
+// by the holder kp for the challenge of a verifier, so that the verifier
+// knows they come from the holder and were not replayed.
+func NewPresentation(credIID byzcoin.InstanceID, challenge []byte,
+	ds []Disclosure, kp *key.Pair) (p Presentation, err error) {
+	p = Presentation{Disclosures: ds, Holder: kp.Public}
+	p.Signature, err = schnorr.Sign(cothority.Suite, kp.Private, p.message(credIID, challenge))
+	return
+}
+
+// Verify returns an error if the presentation isn't signed by its holder for
+// this credential and challenge, or if one of its disclosures is not
+// committed to in cs. The verifier still needs to check that the holder can
+// sign for the darc of the credential.
+func (p Presentation) Verify(cs CredentialStruct, credIID byzcoin.InstanceID, challenge []byte) error {
+	if len(challenge) < 16 {
+		return errors.New("the challenge is too short")
+	}
+	if p.Holder == nil {
+		return errors.New("missing the holder of the presentation")
+	}
+	if err := schnorr.Verify(cothority.Suite, p.Holder, p.message(credIID, challenge), p.Signature); err != nil {
+		return errors.New("wrong signature of the holder: " + err.Error())
+	}
+	return cs.VerifyDisclosures(p.Disclosures)
+}
+
+// message returns what the holder signs: the credential, the challenge and
+// the commitments of the disclosures.
+func (p Presentation) message(credIID byzcoin.InstanceID, challenge []byte) []byte {
+	h := sha256.New()
+	fields := [][]byte{[]byte("credential-presentation"), credIID[:], challenge}
+	for _, d := range p.Disclosures {
+		fields = append(fields, d.Commitment())
+	}
+	for _, field := range fields {
+		binary.Write(h, binary.LittleEndian, uint32(len(field)))
+		h.Write(field)
+	}
+	return h.Sum(nil)
+}
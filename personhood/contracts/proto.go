@@ -40,6 +40,18 @@ type RoPaSciStruct struct {
 // CredentialStruct holds a slice of credentials.
 type CredentialStruct struct {
 	Credentials []Credential
+	// Expires is the Unix time in seconds from which the credential is
+	// stale. It is only set by the "renew" command, and 0 if the credential
+	// has never been renewed.
+	Expires int64 `protobuf:"opt"`
+	// Party is the pop-party of the last renewal.
+	Party *byzcoin.InstanceID `protobuf:"opt"`
+	// PartyTime is the DateTime of Party, so that the next renewal needs a
+	// newer party.
+	PartyTime uint64 `protobuf:"opt"`
+	// Commitments hide the attributes that the owner only discloses to
+	// chosen verifiers, see Disclosure.
+	Commitments [][]byte `protobuf:"opt"`
 }
 
 // Credential represents one identity of the user.
@@ -54,6 +66,30 @@ type Attribute struct {
 	Value []byte
 }
 
+// Disclosure reveals one attribute committed to in a credential.
+type Disclosure struct {
+	// Credential is the name of the credential of the attribute.
+	Credential string
+	// Name of the attribute.
+	Name string
+	// Value of the attribute.
+	Value []byte
+	// Salt hides the value in the commitment.
+	Salt []byte
+}
+
+// Presentation is the answer of the holder of a credential to the challenge
+// of a verifier: the disclosures, signed with the challenge by a key that
+// can sign for the darc of the credential.
+type Presentation struct {
+	// Disclosures are the attributes the holder reveals.
+	Disclosures []Disclosure
+	// Holder is the Ed25519 public key that signed the presentation.
+	Holder kyber.Point
+	// Signature is the schnorr signature of the holder.
+	Signature []byte
+}
+
 // SpawnerStruct holds the data necessary for knowing how much spawning
 // of a certain contract costs.
 type SpawnerStruct struct {
@@ -96,6 +132,10 @@ type PopPartyStruct struct {
 	// Next is a link to the instanceID of the next party. It can be
 	// nil if there is no next party.
 	Next byzcoin.InstanceID `protobuf:"opt"`
+	// Remote holds the attendees who proved their liveness to an organizer
+	// remotely instead of being scanned. They are added to the attendees
+	// when the party is finalized.
+	Remote []RemoteAttendee
 }
 
 // PopDesc holds the name, date and a roster of all involved conodes.
@@ -128,3 +168,81 @@ type Attendees struct {
 type LRSTag struct {
 	Tag []byte
 }
+
+// RemoteAttendee is an attendee attested by an organizer, typically after a
+// video call, instead of having their public key scanned.
+type RemoteAttendee struct {
+	// Key is the public key of the attendee.
+	Key kyber.Point
+	// Organizer is the darc-identity of the organizer who co-signed the
+	// attestation.
+	Organizer string
+}
+
+// ReputationStruct holds the reputation points that the attendees of a
+// pop-party grant each other.
+type ReputationStruct struct {
+	// Party is the instance ID of the finalized pop-party.
+	Party byzcoin.InstanceID
+	// EpochBlocks is the length of an epoch in blocks.
+	EpochBlocks uint64
+	// Limit is how many points an attendee can grant during an epoch.
+	Limit uint64
+	// Members are the registered attendees.
+	Members []ReputationMember
+}
+
+// ReputationMember is a registered attendee, known by the tag of their
+// linkable ring signatures on the reputation instance.
+type ReputationMember struct {
+	// Tag of the linkable ring signatures of the attendee.
+	Tag []byte
+	// Points is the reputation of the attendee.
+	Points uint64
+	// Epoch is the last epoch in which the attendee granted points.
+	Epoch uint64
+	// Granted is how many points the attendee granted during Epoch.
+	Granted uint64
+	// Grants is how many times the attendee granted points.
+	Grants uint64
+}
+
+// FairExchangeStruct holds a two-party exchange where the first player
+// commits to its move before the second player plays.
+type FairExchangeStruct struct {
+	// Payoff is the ID of the registered payoff of the exchange.
+	Payoff string
+	// Stake is what each player puts in the pot.
+	Stake byzcoin.Coin
+	// FirstHash is the sha256 of the move of the first player.
+	FirstHash []byte
+	// FirstAccount receives the coins of the first player.
+	FirstAccount byzcoin.InstanceID
+	// Second is the move of the second player, once played.
+	Second []byte
+	// SecondAccount receives the coins of the second player.
+	SecondAccount byzcoin.InstanceID
+	// Timeout is how many blocks a player has for their next step.
+	Timeout uint64
+	// Deadline is the block index from which the exchange can time out.
+	Deadline uint64
+	// Done is true once the pot has been paid out.
+	Done bool
+}
+
+// FaucetStruct holds the coins a faucet gives to the attendees of a
+// pop-party.
+type FaucetStruct struct {
+	// Party is the instance ID of the finalized pop-party.
+	Party byzcoin.InstanceID
+	// Balance holds the coins left in the faucet.
+	Balance byzcoin.Coin
+	// Amount is how many coins an attendee gets per claim.
+	Amount uint64
+	// EpochBlocks is the length of an epoch in blocks.
+	EpochBlocks uint64
+	// Epoch is the epoch of the last claim.
+	Epoch uint64
+	// Claims are the tags of the attendees who claimed during Epoch.
+	Claims [][]byte
+}
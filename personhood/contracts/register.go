@@ -14,6 +14,13 @@ func init() {
 		ContractCredentialFromBytes))
 	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractRoPaSciID,
 		ContractRoPaSciFromBytes))
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractReputationID,
+		ContractReputationFromBytes))
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractFairExchangeID,
+		ContractFairExchangeFromBytes))
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractFaucetID,
+		ContractFaucetFromBytes))
+	log.ErrFatal(RegisterPayoff(ContractRoPaSciID, PayoffRoPaSci{}))
 }
 
 func newArg(name string, val []byte) byzcoin.Argument {
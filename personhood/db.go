@@ -5,12 +5,11 @@ import (
 
 	"go.dedis.ch/cothority/v3/personhood/contracts"
 
-	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
 
-	"go.dedis.ch/protobuf"
 	"golang.org/x/xerrors"
 )
 
@@ -27,7 +26,7 @@ func init() {
 func (s *Service) save() error {
 	s.storage.Lock()
 	defer s.storage.Unlock()
-	return s.Save(storageKey, s.storage)
+	return atrest.Save(s, ServiceName, storageKey, s.storage)
 }
 
 // Tries to load the configuration and updates the data in the service
@@ -38,7 +37,7 @@ func (s *Service) tryLoad() error {
 	if err != nil {
 		return err
 	}
-	buf, err := s.LoadRaw(storageKey)
+	msg, err := atrest.Load(s, ServiceName, storageKey)
 	if err != nil {
 		return err
 	}
@@ -51,16 +50,21 @@ func (s *Service) tryLoad() error {
 		return s.SaveVersion(dbVersion)
 	case 1:
 		log.Info("Migrating personhood-database from version 1 to 2")
-		var s1 storage1
-		err = protobuf.DecodeWithConstructors(buf[16:], &s1,
-			network.DefaultConstructors(cothority.Suite))
+		s1, ok := msg.(*storage1)
+		if !ok {
+			return xerrors.New("couldn't load the storage of version 1")
+		}
 		s.storage.RoPaSci = s1.RoPaSci
 		s.storage.Parties = s1.Parties
 		s.storage.Polls = s1.Polls
 		return s.SaveVersion(dbVersion)
 	case 2:
-		return protobuf.DecodeWithConstructors(buf[16:], s.storage,
-			network.DefaultConstructors(cothority.Suite))
+		s2, ok := msg.(*storage2)
+		if !ok {
+			return xerrors.New("couldn't load the storage")
+		}
+		s.storage = s2
+		return nil
 	default:
 		return xerrors.New("unknown version")
 	}
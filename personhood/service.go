@@ -9,9 +9,11 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"errors"
+	"net/http"
 	"sort"
 	"time"
 
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/cothority/v3/personhood/contracts"
 
 	"go.dedis.ch/cothority/v3"
@@ -53,6 +55,13 @@ type Service struct {
 	storage *storage2
 }
 
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
 // Capabilities returns the version of endpoints this conode offers:
 // The versioning is a 24 bit value, that can be interpreted in hexadecimal
 // as the following:
@@ -354,7 +363,7 @@ func (s *Service) verifySignature(bcID skipchain.SkipBlockID, identity darc.Iden
 
 // PartyList can either store a new party in the list, or just return the list of
 // available parties. It doesn't return finalized parties, so as not to confuse the
-// clients, but keeps them in the list for other methods like ReadMessage.
+// clients, but keeps them in the list.
 func (s *Service) PartyList(rq *PartyList) (*PartyListResponse, error) {
 	log.Lvlf2("PartyList: %+v", rq)
 	if rq.WipeParties != nil && *rq.WipeParties {
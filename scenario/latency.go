@@ -0,0 +1,107 @@
+package scenario
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Latency is a distribution of the latencies added to the requests.
+type Latency interface {
+	// Sample returns a latency of the distribution.
+	Sample(r *rand.Rand) time.Duration
+}
+
+// ParseLatency returns the distribution described by s, which is one of:
+//
+//	none                     no latency
+//	constant:<d>             always d
+//	uniform:<min>,<max>      uniform between min and max
+//	normal:<mean>,<stddev>   normal, without the negative latencies
+//	exponential:<mean>       exponential, for the long tails
+//
+// with durations in the format of time.ParseDuration, like
+// "normal:50ms,10ms".
+func ParseLatency(s string) (Latency, error) {
+	if s == "" || s == "none" {
+		return constant(0), nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("latency %q has no parameters", s)
+	}
+	var params []time.Duration
+	for _, p := range strings.Split(parts[1], ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("latency %q: %v", s, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("latency %q is negative", s)
+		}
+		params = append(params, d)
+	}
+
+	want := 1
+	var l Latency
+	switch parts[0] {
+	case "constant":
+		l = constant(params[0])
+	case "uniform":
+		want = 2
+		if len(params) == want {
+			if params[1] < params[0] {
+				return nil, fmt.Errorf("latency %q has max below min", s)
+			}
+			l = uniform{min: params[0], max: params[1]}
+		}
+	case "normal":
+		want = 2
+		if len(params) == want {
+			l = normal{mean: params[0], stddev: params[1]}
+		}
+	case "exponential":
+		l = exponential{mean: params[0]}
+	default:
+		return nil, fmt.Errorf("unknown latency distribution %q", parts[0])
+	}
+	if len(params) != want {
+		return nil, fmt.Errorf("latency %q needs %d parameters", s, want)
+	}
+	return l, nil
+}
+
+type constant time.Duration
+
+func (c constant) Sample(*rand.Rand) time.Duration {
+	return time.Duration(c)
+}
+
+type uniform struct {
+	min, max time.Duration
+}
+
+func (u uniform) Sample(r *rand.Rand) time.Duration {
+	return u.min + time.Duration(r.Int63n(int64(u.max-u.min)+1))
+}
+
+type normal struct {
+	mean, stddev time.Duration
+}
+
+func (n normal) Sample(r *rand.Rand) time.Duration {
+	d := n.mean + time.Duration(r.NormFloat64()*float64(n.stddev))
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+type exponential struct {
+	mean time.Duration
+}
+
+func (e exponential) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(e.mean))
+}
@@ -0,0 +1,58 @@
+package scenario
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLatency(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, s := range []string{"", "none", "constant:0s"} {
+		l, err := ParseLatency(s)
+		require.NoError(t, err)
+		require.Equal(t, time.Duration(0), l.Sample(r))
+	}
+
+	l, err := ParseLatency("constant:50ms")
+	require.NoError(t, err)
+	require.Equal(t, 50*time.Millisecond, l.Sample(r))
+
+	l, err = ParseLatency("uniform:10ms, 20ms")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		d := l.Sample(r)
+		require.True(t, d >= 10*time.Millisecond && d <= 20*time.Millisecond)
+	}
+
+	for _, s := range []string{"normal:50ms,10ms", "exponential:50ms"} {
+		l, err = ParseLatency(s)
+		require.NoError(t, err)
+		var sum time.Duration
+		for i := 0; i < 1000; i++ {
+			d := l.Sample(r)
+			require.True(t, d >= 0)
+			sum += d
+		}
+		mean := sum / 1000
+		require.True(t, mean > 40*time.Millisecond &&
+			mean < 60*time.Millisecond, "%s has mean %s", s, mean)
+	}
+
+	for _, s := range []string{
+		"constant",
+		"constant:",
+		"constant:1s,2s",
+		"constant:-1s",
+		"uniform:1s",
+		"uniform:2s,1s",
+		"normal:1s",
+		"pareto:1s",
+	} {
+		_, err = ParseLatency(s)
+		require.Error(t, err, s)
+	}
+}
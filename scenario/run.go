@@ -0,0 +1,118 @@
+package scenario
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/simul/monitor"
+)
+
+// Run runs the scenario for the given number of rounds. It creates the
+// ledger on the roster, and the clients on this node, which must be the
+// first node of the roster.
+func Run(c Config, roster *onet.Roster, rounds int) error {
+	p, err := c.parse(len(roster.List))
+	if err != nil {
+		return fmt.Errorf("invalid scenario: %v", err)
+	}
+
+	setup := monitor.NewTimeMeasure(MetricSetup)
+	l, err := newLedger(roster, p.blockInterval)
+	if err != nil {
+		return err
+	}
+	clients := make([]*client, p.Clients)
+	for i := range clients {
+		clients[i], err = l.newClient(fmt.Sprintf("client %d", i), p.latency,
+			p.Seed+int64(i)+1)
+		if err != nil {
+			return err
+		}
+	}
+	setup.Record()
+
+	r := rand.New(rand.NewSource(p.Seed))
+	for round := 0; round < rounds; round++ {
+		log.Lvl1("Starting round", round)
+		roundM := monitor.NewTimeMeasure(MetricRound)
+		start := time.Now()
+		stop := make(chan struct{})
+		churns := p.churn(roster, r, stop)
+
+		done := make([]int, len(clients))
+		failed := make([]int, len(clients))
+		var wg sync.WaitGroup
+		for i, cl := range clients {
+			wg.Add(1)
+			go func(i int, cl *client) {
+				defer wg.Done()
+				done[i], failed[i] = cl.round(p.Transactions, p.Secrets)
+			}(i, cl)
+		}
+		wg.Wait()
+		roundM.Record()
+		elapsed := time.Since(start)
+		close(stop)
+
+		var totalDone, totalFailed int
+		for i := range clients {
+			totalDone += done[i]
+			totalFailed += failed[i]
+		}
+		log.Lvlf1("Round %d: %d requests done, %d failed in %s", round,
+			totalDone, totalFailed, elapsed)
+		monitor.RecordSingleMeasure(MetricThroughput,
+			float64(totalDone)/elapsed.Seconds())
+		monitor.RecordSingleMeasure(MetricFailures, float64(totalFailed))
+		monitor.RecordSingleMeasure(MetricChurns, float64(<-churns))
+
+		// Let the nodes come back before the next round, or before the
+		// simulation closes them.
+		time.Sleep(p.downtime)
+	}
+	// Let the last blocks propagate to all the nodes before they close.
+	time.Sleep(p.blockInterval)
+	return nil
+}
+
+// churn takes ChurnNodes random nodes down every interval, starting now,
+// until stop is closed. The root of the roster always stays up. The returned
+// channel gets the number of nodes taken down.
+func (p *plan) churn(roster *onet.Roster, r *rand.Rand,
+	stop <-chan struct{}) <-chan int {
+	count := make(chan int, 1)
+	if p.ChurnNodes == 0 {
+		count <- 0
+		return count
+	}
+
+	go func() {
+		cl := onet.NewClient(cothority.Suite, ServiceName)
+		n := 0
+		for {
+			for _, i := range r.Perm(len(roster.List) - 1)[:p.ChurnNodes] {
+				si := roster.List[i+1]
+				err := cl.SendProtobuf(si, &Churn{Downtime: p.downtime},
+					&ChurnReply{})
+				if err != nil {
+					log.Warnf("Couldn't take %s down: %v", si, err)
+					continue
+				}
+				n++
+			}
+
+			select {
+			case <-stop:
+				count <- n
+				return
+			case <-time.After(p.interval):
+			}
+		}
+	}()
+	return count
+}
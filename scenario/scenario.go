@@ -0,0 +1,143 @@
+// Package scenario runs reusable simulation scenarios against a roster: a
+// ledger with an LTS, clients sending a mix of ByzCoin transactions and
+// Calypso re-encryptions, latencies added to their requests, and nodes going
+// down and coming back while they do.
+//
+// Every scenario records the same measures, named by the Metric constants,
+// so that the CSV files of two releases running the same simulation file can
+// be compared column by column.
+//
+// A scenario is described by a Config, which is a part of the simulation
+// file. The ChurnAndLoad simulation runs it as is, and other simulations can
+// embed a Config and call Run from their own Run method.
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// The names of the measures recorded by every scenario.
+const (
+	// MetricSetup is the time to create the ledger, the LTS and the darcs of
+	// the clients.
+	MetricSetup = "setup"
+	// MetricRound is the time of a round, until all clients are done.
+	MetricRound = "round"
+	// MetricTransaction is the time until a ByzCoin transaction is included.
+	MetricTransaction = "byzcoin_tx"
+	// MetricWrite is the time until a Calypso write is included.
+	MetricWrite = "calypso_write"
+	// MetricRead is the time until a Calypso read is included.
+	MetricRead = "calypso_read"
+	// MetricDecrypt is the time of the re-encryption of a secret.
+	MetricDecrypt = "calypso_decrypt"
+	// MetricThroughput is the number of transactions and secrets done per
+	// second in a round.
+	MetricThroughput = "throughput"
+	// MetricFailures is the number of requests that failed in a round.
+	MetricFailures = "failures"
+	// MetricChurns is the number of nodes that went down in a round.
+	MetricChurns = "churns"
+)
+
+// Config describes a scenario. Its fields are the columns of the simulation
+// file, and can change from one run to the other.
+type Config struct {
+	// Clients is the number of clients sending their requests in parallel.
+	Clients int
+	// Transactions is the number of ByzCoin transactions every client sends
+	// in a round.
+	Transactions int
+	// Secrets is the number of secrets every client writes, reads and
+	// decrypts with Calypso in a round.
+	Secrets int
+	// Latency is the distribution of the latency added to every request of
+	// the clients, in the format of ParseLatency.
+	Latency string
+	// ChurnNodes is the number of nodes that are down at the same time. It is
+	// capped at the number of faulty nodes the roster tolerates, and the
+	// root, which runs the clients, never goes down.
+	ChurnNodes int
+	// ChurnDowntime is how long a node stays down, 5s by default.
+	ChurnDowntime string
+	// ChurnInterval is the time between two failures of nodes, twice the
+	// downtime by default. It can't be shorter than the downtime.
+	ChurnInterval string
+	// BlockInterval is the block interval of the ledger, 1s by default.
+	BlockInterval string
+	// Seed makes the choices of the nodes going down and the latencies
+	// repeatable.
+	Seed int64
+}
+
+// plan is a Config with its values parsed and the defaults applied.
+type plan struct {
+	Config
+	latency       Latency
+	downtime      time.Duration
+	interval      time.Duration
+	blockInterval time.Duration
+}
+
+// parse checks the Config for a roster of the given number of nodes.
+func (c Config) parse(nodes int) (*plan, error) {
+	p := &plan{Config: c}
+	if p.Clients <= 0 {
+		p.Clients = 1
+	}
+	if p.Transactions < 0 || p.Secrets < 0 {
+		return nil, errors.New("negative number of requests")
+	}
+
+	var err error
+	p.latency, err = ParseLatency(c.Latency)
+	if err != nil {
+		return nil, err
+	}
+	p.blockInterval, err = parseDuration(c.BlockInterval, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("block interval: %v", err)
+	}
+
+	if faulty := (nodes - 1) / 3; p.ChurnNodes > faulty {
+		log.Warnf("A roster of %d nodes tolerates %d nodes down, not %d",
+			nodes, faulty, p.ChurnNodes)
+		p.ChurnNodes = faulty
+	}
+	if p.ChurnNodes <= 0 {
+		p.ChurnNodes = 0
+		return p, nil
+	}
+	p.downtime, err = parseDuration(c.ChurnDowntime, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("churn downtime: %v", err)
+	}
+	p.interval, err = parseDuration(c.ChurnInterval, 2*p.downtime)
+	if err != nil {
+		return nil, fmt.Errorf("churn interval: %v", err)
+	}
+	if p.interval < p.downtime {
+		return nil, errors.New("churn interval is shorter than the downtime")
+	}
+	return p, nil
+}
+
+// parseDuration parses a positive duration, with a default value if it is
+// empty.
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s is not positive", s)
+	}
+	return d, nil
+}
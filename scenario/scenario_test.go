@@ -0,0 +1,50 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	p, err := Config{}.parse(4)
+	require.NoError(t, err)
+	require.Equal(t, 1, p.Clients)
+	require.Equal(t, time.Second, p.blockInterval)
+	require.Equal(t, 0, p.ChurnNodes)
+	require.Equal(t, time.Duration(0), p.downtime)
+
+	p, err = Config{ChurnNodes: 1}.parse(4)
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, p.downtime)
+	require.Equal(t, 10*time.Second, p.interval)
+
+	// A roster of 7 nodes tolerates 2 nodes down.
+	p, err = Config{ChurnNodes: 5, ChurnDowntime: "2s"}.parse(7)
+	require.NoError(t, err)
+	require.Equal(t, 2, p.ChurnNodes)
+	require.Equal(t, 4*time.Second, p.interval)
+
+	// And a roster of 3 nodes none.
+	p, err = Config{ChurnNodes: 1}.parse(3)
+	require.NoError(t, err)
+	require.Equal(t, 0, p.ChurnNodes)
+
+	for _, c := range []Config{
+		{Transactions: -1},
+		{Latency: "gaussian:1s"},
+		{BlockInterval: "1"},
+		{BlockInterval: "-1s"},
+		{ChurnNodes: 1, ChurnDowntime: "2s", ChurnInterval: "1s"},
+	} {
+		_, err = c.parse(4)
+		require.Error(t, err, "%+v", c)
+	}
+}
+
+func TestPlan_ChurnNone(t *testing.T) {
+	p, err := Config{}.parse(4)
+	require.NoError(t, err)
+	require.Equal(t, 0, <-p.churn(nil, nil, nil))
+}
@@ -0,0 +1,118 @@
+package scenario
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// ServiceName of the service preparing the nodes for the scenarios. It only
+// runs in the simulations, as it lets anybody stop the node.
+const ServiceName = "Scenario"
+
+func init() {
+	_, err := onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+}
+
+// Service prepares the node for the scenarios and takes it down when the
+// scenario asks for it.
+type Service struct {
+	*onet.ServiceProcessor
+	serverMutex sync.Mutex
+	server      *onet.Server
+	down        bool
+}
+
+// setServer gives the server to take down, as the services only see their
+// context.
+func (s *Service) setServer(server *onet.Server) {
+	s.serverMutex.Lock()
+	defer s.serverMutex.Unlock()
+	s.server = server
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// Prepare authorizes the ledger on the Calypso service of the node, signing
+// the request with the key of the node. As Churn, it only runs on the nodes
+// of a simulation.
+func (s *Service) Prepare(req *Prepare) (*PrepareReply, error) {
+	s.serverMutex.Lock()
+	inSimulation := s.server != nil
+	s.serverMutex.Unlock()
+	if !inSimulation {
+		return nil, errors.New("node is not in a simulation")
+	}
+
+	cs, ok := s.Service(calypso.ServiceName).(*calypso.Service)
+	if !ok {
+		return nil, errors.New("calypso service is not running")
+	}
+
+	ts := time.Now().Unix()
+	msg := append(append([]byte{}, req.ByzCoinID...), make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(msg[len(req.ByzCoinID):], uint64(ts))
+	sig, err := schnorr.Sign(cothority.Suite, s.ServerIdentity().GetPrivate(),
+		msg)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't sign the authorization: %v", err)
+	}
+	_, err = cs.Authorize(&calypso.Authorize{ByzCoinID: req.ByzCoinID,
+		Timestamp: ts, Signature: sig})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't authorize the ledger: %v", err)
+	}
+	return &PrepareReply{}, nil
+}
+
+// Churn takes the node down for the requested time: it stops handling the
+// messages of the other nodes, and handles them once it is back, like a node
+// that lost its connection.
+func (s *Service) Churn(req *Churn) (*ChurnReply, error) {
+	s.serverMutex.Lock()
+	defer s.serverMutex.Unlock()
+	if s.server == nil {
+		return nil, errors.New("node is not in a simulation")
+	}
+	if s.down {
+		return nil, errors.New("node is already down")
+	}
+
+	log.Lvlf2("%s goes down for %s", s.ServerIdentity(), req.Downtime)
+	s.down = true
+	s.server.Pause()
+	time.AfterFunc(req.Downtime, func() {
+		s.serverMutex.Lock()
+		defer s.serverMutex.Unlock()
+		log.Lvlf2("%s is back", s.ServerIdentity())
+		s.server.Unpause()
+		s.down = false
+	})
+	return &ChurnReply{}, nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+	}
+	if err := s.RegisterHandlers(s.Prepare, s.Churn); err != nil {
+		return nil, errors.New("couldn't register handlers: " + err.Error())
+	}
+	return s, nil
+}
@@ -0,0 +1,63 @@
+package scenario
+
+import (
+	"errors"
+
+	"github.com/BurntSushi/toml"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Name of the simulation running a scenario.
+const Name = "ChurnAndLoad"
+
+func init() {
+	onet.SimulationRegister(Name, NewSimulation)
+}
+
+// Simulation runs the scenario of its Config with the nodes of the
+// simulation.
+type Simulation struct {
+	onet.SimulationBFTree
+	Config
+}
+
+// NewSimulation returns the new simulation, where all fields are
+// initialised using the config-file
+func NewSimulation(config string) (onet.Simulation, error) {
+	s := &Simulation{}
+	_, err := toml.Decode(config, s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Setup creates the tree used for that simulation
+func (s *Simulation) Setup(dir string, hosts []string) (
+	*onet.SimulationConfig, error) {
+	sc := &onet.SimulationConfig{}
+	s.CreateRoster(sc, hosts, 2000)
+	err := s.CreateTree(sc)
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// Node gives the server to the Scenario service, so that it can take it
+// down, and initializes the roster and the tree.
+func (s *Simulation) Node(config *onet.SimulationConfig) error {
+	svc, ok := config.Server.Service(ServiceName).(*Service)
+	if !ok {
+		return errors.New("scenario service is not running")
+	}
+	svc.setServer(config.Server)
+	return s.SimulationBFTree.Node(config)
+}
+
+// Run runs the scenario on the root of the simulation.
+func (s *Simulation) Run(config *onet.SimulationConfig) error {
+	log.Lvl2("Size is:", len(config.Roster.List), "rounds:", s.Rounds)
+	return Run(s.Config, config.Roster, s.Rounds)
+}
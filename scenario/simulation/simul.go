@@ -0,0 +1,10 @@
+package main
+
+import (
+	_ "go.dedis.ch/cothority/v3/scenario"
+	"go.dedis.ch/onet/v3/simul"
+)
+
+func main() {
+	simul.Start()
+}
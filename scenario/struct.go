@@ -0,0 +1,29 @@
+package scenario
+
+import (
+	"time"
+
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessages(Prepare{}, PrepareReply{}, Churn{}, ChurnReply{})
+}
+
+// Prepare asks a node to let the ledger of the scenario use its Calypso
+// service.
+type Prepare struct {
+	ByzCoinID skipchain.SkipBlockID
+}
+
+// PrepareReply is the answer to a Prepare.
+type PrepareReply struct{}
+
+// Churn asks a node to go down for the given time.
+type Churn struct {
+	Downtime time.Duration
+}
+
+// ChurnReply is sent by the node before it goes down.
+type ChurnReply struct{}
@@ -0,0 +1,239 @@
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/simul/monitor"
+)
+
+// ledger is the ledger and the LTS used by the clients of a scenario.
+type ledger struct {
+	roster        *onet.Roster
+	id            skipchain.SkipBlockID
+	genesis       *darc.Darc
+	admin         darc.Signer
+	adminCtr      uint64
+	lts           *calypso.CreateLTSReply
+	blockInterval time.Duration
+}
+
+// newLedger creates a ledger on the roster, authorizes it on all the nodes,
+// and creates an LTS shared by all the nodes.
+func newLedger(roster *onet.Roster, blockInterval time.Duration) (*ledger,
+	error) {
+	l := &ledger{
+		roster:        roster,
+		admin:         darc.NewSignerEd25519(nil, nil),
+		blockInterval: blockInterval,
+	}
+	gm, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:" + calypso.ContractLongTermSecretID},
+		l.admin.Identity())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't setup genesis message: %v", err)
+	}
+	gm.BlockInterval = blockInterval
+	bc, _, err := byzcoin.NewLedger(gm, false)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create genesis block: %v", err)
+	}
+	l.id = bc.ID
+	l.genesis = &gm.GenesisDarc
+
+	cl := onet.NewClient(cothority.Suite, ServiceName)
+	for _, si := range roster.List {
+		err := cl.SendProtobuf(si, &Prepare{ByzCoinID: l.id}, &PrepareReply{})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't prepare %s: %v", si, err)
+		}
+	}
+
+	l.adminCtr++
+	l.lts, err = calypso.NewClient(bc).CreateLTS(roster, l.genesis.GetBaseID(),
+		[]darc.Signer{l.admin}, []uint64{l.adminCtr})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the LTS: %v", err)
+	}
+	return l, nil
+}
+
+// client sends the requests of one client. It has its own signer and darc,
+// so that the clients don't share a counter.
+type client struct {
+	*ledger
+	bc      *byzcoin.Client
+	calypso *calypso.Client
+	signer  darc.Signer
+	darc    *darc.Darc
+	counter uint64
+	rand    *rand.Rand
+	latency Latency
+}
+
+// newClient spawns the darc of a new client. The clients are created one
+// after the other, as they are spawned by the admin.
+func (l *ledger) newClient(name string, latency Latency,
+	seed int64) (*client, error) {
+	c := &client{
+		ledger:  l,
+		bc:      byzcoin.NewClient(l.id, *l.roster),
+		signer:  darc.NewSignerEd25519(nil, nil),
+		rand:    rand.New(rand.NewSource(seed)),
+		latency: latency,
+	}
+	c.calypso = calypso.NewClient(c.bc)
+
+	id := []darc.Identity{c.signer.Identity()}
+	c.darc = darc.NewDarc(darc.InitRules(id, id), []byte(name))
+	for _, action := range []string{"spawn:" + contracts.ContractValueID,
+		"spawn:" + calypso.ContractWriteID, "spawn:" + calypso.ContractReadID} {
+		err := c.darc.Rules.AddRule(darc.Action(action),
+			expression.InitOrExpr(c.signer.Identity().String()))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't add rule: %v", err)
+		}
+	}
+	l.adminCtr++
+	_, err := c.calypso.SpawnDarc(l.admin, l.adminCtr, *l.genesis, *c.darc, 10)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't spawn the darc of %s: %v", name, err)
+	}
+	return c, nil
+}
+
+// round sends the transactions and the secrets of a round in a random order,
+// and returns how many of them succeeded and failed.
+func (c *client) round(transactions, secrets int) (done, failed int) {
+	requests := make([]func() error, 0, transactions+secrets)
+	for i := 0; i < transactions; i++ {
+		requests = append(requests, c.transaction)
+	}
+	for i := 0; i < secrets; i++ {
+		requests = append(requests, c.secret)
+	}
+	c.rand.Shuffle(len(requests), func(i, j int) {
+		requests[i], requests[j] = requests[j], requests[i]
+	})
+
+	for _, request := range requests {
+		if err := request(); err != nil {
+			log.Warn(err)
+			c.resync()
+			failed++
+			continue
+		}
+		done++
+	}
+	return
+}
+
+// transaction spawns a value instance and waits for it to be included.
+func (c *client) transaction() error {
+	value := make([]byte, 32)
+	c.rand.Read(value)
+	c.counter++
+	tx, err := c.bc.CreateTransaction(byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(c.darc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: contracts.ContractValueID,
+			Args:       byzcoin.Arguments{{Name: "value", Value: value}},
+		},
+		SignerCounter: []uint64{c.counter},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create the transaction: %v", err)
+	}
+	if err := tx.FillSignersAndSignWith(c.signer); err != nil {
+		return fmt.Errorf("couldn't sign the transaction: %v", err)
+	}
+
+	m := monitor.NewTimeMeasure(MetricTransaction)
+	c.delay()
+	if _, err := c.bc.AddTransactionAndWait(tx, 10); err != nil {
+		return fmt.Errorf("couldn't add the transaction: %v", err)
+	}
+	m.Record()
+	return nil
+}
+
+// secret writes a secret, reads it, and checks that the re-encrypted secret
+// is the one written.
+func (c *client) secret() error {
+	key := make([]byte, 16)
+	c.rand.Read(key)
+
+	m := monitor.NewTimeMeasure(MetricWrite)
+	c.delay()
+	write := calypso.NewWrite(cothority.Suite, c.lts.InstanceID,
+		c.darc.GetBaseID(), c.lts.X, key)
+	c.counter++
+	wr, err := c.calypso.AddWrite(write, c.signer, c.counter, *c.darc, 10)
+	if err != nil {
+		return fmt.Errorf("couldn't write the secret: %v", err)
+	}
+	writeProof, err := c.calypso.WaitProof(wr.InstanceID, c.blockInterval, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't get the proof of the write: %v", err)
+	}
+	m.Record()
+
+	m = monitor.NewTimeMeasure(MetricRead)
+	c.delay()
+	c.counter++
+	re, err := c.calypso.AddRead(writeProof, c.signer, c.counter, 10)
+	if err != nil {
+		return fmt.Errorf("couldn't read the secret: %v", err)
+	}
+	readProof, err := c.calypso.WaitProof(re.InstanceID, c.blockInterval, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't get the proof of the read: %v", err)
+	}
+	m.Record()
+
+	m = monitor.NewTimeMeasure(MetricDecrypt)
+	c.delay()
+	dk, err := c.calypso.DecryptKey(&calypso.DecryptKey{Read: *readProof,
+		Write: *writeProof})
+	if err != nil {
+		return fmt.Errorf("couldn't decrypt the secret: %v", err)
+	}
+	m.Record()
+	recovered, err := dk.RecoverKey(c.signer.Ed25519.Secret)
+	if err != nil {
+		return fmt.Errorf("couldn't recover the secret: %v", err)
+	}
+	if !bytes.Equal(recovered, key) {
+		return errors.New("decrypted a wrong secret")
+	}
+	return nil
+}
+
+// delay waits for a latency of the distribution of the scenario, before
+// sending a request.
+func (c *client) delay() {
+	time.Sleep(c.latency.Sample(c.rand))
+}
+
+// resync gets the counter of the signer after a failed request, which may or
+// may not have been included.
+func (c *client) resync() {
+	resp, err := c.bc.GetSignerCounters(c.signer.Identity().String())
+	if err != nil || len(resp.Counters) != 1 {
+		log.Warnf("Couldn't get the counter of the client: %v", err)
+		return
+	}
+	c.counter = resp.Counters[0]
+}
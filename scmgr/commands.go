@@ -191,6 +191,20 @@ func getCommands() cli.Commands {
 			},
 		},
 
+		{
+			Name:      "health",
+			Usage:     "show the view each node of the roster has of a skipchain",
+			ArgsUsage: "skipchain-id [" + groupsDef + "]",
+			Action:    scHealth,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "maxlag",
+					Value: 1,
+					Usage: "how many blocks a node can be behind before being a straggler",
+				},
+			},
+		},
+
 		{
 			Name:    "scdns",
 			Usage:   "skipchain dns handling for web frontend",
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Status of a node in the health table.
+const (
+	healthOK          = "ok"
+	healthStraggler   = "straggler"
+	healthFork        = "fork"
+	healthUnreachable = "unreachable"
+)
+
+// nodeView is the view a node has of a skipchain.
+type nodeView struct {
+	Node *network.ServerIdentity
+	// Latest is the latest block the node knows of, nil if unreachable.
+	Latest *skipchain.SkipBlock
+	// Follow tells if the node follows the chain, if scmgr is linked to it.
+	Follow string
+}
+
+// healthRow is a line of the health table.
+type healthRow struct {
+	Address network.Address
+	Index   int
+	Lag     int
+	Hash    skipchain.SkipBlockID
+	Follow  string
+	Status  string
+}
+
+// scHealth asks every node of the roster of a chain for the latest block it
+// knows, and prints a table of their views, marking the nodes lagging behind
+// the most recent block and the ones having different blocks at the same
+// index. It returns an error if any node is not healthy.
+func scHealth(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return errors.New("please give skipchain-id [group-definition]")
+	}
+	scid, err := hex.DecodeString(c.Args().First())
+	if err != nil {
+		return errors.New("invalid skipchain-id: " + err.Error())
+	}
+
+	cfg := getConfigOrFail(c)
+	var roster *onet.Roster
+	if c.NArg() > 1 {
+		roster = readGroupArgs(c, 1).Roster
+	} else {
+		sb := cfg.Db.GetByID(scid)
+		if sb == nil {
+			return errors.New("unknown skipchain, please give a " +
+				"group-definition or fetch it with 'scdns fetch'")
+		}
+		roster = sb.Roster
+	}
+
+	cl := skipchain.NewClient()
+	gcr, err := cl.GetUpdateChain(roster, scid)
+	if err != nil {
+		return fmt.Errorf("couldn't get the latest block: %v", err)
+	}
+	latest := gcr.Update[len(gcr.Update)-1]
+
+	var views []nodeView
+	for _, si := range latest.Roster.List {
+		view := nodeView{Node: si, Follow: "-"}
+
+		reply := &skipchain.GetUpdateChainReply{}
+		err := cl.SendProtobuf(si, &skipchain.GetUpdateChain{LatestID: scid},
+			reply)
+		if err != nil {
+			log.Lvlf2("%s is unreachable: %v", si.Address, err)
+		} else if len(reply.Update) > 0 {
+			view.Latest = reply.Update[len(reply.Update)-1]
+		}
+
+		if l, ok := cfg.Values.Link[si.Public.String()]; ok {
+			view.Follow = followStatus(cl, l, scid)
+		}
+		views = append(views, view)
+	}
+
+	rows := healthRows(views, c.Int("maxlag"))
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tINDEX\tLAG\tBLOCK\tFOLLOW\tSTATUS")
+	unhealthy := 0
+	for _, r := range rows {
+		if r.Status == healthUnreachable {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t%s\t%s\n", r.Address, r.Follow,
+				r.Status)
+		} else {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%x\t%s\t%s\n", r.Address, r.Index,
+				r.Lag, []byte(r.Hash)[:8], r.Follow, r.Status)
+		}
+		if r.Status != healthOK {
+			unhealthy++
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if unhealthy > 0 {
+		return fmt.Errorf("%d of %d nodes are not healthy", unhealthy,
+			len(rows))
+	}
+	return nil
+}
+
+// healthRows computes the lag of every node behind the most recent block
+// and its status. Nodes reporting different blocks at the same index are
+// marked as forks, nodes lagging more than maxLag blocks as stragglers.
+func healthRows(views []nodeView, maxLag int) []healthRow {
+	top := -1
+	hashes := map[int]map[string]bool{}
+	for _, v := range views {
+		if v.Latest == nil {
+			continue
+		}
+		if v.Latest.Index > top {
+			top = v.Latest.Index
+		}
+		if hashes[v.Latest.Index] == nil {
+			hashes[v.Latest.Index] = map[string]bool{}
+		}
+		hashes[v.Latest.Index][string(v.Latest.Hash)] = true
+	}
+
+	rows := make([]healthRow, len(views))
+	for i, v := range views {
+		rows[i] = healthRow{Address: v.Node.Address, Follow: v.Follow}
+		if v.Latest == nil {
+			rows[i].Status = healthUnreachable
+			continue
+		}
+
+		rows[i].Index = v.Latest.Index
+		rows[i].Lag = top - v.Latest.Index
+		rows[i].Hash = v.Latest.Hash
+		switch {
+		case len(hashes[v.Latest.Index]) > 1:
+			rows[i].Status = healthFork
+		case rows[i].Lag > maxLag:
+			rows[i].Status = healthStraggler
+		default:
+			rows[i].Status = healthOK
+		}
+	}
+	return rows
+}
+
+// followStatus returns "yes" if the linked node follows the chain, "no" if
+// it doesn't, and "all" if it accepts any chain.
+func followStatus(cl *skipchain.Client, l *link,
+	scid skipchain.SkipBlockID) string {
+	list, err := cl.ListFollow(l.Conode, l.Private)
+	if err != nil {
+		log.Lvlf2("couldn't list the follows of %s: %v", l.Address, err)
+		return "error"
+	}
+
+	if (list.FollowIDs == nil || len(*list.FollowIDs) == 0) &&
+		(list.Follow == nil || len(*list.Follow) == 0) {
+		return "all"
+	}
+	if list.FollowIDs != nil {
+		for _, id := range *list.FollowIDs {
+			if id.Equal(scid) {
+				return "yes"
+			}
+		}
+	}
+	if list.Follow != nil {
+		for _, fct := range *list.Follow {
+			if fct.Block.SkipChainID().Equal(scid) {
+				return "yes"
+			}
+		}
+	}
+	return "no"
+}
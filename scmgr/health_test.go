@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestHealthRows(t *testing.T) {
+	block := func(index int, hash string) *skipchain.SkipBlock {
+		return &skipchain.SkipBlock{
+			SkipBlockFix: &skipchain.SkipBlockFix{Index: index},
+			Hash:         []byte(hash),
+		}
+	}
+	node := func(port string) *network.ServerIdentity {
+		return network.NewServerIdentity(nil,
+			network.NewAddress(network.TLS, "localhost:"+port))
+	}
+
+	views := []nodeView{
+		{Node: node("2002"), Latest: block(5, "a5"), Follow: "yes"},
+		{Node: node("2004"), Latest: block(4, "a4")},
+		{Node: node("2006"), Latest: block(2, "a2")},
+		{Node: node("2008")},
+		{Node: node("2010"), Latest: block(4, "b4")},
+	}
+	rows := healthRows(views, 1)
+	require.Len(t, rows, len(views))
+
+	require.Equal(t, healthOK, rows[0].Status)
+	require.Equal(t, 0, rows[0].Lag)
+	require.Equal(t, "yes", rows[0].Follow)
+	require.Equal(t, healthFork, rows[1].Status)
+	require.Equal(t, 1, rows[1].Lag)
+	require.Equal(t, healthStraggler, rows[2].Status)
+	require.Equal(t, 3, rows[2].Lag)
+	require.Equal(t, healthUnreachable, rows[3].Status)
+	require.Equal(t, healthFork, rows[4].Status)
+
+	// A bigger lag is tolerated
+	rows = healthRows(views[:3], 3)
+	for _, r := range rows {
+		require.Equal(t, healthOK, r.Status)
+	}
+}
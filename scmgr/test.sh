@@ -20,6 +20,7 @@ main(){
 	run testAdd
 	run testIndex
 	run testFetch
+	run testHealth
 	run testLink
 	run testLinklist
 	run testUnlink
@@ -175,6 +176,18 @@ testFetch(){
 	testGrep 2004 runSc scdns list
 }
 
+testHealth(){
+	startCl
+	setupGenesis
+	testFail runSc health
+	testFail runSc health 1234
+	testGrep "NODE.*STATUS" runSc health $ID
+	testGrep "2002 .* ok" runSc health $ID
+	testGrep "2004 .* ok" runSc health $ID public.toml
+	rm -rf "$CFG"
+	testFail runSc health $ID
+}
+
 testRestart(){
 	startCl
 	setupGenesis
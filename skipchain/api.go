@@ -7,6 +7,7 @@ import (
 
 	"go.dedis.ch/cothority/v3"
 	status "go.dedis.ch/cothority/v3/status/service"
+	"go.dedis.ch/cothority/v3/transport"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
@@ -20,6 +21,8 @@ type Client struct {
 	*onet.Client
 	// Used for SendProtobufParallel. If it is nil, default values will be used.
 	options *onet.ParallelOptions
+	// Replaces SendProtobufParallel if it is set.
+	transport *transport.Transport
 }
 
 // NewClient instantiates a new client with name 'n'
@@ -46,6 +49,24 @@ func (c *Client) DontContact(si *network.ServerIdentity) {
 	c.options.IgnoreNodes = []*network.ServerIdentity{si}
 }
 
+// UseTransport makes the requests asking any node of a roster go through the
+// transport, with its retries, failover and circuit breakers. The requests to
+// a given node are sent as before.
+func (c *Client) UseTransport(t *transport.Transport) {
+	c.transport = t
+}
+
+// sendParallel sends the request to the nodes, through the transport if there
+// is one.
+func (c *Client) sendParallel(nodes []*network.ServerIdentity, msg,
+	reply interface{}) (*network.ServerIdentity, error) {
+	if c.transport != nil {
+		return c.transport.Send(c.Client, transport.Order(nodes, c.options),
+			msg, reply)
+	}
+	return c.SendProtobufParallel(nodes, msg, reply, c.options)
+}
+
 // StoreSkipBlockSignature asks the cothority to store the new skipblock, and
 // eventually attach it after the target skipblock.
 //  - target is a skipblock, and the new skipblock is going to be added after
@@ -278,11 +299,11 @@ func (c *Client) GetUpdateChainLevel(initRoster *onet.Roster,
 				return update, nil
 			}
 		}
-		node, err := c.SendProtobufParallel(roster.List, &GetUpdateChain{
+		node, err := c.sendParallel(roster.List, &GetUpdateChain{
 			LatestID:  latest,
 			MaxHeight: maxLevel,
 			MaxBlocks: mb,
-		}, r2, c.options)
+		}, r2)
 		if err != nil {
 			same, err := roster.Equal(initRoster)
 			if same || err != nil {
@@ -406,7 +427,7 @@ func (c *Client) GetAllSkipChainIDs(si *network.ServerIdentity) (reply *GetAllSk
 // or an error if that block is not found.
 func (c *Client) GetSingleBlock(roster *onet.Roster, id SkipBlockID) (*SkipBlock, error) {
 	var reply = &SkipBlock{}
-	_, err := c.SendProtobufParallel(roster.List, &GetSingleBlock{id}, reply, c.options)
+	_, err := c.sendParallel(roster.List, &GetSingleBlock{id}, reply)
 	if err != nil {
 		return nil, errors.New("all nodes failed to return block: " + err.Error())
 	}
@@ -426,8 +447,7 @@ func (c *Client) GetSingleBlock(roster *onet.Roster, id SkipBlockID) (*SkipBlock
 func (c *Client) GetSingleBlockByIndex(roster *onet.Roster, genesis SkipBlockID, index int) (reply *GetSingleBlockByIndexReply, err error) {
 	reply = &GetSingleBlockByIndexReply{}
 
-	_, err = c.SendProtobufParallel(roster.List, &GetSingleBlockByIndex{genesis, index}, reply,
-		c.options)
+	_, err = c.sendParallel(roster.List, &GetSingleBlockByIndex{genesis, index}, reply)
 	if err != nil {
 		return
 	}
@@ -16,18 +16,25 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/xerrors"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/admission"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/cothority/v3/keyrotation"
 	"go.dedis.ch/cothority/v3/messaging"
+	"go.dedis.ch/cothority/v3/wireversion"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
@@ -44,15 +51,30 @@ const bftFollowBlock = "SkipchainBFTFollow"
 const bdnNewBlock = "SkipchainBDNNew"
 const bdnFollowBlock = "SkipchainBDNFollow"
 
+// CurrentWireVersion is the version of the messages sent between the
+// conodes by the service, as exchanged by the wireversion package.
+const CurrentWireVersion = wireversion.Legacy
+
+// OldestWireVersion is the oldest version of the messages the service can
+// still talk.
+const OldestWireVersion = wireversion.Legacy
+
 var storageKey = []byte("skipchainconfig")
 var dbVersion = 1
 var suite = pairing.NewSuiteBn256()
 
 var sid onet.ServiceID
 
+// admissionLimits bound how fast a client can add blocks when the conode
+// enables the admission control.
+var admissionLimits = []admission.Limit{
+	{Endpoint: ServiceName + "/StoreSkipBlock", Rate: 1, Burst: 10},
+}
+
 func init() {
 	sid, _ = onet.RegisterNewServiceWithSuite(ServiceName, suite, newSkipchainService)
 	network.RegisterMessages(&Storage{})
+	log.ErrFatal(admission.RegisterDefaults(admissionLimits...))
 }
 
 // Service handles adding new SkipBlocks
@@ -80,6 +102,70 @@ type Service struct {
 	disableForwardLink bool
 }
 
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// WireVersion implements wireversion.Versioner.
+func (s *Service) WireVersion() wireversion.Range {
+	return wireversion.Range{Current: CurrentWireVersion,
+		Oldest: OldestWireVersion}
+}
+
+// RotateKey implements keyrotation.Rotator. The leader of the skipchains
+// with the standard verification whose latest roster holds the old key adds
+// a block with the rotated roster. The chains with other verifications, like
+// the ones of ByzCoin, are left to their services. A leader can't rotate its
+// own key this way, as it would have to propose the block with the new one.
+func (s *Service) RotateKey(t *keyrotation.Transition) ([]*onet.Roster,
+	error) {
+	chains, err := s.db.GetSkipchains()
+	if err != nil {
+		return nil, xerrors.Errorf("getting the skipchains: %v", err)
+	}
+
+	var rosters []*onet.Roster
+	var errs []string
+	for _, latest := range chains {
+		if latest.Roster == nil ||
+			!reflect.DeepEqual(latest.VerifierIDs, VerificationStandard) {
+			continue
+		}
+		rotated := t.Replace(latest.Roster)
+		if rotated == nil {
+			continue
+		}
+		rosters = append(rosters, latest.Roster)
+		if !s.ServerIdentity().Equal(latest.Roster.Get(0)) {
+			continue
+		}
+		if t.Index(latest.Roster) == 0 {
+			errs = append(errs, fmt.Sprintf("%x: the leader can't rotate "+
+				"its own key", latest.SkipChainID()))
+			continue
+		}
+
+		sb := latest.Copy()
+		sb.Roster = rotated
+		_, err := s.StoreSkipBlockInternal(&StoreSkipBlock{
+			TargetSkipChainID: latest.Hash,
+			NewBlock:          sb,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%x: %v", latest.SkipChainID(),
+				err))
+		}
+	}
+	if len(errs) > 0 {
+		return rosters, xerrors.Errorf("couldn't rotate the key: %s",
+			strings.Join(errs, "; "))
+	}
+	return rosters, nil
+}
+
 type chainLocker struct {
 	sync.Mutex
 	// the key type is string because []byte is not allowed
@@ -1153,6 +1239,12 @@ func (s *Service) SetPropTimeout(t time.Duration) {
 // makes sure that skipchain is not processing requests and will avoid
 // further requests that might be queued up.
 func (s *Service) TestClose() {
+	s.Drain()
+}
+
+// Drain refuses the new requests and blocks, and waits for the running
+// ones. The conode calls it before closing the server.
+func (s *Service) Drain() {
 	s.closedMutex.Lock()
 	if !s.closed {
 		s.closed = true
@@ -1941,7 +2033,7 @@ func (s *Service) save() {
 	s.storageMutex.Lock()
 	defer s.storageMutex.Unlock()
 	log.Lvl3("Saving service")
-	err := s.Save(storageKey, s.Storage)
+	err := atrest.Save(s, ServiceName, storageKey, s.Storage)
 	if err != nil {
 		log.Error("Couldn't save file:", err)
 	}
@@ -1951,7 +2043,7 @@ func (s *Service) save() {
 // Tries to load the configuration and updates the data in the service
 // if it finds a valid config-file.
 func (s *Service) tryLoad() error {
-	msg, err := s.Load(storageKey)
+	msg, err := atrest.Load(s, ServiceName, storageKey)
 	if err != nil {
 		return err
 	}
@@ -1992,6 +2084,11 @@ func newSkipchainService(c *onet.Context) (onet.Service, error) {
 	if err := s.tryLoad(); err != nil {
 		return nil, err
 	}
+	if n, err := atrest.SealBucket(db, bucket); err != nil {
+		return nil, err
+	} else if n > 0 {
+		log.Lvlf1("Encrypted %d skipblocks of the database", n)
+	}
 	log.ErrFatal(s.RegisterHandlers(s.StoreSkipBlock, s.GetUpdateChain,
 		s.GetSingleBlock, s.GetSingleBlockByIndex, s.GetAllSkipchains,
 		s.GetAllSkipChainIDs, s.OptimizeProof,
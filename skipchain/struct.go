@@ -2,8 +2,6 @@ package skipchain
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -13,9 +11,9 @@ import (
 	"sync"
 	"time"
 
-	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
-	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/atrest"
 	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/cothority/v3/chainverify"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/onet/v3"
@@ -517,43 +515,31 @@ func (sb *SkipBlock) SignatureProtocol() (string, string) {
 
 // CalculateHash hashes all fixed fields of the skipblock.
 func (sb *SkipBlock) CalculateHash() SkipBlockID {
-	hash := sha256.New()
-	for _, i := range []int{sb.Index, sb.Height, sb.MaximumHeight,
-		sb.BaseHeight} {
-		err := binary.Write(hash, binary.LittleEndian, int32(i))
-		if err != nil {
-			panic("error writing to hash:" + err.Error())
-		}
+	block := chainverify.Block{
+		Index:           sb.Index,
+		Height:          sb.Height,
+		MaximumHeight:   sb.MaximumHeight,
+		BaseHeight:      sb.BaseHeight,
+		GenesisID:       sb.GenesisID,
+		Data:            sb.Data,
+		SignatureScheme: sb.SignatureScheme,
 	}
-
 	for _, bl := range sb.BackLinkIDs {
-		hash.Write(bl)
+		block.BackLinkIDs = append(block.BackLinkIDs, bl)
 	}
 	for _, v := range sb.VerifierIDs {
-		hash.Write(v[:])
+		block.VerifierIDs = append(block.VerifierIDs, v[:])
 	}
-	hash.Write(sb.GenesisID)
-	hash.Write(sb.Data)
 	if sb.Roster != nil {
 		for _, pub := range sb.Roster.Publics() {
-			_, err := pub.MarshalTo(hash)
+			buf, err := pub.MarshalBinary()
 			if err != nil {
 				panic("couldn't marshall point to hash: " + err.Error())
 			}
+			block.Publics = append(block.Publics, buf)
 		}
 	}
-
-	// For backwards compatibility, the signature scheme is only added to
-	// the hash when different from the previous default (== 0)
-	if sb.SignatureScheme > 0 {
-		err := binary.Write(hash, binary.LittleEndian, sb.SignatureScheme)
-		if err != nil {
-			panic("error writing to hash: " + err.Error())
-		}
-	}
-
-	buf := hash.Sum(nil)
-	return buf
+	return block.Hash()
 }
 
 func (sb *SkipBlock) updateHash() SkipBlockID {
@@ -691,9 +677,9 @@ func (sbs Proof) verifyChain() error {
 // scheme at index i
 const (
 	// BlsSignatureSchemeIndex is the index for BLS signatures
-	BlsSignatureSchemeIndex = uint32(iota)
+	BlsSignatureSchemeIndex = chainverify.BlsScheme
 	// BdnSignatureSchemeIndex is the index for BDN signatures
-	BdnSignatureSchemeIndex
+	BdnSignatureSchemeIndex = chainverify.BdnScheme
 )
 
 // ForwardLink can be used to jump from old blocks to newer
@@ -735,13 +721,11 @@ func NewForwardLink(from, to *SkipBlock) *ForwardLink {
 // if NewRoster is nil, then it is calculated as
 // sha256(From.Hash()|To.Hash())
 func (fl *ForwardLink) Hash() SkipBlockID {
-	hash := sha256.New()
-	hash.Write(fl.From)
-	hash.Write(fl.To)
+	var rosterID []byte
 	if fl.NewRoster != nil {
-		hash.Write(fl.NewRoster.ID[:])
+		rosterID = fl.NewRoster.ID[:]
 	}
-	return hash.Sum(nil)
+	return chainverify.LinkHash(fl.From, fl.To, rosterID)
 }
 
 // Copy makes a deep copy of a ForwardLink
@@ -777,14 +761,8 @@ func (fl *ForwardLink) VerifyWithScheme(suite *pairing.SuiteBn256, pubs []kyber.
 		return errors.New("wrong hash of forward link")
 	}
 
-	switch scheme {
-	case BlsSignatureSchemeIndex:
-		return protocol.BlsSignature(fl.Signature.Sig).Verify(suite, fl.Signature.Msg, pubs)
-	case BdnSignatureSchemeIndex:
-		return bdnproto.BdnSignature(fl.Signature.Sig).Verify(suite, fl.Signature.Msg, pubs)
-	default:
-		return errors.New("unknown signature scheme")
-	}
+	return chainverify.VerifySignature(suite, pubs, fl.Signature.Msg,
+		fl.Signature.Sig, scheme)
 }
 
 // IsEmpty indicates whether this forwardlink is merely a placeholder for
@@ -1171,6 +1149,10 @@ func (db *SkipBlockDB) GetFuzzy(id string) (*SkipBlock, error) {
 		c := tx.Bucket([]byte(db.bucketName)).Cursor()
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.HasPrefix(k, match) {
+				v, err := atrest.Open(atrest.Location(db.bucketName, k), v)
+				if err != nil {
+					return err
+				}
 				_, msg, err := network.Unmarshal(v, suite)
 				if err != nil {
 					return errors.New("Unmarshal failed with error: " + err.Error())
@@ -1181,6 +1163,10 @@ func (db *SkipBlockDB) GetFuzzy(id string) (*SkipBlock, error) {
 		}
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.HasSuffix(k, match) {
+				v, err := atrest.Open(atrest.Location(db.bucketName, k), v)
+				if err != nil {
+					return err
+				}
 				_, msg, err := network.Unmarshal(v, suite)
 				if err != nil {
 					return errors.New("Unmarshal failed with error: " + err.Error())
@@ -1356,7 +1342,7 @@ func (db *SkipBlockDB) storeToTx(tx *bbolt.Tx, sb *SkipBlock) error {
 	if err != nil {
 		return err
 	}
-	return tx.Bucket([]byte(db.bucketName)).Put(key, val)
+	return atrest.Put(tx.Bucket([]byte(db.bucketName)), db.bucketName, key, val)
 }
 
 // getFromTx returns the skipblock identified by sbID.
@@ -1368,7 +1354,10 @@ func (db *SkipBlockDB) getFromTx(tx *bbolt.Tx, sbID SkipBlockID) (*SkipBlock, er
 		return nil, xerrors.New("cannot look up skipblock with ID == nil")
 	}
 
-	val := tx.Bucket(db.bucketName).Get(sbID)
+	val, err := atrest.Get(tx.Bucket(db.bucketName), db.bucketName, sbID)
+	if err != nil {
+		return nil, err
+	}
 	if val == nil {
 		return nil, nil
 	}
@@ -1394,6 +1383,10 @@ func (db *SkipBlockDB) getAll() (map[string]*SkipBlock, error) {
 	err := db.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(db.bucketName))
 		return b.ForEach(func(k, v []byte) error {
+			v, err := atrest.Open(atrest.Location(db.bucketName, k), v)
+			if err != nil {
+				return err
+			}
 			_, sbMsg, err := network.Unmarshal(v, suite)
 			if err != nil {
 				return err
@@ -1435,8 +1428,12 @@ func (db *SkipBlockDB) getAllSkipchains() (map[string]*SkipBlock, error) {
 	err := db.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(db.bucketName))
 		return b.ForEach(func(k, v []byte) error {
+			v, err := atrest.Open(atrest.Location(db.bucketName, k), v)
+			if err != nil {
+				return err
+			}
 			var sbs skipBlockShort
-			err := protobuf.Decode(v[16:], &sbs)
+			err = protobuf.Decode(v[16:], &sbs)
 			if err != nil {
 				return err
 			}
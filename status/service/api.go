@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/transport"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
@@ -16,6 +17,7 @@ import (
 // Client is a structure to communicate with status service
 type Client struct {
 	*onet.Client
+	transport *transport.Transport
 }
 
 // NewClient makes a new Client
@@ -23,16 +25,50 @@ func NewClient() *Client {
 	return &Client{Client: onet.NewClient(cothority.Suite, ServiceName)}
 }
 
+// UseTransport retries the requests for the status of a node with the policy
+// of the transport, until its deadline, and skips the node while its circuit
+// breaker is open.
+func (c *Client) UseTransport(t *transport.Transport) {
+	c.transport = t
+}
+
+// send sends the request to the node, through the transport if there is one.
+func (c *Client) send(dst *network.ServerIdentity, msg, reply interface{}) error {
+	if c.transport != nil {
+		_, err := c.transport.Send(c.Client, []*network.ServerIdentity{dst},
+			msg, reply)
+		return err
+	}
+	return c.SendProtobuf(dst, msg, reply)
+}
+
 // Request sends requests to all other members of network and creates client.
 func (c *Client) Request(dst *network.ServerIdentity) (*Response, error) {
 	resp := &Response{}
-	err := c.SendProtobuf(dst, &Request{}, resp)
+	err := c.send(dst, &Request{}, resp)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
+// GetStatusHistory returns the status samples of the node taken since the
+// given time, or all of them for the zero time. It is empty if the node
+// doesn't keep a history.
+func (c *Client) GetStatusHistory(dst *network.ServerIdentity, since time.Time) ([]StatusSample, error) {
+	// UnixNano is undefined for the times before 1678, like the zero time.
+	var ns int64
+	if since.After(time.Unix(0, 0)) {
+		ns = since.UnixNano()
+	}
+	resp := &StatusHistory{}
+	err := c.send(dst, &GetStatusHistory{Since: ns}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Samples, nil
+}
+
 // CheckConnectivity sends a message from all nodes to all nodes in the list
 // and checks if all messages are received correctly. If findFaulty == true,
 // then the service will try very hard to get a list of nodes that can
@@ -64,6 +100,33 @@ func (c *Client) CheckConnectivity(priv kyber.Scalar, list []*network.ServerIden
 	return resp.Nodes, nil
 }
 
+// CheckConnectivityMatrix has every node in the list ping every other node,
+// waiting at most timeout for each answer. It returns the round-trip times
+// between all the nodes, with the list re-ordered so that the first node
+// stays first.
+func (c *Client) CheckConnectivityMatrix(priv kyber.Scalar, list []*network.ServerIdentity,
+	timeout time.Duration) (*CheckConnectivityMatrixReply, error) {
+	conn := &CheckConnectivityMatrix{
+		List:    list,
+		Timeout: int64(timeout),
+		Time:    time.Now().Unix(),
+	}
+	hash, err := conn.hash()
+	if err != nil {
+		return nil, errors.New("couldn't hash message: " + err.Error())
+	}
+	conn.Signature, err = schnorr.Sign(cothority.Suite, priv, hash)
+	if err != nil {
+		return nil, errors.New("couldn't sign message: " + err.Error())
+	}
+	resp := &CheckConnectivityMatrixReply{}
+	err = c.SendProtobuf(list[0], conn, resp)
+	if err != nil {
+		return nil, errors.New("failed to send CheckConnectivityMatrix: " + err.Error())
+	}
+	return resp, nil
+}
+
 func (c *CheckConnectivity) hash() ([]byte, error) {
 	hash := sha256.New()
 	timeBuf := make([]byte, 8)
@@ -89,3 +152,23 @@ func (c *CheckConnectivity) hash() ([]byte, error) {
 	}
 	return hash.Sum(nil), nil
 }
+
+func (c *CheckConnectivityMatrix) hash() ([]byte, error) {
+	hash := sha256.New()
+	timeBuf := make([]byte, 8)
+
+	binary.LittleEndian.PutUint64(timeBuf, uint64(c.Time))
+	hash.Write(timeBuf)
+
+	binary.LittleEndian.PutUint64(timeBuf, uint64(c.Timeout))
+	hash.Write(timeBuf)
+
+	for _, si := range c.List {
+		buf, err := protobuf.Encode(si)
+		if err != nil {
+			return nil, errors.New("couldn't encode ServerIdentity: " + err.Error())
+		}
+		hash.Write(buf)
+	}
+	return hash.Sum(nil), nil
+}
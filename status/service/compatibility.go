@@ -0,0 +1,143 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// ProtocolVersioner is implemented by the services whose protocol can change
+// between releases, so that the conodes of a roster can check they talk the
+// same version.
+type ProtocolVersioner interface {
+	ProtocolVersion() int
+}
+
+// protocols returns the protocol versions of the services implementing
+// ProtocolVersioner.
+func (st *Stat) protocols() map[string]int {
+	versions := make(map[string]int)
+	for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+		if pv, ok := st.Context.Service(name).(ProtocolVersioner); ok {
+			versions[name] = pv.ProtocolVersion()
+		}
+	}
+	return versions
+}
+
+// NodeVersions is what a node of the roster runs.
+type NodeVersions struct {
+	Server    *network.ServerIdentity
+	Version   string
+	Services  []string
+	Protocols map[string]int
+	// Err is set if the node didn't answer.
+	Err string
+}
+
+// CompatibilityReport holds the versions of all the nodes of a roster and
+// the differences between them.
+type CompatibilityReport struct {
+	Nodes []NodeVersions
+	// Mismatches describes the differences that can break the roster, it is
+	// empty if all the nodes run the same.
+	Mismatches []string
+}
+
+// CheckCompatibility asks all the nodes in the list for their version, their
+// services and the versions of their protocols, and reports the differences.
+// A node that doesn't answer is a mismatch too.
+func (c *Client) CheckCompatibility(list []*network.ServerIdentity) *CompatibilityReport {
+	report := &CompatibilityReport{}
+	for _, si := range list {
+		nv := NodeVersions{Server: si}
+		resp, err := c.Request(si)
+		if err != nil {
+			nv.Err = err.Error()
+		} else {
+			nv.Protocols = resp.Protocols
+			if conode := resp.Status["Conode"]; conode != nil {
+				nv.Version = conode.Field["version"]
+			}
+			if generic := resp.Status["Generic"]; generic != nil &&
+				generic.Field["Available_Services"] != "" {
+				nv.Services = strings.Split(generic.Field["Available_Services"], ",")
+				sort.Strings(nv.Services)
+			}
+		}
+		report.Nodes = append(report.Nodes, nv)
+	}
+	report.Mismatches = report.mismatches()
+	return report
+}
+
+// mismatches compares the nodes that answered.
+func (r *CompatibilityReport) mismatches() []string {
+	var list []string
+	versions := make(map[string][]string)
+	services := make(map[string][]string)
+	protocols := make(map[string]map[string][]string)
+	answered := 0
+	for _, nv := range r.Nodes {
+		node := nv.Server.Address.String()
+		if nv.Err != "" {
+			list = append(list, fmt.Sprintf("%s didn't answer: %s", node, nv.Err))
+			continue
+		}
+		answered++
+		versions[nv.Version] = append(versions[nv.Version], node)
+		for _, s := range nv.Services {
+			services[s] = append(services[s], node)
+		}
+		for s, v := range nv.Protocols {
+			if protocols[s] == nil {
+				protocols[s] = make(map[string][]string)
+			}
+			version := fmt.Sprint(v)
+			protocols[s][version] = append(protocols[s][version], node)
+		}
+	}
+
+	if len(versions) > 1 {
+		list = append(list, "different versions: "+describe(versions))
+	}
+	for _, s := range sortedKeys(services) {
+		if len(services[s]) != answered {
+			list = append(list, fmt.Sprintf("service %s only on %s", s,
+				strings.Join(services[s], ", ")))
+		}
+	}
+	var names []string
+	for s := range protocols {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	for _, s := range names {
+		if len(protocols[s]) > 1 {
+			list = append(list, fmt.Sprintf("different %s protocols: %s", s,
+				describe(protocols[s])))
+		}
+	}
+	return list
+}
+
+// describe returns the nodes of every value, sorted by value.
+func describe(nodes map[string][]string) string {
+	var parts []string
+	for _, v := range sortedKeys(nodes) {
+		parts = append(parts, fmt.Sprintf("%q on %s", v, strings.Join(nodes[v], ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
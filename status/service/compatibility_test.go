@@ -0,0 +1,37 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestCompatibilityReport_Mismatches(t *testing.T) {
+	node := func(addr, version string, byzcoin int, services ...string) NodeVersions {
+		return NodeVersions{
+			Server:    network.NewServerIdentity(nil, network.NewAddress(network.TLS, addr)),
+			Version:   version,
+			Services:  services,
+			Protocols: map[string]int{"ByzCoin": byzcoin},
+		}
+	}
+	r := &CompatibilityReport{Nodes: []NodeVersions{
+		node("a:2000", "3.4.5", 1, "ByzCoin", "Status"),
+		node("b:2000", "3.4.5", 1, "ByzCoin", "Status"),
+	}}
+	require.Empty(t, r.mismatches())
+
+	r.Nodes = append(r.Nodes, node("c:2000", "3.4.6", 2, "Status"))
+	r.Nodes = append(r.Nodes, NodeVersions{
+		Server: network.NewServerIdentity(nil, network.NewAddress(network.TLS, "d:2000")),
+		Err:    "timeout",
+	})
+	mismatches := r.mismatches()
+	require.Equal(t, 4, len(mismatches))
+	require.Contains(t, mismatches[0], "d:2000 didn't answer")
+	require.Contains(t, mismatches[1], "different versions")
+	require.Contains(t, mismatches[2], "service ByzCoin only on")
+	require.Contains(t, mismatches[3], "different ByzCoin protocols")
+}
@@ -0,0 +1,91 @@
+package status
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.dedis.ch/cothority/v3/atrest"
+	"go.dedis.ch/onet/v3"
+	"go.etcd.io/bbolt"
+)
+
+// Paths of the liveness and readiness probes, as used by the orchestration
+// platforms.
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// livenessBucket is written by Alive to check that the database is writable.
+var livenessBucket = []byte("liveness")
+
+// Readier is implemented by the services that need to be done with some work,
+// like catching up with a chain, before the node can serve requests.
+type Readier interface {
+	Ready() error
+}
+
+// healthzHandler answers as long as the conode runs.
+func (st *Stat) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok\n"))
+}
+
+// readyzHandler answers with an error while the node is not ready.
+func (st *Stat) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if err := st.ready(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// ready returns an error if the database is not open, if a service is not
+// registered or if a service is not ready.
+func (st *Stat) ready() error {
+	db, _, err := st.database(storageBucket)
+	if err != nil {
+		return err
+	}
+	if err := db.View(func(*bbolt.Tx) error { return nil }); err != nil {
+		return errors.New("database is not readable: " + err.Error())
+	}
+	for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+		s := st.Context.Service(name)
+		if s == nil {
+			return errors.New("service " + name + " is not registered")
+		}
+		if r, ok := s.(Readier); ok {
+			if err := r.Ready(); err != nil {
+				return errors.New("service " + name + " is not ready: " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// Alive returns an error if the database of the conode is not writable, which
+// is used by the watchdogs restarting the hung nodes.
+func (st *Stat) Alive() error {
+	db, name, err := st.database(livenessBucket)
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(name)
+		if b == nil {
+			return errors.New("missing bucket")
+		}
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(time.Now().Unix()))
+		return atrest.Put(b, name, []byte("last"), buf)
+	})
+	if err != nil {
+		return errors.New("database is not writable: " + err.Error())
+	}
+	return nil
+}
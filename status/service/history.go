@@ -0,0 +1,102 @@
+package status
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// historyEnv holds how often the status is sampled for the history, like
+// "1m". The history is not kept if it is empty or invalid.
+const historyEnv = "COTHORITY_STATUS_HISTORY"
+
+// historySize is the number of samples kept, the oldest ones are dropped
+// first.
+const historySize = 1440
+
+// history is a ring buffer of status samples.
+type history struct {
+	sync.Mutex
+	samples []StatusSample
+	next    int
+}
+
+func newHistory(size int) *history {
+	return &history{samples: make([]StatusSample, 0, size)}
+}
+
+// add stores the sample, replacing the oldest one if the buffer is full.
+func (h *history) add(s StatusSample) {
+	h.Lock()
+	defer h.Unlock()
+	if len(h.samples) < cap(h.samples) {
+		h.samples = append(h.samples, s)
+		return
+	}
+	h.samples[h.next] = s
+	h.next = (h.next + 1) % len(h.samples)
+}
+
+// since returns the samples taken at or after the given time, oldest first.
+func (h *history) since(t int64) []StatusSample {
+	h.Lock()
+	defer h.Unlock()
+	var list []StatusSample
+	for i := range h.samples {
+		s := h.samples[(h.next+i)%len(h.samples)]
+		if s.Time >= t {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// sample returns the numeric fields of the current status, like the traffic
+// and the number of connections.
+func (st *Stat) sample() StatusSample {
+	s := StatusSample{
+		Time:   time.Now().UnixNano(),
+		Values: make(map[string]float64),
+	}
+	for service, status := range st.statuses() {
+		for key, field := range status.Field {
+			if v, ok := metricValue(field); ok {
+				s.Values[service+"."+key] = v
+			}
+		}
+	}
+	return s
+}
+
+// keepHistory samples the status at the interval set in the environment.
+func (st *Stat) keepHistory() {
+	env := os.Getenv(historyEnv)
+	if env == "" {
+		return
+	}
+	interval, err := time.ParseDuration(env)
+	if err != nil || interval <= 0 {
+		log.Error("invalid "+historyEnv+":", env)
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				st.history.add(st.sample())
+			case <-st.closing:
+				return
+			}
+		}
+	}()
+}
+
+// GetStatusHistory returns the status samples taken since the requested
+// time. Zero returns all of them.
+func (st *Stat) GetStatusHistory(req *GetStatusHistory) (*StatusHistory, error) {
+	return &StatusHistory{Samples: st.history.since(req.Since)}, nil
+}
@@ -0,0 +1,34 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	h := newHistory(3)
+	require.Empty(t, h.since(0))
+
+	for i := int64(1); i <= 5; i++ {
+		h.add(StatusSample{Time: i})
+	}
+	samples := h.since(0)
+	require.Equal(t, 3, len(samples))
+	for i, s := range samples {
+		require.Equal(t, int64(i+3), s.Time)
+	}
+	require.Equal(t, 1, len(h.since(5)))
+	require.Empty(t, h.since(6))
+}
+
+func TestStat_Close(t *testing.T) {
+	st := &Stat{history: newHistory(3), closing: make(chan struct{})}
+	st.Close()
+	st.Close()
+	select {
+	case <-st.closing:
+	default:
+		t.Fatal("the service is not closed")
+	}
+}
@@ -0,0 +1,234 @@
+package status
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// MatrixName is the name of the protocol measuring the latencies between all
+// the nodes of a roster.
+const MatrixName = "ConnectivityMatrix"
+
+func init() {
+	network.RegisterMessages(&matrixStart{}, &matrixPing{}, &matrixPong{}, &matrixRow{})
+	_, err := onet.GlobalProtocolRegister(MatrixName, newMatrixProtocol)
+	log.ErrFatal(err)
+}
+
+// matrixStart is sent by the root to ask the other nodes to ping everybody.
+type matrixStart struct {
+	Timeout int64
+}
+
+// matrixPing is sent by every node to every other node.
+type matrixPing struct{}
+
+// matrixPong answers a matrixPing.
+type matrixPong struct{}
+
+// matrixRow is sent back to the root with the latencies measured by a node.
+type matrixRow struct {
+	Latencies []int64
+}
+
+// matrixProtocol has all the nodes of the tree ping each other, and
+// collects the round-trip times at the root.
+type matrixProtocol struct {
+	*onet.TreeNodeInstance
+	// Rows is the matrix of the round-trip times in nanoseconds, indexed by
+	// the roster index of the nodes. It is sent on Finished.
+	Rows     []LatencyRow
+	Finished chan []LatencyRow
+
+	sync.Mutex
+	timeout  time.Duration
+	sent     map[int]time.Time
+	row      []int64
+	pongs    int
+	pings    int
+	rowSent  bool
+	rowsRcvd int
+	done     bool
+}
+
+func newMatrixProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	p := &matrixProtocol{
+		TreeNodeInstance: n,
+		Finished:         make(chan []LatencyRow, 1),
+		sent:             make(map[int]time.Time),
+	}
+	for _, h := range []interface{}{p.handleStart, p.handlePing, p.handlePong, p.handleRow} {
+		if err := n.RegisterHandler(h); err != nil {
+			return nil, errors.New("couldn't register handler: " + err.Error())
+		}
+	}
+	return p, nil
+}
+
+// Start asks all the nodes to ping each other, and pings them too. The
+// timeout must be set first.
+func (p *matrixProtocol) Start() error {
+	n := len(p.Roster().List)
+	p.Rows = make([]LatencyRow, n)
+	for i := range p.Rows {
+		p.Rows[i].Latencies = failedRow(n, i)
+	}
+	for _, tn := range p.Children() {
+		if err := p.SendTo(tn, &matrixStart{Timeout: int64(p.timeout)}); err != nil {
+			log.Lvl2("couldn't contact", tn.ServerIdentity, err)
+		}
+	}
+	p.pingAll()
+	// The rows of the other nodes come after their own timeout.
+	time.AfterFunc(2*p.timeout, p.finish)
+	return nil
+}
+
+func (p *matrixProtocol) handleStart(msg struct {
+	*onet.TreeNode
+	matrixStart
+}) error {
+	p.Lock()
+	p.timeout = time.Duration(msg.Timeout)
+	p.Unlock()
+	p.pingAll()
+	// Keep on answering the pings of slower nodes until they time out.
+	time.AfterFunc(2*p.timeout, p.finish)
+	return nil
+}
+
+// pingAll pings all the other nodes and sends the row once they answered or
+// the timeout is over.
+func (p *matrixProtocol) pingAll() {
+	p.Lock()
+	p.row = failedRow(len(p.Roster().List), p.TreeNode().RosterIndex)
+	p.Unlock()
+	for _, tn := range p.List() {
+		if tn.ID.Equal(p.TreeNode().ID) {
+			continue
+		}
+		p.Lock()
+		p.sent[tn.RosterIndex] = time.Now()
+		p.Unlock()
+		if err := p.SendTo(tn, &matrixPing{}); err != nil {
+			log.Lvl2("couldn't ping", tn.ServerIdentity, err)
+		}
+	}
+	time.AfterFunc(p.timeout, p.sendRow)
+}
+
+func (p *matrixProtocol) handlePing(msg struct {
+	*onet.TreeNode
+	matrixPing
+}) error {
+	err := p.SendTo(msg.TreeNode, &matrixPong{})
+	p.Lock()
+	p.pings++
+	p.Unlock()
+	p.checkDone()
+	return err
+}
+
+func (p *matrixProtocol) handlePong(msg struct {
+	*onet.TreeNode
+	matrixPong
+}) error {
+	p.Lock()
+	if sent, ok := p.sent[msg.RosterIndex]; ok && p.row[msg.RosterIndex] < 0 {
+		p.row[msg.RosterIndex] = int64(time.Since(sent))
+		p.pongs++
+	}
+	complete := p.pongs == len(p.List())-1
+	p.Unlock()
+	if complete {
+		p.sendRow()
+	}
+	return nil
+}
+
+// sendRow sends the row to the root, or stores it at the root.
+func (p *matrixProtocol) sendRow() {
+	p.Lock()
+	if p.rowSent {
+		p.Unlock()
+		return
+	}
+	p.rowSent = true
+	row := append([]int64{}, p.row...)
+	p.Unlock()
+
+	if p.IsRoot() {
+		p.storeRow(p.TreeNode().RosterIndex, row)
+	} else if err := p.SendToParent(&matrixRow{Latencies: row}); err != nil {
+		log.Lvl2("couldn't send the row to the root:", err)
+	}
+	p.checkDone()
+}
+
+func (p *matrixProtocol) handleRow(msg struct {
+	*onet.TreeNode
+	matrixRow
+}) error {
+	if len(msg.Latencies) != len(p.Roster().List) {
+		return errors.New("got a row of the wrong size")
+	}
+	p.storeRow(msg.RosterIndex, msg.Latencies)
+	return nil
+}
+
+func (p *matrixProtocol) storeRow(index int, row []int64) {
+	p.Lock()
+	p.Rows[index].Latencies = row
+	p.rowsRcvd++
+	complete := p.rowsRcvd == len(p.List())
+	p.Unlock()
+	if complete {
+		p.finish()
+	}
+}
+
+// checkDone finishes a node once it sent its row and answered all the
+// pings.
+func (p *matrixProtocol) checkDone() {
+	p.Lock()
+	done := !p.IsRoot() && p.rowSent && p.pings == len(p.List())-1
+	p.Unlock()
+	if done {
+		p.finish()
+	}
+}
+
+// finish ends the protocol once, and sends the rows at the root.
+func (p *matrixProtocol) finish() {
+	p.Lock()
+	if p.done {
+		p.Unlock()
+		return
+	}
+	p.done = true
+	var rows []LatencyRow
+	if p.IsRoot() {
+		rows = append(rows, p.Rows...)
+	}
+	p.Unlock()
+	if p.IsRoot() {
+		p.Finished <- rows
+	}
+	p.Done()
+}
+
+// failedRow returns a row of failed latencies, except to the node itself.
+func failedRow(n, self int) []int64 {
+	row := make([]int64, n)
+	for i := range row {
+		if i != self {
+			row[i] = -1
+		}
+	}
+	return row
+}
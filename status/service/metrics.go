@@ -0,0 +1,137 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// metricsEnv holds the address of the HTTP server of the metrics and of the
+// health probes, like ":9100". Nothing is served if it is empty.
+const metricsEnv = "COTHORITY_STATUS_METRICS"
+
+// metricsPath is where the metrics are served.
+const metricsPath = "/metrics"
+
+// serveMetrics starts the HTTP server of the metrics and of the health probes
+// if its address is set in the environment.
+func (st *Stat) serveMetrics() {
+	addr := os.Getenv(metricsEnv)
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, st.metricsHandler)
+	mux.HandleFunc(healthzPath, st.healthzHandler)
+	mux.HandleFunc(readyzPath, st.readyzHandler)
+	go func() {
+		log.Lvl1("Serving the status metrics on", addr+metricsPath)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("couldn't serve the status metrics:", err)
+		}
+	}()
+}
+
+func (st *Stat) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w, st.statuses())
+	storage, err := st.storage()
+	if err != nil {
+		log.Error("couldn't get the storage statistics:", err)
+		return
+	}
+	writeStorageMetrics(w, storage)
+}
+
+// writeStorageMetrics writes the database usage of the services as gauges
+// with a service label.
+func writeStorageMetrics(w io.Writer, storage []*ServiceStorage) {
+	for _, m := range []struct {
+		name  string
+		value func(*ServiceStorage) int
+	}{
+		{"conode_storage_buckets", func(s *ServiceStorage) int { return s.Buckets }},
+		{"conode_storage_keys", func(s *ServiceStorage) int { return s.Keys }},
+		{"conode_storage_bytes", func(s *ServiceStorage) int { return s.Bytes }},
+	} {
+		fmt.Fprintf(w, "# TYPE %s gauge\n", m.name)
+		for _, s := range storage {
+			fmt.Fprintf(w, "%s{service=%q} %d\n", m.name, s.Service, m.value(s))
+		}
+	}
+}
+
+// writeMetrics writes the statuses in the Prometheus text format. Every
+// numeric field, or duration in seconds, is a gauge named
+// conode_<service>_<field>. The other fields of a service are the labels of
+// its conode_<service>_info gauge, whose value is always 1.
+func writeMetrics(w io.Writer, statuses map[string]*onet.Status) {
+	services := make([]string, 0, len(statuses))
+	for service := range statuses {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		fields := statuses[service].Field
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var labels []string
+		for _, key := range keys {
+			value, ok := metricValue(fields[key])
+			if !ok {
+				labels = append(labels, fmt.Sprintf("%s=%q", metricName(key), fields[key]))
+				continue
+			}
+			name := "conode_" + metricName(service) + "_" + metricName(key)
+			fmt.Fprintf(w, "# TYPE %s gauge\n%s %s\n", name, name,
+				strconv.FormatFloat(value, 'g', -1, 64))
+		}
+		if len(labels) > 0 {
+			name := "conode_" + metricName(service) + "_info"
+			fmt.Fprintf(w, "# TYPE %s gauge\n%s{%s} 1\n", name, name, strings.Join(labels, ","))
+		}
+	}
+}
+
+// metricValue returns the value of a numeric field, or of a duration in
+// seconds.
+func metricValue(field string) (float64, bool) {
+	if v, err := strconv.ParseFloat(field, 64); err == nil {
+		return v, true
+	}
+	if d, err := time.ParseDuration(field); err == nil {
+		return d.Seconds(), true
+	}
+	return 0, false
+}
+
+// metricName returns the name in lower case, with all the characters that
+// cannot be in a Prometheus name replaced by '_'.
+func metricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+}
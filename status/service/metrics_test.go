@@ -0,0 +1,49 @@
+package status
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.dedis.ch/onet/v3"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	writeMetrics(&buf, map[string]*onet.Status{
+		"Generic": {Field: map[string]string{
+			"TX_bytes": "1024",
+			"Uptime":   "1m30s",
+			"System":   "linux/amd64",
+		}},
+		"Conode": {Field: map[string]string{"version": "3.4.5"}},
+	})
+	require.Equal(t, `# TYPE conode_conode_info gauge
+conode_conode_info{version="3.4.5"} 1
+# TYPE conode_generic_tx_bytes gauge
+conode_generic_tx_bytes 1024
+# TYPE conode_generic_uptime gauge
+conode_generic_uptime 90
+# TYPE conode_generic_info gauge
+conode_generic_info{system="linux/amd64"} 1
+`, buf.String())
+}
+
+func TestWriteStorageMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	writeStorageMetrics(&buf, []*ServiceStorage{
+		{Service: "Skipchain", Buckets: 1, Keys: 10, Bytes: 4096},
+		{Service: "Status", Buckets: 1},
+	})
+	require.Equal(t, `# TYPE conode_storage_buckets gauge
+conode_storage_buckets{service="Skipchain"} 1
+conode_storage_buckets{service="Status"} 1
+# TYPE conode_storage_keys gauge
+conode_storage_keys{service="Skipchain"} 10
+conode_storage_keys{service="Status"} 0
+# TYPE conode_storage_bytes gauge
+conode_storage_bytes{service="Skipchain"} 4096
+conode_storage_bytes{service="Status"} 0
+`, buf.String())
+}
@@ -7,6 +7,8 @@ import (
 
 // PROTOSTART
 // type :map\[string\]onet.Status:map<string, onet.Status>
+// type :map\[string\]float64:map<string, double>
+// type :map\[string\]int:map<string, sint32>
 // package status;
 //
 // option java_package = "ch.epfl.dedis.lib.proto";
@@ -23,6 +25,22 @@ type Request struct {
 type Response struct {
 	Status         map[string]*onet.Status
 	ServerIdentity *network.ServerIdentity
+	Storage        []*ServiceStorage
+	// Protocols holds the protocol versions of the services that have one.
+	Protocols map[string]int
+}
+
+// ServiceStorage is how much of the database of the conode a service uses.
+type ServiceStorage struct {
+	// Service is the name of the service.
+	Service string
+	// Buckets is the number of buckets of the service, including the nested
+	// ones, like one per skipchain.
+	Buckets int
+	// Keys is the number of keys in the buckets.
+	Keys int
+	// Bytes is the size in use by the buckets.
+	Bytes int
 }
 
 // CheckConnectivity is sent by a client to check the connectivity of a given
@@ -46,3 +64,51 @@ type CheckConnectivity struct {
 type CheckConnectivityReply struct {
 	Nodes []*network.ServerIdentity
 }
+
+// CheckConnectivityMatrix is sent by a client to have every node of a roster
+// ping every other node. The Time and the Signature are checked like the ones
+// of CheckConnectivity, on the following message:
+//   sha256( bytes.LittleEndian.PutUInt64(Time) |
+//           binary.LittleEndian.PutUInt64(Timeout) |
+//           protobuf.Encode(List[0]) | protobuf.Encode(List[1])... )
+type CheckConnectivityMatrix struct {
+	Time      int64
+	Timeout   int64
+	List      []*network.ServerIdentity
+	Signature []byte
+}
+
+// CheckConnectivityMatrixReply holds the round-trip times between all the
+// nodes. Rows[i].Latencies[j] is the time node Nodes[i] waited for the answer
+// of node Nodes[j].
+type CheckConnectivityMatrixReply struct {
+	Nodes []*network.ServerIdentity
+	Rows  []LatencyRow
+}
+
+// LatencyRow holds the round-trip times measured by a node, in nanoseconds,
+// with -1 for the nodes that didn't answer in time.
+type LatencyRow struct {
+	Latencies []int64
+}
+
+// GetStatusHistory asks for the status samples of the node.
+type GetStatusHistory struct {
+	// Since is the time of the oldest sample to return, in nanoseconds since
+	// the epoch.
+	Since int64
+}
+
+// StatusHistory holds the status samples of the node, oldest first.
+type StatusHistory struct {
+	Samples []StatusSample
+}
+
+// StatusSample holds the numeric fields of the status of a node at a given
+// time.
+type StatusSample struct {
+	// Time of the sample, in nanoseconds since the epoch.
+	Time int64
+	// Values is indexed by <service>.<field>, like Generic.TX_bytes.
+	Values map[string]float64
+}
@@ -9,12 +9,15 @@ package status
 import (
 	"errors"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/clienthook"
 	"go.dedis.ch/cothority/v3/messaging"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
 	"math"
+	"net/http"
+	"sync"
 	"time"
 )
 
@@ -33,6 +36,23 @@ func init() {
 // on a server.
 type Stat struct {
 	*onet.ServiceProcessor
+	history      *history
+	storageCache storageCache
+	// closing stops the goroutines of the service once it is closed.
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// Close stops the sampling of the history and of the storage usage.
+func (st *Stat) Close() {
+	st.closeOnce.Do(func() { close(st.closing) })
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (st *Stat) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		st.ServiceProcessor.ProcessClientRequest)
 }
 
 // Version will be set by the main() function before starting the server.
@@ -40,18 +60,29 @@ var Version = "unknown"
 
 // Request treats external request to this service.
 func (st *Stat) Request(req *Request) (network.Message, error) {
+	statuses := st.statuses()
+	storage, err := st.storage()
+	if err != nil {
+		log.Error("couldn't get the storage statistics:", err)
+	}
+	log.Lvl4("Returning", statuses)
+	return &Response{
+		Status:         statuses,
+		ServerIdentity: st.ServerIdentity(),
+		Storage:        storage,
+		Protocols:      st.protocols(),
+	}, nil
+}
+
+// statuses returns the status reports of all the services.
+func (st *Stat) statuses() map[string]*onet.Status {
 	statuses := st.Context.ReportStatus()
 
 	// Add this in here, because onet no longer knows the version, it is just a
 	// support library and should never really have known it.
 	statuses["Conode"] = &onet.Status{Field: make(map[string]string)}
 	statuses["Conode"].Field["version"] = Version
-
-	log.Lvl4("Returning", statuses)
-	return &Response{
-		Status:         statuses,
-		ServerIdentity: st.ServerIdentity(),
-	}, nil
+	return statuses
 }
 
 var errTimeout = errors.New("timeout while waiting for replies")
@@ -63,23 +94,9 @@ func (st *Stat) CheckConnectivity(req *CheckConnectivity) (*CheckConnectivityRep
 	if err != nil {
 		return nil, errors.New("couldn't hash message: " + err.Error())
 	}
-	err = schnorr.Verify(cothority.Suite, st.ServerIdentity().Public, hash, req.Signature)
+	list, err := st.checkRequest(hash, req.Signature, req.Time, req.List)
 	if err != nil {
-		return nil, errors.New("signature verification failed: " + err.Error())
-	}
-	if math.Abs(time.Now().Sub(time.Unix(req.Time,
-		0)).Seconds()) > maxRequestAge.Seconds() {
-		return nil, errors.New("too old request")
-	}
-
-	list := req.List
-	id, _ := onet.NewRoster(list).Search(st.ServerIdentity().ID)
-	if id < 0 {
-		return nil, errors.New("cannot check nodes without being in the roster")
-	}
-	if id > 0 {
-		log.Lvl1("Re-arranging list")
-		list[0], list[id] = list[id], list[0]
+		return nil, err
 	}
 
 	// Test the whole list
@@ -112,6 +129,63 @@ func (st *Stat) CheckConnectivity(req *CheckConnectivity) (*CheckConnectivityRep
 	return &CheckConnectivityReply{newList}, nil
 }
 
+// CheckConnectivityMatrix has every node of the roster ping every other node,
+// and returns all the round-trip times.
+func (st *Stat) CheckConnectivityMatrix(req *CheckConnectivityMatrix) (*CheckConnectivityMatrixReply, error) {
+	if req.Timeout <= 0 {
+		return nil, errors.New("the timeout must be positive")
+	}
+	hash, err := req.hash()
+	if err != nil {
+		return nil, errors.New("couldn't hash message: " + err.Error())
+	}
+	list, err := st.checkRequest(hash, req.Signature, req.Time, req.List)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := onet.NewRoster(list).GenerateNaryTree(len(list) - 1)
+	p, err := st.CreateProtocol(MatrixName, tree)
+	if err != nil {
+		return nil, errors.New("protocol creation failed: " + err.Error())
+	}
+	mp := p.(*matrixProtocol)
+	mp.timeout = time.Duration(req.Timeout)
+	if err = p.Start(); err != nil {
+		return nil, errors.New("couldn't start protocol: " + err.Error())
+	}
+	select {
+	case rows := <-mp.Finished:
+		return &CheckConnectivityMatrixReply{Nodes: list, Rows: rows}, nil
+	case <-time.After(3 * mp.timeout):
+		return nil, errTimeout
+	}
+}
+
+// checkRequest verifies that the request is recent and signed by this node,
+// and returns the list with this node first.
+func (st *Stat) checkRequest(hash, sig []byte, t int64,
+	list []*network.ServerIdentity) ([]*network.ServerIdentity, error) {
+	err := schnorr.Verify(cothority.Suite, st.ServerIdentity().Public, hash, sig)
+	if err != nil {
+		return nil, errors.New("signature verification failed: " + err.Error())
+	}
+	if math.Abs(time.Now().Sub(time.Unix(t,
+		0)).Seconds()) > maxRequestAge.Seconds() {
+		return nil, errors.New("too old request")
+	}
+
+	id, _ := onet.NewRoster(list).Search(st.ServerIdentity().ID)
+	if id < 0 {
+		return nil, errors.New("cannot check nodes without being in the roster")
+	}
+	if id > 0 {
+		log.Lvl1("Re-arranging list")
+		list[0], list[id] = list[id], list[0]
+	}
+	return list, nil
+}
+
 func (st *Stat) testNodes(nodes []*network.ServerIdentity, to time.Duration) error {
 	r := onet.NewRoster(nodes)
 	tree := r.GenerateBinaryTree()
@@ -139,11 +213,17 @@ func (st *Stat) testNodes(nodes []*network.ServerIdentity, to time.Duration) err
 func newStatService(c *onet.Context) (onet.Service, error) {
 	s := &Stat{
 		ServiceProcessor: onet.NewServiceProcessor(c),
+		history:          newHistory(historySize),
+		closing:          make(chan struct{}),
 	}
-	err := s.RegisterHandlers(s.Request, s.CheckConnectivity)
+	err := s.RegisterHandlers(s.Request, s.CheckConnectivity,
+		s.CheckConnectivityMatrix, s.GetStatusHistory)
 	if err != nil {
 		return nil, errors.New("couldn't register handlers: " + err.Error())
 	}
+	s.serveMetrics()
+	s.keepHistory()
+	s.keepStorage()
 
 	return s, nil
 }
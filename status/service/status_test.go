@@ -1,15 +1,20 @@
 package status
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.dedis.ch/cothority/v3/transport"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
 )
 
 var tSuite = suites.MustFind("Ed25519")
@@ -54,6 +59,52 @@ func TestStat_Connectivity(t *testing.T) {
 	local.Check = onet.CheckNone
 }
 
+// Checks that every node measures a latency to every other node, and that a
+// paused node shows up as a failed row and column.
+func TestStat_ConnectivityMatrix(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+
+	servers, ro, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	cl := NewClient()
+	priv := servers[0].ServerIdentity.GetPrivate()
+	repl, err := cl.CheckConnectivityMatrix(priv, ro.List, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, len(ro.List), len(repl.Nodes))
+	require.Equal(t, len(ro.List), len(repl.Rows))
+	for i, row := range repl.Rows {
+		require.Equal(t, len(ro.List), len(row.Latencies))
+		for j, l := range row.Latencies {
+			if i == j {
+				require.Equal(t, int64(0), l)
+			} else {
+				require.True(t, l > 0)
+			}
+		}
+	}
+
+	_, err = cl.CheckConnectivityMatrix(priv, ro.List, 0)
+	require.Error(t, err)
+
+	servers[2].Pause()
+	repl, err = cl.CheckConnectivityMatrix(priv, ro.List, 500*time.Millisecond)
+	require.NoError(t, err)
+	for i, row := range repl.Rows {
+		for j, l := range row.Latencies {
+			switch {
+			case i == j:
+				require.Equal(t, int64(0), l)
+			case i == 2 || j == 2:
+				require.Equal(t, int64(-1), l)
+			default:
+				require.True(t, l > 0)
+			}
+		}
+	}
+	local.Check = onet.CheckNone
+}
+
 func TestStat_Request(t *testing.T) {
 	local := onet.NewTCPTest(tSuite)
 
@@ -69,4 +120,77 @@ func TestStat_Request(t *testing.T) {
 	require.NoError(t, err)
 	log.Lvl1(stat)
 	assert.NotEmpty(t, stat.Status["Generic"].Field["Available_Services"])
+	require.NotEmpty(t, stat.Storage)
+}
+
+func TestStat_RequestTransport(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	_, el, _ := local.GenTree(1, false)
+	defer local.CloseAll()
+
+	p := transport.DefaultPolicy
+	p.Backoff = time.Millisecond
+	p.BreakerThreshold = 2
+	p.BreakerCooldown = time.Hour
+	client := NewTestClient(local)
+	client.UseTransport(transport.New(p))
+
+	stat, err := client.Request(el.List[0])
+	require.NoError(t, err)
+	require.NotEmpty(t, stat.Storage)
+
+	down := network.NewServerIdentity(tSuite.Point().Pick(tSuite.RandomStream()),
+		network.NewAddress(network.PlainTCP, "127.0.0.1:2"))
+	_, err = client.Request(down)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "after 2 attempts")
+
+	_, err = client.Request(down)
+	require.True(t, errors.Is(err, transport.ErrOpen))
+
+	_, err = client.Request(el.List[0])
+	require.NoError(t, err)
+}
+
+func TestStat_Ready(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	servers, _, _ := local.GenTree(1, false)
+	defer local.CloseAll()
+
+	st := servers[0].Service(ServiceName).(*Stat)
+	require.NoError(t, st.ready())
+
+	for _, h := range []http.HandlerFunc{st.healthzHandler, st.readyzHandler} {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+}
+
+func TestStat_Alive(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	servers, _, _ := local.GenTree(1, false)
+	defer local.CloseAll()
+
+	st := servers[0].Service(ServiceName).(*Stat)
+	require.NoError(t, st.Alive())
+	require.NoError(t, st.Alive())
+}
+
+func TestStat_GetStatusHistory(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	servers, ro, _ := local.GenTree(1, false)
+	defer local.CloseAll()
+
+	st := servers[0].Service(ServiceName).(*Stat)
+	st.history.add(st.sample())
+
+	samples, err := NewTestClient(local).GetStatusHistory(ro.List[0], time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(samples))
+	require.NotEmpty(t, samples[0].Values)
+
+	samples, err = NewTestClient(local).GetStatusHistory(ro.List[0], time.Now())
+	require.NoError(t, err)
+	require.Empty(t, samples)
 }
@@ -0,0 +1,135 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.etcd.io/bbolt"
+)
+
+// storageBucket is only requested to get the database of the conode, which is
+// shared by all the services.
+var storageBucket = []byte("storage")
+
+// storageInterval is how often the database usage is computed, as it walks
+// all the buckets of the database.
+var storageInterval = time.Minute
+
+// storageCache holds the last database usage of the services.
+type storageCache struct {
+	sync.Mutex
+	computed bool
+	usage    []*ServiceStorage
+	err      error
+}
+
+// storage returns the last database usage of every service, sorted by
+// service name. Only the first call walks the database, then keepStorage
+// updates it.
+func (st *Stat) storage() ([]*ServiceStorage, error) {
+	st.storageCache.Lock()
+	defer st.storageCache.Unlock()
+	if !st.storageCache.computed {
+		st.updateStorage()
+	}
+	return st.storageCache.usage, st.storageCache.err
+}
+
+// updateStorage computes the database usage. The caller must hold the lock
+// of the cache.
+func (st *Stat) updateStorage() {
+	st.storageCache.usage, st.storageCache.err = st.computeStorage()
+	st.storageCache.computed = true
+}
+
+// keepStorage updates the database usage every storageInterval.
+func (st *Stat) keepStorage() {
+	go func() {
+		ticker := time.NewTicker(storageInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-st.closing:
+				return
+			}
+			st.storageCache.Lock()
+			st.updateStorage()
+			if st.storageCache.err != nil {
+				log.Error("couldn't get the storage statistics:", st.storageCache.err)
+			}
+			st.storageCache.Unlock()
+		}
+	}()
+}
+
+// database returns the database of the conode, or an error if it can't be
+// opened.
+func (st *Stat) database(bucket []byte) (db *bbolt.DB, name []byte, err error) {
+	// onet panics if it can't create the bucket.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("couldn't get the bucket: %v", r)
+		}
+	}()
+	db, name = st.GetAdditionalBucket(bucket)
+	if db == nil {
+		return nil, nil, errors.New("database is not open")
+	}
+	return db, name, nil
+}
+
+// computeStorage returns the database usage of every service, sorted by
+// service name. The buckets of a service are named after the service, as
+// given by onet.
+func (st *Stat) computeStorage() ([]*ServiceStorage, error) {
+	names := onet.ServiceFactory.RegisteredServiceNames()
+	// Longest names first, so that a service whose name is a prefix of
+	// another one doesn't get its buckets.
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	usage := make(map[string]*ServiceStorage)
+
+	db, _, err := st.database(storageBucket)
+	if err != nil {
+		return nil, err
+	}
+	err = db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			service := "unknown"
+			for _, n := range names {
+				if string(name) == n || strings.HasPrefix(string(name), n+"_") {
+					service = n
+					break
+				}
+			}
+			if usage[service] == nil {
+				usage[service] = &ServiceStorage{Service: service}
+			}
+			s := b.Stats()
+			usage[service].Buckets += s.BucketN
+			usage[service].Keys += s.KeyN
+			usage[service].Bytes += s.BranchInuse + s.LeafInuse
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*ServiceStorage, 0, len(usage))
+	for _, s := range usage {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Service < list[j].Service
+	})
+	return list, nil
+}
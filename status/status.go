@@ -75,6 +75,14 @@ func main() {
 			Value: 0,
 			Usage: "debug-level: `integer`: 1 for terse, 5 for maximal",
 		},
+		cli.BoolFlag{
+			Name:  "json, j",
+			Usage: "same as -format json",
+		},
+		cli.DurationFlag{
+			Name:  "watch, w",
+			Usage: "refresh the status at this `interval`, highlighting the changed values",
+		},
 		cli.BoolFlag{
 			Name: "connectivity, c",
 		},
@@ -126,9 +134,28 @@ func main() {
 					},
 					Action: serve,
 				},
+				{
+					Name:      "matrix",
+					Usage:     "prints the round-trip times between all the nodes",
+					ArgsUsage: "group.toml private.toml",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "timeout, to",
+							Usage: "timeout to wait for the answer of a node",
+							Value: "1s",
+						},
+					},
+					Action: matrix,
+				},
 			},
 			Action: connectivity,
 		},
+		{
+			Name:      "compatibility",
+			Usage:     "checks that all nodes run the same version, services and protocols",
+			ArgsUsage: "group.toml",
+			Action:    compatibility,
+		},
 	}
 	app.Action = func(c *cli.Context) error {
 		log.SetUseColors(false)
@@ -148,10 +175,14 @@ type se struct {
 }
 
 // will contact all cothorities in the group-file and print
-// the status-report of each one.
+// the status-report of each one. With -watch, it does so again at every
+// interval.
 func action(c *cli.Context) error {
 	groupToml := c.GlobalString("g")
 	format := c.String("format")
+	if c.Bool("json") {
+		format = "json"
+	}
 	var list []*network.ServerIdentity
 
 	host := c.String("host")
@@ -179,6 +210,31 @@ func action(c *cli.Context) error {
 	}
 	cl := status.NewClient()
 
+	watch := c.Duration("watch")
+	// previous holds the values of the last refresh, to highlight the
+	// changes. It stays nil without -watch.
+	var previous map[string]string
+	if watch > 0 {
+		previous = make(map[string]string)
+	}
+	for {
+		if watch > 0 && format == "txt" {
+			// Clear the terminal.
+			fmt.Print("\033[H\033[2J")
+		}
+		printStatuses(cl, list, format, previous)
+		if watch <= 0 {
+			return nil
+		}
+		time.Sleep(watch)
+	}
+}
+
+// printStatuses requests the status of all the servers in the list and prints
+// them. If previous is not nil, the values that changed since the previous
+// call are highlighted.
+func printStatuses(cl *status.Client, list []*network.ServerIdentity, format string,
+	previous map[string]string) {
 	var all []se
 	for _, server := range list {
 		sr, err := cl.Request(server)
@@ -190,7 +246,7 @@ func action(c *cli.Context) error {
 			if err != nil {
 				log.Error(err)
 			} else {
-				printTxt(sr)
+				printTxt(sr, previous)
 			}
 		} else {
 			// JSON
@@ -204,7 +260,6 @@ func action(c *cli.Context) error {
 	if format == "json" {
 		printJSON(all)
 	}
-	return nil
 }
 
 func connectivity(c *cli.Context) error {
@@ -249,6 +304,71 @@ func connectivity(c *cli.Context) error {
 	return nil
 }
 
+func matrix(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("please give 2 arguments: group.toml private.toml")
+	}
+	ro, err := readGroup(c.Args().First())
+	if err != nil {
+		return errors.New("couldn't read file: " + err.Error())
+	}
+	to, err := time.ParseDuration(c.String("timeout"))
+	if err != nil {
+		return errors.New("duration parse error: " + err.Error())
+	}
+	coth, err := app.LoadCothority(c.Args().Get(1))
+	if err != nil {
+		return errors.New("error while loading private.toml: " + err.Error())
+	}
+	si, err := coth.GetServerIdentity()
+	if err != nil {
+		return errors.New("private.toml didn't have a serverIdentity: " + err.Error())
+	}
+	resp, err := status.NewClient().CheckConnectivityMatrix(si.GetPrivate(), ro.List, to)
+	if err != nil {
+		return errors.New("couldn't get the matrix: " + err.Error())
+	}
+	for i, row := range resp.Rows {
+		log.Info("From", resp.Nodes[i].String())
+		for j, l := range row.Latencies {
+			if i == j {
+				continue
+			}
+			rtt := "failed"
+			if l >= 0 {
+				rtt = time.Duration(l).String()
+			}
+			log.Infof("  to %s: %s", resp.Nodes[j].String(), rtt)
+		}
+	}
+	return nil
+}
+
+func compatibility(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("please give the group.toml")
+	}
+	ro, err := readGroup(c.Args().First())
+	if err != nil {
+		return errors.New("couldn't read file: " + err.Error())
+	}
+	report := status.NewClient().CheckCompatibility(ro.List)
+	for _, nv := range report.Nodes {
+		if nv.Err != "" {
+			log.Infof("%s: %s", nv.Server.Address, nv.Err)
+			continue
+		}
+		log.Infof("%s: version %s, protocols %v", nv.Server.Address, nv.Version,
+			nv.Protocols)
+	}
+	if len(report.Mismatches) > 0 {
+		return errors.New("the nodes are not compatible:\n  " +
+			strings.Join(report.Mismatches, "\n  "))
+	}
+	log.Info("All nodes are compatible")
+	return nil
+}
+
 // readGroup takes a toml file name and reads the file, returning the entities
 // within.
 func readGroup(tomlFileName string) (*onet.Roster, error) {
@@ -268,26 +388,51 @@ func readGroup(tomlFileName string) (*onet.Roster, error) {
 	return g.Roster, err
 }
 
-// prints the status response that is returned from the server
-func printTxt(e *status.Response) {
+// prints the status response that is returned from the server. The values
+// that changed since they were stored in previous are shown in bold, and
+// previous is updated.
+func printTxt(e *status.Response, previous map[string]string) {
 	log.Info("-----------------------------------------------")
 	log.Infof("Address = \"%s\"", e.ServerIdentity.Address)
 	log.Info("Suite = \"Ed25519\"")
 	log.Infof("Public = \"%s\"", e.ServerIdentity.Public)
 	log.Infof("Description = \"%s\"", e.ServerIdentity.Description)
 	log.Info("-----------------------------------------------")
-	var a []string
 	if e.Status == nil {
 		log.Error("no status from ", e.ServerIdentity)
 		return
 	}
 
+	fields := make(map[string]string)
 	for sec, st := range e.Status {
 		for key, value := range st.Field {
-			a = append(a, (sec + "." + key + ": " + value))
+			fields[sec+"."+key] = value
+		}
+	}
+	for _, s := range e.Storage {
+		fields["Storage."+s.Service] = fmt.Sprintf("%d buckets, %d keys, %d bytes",
+			s.Buckets, s.Keys, s.Bytes)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var a []string
+	for _, key := range keys {
+		value := fields[key]
+		line := key + ": " + value
+		if previous != nil {
+			id := e.ServerIdentity.Address.String() + "/" + key
+			if old, ok := previous[id]; ok && old != value {
+				line = "\033[1m" + line + "\033[0m"
+			}
+			previous[id] = value
 		}
+		a = append(a, line)
 	}
-	sort.Strings(a)
 	log.Info(strings.Join(a, "\n"))
 }
 
@@ -21,6 +21,8 @@ testNetwork(){
     testGrep "Available_Services" runCl -g public.toml
     testGrep "Available_Services" runCl --host localhost:2002
     testGrep "Available_Services" runCl --host tls://localhost:2002
+    testGrep '"Err": "ok"' runCl -g public.toml --json
+    testGrep "All nodes are compatible" runCl compatibility public.toml
 }
 
 testConnectivity(){
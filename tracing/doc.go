@@ -0,0 +1,22 @@
+// Package tracing follows a single client request across the logs of all the
+// conodes handling it.
+//
+// Every request that supports tracing carries an ID, either chosen by the
+// client or created by the first service receiving the request. The services
+// pass it on in the messages of the protocols they start, and log with a
+// Logger that adds the ID to every line, so that
+//
+//	grep trace=<id> *.log
+//
+// returns everything the conodes did for that request.
+//
+// The other client requests are logged by Request when they arrive and when
+// they are done, with the ID of their Header, and get a span.
+//
+// The same IDs are used for the spans, which measure how long the calls and
+// the phases of the protocols took. They are only recorded after EnableOTLP,
+// which sends them to an OpenTelemetry collector.
+//
+// For more information, please see
+// https://github.com/dedis/cothority/blob/master/tracing/README.md.
+package tracing
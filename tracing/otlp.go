@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Parameters of the OTLP exporter: the spans are sent in batches of at most
+// otlpBatchSize spans, at least every otlpFlushInterval. If the collector is
+// too slow, the spans beyond otlpQueueSize are dropped.
+var (
+	otlpBatchSize     = 512
+	otlpFlushInterval = 2 * time.Second
+	otlpQueueSize     = 4096
+	otlpTimeout       = 10 * time.Second
+)
+
+// otlpTracesPath is the path of the OTLP/HTTP endpoint for traces.
+const otlpTracesPath = "/v1/traces"
+
+// Kinds and status codes of the OTLP spans.
+const (
+	otlpKindInternal = 1
+	otlpStatusOK     = 1
+	otlpStatusError  = 2
+)
+
+var exporterMutex sync.RWMutex
+var exporter *otlpExporter
+
+func getExporter() *otlpExporter {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	return exporter
+}
+
+// otlpExporter sends the spans to an OpenTelemetry collector with the
+// OTLP/HTTP protocol, encoded in JSON, so that no other dependency is needed.
+type otlpExporter struct {
+	url      string
+	resource otlpResource
+	client   *http.Client
+	spans    chan *Span
+	done     chan struct{}
+	// dropped counts the spans dropped since the last batch, atomically.
+	dropped int64
+}
+
+// EnableOTLP starts recording the spans and sends them to the OpenTelemetry
+// collector at the endpoint, e.g. http://localhost:4318 for Jaeger. The spans
+// are sent for the given service name, with the key and value attributes.
+// The returned function sends the remaining spans and stops recording.
+func EnableOTLP(endpoint, service string, kv ...interface{}) (func(),
+	error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("OTLP endpoint must be an http or https URL")
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+
+	exporterMutex.Lock()
+	defer exporterMutex.Unlock()
+	if exporter != nil {
+		return nil, errors.New("OTLP exporter is already enabled")
+	}
+	e := &otlpExporter{
+		url: u.String(),
+		resource: otlpResource{Attributes: otlpAttributes(
+			append([]interface{}{"service.name", service}, kv...))},
+		client: &http.Client{Timeout: otlpTimeout},
+		spans:  make(chan *Span, otlpQueueSize),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	exporter = e
+
+	return func() {
+		exporterMutex.Lock()
+		exporter = nil
+		close(e.spans)
+		exporterMutex.Unlock()
+		<-e.done
+	}, nil
+}
+
+// export queues the span to the exporter, if it is still enabled, or drops it
+// if the queue is full.
+func export(s *Span) {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	if exporter == nil {
+		return
+	}
+	select {
+	case exporter.spans <- s:
+	default:
+		atomic.AddInt64(&exporter.dropped, 1)
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s, ok := <-e.spans:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) < otlpBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		e.send(batch)
+		batch = nil
+	}
+}
+
+func (e *otlpExporter) send(batch []*Span) {
+	if dropped := atomic.SwapInt64(&e.dropped, 0); dropped > 0 {
+		log.Warnf("dropped %d spans, the collector is too slow", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	buf, err := json.Marshal(e.request(batch))
+	if err != nil {
+		log.Error("couldn't encode the spans:", err)
+		return
+	}
+	resp, err := e.client.Post(e.url, "application/json",
+		bytes.NewReader(buf))
+	if err != nil {
+		log.Warn("couldn't send the spans to the collector:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Warn("the collector refused the spans:", resp.Status)
+	}
+}
+
+// The OTLP messages, with only the fields we set. They are described in
+// https://github.com/open-telemetry/opentelemetry-proto.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *otlpExporter) request(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = otlpSpan{
+			TraceID:           otlpTraceID(s.trace),
+			SpanID:            s.id.String(),
+			Name:              s.name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if len(s.parent) > 0 {
+			spans[i].ParentSpanID = s.parent.String()
+		}
+		if s.err != nil {
+			spans[i].Status = otlpStatus{Code: otlpStatusError,
+				Message: s.err.Error()}
+		}
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: e.resource,
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "go.dedis.ch/cothority/v3/tracing"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+// otlpTraceID returns the hex encoding of the trace ID on 16 bytes, as
+// required by OTLP. Shorter IDs given by the clients are padded with zeros,
+// longer ones are cut.
+func otlpTraceID(id ID) string {
+	buf := make([]byte, 16)
+	if len(id) > len(buf) {
+		id = id[:len(buf)]
+	}
+	copy(buf[len(buf)-len(id):], id)
+	return ID(buf).String()
+}
+
+// otlpAttributes converts the key and value pairs to attributes, keeping the
+// type of the numbers and booleans.
+func otlpAttributes(kv []interface{}) []otlpAttribute {
+	var attrs []otlpAttribute
+	for i := 0; i+1 < len(kv); i += 2 {
+		var v otlpValue
+		switch value := kv[i+1].(type) {
+		case bool:
+			v.BoolValue = &value
+		case int, int32, int64, uint, uint32, uint64:
+			s := fmt.Sprint(value)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		attrs = append(attrs, otlpAttribute{Key: fmt.Sprint(kv[i]),
+			Value: v})
+	}
+	return attrs
+}
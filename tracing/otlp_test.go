@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLP(t *testing.T) {
+	require.Nil(t, StartSpan(NewID(), nil, "disabled"))
+
+	requests := make(chan otlpRequest, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		require.Equal(t, otlpTracesPath, r.URL.Path)
+		var req otlpRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests <- req
+	}))
+	defer srv.Close()
+
+	_, err := EnableOTLP("localhost:4318", "test")
+	require.Error(t, err)
+	stop, err := EnableOTLP(srv.URL, "test", "node", 1)
+	require.NoError(t, err)
+	_, err = EnableOTLP(srv.URL, "test")
+	require.Error(t, err)
+
+	trace := ID{1, 2}
+	root := StartSpan(trace, nil, "root", "txs", 3)
+	child := root.Child("child")
+	child.SetAttributes("accepted", false)
+	child.End(errors.New("refused"))
+	child.End(nil)
+	root.End(nil)
+	stop()
+	require.Nil(t, StartSpan(trace, nil, "stopped"))
+
+	var spans []otlpSpan
+	timeout := time.After(10 * time.Second)
+	for len(spans) < 2 {
+		select {
+		case req := <-requests:
+			require.Len(t, req.ResourceSpans, 1)
+			rs := req.ResourceSpans[0]
+			require.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+			require.Equal(t, "test", *rs.Resource.Attributes[0].Value.StringValue)
+			require.Equal(t, "1", *rs.Resource.Attributes[1].Value.IntValue)
+			spans = append(spans, rs.ScopeSpans[0].Spans...)
+		case <-timeout:
+			require.Fail(t, "didn't get the spans")
+		}
+	}
+	require.Len(t, spans, 2)
+
+	c, r := spans[0], spans[1]
+	require.Equal(t, "child", c.Name)
+	require.Equal(t, "00000000000000000000000000000102", c.TraceID)
+	require.Equal(t, r.TraceID, c.TraceID)
+	require.Equal(t, r.SpanID, c.ParentSpanID)
+	require.Equal(t, "", r.ParentSpanID)
+	require.Equal(t, otlpStatusError, c.Status.Code)
+	require.Equal(t, "refused", c.Status.Message)
+	require.False(t, *c.Attributes[0].Value.BoolValue)
+	require.Equal(t, otlpStatusOK, r.Status.Code)
+	require.Equal(t, "3", *r.Attributes[0].Value.IntValue)
+}
+
+func TestSpan_Nil(t *testing.T) {
+	var s *Span
+	require.Nil(t, s.Child("child"))
+	require.Nil(t, s.ID())
+	s.SetAttributes("a", 1)
+	s.End(nil)
+}
+
+func TestRequest_Span(t *testing.T) {
+	requests := make(chan otlpRequest, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		var req otlpRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests <- req
+	}))
+	defer srv.Close()
+
+	stop, err := EnableOTLP(srv.URL, "test")
+	require.NoError(t, err)
+	req := httptest.NewRequest("GET", "/Skipchain/GetUpdateChain", nil)
+	req.Header.Set(Header, "0102")
+	Request(req, "Skipchain", "GetUpdateChain")(errors.New("unknown chain"))
+	stop()
+
+	select {
+	case r := <-requests:
+		spans := r.ResourceSpans[0].ScopeSpans[0].Spans
+		require.Len(t, spans, 1)
+		require.Equal(t, "Skipchain.GetUpdateChain", spans[0].Name)
+		require.Equal(t, "00000000000000000000000000000102", spans[0].TraceID)
+		require.Equal(t, otlpStatusError, spans[0].Status.Code)
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "didn't get the span")
+	}
+}
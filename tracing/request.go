@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Header is the HTTP header in which the clients, or the proxies in front of
+// the conode, can give in hex the trace ID of a websocket request.
+const Header = "X-Trace-Id"
+
+// Request logs a client request to an endpoint of the service, with the
+// trace ID of its Header or a new one, and starts its span. It is called by
+// clienthook.Process for the requests of every service, so that every
+// endpoint is traced even if its messages don't carry an ID. The returned
+// function logs the end of the request with its duration and error, and ends
+// the span.
+func Request(req *http.Request, service, endpoint string) func(error) {
+	id := requestID(req)
+	l := NewLogger(id, "service", service, "endpoint", endpoint,
+		"client", req.RemoteAddr)
+	span := StartSpan(id, nil, service+"."+endpoint, "client",
+		req.RemoteAddr)
+	pos := callerField(2)
+	log.Lvl3(l.line("client request", nil) + pos)
+	start := time.Now()
+	return func(err error) {
+		span.End(err)
+		duration := time.Since(start)
+		if err != nil {
+			log.Lvl2(l.line("client request failed",
+				[]interface{}{"duration", duration, "err", err}) + pos)
+			return
+		}
+		log.Lvl3(l.line("client request done",
+			[]interface{}{"duration", duration}) + pos)
+	}
+}
+
+// requestID returns the ID of the Header of the request, or a new one if it
+// is missing or invalid.
+func requestID(req *http.Request) ID {
+	id, err := hex.DecodeString(req.Header.Get(Header))
+	if err != nil {
+		return NewID()
+	}
+	return OrNew(id)
+}
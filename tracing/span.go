@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// spanIDLen is the length of a span ID, as in OpenTelemetry.
+const spanIDLen = 8
+
+// SpanID identifies a span in a trace. It is a slice so that it can be an
+// optional field of the protobuf messages, to start the spans of a remote node
+// as children of the local one.
+type SpanID []byte
+
+// String returns the hex encoding of the span ID, or "-" if it is empty.
+func (id SpanID) String() string {
+	if len(id) == 0 {
+		return "-"
+	}
+	return hex.EncodeToString(id)
+}
+
+// Span measures the duration of an operation of a request, like a service
+// call, the phase of a protocol or the handling of a message. The spans are
+// only recorded when an exporter is enabled, else StartSpan returns nil. All
+// the methods accept a nil span, so that the callers don't need to check.
+type Span struct {
+	trace  ID
+	id     SpanID
+	parent SpanID
+	name   string
+	start  time.Time
+	end    time.Time
+	attrs  []interface{}
+	err    error
+	once   sync.Once
+}
+
+// StartSpan starts a span of the given trace with its key and value
+// attributes. It is the child of the parent span if it is set, which can be
+// the span of another node.
+func StartSpan(trace ID, parent SpanID, name string,
+	kv ...interface{}) *Span {
+	if getExporter() == nil {
+		return nil
+	}
+
+	return &Span{
+		trace:  trace,
+		id:     randomBytes(spanIDLen),
+		parent: parent,
+		name:   name,
+		start:  time.Now(),
+		attrs:  kv,
+	}
+}
+
+// Child starts a span of the same trace, as a child of this span.
+func (s *Span) Child(name string, kv ...interface{}) *Span {
+	if s == nil {
+		return nil
+	}
+	return StartSpan(s.trace, s.id, name, kv...)
+}
+
+// ID returns the ID of the span, to be sent to the nodes starting the child
+// spans. It is nil for a nil span.
+func (s *Span) ID() SpanID {
+	if s == nil {
+		return nil
+	}
+	return s.id
+}
+
+// SetAttributes adds key and value attributes to the span.
+func (s *Span) SetAttributes(kv ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, kv...)
+}
+
+// End ends the span and sends it to the exporter. If err is not nil, the
+// span is marked as failed. Only the first call has an effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.end = time.Now()
+		s.err = err
+		export(s)
+	})
+}
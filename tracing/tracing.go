@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// idLen is the length of the IDs created by NewID, as the trace IDs of
+// OpenTelemetry.
+const idLen = 16
+
+// maxIDLen is the length above which the IDs given by the clients are
+// replaced, so that they can't fill the logs and the messages of the
+// protocols.
+const maxIDLen = 2 * idLen
+
+// ID identifies a request across conodes. It is a slice so that it can be an
+// optional field of the protobuf messages.
+type ID []byte
+
+// NewID returns a random ID.
+func NewID() ID {
+	return randomBytes(idLen)
+}
+
+// OrNew returns the ID if it is set and not longer than twice the length of
+// a new ID, else a new one. It is used by the services to keep the ID given by
+// the client.
+func OrNew(id ID) ID {
+	if len(id) > 0 && len(id) <= maxIDLen {
+		return id
+	}
+	return NewID()
+}
+
+// String returns the hex encoding of the ID, or "-" if it is empty.
+func (id ID) String() string {
+	if len(id) == 0 {
+		return "-"
+	}
+	return hex.EncodeToString(id)
+}
+
+// randomBytes returns n random bytes. As the IDs only help reading the logs
+// and the traces, they are left at zero if the random source fails.
+func randomBytes(n int) []byte {
+	buf := make([]byte, n)
+	_, err := rand.Read(buf)
+	if err != nil {
+		log.Warn("couldn't create a random ID:", err)
+	}
+	return buf
+}
+
+// Logger writes log lines in the logfmt style, made of the message followed
+// by key=value fields, the first of which being the trace ID:
+//
+//	reencrypting the key trace=8f3c2e4b7d910a55c41e9b06a2f7d318 service=Calypso node=tls://...
+//
+// It uses the onet logger, so the lines are shown depending on the debug
+// level like the others. As onet shows the position of its own caller, which
+// is always this file, the lines end with a caller field telling where the
+// Logger was called.
+type Logger struct {
+	fields string
+}
+
+// NewLogger returns a Logger for the given ID, adding the fields given as
+// key and value pairs to every line.
+func NewLogger(id ID, kv ...interface{}) Logger {
+	return Logger{fields: " trace=" + id.String()}.With(kv...)
+}
+
+// With returns a Logger that adds the given key and value pairs to every
+// line.
+func (l Logger) With(kv ...interface{}) Logger {
+	return Logger{fields: l.fields + formatFields(kv)}
+}
+
+// Lvl1 logs at debug level 1.
+func (l Logger) Lvl1(msg string, kv ...interface{}) {
+	log.Lvl1(l.line(msg, kv) + callerField(2))
+}
+
+// Lvl2 logs at debug level 2.
+func (l Logger) Lvl2(msg string, kv ...interface{}) {
+	log.Lvl2(l.line(msg, kv) + callerField(2))
+}
+
+// Lvl3 logs at debug level 3.
+func (l Logger) Lvl3(msg string, kv ...interface{}) {
+	log.Lvl3(l.line(msg, kv) + callerField(2))
+}
+
+// Info logs whatever the debug level.
+func (l Logger) Info(msg string, kv ...interface{}) {
+	log.Info(l.line(msg, kv) + callerField(2))
+}
+
+// Warn logs a warning.
+func (l Logger) Warn(msg string, kv ...interface{}) {
+	log.Warn(l.line(msg, kv) + callerField(2))
+}
+
+// Error logs an error.
+func (l Logger) Error(msg string, kv ...interface{}) {
+	log.Error(l.line(msg, kv) + callerField(2))
+}
+
+func (l Logger) line(msg string, kv []interface{}) string {
+	return msg + l.fields + formatFields(kv)
+}
+
+// callerField returns the caller field of the function skip frames above
+// callerField, 2 being the caller of the method of the Logger.
+func callerField(skip int) string {
+	_, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return " caller=?"
+	}
+	return fmt.Sprintf(" caller=%s:%d",
+		filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)),
+		line)
+}
+
+// formatFields returns the key and value pairs as " key=value", quoting the
+// values with spaces. A missing value is shown as "?".
+func formatFields(kv []interface{}) string {
+	var res strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		value := "?"
+		if i+1 < len(kv) {
+			value = fmt.Sprint(kv[i+1])
+		}
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&res, " %v=%s", kv[i], value)
+	}
+	return res.String()
+}
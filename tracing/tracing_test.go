@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestID(t *testing.T) {
+	id := NewID()
+	require.Len(t, id, idLen)
+	require.NotEqual(t, id, NewID())
+	require.Equal(t, id, OrNew(id))
+	require.Len(t, OrNew(nil), idLen)
+	long := make(ID, maxIDLen+1)
+	require.Len(t, OrNew(long), idLen)
+	require.Equal(t, long[:maxIDLen], OrNew(long[:maxIDLen]))
+	require.Equal(t, "-", ID(nil).String())
+	require.Equal(t, "0102", ID{1, 2}.String())
+}
+
+func TestLogger(t *testing.T) {
+	l := NewLogger(ID{0xab}, "service", "Calypso")
+	require.Equal(t, "start trace=ab service=Calypso",
+		l.line("start", nil))
+
+	child := l.With("node", 2)
+	require.Equal(t, `done trace=ab service=Calypso node=2 err="no reply" `+
+		`empty="" odd=?`,
+		child.line("done", []interface{}{"err", "no reply", "empty", "",
+			"odd"}))
+
+	// With doesn't change the parent logger
+	require.Equal(t, "start trace=ab service=Calypso",
+		l.line("start", nil))
+	require.Equal(t, "x trace=- a=b", NewLogger(nil).line("x",
+		[]interface{}{"a", "b"}))
+}
+
+func TestRequestID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/Calypso/DecryptKey", nil)
+	require.Len(t, requestID(req), idLen)
+
+	req.Header.Set(Header, "0102")
+	require.Equal(t, ID{1, 2}, requestID(req))
+
+	req.Header.Set(Header, "not hex")
+	require.Len(t, requestID(req), idLen)
+
+	// The IDs too long to be logged are replaced.
+	req.Header.Set(Header, hex.EncodeToString(make([]byte, maxIDLen+1)))
+	require.Len(t, requestID(req), idLen)
+}
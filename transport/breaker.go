@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3/network"
+)
+
+// breaker counts the failures in a row of a node.
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// breakers are the circuit breakers of the nodes, by their ID.
+type breakers struct {
+	sync.Mutex
+	nodes map[network.ServerIdentityID]*breaker
+}
+
+func newBreakers() *breakers {
+	return &breakers{nodes: make(map[network.ServerIdentityID]*breaker)}
+}
+
+// allow returns false if the breaker of the node is open. Once the cooldown
+// is over, the breaker stays half-open: the next failure opens it again.
+func (bs *breakers) allow(si *network.ServerIdentity, p Policy,
+	now time.Time) bool {
+	if p.BreakerThreshold <= 0 {
+		return true
+	}
+	bs.Lock()
+	defer bs.Unlock()
+	b, ok := bs.nodes[si.ID]
+	return !ok || !now.Before(b.openUntil)
+}
+
+// success closes the breaker of the node.
+func (bs *breakers) success(si *network.ServerIdentity) {
+	bs.Lock()
+	defer bs.Unlock()
+	delete(bs.nodes, si.ID)
+}
+
+// failure counts a failure of the node, and opens its breaker once there are
+// enough of them.
+func (bs *breakers) failure(si *network.ServerIdentity, p Policy,
+	now time.Time) {
+	if p.BreakerThreshold <= 0 {
+		return
+	}
+	bs.Lock()
+	defer bs.Unlock()
+	b, ok := bs.nodes[si.ID]
+	if !ok {
+		b = &breaker{}
+		bs.nodes[si.ID] = b
+	}
+	b.failures++
+	if b.failures >= p.BreakerThreshold {
+		b.openUntil = now.Add(p.BreakerCooldown)
+	}
+}
@@ -0,0 +1,339 @@
+// Package transport sends the requests of the clients of the services with a
+// common resilience policy: every request is retried on a failing node, fails
+// over to the other nodes of the roster, skips the nodes whose circuit breaker
+// is open, and gives up at the deadline of its context.
+//
+// The clients of ByzCoin, Skipchain, Calypso, Status and EventLog have a
+// UseTransport method. Without a transport, they keep asking the nodes as they
+// always did:
+//
+//	t := transport.New(transport.DefaultPolicy)
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	cl := byzcoin.NewClient(id, roster)
+//	cl.UseTransport(t.WithContext(ctx))
+//
+// The transports returned by WithContext share the circuit breakers of their
+// parent, so that a node failing for a request is skipped by the others.
+//
+// The deadlines are enforced by the client only: the nodes aren't told about
+// them, and keep working on the requests the client gave up on.
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// ErrOpen is wrapped in the errors of the requests whose nodes all have an
+// open circuit breaker.
+var ErrOpen = errors.New("circuit breaker open")
+
+// Policy is how the requests are retried and failed over.
+type Policy struct {
+	// Retries is how many times a request is sent again to a node before
+	// failing over to the next one.
+	Retries int
+	// Backoff is the wait before the first retry on a node, doubled for
+	// every retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Failover sends the request to the next node of the roster when a node
+	// fails. Without it, only the first node is asked.
+	Failover bool
+	// BreakerThreshold is how many failures in a row open the circuit
+	// breaker of a node, 0 to never open it. An open breaker lets one
+	// request through after BreakerCooldown, and closes again if it
+	// succeeds.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Timeout bounds every request, with all its attempts, on top of the
+	// deadline of the context. It is not bounded if it is 0. The deadline
+	// is only known to the client: the node isn't told about it, and an
+	// attempt abandoned at the deadline keeps running until the node
+	// answers or the connection fails, while the request fails over.
+	Timeout time.Duration
+	// Retryable returns false for the errors that the other attempts would
+	// get too, so that the request fails at once and the node isn't blamed.
+	// All the errors are retried if it is nil.
+	Retryable func(error) bool
+}
+
+// DefaultPolicy retries once on every node of the roster, and skips a node
+// for 30 seconds after 3 failures. It only retries the errors of the
+// connections, so that a request refused by a service fails at once.
+var DefaultPolicy = Policy{
+	Retries:          1,
+	Backoff:          100 * time.Millisecond,
+	MaxBackoff:       2 * time.Second,
+	Failover:         true,
+	BreakerThreshold: 3,
+	BreakerCooldown:  30 * time.Second,
+	Retryable:        IsConnectionError,
+}
+
+// connectionMessages are in the errors of the connections that onet doesn't
+// wrap.
+var connectionMessages = []string{
+	"connection refused", "connection reset", "broken pipe", "EOF",
+	"i/o timeout", "no such host", "network is unreachable",
+	"websocket: close 1006",
+}
+
+// IsConnectionError returns whether the error comes from the connection to
+// the node rather than from the service: the node couldn't be reached, or
+// it closed the connection without giving an error. The services close the
+// websocket with a code of 4000 or more when they refuse a request.
+func IsConnectionError(err error) bool {
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) {
+		return ce.Code < 4000
+	}
+	var ne net.Error
+	if errors.As(err, &ne) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	msg := err.Error()
+	for _, m := range connectionMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry, starting at 1.
+func (p Policy) backoff(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+func (p Policy) retryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Transport sends the requests with a policy. It is safe for concurrent use.
+type Transport struct {
+	policy   Policy
+	ctx      context.Context
+	breakers *breakers
+}
+
+// New returns a transport with the policy and no deadline.
+func New(p Policy) *Transport {
+	return &Transport{
+		policy:   p,
+		ctx:      context.Background(),
+		breakers: newBreakers(),
+	}
+}
+
+// Policy returns the policy of the transport.
+func (t *Transport) Policy() Policy {
+	return t.policy
+}
+
+// Context returns the context bounding the requests of the transport.
+func (t *Transport) Context() context.Context {
+	return t.ctx
+}
+
+// WithContext returns a transport whose requests end with the context. It
+// shares the circuit breakers of t.
+func (t *Transport) WithContext(ctx context.Context) *Transport {
+	return &Transport{policy: t.policy, ctx: ctx, breakers: t.breakers}
+}
+
+// Send sends the request to the nodes in turn, following the policy, and
+// decodes the reply of the first node answering. It returns that node.
+func (t *Transport) Send(c *onet.Client, nodes []*network.ServerIdentity,
+	msg, reply interface{}) (*network.ServerIdentity, error) {
+	return t.SendWithDecoder(c, nodes, msg, reply, nil)
+}
+
+// SendWithDecoder is like Send, but decodes the replies with the decoder, so
+// that a node whose reply doesn't verify counts as failing.
+func (t *Transport) SendWithDecoder(c *onet.Client,
+	nodes []*network.ServerIdentity, msg, reply interface{},
+	decoder protobuf.Decoder) (*network.ServerIdentity, error) {
+	// Every attempt decodes into its own reply, as an attempt abandoned at
+	// the deadline can still answer while the next one runs.
+	si, ret, err := t.do(nodes, func(si *network.ServerIdentity) (interface{},
+		error) {
+		ret := newReply(reply)
+		if decoder == nil {
+			return ret, c.SendProtobuf(si, msg, ret)
+		}
+		return ret, c.SendProtobufWithDecoder(si, msg, ret, decoder)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply != nil {
+		reflect.ValueOf(reply).Elem().Set(reflect.ValueOf(ret).Elem())
+	}
+	return si, nil
+}
+
+// newReply returns a new value of the type pointed to by reply, or nil.
+func newReply(reply interface{}) interface{} {
+	if reply == nil {
+		return nil
+	}
+	return reflect.New(reflect.TypeOf(reply).Elem()).Interface()
+}
+
+// result is the outcome of an attempt.
+type result struct {
+	ret interface{}
+	err error
+}
+
+// do runs the attempts of a request until one of them succeeds, the policy
+// gives up, or the context ends.
+func (t *Transport) do(nodes []*network.ServerIdentity,
+	send func(*network.ServerIdentity) (interface{}, error)) (
+	*network.ServerIdentity, interface{}, error) {
+	if len(nodes) == 0 {
+		return nil, nil, errors.New("no nodes to send the request to")
+	}
+	ctx := t.ctx
+	if t.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.policy.Timeout)
+		defer cancel()
+	}
+	if !t.policy.Failover {
+		nodes = nodes[:1]
+	}
+
+	var lastErr error
+	attempts := 0
+	for _, si := range nodes {
+		for retry := 0; retry <= t.policy.Retries; retry++ {
+			if !t.breakers.allow(si, t.policy, time.Now()) {
+				break
+			}
+			if retry > 0 {
+				if err := sleep(ctx, t.policy.backoff(retry)); err != nil {
+					return nil, nil, deadlineError(err, attempts, lastErr)
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, nil, deadlineError(err, attempts, lastErr)
+			}
+
+			attempts++
+			done := make(chan result, 1)
+			go func() {
+				ret, err := send(si)
+				done <- result{ret, err}
+			}()
+			var res result
+			select {
+			case res = <-done:
+			case <-ctx.Done():
+				return nil, nil, deadlineError(ctx.Err(), attempts, lastErr)
+			}
+
+			if res.err == nil {
+				t.breakers.success(si)
+				return si, res.ret, nil
+			}
+			lastErr = fmt.Errorf("%s: %w", si.Address, res.err)
+			if !t.policy.retryable(res.err) {
+				return nil, nil, lastErr
+			}
+			t.breakers.failure(si, t.policy, time.Now())
+		}
+	}
+
+	if attempts == 0 {
+		return nil, nil, fmt.Errorf("%w on all the %d nodes", ErrOpen,
+			len(nodes))
+	}
+	return nil, nil, fmt.Errorf("request failed after %d attempts: %w",
+		attempts, lastErr)
+}
+
+// deadlineError returns the error of a request whose context ended.
+func deadlineError(err error, attempts int, lastErr error) error {
+	if lastErr == nil {
+		return fmt.Errorf("request stopped after %d attempts: %w", attempts,
+			err)
+	}
+	return fmt.Errorf("request stopped after %d attempts: %w (last error: %v)",
+		attempts, err, lastErr)
+}
+
+// sleep waits for the duration, or until the context ends.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Order returns the nodes in the order a client with the options asks them:
+// shuffled unless DontShuffle is set, in which case they start at StartNode,
+// without the IgnoreNodes, and only the AskNodes first ones if it is set.
+func Order(nodes []*network.ServerIdentity,
+	opts *onet.ParallelOptions) []*network.ServerIdentity {
+	if opts == nil {
+		opts = &onet.ParallelOptions{}
+	}
+	ordered := make([]*network.ServerIdentity, 0, len(nodes))
+	if opts.DontShuffle {
+		for i := range nodes {
+			ordered = append(ordered, nodes[(opts.StartNode+i)%len(nodes)])
+		}
+	} else {
+		for _, i := range rand.Perm(len(nodes)) {
+			ordered = append(ordered, nodes[i])
+		}
+	}
+
+	kept := ordered[:0]
+	for _, si := range ordered {
+		ignored := false
+		for _, ign := range opts.IgnoreNodes {
+			if si.Equal(ign) {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			kept = append(kept, si)
+		}
+	}
+	if opts.AskNodes > 0 && len(kept) > opts.AskNodes {
+		kept = kept[:opts.AskNodes]
+	}
+	return kept
+}
@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func newNodes(n int) []*network.ServerIdentity {
+	nodes := make([]*network.ServerIdentity, n)
+	for i := range nodes {
+		kp := key.NewKeyPair(cothority.Suite)
+		nodes[i] = network.NewServerIdentity(kp.Public,
+			network.NewAddress(network.TLS, fmt.Sprintf("127.0.0.1:%d",
+				7770+2*i)))
+	}
+	return nodes
+}
+
+// fakeNodes answers the attempts, failing for the nodes in down.
+type fakeNodes struct {
+	sync.Mutex
+	down  map[*network.ServerIdentity]bool
+	calls []*network.ServerIdentity
+}
+
+func (f *fakeNodes) send(si *network.ServerIdentity) (interface{}, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = append(f.calls, si)
+	if f.down[si] {
+		return nil, &net.OpError{Op: "dial", Net: "tcp",
+			Err: errors.New("connection refused")}
+	}
+	return si.Address, nil
+}
+
+func testPolicy() Policy {
+	p := DefaultPolicy
+	p.Backoff = time.Millisecond
+	p.MaxBackoff = 4 * time.Millisecond
+	return p
+}
+
+func TestTransport_Failover(t *testing.T) {
+	nodes := newNodes(3)
+	f := &fakeNodes{down: map[*network.ServerIdentity]bool{nodes[0]: true}}
+	tr := New(testPolicy())
+
+	si, ret, err := tr.do(nodes, f.send)
+	require.NoError(t, err)
+	require.Equal(t, nodes[1], si)
+	require.Equal(t, nodes[1].Address, ret)
+	require.Equal(t, []*network.ServerIdentity{nodes[0], nodes[0], nodes[1]},
+		f.calls)
+
+	p := testPolicy()
+	p.Failover = false
+	f.calls = nil
+	_, _, err = New(p).do(nodes, f.send)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "after 2 attempts")
+	require.Contains(t, err.Error(), "connection refused")
+	require.Len(t, f.calls, 2)
+}
+
+func TestTransport_NotRetryable(t *testing.T) {
+	nodes := newNodes(3)
+	f := &fakeNodes{down: map[*network.ServerIdentity]bool{nodes[0]: true}}
+	p := testPolicy()
+	p.Retryable = func(error) bool { return false }
+
+	_, _, err := New(p).do(nodes, f.send)
+	require.Error(t, err)
+	require.Len(t, f.calls, 1)
+}
+
+func TestIsConnectionError(t *testing.T) {
+	require.True(t, IsConnectionError(&net.OpError{Op: "dial",
+		Err: errors.New("refused")}))
+	require.True(t, IsConnectionError(fmt.Errorf("read: %w", io.EOF)))
+	require.True(t, IsConnectionError(&websocket.CloseError{
+		Code: websocket.CloseAbnormalClosure}))
+	require.True(t, IsConnectionError(errors.New("dial tcp: connection refused")))
+	require.False(t, IsConnectionError(&websocket.CloseError{Code: 4100,
+		Text: "invalid transaction"}))
+	require.False(t, IsConnectionError(errors.New("invalid transaction")))
+
+	// A service error fails the request at once, with the default policy.
+	nodes := newNodes(2)
+	calls := 0
+	send := func(si *network.ServerIdentity) (interface{}, error) {
+		calls++
+		return nil, errors.New("darc doesn't allow the instruction")
+	}
+	_, _, err := New(testPolicy()).do(nodes, send)
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestTransport_Breaker(t *testing.T) {
+	nodes := newNodes(2)
+	f := &fakeNodes{down: map[*network.ServerIdentity]bool{nodes[0]: true}}
+	p := testPolicy()
+	p.Retries = 0
+	p.BreakerThreshold = 2
+	p.BreakerCooldown = time.Hour
+	tr := New(p)
+
+	for i := 0; i < 2; i++ {
+		si, _, err := tr.do(nodes, f.send)
+		require.NoError(t, err)
+		require.Equal(t, nodes[1], si)
+	}
+	require.Len(t, f.calls, 4)
+
+	// The breaker of the first node is open, also for the transports of
+	// the contexts.
+	f.calls = nil
+	si, _, err := tr.WithContext(context.Background()).do(nodes, f.send)
+	require.NoError(t, err)
+	require.Equal(t, nodes[1], si)
+	require.Equal(t, []*network.ServerIdentity{nodes[1]}, f.calls)
+
+	_, _, err = tr.do(nodes[:1], f.send)
+	require.True(t, errors.Is(err, ErrOpen))
+
+	// After the cooldown, one request goes through and closes the breaker.
+	bs := tr.breakers
+	require.True(t, bs.allow(nodes[0], p, time.Now().Add(2*time.Hour)))
+	delete(f.down, nodes[0])
+	bs.nodes[nodes[0].ID].openUntil = time.Now()
+	si, _, err = tr.do(nodes, f.send)
+	require.NoError(t, err)
+	require.Equal(t, nodes[0], si)
+	require.True(t, bs.allow(nodes[0], p, time.Now()))
+	require.Empty(t, bs.nodes)
+}
+
+func TestTransport_Deadline(t *testing.T) {
+	nodes := newNodes(2)
+	block := make(chan struct{})
+	defer close(block)
+	send := func(si *network.ServerIdentity) (interface{}, error) {
+		<-block
+		return nil, nil
+	}
+
+	p := testPolicy()
+	p.Timeout = 20 * time.Millisecond
+	start := time.Now()
+	_, _, err := New(p).do(nodes, send)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.True(t, time.Since(start) < time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = New(testPolicy()).WithContext(ctx).do(nodes, send)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Contains(t, err.Error(), "after 0 attempts")
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := Policy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.Equal(t, time.Second, p.backoff(1))
+	require.Equal(t, 2*time.Second, p.backoff(2))
+	require.Equal(t, 4*time.Second, p.backoff(3))
+	require.Equal(t, 5*time.Second, p.backoff(4))
+	require.Equal(t, 5*time.Second, p.backoff(10))
+}
+
+func TestOrder(t *testing.T) {
+	nodes := newNodes(4)
+	require.ElementsMatch(t, nodes, Order(nodes, nil))
+
+	ordered := Order(nodes, &onet.ParallelOptions{DontShuffle: true,
+		StartNode: 2, IgnoreNodes: []*network.ServerIdentity{nodes[3]}})
+	require.Equal(t, []*network.ServerIdentity{nodes[2], nodes[0], nodes[1]},
+		ordered)
+
+	ordered = Order(nodes, &onet.ParallelOptions{DontShuffle: true,
+		StartNode: 1, AskNodes: 1})
+	require.Equal(t, []*network.ServerIdentity{nodes[1]}, ordered)
+}
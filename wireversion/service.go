@@ -0,0 +1,293 @@
+package wireversion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/clienthook"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// ServiceName is the name of the service exchanging the versions.
+const ServiceName = "WireVersion"
+
+// HelloTimeout is how long to wait for the answer of a node before assuming
+// it speaks Legacy.
+var HelloTimeout = 2 * time.Second
+
+// CacheTTL is how long the versions of a node are kept before they are asked
+// again, so that an upgraded node is noticed.
+var CacheTTL = 5 * time.Minute
+
+// FailureTTL is how long a node that couldn't be reached is ignored before it
+// is asked again, so that the requests don't all wait for it.
+var FailureTTL = 10 * time.Second
+
+func init() {
+	_, err := onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+}
+
+// Service exchanges the versions of the services with the other nodes.
+type Service struct {
+	*onet.ServiceProcessor
+	peersMutex sync.Mutex
+	peers      map[network.ServerIdentityID]*peer
+}
+
+// ProcessClientRequest implements onet.Service and runs the client hook of
+// the conode.
+func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byte) ([]byte, *onet.StreamingTunnel, error) {
+	return clienthook.Process(req, ServiceName, path, buf,
+		s.ServiceProcessor.ProcessClientRequest)
+}
+
+// peer holds the versions of a node, once ready is closed.
+type peer struct {
+	ready    chan struct{}
+	once     sync.Once
+	versions map[string]Range
+	err      error
+	expiry   time.Time
+}
+
+func newPeer() *peer {
+	return &peer{ready: make(chan struct{})}
+}
+
+// set stores the versions of the node, or the error reaching it, and wakes
+// up the waiting requests. Only the first call has an effect.
+func (p *peer) set(versions []ServiceVersion, err error) {
+	p.once.Do(func() {
+		p.versions = make(map[string]Range)
+		for _, sv := range versions {
+			p.versions[sv.Service] = sv.Range
+		}
+		p.err = err
+		p.expiry = time.Now().Add(CacheTTL)
+		if err != nil {
+			p.expiry = time.Now().Add(FailureTTL)
+		}
+		close(p.ready)
+	})
+}
+
+// expired returns true if the versions have to be asked again. A peer
+// waiting for its answer is not expired.
+func (p *peer) expired(now time.Time) bool {
+	select {
+	case <-p.ready:
+		return !now.Before(p.expiry)
+	default:
+		return false
+	}
+}
+
+// Negotiate returns the version of the messages of the service to use with
+// the node, or an error wrapping ErrIncompatible if they don't share one. If
+// the node can't be reached, the current version is returned.
+func (s *Service) Negotiate(si *network.ServerIdentity,
+	service string) (int, error) {
+	local, err := s.local(service)
+	if err != nil {
+		return 0, err
+	}
+	if si.Equal(s.ServerIdentity()) {
+		return local.Current, nil
+	}
+
+	versions, err := s.peerVersions(si)
+	if err != nil {
+		log.Lvlf2("%s: couldn't exchange the versions with %s: %v",
+			s.ServerIdentity(), si, err)
+		return local.Current, nil
+	}
+	r, ok := versions[service]
+	if !ok {
+		r = LegacyRange
+	}
+	v, err := Common(local, r)
+	if err != nil {
+		return 0, fmt.Errorf("%s on %s: %w", service, si.Address, err)
+	}
+	return v, nil
+}
+
+// NegotiateRoster returns the version of the messages of the service to use
+// with all the nodes of the roster, which is the smallest version negotiated
+// with every node. The error lists all the incompatible nodes.
+func (s *Service) NegotiateRoster(roster *onet.Roster,
+	service string) (int, error) {
+	local, err := s.local(service)
+	if err != nil {
+		return 0, err
+	}
+
+	versions := make([]int, len(roster.List))
+	errs := make([]error, len(roster.List))
+	var wg sync.WaitGroup
+	for i, si := range roster.List {
+		wg.Add(1)
+		go func(i int, si *network.ServerIdentity) {
+			defer wg.Done()
+			versions[i], errs[i] = s.Negotiate(si, service)
+		}(i, si)
+	}
+	wg.Wait()
+
+	v := local.Current
+	err = nil
+	for i := range roster.List {
+		switch {
+		case errs[i] == nil:
+			if versions[i] < v {
+				v = versions[i]
+			}
+		case err == nil:
+			err = errs[i]
+		default:
+			err = fmt.Errorf("%w; %v", err, errs[i])
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// local returns the range of versions of the service on this node.
+func (s *Service) local(service string) (Range, error) {
+	v, ok := s.Context.Service(service).(Versioner)
+	if !ok {
+		return Range{}, fmt.Errorf("service %s has no wire version", service)
+	}
+	return v.WireVersion(), nil
+}
+
+// localVersions returns the versions of all the services of this node
+// implementing Versioner.
+func (s *Service) localVersions() []ServiceVersion {
+	var versions []ServiceVersion
+	for _, name := range onet.ServiceFactory.RegisteredServiceNames() {
+		if v, ok := s.Context.Service(name).(Versioner); ok {
+			versions = append(versions, ServiceVersion{Service: name,
+				Range: v.WireVersion()})
+		}
+	}
+	return versions
+}
+
+// peerVersions returns the versions of the services of the node, asking it
+// for them if they are not known yet or expired.
+func (s *Service) peerVersions(si *network.ServerIdentity) (map[string]Range,
+	error) {
+	s.peersMutex.Lock()
+	p, ok := s.peers[si.ID]
+	if !ok || p.expired(time.Now()) {
+		p = newPeer()
+		s.peers[si.ID] = p
+		s.peersMutex.Unlock()
+		s.hello(si, p)
+	} else {
+		s.peersMutex.Unlock()
+	}
+
+	<-p.ready
+	return p.versions, p.err
+}
+
+// hello sends our versions to the node and waits for its answer.
+func (s *Service) hello(si *network.ServerIdentity, p *peer) {
+	err := s.SendRaw(si, &Hello{Versions: s.localVersions()})
+	if err != nil {
+		p.set(nil, err)
+		return
+	}
+
+	select {
+	case <-p.ready:
+	case <-time.After(HelloTimeout):
+		log.Lvlf2("%s: %s doesn't exchange the versions, assuming version %d",
+			s.ServerIdentity(), si, Legacy)
+		p.set(nil, nil)
+	}
+}
+
+// handleHello stores the versions of the sender, which may have changed if
+// it restarted, and answers with our versions.
+func (s *Service) handleHello(env *network.Envelope) error {
+	hello, ok := env.Msg.(*Hello)
+	if !ok {
+		return errors.New("didn't get a Hello message")
+	}
+
+	s.store(env.ServerIdentity, hello.Versions)
+	return s.SendRaw(env.ServerIdentity,
+		&HelloReply{Versions: s.localVersions()})
+}
+
+// handleHelloReply stores the versions of the node we sent a Hello to.
+func (s *Service) handleHelloReply(env *network.Envelope) error {
+	reply, ok := env.Msg.(*HelloReply)
+	if !ok {
+		return errors.New("didn't get a HelloReply message")
+	}
+
+	s.store(env.ServerIdentity, reply.Versions)
+	return nil
+}
+
+// store replaces the versions of the node, also if they were assumed after a
+// timeout, and wakes up the requests waiting for them.
+func (s *Service) store(si *network.ServerIdentity,
+	versions []ServiceVersion) {
+	p := newPeer()
+	p.set(versions, nil)
+	s.peersMutex.Lock()
+	old := s.peers[si.ID]
+	s.peers[si.ID] = p
+	s.peersMutex.Unlock()
+	if old != nil {
+		old.set(versions, nil)
+	}
+}
+
+// ForRoster returns the version of the messages of the service to use with
+// the roster, as negotiated by the WireVersion service of the context. The
+// first call for a node can wait for HelloTimeout, so it must not be made
+// while signing a block.
+func ForRoster(c *onet.Context, service string,
+	roster *onet.Roster) (int, error) {
+	s, ok := c.Service(ServiceName).(*Service)
+	if !ok {
+		return 0, errors.New("wire version service is not running")
+	}
+	return s.NegotiateRoster(roster, service)
+}
+
+// ForNode returns the version of the messages of the service to use with the
+// node, as negotiated by the WireVersion service of the context. Like
+// ForRoster, it can wait for HelloTimeout.
+func ForNode(c *onet.Context, service string,
+	si *network.ServerIdentity) (int, error) {
+	s, ok := c.Service(ServiceName).(*Service)
+	if !ok {
+		return 0, errors.New("wire version service is not running")
+	}
+	return s.Negotiate(si, service)
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+		peers:            make(map[network.ServerIdentityID]*peer),
+	}
+	s.RegisterProcessorFunc(helloMsgID, s.handleHello)
+	s.RegisterProcessorFunc(helloReplyMsgID, s.handleHelloReply)
+	return s, nil
+}
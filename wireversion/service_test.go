@@ -0,0 +1,91 @@
+package wireversion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+const testServiceName = "WireVersionTest"
+
+func init() {
+	_, err := onet.RegisterNewService(testServiceName,
+		func(c *onet.Context) (onet.Service, error) {
+			return &testService{ServiceProcessor: onet.NewServiceProcessor(c),
+				r: Range{Current: 2, Oldest: 1}}, nil
+		})
+	log.ErrFatal(err)
+}
+
+type testService struct {
+	*onet.ServiceProcessor
+	r Range
+}
+
+func (ts *testService) WireVersion() Range {
+	return ts.r
+}
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_Negotiate(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	servers, roster, _ := local.GenTree(4, false)
+	servers[2].Service(testServiceName).(*testService).r = Range{Current: 3,
+		Oldest: 2}
+	servers[3].Service(testServiceName).(*testService).r = Range{Current: 4,
+		Oldest: 3}
+	s := servers[0].Service(ServiceName).(*Service)
+
+	v, err := s.Negotiate(servers[0].ServerIdentity, testServiceName)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+	v, err = s.Negotiate(servers[1].ServerIdentity, testServiceName)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+	v, err = s.Negotiate(servers[2].ServerIdentity, testServiceName)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+	_, err = s.Negotiate(servers[3].ServerIdentity, testServiceName)
+	require.True(t, errors.Is(err, ErrIncompatible))
+
+	// The nodes that got a Hello know the versions of the sender
+	v, err = servers[2].Service(ServiceName).(*Service).Negotiate(
+		servers[0].ServerIdentity, testServiceName)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+
+	_, err = ForRoster(s.Context, testServiceName, roster)
+	require.True(t, errors.Is(err, ErrIncompatible))
+	v, err = ForRoster(s.Context, testServiceName,
+		onet.NewRoster(roster.List[:3]))
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+
+	_, err = s.Negotiate(servers[1].ServerIdentity, "unknown")
+	require.Error(t, err)
+}
+
+func TestPeer_Expired(t *testing.T) {
+	now := time.Now()
+	p := newPeer()
+	require.False(t, p.expired(now.Add(time.Hour)))
+
+	p.set([]ServiceVersion{{Service: "s", Range: LegacyRange}}, nil)
+	require.False(t, p.expired(now.Add(CacheTTL/2)))
+	require.True(t, p.expired(now.Add(CacheTTL+time.Second)))
+
+	// The nodes that can't be reached are only asked again after FailureTTL.
+	p = newPeer()
+	p.set(nil, errors.New("unreachable"))
+	require.False(t, p.expired(now))
+	require.True(t, p.expired(now.Add(FailureTTL+time.Second)))
+}
@@ -0,0 +1,28 @@
+package wireversion
+
+import (
+	"go.dedis.ch/onet/v3/network"
+)
+
+var (
+	helloMsgID      = network.RegisterMessage(&Hello{})
+	helloReplyMsgID = network.RegisterMessage(&HelloReply{})
+)
+
+// ServiceVersion is the range of versions of a service.
+type ServiceVersion struct {
+	Service string
+	Range   Range
+}
+
+// Hello is sent to a node to learn the versions of its services, and gives
+// it the versions of the sender.
+type Hello struct {
+	Versions []ServiceVersion
+}
+
+// HelloReply is the answer to a Hello, with the versions of the services of
+// the node.
+type HelloReply struct {
+	Versions []ServiceVersion
+}
@@ -0,0 +1,68 @@
+// Package wireversion lets the conodes of a roster agree on the version of the
+// messages they exchange for every service, so that a roster running
+// different releases during a rolling upgrade fails loudly instead of
+// dropping the messages it can't unmarshal.
+//
+// A service whose messages can change between releases implements Versioner.
+// The first time a conode talks to another one, the WireVersion service of
+// both exchange the ranges of versions of all their services, and keep them
+// for CacheTTL. The services ask it for the version to use with a node or a
+// roster before starting a protocol:
+//
+//   - if both nodes speak the newest version of the other, they use the
+//     smallest current version of the two
+//   - a node that doesn't answer the exchange in HelloTimeout, or doesn't give
+//     a version for the service, runs a release from before the exchange and
+//     speaks Legacy
+//   - if the ranges of the two nodes don't overlap, the request fails with an
+//     error wrapping ErrIncompatible
+//   - a node that can't be reached is ignored, as the service would fail to
+//     send it its messages anyway, and is asked again after FailureTTL
+package wireversion
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Legacy is the version of the messages of the services before the versions
+// were exchanged.
+const Legacy = 1
+
+// ErrIncompatible is wrapped in the errors of the nodes that don't share a
+// version of a service.
+var ErrIncompatible = errors.New("incompatible wire versions")
+
+// Range is the versions of the messages of a service a node can talk.
+type Range struct {
+	// Current is the version the node sends by default.
+	Current int
+	// Oldest is the oldest version the node can still send and receive.
+	Oldest int
+}
+
+// LegacyRange is the range of the nodes running a release from before the
+// versions were exchanged.
+var LegacyRange = Range{Current: Legacy, Oldest: Legacy}
+
+// Versioner is implemented by the services whose messages between conodes
+// can change between releases.
+type Versioner interface {
+	WireVersion() Range
+}
+
+// Common returns the version two nodes with the given ranges use, which is
+// the smallest of their current versions, or an error if one of them can't
+// talk it anymore.
+func Common(local, peer Range) (int, error) {
+	v := local.Current
+	if peer.Current < v {
+		v = peer.Current
+	}
+	if v < local.Oldest || v < peer.Oldest {
+		return 0, fmt.Errorf("%w: versions %d to %d here, %d to %d on the "+
+			"peer", ErrIncompatible, local.Oldest, local.Current, peer.Oldest,
+			peer.Current)
+	}
+	return v, nil
+}
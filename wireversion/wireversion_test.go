@@ -0,0 +1,26 @@
+package wireversion
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommon(t *testing.T) {
+	v, err := Common(Range{Current: 3, Oldest: 1}, Range{Current: 2, Oldest: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+
+	v, err = Common(Range{Current: 2, Oldest: 1}, LegacyRange)
+	require.NoError(t, err)
+	require.Equal(t, Legacy, v)
+
+	// The peer doesn't talk our newest version anymore
+	_, err = Common(Range{Current: 2, Oldest: 1}, Range{Current: 4, Oldest: 3})
+	require.True(t, errors.Is(err, ErrIncompatible))
+
+	// We don't talk the version of the peer anymore
+	_, err = Common(Range{Current: 3, Oldest: 2}, LegacyRange)
+	require.True(t, errors.Is(err, ErrIncompatible))
+}